@@ -1,8 +1,15 @@
 package cluster
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
 	rpcDriver "github.com/rancher/kontainer-engine/driver"
-	"github.com/sirupsen/logrus"
+	"github.com/rancher/kontainer-engine/utils"
 )
 
 const (
@@ -12,18 +19,38 @@ const (
 	Running     = "Running"
 	Error       = "Error"
 	Updating    = "Updating"
+	// Removed means the cloud resources are removed but the config is kept to create the cluster again
+	Removed = "Removed"
+	// Interrupted means the create was interrupted, e.g. with Ctrl-C, before the cluster was running
+	Interrupted = "Interrupted"
+	// Restoring means the cluster is being restored from one of its snapshots
+	Restoring = "Restoring"
+	// Deleting means the provider reported the cluster as being deleted
+	Deleting = "Deleting"
 )
 
+// ErrInterrupted is returned by CreateContext when its context is done before the cluster is created
+var ErrInterrupted = errors.New("create interrupted")
+
 // Cluster represents a kubernetes cluster
 type Cluster struct {
+	// The schema version of the persisted config
+	SchemaVersion int `json:"schemaVersion,omitempty" yaml:"schema_version,omitempty"`
 	// The cluster driver to provision cluster
 	Driver Driver `json:"-"`
 	// The name of the cluster driver
 	DriverName string `json:"driverName,omitempty" yaml:"driver_name,omitempty"`
+	// The version of the cluster driver that created the cluster
+	DriverVersion string `json:"driverVersion,omitempty" yaml:"driver_version,omitempty"`
 	// The name of the cluster
 	Name string `json:"name,omitempty" yaml:"name,omitempty"`
 	// The status of the cluster
 	Status string `json:"status,omitempty" yaml:"status,omitempty"`
+	// Whether the driver is creating the cluster, updates and restores persist Post-Checking too so only a cluster that
+	// has it is a half-done create
+	CreatePending bool `json:"createPending,omitempty" yaml:"create_pending,omitempty"`
+	// The time the cluster was created(RFC3339, UTC)
+	CreatedAt string `json:"createdAt,omitempty" yaml:"created_at,omitempty"`
 
 	// specific info about kubernetes cluster
 	// Kubernetes cluster version
@@ -32,6 +59,10 @@ type Cluster struct {
 	ServiceAccountToken string `json:"serviceAccountToken,omitempty" yaml:"service_account_token,omitempty"`
 	// Kubernetes API master endpoint
 	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	// All the API endpoints reported by the driver keyed by type, e.g. public and private
+	Endpoints map[string]string `json:"endpoints,omitempty" yaml:"endpoints,omitempty"`
+	// The endpoint type written to kubeconfig, empty means Endpoint
+	EndpointType string `json:"endpointType,omitempty" yaml:"endpoint_type,omitempty"`
 	// Username for http basic authentication
 	Username string `json:"username,omitempty" yaml:"username,omitempty"`
 	// Password for http basic authentication
@@ -47,15 +78,60 @@ type Cluster struct {
 
 	// Metadata store specific driver options per cloud provider
 	Metadata map[string]string `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	// ProviderMetadata holds the identifiers of the cloud resources of the cluster, e.g. its self link
+	ProviderMetadata map[string]string `json:"providerMetadata,omitempty" yaml:"provider_metadata,omitempty"`
+	// Options are the driver options the cluster was created or last updated with, without the credential. Update
+	// starts from them so that the options not given again keep their value
+	Options *rpcDriver.DriverOptions `json:"options,omitempty" yaml:"options,omitempty"`
+	// SpecHash is the SpecHash of the options the cluster was created or last updated with, Update doesn't call the
+	// driver when it is unchanged
+	SpecHash string `json:"specHash,omitempty" yaml:"spec_hash,omitempty"`
+	// VolatileOptions are left out of SpecHash besides the credential, impersonation and wait options, e.g. the flags
+	// of a command that don't change the cluster
+	VolatileOptions []string `json:"-" yaml:"-"`
+	// ForceUpdate makes Update call the driver even if the options are unchanged
+	ForceUpdate bool `json:"-" yaml:"-"`
+	// ResponseHook, if set, is called by Create with the info the driver reported once the cluster is created, before
+	// it is checked and copied to the cluster
+	ResponseHook func(info rpcDriver.ClusterInfo) `json:"-" yaml:"-"`
+	// Warnings are the warnings the driver returned with the last create or update, they aren't persisted
+	Warnings []string `json:"-" yaml:"-"`
 
 	PersistStore PersistStore `json:"-" yaml:"-"`
 
 	ConfigGetter ConfigGetter `json:"-" yaml:"-"`
+
+	// Clock timestamps the cluster, nil means the wall clock
+	Clock utils.Clock `json:"-" yaml:"-"`
+}
+
+// State is the stored state of a cluster as reported by PersistStore.Check
+type State int
+
+const (
+	// StateNotFound means the cluster isn't stored
+	StateNotFound State = iota
+	// StateNotRunning means the cluster is stored but its status isn't Running
+	StateNotRunning
+	// StateRunning means the cluster is stored and running
+	StateRunning
+)
+
+func (s State) String() string {
+	switch s {
+	case StateNotFound:
+		return "NotFound"
+	case StateNotRunning:
+		return "NotRunning"
+	case StateRunning:
+		return "Running"
+	}
+	return "Unknown"
 }
 
 // PersistStore defines the interface for persist options like check and store
 type PersistStore interface {
-	Check(name string) (bool, error)
+	Check(name string) (State, error)
 	Get(name string) (Cluster, error)
 	Store(cluster Cluster) error
 	PersistStatus(cluster Cluster, status string) error
@@ -68,11 +144,14 @@ type ConfigGetter interface {
 
 // Driver defines how a cluster should be created and managed. Different drivers represents different providers.
 type Driver interface {
-	// Create creates a cluster
-	Create() error
+	// Create creates a cluster, the result has the warnings of the driver
+	Create() (rpcDriver.OperationResult, error)
 
-	// Update updates a cluster
-	Update() error
+	// Resume finishes a create that stopped after the driver was asked to create the cluster
+	Resume() (rpcDriver.OperationResult, error)
+
+	// Update updates a cluster, the result has the warnings of the driver
+	Update() (rpcDriver.OperationResult, error)
 
 	// Get a general cluster info
 	Get() rpcDriver.ClusterInfo
@@ -94,12 +173,40 @@ type Driver interface {
 
 	// Set driver options for cluster driver
 	SetDriverOptions(options rpcDriver.DriverOptions) error
+
+	// GetVersion returns the version of the driver
+	GetVersion() (string, error)
+
+	// Snapshot takes a snapshot of the cluster
+	Snapshot() (rpcDriver.SnapshotResult, error)
+
+	// Restore restores the cluster from the snapshot called id
+	Restore(id string) (rpcDriver.SnapshotResult, error)
+}
+
+func (c *Cluster) now() time.Time {
+	if c.Clock == nil {
+		return time.Now()
+	}
+	return c.Clock.Now()
 }
 
 // Create creates a cluster
 func (c *Cluster) Create() error {
-	if err := c.createInner(); err != nil {
-		if err := c.PersistStore.PersistStatus(*c, Error); err != nil {
+	return c.CreateContext(context.Background())
+}
+
+// CreateContext creates a cluster. Once ctx is done the create stops before its next step and the cluster is
+// persisted as Interrupted. The lines it logs have the operation id of ctx, see utils.WithOperationID.
+func (c *Cluster) CreateContext(ctx context.Context) error {
+	err := c.createInner(ctx)
+	c.CreatePending = false
+	if err != nil {
+		status := Error
+		if err == ErrInterrupted {
+			status = Interrupted
+		}
+		if err := c.PersistStore.PersistStatus(*c, status); err != nil {
 			return err
 		}
 		return err
@@ -107,15 +214,32 @@ func (c *Cluster) Create() error {
 	return c.PersistStore.PersistStatus(*c, Running)
 }
 
-func (c *Cluster) createInner() error {
+func interrupted(ctx context.Context) error {
+	if ctx.Err() != nil {
+		return ErrInterrupted
+	}
+	return nil
+}
+
+// Resumable tells whether the create of the stored cluster stopped after the driver was asked to create it, e.g. the
+// process creating it died, so that the provider may have a partly created cluster
+func (c *Cluster) Resumable() bool {
+	return c.Status == Creating || (c.Status == PostCheck && c.CreatePending)
+}
+
+func (c *Cluster) createInner(ctx context.Context) error {
 	// check if it is already created
-	if ok, err := c.isCreated(); err == nil && ok {
-		logrus.Warnf("Cluster %s already exists.", c.Name)
-		return nil
-	} else if err != nil {
+	if state, err := c.PersistStore.Check(c.Name); err != nil {
 		return err
+	} else if state == StateRunning {
+		utils.OperationLogger(ctx).Warnf("Cluster %s already exists.", c.Name)
+		return nil
 	}
+	resume := c.Resumable()
 
+	if c.CreatedAt == "" {
+		c.CreatedAt = c.now().UTC().Format(time.RFC3339)
+	}
 	if err := c.PersistStore.PersistStatus(*c, PreCreating); err != nil {
 		return err
 	}
@@ -125,6 +249,13 @@ func (c *Cluster) createInner() error {
 	if err != nil {
 		return err
 	}
+	c.Options = storedOptions(driverOpts)
+	if c.SpecHash, err = SpecHash(driverOpts, c.VolatileOptions...); err != nil {
+		return err
+	}
+	if err := interrupted(ctx); err != nil {
+		return err
+	}
 
 	// also set metadata value to retrieve the cluster info
 	for k, v := range c.Metadata {
@@ -133,64 +264,187 @@ func (c *Cluster) createInner() error {
 
 	// pass cluster config to rpc driver
 	if err := c.Driver.SetDriverOptions(driverOpts); err != nil {
-		return err
+		return classifyDriverError(err)
 	}
 
 	info := c.Driver.Get()
 	transformClusterInfo(c, info)
 
-	if err := c.PersistStore.PersistStatus(*c, Creating); err != nil {
+	// record the driver version so later operations can detect a driver upgrade
+	version, err := c.Driver.GetVersion()
+	if err != nil {
+		return err
+	}
+	c.DriverVersion = version
+
+	if err := interrupted(ctx); err != nil {
 		return err
 	}
-	// create cluster
-	if err := c.Driver.Create(); err != nil {
+	c.CreatePending = true
+	if err := c.PersistStore.PersistStatus(*c, Creating); err != nil {
 		return err
 	}
+	// create cluster, or finish the half-done create
+	create := c.Driver.Create
+	if resume {
+		utils.OperationLogger(ctx).Infof("Resuming the create of cluster %s", c.Name)
+		create = c.Driver.Resume
+	}
+	result, err := create()
+	if err != nil {
+		// the call was canceled with the create
+		if err := interrupted(ctx); err != nil {
+			return err
+		}
+		return classifyDriverError(err)
+	}
+	c.reportWarnings(ctx, result)
 
+	if err := interrupted(ctx); err != nil {
+		return err
+	}
 	if err := c.PersistStore.PersistStatus(*c, PostCheck); err != nil {
 		return err
 	}
 	// receive cluster info back
 	if err := c.Driver.PostCheck(); err != nil {
-		return err
+		return classifyDriverError(err)
 	}
 	info = c.Driver.Get()
+	if c.ResponseHook != nil {
+		c.ResponseHook(info)
+	}
 	transformClusterInfo(c, info)
+	if err := checkCreated(c); err != nil {
+		return err
+	}
 
 	// persist cluster info
 	return c.Store()
 }
 
-// Update updates a cluster
+// reportWarnings logs the warnings the driver returned with the result of a create or an update and keeps them in
+// Warnings
+func (c *Cluster) reportWarnings(ctx context.Context, result rpcDriver.OperationResult) {
+	c.Warnings = result.Warnings
+	for _, warning := range c.Warnings {
+		utils.OperationLogger(ctx).Warnf("Driver %s: %s", c.DriverName, warning)
+	}
+}
+
+// checkCreated rejects a cluster the driver reported as created without the endpoint or the credential of its API
+// server, the cluster would be persisted as running but couldn't be used
+func checkCreated(c *Cluster) error {
+	missing := []string{}
+	if c.Endpoint == "" {
+		missing = append(missing, "an API endpoint")
+	}
+	if c.ServiceAccountToken == "" && c.Username == "" && (c.ClientCertificate == "" || c.ClientKey == "") {
+		missing = append(missing, "a service account token, a username or a client certificate")
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("driver %s reported cluster %s as created without %s, the cluster can't be used. "+
+		"It may exist at the provider, check it and remove it with 'rm --force %s'", c.DriverName, c.Name, strings.Join(missing, " or "), c.Name)
+}
+
+// Update updates a cluster. It doesn't call the driver if the SpecHash of the options is the stored one, unless
+// ForceUpdate is set
 func (c *Cluster) Update() error {
+	return c.UpdateContext(context.Background())
+}
+
+// UpdateContext updates a cluster like Update, the lines it logs have the operation id of ctx
+func (c *Cluster) UpdateContext(ctx context.Context) error {
 	driverOpts, err := c.ConfigGetter.GetConfig()
 	if err != nil {
 		return err
 	}
+	hash, err := SpecHash(driverOpts, c.VolatileOptions...)
+	if err != nil {
+		return err
+	}
+	if hash == c.SpecHash && !c.ForceUpdate {
+		utils.OperationLogger(ctx).Infof("Cluster %s is up to date, the options are unchanged", c.Name)
+		return nil
+	}
+	// the new options and their hash are only stored once the driver applied them, a failed update is run again by the
+	// next update with the same options
+	options := storedOptions(driverOpts)
 	driverOpts.StringOptions["name"] = c.Name
 	for k, v := range c.Metadata {
 		driverOpts.StringOptions[k] = v
 	}
 	if err := c.Driver.SetDriverOptions(driverOpts); err != nil {
-		return err
+		return classifyDriverError(err)
 	}
+	c.CreatePending = false
 	if err := c.PersistStore.PersistStatus(*c, Updating); err != nil {
 		return err
 	}
-	if err := c.Driver.Update(); err != nil {
-		return err
+	result, err := c.Driver.Update()
+	if err != nil {
+		return classifyDriverError(err)
 	}
+	c.reportWarnings(ctx, result)
 	if err := c.PersistStore.PersistStatus(*c, PostCheck); err != nil {
 		return err
 	}
 	if err := c.Driver.PostCheck(); err != nil {
-		return err
+		return classifyDriverError(err)
 	}
 	info := c.Driver.Get()
 	transformClusterInfo(c, info)
+	// the cluster is now managed by the running driver
+	version, err := c.Driver.GetVersion()
+	if err != nil {
+		return err
+	}
+	c.DriverVersion = version
+	c.Options = options
+	c.SpecHash = hash
 	return c.Store()
 }
 
+// VerifyDriverVersion makes sure the running driver has the same version as the driver that created the cluster.
+// If allowMismatch is set a different version is only logged as a warning.
+func (c *Cluster) VerifyDriverVersion(allowMismatch bool) error {
+	return c.VerifyDriverVersionContext(context.Background(), allowMismatch)
+}
+
+// VerifyDriverVersionContext verifies the driver version like VerifyDriverVersion, the warning it logs has the
+// operation id of ctx
+func (c *Cluster) VerifyDriverVersionContext(ctx context.Context, allowMismatch bool) error {
+	// clusters created before driver versions were recorded can't be checked
+	if c.DriverVersion == "" {
+		return nil
+	}
+	version, err := c.Driver.GetVersion()
+	if err != nil {
+		return err
+	}
+	if version == c.DriverVersion {
+		return nil
+	}
+	if !allowMismatch {
+		return fmt.Errorf("cluster %s was created by %s driver %s but the available driver is %s", c.Name, c.DriverName, c.DriverVersion, version)
+	}
+	utils.OperationLogger(ctx).Warnf("Cluster %s was created by %s driver %s but the available driver is %s", c.Name, c.DriverName, c.DriverVersion, version)
+	return nil
+}
+
+// storedOptions copies driverOpts without the credential and the options the driver declares secret so that they
+// aren't persisted in plain text. SecretKeys and SetKeys still record that the secret options were set.
+func storedOptions(driverOpts rpcDriver.DriverOptions) *rpcDriver.DriverOptions {
+	options := rpcDriver.CopyDriverOptions(&driverOpts)
+	for _, name := range append([]string{rpcDriver.CredentialOption}, options.SecretKeys...) {
+		delete(options.StringOptions, name)
+		delete(options.StringSliceOptions, name)
+	}
+	return options
+}
+
 func transformClusterInfo(c *Cluster, clusterInfo rpcDriver.ClusterInfo) {
 	c.ClientCertificate = clusterInfo.ClientCertificate
 	c.ClientKey = clusterInfo.ClientKey
@@ -199,8 +453,24 @@ func transformClusterInfo(c *Cluster, clusterInfo rpcDriver.ClusterInfo) {
 	c.Password = clusterInfo.Password
 	c.Version = clusterInfo.Version
 	c.Endpoint = clusterInfo.Endpoint
+	c.Endpoints = clusterInfo.Endpoints
 	c.NodeCount = clusterInfo.NodeCount
+	recorded := c.Metadata
 	c.Metadata = clusterInfo.Metadata
+	// the last snapshot and the kubeconfig path are recorded by kontainer-engine, keep them when the driver doesn't
+	// report them
+	for _, key := range []string{rpcDriver.SnapshotIDMetadata, rpcDriver.KubeConfigOutMetadata} {
+		if _, ok := c.Metadata[key]; !ok && recorded[key] != "" {
+			if c.Metadata == nil {
+				c.Metadata = map[string]string{}
+			}
+			c.Metadata[key] = recorded[key]
+		}
+	}
+	// the identifiers are only known once the cluster is created, keep them when the driver doesn't report them again
+	if len(clusterInfo.ProviderMetadata) > 0 {
+		c.ProviderMetadata = clusterInfo.ProviderMetadata
+	}
 	c.ServiceAccountToken = clusterInfo.ServiceAccountToken
 }
 
@@ -215,13 +485,142 @@ func (c *Cluster) Remove() error {
 	}
 	driverOptions.StringOptions["name"] = c.Name
 	if err := c.Driver.SetDriverOptions(driverOptions); err != nil {
+		return classifyDriverError(err)
+	}
+	return classifyDriverError(c.Driver.Remove())
+}
+
+// Snapshot asks the driver to take a snapshot of the cluster and records the identifier of the snapshot in the
+// metadata of the stored cluster. It is an *UnsupportedError if the driver can't take snapshots.
+func (c *Cluster) Snapshot() (string, error) {
+	driverOptions, err := c.ConfigGetter.GetConfig()
+	if err != nil {
+		return "", err
+	}
+	for k, v := range c.Metadata {
+		driverOptions.StringOptions[k] = v
+	}
+	driverOptions.StringOptions["name"] = c.Name
+	if err := c.Driver.SetDriverOptions(driverOptions); err != nil {
+		return "", classifyDriverError(err)
+	}
+	result, err := c.Driver.Snapshot()
+	if err != nil {
+		return "", classifyDriverError(err)
+	}
+	switch result.Status {
+	case rpcDriver.SnapshotOK:
+	case rpcDriver.SnapshotUnsupported:
+		return "", &UnsupportedError{DriverName: c.DriverName, Operation: "snapshots"}
+	default:
+		return "", fmt.Errorf("driver %s failed to take a snapshot of cluster %s (%s): %s", c.DriverName, c.Name, result.Status, result.Message)
+	}
+	if result.Id == "" {
+		return "", fmt.Errorf("driver %s reported a snapshot of cluster %s without its identifier", c.DriverName, c.Name)
+	}
+	if c.Metadata == nil {
+		c.Metadata = map[string]string{}
+	}
+	c.Metadata[rpcDriver.SnapshotIDMetadata] = result.Id
+	return result.Id, c.Store()
+}
+
+// Restore asks the driver to restore the cluster from the snapshot called id then stores the cluster as the driver
+// reports it afterwards. It is an *UnsupportedError if the driver can't restore snapshots.
+func (c *Cluster) Restore(id string) error {
+	driverOptions, err := c.ConfigGetter.GetConfig()
+	if err != nil {
 		return err
 	}
-	return c.Driver.Remove()
+	for k, v := range c.Metadata {
+		driverOptions.StringOptions[k] = v
+	}
+	driverOptions.StringOptions["name"] = c.Name
+	if err := c.Driver.SetDriverOptions(driverOptions); err != nil {
+		return classifyDriverError(err)
+	}
+	c.CreatePending = false
+	if err := c.PersistStore.PersistStatus(*c, Restoring); err != nil {
+		return err
+	}
+	if err := c.restoreInner(id); err != nil {
+		// the cluster may be partly restored, unless the driver doesn't restore snapshots
+		status := Error
+		if _, ok := err.(*UnsupportedError); ok {
+			status = c.Status
+		}
+		if err := c.PersistStore.PersistStatus(*c, status); err != nil {
+			return err
+		}
+		return err
+	}
+	transformClusterInfo(c, c.Driver.Get())
+	c.Status = Running
+	return c.Store()
 }
 
-func (c *Cluster) isCreated() (bool, error) {
-	return c.PersistStore.Check(c.Name)
+// restoreInner asks the driver to restore the cluster from the snapshot called id and checks the restored cluster
+func (c *Cluster) restoreInner(id string) error {
+	result, err := c.Driver.Restore(id)
+	if err != nil {
+		return classifyDriverError(err)
+	}
+	switch result.Status {
+	case rpcDriver.SnapshotOK:
+	case rpcDriver.SnapshotUnsupported:
+		return &UnsupportedError{DriverName: c.DriverName, Operation: "restoring snapshots"}
+	default:
+		return fmt.Errorf("driver %s failed to restore cluster %s from snapshot %s (%s): %s", c.DriverName, c.Name, id, result.Status, result.Message)
+	}
+	if err := c.PersistStore.PersistStatus(*c, PostCheck); err != nil {
+		return err
+	}
+	if err := c.Driver.PostCheck(); err != nil {
+		return classifyDriverError(err)
+	}
+	return nil
+}
+
+// Refresh asks the driver for the connection details of the cluster as they are now, e.g. after the provider moved its
+// endpoint, and stores them. Unlike Update nothing is provisioned. It returns the names of the fields that changed.
+func (c *Cluster) Refresh() ([]string, error) {
+	driverOptions, err := c.ConfigGetter.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range c.Metadata {
+		driverOptions.StringOptions[k] = v
+	}
+	driverOptions.StringOptions["name"] = c.Name
+	if err := c.Driver.SetDriverOptions(driverOptions); err != nil {
+		return nil, classifyDriverError(err)
+	}
+	if err := c.Driver.PostCheck(); err != nil {
+		return nil, classifyDriverError(err)
+	}
+	before := *c
+	transformClusterInfo(c, c.Driver.Get())
+	changed := []string{}
+	for _, field := range []struct {
+		name          string
+		before, after interface{}
+	}{
+		{"endpoint", before.Endpoint, c.Endpoint},
+		{"endpoints", before.Endpoints, c.Endpoints},
+		{"rootCACert", before.RootCACert, c.RootCACert},
+		{"clientCertificate", before.ClientCertificate, c.ClientCertificate},
+		{"clientKey", before.ClientKey, c.ClientKey},
+		{"serviceAccountToken", before.ServiceAccountToken, c.ServiceAccountToken},
+		{"username", before.Username, c.Username},
+		{"password", before.Password, c.Password},
+		{"version", before.Version, c.Version},
+		{"nodeCount", before.NodeCount, c.NodeCount},
+	} {
+		if !reflect.DeepEqual(field.before, field.after) {
+			changed = append(changed, field.name)
+		}
+	}
+	return changed, c.Store()
 }
 
 // Store persists cluster information
@@ -0,0 +1,82 @@
+package cluster
+
+import (
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"gopkg.in/check.v1"
+)
+
+type CreateTestSuite struct{}
+
+var _ = check.Suite(&CreateTestSuite{})
+
+// infoDriver creates the clusters successfully and reports info for them
+type infoDriver struct {
+	countingDriver
+	info rpcDriver.ClusterInfo
+}
+
+func (d *infoDriver) Create() (rpcDriver.OperationResult, error) {
+	return rpcDriver.OperationResult{}, nil
+}
+func (d *infoDriver) Get() rpcDriver.ClusterInfo  { return d.info }
+func (d *infoDriver) DriverName() string          { return "mock" }
+func (d *infoDriver) GetVersion() (string, error) { return "v0.1.0", nil }
+
+// statusStore records the persisted statuses
+type statusStore struct {
+	discardStore
+	statuses []string
+	stored   int
+}
+
+func (s *statusStore) Store(cluster Cluster) error {
+	s.stored++
+	return nil
+}
+
+func (s *statusStore) PersistStatus(cluster Cluster, status string) error {
+	s.statuses = append(s.statuses, status)
+	return nil
+}
+
+func (s *CreateTestSuite) create(info rpcDriver.ClusterInfo) (*statusStore, error) {
+	store := &statusStore{}
+	cls := Cluster{
+		Name:         "foo",
+		DriverName:   "mock",
+		Driver:       &infoDriver{info: info},
+		PersistStore: store,
+		ConfigGetter: optionsGetter(specOptions()),
+	}
+	return store, cls.Create()
+}
+
+func (s *CreateTestSuite) TestCreateWithoutEndpoint(c *check.C) {
+	store, err := s.create(rpcDriver.ClusterInfo{ServiceAccountToken: "token"})
+	c.Assert(err, check.ErrorMatches, "driver mock reported cluster foo as created without an API endpoint, .*rm --force foo.*")
+	// the cluster isn't stored as running
+	c.Assert(store.stored, check.Equals, 0)
+	c.Assert(store.statuses[len(store.statuses)-1], check.Equals, Error)
+}
+
+func (s *CreateTestSuite) TestCreateWithoutCredential(c *check.C) {
+	store, err := s.create(rpcDriver.ClusterInfo{Endpoint: "1.1.1.1", ClientCertificate: "cert"})
+	c.Assert(err, check.ErrorMatches, ".*without a service account token, a username or a client certificate, .*")
+	c.Assert(store.statuses[len(store.statuses)-1], check.Equals, Error)
+
+	_, err = s.create(rpcDriver.ClusterInfo{})
+	c.Assert(err, check.ErrorMatches, ".*without an API endpoint or a service account token, .*")
+}
+
+func (s *CreateTestSuite) TestCreateWithEndpointAndCredential(c *check.C) {
+	for _, info := range []rpcDriver.ClusterInfo{
+		{Endpoint: "1.1.1.1", ServiceAccountToken: "token"},
+		{Endpoint: "1.1.1.1", Username: "admin", Password: "secret"},
+		{Endpoint: "1.1.1.1", ClientCertificate: "cert", ClientKey: "key"},
+	} {
+		store, err := s.create(info)
+		c.Assert(err, check.IsNil)
+		c.Assert(store.stored, check.Equals, 1)
+		c.Assert(store.statuses[len(store.statuses)-1], check.Equals, Running)
+	}
+}
@@ -0,0 +1,156 @@
+// Package clustertest provides utilities for testing the implementations of the cluster package interfaces
+package clustertest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rancher/kontainer-engine/cluster"
+)
+
+// StoreConformanceTest checks that the PersistStore returned by factory satisfies the invariants every store must keep:
+//
+//  - Check of a cluster that was never stored returns StateNotFound and no error, Get returns an error
+//  - PersistStatus stores the cluster with the status, Check then returns StateRunning for Running and StateNotRunning for any other status
+//  - Get returns what Store stored, with SchemaVersion set to CurrentSchemaVersion
+//  - Store overwrites the cluster stored with the same name and leaves the other clusters alone
+//  - PersistStatus only changes the status of the cluster it is given
+//  - Changing a cluster after it is stored doesn't change the stored cluster
+//
+// factory is called once per case and must return an empty store.
+func StoreConformanceTest(t *testing.T, factory func() cluster.PersistStore) {
+	t.Run("NotFound", func(t *testing.T) {
+		persistStore := factory()
+		state, err := persistStore.Check("foo")
+		if err != nil || state != cluster.StateNotFound {
+			t.Errorf("Check of a missing cluster returned %v, %v, want %v, nil", state, err, cluster.StateNotFound)
+		}
+		if _, err := persistStore.Get("foo"); err == nil {
+			t.Error("Get of a missing cluster didn't return an error")
+		}
+	})
+
+	t.Run("Check", func(t *testing.T) {
+		persistStore := factory()
+		for _, tc := range []struct {
+			status string
+			state  cluster.State
+		}{
+			{cluster.PreCreating, cluster.StateNotRunning},
+			{cluster.Creating, cluster.StateNotRunning},
+			{cluster.Running, cluster.StateRunning},
+			{cluster.Error, cluster.StateNotRunning},
+			{cluster.Interrupted, cluster.StateNotRunning},
+		} {
+			if err := persistStore.PersistStatus(cluster.Cluster{Name: "foo", DriverName: "mock"}, tc.status); err != nil {
+				t.Fatalf("PersistStatus %s failed: %v", tc.status, err)
+			}
+			state, err := persistStore.Check("foo")
+			if err != nil || state != tc.state {
+				t.Errorf("Check of a %s cluster returned %v, %v, want %v, nil", tc.status, state, err, tc.state)
+			}
+		}
+	})
+
+	t.Run("StoreAndGet", func(t *testing.T) {
+		persistStore := factory()
+		cls := conformanceCluster("foo")
+		if err := persistStore.Store(cls); err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+		got, err := persistStore.Get("foo")
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		expected := conformanceCluster("foo")
+		expected.SchemaVersion = cluster.CurrentSchemaVersion
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("Get returned %+v, want %+v", got, expected)
+		}
+	})
+
+	t.Run("Overwrite", func(t *testing.T) {
+		persistStore := factory()
+		for _, name := range []string{"foo", "bar"} {
+			if err := persistStore.Store(conformanceCluster(name)); err != nil {
+				t.Fatalf("Store failed: %v", err)
+			}
+		}
+		cls := conformanceCluster("foo")
+		cls.NodeCount = 5
+		cls.Metadata = map[string]string{"zone": "us-west1-b"}
+		if err := persistStore.Store(cls); err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+		got, err := persistStore.Get("foo")
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if got.NodeCount != 5 || !reflect.DeepEqual(got.Metadata, cls.Metadata) {
+			t.Errorf("Store didn't overwrite the cluster, got %+v", got)
+		}
+		other, err := persistStore.Get("bar")
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if other.NodeCount != 3 {
+			t.Errorf("Store of foo changed bar, got %+v", other)
+		}
+	})
+
+	t.Run("PersistStatus", func(t *testing.T) {
+		persistStore := factory()
+		if err := persistStore.Store(conformanceCluster("foo")); err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+		cls, err := persistStore.Get("foo")
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if err := persistStore.PersistStatus(cls, cluster.Updating); err != nil {
+			t.Fatalf("PersistStatus failed: %v", err)
+		}
+		got, err := persistStore.Get("foo")
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		cls.Status = cluster.Updating
+		if !reflect.DeepEqual(got, cls) {
+			t.Errorf("PersistStatus changed more than the status, got %+v, want %+v", got, cls)
+		}
+	})
+
+	t.Run("Copy", func(t *testing.T) {
+		persistStore := factory()
+		cls := conformanceCluster("foo")
+		if err := persistStore.Store(cls); err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+		cls.Metadata["zone"] = "changed"
+		cls.ProviderMetadata["selfLink"] = "changed"
+		got, err := persistStore.Get("foo")
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if got.Metadata["zone"] != "us-central1-a" || got.ProviderMetadata["selfLink"] != "https://mock.local/clusters/foo" {
+			t.Errorf("changing a stored cluster changed the store, got %+v", got)
+		}
+	})
+}
+
+func conformanceCluster(name string) cluster.Cluster {
+	return cluster.Cluster{
+		Name:                name,
+		DriverName:          "mock",
+		DriverVersion:       "v0.1.0",
+		Status:              cluster.Running,
+		CreatedAt:           "2017-12-01T00:00:00Z",
+		Version:             "v1.8.4",
+		ServiceAccountToken: "token",
+		Endpoint:            "1.1.1.1",
+		Endpoints:           map[string]string{"public": "1.1.1.1"},
+		NodeCount:           3,
+		Metadata:            map[string]string{"zone": "us-central1-a"},
+		ProviderMetadata:    map[string]string{"selfLink": "https://mock.local/clusters/" + name},
+	}
+}
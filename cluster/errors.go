@@ -0,0 +1,48 @@
+package cluster
+
+import (
+	"fmt"
+
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+)
+
+// DriverError is a failure of the provider the driver classified with one of the rpcDriver error codes, e.g.
+// rpcDriver.ErrorQuotaExceeded, so that the callers can react to it
+type DriverError struct {
+	Code    string
+	Message string
+}
+
+func (e *DriverError) Error() string {
+	return fmt.Sprintf("%s (%s)", e.Message, e.Code)
+}
+
+// Retryable reports whether the same request can succeed later, e.g. once the quota is available again or the conflicting
+// operation is done. A rejected
+// credential or option fails again until it is changed.
+func (e *DriverError) Retryable() bool {
+	return e.Code == rpcDriver.ErrorQuotaExceeded || e.Code == rpcDriver.ErrorConflict
+}
+
+// classifyDriverError maps the failure of a driver rpc the driver classified into a *DriverError, the other errors
+// are returned unchanged
+func classifyDriverError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if providerErr := rpcDriver.ProviderErrorFromStatus(err); providerErr != nil {
+		return &DriverError{Code: providerErr.Code, Message: providerErr.Message}
+	}
+	return err
+}
+
+// UnsupportedError is returned for an operation the driver of the cluster doesn't support
+type UnsupportedError struct {
+	DriverName string
+	// Operation names what isn't supported, e.g. snapshots
+	Operation string
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("driver %s doesn't support %s", e.DriverName, e.Operation)
+}
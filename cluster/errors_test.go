@@ -0,0 +1,145 @@
+package cluster
+
+import (
+	"errors"
+
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/rancher/kontainer-engine/driver/mock"
+	"gopkg.in/check.v1"
+)
+
+type DriverErrorTestSuite struct {
+	addr string
+}
+
+var _ = check.Suite(&DriverErrorTestSuite{})
+
+func (s *DriverErrorTestSuite) SetUpSuite(c *check.C) {
+	addr := make(chan string)
+	go rpcDriver.NewServer(mock.NewDriver(), addr).Serve()
+	s.addr = <-addr
+}
+
+func (s *DriverErrorTestSuite) TestCreateClassifiedFailures(c *check.C) {
+	for _, test := range []struct {
+		code      string
+		retryable bool
+	}{
+		{code: rpcDriver.ErrorQuotaExceeded, retryable: true},
+		{code: rpcDriver.ErrorAuthFailed},
+		{code: rpcDriver.ErrorConflict, retryable: true},
+		{code: rpcDriver.ErrorNotFound},
+		{code: rpcDriver.ErrorInvalid},
+	} {
+		name := "fail-" + test.code
+		options := specOptions()
+		options.StringOptions["name"] = name
+		cls, err := NewCluster("mock", s.addr, name, optionsGetter(options), &statusStore{})
+		c.Assert(err, check.IsNil)
+
+		err = cls.Create()
+		c.Assert(err, check.FitsTypeOf, &DriverError{})
+		driverErr := err.(*DriverError)
+		c.Assert(driverErr.Code, check.Equals, test.code)
+		c.Assert(driverErr.Retryable(), check.Equals, test.retryable)
+		c.Assert(err, check.ErrorMatches, "the mock provider failed to create cluster "+name+` \(`+test.code+`\)`)
+	}
+}
+
+func (s *DriverErrorTestSuite) TestUnclassifiedFailure(c *check.C) {
+	// the mock rejects a cluster without a name
+	options := specOptions()
+	delete(options.StringOptions, "name")
+	cls, err := NewCluster("mock", s.addr, "", optionsGetter(options), &statusStore{})
+	c.Assert(err, check.IsNil)
+	err = cls.Create()
+	c.Assert(err, check.NotNil)
+	_, ok := err.(*DriverError)
+	c.Assert(ok, check.Equals, false)
+	c.Assert(err, check.ErrorMatches, ".*cluster name is required")
+}
+
+// snapshotDriver reports result for the snapshots and the restores, or fails the restores with err and the post
+// checks with postCheckErr
+type snapshotDriver struct {
+	countingDriver
+	result       rpcDriver.SnapshotResult
+	err          error
+	postCheckErr error
+}
+
+func (d *snapshotDriver) Snapshot() (rpcDriver.SnapshotResult, error)         { return d.result, nil }
+func (d *snapshotDriver) Restore(id string) (rpcDriver.SnapshotResult, error) { return d.result, d.err }
+func (d *snapshotDriver) PostCheck() error                                    { return d.postCheckErr }
+
+func (s *DriverErrorTestSuite) TestSnapshot(c *check.C) {
+	store := &statusStore{}
+	cls := Cluster{
+		Name:         "foo",
+		DriverName:   "mock",
+		Driver:       &snapshotDriver{result: rpcDriver.SnapshotResult{Status: rpcDriver.SnapshotOK, Id: "snap-1"}},
+		PersistStore: store,
+		ConfigGetter: optionsGetter(specOptions()),
+		Metadata:     map[string]string{"zone": "us-central1-a"},
+	}
+	id, err := cls.Snapshot()
+	c.Assert(err, check.IsNil)
+	c.Assert(id, check.Equals, "snap-1")
+	c.Assert(cls.Metadata, check.DeepEquals, map[string]string{"zone": "us-central1-a", rpcDriver.SnapshotIDMetadata: "snap-1"})
+	c.Assert(store.stored, check.Equals, 1)
+
+	// the snapshot id is kept when the driver reports its own metadata
+	transformClusterInfo(&cls, rpcDriver.ClusterInfo{Metadata: map[string]string{"zone": "us-east1-b"}})
+	c.Assert(cls.Metadata, check.DeepEquals, map[string]string{"zone": "us-east1-b", rpcDriver.SnapshotIDMetadata: "snap-1"})
+
+	cls.Driver = &snapshotDriver{result: rpcDriver.SnapshotResult{Status: rpcDriver.SnapshotUnsupported}}
+	_, err = cls.Snapshot()
+	c.Assert(err, check.FitsTypeOf, &UnsupportedError{})
+	c.Assert(err, check.ErrorMatches, "driver mock doesn't support snapshots")
+	c.Assert(store.stored, check.Equals, 1)
+}
+
+func (s *DriverErrorTestSuite) TestRestore(c *check.C) {
+	store := &statusStore{}
+	cls := Cluster{
+		Name:         "foo",
+		DriverName:   "mock",
+		Status:       Error,
+		Driver:       &snapshotDriver{result: rpcDriver.SnapshotResult{Status: rpcDriver.SnapshotOK, Id: "snap-1"}},
+		PersistStore: store,
+		ConfigGetter: optionsGetter(specOptions()),
+	}
+	c.Assert(cls.Restore("snap-1"), check.IsNil)
+	c.Assert(store.statuses, check.DeepEquals, []string{Restoring, PostCheck})
+	c.Assert(cls.Status, check.Equals, Running)
+	c.Assert(store.stored, check.Equals, 1)
+
+	cls.Driver = &snapshotDriver{result: rpcDriver.SnapshotResult{Status: rpcDriver.SnapshotUnsupported}}
+	err := cls.Restore("snap-1")
+	c.Assert(err, check.FitsTypeOf, &UnsupportedError{})
+	c.Assert(err, check.ErrorMatches, "driver mock doesn't support restoring snapshots")
+	c.Assert(store.stored, check.Equals, 1)
+	c.Assert(store.statuses, check.DeepEquals, []string{Restoring, PostCheck, Restoring, Running})
+}
+
+func (s *DriverErrorTestSuite) TestFailedRestore(c *check.C) {
+	for _, driver := range []*snapshotDriver{
+		{err: errors.New("snapshot snap-1 is corrupt")},
+		{result: rpcDriver.SnapshotResult{Status: "Failed", Message: "the disks can't be attached"}},
+		{result: rpcDriver.SnapshotResult{Status: rpcDriver.SnapshotOK}, postCheckErr: errors.New("the API server isn't ready")},
+	} {
+		store := &statusStore{}
+		cls := Cluster{
+			Name:         "foo",
+			DriverName:   "mock",
+			Status:       Running,
+			Driver:       driver,
+			PersistStore: store,
+			ConfigGetter: optionsGetter(specOptions()),
+		}
+		c.Assert(cls.Restore("snap-1"), check.NotNil)
+		// the cluster isn't left restoring
+		c.Assert(store.statuses[len(store.statuses)-1], check.Equals, Error)
+		c.Assert(store.stored, check.Equals, 0)
+	}
+}
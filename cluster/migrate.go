@@ -0,0 +1,74 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// CurrentSchemaVersion is the schema version of the cluster config written by this build
+const CurrentSchemaVersion = 1
+
+// migration upgrades a raw cluster config by exactly one schema version
+type migration func(raw map[string]interface{}) error
+
+// migrations[i] upgrades a config from schema version i to i+1
+var migrations = []migration{
+	migrateV0ToV1,
+}
+
+// Migrate decodes a persisted cluster config and upgrades it to CurrentSchemaVersion.
+// It returns true if the config was changed and should be rewritten.
+func Migrate(data []byte) (Cluster, bool, error) {
+	raw := map[string]interface{}{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return Cluster{}, false, err
+	}
+	version := 0
+	if v, ok := raw["schemaVersion"].(float64); ok {
+		version = int(v)
+	}
+	if version > CurrentSchemaVersion {
+		logrus.Warnf("Cluster config has schema version %d which is newer than the supported version %d, some fields may be ignored and it can't be written", version, CurrentSchemaVersion)
+	}
+	migrated := false
+	for ; version < CurrentSchemaVersion; version++ {
+		if err := migrations[version](raw); err != nil {
+			return Cluster{}, false, err
+		}
+		raw["schemaVersion"] = version + 1
+		migrated = true
+	}
+
+	cls := Cluster{}
+	if !migrated {
+		err := json.Unmarshal(data, &cls)
+		return cls, false, err
+	}
+	upgraded, err := json.Marshal(raw)
+	if err != nil {
+		return Cluster{}, false, err
+	}
+	if err := json.Unmarshal(upgraded, &cls); err != nil {
+		return Cluster{}, false, err
+	}
+	return cls, true, nil
+}
+
+// StampSchemaVersion sets the schema version of cls to CurrentSchemaVersion before it is written. A cluster read from a
+// config with a newer schema is refused, writing it would label it with the older version and drop the fields this
+// build doesn't know.
+func StampSchemaVersion(cls *Cluster) error {
+	if cls.SchemaVersion > CurrentSchemaVersion {
+		return fmt.Errorf("the config of cluster %s has schema version %d which is newer than the supported version %d, upgrade kontainer-engine to change it",
+			cls.Name, cls.SchemaVersion, CurrentSchemaVersion)
+	}
+	cls.SchemaVersion = CurrentSchemaVersion
+	return nil
+}
+
+// migrateV0ToV1 upgrades configs written before schemaVersion existed. The shape is unchanged, only the version is stamped
+func migrateV0ToV1(raw map[string]interface{}) error {
+	return nil
+}
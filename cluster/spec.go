@@ -0,0 +1,43 @@
+package cluster
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+)
+
+// volatileOptions change how the driver authenticates or how long it waits, not the cluster, so they are left out of
+// SpecHash
+var volatileOptions = []string{
+	rpcDriver.CredentialOption,
+	rpcDriver.ImpersonateServiceAccountOption,
+	rpcDriver.ImpersonateUserOption,
+	rpcDriver.WaitInitialIntervalOption,
+	rpcDriver.WaitMaxIntervalOption,
+	rpcDriver.WaitTimeoutOption,
+}
+
+// SpecHash hashes the driver options without the volatile ones: the credential, the impersonation and wait options,
+// the names in volatile, which options were set explicitly and which are secret. The secret options themselves are
+// left out too, they aren't stored to compare them with. The json encoding sorts the keys of the option maps, so
+// equal options always have the same hash.
+func SpecHash(driverOptions rpcDriver.DriverOptions, volatile ...string) (string, error) {
+	options := rpcDriver.CopyDriverOptions(&driverOptions)
+	unhashed := append(append(append([]string{}, volatileOptions...), volatile...), options.SecretKeys...)
+	options.SetKeys = nil
+	options.SecretKeys = nil
+	for _, name := range unhashed {
+		delete(options.BoolOptions, name)
+		delete(options.StringOptions, name)
+		delete(options.IntOptions, name)
+		delete(options.StringSliceOptions, name)
+	}
+	data, err := json.Marshal(options)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
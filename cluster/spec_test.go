@@ -0,0 +1,208 @@
+package cluster
+
+import (
+	"errors"
+
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"gopkg.in/check.v1"
+)
+
+type SpecHashTestSuite struct{}
+
+var _ = check.Suite(&SpecHashTestSuite{})
+
+func specOptions() rpcDriver.DriverOptions {
+	return rpcDriver.DriverOptions{
+		BoolOptions:        map[string]bool{"enable-alpha-feature": true},
+		StringOptions:      map[string]string{"name": "foo", "zone": "us-central1-a", "b": "2", "a": "1"},
+		IntOptions:         map[string]int64{"node-count": 3},
+		StringSliceOptions: map[string]*rpcDriver.StringSlice{"labels": {Value: []string{"team=core"}}},
+	}
+}
+
+func (s *SpecHashTestSuite) TestSecretOptions(c *check.C) {
+	options := specOptions()
+	options.StringOptions["registry-auth"] = "registry-auth-custom-9z8y"
+	options.SecretKeys = []string{"registry-auth"}
+	stored := storedOptions(options)
+	_, ok := stored.StringOptions["registry-auth"]
+	c.Assert(ok, check.Equals, false)
+	c.Assert(stored.SecretKeys, check.DeepEquals, []string{"registry-auth"})
+
+	// the stored options without the secret have the hash of the options they were stored from
+	hash, err := SpecHash(options)
+	c.Assert(err, check.IsNil)
+	storedHash, err := SpecHash(*stored)
+	c.Assert(err, check.IsNil)
+	c.Assert(storedHash, check.Equals, hash)
+}
+
+func (s *SpecHashTestSuite) TestStable(c *check.C) {
+	first, err := SpecHash(specOptions())
+	c.Assert(err, check.IsNil)
+	for i := 0; i < 10; i++ {
+		again, err := SpecHash(specOptions())
+		c.Assert(err, check.IsNil)
+		c.Assert(again, check.Equals, first)
+	}
+
+	// the volatile options and which options were set don't change the hash
+	volatile := specOptions()
+	volatile.StringOptions[rpcDriver.CredentialOption] = "rotated"
+	volatile.StringOptions[rpcDriver.WaitTimeoutOption] = "2h"
+	volatile.StringOptions["write-credentials"] = "/tmp/foo"
+	volatile.SetKeys = []string{"node-count"}
+	hash, err := SpecHash(volatile, "write-credentials")
+	c.Assert(err, check.IsNil)
+	c.Assert(hash, check.Equals, first)
+	// the options aren't changed
+	c.Assert(volatile.StringOptions[rpcDriver.CredentialOption], check.Equals, "rotated")
+}
+
+func (s *SpecHashTestSuite) TestChanged(c *check.C) {
+	first, err := SpecHash(specOptions())
+	c.Assert(err, check.IsNil)
+	for _, change := range []func(*rpcDriver.DriverOptions){
+		func(o *rpcDriver.DriverOptions) { o.IntOptions["node-count"] = 4 },
+		func(o *rpcDriver.DriverOptions) { o.BoolOptions["enable-alpha-feature"] = false },
+		func(o *rpcDriver.DriverOptions) { o.StringOptions["zone"] = "europe-west1-b" },
+		func(o *rpcDriver.DriverOptions) { o.StringSliceOptions["labels"].Value = nil },
+		func(o *rpcDriver.DriverOptions) { o.NodePools = []*rpcDriver.NodePool{{Name: "default", Count: 3}} },
+	} {
+		options := specOptions()
+		change(&options)
+		hash, err := SpecHash(options)
+		c.Assert(err, check.IsNil)
+		c.Assert(hash, check.Not(check.Equals), first)
+	}
+}
+
+// countingDriver counts the updates of the clusters
+type countingDriver struct {
+	Driver
+	updates int
+}
+
+func (d *countingDriver) SetDriverOptions(options rpcDriver.DriverOptions) error { return nil }
+func (d *countingDriver) Update() (rpcDriver.OperationResult, error) {
+	d.updates++
+	return rpcDriver.OperationResult{}, nil
+}
+func (d *countingDriver) PostCheck() error            { return nil }
+func (d *countingDriver) Get() rpcDriver.ClusterInfo  { return rpcDriver.ClusterInfo{} }
+func (d *countingDriver) GetVersion() (string, error) { return "v0.1.0", nil }
+
+type optionsGetter rpcDriver.DriverOptions
+
+func (o optionsGetter) GetConfig() (rpcDriver.DriverOptions, error) {
+	return *rpcDriver.CopyDriverOptions((*rpcDriver.DriverOptions)(&o)), nil
+}
+
+// discardStore persists nothing
+type discardStore struct{}
+
+func (discardStore) Check(name string) (State, error)                   { return StateNotFound, nil }
+func (discardStore) Get(name string) (Cluster, error)                   { return Cluster{}, nil }
+func (discardStore) Store(cluster Cluster) error                        { return nil }
+func (discardStore) PersistStatus(cluster Cluster, status string) error { return nil }
+
+func (s *SpecHashTestSuite) TestUpdateSkipsUnchangedOptions(c *check.C) {
+	driver := &countingDriver{}
+	hash, err := SpecHash(specOptions())
+	c.Assert(err, check.IsNil)
+	cls := Cluster{Name: "foo", Driver: driver, PersistStore: discardStore{}, SpecHash: hash}
+
+	cls.ConfigGetter = optionsGetter(specOptions())
+	c.Assert(cls.Update(), check.IsNil)
+	c.Assert(driver.updates, check.Equals, 0)
+
+	cls.ForceUpdate = true
+	c.Assert(cls.Update(), check.IsNil)
+	c.Assert(driver.updates, check.Equals, 1)
+
+	changed := specOptions()
+	changed.IntOptions["node-count"] = 5
+	cls.ForceUpdate = false
+	cls.ConfigGetter = optionsGetter(changed)
+	c.Assert(cls.Update(), check.IsNil)
+	c.Assert(driver.updates, check.Equals, 2)
+	c.Assert(cls.SpecHash, check.Not(check.Equals), hash)
+	// the changed options are the new ones to compare to
+	c.Assert(cls.Update(), check.IsNil)
+	c.Assert(driver.updates, check.Equals, 2)
+}
+
+// failingUpdateDriver fails the first update and applies the next ones
+type failingUpdateDriver struct {
+	countingDriver
+}
+
+func (d *failingUpdateDriver) Update() (rpcDriver.OperationResult, error) {
+	d.updates++
+	if d.updates == 1 {
+		return rpcDriver.OperationResult{}, errors.New("quota exceeded")
+	}
+	return rpcDriver.OperationResult{}, nil
+}
+
+// lastClusterStore keeps the cluster as it was last persisted
+type lastClusterStore struct {
+	discardStore
+	cluster Cluster
+}
+
+func (s *lastClusterStore) Store(cluster Cluster) error {
+	s.cluster = cluster
+	return nil
+}
+
+func (s *lastClusterStore) PersistStatus(cluster Cluster, status string) error {
+	cluster.Status = status
+	s.cluster = cluster
+	return nil
+}
+
+func (s *SpecHashTestSuite) TestFailedUpdateRunsAgain(c *check.C) {
+	driver := &failingUpdateDriver{}
+	hash, err := SpecHash(specOptions())
+	c.Assert(err, check.IsNil)
+	store := &lastClusterStore{}
+	cls := Cluster{Name: "foo", Driver: driver, PersistStore: store, SpecHash: hash}
+	changed := specOptions()
+	changed.IntOptions["node-count"] = 5
+	cls.ConfigGetter = optionsGetter(changed)
+	c.Assert(cls.Update(), check.ErrorMatches, "quota exceeded")
+	// the stored hash is still the one of the options the cluster has
+	c.Assert(store.cluster.Status, check.Equals, Updating)
+	c.Assert(store.cluster.SpecHash, check.Equals, hash)
+
+	// the same update runs again from the stored cluster
+	stored := store.cluster
+	stored.Driver = driver
+	stored.PersistStore = store
+	stored.ConfigGetter = optionsGetter(changed)
+	c.Assert(stored.Update(), check.IsNil)
+	c.Assert(driver.updates, check.Equals, 2)
+	c.Assert(store.cluster.SpecHash, check.Not(check.Equals), hash)
+	c.Assert(store.cluster.Options.IntOptions["node-count"], check.Equals, int64(5))
+}
+
+// failingPostCheckDriver applies the updates and fails their post check
+type failingPostCheckDriver struct {
+	countingDriver
+}
+
+func (d *failingPostCheckDriver) PostCheck() error { return errors.New("nodes not ready") }
+
+func (s *SpecHashTestSuite) TestFailedUpdateNotResumable(c *check.C) {
+	store := &lastClusterStore{}
+	cls := Cluster{Name: "foo", Status: Running, Driver: &failingPostCheckDriver{}, PersistStore: store, ConfigGetter: optionsGetter(specOptions())}
+	c.Assert(cls.Update(), check.ErrorMatches, "nodes not ready")
+	// the cluster exists, create doesn't resume it
+	c.Assert(store.cluster.Status, check.Equals, PostCheck)
+	c.Assert(store.cluster.Resumable(), check.Equals, false)
+
+	// a create that stopped in its post check is resumed
+	created := Cluster{Name: "bar", Status: PostCheck, CreatePending: true}
+	c.Assert(created.Resumable(), check.Equals, true)
+}
@@ -0,0 +1,196 @@
+package cluster
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// verifyTimeout is how long VerifyConnectivity waits for the API server
+	verifyTimeout = 15 * time.Second
+	// DefaultUserAgent is the user agent of the API server calls when VerifyOptions doesn't set one
+	DefaultUserAgent = "kontainer-engine"
+	// clockSkewThreshold is how far the local clock can be from the clock of the API server before a certificate that
+	// is expired or not yet valid is blamed on the local clock
+	clockSkewThreshold = time.Minute
+)
+
+// VerifyOptions customizes the requests VerifyConnectivityWithOptions sends to the API server, e.g. for an API gateway
+// that rejects the requests without some headers
+type VerifyOptions struct {
+	// UserAgent defaults to DefaultUserAgent
+	UserAgent string
+	// Headers are added to the request, they take precedence over the user agent
+	Headers map[string]string
+}
+
+// ConnectivityError is returned by VerifyConnectivity when the API server can't be used with the stored credential.
+// Status is one of the driver connectivity statuses: bad-credentials, network or unknown.
+type ConnectivityError struct {
+	Status   string
+	Endpoint string
+	// StatusCode is the http status returned by the API server, 0 if it wasn't reached
+	StatusCode int
+	Err        error
+}
+
+func (e *ConnectivityError) Error() string {
+	return fmt.Sprintf("API server %s of the cluster failed the connectivity check (%s): %v", e.Endpoint, e.Status, e.Err)
+}
+
+// VerifyConnectivity calls the /version endpoint of the API server of c with its stored endpoint, credential and CA
+// certificate, so that a generated kubeconfig is known to work
+func VerifyConnectivity(c *Cluster) error {
+	return VerifyConnectivityWithOptions(c, VerifyOptions{})
+}
+
+// VerifyConnectivityWithOptions is VerifyConnectivity with a custom user agent and extra headers
+func VerifyConnectivityWithOptions(c *Cluster, opts VerifyOptions) error {
+	endpoint := c.Endpoint
+	if e, ok := c.Endpoints[c.EndpointType]; ok && e != "" {
+		endpoint = e
+	}
+	if endpoint == "" {
+		return &ConnectivityError{Status: rpcDriver.ConnectivityUnknown, Err: fmt.Errorf("cluster %s has no endpoint", c.Name)}
+	}
+	if !strings.HasPrefix(endpoint, "https://") && !strings.HasPrefix(endpoint, "http://") {
+		endpoint = "https://" + endpoint
+	}
+	connectivityErr := func(status string, statusCode int, err error) error {
+		return &ConnectivityError{Status: status, Endpoint: endpoint, StatusCode: statusCode, Err: err}
+	}
+
+	tlsConfig, err := clientTLSConfig(c)
+	if err != nil {
+		return connectivityErr(rpcDriver.ConnectivityUnknown, 0, err)
+	}
+	client := &http.Client{
+		Timeout:   verifyTimeout,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig, Proxy: http.ProxyFromEnvironment},
+	}
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(endpoint, "/")+"/version", nil)
+	if err != nil {
+		return connectivityErr(rpcDriver.ConnectivityUnknown, 0, err)
+	}
+	if c.ServiceAccountToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.ServiceAccountToken)
+	} else if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	userAgent := opts.UserAgent
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	req.Header.Set("User-Agent", userAgent)
+	for k, v := range opts.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		if isCertificateTimeError(err) {
+			err = blameClockSkew(c, endpoint, err)
+		}
+		return connectivityErr(rpcDriver.ConnectivityNetwork, 0, err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return nil
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return connectivityErr(rpcDriver.ConnectivityBadCredentials, resp.StatusCode, fmt.Errorf("the credential was rejected with %s", resp.Status))
+	}
+	return connectivityErr(rpcDriver.ConnectivityUnknown, resp.StatusCode, fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(body))))
+}
+
+// isCertificateTimeError tells whether err is a certificate of the API server that is expired or not yet valid by the
+// local clock
+func isCertificateTimeError(err error) bool {
+	for err != nil {
+		switch e := err.(type) {
+		case x509.CertificateInvalidError:
+			return e.Reason == x509.Expired
+		case *url.Error:
+			err = e.Err
+		case interface{ Unwrap() error }:
+			// the newer TLS stacks wrap the x509 error in their own verification error
+			err = e.Unwrap()
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// blameClockSkew compares the local clock with the Date of a response of the API server. If they are apart the
+// certificate error err is most likely the local clock being wrong, which is logged and added to err.
+func blameClockSkew(c *Cluster, endpoint string, err error) error {
+	// the certificate can't be trusted by the local clock, nothing but the Date header is read and no credential is sent
+	client := &http.Client{
+		Timeout:   verifyTimeout,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, Proxy: http.ProxyFromEnvironment},
+	}
+	resp, headErr := client.Head(endpoint)
+	if headErr != nil {
+		return err
+	}
+	resp.Body.Close()
+	serverTime, dateErr := http.ParseTime(resp.Header.Get("Date"))
+	if dateErr != nil {
+		return err
+	}
+	skew := c.now().Sub(serverTime).Round(time.Second)
+	direction := "ahead of"
+	if skew < 0 {
+		skew, direction = -skew, "behind"
+	}
+	if skew < clockSkewThreshold {
+		return err
+	}
+	logrus.Warnf("The local clock is %v %s the clock of the API server %s of cluster %s, the certificate of the API server fails to validate because of the local clock", skew, direction, endpoint, c.Name)
+	return fmt.Errorf("%v: probable clock skew, the local clock is %v %s the API server, fix the local clock", err, skew, direction)
+}
+
+// clientTLSConfig trusts the stored CA certificate of c, or the system CAs if there is none, and presents the client
+// certificate of c if it has one. The certificates are validated by the clock of c.
+func clientTLSConfig(c *Cluster) (*tls.Config, error) {
+	config := &tls.Config{Time: c.now}
+	if c.RootCACert != "" {
+		caCert, err := base64.StdEncoding.DecodeString(c.RootCACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode the CA certificate: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("the CA certificate isn't a PEM certificate")
+		}
+		config.RootCAs = pool
+	}
+	if c.ClientCertificate != "" && c.ClientKey != "" {
+		cert, err := base64.StdEncoding.DecodeString(c.ClientCertificate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode the client certificate: %v", err)
+		}
+		key, err := base64.StdEncoding.DecodeString(c.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode the client key: %v", err)
+		}
+		pair, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{pair}
+	}
+	return config, nil
+}
@@ -0,0 +1,122 @@
+package cluster
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/rancher/kontainer-engine/utils"
+	"gopkg.in/check.v1"
+)
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) {
+	check.TestingT(t)
+}
+
+type VerifyTestSuite struct {
+	server *httptest.Server
+}
+
+var _ = check.Suite(&VerifyTestSuite{})
+
+func (s *VerifyTestSuite) SetUpTest(c *check.C) {
+	s.server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/version" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer good-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"gitVersion":"v1.8.4"}`))
+	}))
+}
+
+func (s *VerifyTestSuite) TearDownTest(c *check.C) {
+	s.server.Close()
+}
+
+func (s *VerifyTestSuite) cluster(token string) *Cluster {
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.server.Certificate().Raw})
+	return &Cluster{
+		Name:                "foo",
+		Endpoint:            s.server.URL,
+		ServiceAccountToken: token,
+		RootCACert:          base64.StdEncoding.EncodeToString(ca),
+	}
+}
+
+func (s *VerifyTestSuite) TestVerifyConnectivity(c *check.C) {
+	c.Assert(VerifyConnectivity(s.cluster("good-token")), check.IsNil)
+}
+
+func (s *VerifyTestSuite) TestVerifyConnectivityUnauthorized(c *check.C) {
+	err := VerifyConnectivity(s.cluster("bad-token"))
+	connectivityErr, ok := err.(*ConnectivityError)
+	c.Assert(ok, check.Equals, true, check.Commentf("error %v isn't a ConnectivityError", err))
+	c.Assert(connectivityErr.Status, check.Equals, rpcDriver.ConnectivityBadCredentials)
+	c.Assert(connectivityErr.StatusCode, check.Equals, http.StatusUnauthorized)
+	c.Assert(connectivityErr.Endpoint, check.Equals, s.server.URL)
+}
+
+func (s *VerifyTestSuite) TestVerifyConnectivityUntrustedCA(c *check.C) {
+	cls := s.cluster("good-token")
+	cls.RootCACert = ""
+	err := VerifyConnectivity(cls)
+	c.Assert(err, check.FitsTypeOf, &ConnectivityError{})
+	c.Assert(err.(*ConnectivityError).Status, check.Equals, rpcDriver.ConnectivityNetwork)
+}
+
+func (s *VerifyTestSuite) TestVerifyConnectivityUnreachable(c *check.C) {
+	cls := s.cluster("good-token")
+	s.server.Close()
+	err := VerifyConnectivity(cls)
+	c.Assert(err, check.FitsTypeOf, &ConnectivityError{})
+	c.Assert(err.(*ConnectivityError).Status, check.Equals, rpcDriver.ConnectivityNetwork)
+}
+
+func (s *VerifyTestSuite) TestVerifyConnectivityHeaders(c *check.C) {
+	headers := make(chan http.Header, 2)
+	s.server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header
+	})
+
+	c.Assert(VerifyConnectivity(s.cluster("good-token")), check.IsNil)
+	c.Assert((<-headers).Get("User-Agent"), check.Equals, DefaultUserAgent)
+
+	c.Assert(VerifyConnectivityWithOptions(s.cluster("good-token"), VerifyOptions{
+		UserAgent: "kontainer-engine/v1.2.3",
+		Headers:   map[string]string{"X-Gateway-Key": "abc", "X-Team": "core"},
+	}), check.IsNil)
+	header := <-headers
+	c.Assert(header.Get("User-Agent"), check.Equals, "kontainer-engine/v1.2.3")
+	c.Assert(header.Get("X-Gateway-Key"), check.Equals, "abc")
+	c.Assert(header.Get("X-Team"), check.Equals, "core")
+	c.Assert(header.Get("Authorization"), check.Equals, "Bearer good-token")
+}
+
+func (s *VerifyTestSuite) TestVerifyConnectivityClockSkew(c *check.C) {
+	// the certificate of the test server expires in 2084
+	cls := s.cluster("good-token")
+	cls.Clock = utils.NewFakeClock(time.Date(2090, 1, 1, 0, 0, 0, 0, time.UTC))
+	err := VerifyConnectivity(cls)
+	c.Assert(err, check.FitsTypeOf, &ConnectivityError{})
+	c.Assert(err.(*ConnectivityError).Status, check.Equals, rpcDriver.ConnectivityNetwork)
+	c.Assert(err, check.ErrorMatches, ".*certificate has expired or is not yet valid.*: probable clock skew, the local clock is .* ahead of the API server, fix the local clock")
+
+	// a clock behind the server is reported too
+	cls.Clock = utils.NewFakeClock(time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC))
+	c.Assert(VerifyConnectivity(cls), check.ErrorMatches, ".*: probable clock skew, the local clock is .* behind the API server, fix the local clock")
+
+	// a clock close to the server's isn't blamed, e.g. for a certificate that really expired
+	cls.Clock = utils.NewFakeClock(time.Now().Add(30 * time.Second))
+	c.Assert(VerifyConnectivity(cls), check.IsNil)
+	c.Assert(blameClockSkew(cls, s.server.URL, errors.New("certificate has expired")), check.ErrorMatches, "certificate has expired")
+}
@@ -0,0 +1,14 @@
+package cmd
+
+import (
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/urfave/cli"
+)
+
+var addonFlag = cli.StringSliceFlag{
+	Name:  "addon",
+	Usage: "Turn an addon of the cluster on or off as NAME=on|off, e.g. dashboard=off, passed to the addon option of the driver whatever its name. Repeat the flag for several addons",
+}
+
+// addons maps --addon to the addon options of the driver
+var addons = toggleOptions{flag: addonFlag, kind: "addon", prefix: rpcDriver.AddonCanonicalPrefix}
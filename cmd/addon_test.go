@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/rancher/kontainer-engine/driver/gke"
+	"gopkg.in/check.v1"
+)
+
+type AddonTestSuite struct{}
+
+var _ = check.Suite(&AddonTestSuite{})
+
+func (s *AddonTestSuite) TestParseAddon(c *check.C) {
+	name, on, err := addons.parse("dashboard=on")
+	c.Assert(err, check.IsNil)
+	c.Assert(name, check.Equals, "dashboard")
+	c.Assert(on, check.Equals, true)
+	_, on, err = addons.parse("dashboard=off")
+	c.Assert(err, check.IsNil)
+	c.Assert(on, check.Equals, false)
+
+	for _, value := range []string{"dashboard", "=on", "dashboard=true", ""} {
+		_, _, err := addons.parse(value)
+		c.Assert(err, check.NotNil, check.Commentf("addon %s", value))
+		c.Assert(ExitCode(err), check.Equals, ExitValidation)
+	}
+}
+
+func (s *AddonTestSuite) TestMapToDriverOptions(c *check.C) {
+	driverOptions, err := resolveDriverOptions([]string{"--addon", "dashboard=on", "--addon", "monitoring=off", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.IsNil)
+	c.Assert(driverOptions.BoolOptions["dashboard"], check.Equals, true)
+	c.Assert(driverOptions.BoolOptions["monitoring"], check.Equals, false)
+	c.Assert(driverOptions.IsSet("dashboard"), check.Equals, true)
+	c.Assert(driverOptions.IsSet("monitoring"), check.Equals, true)
+
+	// the option of the driver can still be given, to the same value
+	driverOptions, err = resolveDriverOptions([]string{"--addon", "dashboard=on", "--dashboard", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.IsNil)
+	c.Assert(driverOptions.BoolOptions["dashboard"], check.Equals, true)
+	_, err = resolveDriverOptions([]string{"--addon", "dashboard=off", "--dashboard", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.ErrorMatches, "--addon dashboard=off and --dashboard are set to different values")
+	c.Assert(ExitCode(err), check.Equals, ExitUsage)
+	_, err = resolveDriverOptions([]string{"--addon", "dashboard=on", "--addon", "dashboard=off", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.ErrorMatches, "addon dashboard is turned both on and off")
+
+	// the addons have the names of the canonical options whatever the name of the options of the driver
+	gkeFlags, err := gke.NewDriver().GetDriverCreateOptions()
+	c.Assert(err, check.IsNil)
+	driverOptions, err = resolveDriverOptions([]string{"--addon", "dashboard=on", "--addon", "network-policy=on", "foo"}, *gkeFlags)
+	c.Assert(err, check.IsNil)
+	c.Assert(driverOptions.BoolOptions["kubernetes-dashboard"], check.Equals, true)
+	c.Assert(driverOptions.BoolOptions["network-policy-config"], check.Equals, true)
+	c.Assert(driverOptions.BoolOptions["http-load-balancing"], check.Equals, false)
+}
+
+func (s *AddonTestSuite) TestUnknownAddon(c *check.C) {
+	_, err := resolveDriverOptions([]string{"--addon", "istio=on", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.ErrorMatches, "the driver has no istio addon, its addons are dashboard, monitoring")
+	c.Assert(ExitCode(err), check.Equals, ExitValidation)
+
+	_, err = resolveDriverOptions([]string{"--addon", "dashboard=on", "foo"}, rpcDriver.DriverFlags{Options: map[string]*rpcDriver.Flag{
+		"region": {Type: rpcDriver.StringType},
+	}})
+	c.Assert(err, check.ErrorMatches, "the driver has no dashboard addon, its addons are none")
+}
@@ -0,0 +1,436 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/rancher/kontainer-engine/plugin"
+	"github.com/rancher/kontainer-engine/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	applyCreate = "create"
+	applyUpdate = "update"
+	applyRemove = "remove"
+	// applyRead is the action of a manifest that failed to be read
+	applyRead = "read"
+)
+
+// ApplyCommand defines the apply command
+func ApplyCommand() cli.Command {
+	return cli.Command{
+		Name:   "apply",
+		Usage:  "Create or update the kubernetes clusters declared in a manifest",
+		Action: applyClusters,
+		Flags: []cli.Flag{
+			cli.StringSliceFlag{
+				Name:  "file,f",
+				Usage: "The manifest (yaml or json) listing the clusters, or a glob pattern of manifests. Repeat the flag for several manifests, they are applied in the order of their sorted paths",
+			},
+			cli.BoolFlag{
+				Name:  "prune",
+				Usage: "Remove the clusters that are not declared in the manifests",
+			},
+			cli.BoolFlag{
+				Name:  "fail-fast",
+				Usage: "Stop at the first manifest or cluster that fails instead of applying the others",
+			},
+			allowVersionMismatchFlag,
+		},
+	}
+}
+
+// clusterManifest lists the clusters to apply
+type clusterManifest struct {
+	Clusters []clusterSpec `json:"clusters,omitempty" yaml:"clusters,omitempty"`
+}
+
+// clusterSpec declares a single cluster in a manifest
+type clusterSpec struct {
+	Name    string                 `json:"name,omitempty" yaml:"name,omitempty"`
+	Driver  string                 `json:"driver,omitempty" yaml:"driver,omitempty"`
+	Options map[string]interface{} `json:"options,omitempty" yaml:"options,omitempty"`
+}
+
+// applyOptions controls how a manifest is applied
+type applyOptions struct {
+	prune                bool
+	allowVersionMismatch bool
+	// failFast skips the clusters after the first failure
+	failFast bool
+}
+
+// applyResult records what happened to a cluster during apply
+type applyResult struct {
+	Name   string
+	Action string
+	Status string
+	Error  string
+}
+
+// staticConfigGetter returns driver options that have been resolved in advance
+type staticConfigGetter struct {
+	driverOptions rpcDriver.DriverOptions
+}
+
+func (s staticConfigGetter) GetConfig() (rpcDriver.DriverOptions, error) {
+	return s.driverOptions, nil
+}
+
+func applyClusters(ctx *cli.Context) error {
+	patterns := ctx.StringSlice("file")
+	if len(patterns) == 0 {
+		return usageErrorWithHelp(ctx, "apply", "manifest file is required")
+	}
+	files, err := expandManifestFiles(patterns)
+	if err != nil {
+		return err
+	}
+	opts := applyOptions{
+		prune:                ctx.Bool("prune"),
+		allowVersionMismatch: ctx.Bool(allowVersionMismatchFlag.Name),
+		failFast:             ctx.Bool("fail-fast"),
+	}
+	manifest, results, err := readManifests(files, opts.failFast)
+	if err != nil {
+		return err
+	}
+	if len(results) > 0 && opts.prune {
+		// the clusters of the unreadable manifests would be removed
+		logrus.Warnf("Not pruning the clusters as %d manifests failed to be read", len(results))
+		opts.prune = false
+	}
+	applied, err := applyManifest(manifest, opts)
+	results = append(results, applied...)
+	if err != nil {
+		return err
+	}
+
+	writer := utils.NewTableWriter([][]string{
+		{"NAME", "Name"},
+		{"ACTION", "Action"},
+		{"STATUS", "Status"},
+		{"ERROR", "Error"},
+	}, ctx)
+	failed := 0
+	for _, result := range results {
+		writer.Write(result)
+		if result.Error != "" {
+			failed++
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d clusters failed to apply", failed, len(results))
+	}
+	return nil
+}
+
+// expandManifestFiles returns the sorted paths of the manifests given with --file, a glob pattern must match a file
+func expandManifestFiles(patterns []string) ([]string, error) {
+	files := []string{}
+	seen := map[string]bool{}
+	for _, pattern := range patterns {
+		matches := []string{pattern}
+		if strings.ContainsAny(pattern, "*?[") {
+			var err error
+			matches, err = filepath.Glob(pattern)
+			if err != nil {
+				return nil, newUsageError("invalid manifest pattern %s: %v", pattern, err)
+			}
+			if len(matches) == 0 {
+				return nil, newNotFoundError("no manifest matches %s", pattern)
+			}
+		}
+		for _, file := range matches {
+			if !seen[file] {
+				seen[file] = true
+				files = append(files, file)
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// readManifests merges the clusters of the manifest files in their order. A manifest that fails to be read is
+// reported in the results, or returned as the error if failFast is set. A cluster declared in two manifests is an error.
+func readManifests(files []string, failFast bool) (clusterManifest, []applyResult, error) {
+	merged := clusterManifest{}
+	results := []applyResult{}
+	declaredIn := map[string]string{}
+	for _, file := range files {
+		manifest, err := readManifest(file)
+		if err != nil {
+			if failFast || len(files) == 1 {
+				return merged, nil, err
+			}
+			logrus.Errorf("Failed to read manifest %s: %v", file, err)
+			results = append(results, applyResult{Name: file, Action: applyRead, Status: "Failed", Error: err.Error()})
+			continue
+		}
+		for _, spec := range manifest.Clusters {
+			if other, ok := declaredIn[spec.Name]; ok {
+				return merged, nil, newValidationError("cluster %s is declared in %s and %s", spec.Name, other, file)
+			}
+			declaredIn[spec.Name] = file
+		}
+		merged.Clusters = append(merged.Clusters, manifest.Clusters...)
+	}
+	return merged, results, nil
+}
+
+func readManifest(file string) (clusterManifest, error) {
+	manifest := clusterManifest{}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return manifest, err
+	}
+	// json is a subset of yaml so both formats are accepted
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return manifest, newValidationError("failed to parse manifest %s: %v", file, err)
+	}
+	return manifest, validateManifest(manifest)
+}
+
+func validateManifest(manifest clusterManifest) error {
+	if len(manifest.Clusters) == 0 {
+		return newValidationError("manifest doesn't declare any cluster")
+	}
+	names := map[string]bool{}
+	for _, spec := range manifest.Clusters {
+		if spec.Name == "" {
+			return newValidationError("cluster name is required in manifest")
+		}
+		if spec.Driver == "" {
+			return newValidationError("driver name is required for cluster %s", spec.Name)
+		}
+		if names[spec.Name] {
+			return newValidationError("cluster %s is declared more than once", spec.Name)
+		}
+		names[spec.Name] = true
+	}
+	return nil
+}
+
+// applyManifest creates or updates every cluster in the manifest, and removes the undeclared ones if prune is set.
+// A failure on one cluster doesn't stop the others, it is recorded in its result instead. With failFast the clusters
+// after a failure are reported as skipped and nothing is pruned.
+func applyManifest(manifest clusterManifest, opts applyOptions) ([]applyResult, error) {
+	results := []applyResult{}
+	declared := map[string]bool{}
+	for i, spec := range manifest.Clusters {
+		declared[spec.Name] = true
+		operation := newOperationContext()
+		action, err := applyCluster(operation, spec, opts)
+		results = append(results, newApplyResult(operation, spec.Name, action, err))
+		if err != nil && opts.failFast {
+			for _, skipped := range manifest.Clusters[i+1:] {
+				results = append(results, applyResult{Name: skipped.Name, Status: "Skipped"})
+			}
+			return results, nil
+		}
+	}
+	if !opts.prune {
+		return results, nil
+	}
+
+	clusters, err := getAllClusters()
+	if err != nil {
+		return results, err
+	}
+	for name, cls := range clusters {
+		if declared[name] {
+			continue
+		}
+		configGetter := staticConfigGetter{
+			driverOptions: newDriverOptions(),
+		}
+		configGetter.driverOptions.StringOptions["name"] = name
+		operation := newOperationContext()
+		err := removeCluster(operation, cls, configGetter, removeOptions{allowVersionMismatch: opts.allowVersionMismatch})
+		results = append(results, newApplyResult(operation, name, applyRemove, err))
+	}
+	return results, nil
+}
+
+func newApplyResult(operation context.Context, name, action string, err error) applyResult {
+	result := applyResult{
+		Name:   name,
+		Action: action,
+		Status: "Success",
+	}
+	if err != nil {
+		result.Status = "Failed"
+		result.Error = redactMessage(err.Error())
+		utils.OperationLogger(operation).Errorf("Failed to %s cluster %s: %s", action, name, result.Error)
+	}
+	return result
+}
+
+// applyCluster updates the cluster if it is already stored, otherwise it creates it
+func applyCluster(ctx context.Context, spec clusterSpec, opts applyOptions) (string, error) {
+	persistStore := newPersistStore(kubeConfigOptions{})
+	// ignore the error as we only care if the cluster is present
+	existing, _ := persistStore.Get(spec.Name)
+	// a cluster removed with --keep-local has no cloud resources to update, it is created again
+	if existing.DriverName != "" && existing.Status != cluster.Removed {
+		_, err := updateFromSpec(ctx, existing, spec, persistStore, opts.allowVersionMismatch)
+		return applyUpdate, err
+	}
+	cls, err := newClusterFromSpec(spec, persistStore)
+	if err != nil {
+		return applyCreate, err
+	}
+	return applyCreate, cls.CreateContext(ctx)
+}
+
+// newClusterFromSpec returns the cluster declared by spec, stored with persistStore once it is created
+func newClusterFromSpec(spec clusterSpec, persistStore cluster.PersistStore) (*cluster.Cluster, error) {
+	rpcClient, addr, err := runSpecDriver(spec)
+	if err != nil {
+		return nil, err
+	}
+	driverFlags, err := rpcClient.GetDriverCreateOptions()
+	if err != nil {
+		return nil, err
+	}
+	driverOptions, err := toDriverOptions(spec, driverFlags, true)
+	if err != nil {
+		return nil, err
+	}
+	return cluster.NewCluster(spec.Driver, addr, spec.Name, staticConfigGetter{driverOptions}, persistStore)
+}
+
+// updateFromSpec updates existing, a cluster stored with persistStore, with the options declared by spec. The
+// create-only options of spec are skipped as they can't be changed.
+func updateFromSpec(ctx context.Context, existing cluster.Cluster, spec clusterSpec, persistStore cluster.PersistStore, allowVersionMismatch bool) (*cluster.Cluster, error) {
+	if existing.DriverName != spec.Driver {
+		return nil, fmt.Errorf("cluster %s is managed by driver %s, not %s", spec.Name, existing.DriverName, spec.Driver)
+	}
+	rpcClient, addr, err := runSpecDriver(spec)
+	if err != nil {
+		return nil, err
+	}
+	driverFlags, err := rpcClient.GetDriverUpdateOptions()
+	if err != nil {
+		return nil, err
+	}
+	driverOptions, err := toDriverOptions(spec, driverFlags, false)
+	if err != nil {
+		return nil, err
+	}
+	cls, err := cluster.FromCluster(&existing, addr, staticConfigGetter{driverOptions}, persistStore)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyDriverVersion(ctx, cls, allowVersionMismatch); err != nil {
+		return nil, err
+	}
+	return cls, cls.UpdateContext(ctx)
+}
+
+// runSpecDriver starts the driver of spec, which must be a built-in driver
+func runSpecDriver(spec clusterSpec) (*rpcDriver.GrpcClient, string, error) {
+	if !plugin.BuiltInDrivers[spec.Driver] {
+		return nil, "", fmt.Errorf("driver %s is not supported", spec.Driver)
+	}
+	return runRPCDriver(spec.Driver)
+}
+
+// toDriverOptions converts the options of a manifest entry into DriverOptions using the types declared by the driver.
+// Declared options missing from the manifest take their default value, the same way cli flags do.
+// If strict is set, an option the driver doesn't declare is an error, otherwise it is skipped.
+func toDriverOptions(spec clusterSpec, driverFlags rpcDriver.DriverFlags, strict bool) (rpcDriver.DriverOptions, error) {
+	driverOptions := newDriverOptions()
+	for k, flag := range driverFlags.Options {
+		switch flag.Type {
+		case rpcDriver.IntType:
+			val, err := strconv.ParseInt(flag.Value, 10, 64)
+			if err != nil {
+				val = 0
+			}
+			driverOptions.IntOptions[k] = val
+		case rpcDriver.StringType:
+			driverOptions.StringOptions[k] = flag.Value
+		case rpcDriver.BoolType:
+			driverOptions.BoolOptions[k] = false
+		case rpcDriver.StringSliceType:
+			driverOptions.StringSliceOptions[k] = &rpcDriver.StringSlice{}
+		}
+	}
+
+	for k, value := range spec.Options {
+		flag, ok := driverFlags.Options[k]
+		if !ok {
+			if strict {
+				return driverOptions, newValidationError("option %s is not supported by driver %s", k, spec.Driver)
+			}
+			continue
+		}
+		if err := setDriverOption(&driverOptions, k, flag.Type, value); err != nil {
+			message := err.Error()
+			if flag.Secret || isSecretOption(k) {
+				message = strings.Replace(message, fmt.Sprint(value), redactValue(fmt.Sprint(value)), -1)
+			}
+			return driverOptions, newValidationError("invalid value for option %s of cluster %s: %s", k, spec.Name, message)
+		}
+	}
+	markSecretOptions(&driverOptions, driverFlags)
+	driverOptions.StringOptions["name"] = spec.Name
+	return driverOptions, nil
+}
+
+func setDriverOption(driverOptions *rpcDriver.DriverOptions, key, optionType string, value interface{}) error {
+	switch optionType {
+	case rpcDriver.IntType:
+		switch v := value.(type) {
+		case int:
+			driverOptions.IntOptions[key] = int64(v)
+		case int64:
+			driverOptions.IntOptions[key] = v
+		case float64:
+			driverOptions.IntOptions[key] = int64(v)
+		default:
+			return fmt.Errorf("expected int but got %v", value)
+		}
+	case rpcDriver.StringType:
+		switch value.(type) {
+		case []interface{}, map[interface{}]interface{}:
+			return fmt.Errorf("expected string but got %v", value)
+		}
+		driverOptions.StringOptions[key] = fmt.Sprint(value)
+	case rpcDriver.BoolType:
+		v, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("expected bool but got %v", value)
+		}
+		driverOptions.BoolOptions[key] = v
+	case rpcDriver.StringSliceType:
+		switch v := value.(type) {
+		case []interface{}:
+			slice := []string{}
+			for _, item := range v {
+				slice = append(slice, fmt.Sprint(item))
+			}
+			driverOptions.StringSliceOptions[key] = &rpcDriver.StringSlice{Value: slice}
+		case string:
+			driverOptions.StringSliceOptions[key] = &rpcDriver.StringSlice{Value: []string{v}}
+		default:
+			return fmt.Errorf("expected a list but got %v", value)
+		}
+	}
+	return nil
+}
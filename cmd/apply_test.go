@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	"github.com/rancher/kontainer-engine/driver/mock"
+	"github.com/rancher/kontainer-engine/store"
+	"gopkg.in/check.v1"
+)
+
+type ApplyTestSuite struct {
+	tempHomeSuite
+}
+
+var _ = check.Suite(&ApplyTestSuite{})
+
+func mockSpec(name string, nodeCount int) clusterSpec {
+	return clusterSpec{
+		Name:   name,
+		Driver: "mock",
+		Options: map[string]interface{}{
+			"node-count": nodeCount,
+			"labels":     []interface{}{"foo=bar"},
+		},
+	}
+}
+
+func (s *ApplyTestSuite) TestApplyCreateUpdatePrune(c *check.C) {
+	results, err := applyManifest(clusterManifest{
+		Clusters: []clusterSpec{mockSpec("apply-a", 1), mockSpec("apply-b", 2)},
+	}, applyOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(results, check.DeepEquals, []applyResult{
+		{Name: "apply-a", Action: applyCreate, Status: "Success"},
+		{Name: "apply-b", Action: applyCreate, Status: "Success"},
+	})
+	clusters, err := store.GetAllClusterFromStore()
+	c.Assert(err, check.IsNil)
+	c.Assert(clusters, check.HasLen, 2)
+	c.Assert(clusters["apply-a"].Status, check.Equals, cluster.Running)
+	c.Assert(clusters["apply-b"].NodeCount, check.Equals, int64(2))
+
+	results, err = applyManifest(clusterManifest{
+		Clusters: []clusterSpec{mockSpec("apply-a", 5)},
+	}, applyOptions{prune: true})
+	c.Assert(err, check.IsNil)
+	c.Assert(results, check.DeepEquals, []applyResult{
+		{Name: "apply-a", Action: applyUpdate, Status: "Success"},
+		{Name: "apply-b", Action: applyRemove, Status: "Success"},
+	})
+	clusters, err = store.GetAllClusterFromStore()
+	c.Assert(err, check.IsNil)
+	c.Assert(clusters, check.HasLen, 1)
+	c.Assert(clusters["apply-a"].NodeCount, check.Equals, int64(5))
+}
+
+func (s *ApplyTestSuite) TestApplyReportsFailures(c *check.C) {
+	// stored locally but unknown to the driver, so the update fails
+	writeClusterConfig(c, "apply-stale", `{"driverName":"mock","name":"apply-stale","status":"Running"}`)
+	bad := mockSpec("apply-bad", 1)
+	bad.Options["unknown-option"] = "foo"
+
+	results, err := applyManifest(clusterManifest{
+		Clusters: []clusterSpec{
+			mockSpec("apply-stale", 2),
+			bad,
+			{Name: "apply-nodriver", Driver: "nope"},
+			mockSpec("apply-good", 1),
+		},
+	}, applyOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(results, check.HasLen, 4)
+	c.Assert(results[0].Action, check.Equals, applyUpdate)
+	c.Assert(results[0].Status, check.Equals, "Failed")
+	c.Assert(results[1].Error, check.Equals, "option unknown-option is not supported by driver mock")
+	c.Assert(results[2].Error, check.Equals, "driver nope is not supported")
+	c.Assert(results[3].Status, check.Equals, "Success")
+}
+
+func (s *ApplyTestSuite) TestReadManifest(c *check.C) {
+	file := filepath.Join(c.MkDir(), "manifest.yml")
+	c.Assert(ioutil.WriteFile(file, []byte(`
+clusters:
+- name: foo
+  driver: mock
+  options:
+    node-count: 3
+    enable-alpha-feature: true
+`), 0600), check.IsNil)
+	manifest, err := readManifest(file)
+	c.Assert(err, check.IsNil)
+	c.Assert(manifest.Clusters, check.HasLen, 1)
+
+	driverFlags, err := mock.NewDriver().GetDriverCreateOptions()
+	c.Assert(err, check.IsNil)
+	driverOptions, err := toDriverOptions(manifest.Clusters[0], *driverFlags, true)
+	c.Assert(err, check.IsNil)
+	c.Assert(driverOptions.IntOptions["node-count"], check.Equals, int64(3))
+	c.Assert(driverOptions.BoolOptions["enable-alpha-feature"], check.Equals, true)
+	c.Assert(driverOptions.StringOptions["name"], check.Equals, "foo")
+
+	c.Assert(ioutil.WriteFile(file, []byte(`{"clusters":[{"name":"foo","driver":"mock"},{"name":"foo","driver":"mock"}]}`), 0600), check.IsNil)
+	_, err = readManifest(file)
+	c.Assert(err, check.ErrorMatches, "cluster foo is declared more than once")
+}
+
+func (s *ApplyTestSuite) TestProviderMetadataPersisted(c *check.C) {
+	_, err := applyManifest(clusterManifest{
+		Clusters: []clusterSpec{mockSpec("provider-metadata", 1)},
+	}, applyOptions{})
+	c.Assert(err, check.IsNil)
+	expected := map[string]string{
+		"id":       "mock-provider-metadata",
+		"selfLink": "https://mock.local/clusters/provider-metadata",
+	}
+	cls, err := cliPersistStore{}.Get("provider-metadata")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.ProviderMetadata, check.DeepEquals, expected)
+
+	// an update doesn't lose them
+	_, err = applyManifest(clusterManifest{
+		Clusters: []clusterSpec{mockSpec("provider-metadata", 2)},
+	}, applyOptions{})
+	c.Assert(err, check.IsNil)
+	cls, err = cliPersistStore{}.Get("provider-metadata")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.NodeCount, check.Equals, int64(2))
+	c.Assert(cls.ProviderMetadata, check.DeepEquals, expected)
+}
+
+// writeManifests writes the manifests named after their keys into a new dir
+func writeManifests(c *check.C, manifests map[string]string) string {
+	dir := c.MkDir()
+	for name, content := range manifests {
+		c.Assert(ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600), check.IsNil)
+	}
+	return dir
+}
+
+func (s *ApplyTestSuite) TestExpandManifestFiles(c *check.C) {
+	dir := writeManifests(c, map[string]string{"b.yml": "", "a.yml": "", "c.json": ""})
+	files, err := expandManifestFiles([]string{filepath.Join(dir, "c.json"), filepath.Join(dir, "*.yml"), filepath.Join(dir, "a.yml")})
+	c.Assert(err, check.IsNil)
+	c.Assert(files, check.DeepEquals, []string{filepath.Join(dir, "a.yml"), filepath.Join(dir, "b.yml"), filepath.Join(dir, "c.json")})
+
+	_, err = expandManifestFiles([]string{filepath.Join(dir, "*.yaml")})
+	c.Assert(err, check.ErrorMatches, "no manifest matches .*")
+	// a path without a pattern is read as it is, so a missing file fails like a single manifest
+	files, err = expandManifestFiles([]string{filepath.Join(dir, "missing.yml")})
+	c.Assert(err, check.IsNil)
+	c.Assert(files, check.HasLen, 1)
+}
+
+func (s *ApplyTestSuite) TestApplyManifestFiles(c *check.C) {
+	dir := writeManifests(c, map[string]string{
+		"1-good.yml":   "clusters:\n- name: batch-a\n  driver: mock\n",
+		"2-bad.yml":    "clusters:\n- name: batch-b\n  driver: mock\n  options:\n    unknown-option: foo\n",
+		"3-good.yml":   `{"clusters":[{"name":"batch-c","driver":"mock","options":{"node-count":2}}]}`,
+		"4-broken.yml": "clusters: [",
+	})
+	files, err := expandManifestFiles([]string{filepath.Join(dir, "*.yml")})
+	c.Assert(err, check.IsNil)
+
+	manifest, readResults, err := readManifests(files, false)
+	c.Assert(err, check.IsNil)
+	c.Assert(readResults, check.HasLen, 1)
+	c.Assert(readResults[0].Name, check.Equals, filepath.Join(dir, "4-broken.yml"))
+	c.Assert(readResults[0].Action, check.Equals, applyRead)
+	c.Assert(readResults[0].Status, check.Equals, "Failed")
+	c.Assert(manifest.Clusters, check.HasLen, 3)
+
+	// the clusters after the failure are skipped with fail-fast
+	results, err := applyManifest(manifest, applyOptions{failFast: true})
+	c.Assert(err, check.IsNil)
+	c.Assert(results, check.DeepEquals, []applyResult{
+		{Name: "batch-a", Action: applyCreate, Status: "Success"},
+		{Name: "batch-b", Action: applyCreate, Status: "Failed", Error: "option unknown-option is not supported by driver mock"},
+		{Name: "batch-c", Status: "Skipped"},
+	})
+	_, err = cliPersistStore{}.Get("batch-c")
+	c.Assert(err, check.NotNil)
+
+	// and applied otherwise
+	results, err = applyManifest(manifest, applyOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(results, check.HasLen, 3)
+	c.Assert(results[0].Action, check.Equals, applyUpdate)
+	c.Assert(results[1].Status, check.Equals, "Failed")
+	c.Assert(results[2], check.DeepEquals, applyResult{Name: "batch-c", Action: applyCreate, Status: "Success"})
+
+	// fail-fast stops at the broken manifest before applying anything
+	_, _, err = readManifests(files, true)
+	c.Assert(err, check.ErrorMatches, "failed to parse manifest .*4-broken.yml: .*")
+}
+
+func (s *ApplyTestSuite) TestClusterInTwoManifests(c *check.C) {
+	dir := writeManifests(c, map[string]string{
+		"a.yml": "clusters:\n- name: twice\n  driver: mock\n",
+		"b.yml": "clusters:\n- name: twice\n  driver: mock\n",
+	})
+	_, _, err := readManifests([]string{filepath.Join(dir, "a.yml"), filepath.Join(dir, "b.yml")}, false)
+	c.Assert(err, check.ErrorMatches, "cluster twice is declared in .*a.yml and .*b.yml")
+}
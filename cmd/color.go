@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"github.com/rancher/kontainer-engine/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// SetColor colors the logs with mode: auto colors them when they are written to a terminal, always and never override
+// the detection. The tables check their own output with the same mode.
+func SetColor(mode string) error {
+	if err := utils.ValidateColorMode(mode); err != nil {
+		return newUsageError("%v", err)
+	}
+	colored := utils.UseColor(mode, logrus.StandardLogger().Out)
+	logrus.SetFormatter(&logrus.TextFormatter{
+		ForceColors:   colored,
+		DisableColors: !colored,
+	})
+	return nil
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	"github.com/rancher/kontainer-engine/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+// unsetValue is the value of a field one of the compared clusters doesn't have
+const unsetValue = "<unset>"
+
+// CompareCommand defines the compare command
+func CompareCommand() cli.Command {
+	return cli.Command{
+		Name:      "compare",
+		Usage:     "Print the driver options and the metadata two stored clusters differ in, the secrets are redacted",
+		ArgsUsage: "cluster-a cluster-b",
+		Action:    compareClusters,
+	}
+}
+
+// configDifference is a field the compared clusters have different values for
+type configDifference struct {
+	Field string `json:"field"`
+	A     string `json:"a"`
+	B     string `json:"b"`
+}
+
+func compareClusters(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		return newUsageError("compare takes the names of two clusters")
+	}
+	nameA, nameB := ctx.Args().Get(0), ctx.Args().Get(1)
+	clusters, err := getAllClusters()
+	if err != nil {
+		return err
+	}
+	for _, name := range []string{nameA, nameB} {
+		if _, ok := clusters[name]; !ok {
+			return newNotFoundError("cluster %v can't be found", name)
+		}
+	}
+	differences := diffClusterConfigs(clusters[nameA], clusters[nameB])
+	if len(differences) == 0 && ctx.GlobalString("output") != OutputJSON {
+		logrus.Infof("Clusters %s and %s have the same driver options and metadata", nameA, nameB)
+		return nil
+	}
+
+	writer := utils.NewTableWriter([][]string{
+		{"FIELD", "Field"},
+		{strings.ToUpper(nameA), "A"},
+		{strings.ToUpper(nameB), "B"},
+	}, ctx)
+	for _, difference := range differences {
+		writer.Write(difference)
+	}
+	return writer.Close()
+}
+
+// diffClusterConfigs returns the fields a and b differ in sorted by name: the driver, the driver options as options.NAME,
+// the node pools and the metadata as metadata.KEY. The secret options and metadata are reported redacted when they
+// differ.
+func diffClusterConfigs(a, b cluster.Cluster) []configDifference {
+	fieldsA, fieldsB := configFields(a), configFields(b)
+	names := []string{}
+	for name := range fieldsA {
+		names = append(names, name)
+	}
+	for name := range fieldsB {
+		if _, ok := fieldsA[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	// the options the driver declared secret when either cluster was created
+	declared := []string{}
+	for _, cls := range []cluster.Cluster{a, b} {
+		if cls.Options != nil {
+			declared = append(declared, cls.Options.SecretKeys...)
+		}
+	}
+	differences := []configDifference{}
+	for _, name := range names {
+		valueA, okA := fieldsA[name]
+		valueB, okB := fieldsB[name]
+		if okA && okB && valueA == valueB {
+			continue
+		}
+		differences = append(differences, configDifference{
+			Field: name,
+			A:     displayedValue(name, valueA, okA, declared),
+			B:     displayedValue(name, valueB, okB, declared),
+		})
+	}
+	return differences
+}
+
+// displayedValue is value of the field called name as printed, the secrets are compared but only their hint is printed
+func displayedValue(name, value string, ok bool, declared []string) string {
+	switch {
+	case !ok:
+		return unsetValue
+	case value != "" && isSecretOption(name[strings.Index(name, ".")+1:], declared...):
+		return redactValue(value)
+	}
+	return value
+}
+
+// configFields flattens the compared config of cls into its fields by name
+func configFields(cls cluster.Cluster) map[string]string {
+	fields := map[string]string{"driverName": cls.DriverName}
+	if cls.Options != nil {
+		options := cls.Options
+		for k, v := range options.StringOptions {
+			// the clusters always differ in their names
+			if k != "name" {
+				fields["options."+k] = v
+			}
+		}
+		for k, v := range options.IntOptions {
+			fields["options."+k] = strconv.FormatInt(v, 10)
+		}
+		for k, v := range options.BoolOptions {
+			fields["options."+k] = strconv.FormatBool(v)
+		}
+		for k, v := range options.StringSliceOptions {
+			if v != nil {
+				fields["options."+k] = strings.Join(v.Value, ",")
+			}
+		}
+		nodePools := []string{}
+		for _, nodePool := range options.NodePools {
+			nodePools = append(nodePools, formatNodePool(nodePool))
+		}
+		if len(nodePools) > 0 {
+			fields["nodePools"] = strings.Join(nodePools, " ")
+		}
+	}
+	for k, v := range cls.Metadata {
+		fields["metadata."+k] = v
+	}
+	return fields
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	"gopkg.in/check.v1"
+)
+
+type CompareTestSuite struct {
+	tempHomeSuite
+	oldArgs []string
+}
+
+var _ = check.Suite(&CompareTestSuite{})
+
+func (s *CompareTestSuite) SetUpTest(c *check.C) {
+	s.tempHomeSuite.SetUpTest(c)
+	s.oldArgs = os.Args
+}
+
+func (s *CompareTestSuite) TearDownTest(c *check.C) {
+	os.Args = s.oldArgs
+	s.tempHomeSuite.TearDownTest(c)
+}
+
+func (s *CompareTestSuite) TestDiffMockClusters(c *check.C) {
+	for _, args := range [][]string{
+		{"--node-count", "2", "--description", "first", "--labels", "env=dev", "one"},
+		{"--node-count", "3", "--description", "second", "--labels", "env=dev", "--enable-alpha-feature", "two"},
+	} {
+		os.Args = append([]string{"kontainer-engine", "create", "--driver", "mock"}, args...)
+		c.Assert(newTestApp().Run(os.Args), check.IsNil)
+	}
+	one, err := cliPersistStore{}.Get("one")
+	c.Assert(err, check.IsNil)
+	two, err := cliPersistStore{}.Get("two")
+	c.Assert(err, check.IsNil)
+
+	c.Assert(diffClusterConfigs(one, two), check.DeepEquals, []configDifference{
+		{Field: "metadata.description", A: "first", B: "second"},
+		{Field: "options.description", A: "first", B: "second"},
+		{Field: "options.enable-alpha-feature", A: "false", B: "true"},
+		{Field: "options.node-count", A: "2", B: "3"},
+	})
+	c.Assert(diffClusterConfigs(one, one), check.HasLen, 0)
+
+	// --output json prints every difference as an object
+	data, err := json.Marshal(diffClusterConfigs(one, two)[0])
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, `{"field":"metadata.description","a":"first","b":"second"}`)
+}
+
+func (s *CompareTestSuite) TestDiffRedactsSecrets(c *check.C) {
+	a := cluster.Cluster{DriverName: "mock", Metadata: map[string]string{"access-token": "a", "zone": "a"}}
+	b := cluster.Cluster{DriverName: "gke", Metadata: map[string]string{"access-token": "b"}}
+	c.Assert(diffClusterConfigs(a, b), check.DeepEquals, []configDifference{
+		{Field: "driverName", A: "mock", B: "gke"},
+		{Field: "metadata.access-token", A: "Redacted", B: "Redacted"},
+		{Field: "metadata.zone", A: "a", B: unsetValue},
+	})
+}
+
+func (s *CompareTestSuite) TestCompareCommand(c *check.C) {
+	c.Assert(cliPersistStore{}.Store(migratedCluster("one", "1.1.1.1")), check.IsNil)
+	app := newTestApp()
+	app.Commands = append(app.Commands, CompareCommand())
+
+	err := app.Run([]string{"kontainer-engine", "compare", "one"})
+	c.Assert(err, check.ErrorMatches, "compare takes the names of two clusters")
+	c.Assert(ExitCode(err), check.Equals, ExitUsage)
+	err = app.Run([]string{"kontainer-engine", "compare", "one", "missing"})
+	c.Assert(err, check.ErrorMatches, "cluster missing can't be found")
+	c.Assert(ExitCode(err), check.Equals, ExitNotFound)
+
+	c.Assert(cliPersistStore{}.Store(migratedCluster("two", "2.2.2.2")), check.IsNil)
+	c.Assert(app.Run([]string{"kontainer-engine", "--output", "json", "compare", "one", "two"}), check.IsNil)
+}
@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/rancher/kontainer-engine/utils"
+	"github.com/urfave/cli"
+)
+
+// ConfigCommand defines the config command
+func ConfigCommand() cli.Command {
+	return cli.Command{
+		Name:  "config",
+		Usage: "Inspect the configuration used to provision kubernetes clusters",
+		Subcommands: []cli.Command{
+			{
+				Name:            "resolve",
+				Usage:           "Print the driver options that create would send to the driver with the same flags, secrets are redacted",
+				ArgsUsage:       "--driver DRIVER [OPTIONS] cluster-name",
+				Action:          resolveConfig,
+				SkipFlagParsing: true,
+			},
+			{
+				Name:      "patch",
+				Usage:     "Apply a JSON patch, RFC 6902, to the stored config of a cluster, e.g. to fix a field no command changes",
+				ArgsUsage: "cluster-name patch.json",
+				Action:    patchConfig,
+				Flags: []cli.Flag{
+					noWaitFlag,
+				},
+			},
+		},
+	}
+}
+
+// immutableConfigFields are the fields of the stored config a patch can't change, by their json name
+var immutableConfigFields = []string{"name", "driverName", "schemaVersion"}
+
+func patchConfig(ctx *cli.Context) error {
+	name, file := ctx.Args().Get(0), ctx.Args().Get(1)
+	if name == "" || file == "" {
+		return usageErrorWithHelp(ctx, "patch", "cluster name and patch file are required")
+	}
+	patch, err := ioutil.ReadFile(file)
+	if err != nil {
+		return newUsageError("failed to read patch %s: %v", file, err)
+	}
+	if err := checkWritable(name); err != nil {
+		return err
+	}
+	lock, err := lockCluster(ctx, name, "config patch")
+	if err != nil {
+		return err
+	}
+	defer unlockCluster(name, lock)
+	persistStore := newPersistStore(kubeConfigOptions{})
+	if state, err := persistStore.Check(name); err != nil {
+		return err
+	} else if state == cluster.StateNotFound {
+		return newNotFoundError("cluster %v can't be found", name)
+	}
+	cls, err := persistStore.Get(name)
+	if err != nil {
+		return err
+	}
+	patched, err := patchCluster(cls, patch)
+	if err != nil {
+		return err
+	}
+	return persistStore.Store(patched)
+}
+
+// patchCluster applies the JSON patch to the config of cls and returns the patched cluster. The patched config must
+// still be a valid cluster config and keep the immutable fields.
+func patchCluster(cls cluster.Cluster, patch []byte) (cluster.Cluster, error) {
+	if err := cluster.StampSchemaVersion(&cls); err != nil {
+		return cls, err
+	}
+	data, err := json.Marshal(cls)
+	if err != nil {
+		return cls, err
+	}
+	patchedData, err := utils.ApplyJSONPatch(data, patch)
+	if err != nil {
+		return cls, newValidationError("failed to patch cluster %s: %v", cls.Name, err)
+	}
+	before, after := map[string]interface{}{}, map[string]interface{}{}
+	if err := json.Unmarshal(data, &before); err != nil {
+		return cls, err
+	}
+	if err := json.Unmarshal(patchedData, &after); err != nil {
+		return cls, newValidationError("the patched config of cluster %s isn't an object: %v", cls.Name, err)
+	}
+	for _, field := range immutableConfigFields {
+		if !reflect.DeepEqual(before[field], after[field]) {
+			return cls, newValidationError("the patch changes %s of cluster %s, it can't be changed", field, cls.Name)
+		}
+	}
+
+	patched := cluster.Cluster{}
+	if err := json.Unmarshal(patchedData, &patched); err != nil {
+		return cls, newValidationError("the patched config of cluster %s is invalid: %v", cls.Name, err)
+	}
+	if err := checkJSONFields(patchedData, &patched); err != nil {
+		return cls, newValidationError("the patched config of cluster %s is invalid: %v", cls.Name, err)
+	}
+	return patched, nil
+}
+
+// checkJSONFields returns an error for the first key of the json object data, or of the objects nested in it, that
+// isn't a field of v. It does what json.Decoder.DisallowUnknownFields does, which the go 1.9 build image doesn't have.
+func checkJSONFields(data []byte, v interface{}) error {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	object := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		// not an object, decoding it into v reports the error
+		return nil
+	}
+	keys := []string{}
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	fields := jsonFields(t)
+	for _, key := range keys {
+		field, ok := fields[strings.ToLower(key)]
+		if !ok {
+			return fmt.Errorf("json: unknown field %q", key)
+		}
+		if err := checkJSONFields(object[key], reflect.New(field).Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonFields returns the types of the fields of the struct t by their lower cased json names, the fields of the
+// embedded structs included
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" || (field.PkgPath != "" && !field.Anonymous) {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for k, v := range jsonFields(embedded) {
+					fields[k] = v
+				}
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[strings.ToLower(name)] = field.Type
+	}
+	return fields
+}
+
+func resolveConfig(ctx *cli.Context) error {
+	args := []string(ctx.Args())
+	if len(args) == 0 {
+		return usageErrorWithHelp(ctx, "resolve", "cluster name is required")
+	}
+	if args[0] == "--help" || args[0] == "-h" {
+		return cli.ShowCommandHelp(ctx, "resolve")
+	}
+	driverName := flagLookup(args, "--driver")
+	if file := flagLookup(args, "--"+fromCRDFlag.Name); driverName == "" && file != "" {
+		crdDriver, _, err := crdDriverAndName(file)
+		if err != nil {
+			return err
+		}
+		driverName = crdDriver
+	}
+	if driverName == "" {
+		persistStore := newPersistStore(kubeConfigOptions{})
+		// ignore the error as we only care if the cluster is present
+		cls, _ := persistStore.Get(args[len(args)-1])
+		if cls.DriverName == "" {
+			return newUsageError("driver name is required")
+		}
+		driverName = cls.DriverName
+	}
+	rpcClient, _, err := runRPCDriver(driverName)
+	if err != nil {
+		return err
+	}
+	driverFlags, err := rpcClient.GetDriverCreateOptions()
+	if err != nil {
+		return err
+	}
+	driverOptions, err := resolveDriverOptions(args, driverFlags)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(redactDriverOptions(driverOptions), "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(append(data, '\n'))
+	return err
+}
+
+// resolveDriverOptions parses args with the create flags and the driver create flags and returns the driver options
+// create would send, so the driver defaults, the create defaults and the flags take precedence the same way.
+func resolveDriverOptions(args []string, driverFlags rpcDriver.DriverFlags) (rpcDriver.DriverOptions, error) {
+	flags := append(CreateCommand().Flags, getDriverFlags(driverFlags)...)
+	set := flag.NewFlagSet("resolve", flag.ContinueOnError)
+	set.SetOutput(ioutil.Discard)
+	for _, f := range flags {
+		f.Apply(set)
+	}
+	if err := set.Parse(args); err != nil {
+		return rpcDriver.DriverOptions{}, err
+	}
+	if set.NArg() == 0 {
+		return rpcDriver.DriverOptions{}, newUsageError("cluster name is required")
+	}
+	ctx := cli.NewContext(nil, set, nil)
+	ctx.Command = cli.Command{Name: "create", Flags: flags}
+	return cliConfigGetter{name: set.Arg(set.NArg() - 1), ctx: ctx, driverFlags: driverFlags}.GetConfig()
+}
@@ -0,0 +1,263 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/rancher/kontainer-engine/driver/mock"
+	"gopkg.in/check.v1"
+)
+
+type ConfigTestSuite struct {
+}
+
+var _ = check.Suite(&ConfigTestSuite{})
+
+func mockCreateFlags(c *check.C) rpcDriver.DriverFlags {
+	driverFlags, err := mock.NewDriver().GetDriverCreateOptions()
+	c.Assert(err, check.IsNil)
+	return *driverFlags
+}
+
+func (s *ConfigTestSuite) TestResolveMergesAllSources(c *check.C) {
+	driverOptions, err := resolveDriverOptions([]string{
+		"--driver", "mock",
+		"--description", "from a flag",
+		"--labels", "a=b", "--labels", "c=d",
+		"--wait-timeout", "10m",
+		"--node-pool", "name=default,count=2",
+		"foo",
+	}, mockCreateFlags(c))
+	c.Assert(err, check.IsNil)
+
+	// the name is the last argument
+	c.Assert(driverOptions.StringOptions["name"], check.Equals, "foo")
+	// driver default
+	c.Assert(driverOptions.IntOptions["node-count"], check.Equals, int64(1))
+	// create default
+	c.Assert(driverOptions.StringOptions["kubeconfig-api-version"], check.Equals, defaultKubeConfigAPIVersion)
+	c.Assert(driverOptions.StringOptions["wait-initial-interval"], check.Equals, "5s")
+	// flags override the defaults
+	c.Assert(driverOptions.StringOptions["driver"], check.Equals, "mock")
+	c.Assert(driverOptions.StringOptions["description"], check.Equals, "from a flag")
+	c.Assert(driverOptions.StringOptions["wait-timeout"], check.Equals, "10m")
+	c.Assert(driverOptions.StringSliceOptions["labels"].Value, check.DeepEquals, []string{"a=b", "c=d"})
+	c.Assert(driverOptions.BoolOptions["enable-alpha-feature"], check.Equals, false)
+	c.Assert(driverOptions.NodePools, check.DeepEquals, []*rpcDriver.NodePool{{Name: "default", Count: 2}})
+}
+
+func (s *ConfigTestSuite) TestKubernetesVersionMapsToDriverOption(c *check.C) {
+	// a second driver naming its version option differently
+	renamed := mockCreateFlags(c)
+	renamed.Options["cluster-version"] = renamed.Options["version"]
+	delete(renamed.Options, "version")
+
+	for option, driverFlags := range map[string]rpcDriver.DriverFlags{
+		"version":         mockCreateFlags(c),
+		"cluster-version": renamed,
+	} {
+		driverOptions, err := resolveDriverOptions([]string{"--kubernetes-version", "1.9.2", "foo"}, driverFlags)
+		c.Assert(err, check.IsNil)
+		c.Assert(driverOptions.StringOptions[option], check.Equals, "1.9.2", check.Commentf("option %s", option))
+
+		// the same version given with the driver option is fine, a different one is an error
+		_, err = resolveDriverOptions([]string{"--kubernetes-version", "1.9.2", "--" + option, "1.9.2", "foo"}, driverFlags)
+		c.Assert(err, check.IsNil)
+		_, err = resolveDriverOptions([]string{"--kubernetes-version", "1.9.2", "--" + option, "1.8.4", "foo"}, driverFlags)
+		c.Assert(err, check.ErrorMatches, "--kubernetes-version and --"+option+" are set to different versions")
+		c.Assert(ExitCode(err), check.Equals, ExitUsage)
+	}
+}
+
+func (s *ConfigTestSuite) TestKubernetesVersionWithoutDriverOption(c *check.C) {
+	driverFlags := mockCreateFlags(c)
+	delete(driverFlags.Options, "version")
+	driverOptions, err := resolveDriverOptions([]string{"--kubernetes-version", "1.9.2", "foo"}, driverFlags)
+	c.Assert(err, check.IsNil)
+	c.Assert(driverOptions.StringOptions["kubernetes-version"], check.Equals, "1.9.2")
+	_, ok := driverOptions.StringOptions["version"]
+	c.Assert(ok, check.Equals, false)
+}
+
+func (s *ConfigTestSuite) TestResolveErrors(c *check.C) {
+	_, err := resolveDriverOptions([]string{"--driver", "mock"}, mockCreateFlags(c))
+	c.Assert(err, check.ErrorMatches, "cluster name is required")
+	_, err = resolveDriverOptions([]string{"--unknown", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.ErrorMatches, "flag provided but not defined: -unknown")
+	_, err = resolveDriverOptions([]string{"--node-pool", "name=default", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.ErrorMatches, `invalid node pool "name=default", count is required`)
+}
+
+func (s *ConfigTestSuite) TestRedactDriverOptions(c *check.C) {
+	driverOptions := newDriverOptions()
+	driverOptions.StringOptions["credential"] = "very secret"
+	driverOptions.StringOptions["client-key"] = "key"
+	driverOptions.StringOptions["password"] = ""
+	driverOptions.StringOptions["gke-credential-path"] = "/tmp/credential.json"
+	driverOptions.StringOptions["description"] = "public"
+	driverOptions.StringOptions["credential-profile"] = "prod"
+	driverOptions.StringSliceOptions["tokens"] = &rpcDriver.StringSlice{Value: []string{"a", "b"}}
+
+	redacted := redactDriverOptions(driverOptions)
+	c.Assert(redacted.StringOptions, check.DeepEquals, map[string]string{
+		"credential":          "Redacted",
+		"client-key":          "Redacted",
+		"password":            "",
+		"gke-credential-path": "/tmp/credential.json",
+		"description":         "public",
+		"credential-profile":  "prod",
+	})
+	c.Assert(redacted.StringSliceOptions["tokens"].Value, check.DeepEquals, []string{"Redacted", "Redacted"})
+	// the options passed in are left alone
+	c.Assert(driverOptions.StringOptions["credential"], check.Equals, "very secret")
+}
+
+func (s *ConfigTestSuite) TestDriverOptionEnv(c *check.C) {
+	defer SetDriverEnvPrefix(DefaultDriverEnvPrefix)
+	for name, env := range map[string]string{
+		"node-count":           "KE_DRIVER_NODE_COUNT",
+		"enable-alpha-feature": "KE_DRIVER_ENABLE_ALPHA_FEATURE",
+		"diskSizeGb":           "KE_DRIVER_DISKSIZEGB",
+		"cluster-ipv4-cidr":    "KE_DRIVER_CLUSTER_IPV4_CIDR",
+		"machine.type":         "KE_DRIVER_MACHINE_TYPE",
+	} {
+		c.Assert(driverOptionEnv(name), check.Equals, env)
+	}
+	SetDriverEnvPrefix("CI_")
+	c.Assert(driverOptionEnv("node-count"), check.Equals, "CI_NODE_COUNT")
+	SetDriverEnvPrefix("")
+	c.Assert(driverOptionEnv("node-count"), check.Equals, "")
+}
+
+func (s *ConfigTestSuite) TestDriverOptionsFromEnv(c *check.C) {
+	for k, v := range map[string]string{
+		"KE_DRIVER_NODE_COUNT":           "3",
+		"KE_DRIVER_DESCRIPTION":          "from the environment",
+		"KE_DRIVER_LABELS":               "a=b,c=d",
+		"KE_DRIVER_ENABLE_ALPHA_FEATURE": "true",
+	} {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	driverOptions, err := resolveDriverOptions([]string{"foo"}, mockCreateFlags(c))
+	c.Assert(err, check.IsNil)
+	c.Assert(driverOptions.IntOptions["node-count"], check.Equals, int64(3))
+	c.Assert(driverOptions.StringOptions["description"], check.Equals, "from the environment")
+	c.Assert(driverOptions.StringSliceOptions["labels"].Value, check.DeepEquals, []string{"a=b", "c=d"})
+	c.Assert(driverOptions.BoolOptions["enable-alpha-feature"], check.Equals, true)
+
+	// a flag set explicitly wins over the environment
+	driverOptions, err = resolveDriverOptions([]string{"--node-count", "5", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.IsNil)
+	c.Assert(driverOptions.IntOptions["node-count"], check.Equals, int64(5))
+
+	// the environment is ignored without prefix
+	SetDriverEnvPrefix("")
+	defer SetDriverEnvPrefix(DefaultDriverEnvPrefix)
+	driverOptions, err = resolveDriverOptions([]string{"foo"}, mockCreateFlags(c))
+	c.Assert(err, check.IsNil)
+	c.Assert(driverOptions.IntOptions["node-count"], check.Equals, int64(1))
+}
+
+func (s *ConfigTestSuite) TestSetKeys(c *check.C) {
+	// one flag of every type is set, the others take their defaults
+	driverOptions, err := resolveDriverOptions([]string{
+		"--description", "set",
+		"--node-count", "1",
+		"--labels", "a=b",
+		"--enable-alpha-feature",
+		"--node-pool", "name=default,count=2",
+		"foo",
+	}, mockCreateFlags(c))
+	c.Assert(err, check.IsNil)
+	for _, name := range []string{"description", "node-count", "labels", "enable-alpha-feature", "node-pool"} {
+		c.Assert(driverOptions.IsSet(name), check.Equals, true, check.Commentf("option %s", name))
+	}
+	// a value equal to the default is still set, the defaulted options of every type aren't
+	c.Assert(driverOptions.IntOptions["node-count"], check.Equals, int64(1))
+	for _, name := range []string{"kubeconfig-api-version", "wait-timeout", "credential", "kubeconfig-extension", "no-store"} {
+		c.Assert(driverOptions.IsSet(name), check.Equals, false, check.Commentf("option %s", name))
+	}
+	// name isn't a flag
+	c.Assert(driverOptions.IsSet("name"), check.Equals, false)
+}
+
+func (s *ConfigTestSuite) TestSetKeysFromEnv(c *check.C) {
+	os.Setenv("KE_DRIVER_DESCRIPTION", "from the environment")
+	defer os.Unsetenv("KE_DRIVER_DESCRIPTION")
+	driverOptions, err := resolveDriverOptions([]string{"--kubernetes-version", "1.9.2", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.IsNil)
+	c.Assert(driverOptions.IsSet("description"), check.Equals, true)
+	c.Assert(driverOptions.IsSet("node-count"), check.Equals, false)
+	// the driver option mapped from --kubernetes-version is set too
+	c.Assert(driverOptions.IsSet("version"), check.Equals, true)
+}
+
+type ConfigPatchTestSuite struct {
+	tempHomeSuite
+}
+
+var _ = check.Suite(&ConfigPatchTestSuite{})
+
+func (s *ConfigPatchTestSuite) writePatch(c *check.C, patch string) string {
+	path := filepath.Join(c.MkDir(), "patch.json")
+	c.Assert(ioutil.WriteFile(path, []byte(patch), 0600), check.IsNil)
+	return path
+}
+
+func (s *ConfigPatchTestSuite) run(args ...string) error {
+	app := newTestApp()
+	app.Commands = append(app.Commands, ConfigCommand())
+	return app.Run(append([]string{"kontainer-engine", "config", "patch"}, args...))
+}
+
+func (s *ConfigPatchTestSuite) TestPatch(c *check.C) {
+	cls := migratedCluster("foo", "1.1.1.1")
+	cls.Metadata = map[string]string{"zone": "us-central1-a", "project": "old"}
+	c.Assert(cliPersistStore{}.Store(cls), check.IsNil)
+
+	c.Assert(s.run("foo", s.writePatch(c, `[
+		{"op": "add", "path": "/metadata/region", "value": "us-central1"},
+		{"op": "replace", "path": "/endpoint", "value": "2.2.2.2"},
+		{"op": "remove", "path": "/metadata/project"}
+	]`)), check.IsNil)
+	patched, err := cliPersistStore{}.Get("foo")
+	c.Assert(err, check.IsNil)
+	c.Assert(patched.Endpoint, check.Equals, "2.2.2.2")
+	c.Assert(patched.Metadata, check.DeepEquals, map[string]string{"zone": "us-central1-a", "region": "us-central1"})
+	c.Assert(patched.ServiceAccountToken, check.Equals, "foo-token")
+	c.Assert(patched.Status, check.Equals, cluster.Running)
+}
+
+func (s *ConfigPatchTestSuite) TestInvalidPatches(c *check.C) {
+	c.Assert(cliPersistStore{}.Store(migratedCluster("foo", "1.1.1.1")), check.IsNil)
+	for patch, message := range map[string]string{
+		`[{"op": "replace", "path": "/name", "value": "bar"}]`:          "the patch changes name of cluster foo, it can't be changed",
+		`[{"op": "remove", "path": "/driverName"}]`:                     "the patch changes driverName of cluster foo, it can't be changed",
+		`[{"op": "add", "path": "/nodeCount", "value": "three"}]`:       "the patched config of cluster foo is invalid: .*",
+		`[{"op": "add", "path": "/nodes", "value": 3}]`:                 `the patched config of cluster foo is invalid: json: unknown field "nodes"`,
+		`[{"op": "add", "path": "/options", "value": {"intOpts": {}}}]`: `the patched config of cluster foo is invalid: json: unknown field "intOpts"`,
+		`[{"op": "remove", "path": "/missing"}]`:                        "failed to patch cluster foo: operation 0 of the JSON patch: missing doesn't exist",
+	} {
+		err := s.run("foo", s.writePatch(c, patch))
+		c.Assert(err, check.ErrorMatches, message, check.Commentf("%s", patch))
+		c.Assert(ExitCode(err), check.Equals, ExitValidation)
+	}
+	cls, err := cliPersistStore{}.Get("foo")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls, check.DeepEquals, func() cluster.Cluster {
+		expected := migratedCluster("foo", "1.1.1.1")
+		expected.SchemaVersion = cluster.CurrentSchemaVersion
+		return expected
+	}())
+
+	err = s.run("missing", s.writePatch(c, `[]`))
+	c.Assert(err, check.ErrorMatches, "cluster missing can't be found")
+	c.Assert(ExitCode(err), check.Equals, ExitNotFound)
+	err = s.run("foo")
+	c.Assert(ExitCode(err), check.Equals, ExitUsage)
+}
@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/ghodss/yaml"
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/rancher/types/apis/management.cattle.io/v3"
+	"github.com/urfave/cli"
+	yamlv2 "gopkg.in/yaml.v2"
+)
+
+const (
+	crdAPIVersion = "management.cattle.io/v3"
+	crdKind       = "Cluster"
+)
+
+var fromCRDFlag = cli.StringFlag{
+	Name:  "from-crd",
+	Usage: "Read the driver options from a management.cattle.io/v3 Cluster document, or its spec alone, in YAML or JSON. The flags that are set override them",
+}
+
+// crdDocument is a management.cattle.io/v3 Cluster as the controllers describe it, only its name and spec are read
+type crdDocument struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+	Metadata   struct {
+		Name string `json:"name,omitempty"`
+	} `json:"metadata,omitempty"`
+	Spec v3.ClusterSpec `json:"spec,omitempty"`
+}
+
+// loadCRD reads the document of file, a Cluster document or the ClusterSpec stub.Create is given
+func loadCRD(file string) (crdDocument, error) {
+	doc := crdDocument{}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return doc, newUsageError("failed to read cluster document %s: %v", file, err)
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return doc, newValidationError("cluster document %s is invalid: %v", file, err)
+	}
+	if doc.APIVersion == "" && doc.Kind == "" && !hasDriverConfig(doc.Spec) {
+		if err := yaml.Unmarshal(data, &doc.Spec); err != nil {
+			return doc, newValidationError("cluster document %s is invalid: %v", file, err)
+		}
+	}
+	if doc.APIVersion != "" && doc.APIVersion != crdAPIVersion {
+		return doc, newValidationError("cluster document %s has apiVersion %s, only %s is supported", file, doc.APIVersion, crdAPIVersion)
+	}
+	if doc.Kind != "" && doc.Kind != crdKind {
+		return doc, newValidationError("cluster document %s is a %s, not a %s", file, doc.Kind, crdKind)
+	}
+	return doc, nil
+}
+
+// hasDriverConfig reports whether spec has the config of any driver
+func hasDriverConfig(spec v3.ClusterSpec) bool {
+	return spec.GoogleKubernetesEngineConfig != nil || spec.AzureKubernetesServiceConfig != nil || spec.RancherKubernetesEngineConfig != nil
+}
+
+// crdDriverName returns the driver whose config the spec has, the way stub.Create picks it
+func crdDriverName(spec v3.ClusterSpec) (string, error) {
+	drivers := []string{}
+	if spec.AzureKubernetesServiceConfig != nil {
+		drivers = append(drivers, "aks")
+	}
+	if spec.GoogleKubernetesEngineConfig != nil {
+		drivers = append(drivers, "gke")
+	}
+	if spec.RancherKubernetesEngineConfig != nil {
+		drivers = append(drivers, "rke")
+	}
+	switch len(drivers) {
+	case 0:
+		return "", newValidationError("the cluster spec has no driver config")
+	case 1:
+		return drivers[0], nil
+	}
+	return "", newValidationError("the cluster spec has the config of the drivers %v, it must have only one", drivers)
+}
+
+// crdDriverOptions converts the config of driverName in spec into the driver options the create flags of the driver
+// would set. Only the fields the spec sets are converted, SetKeys lists them.
+func crdDriverOptions(spec v3.ClusterSpec, driverName string) (rpcDriver.DriverOptions, error) {
+	driverOptions := newDriverOptions()
+	switch driverName {
+	case "gke":
+		config := spec.GoogleKubernetesEngineConfig
+		if config == nil {
+			break
+		}
+		for name, value := range map[string]string{
+			"project-id":               config.ProjectID,
+			"zone":                     config.Zone,
+			"cluster-ipv4-cidr":        config.ClusterIpv4Cidr,
+			"description":              config.Description,
+			"machine-type":             config.MachineType,
+			"node-version":             config.NodeVersion,
+			"master-version":           config.MasterVersion,
+			rpcDriver.CredentialOption: config.Credential,
+			"imageType":                config.ImageType,
+			"gke-network":              config.Network,
+			"gke-subnetwork":           config.SubNetwork,
+		} {
+			if value != "" {
+				driverOptions.StringOptions[name] = value
+			}
+		}
+		for name, value := range map[string]int64{
+			"node-count":   config.NodeCount,
+			"disk-size-gb": config.DiskSizeGb,
+		} {
+			if value != 0 {
+				driverOptions.IntOptions[name] = value
+			}
+		}
+		for name, value := range map[string]bool{
+			"enable-alpha-feature":       config.EnableAlphaFeature,
+			"http-load-balancing":        config.HTTPLoadBalancing,
+			"horizontal-pod-autoscaling": config.HorizontalPodAutoscaling,
+			"kubernetes-dashboard":       config.KubernetesDashboard,
+			"network-policy-config":      config.NetworkPolicyConfig,
+			"legacyAbac":                 config.LegacyAbac,
+		} {
+			if value {
+				driverOptions.BoolOptions[name] = value
+			}
+		}
+		if len(config.Labels) > 0 {
+			labels := []string{}
+			for k, v := range config.Labels {
+				labels = append(labels, fmt.Sprintf("%s=%s", k, v))
+			}
+			sort.Strings(labels)
+			driverOptions.StringSliceOptions["labels"] = &rpcDriver.StringSlice{Value: labels}
+		}
+		if len(config.Locations) > 0 {
+			driverOptions.StringSliceOptions["locations"] = &rpcDriver.StringSlice{Value: config.Locations}
+		}
+	case "rke":
+		if spec.RancherKubernetesEngineConfig == nil {
+			break
+		}
+		config, err := yamlv2.Marshal(spec.RancherKubernetesEngineConfig)
+		if err != nil {
+			return driverOptions, err
+		}
+		driverOptions.StringOptions["rkeConfig"] = string(config)
+	default:
+		return driverOptions, newValidationError("driver %s can't be configured with a cluster document", driverName)
+	}
+	driverOptions.SetKeys = driverOptionsKeys(driverOptions)
+	if len(driverOptions.SetKeys) == 0 {
+		return driverOptions, newValidationError("the cluster spec has no config for driver %s", driverName)
+	}
+	return driverOptions, nil
+}
+
+// driverOptionsKeys returns the sorted names of all the options of driverOptions
+func driverOptionsKeys(driverOptions rpcDriver.DriverOptions) []string {
+	keys := []string{}
+	for k := range driverOptions.StringOptions {
+		keys = append(keys, k)
+	}
+	for k := range driverOptions.IntOptions {
+		keys = append(keys, k)
+	}
+	for k := range driverOptions.BoolOptions {
+		keys = append(keys, k)
+	}
+	for k := range driverOptions.StringSliceOptions {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// crdOptions returns the driver options of the --from-crd document in ctx for the --driver driver, or the driver of
+// the document if --driver isn't set. It returns nil if the flag isn't set.
+func crdOptions(ctx *cli.Context) (*rpcDriver.DriverOptions, error) {
+	file := ctx.String(fromCRDFlag.Name)
+	if file == "" {
+		return nil, nil
+	}
+	if ctx.String(fromTemplateFlag.Name) != "" {
+		return nil, newUsageError("--%s and --%s can't be used together", fromCRDFlag.Name, fromTemplateFlag.Name)
+	}
+	doc, err := loadCRD(file)
+	if err != nil {
+		return nil, err
+	}
+	driverName := ctx.String("driver")
+	if driverName == "" {
+		if driverName, err = crdDriverName(doc.Spec); err != nil {
+			return nil, err
+		}
+	}
+	driverOptions, err := crdDriverOptions(doc.Spec, driverName)
+	if err != nil {
+		return nil, err
+	}
+	return &driverOptions, nil
+}
+
+// crdDriverAndName returns the driver and the cluster name of the document of file, create uses them when neither
+// --driver nor a cluster name is given
+func crdDriverAndName(file string) (string, string, error) {
+	doc, err := loadCRD(file)
+	if err != nil {
+		return "", "", err
+	}
+	driverName, err := crdDriverName(doc.Spec)
+	return driverName, doc.Metadata.Name, err
+}
+
+// applyCRD sets the options of the cluster document that the command line or the environment doesn't set. They are
+// kept in SetKeys, as if they were given with the flags, so --as-command recreates them.
+func applyCRD(crd *rpcDriver.DriverOptions, driverOpts rpcDriver.DriverOptions) rpcDriver.DriverOptions {
+	merged := rpcDriver.CopyDriverOptions(&driverOpts)
+	for k, v := range crd.StringOptions {
+		merged.StringOptions[k] = v
+	}
+	for k, v := range crd.IntOptions {
+		merged.IntOptions[k] = v
+	}
+	for k, v := range crd.BoolOptions {
+		merged.BoolOptions[k] = v
+	}
+	for k, v := range crd.StringSliceOptions {
+		merged.StringSliceOptions[k] = v
+	}
+	overlaySetOptions(merged, driverOpts)
+	merged.SetKeys = append([]string(nil), driverOpts.SetKeys...)
+	for _, k := range crd.SetKeys {
+		if !merged.IsSet(k) {
+			merged.SetKeys = append(merged.SetKeys, k)
+		}
+	}
+	return *merged
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/rancher/kontainer-engine/driver/gke"
+	"gopkg.in/check.v1"
+)
+
+type CRDTestSuite struct{}
+
+var _ = check.Suite(&CRDTestSuite{})
+
+const gkeCRD = `apiVersion: management.cattle.io/v3
+kind: Cluster
+metadata:
+  name: from-crd
+spec:
+  googleKubernetesEngineConfig:
+    projectId: my-project
+    zone: us-central1-a
+    nodeCount: 3
+    diskSizeGb: 50
+    machineType: n1-standard-2
+    masterVersion: 1.9.7-gke.3
+    labels:
+      team: infra
+      env: prod
+    locations:
+    - us-central1-a
+    - us-central1-b
+    httpLoadBalancing: true
+    network: default
+`
+
+// writeCRD writes data to a file in a temporary directory and returns its path
+func writeCRD(c *check.C, data string) string {
+	file := filepath.Join(c.MkDir(), "cluster.yml")
+	c.Assert(ioutil.WriteFile(file, []byte(data), 0600), check.IsNil)
+	return file
+}
+
+func (s *CRDTestSuite) TestGKEDriverOptions(c *check.C) {
+	doc, err := loadCRD(writeCRD(c, gkeCRD))
+	c.Assert(err, check.IsNil)
+	c.Assert(doc.Metadata.Name, check.Equals, "from-crd")
+	driverName, err := crdDriverName(doc.Spec)
+	c.Assert(err, check.IsNil)
+	c.Assert(driverName, check.Equals, "gke")
+
+	driverOptions, err := crdDriverOptions(doc.Spec, driverName)
+	c.Assert(err, check.IsNil)
+	c.Assert(driverOptions, check.DeepEquals, rpcDriver.DriverOptions{
+		StringOptions: map[string]string{
+			"project-id":     "my-project",
+			"zone":           "us-central1-a",
+			"machine-type":   "n1-standard-2",
+			"master-version": "1.9.7-gke.3",
+			"gke-network":    "default",
+		},
+		IntOptions:  map[string]int64{"node-count": 3, "disk-size-gb": 50},
+		BoolOptions: map[string]bool{"http-load-balancing": true},
+		StringSliceOptions: map[string]*rpcDriver.StringSlice{
+			"labels":    {Value: []string{"env=prod", "team=infra"}},
+			"locations": {Value: []string{"us-central1-a", "us-central1-b"}},
+		},
+		SetKeys: []string{"disk-size-gb", "gke-network", "http-load-balancing", "labels", "locations", "machine-type",
+			"master-version", "node-count", "project-id", "zone"},
+	})
+}
+
+func (s *CRDTestSuite) TestSpecOnly(c *check.C) {
+	doc, err := loadCRD(writeCRD(c, "googleKubernetesEngineConfig:\n  projectId: my-project\n"))
+	c.Assert(err, check.IsNil)
+	c.Assert(doc.Spec.GoogleKubernetesEngineConfig, check.NotNil)
+	c.Assert(doc.Spec.GoogleKubernetesEngineConfig.ProjectID, check.Equals, "my-project")
+}
+
+func (s *CRDTestSuite) TestFlagsOverride(c *check.C) {
+	gkeFlags, err := gke.NewDriver().GetDriverCreateOptions()
+	c.Assert(err, check.IsNil)
+	file := writeCRD(c, gkeCRD)
+	driverOptions, err := resolveDriverOptions([]string{"--from-crd", file, "--node-count", "5", "foo"}, *gkeFlags)
+	c.Assert(err, check.IsNil)
+	c.Assert(driverOptions.IntOptions["node-count"], check.Equals, int64(5))
+	c.Assert(driverOptions.IntOptions["disk-size-gb"], check.Equals, int64(50))
+	c.Assert(driverOptions.StringOptions["project-id"], check.Equals, "my-project")
+	c.Assert(driverOptions.StringOptions["name"], check.Equals, "foo")
+	c.Assert(driverOptions.IsSet("node-count"), check.Equals, true)
+	c.Assert(driverOptions.IsSet("zone"), check.Equals, true)
+	// the defaults of the options the document doesn't set are kept
+	c.Assert(driverOptions.StringOptions["description"], check.Equals, gkeFlags.Options["description"].Value)
+
+	// the path of the document isn't part of the spec, only the options read from it are
+	moved, err := resolveDriverOptions([]string{"--from-crd", writeCRD(c, gkeCRD), "--node-count", "5", "foo"}, *gkeFlags)
+	c.Assert(err, check.IsNil)
+	hash, err := cluster.SpecHash(driverOptions, volatileOptions...)
+	c.Assert(err, check.IsNil)
+	movedHash, err := cluster.SpecHash(moved, volatileOptions...)
+	c.Assert(err, check.IsNil)
+	c.Assert(movedHash, check.Equals, hash)
+
+	_, err = resolveDriverOptions([]string{"--from-crd", file, "--from-template", "bar", "foo"}, *gkeFlags)
+	c.Assert(err, check.ErrorMatches, "--from-crd and --from-template can't be used together")
+	c.Assert(ExitCode(err), check.Equals, ExitUsage)
+}
+
+func (s *CRDTestSuite) TestInvalidDocuments(c *check.C) {
+	for data, expected := range map[string]string{
+		"apiVersion: v1\nkind: Cluster\n":                         "cluster document .* has apiVersion v1, only management.cattle.io/v3 is supported",
+		"kind: Node\nspec:\n  googleKubernetesEngineConfig: {}\n": "cluster document .* is a Node, not a Cluster",
+		"kind: Cluster\nspec: [1]\n":                              "cluster document .* is invalid: .*",
+	} {
+		_, err := loadCRD(writeCRD(c, data))
+		c.Assert(err, check.ErrorMatches, expected)
+		c.Assert(ExitCode(err), check.Equals, ExitValidation)
+	}
+
+	doc, err := loadCRD(writeCRD(c, "kind: Cluster\nspec:\n  description: no driver\n"))
+	c.Assert(err, check.IsNil)
+	_, err = crdDriverName(doc.Spec)
+	c.Assert(err, check.ErrorMatches, "the cluster spec has no driver config")
+
+	doc, err = loadCRD(writeCRD(c, gkeCRD))
+	c.Assert(err, check.IsNil)
+	_, err = crdDriverOptions(doc.Spec, "rke")
+	c.Assert(err, check.ErrorMatches, "the cluster spec has no config for driver rke")
+	_, err = crdDriverOptions(doc.Spec, "mock")
+	c.Assert(err, check.ErrorMatches, "driver mock can't be configured with a cluster document")
+}
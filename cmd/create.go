@@ -1,20 +1,23 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"os"
 	"strings"
 
 	"path/filepath"
+	"sort"
 
-	"io/ioutil"
 	"strconv"
 
 	"fmt"
 
 	"github.com/rancher/kontainer-engine/cluster"
 	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/rancher/kontainer-engine/plugin"
 	"github.com/rancher/kontainer-engine/utils"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
@@ -25,8 +28,15 @@ const (
 	clientKey         = "key.pem"
 	clientCert        = "cert.pem"
 	defaultConfigName = "config.json"
+	endpointFile      = "endpoint"
+	tokenFile         = "token"
 )
 
+var kubernetesVersionFlag = cli.StringFlag{
+	Name:  "kubernetes-version",
+	Usage: "The kubernetes version of the cluster, passed to the version option of the driver whatever its name",
+}
+
 // CreateCommand defines the create command
 func CreateCommand() cli.Command {
 	return cli.Command{
@@ -34,12 +44,67 @@ func CreateCommand() cli.Command {
 		Usage:           "Create a kubernetes cluster",
 		Action:          createWapper,
 		SkipFlagParsing: true,
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			cli.StringFlag{
 				Name:  "driver",
 				Usage: "Driver to create kubernetes clusters",
 			},
-		},
+			cli.StringFlag{
+				Name:  "write-credentials",
+				Usage: "Write the endpoint, token and certificates of the created cluster to files under this directory",
+			},
+			cli.StringFlag{
+				Name:  "endpoint-type",
+				Usage: "The API endpoint written to kubeconfig, public or private. Defaults to the endpoint reported by the driver",
+			},
+			nodePoolFlag,
+			nodeLabelFlag,
+			nodeTaintFlag,
+			kubernetesVersionFlag,
+			networkFlag,
+			subnetFlag,
+			maintenanceWindowFlag,
+			maintenanceExclusionFlag,
+			oidcIssuerFlag,
+			oidcClientIDFlag,
+			oidcUsernameClaimFlag,
+			oidcGroupsClaimFlag,
+			apiAudienceFlag,
+			addonFlag,
+			featureFlag,
+			driverArgFlag,
+			cli.StringFlag{
+				Name:  "kubeconfig-api-version",
+				Usage: "The apiVersion of the generated kubeconfig",
+				Value: defaultKubeConfigAPIVersion,
+			},
+			noKubeConfigFlag,
+			kubeConfigOutFlag,
+			cli.StringSliceFlag{
+				Name:  "kubeconfig-extension",
+				Usage: "An extension added to the cluster entry of kubeconfig as NAME=JSON, e.g. 'kontainer-engine={\"uid\":\"1234\"}'",
+			},
+			postCreateHookFlag,
+			hookRequiredFlag,
+			credentialProfileFlag,
+			credentialProviderFlag,
+			vaultAddrFlag,
+			vaultPathFlag,
+			vaultKeyFlag,
+			credentialStdinFlag,
+			impersonateServiceAccountFlag,
+			impersonateUserFlag,
+			fromTemplateFlag,
+			fromCRDFlag,
+			verifyConnectivityFlag,
+			noStoreFlag,
+			clusterConfigFlag,
+			interactiveFlag,
+			generateNameFlag,
+			noWaitFlag,
+			debugDumpFlag,
+			debugDumpRedactFlag,
+		}, waitFlags...),
 	}
 }
 
@@ -50,54 +115,77 @@ func createWapper(ctx *cli.Context) error {
 	}
 
 	driverName := flagHackLookup("--driver")
+	if templateName := flagHackLookup("--" + fromTemplateFlag.Name); driverName == "" && templateName != "" {
+		template, err := getTemplateCluster(newPersistStore(kubeConfigOptions{}), templateName)
+		if err != nil {
+			return err
+		}
+		driverName = template.DriverName
+	}
+	if file := flagHackLookup("--" + fromCRDFlag.Name); driverName == "" && file != "" {
+		crdDriver, _, err := crdDriverAndName(file)
+		if err != nil {
+			return err
+		}
+		driverName = crdDriver
+	}
 	if driverName == "" {
-		persistStore := cliPersistStore{}
+		persistStore := newPersistStore(kubeConfigOptions{})
 		// ingore the error as we only care if cluster.name is present
-		cls, _ := persistStore.Get(os.Args[len(os.Args)-1])
+		cls, _ := persistStore.Get(trailingClusterName(os.Args, CreateCommand().Flags))
 		if cls.DriverName != "" {
 			driverName = cls.DriverName
 		} else {
-			logrus.Error("Driver name is required")
-			return cli.ShowCommandHelp(ctx, "create")
+			return usageErrorWithHelp(ctx, "create", "driver name is required")
 		}
 	}
-	rpcClient, addr, err := runRPCDriver(driverName)
-	if err != nil {
-		return err
+	if !plugin.BuiltInDrivers[driverName] {
+		return usageErrorWithHelp(ctx, "create", fmt.Sprintf("driver %s is not supported, 'kontainer-engine drivers' lists the supported drivers", driverName))
 	}
-	driverFlags, err := rpcClient.GetDriverCreateOptions()
+	driverFlags, addr, err := discoverCreateOptions(driverName)
 	if err != nil {
-		return err
+		return driverDiscoveryError(ctx, "create", driverName, err)
 	}
-	flags := getDriverFlags(driverFlags)
-	for i, command := range ctx.App.Commands {
-		if command.Name == "create" {
-			createCmd := &ctx.App.Commands[i]
-			createCmd.SkipFlagParsing = false
-			createCmd.Flags = append(createCmd.Flags, flags...)
-			createCmd.Action = create
+	if helpRequested() && ctx.GlobalString("output") == OutputJSON {
+		return writeCreateHelp(os.Stdout, driverName, driverFlags)
+	}
+	if !helpRequested() && interactiveRequested() {
+		if os.Args, err = interactiveArgs(newPrompter(), os.Args, driverFlags); err != nil {
+			return err
 		}
 	}
-	// append plugin addr if it is built-in driver
-	if len(os.Args) > 1 && addr != "" {
-		args := []string{os.Args[0], "--plugin-listen-addr", addr}
-		args = append(args, os.Args[1:len(os.Args)]...)
-		return ctx.App.Run(args)
+	return rerunWithDriverFlags(ctx, "create", getDriverFlags(driverFlags), func(ctx *cli.Context) error {
+		return create(ctx, driverFlags)
+	}, addr)
+}
+
+// discoverCreateOptions runs the driver and returns its create flags with the address of its rpc server, tests replace it
+// to fail the discovery
+var discoverCreateOptions = func(driverName string) (rpcDriver.DriverFlags, string, error) {
+	rpcClient, addr, err := runRPCDriver(driverName)
+	if err != nil {
+		return rpcDriver.DriverFlags{}, "", err
 	}
-	return ctx.App.Run(os.Args)
+	driverFlags, err := rpcClient.GetDriverCreateOptions()
+	return driverFlags, addr, err
 }
 
 func flagHackLookup(flagName string) string {
+	return flagLookup(os.Args, flagName)
+}
+
+// flagLookup returns the value of flagName in args before they are parsed
+func flagLookup(args []string, flagName string) string {
 	// e.g. "-d" for "--driver"
 	flagPrefix := flagName[1:3]
 
 	// TODO: Should we support -flag-name (single hyphen) syntax as well?
-	for i, arg := range os.Args {
+	for i, arg := range args {
 		if strings.Contains(arg, flagPrefix) {
 			// format '--driver foo' or '-d foo'
 			if arg == flagPrefix || arg == flagName {
-				if i+1 < len(os.Args) {
-					return os.Args[i+1]
+				if i+1 < len(args) {
+					return args[i+1]
 				}
 			}
 
@@ -111,36 +199,198 @@ func flagHackLookup(flagName string) string {
 	return ""
 }
 
+// trailingClusterName returns the last of args as the cluster name before the args are parsed, empty if it is a flag or
+// the value of one of flags
+func trailingClusterName(args []string, flags []cli.Flag) string {
+	if len(args) == 0 {
+		return ""
+	}
+	last := args[len(args)-1]
+	if strings.HasPrefix(last, "-") {
+		return ""
+	}
+	if len(args) > 1 {
+		if flag := lookupFlag(flags, args[len(args)-2]); flag != nil && takesValue(flag) {
+			return ""
+		}
+	}
+	return last
+}
+
+// sliceFlagValueLast returns the name of the string slice flag of flags whose value is the last of args, empty if the
+// last argument isn't the value of such a flag. The value is then either taken for the cluster name or the flag, after
+// the cluster name, is ignored.
+func sliceFlagValueLast(args []string, flags []cli.Flag) string {
+	if len(args) < 2 || strings.HasPrefix(args[len(args)-1], "-") {
+		return ""
+	}
+	flag := lookupFlag(flags, args[len(args)-2])
+	if flag == nil {
+		return ""
+	}
+	if _, ok := flag.(cli.StringSliceFlag); !ok {
+		return ""
+	}
+	return flagNames(flag)[0]
+}
+
+// lookupFlag returns the flag of flags called by arg, e.g. --file or -f, nil if there is none
+func lookupFlag(flags []cli.Flag, arg string) cli.Flag {
+	if !strings.HasPrefix(arg, "-") || strings.Contains(arg, "=") {
+		return nil
+	}
+	name := strings.TrimLeft(arg, "-")
+	for _, flag := range flags {
+		for _, n := range flagNames(flag) {
+			if n == name {
+				return flag
+			}
+		}
+	}
+	return nil
+}
+
+// flagNames returns the name and the aliases of flag, e.g. file and f for "file,f"
+func flagNames(flag cli.Flag) []string {
+	names := []string{}
+	for _, name := range strings.Split(flag.GetName(), ",") {
+		names = append(names, strings.TrimSpace(name))
+	}
+	return names
+}
+
+// takesValue tells whether flag consumes the next argument, all the flags but the bool ones do
+func takesValue(flag cli.Flag) bool {
+	switch flag.(type) {
+	case cli.BoolFlag, cli.BoolTFlag:
+		return false
+	}
+	return true
+}
+
 type cliConfigGetter struct {
 	name string
 	ctx  *cli.Context
+	// driverFlags are the create flags of the driver, they map the canonical options to the driver options
+	driverFlags rpcDriver.DriverFlags
+	// template are the stored options of the --from-template cluster, the flags that are set override them
+	template *rpcDriver.DriverOptions
 }
 
 func (c cliConfigGetter) GetConfig() (rpcDriver.DriverOptions, error) {
-	driverOpts := getDriverOpts(c.ctx)
+	driverOpts, err := getDriverOpts(c.ctx)
+	if err != nil {
+		return driverOpts, err
+	}
+	applySecretDefaults(&driverOpts, c.driverFlags)
+	if err := setStdinCredential(&driverOpts); err != nil {
+		return driverOpts, err
+	}
+	if c.template != nil {
+		driverOpts = applyTemplate(c.template, driverOpts)
+	}
+	crd, err := crdOptions(c.ctx)
+	if err != nil {
+		return driverOpts, err
+	}
+	if crd != nil {
+		driverOpts = applyCRD(crd, driverOpts)
+	}
+	if err := resolveCredentialProfile(&driverOpts); err != nil {
+		return driverOpts, err
+	}
+	if err := resolveCredentialProvider(&driverOpts); err != nil {
+		return driverOpts, err
+	}
+	if err := validateImpersonation(driverOpts); err != nil {
+		return driverOpts, err
+	}
+	if err := mapKubernetesVersion(&driverOpts, c.driverFlags); err != nil {
+		return driverOpts, err
+	}
+	if err := mapNodeLabelsAndTaints(&driverOpts, c.driverFlags); err != nil {
+		return driverOpts, err
+	}
+	if err := mapNetworkOptions(&driverOpts, c.driverFlags); err != nil {
+		return driverOpts, err
+	}
+	if err := mapMaintenanceOptions(&driverOpts, c.driverFlags); err != nil {
+		return driverOpts, err
+	}
+	if err := mapOIDCOptions(&driverOpts, c.driverFlags); err != nil {
+		return driverOpts, err
+	}
+	if err := addons.mapOptions(&driverOpts, c.driverFlags); err != nil {
+		return driverOpts, err
+	}
+	if err := features.mapOptions(&driverOpts, c.driverFlags); err != nil {
+		return driverOpts, err
+	}
+	if err := mapDriverArgs(&driverOpts, c.driverFlags); err != nil {
+		return driverOpts, err
+	}
+	markSecretOptions(&driverOpts, c.driverFlags)
 	driverOpts.StringOptions["name"] = c.name
 	return driverOpts, nil
 }
 
-type cliPersistStore struct{}
+// mapKubernetesVersion sets the version option of the driver to the value of --kubernetes-version. Drivers without a
+// version option ignore it.
+func mapKubernetesVersion(driverOptions *rpcDriver.DriverOptions, driverFlags rpcDriver.DriverFlags) error {
+	version := driverOptions.StringOptions[kubernetesVersionFlag.Name]
+	if version == "" {
+		return nil
+	}
+	option := canonicalOption(driverFlags, rpcDriver.KubernetesVersionCanonical)
+	if option == "" {
+		logrus.Warnf("the driver has no kubernetes version option, --%s is ignored", kubernetesVersionFlag.Name)
+		return nil
+	}
+	if driverOptions.IsSet(option) && driverOptions.StringOptions[option] != version {
+		return newUsageError("--%s and --%s are set to different versions", kubernetesVersionFlag.Name, option)
+	}
+	if !driverOptions.IsSet(option) {
+		driverOptions.SetKeys = append(driverOptions.SetKeys, option)
+	}
+	driverOptions.StringOptions[option] = version
+	return nil
+}
+
+// canonicalOption returns the name of the driver option with the canonical name, empty if there is none
+func canonicalOption(driverFlags rpcDriver.DriverFlags, canonical string) string {
+	names := []string{}
+	for name, flag := range driverFlags.Options {
+		if flag != nil && flag.Canonical == canonical {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+	return names[0]
+}
 
-func (c cliPersistStore) Check(name string) (bool, error) {
-	path := filepath.Join(utils.HomeDir(), "clusters", name)
-	if _, err := os.Stat(filepath.Join(path, defaultConfigName)); os.IsNotExist(err) {
-		return false, nil
+type cliPersistStore struct {
+	kubeConfig kubeConfigOptions
+}
+
+func (c cliPersistStore) Check(name string) (cluster.State, error) {
+	path := filepath.Join(utils.HomeDir(), "clusters", name, defaultConfigName)
+	data, err := fileReadRetry.read(path)
+	if os.IsNotExist(err) {
+		return cluster.StateNotFound, nil
+	} else if err != nil {
+		return cluster.StateNotFound, fmt.Errorf("failed to read config of cluster %s from %s: %v", name, path, err)
 	}
 	cls := cluster.Cluster{}
-	data, err := ioutil.ReadFile(filepath.Join(path, defaultConfigName))
-	if err != nil {
-		return false, err
-	}
 	if err := json.Unmarshal(data, &cls); err != nil {
-		return false, err
+		return cluster.StateNotFound, fmt.Errorf("failed to parse config of cluster %s from %s: %v", name, path, err)
 	}
 	if cls.Status != cluster.Running {
-		return false, nil
+		return cluster.StateNotRunning, nil
 	}
-	return true, nil
+	return cluster.StateRunning, nil
 }
 
 func (c cliPersistStore) Get(name string) (cluster.Cluster, error) {
@@ -148,24 +398,30 @@ func (c cliPersistStore) Get(name string) (cluster.Cluster, error) {
 	if _, err := os.Stat(filepath.Join(path, defaultConfigName)); os.IsNotExist(err) {
 		return cluster.Cluster{}, fmt.Errorf("%s not found", name)
 	}
-	cls := cluster.Cluster{}
-	data, err := ioutil.ReadFile(filepath.Join(path, defaultConfigName))
+	configPath := filepath.Join(path, defaultConfigName)
+	data, err := fileReadRetry.read(configPath)
 	if err != nil {
-		return cluster.Cluster{}, err
+		return cluster.Cluster{}, fmt.Errorf("failed to read config of cluster %s from %s: %v", name, configPath, err)
 	}
-	if err := json.Unmarshal(data, &cls); err != nil {
-		return cluster.Cluster{}, err
+	cls, migrated, err := cluster.Migrate(data)
+	if err != nil {
+		return cluster.Cluster{}, fmt.Errorf("failed to parse config of cluster %s from %s: %v, fix the file or remove %s to forget the cluster", name, configPath, err, path)
+	}
+	// with --read-only the config is upgraded again by the next read
+	if migrated && !readOnly {
+		logrus.Debugf("Upgrading config of cluster %s to schema version %d", name, cluster.CurrentSchemaVersion)
+		if data, err := json.Marshal(cls); err == nil {
+			if err := utils.WritePrivateFile(data, configPath); err != nil {
+				logrus.Warnf("Failed to rewrite upgraded config of cluster %s: %v", name, err)
+			}
+		}
 	}
 	return cls, nil
 }
 
 func (c cliPersistStore) Store(cls cluster.Cluster) error {
-	// store kube config file
-	if err := storeConfig(cls); err != nil {
-		return err
-	}
-	// store json config file
-	fileDir := filepath.Join(utils.HomeDir(), "clusters", cls.Name)
+	// the certificates and the json config of a cluster are replaced together so that a failed write leaves the previous ones
+	files := map[string][]byte{}
 	for k, v := range map[string]string{
 		cls.RootCACert:        caPem,
 		cls.ClientKey:         clientKey,
@@ -175,53 +431,126 @@ func (c cliPersistStore) Store(cls cluster.Cluster) error {
 		if err != nil {
 			return err
 		}
-		if err := utils.WriteToFile(data, filepath.Join(fileDir, v)); err != nil {
-			return err
-		}
+		files[v] = data
+	}
+	if err := cluster.StampSchemaVersion(&cls); err != nil {
+		return err
 	}
 	data, err := json.Marshal(cls)
 	if err != nil {
 		return err
 	}
-	return utils.WriteToFile(data, filepath.Join(fileDir, defaultConfigName))
+	files[defaultConfigName] = data
+	// the directory holds the keys of the cluster, only the current user can read it
+	if err := utils.WritePrivateDirectory(filepath.Join(utils.HomeDir(), "clusters", cls.Name), files); err != nil {
+		return err
+	}
+	if c.kubeConfig.skip {
+		return nil
+	}
+	// store kube config file
+	return storeConfig(cls, c.kubeConfig)
 }
 
-func (c cliPersistStore) PersistStatus(cluster cluster.Cluster, status string) error {
-	fileDir := filepath.Join(utils.HomeDir(), "clusters", cluster.Name)
-	cluster.Status = status
-	data, err := json.Marshal(cluster)
+func (c cliPersistStore) PersistStatus(cls cluster.Cluster, status string) error {
+	fileDir := filepath.Join(utils.HomeDir(), "clusters", cls.Name)
+	cls.Status = status
+	if err := cluster.StampSchemaVersion(&cls); err != nil {
+		return err
+	}
+	data, err := json.Marshal(cls)
 	if err != nil {
 		return err
 	}
-	return utils.WriteToFile(data, filepath.Join(fileDir, defaultConfigName))
+	return utils.WritePrivateFile(data, filepath.Join(fileDir, defaultConfigName))
 }
 
-func create(ctx *cli.Context) error {
-	persistStore := cliPersistStore{}
+func create(ctx *cli.Context, driverFlags rpcDriver.DriverFlags) error {
+	kubeConfig, err := newKubeConfigOptions(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := useNoStore(ctx); err != nil {
+		return err
+	}
+	persistStore := newPersistStore(kubeConfig)
 	addr := ctx.GlobalString("plugin-listen-addr")
 	name := ""
 	if ctx.NArg() > 0 {
 		name = ctx.Args().Get(0)
 	}
+	if file := ctx.String(fromCRDFlag.Name); name == "" && file != "" {
+		if _, name, err = crdDriverAndName(file); err != nil {
+			return err
+		}
+	}
+	if name, err = generatedClusterName(ctx, persistStore, name); err != nil {
+		return err
+	}
+	if name != "" {
+		lock, err := lockCluster(ctx, name, "create")
+		if err != nil {
+			return err
+		}
+		defer unlockCluster(name, lock)
+	}
+	template, err := templateCluster(ctx, persistStore, name)
+	if err != nil {
+		return err
+	}
 	configGetter := cliConfigGetter{
-		name: name,
-		ctx:  ctx,
+		name:        name,
+		ctx:         ctx,
+		driverFlags: driverFlags,
+	}
+	if template != nil {
+		configGetter.template = template.Options
 	}
 	// first try to receive the cluster from disk
 	// ingore the error as we only care if cluster.name is present
-	clusterFrom, _ := persistStore.Get(os.Args[len(os.Args)-1])
+	endpointType := ctx.String("endpoint-type")
+	if err := validateEndpointType(endpointType); err != nil {
+		return err
+	}
+	if flag := sliceFlagValueLast(os.Args, ctx.Command.Flags); flag != "" {
+		logrus.Warnf("The last argument %q is the value of --%s, not the cluster name. Give the cluster name after all the flags", os.Args[len(os.Args)-1], flag)
+	}
+	clusterFrom := cluster.Cluster{}
+	if name != "" {
+		clusterFrom, _ = persistStore.Get(name)
+	}
 	if clusterFrom.DriverName != "" {
+		// a half-done create is finished with the options it was started with, the flags given again override them
+		if clusterFrom.Resumable() && clusterFrom.Options != nil {
+			configGetter.template = clusterFrom.Options
+		}
 		cls, err := cluster.FromCluster(&clusterFrom, addr, configGetter, persistStore)
 		if err != nil {
 			return err
 		}
-		return cls.Create()
+		if endpointType != "" {
+			cls.EndpointType = endpointType
+		}
+		cls.VolatileOptions = volatileOptions
+		setDebugDump(ctx, cls)
+		operation := newOperationContext()
+		if err := createWithInterrupts(operation, cls, persistStore); err != nil {
+			return err
+		}
+		return afterCreate(operation, ctx, *cls, kubeConfig)
 	}
 	// if cluster doesn't exist then we try to create a new one
 	driverName := ctx.String("driver")
+	if driverName == "" && template != nil {
+		driverName = template.DriverName
+	}
+	if file := ctx.String(fromCRDFlag.Name); driverName == "" && file != "" {
+		if driverName, _, err = crdDriverAndName(file); err != nil {
+			return err
+		}
+	}
 	if driverName == "" {
-		logrus.Error("Driver name is required")
-		return cli.ShowCommandHelp(ctx, "create")
+		return usageErrorWithHelp(ctx, "create", "driver name is required")
 	}
 
 	cls, err := cluster.NewCluster(driverName, addr, name, configGetter, persistStore)
@@ -229,10 +558,140 @@ func create(ctx *cli.Context) error {
 		return err
 	}
 	if cls.Name == "" {
-		logrus.Error("Cluster name is required")
-		return cli.ShowCommandHelp(ctx, "create")
+		return usageErrorWithHelp(ctx, "create", "cluster name is required")
+	}
+	cls.EndpointType = endpointType
+	cls.VolatileOptions = volatileOptions
+	setDebugDump(ctx, cls)
+	operation := newOperationContext()
+	if err := createWithInterrupts(operation, cls, persistStore); err != nil {
+		return err
+	}
+	return afterCreate(operation, ctx, *cls, kubeConfig)
+}
+
+// afterCreate waits for the API server if asked, writes the credentials and runs the post-create hook of the created
+// cluster, logging under the operation of the create
+func afterCreate(operation context.Context, ctx *cli.Context, cls cluster.Cluster, kubeConfig kubeConfigOptions) error {
+	if ctx.Bool(verifyConnectivityFlag.Name) {
+		if err := waitForConnectivity(ctx, cls); err != nil {
+			return err
+		}
+	}
+	if err := writeResults(createResultWriters(operation, ctx, kubeConfig), cls); err != nil {
+		return err
+	}
+	return postCreate(operation, ctx, cls, kubeConfig)
+}
+
+// createResultWriters returns the sinks the result of a create is written to: the --kubeconfig-out file, the
+// --write-credentials directory and stdout. Stdout is only written once the files are, so that the json output is
+// either the result or the error of the create
+func createResultWriters(operation context.Context, ctx *cli.Context, kubeConfig kubeConfigOptions) []resultWriter {
+	writers := []resultWriter{
+		{sink: "kubeconfig file", write: func(cls cluster.Cluster) error {
+			return outputKubeConfig(operation, ctx, cls, kubeConfig)
+		}},
+		{sink: "credentials directory", write: func(cls cluster.Cluster) error {
+			return outputCredentials(ctx, cls)
+		}},
+	}
+	// with --no-store the output is the kubeconfig instead of the result, the store wrote the --cluster-config file
+	if ctx.Bool(noStoreFlag.Name) {
+		return append(writers, resultWriter{sink: "stdout", onSuccess: true, write: func(cls cluster.Cluster) error {
+			return writeKubeConfig(os.Stdout, cls, kubeConfig)
+		}})
+	}
+	return append(writers, resultWriter{sink: "stdout", onSuccess: true, write: func(cls cluster.Cluster) error {
+		return writeOperationResult(os.Stdout, ctx.GlobalString("output"), cls)
+	}})
+}
+
+// outputKubeConfig writes the kubeconfig of the created cluster to the path of --kubeconfig-out if it is set, and
+// records the path in the metadata of the cluster
+func outputKubeConfig(operation context.Context, ctx *cli.Context, cls cluster.Cluster, kubeConfig kubeConfigOptions) error {
+	path := ctx.String(kubeConfigOutFlag.Name)
+	if path == "" {
+		return nil
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	config := bytes.Buffer{}
+	if err := writeKubeConfig(&config, cls, kubeConfig); err != nil {
+		return err
+	}
+	// the kubeconfig holds the credentials of the cluster, its directories are only for the current user
+	if err := utils.WritePrivateFile(config.Bytes(), path); err != nil {
+		return fmt.Errorf("failed to write the kubeconfig to %s: %v", path, err)
+	}
+	utils.OperationLogger(operation).Infof("Wrote the kubeconfig of cluster %s to %s", cls.Name, path)
+	if cls.Metadata == nil {
+		cls.Metadata = map[string]string{}
+	}
+	cls.Metadata[rpcDriver.KubeConfigOutMetadata] = path
+	return cls.PersistStore.PersistStatus(cls, cls.Status)
+}
+
+// outputCredentials writes the connection info of the created cluster if --write-credentials is set and prints the
+// directory, except with the json output
+func outputCredentials(ctx *cli.Context, cls cluster.Cluster) error {
+	dir := ctx.String("write-credentials")
+	if dir == "" {
+		return nil
+	}
+	if err := writeCredentials(dir, cls); err != nil {
+		return err
+	}
+	// the json output is only the result so that it can be decoded, the directory is given by the caller anyway
+	if ctx.GlobalString("output") != OutputJSON {
+		fmt.Println(dir)
+	}
+	return nil
+}
+
+// writeCredentials writes endpoint, token, ca, cert and key into dir. Only the current user can read them. A missing
+// dir is created for the current user only, the permissions of an existing one are left as they are
+func writeCredentials(dir string, cls cluster.Cluster) error {
+	info, err := os.Stat(dir)
+	switch {
+	case os.IsNotExist(err):
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+		// MkdirAll applies the umask
+		if err := os.Chmod(dir, 0700); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	case !info.IsDir():
+		return fmt.Errorf("the credentials directory %s is not a directory", dir)
+	case info.Mode().Perm()&0077 != 0:
+		logrus.Warnf("The credentials directory %s can be listed by other users, only the credential files in it are private", dir)
 	}
-	return cls.Create()
+	files := map[string][]byte{
+		endpointFile: []byte(cls.Endpoint),
+		tokenFile:    []byte(cls.ServiceAccountToken),
+	}
+	for k, v := range map[string]string{
+		caPem:      cls.RootCACert,
+		clientCert: cls.ClientCertificate,
+		clientKey:  cls.ClientKey,
+	} {
+		data, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return err
+		}
+		files[k] = data
+	}
+	for name, data := range files {
+		if err := utils.WritePrivateFile(data, filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func lookUpDebugFlag() bool {
@@ -244,9 +703,45 @@ func lookUpDebugFlag() bool {
 	return false
 }
 
+// DefaultDriverEnvPrefix is the default prefix of the environment variables defaulting the driver options
+const DefaultDriverEnvPrefix = "KE_DRIVER_"
+
+// driverEnvPrefix is the prefix of the environment variables defaulting the driver options, empty disables them
+var driverEnvPrefix = DefaultDriverEnvPrefix
+
+// SetDriverEnvPrefix sets the prefix of the environment variables defaulting the driver options, empty disables them
+func SetDriverEnvPrefix(prefix string) {
+	driverEnvPrefix = prefix
+}
+
+// driverOptionEnv returns the environment variable defaulting a driver option: the prefix followed by the option name
+// upper cased with '_' for any other character than letters and digits, e.g. KE_DRIVER_NODE_COUNT for node-count
+func driverOptionEnv(name string) string {
+	if driverEnvPrefix == "" {
+		return ""
+	}
+	return driverEnvPrefix + envName(name)
+}
+
 func getDriverFlags(opts rpcDriver.DriverFlags) []cli.Flag {
+	return driverCLIFlags(opts, true)
+}
+
+// getUpdateDriverFlags returns the flags of the update options of a driver. Unlike the create flags they don't take
+// their value from the environment: update only changes the options given on the command line, a variable left over
+// from a create would be taken as set and change the cluster.
+func getUpdateDriverFlags(opts rpcDriver.DriverFlags) []cli.Flag {
+	return driverCLIFlags(opts, false)
+}
+
+// driverCLIFlags returns the flags of the driver options, defaulted from their environment variables if fromEnv is set
+func driverCLIFlags(opts rpcDriver.DriverFlags, fromEnv bool) []cli.Flag {
 	flags := []cli.Flag{}
 	for k, v := range opts.Options {
+		envVar := ""
+		if fromEnv {
+			envVar = driverOptionEnv(k)
+		}
 		switch v.Type {
 		case "int":
 			val, err := strconv.Atoi(v.Value)
@@ -254,25 +749,38 @@ func getDriverFlags(opts rpcDriver.DriverFlags) []cli.Flag {
 				val = 0
 			}
 			flags = append(flags, cli.Int64Flag{
-				Name:  k,
-				Usage: v.Usage,
-				Value: int64(val),
+				Name:   k,
+				Usage:  v.Usage,
+				Value:  int64(val),
+				EnvVar: envVar,
 			})
 		case "string":
+			// the default of a secret option would be printed by the help, it is set by applySecretDefaults instead
+			if v.Secret && v.Value != "" {
+				flags = append(flags, cli.StringFlag{
+					Name:   k,
+					Usage:  v.Usage + " (secret, its default isn't shown)",
+					EnvVar: envVar,
+				})
+				continue
+			}
 			flags = append(flags, cli.StringFlag{
-				Name:  k,
-				Usage: v.Usage,
-				Value: v.Value,
+				Name:   k,
+				Usage:  v.Usage,
+				Value:  v.Value,
+				EnvVar: envVar,
 			})
 		case "stringSlice":
 			flags = append(flags, cli.StringSliceFlag{
-				Name:  k,
-				Usage: v.Usage,
+				Name:   k,
+				Usage:  v.Usage,
+				EnvVar: envVar,
 			})
 		case "bool":
 			flags = append(flags, cli.BoolFlag{
-				Name:  k,
-				Usage: v.Usage,
+				Name:   k,
+				Usage:  v.Usage,
+				EnvVar: envVar,
 			})
 		}
 	}
@@ -0,0 +1,340 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	"github.com/rancher/kontainer-engine/driver/mock"
+	"github.com/rancher/kontainer-engine/utils"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/check.v1"
+)
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) {
+	check.TestingT(t)
+}
+
+// tempHomeSuite points HOME to a temp dir for every test so the tests never touch the real store
+type tempHomeSuite struct {
+	home    string
+	oldHome string
+}
+
+func (s *tempHomeSuite) SetUpTest(c *check.C) {
+	s.oldHome = os.Getenv("HOME")
+	s.home = c.MkDir()
+	os.Setenv("HOME", s.home)
+}
+
+func (s *tempHomeSuite) TearDownTest(c *check.C) {
+	os.Setenv("HOME", s.oldHome)
+}
+
+type PersistStoreTestSuite struct {
+	tempHomeSuite
+}
+
+var _ = check.Suite(&PersistStoreTestSuite{})
+
+func writeClusterConfig(c *check.C, name, content string) string {
+	path := filepath.Join(utils.HomeDir(), "clusters", name, defaultConfigName)
+	if err := utils.WriteToFile([]byte(content), path); err != nil {
+		c.Fatal(err)
+	}
+	return path
+}
+
+func (s *PersistStoreTestSuite) TestGetMigratesV0Config(c *check.C) {
+	path := writeClusterConfig(c, "foo", `{"driverName":"gke","name":"foo","status":"Running","endpoint":"1.1.1.1","nodeCount":3,"metadata":{"zone":"us-central1-a"}}`)
+
+	cls, err := cliPersistStore{}.Get("foo")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.SchemaVersion, check.Equals, cluster.CurrentSchemaVersion)
+	c.Assert(cls.DriverName, check.Equals, "gke")
+	c.Assert(cls.Status, check.Equals, cluster.Running)
+	c.Assert(cls.Endpoint, check.Equals, "1.1.1.1")
+	c.Assert(cls.NodeCount, check.Equals, int64(3))
+	c.Assert(cls.Metadata["zone"], check.Equals, "us-central1-a")
+
+	// the upgraded config is written back
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, check.IsNil)
+	raw := map[string]interface{}{}
+	c.Assert(json.Unmarshal(data, &raw), check.IsNil)
+	c.Assert(raw["schemaVersion"], check.Equals, float64(cluster.CurrentSchemaVersion))
+}
+
+func (s *PersistStoreTestSuite) TestGetFutureSchemaVersion(c *check.C) {
+	writeClusterConfig(c, "foo", `{"schemaVersion":99,"driverName":"gke","name":"foo","status":"Running"}`)
+
+	cls, err := cliPersistStore{}.Get("foo")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.SchemaVersion, check.Equals, 99)
+	c.Assert(cls.DriverName, check.Equals, "gke")
+}
+
+func (s *PersistStoreTestSuite) TestFutureSchemaVersionNotWritten(c *check.C) {
+	config := `{"schemaVersion":99,"driverName":"gke","name":"foo","status":"Running","newField":"kept"}`
+	path := writeClusterConfig(c, "foo", config)
+
+	cls, err := cliPersistStore{}.Get("foo")
+	c.Assert(err, check.IsNil)
+	message := "the config of cluster foo has schema version 99 which is newer than the supported version 1, upgrade kontainer-engine to change it"
+	c.Assert(cliPersistStore{}.PersistStatus(cls, cluster.Updating), check.ErrorMatches, message)
+	c.Assert(cliPersistStore{kubeConfig: kubeConfigOptions{skip: true}}.Store(cls), check.ErrorMatches, message)
+
+	// the config keeps its version and the fields this version doesn't know
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, config)
+}
+
+func (s *PersistStoreTestSuite) TestStoreWritesSchemaVersion(c *check.C) {
+	err := cliPersistStore{}.PersistStatus(cluster.Cluster{Name: "foo", DriverName: "gke"}, cluster.Creating)
+	c.Assert(err, check.IsNil)
+
+	cls, err := cliPersistStore{}.Get("foo")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.SchemaVersion, check.Equals, cluster.CurrentSchemaVersion)
+	c.Assert(cls.Status, check.Equals, cluster.Creating)
+}
+
+func (s *PersistStoreTestSuite) TestWriteCredentials(c *check.C) {
+	dir := filepath.Join(c.MkDir(), "credentials")
+	cls := cluster.Cluster{
+		Name:                "foo",
+		Endpoint:            "1.1.1.1",
+		ServiceAccountToken: "token",
+		RootCACert:          base64.StdEncoding.EncodeToString([]byte("ca")),
+		ClientCertificate:   base64.StdEncoding.EncodeToString([]byte("cert")),
+		ClientKey:           base64.StdEncoding.EncodeToString([]byte("key")),
+	}
+	c.Assert(writeCredentials(dir, cls), check.IsNil)
+
+	info, err := os.Stat(dir)
+	c.Assert(err, check.IsNil)
+	c.Assert(info.Mode().Perm(), check.Equals, os.FileMode(0700))
+	for name, content := range map[string]string{
+		endpointFile: "1.1.1.1",
+		tokenFile:    "token",
+		caPem:        "ca",
+		clientCert:   "cert",
+		clientKey:    "key",
+	} {
+		info, err := os.Stat(filepath.Join(dir, name))
+		c.Assert(err, check.IsNil)
+		c.Assert(info.Mode().Perm(), check.Equals, os.FileMode(0600))
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		c.Assert(err, check.IsNil)
+		c.Assert(string(data), check.Equals, content)
+	}
+}
+
+func (s *PersistStoreTestSuite) TestWriteCredentialsToExistingDir(c *check.C) {
+	logs := bytes.Buffer{}
+	logrus.SetOutput(&logs)
+	defer logrus.SetOutput(os.Stderr)
+	dir := c.MkDir()
+	c.Assert(os.Chmod(dir, 0755), check.IsNil)
+	c.Assert(writeCredentials(dir, cluster.Cluster{Name: "foo", Endpoint: "1.1.1.1", ServiceAccountToken: "token"}), check.IsNil)
+
+	// a directory the user gave, e.g. the current one, isn't made private but the files are
+	info, err := os.Stat(dir)
+	c.Assert(err, check.IsNil)
+	c.Assert(info.Mode().Perm(), check.Equals, os.FileMode(0755))
+	info, err = os.Stat(filepath.Join(dir, tokenFile))
+	c.Assert(err, check.IsNil)
+	c.Assert(info.Mode().Perm(), check.Equals, os.FileMode(0600))
+	c.Assert(logs.String(), check.Matches, `(?s).*level=warning msg="The credentials directory .* can be listed by other users.*`)
+
+	file := filepath.Join(dir, "file")
+	c.Assert(ioutil.WriteFile(file, nil, 0600), check.IsNil)
+	c.Assert(writeCredentials(file, cluster.Cluster{}), check.ErrorMatches, "the credentials directory .* is not a directory")
+}
+
+func (s *PersistStoreTestSuite) TestCheck(c *check.C) {
+	writeClusterConfig(c, "running", `{"driverName":"mock","name":"running","status":"Running"}`)
+	writeClusterConfig(c, "error", `{"driverName":"mock","name":"error","status":"Error"}`)
+	path := writeClusterConfig(c, "corrupt", `{"driverName":`)
+
+	for name, expected := range map[string]cluster.State{
+		"running": cluster.StateRunning,
+		"error":   cluster.StateNotRunning,
+		"missing": cluster.StateNotFound,
+	} {
+		state, err := cliPersistStore{}.Check(name)
+		c.Assert(err, check.IsNil)
+		c.Assert(state, check.Equals, expected, check.Commentf("cluster %s", name))
+	}
+
+	_, err := cliPersistStore{}.Check("corrupt")
+	c.Assert(err, check.ErrorMatches, "failed to parse config of cluster corrupt from "+path+": .*")
+}
+
+func (s *PersistStoreTestSuite) TestGetCorruptConfig(c *check.C) {
+	path := writeClusterConfig(c, "foo", `{"driverName":"gke","name":`)
+
+	_, err := cliPersistStore{}.Get("foo")
+	c.Assert(err, check.NotNil)
+	c.Assert(strings.Contains(err.Error(), "failed to parse config of cluster foo from "+path), check.Equals, true, check.Commentf("error: %v", err))
+	c.Assert(strings.Contains(err.Error(), "remove "+filepath.Dir(path)), check.Equals, true, check.Commentf("error: %v", err))
+}
+
+func (s *PersistStoreTestSuite) TestStoreInvalidCertificateWritesNothing(c *check.C) {
+	err := cliPersistStore{}.Store(cluster.Cluster{Name: "foo", RootCACert: "not base64!"})
+	c.Assert(err, check.NotNil)
+	_, err = os.Stat(filepath.Join(utils.HomeDir(), "clusters", "foo"))
+	c.Assert(os.IsNotExist(err), check.Equals, true)
+	_, err = os.Stat(utils.KubeConfigFilePath())
+	c.Assert(os.IsNotExist(err), check.Equals, true)
+}
+
+func (s *PersistStoreTestSuite) TestStoreIsPrivate(c *check.C) {
+	if runtime.GOOS == "windows" {
+		c.Skip("file modes aren't enforced on windows")
+	}
+	cls := migratedCluster("foo", "1.1.1.1")
+	cls.ClientKey = base64.StdEncoding.EncodeToString([]byte("key"))
+	cls.ClientCertificate = base64.StdEncoding.EncodeToString([]byte("cert"))
+	store := cliPersistStore{kubeConfig: kubeConfigOptions{skip: true}}
+	c.Assert(store.Store(cls), check.IsNil)
+	c.Assert(store.PersistStatus(cls, cluster.Updating), check.IsNil)
+
+	clusters := filepath.Join(utils.HomeDir(), "clusters")
+	for _, dir := range []string{clusters, filepath.Join(clusters, "foo")} {
+		info, err := os.Stat(dir)
+		c.Assert(err, check.IsNil)
+		c.Assert(info.Mode().Perm(), check.Equals, os.FileMode(0700), check.Commentf("%s", dir))
+	}
+	for _, name := range []string{caPem, clientKey, clientCert, defaultConfigName} {
+		info, err := os.Stat(filepath.Join(clusters, "foo", name))
+		c.Assert(err, check.IsNil)
+		c.Assert(info.Mode().Perm(), check.Equals, os.FileMode(0600), check.Commentf("%s", name))
+	}
+}
+
+type CreateArgsTestSuite struct {
+	tempHomeSuite
+	oldArgs []string
+}
+
+var _ = check.Suite(&CreateArgsTestSuite{})
+
+func (s *CreateArgsTestSuite) SetUpTest(c *check.C) {
+	s.tempHomeSuite.SetUpTest(c)
+	s.oldArgs = os.Args
+}
+
+func (s *CreateArgsTestSuite) TearDownTest(c *check.C) {
+	os.Args = s.oldArgs
+	s.tempHomeSuite.TearDownTest(c)
+}
+
+func (s *CreateArgsTestSuite) TestSliceFlagValueLast(c *check.C) {
+	flags := append(CreateCommand().Flags, getDriverFlags(mockCreateFlags(c))...)
+	for _, t := range []struct {
+		args []string
+		flag string
+	}{
+		{[]string{"kontainer-engine", "create", "--driver", "mock", "--labels", "team=core"}, "labels"},
+		{[]string{"kontainer-engine", "create", "--driver", "mock", "foo", "--node-label", "team=core"}, "node-label"},
+		{[]string{"kontainer-engine", "create", "--driver", "mock", "-node-taint", "gpu:NoSchedule"}, "node-taint"},
+		{[]string{"kontainer-engine", "create", "--driver", "mock", "--labels", "team=core", "foo"}, ""},
+		{[]string{"kontainer-engine", "create", "--driver", "mock", "--labels=team=core"}, ""},
+		{[]string{"kontainer-engine", "create", "--driver", "mock", "--description", "foo"}, ""},
+		{[]string{"kontainer-engine", "create", "--labels", "--debug-dump-redact"}, ""},
+		{[]string{"foo"}, ""},
+	} {
+		c.Assert(sliceFlagValueLast(t.args, flags), check.Equals, t.flag, check.Commentf("args %v", t.args))
+	}
+}
+
+func (s *CreateArgsTestSuite) TestTrailingClusterName(c *check.C) {
+	flags := CreateCommand().Flags
+	c.Assert(trailingClusterName([]string{"kontainer-engine", "create", "--node-label", "team=core", "foo"}, flags), check.Equals, "foo")
+	c.Assert(trailingClusterName([]string{"kontainer-engine", "create", "--no-kubeconfig", "foo"}, flags), check.Equals, "foo")
+	c.Assert(trailingClusterName([]string{"kontainer-engine", "create", "--node-label", "team=core"}, flags), check.Equals, "")
+	c.Assert(trailingClusterName([]string{"kontainer-engine", "create", "--no-kubeconfig"}, flags), check.Equals, "")
+	c.Assert(trailingClusterName(nil, flags), check.Equals, "")
+}
+
+func (s *CreateArgsTestSuite) TestSliceValueNotTakenForName(c *check.C) {
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "existing"}
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+
+	// the value of --labels names a stored cluster, it isn't created again under an empty name
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "--labels", "existing"}
+	err := newTestApp().Run(os.Args)
+	c.Assert(err, check.ErrorMatches, "cluster name is required")
+	os.Args = []string{"kontainer-engine", "create", "--node-label", "existing"}
+	err = newTestApp().Run(os.Args)
+	c.Assert(err, check.ErrorMatches, "driver name is required")
+}
+
+func (s *CreateArgsTestSuite) TestResumeInterruptedCreate(c *check.C) {
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "--node-count", "3", "--description", "intent", "half"}
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+	created, err := cliPersistStore{}.Get("half")
+	c.Assert(err, check.IsNil)
+
+	// the process died while the driver was creating the cluster, before the provider had it
+	c.Assert(removeCluster(context.Background(), created, staticConfigGetter{driverOptions: newDriverOptions()}, removeOptions{keepLocal: true}), check.IsNil)
+	c.Assert(cliPersistStore{}.PersistStatus(created, cluster.Creating), check.IsNil)
+
+	// the create is resumed with the options it was started with
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "half"}
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+	resumed, err := cliPersistStore{}.Get("half")
+	c.Assert(err, check.IsNil)
+	c.Assert(resumed.Status, check.Equals, cluster.Running)
+	c.Assert(resumed.Metadata[mock.ResumedMetadata], check.Equals, "true")
+	c.Assert(resumed.NodeCount, check.Equals, int64(3))
+	c.Assert(resumed.Metadata["description"], check.Equals, "intent")
+	c.Assert(resumed.CreatedAt, check.Equals, created.CreatedAt)
+}
+
+func (s *CreateArgsTestSuite) TestFailedUpdateNotResumed(c *check.C) {
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "--node-count", "3", "updated"}
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+	created, err := cliPersistStore{}.Get("updated")
+	c.Assert(err, check.IsNil)
+	c.Assert(created.CreatePending, check.Equals, false)
+
+	// the post check of an update failed, the cluster is left Post-Checking
+	c.Assert(cliPersistStore{}.PersistStatus(created, cluster.PostCheck), check.IsNil)
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "updated"}
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+	recreated, err := cliPersistStore{}.Get("updated")
+	c.Assert(err, check.IsNil)
+	_, resumed := recreated.Metadata[mock.ResumedMetadata]
+	c.Assert(resumed, check.Equals, false)
+}
+
+func (s *CreateArgsTestSuite) TestCreateWithoutPartialState(c *check.C) {
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "--node-count", "3", "interrupted"}
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+	created, err := cliPersistStore{}.Get("interrupted")
+	c.Assert(err, check.IsNil)
+
+	// an interrupted create was cleaned up by the driver, it starts afresh from the flags
+	c.Assert(removeCluster(context.Background(), created, staticConfigGetter{driverOptions: newDriverOptions()}, removeOptions{keepLocal: true}), check.IsNil)
+	c.Assert(cliPersistStore{}.PersistStatus(created, cluster.Interrupted), check.IsNil)
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "--node-count", "2", "interrupted"}
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+	recreated, err := cliPersistStore{}.Get("interrupted")
+	c.Assert(err, check.IsNil)
+	c.Assert(recreated.Status, check.Equals, cluster.Running)
+	c.Assert(recreated.NodeCount, check.Equals, int64(2))
+	_, resumed := recreated.Metadata[mock.ResumedMetadata]
+	c.Assert(resumed, check.Equals, false)
+}
@@ -0,0 +1,327 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/rancher/kontainer-engine/utils"
+	"github.com/urfave/cli"
+)
+
+var (
+	credentialProfileFlag = cli.StringFlag{
+		Name:  "credential-profile",
+		Usage: "The name of a credential profile added with 'credential add', its credential is passed to the driver",
+	}
+	impersonateServiceAccountFlag = cli.StringFlag{
+		Name:  rpcDriver.ImpersonateServiceAccountOption,
+		Usage: "The service account the driver impersonates with the credential, e.g. a GCP service account email, for the drivers supporting it",
+	}
+	impersonateUserFlag = cli.StringFlag{
+		Name:  rpcDriver.ImpersonateUserOption,
+		Usage: "The user the driver impersonates with the credential, for the drivers supporting it",
+	}
+	credentialProviderFlag = cli.StringFlag{
+		Name:  "credential-provider",
+		Usage: "Fetch the credential of the driver from a secret backend: vault, see --vault-addr, --vault-path and --vault-key",
+	}
+	credentialStdinFlag = cli.BoolFlag{
+		Name:  "credential-stdin",
+		Usage: "Read the credential of the driver from stdin, so that it doesn't show in the process list like a flag value",
+	}
+	profileNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+	// credentialInput is where --credential-stdin and 'credential add --from-file -' read the credential, tests replace it
+	credentialInput io.Reader = os.Stdin
+	// stdinCredential is the credential read from credentialInput, create runs again to parse the driver flags and
+	// stdin can only be read once
+	stdinCredential *string
+)
+
+// credentialProvider fetches the credential of the driver from a secret backend
+type credentialProvider interface {
+	credential() (string, error)
+}
+
+// credentialProviders are the values of --credential-provider, they create the provider from the driver options
+var credentialProviders = map[string]func(rpcDriver.DriverOptions) (credentialProvider, error){
+	vaultProvider: newVaultCredentialProvider,
+}
+
+// credentialProfile is a named credential of a driver, stored in the credentials directory
+type credentialProfile struct {
+	Name       string `json:"name,omitempty"`
+	Driver     string `json:"driver,omitempty"`
+	Credential string `json:"credential,omitempty"`
+}
+
+// CredentialCommand defines the credential command
+func CredentialCommand() cli.Command {
+	return cli.Command{
+		Name:  "credential",
+		Usage: "Manage the credential profiles of the drivers",
+		Subcommands: []cli.Command{
+			{
+				Name:      "add",
+				Usage:     "Add or replace a credential profile",
+				ArgsUsage: "profile-name",
+				Action:    addCredentialProfile,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "driver",
+						Usage: "The driver using the credential",
+					},
+					cli.StringFlag{
+						Name:  "from-file",
+						Usage: "The file holding the credential, e.g. the json key of a google service account, '-' reads it from stdin",
+					},
+					cli.StringFlag{
+						Name:  "value",
+						Usage: "The credential itself",
+					},
+				},
+			},
+			{
+				Name:      "list",
+				ShortName: "ls",
+				Usage:     "List the credential profiles",
+				Action:    listCredentialProfiles,
+			},
+			{
+				Name:      "remove",
+				ShortName: "rm",
+				Usage:     "Remove credential profiles",
+				ArgsUsage: "profile-name...",
+				Action:    removeCredentialProfiles,
+			},
+		},
+	}
+}
+
+func credentialsDir() string {
+	return filepath.Join(utils.HomeDir(), "credentials")
+}
+
+func validateProfileName(name string) error {
+	if !profileNameRegexp.MatchString(name) {
+		return newValidationError("invalid credential profile name %q, use letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+}
+
+func addCredentialProfile(ctx *cli.Context) error {
+	name := ctx.Args().Get(0)
+	if name == "" {
+		return usageErrorWithHelp(ctx, "add", "profile name is required")
+	}
+	file, value := ctx.String("from-file"), ctx.String("value")
+	if (file == "") == (value == "") {
+		return newUsageError("exactly one of --from-file and --value is required")
+	}
+	if file == "-" {
+		credential, err := readStdinCredential()
+		if err != nil {
+			return err
+		}
+		value = credential
+	} else if file != "" {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read credential: %v", err)
+		}
+		value = string(data)
+	}
+	return storeCredentialProfile(credentialProfile{
+		Name:       name,
+		Driver:     ctx.String("driver"),
+		Credential: value,
+	})
+}
+
+// storeCredentialProfile writes profile so that only the current user can read it. It isn't encrypted, there is no
+// encryption key in kontainer-engine, the stores and the kubeconfig file are plaintext as well
+func storeCredentialProfile(profile credentialProfile) error {
+	if err := validateProfileName(profile.Name); err != nil {
+		return err
+	}
+	if profile.Driver == "" {
+		return errors.New("driver name is required")
+	}
+	data, err := json.Marshal(profile)
+	if err != nil {
+		return err
+	}
+	return utils.WritePrivateFile(data, filepath.Join(credentialsDir(), profile.Name))
+}
+
+func getCredentialProfile(name string) (credentialProfile, error) {
+	profile := credentialProfile{}
+	if err := validateProfileName(name); err != nil {
+		return profile, err
+	}
+	path := filepath.Join(credentialsDir(), name)
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return profile, newNotFoundError("credential profile %s not found", name)
+	} else if err != nil {
+		return profile, fmt.Errorf("failed to read credential profile %s: %v", name, err)
+	}
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return profile, fmt.Errorf("failed to parse credential profile %s from %s: %v", name, path, err)
+	}
+	return profile, nil
+}
+
+func getAllCredentialProfiles() ([]credentialProfile, error) {
+	files, err := ioutil.ReadDir(credentialsDir())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	profiles := []credentialProfile{}
+	for _, file := range files {
+		if file.IsDir() || validateProfileName(file.Name()) != nil {
+			continue
+		}
+		profile, err := getCredentialProfile(file.Name())
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, profile)
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+	return profiles, nil
+}
+
+func listCredentialProfiles(ctx *cli.Context) error {
+	profiles, err := getAllCredentialProfiles()
+	if err != nil {
+		return err
+	}
+	writer := utils.NewTableWriter([][]string{
+		{"NAME", "Name"},
+		{"DRIVER", "Driver"},
+	}, ctx)
+	for _, profile := range profiles {
+		// never print the credential itself
+		profile.Credential = ""
+		writer.Write(profile)
+	}
+	return writer.Close()
+}
+
+func removeCredentialProfiles(ctx *cli.Context) error {
+	if ctx.NArg() == 0 {
+		return usageErrorWithHelp(ctx, "remove", "profile name is required")
+	}
+	for _, name := range ctx.Args() {
+		if _, err := getCredentialProfile(name); err != nil {
+			return err
+		}
+		if err := os.Remove(filepath.Join(credentialsDir(), name)); err != nil {
+			return err
+		}
+		fmt.Println(name)
+	}
+	return nil
+}
+
+// resolveCredentialProfile sets the credential option of driverOptions from the profile named by the credential-profile option
+func resolveCredentialProfile(driverOptions *rpcDriver.DriverOptions) error {
+	name := driverOptions.StringOptions[credentialProfileFlag.Name]
+	if name == "" {
+		return nil
+	}
+	if driverOptions.StringOptions[rpcDriver.CredentialOption] != "" {
+		return newUsageError("--%s and --%s can't be used together", rpcDriver.CredentialOption, credentialProfileFlag.Name)
+	}
+	profile, err := getCredentialProfile(name)
+	if err != nil {
+		return err
+	}
+	if driver := driverOptions.StringOptions["driver"]; driver != "" && driver != profile.Driver {
+		return newValidationError("credential profile %s is for driver %s, not %s", name, profile.Driver, driver)
+	}
+	driverOptions.StringOptions[rpcDriver.CredentialOption] = profile.Credential
+	return nil
+}
+
+// resolveCredentialProvider sets the credential option of driverOptions from the provider named by the
+// credential-provider option
+func resolveCredentialProvider(driverOptions *rpcDriver.DriverOptions) error {
+	name := driverOptions.StringOptions[credentialProviderFlag.Name]
+	if name == "" {
+		return nil
+	}
+	if driverOptions.StringOptions[rpcDriver.CredentialOption] != "" || driverOptions.StringOptions[credentialProfileFlag.Name] != "" {
+		return newUsageError("--%s can't be used with --%s or --%s", credentialProviderFlag.Name, rpcDriver.CredentialOption, credentialProfileFlag.Name)
+	}
+	newProvider, ok := credentialProviders[name]
+	if !ok {
+		return newUsageError("unknown credential provider %s, use %s", name, vaultProvider)
+	}
+	provider, err := newProvider(*driverOptions)
+	if err != nil {
+		return err
+	}
+	credential, err := provider.credential()
+	if err != nil {
+		return err
+	}
+	driverOptions.StringOptions[rpcDriver.CredentialOption] = credential
+	return nil
+}
+
+// readStdinCredential reads the credential from stdin the first time it is called and returns it on every call, without
+// the trailing newline
+func readStdinCredential() (string, error) {
+	if stdinCredential != nil {
+		return *stdinCredential, nil
+	}
+	data, err := ioutil.ReadAll(credentialInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to read credential from stdin: %v", err)
+	}
+	credential := strings.TrimRight(string(data), "\r\n")
+	if credential == "" {
+		return "", newUsageError("no credential was read from stdin")
+	}
+	stdinCredential = &credential
+	return credential, nil
+}
+
+// setStdinCredential sets the credential option of driverOptions from stdin if the credential-stdin option is set
+func setStdinCredential(driverOptions *rpcDriver.DriverOptions) error {
+	if !driverOptions.BoolOptions[credentialStdinFlag.Name] {
+		return nil
+	}
+	if driverOptions.StringOptions[rpcDriver.CredentialOption] != "" || driverOptions.StringOptions[credentialProfileFlag.Name] != "" {
+		return newUsageError("--%s can't be used with --%s or --%s", credentialStdinFlag.Name, rpcDriver.CredentialOption, credentialProfileFlag.Name)
+	}
+	credential, err := readStdinCredential()
+	if err != nil {
+		return err
+	}
+	driverOptions.StringOptions[rpcDriver.CredentialOption] = credential
+	if !driverOptions.IsSet(rpcDriver.CredentialOption) {
+		driverOptions.SetKeys = append(driverOptions.SetKeys, rpcDriver.CredentialOption)
+	}
+	return nil
+}
+
+// validateImpersonation returns a usage error if driverOptions impersonate both a service account and a user
+func validateImpersonation(driverOptions rpcDriver.DriverOptions) error {
+	if driverOptions.StringOptions[rpcDriver.ImpersonateServiceAccountOption] != "" && driverOptions.StringOptions[rpcDriver.ImpersonateUserOption] != "" {
+		return newUsageError("--%s and --%s can't be used together", rpcDriver.ImpersonateServiceAccountOption, rpcDriver.ImpersonateUserOption)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"gopkg.in/check.v1"
+)
+
+type CredentialTestSuite struct {
+	tempHomeSuite
+}
+
+var _ = check.Suite(&CredentialTestSuite{})
+
+func (s *CredentialTestSuite) TestStoreProfilePrivately(c *check.C) {
+	c.Assert(storeCredentialProfile(credentialProfile{Name: "prod", Driver: "gke", Credential: "secret"}), check.IsNil)
+
+	info, err := os.Stat(filepath.Join(credentialsDir(), "prod"))
+	c.Assert(err, check.IsNil)
+	c.Assert(info.Mode().Perm(), check.Equals, os.FileMode(0600))
+	info, err = os.Stat(credentialsDir())
+	c.Assert(err, check.IsNil)
+	c.Assert(info.Mode().Perm(), check.Equals, os.FileMode(0700))
+
+	// adding a profile again replaces it
+	c.Assert(storeCredentialProfile(credentialProfile{Name: "prod", Driver: "gke", Credential: "rotated"}), check.IsNil)
+	profile, err := getCredentialProfile("prod")
+	c.Assert(err, check.IsNil)
+	c.Assert(profile, check.Equals, credentialProfile{Name: "prod", Driver: "gke", Credential: "rotated"})
+}
+
+func (s *CredentialTestSuite) TestListProfiles(c *check.C) {
+	for _, name := range []string{"staging", "dev", "prod"} {
+		c.Assert(storeCredentialProfile(credentialProfile{Name: name, Driver: "mock", Credential: name}), check.IsNil)
+	}
+	profiles, err := getAllCredentialProfiles()
+	c.Assert(err, check.IsNil)
+	names := []string{}
+	for _, profile := range profiles {
+		names = append(names, profile.Name)
+	}
+	c.Assert(names, check.DeepEquals, []string{"dev", "prod", "staging"})
+}
+
+func (s *CredentialTestSuite) TestInvalidProfiles(c *check.C) {
+	c.Assert(storeCredentialProfile(credentialProfile{Name: "../escape", Driver: "gke"}), check.ErrorMatches, `invalid credential profile name "../escape".*`)
+	c.Assert(storeCredentialProfile(credentialProfile{Name: "prod"}), check.ErrorMatches, "driver name is required")
+	_, err := getCredentialProfile("missing")
+	c.Assert(err, check.ErrorMatches, "credential profile missing not found")
+}
+
+func (s *CredentialTestSuite) TestResolveProfile(c *check.C) {
+	c.Assert(storeCredentialProfile(credentialProfile{Name: "prod", Driver: "mock", Credential: "secret"}), check.IsNil)
+
+	driverOptions, err := resolveDriverOptions([]string{"--driver", "mock", "--credential-profile", "prod", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.IsNil)
+	c.Assert(driverOptions.StringOptions[rpcDriver.CredentialOption], check.Equals, "secret")
+
+	// without a profile the credential is left alone
+	driverOptions, err = resolveDriverOptions([]string{"--driver", "mock", "--credential", "inline", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.IsNil)
+	c.Assert(driverOptions.StringOptions[rpcDriver.CredentialOption], check.Equals, "inline")
+
+	_, err = resolveDriverOptions([]string{"--driver", "mock", "--credential", "inline", "--credential-profile", "prod", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.ErrorMatches, "--credential and --credential-profile can't be used together")
+	_, err = resolveDriverOptions([]string{"--driver", "gke", "--credential-profile", "prod", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.ErrorMatches, "credential profile prod is for driver mock, not gke")
+	_, err = resolveDriverOptions([]string{"--driver", "mock", "--credential-profile", "missing", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.ErrorMatches, "credential profile missing not found")
+}
+
+func (s *CredentialTestSuite) TestImpersonationReachesDriver(c *check.C) {
+	defer func(args []string) { os.Args = args }(os.Args)
+	// the mock driver reports the impersonation options it is given in the metadata of the cluster
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "--impersonate-service-account", "deployer@project.iam.gserviceaccount.com", "sa"}
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+	cls, err := cliPersistStore{}.Get("sa")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.Status, check.Equals, cluster.Running)
+	c.Assert(cls.Metadata[rpcDriver.ImpersonateServiceAccountOption], check.Equals, "deployer@project.iam.gserviceaccount.com")
+	_, ok := cls.Metadata[rpcDriver.ImpersonateUserOption]
+	c.Assert(ok, check.Equals, false)
+
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "--impersonate-user", "admin@example.com", "user"}
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+	cls, err = cliPersistStore{}.Get("user")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.Metadata[rpcDriver.ImpersonateUserOption], check.Equals, "admin@example.com")
+}
+
+func (s *CredentialTestSuite) TestImpersonationExclusive(c *check.C) {
+	defer func(args []string) { os.Args = args }(os.Args)
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "--impersonate-service-account", "deployer", "--impersonate-user", "admin", "both"}
+	err := newTestApp().Run(os.Args)
+	c.Assert(err, check.ErrorMatches, "--impersonate-service-account and --impersonate-user can't be used together")
+	c.Assert(ExitCode(err), check.Equals, ExitUsage)
+	// the driver isn't called
+	cls, err := cliPersistStore{}.Get("both")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.Status, check.Equals, cluster.Error)
+	c.Assert(cls.Endpoint, check.Equals, "")
+}
+
+func (s *CredentialTestSuite) TestCredentialFromStdin(c *check.C) {
+	defer func() {
+		credentialInput, stdinCredential = os.Stdin, nil
+	}()
+	key := `{"type": "service_account", "private_key": "` + strings.Repeat("k", 4096) + `"}`
+	credentialInput, stdinCredential = strings.NewReader(key+"\n"), nil
+
+	driverOptions, err := resolveDriverOptions([]string{"--driver", "mock", "--credential-stdin", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.IsNil)
+	c.Assert(driverOptions.StringOptions[rpcDriver.CredentialOption], check.Equals, key)
+	c.Assert(driverOptions.IsSet(rpcDriver.CredentialOption), check.Equals, true)
+	// create runs again to parse the driver flags, the credential is read once
+	driverOptions, err = resolveDriverOptions([]string{"--driver", "mock", "--credential-stdin", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.IsNil)
+	c.Assert(driverOptions.StringOptions[rpcDriver.CredentialOption], check.Equals, key)
+
+	_, err = resolveDriverOptions([]string{"--driver", "mock", "--credential-stdin", "--credential", "inline", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.ErrorMatches, "--credential-stdin can't be used with --credential or --credential-profile")
+	c.Assert(ExitCode(err), check.Equals, ExitUsage)
+
+	credentialInput, stdinCredential = strings.NewReader(""), nil
+	_, err = resolveDriverOptions([]string{"--driver", "mock", "--credential-stdin", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.ErrorMatches, "no credential was read from stdin")
+}
+
+func (s *CredentialTestSuite) TestAddProfileFromStdin(c *check.C) {
+	defer func(args []string) {
+		os.Args, credentialInput, stdinCredential = args, os.Stdin, nil
+	}(os.Args)
+	credentialInput, stdinCredential = strings.NewReader("piped\n"), nil
+	app := newTestApp()
+	app.Commands = append(app.Commands, CredentialCommand())
+	os.Args = []string{"kontainer-engine", "credential", "add", "--driver", "mock", "--from-file", "-", "prod"}
+	c.Assert(app.Run(os.Args), check.IsNil)
+	profile, err := getCredentialProfile("prod")
+	c.Assert(err, check.IsNil)
+	c.Assert(profile.Credential, check.Equals, "piped")
+}
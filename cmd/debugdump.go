@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"encoding/json"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/rancher/kontainer-engine/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+var (
+	debugDumpFlag = cli.StringFlag{
+		Name:  "debug-dump",
+		Usage: "Write the cluster info returned by the driver once the cluster is created, before it is checked and stored, as json to this file",
+	}
+	debugDumpRedactFlag = cli.BoolFlag{
+		Name:  "debug-dump-redact",
+		Usage: "Redact the password, the token, the client certificate and key and the secret metadata in the --debug-dump file",
+	}
+)
+
+// debugDump is the content of the --debug-dump file
+type debugDump struct {
+	Driver   string                `json:"driver"`
+	Cluster  string                `json:"cluster"`
+	Response rpcDriver.ClusterInfo `json:"response"`
+}
+
+// setDebugDump makes the create of cls write the response of the driver to the --debug-dump file of ctx, if set. The
+// dump is only for debugging, failing to write it is logged without failing the create
+func setDebugDump(ctx *cli.Context, cls *cluster.Cluster) {
+	path := ctx.String(debugDumpFlag.Name)
+	if path == "" {
+		return
+	}
+	redact := ctx.Bool(debugDumpRedactFlag.Name)
+	cls.ResponseHook = func(info rpcDriver.ClusterInfo) {
+		if err := writeDebugDump(path, cls.DriverName, cls.Name, info, redact); err != nil {
+			logrus.Warnf("Failed to write the driver response of cluster %s to %s: %v", cls.Name, path, err)
+		}
+	}
+}
+
+// writeDebugDump writes the response of a driver as json to path, which only the current user can read as the
+// secrets are kept unless redact is set
+func writeDebugDump(path, driverName, name string, info rpcDriver.ClusterInfo, redact bool) error {
+	if redact {
+		info = redactClusterInfo(info)
+	}
+	data, err := json.MarshalIndent(debugDump{Driver: driverName, Cluster: name, Response: info}, "", "\t")
+	if err != nil {
+		return err
+	}
+	return utils.WritePrivateFile(append(data, '\n'), path)
+}
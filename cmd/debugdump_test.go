@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/check.v1"
+)
+
+type DebugDumpTestSuite struct {
+	tempHomeSuite
+	oldArgs []string
+}
+
+var _ = check.Suite(&DebugDumpTestSuite{})
+
+func (s *DebugDumpTestSuite) SetUpTest(c *check.C) {
+	s.tempHomeSuite.SetUpTest(c)
+	s.oldArgs = os.Args
+}
+
+func (s *DebugDumpTestSuite) TearDownTest(c *check.C) {
+	os.Args = s.oldArgs
+	s.tempHomeSuite.TearDownTest(c)
+}
+
+func (s *DebugDumpTestSuite) readDump(c *check.C, path string) debugDump {
+	info, err := os.Stat(path)
+	c.Assert(err, check.IsNil)
+	c.Assert(info.Mode().Perm(), check.Equals, os.FileMode(0600))
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, check.IsNil)
+	dump := debugDump{}
+	c.Assert(json.Unmarshal(data, &dump), check.IsNil)
+	return dump
+}
+
+func (s *DebugDumpTestSuite) TestDebugDump(c *check.C) {
+	path := filepath.Join(c.MkDir(), "response.json")
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "--node-count", "3", "--debug-dump", path, "dumped"}
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+
+	dump := s.readDump(c, path)
+	c.Assert(dump.Driver, check.Equals, "mock")
+	c.Assert(dump.Cluster, check.Equals, "dumped")
+	c.Assert(dump.Response.NodeCount, check.Equals, int64(3))
+	c.Assert(dump.Response.ServiceAccountToken, check.Equals, "dumped-token")
+	c.Assert(dump.Response.ClientKey, check.Equals, base64.StdEncoding.EncodeToString([]byte("dumped-key")))
+	c.Assert(dump.Response.Endpoint, check.Not(check.Equals), "")
+}
+
+func (s *DebugDumpTestSuite) TestDebugDumpRedacted(c *check.C) {
+	path := filepath.Join(c.MkDir(), "response.json")
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "--debug-dump", path, "--debug-dump-redact", "dumped"}
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+
+	dump := s.readDump(c, path)
+	// the long secrets keep their last characters as a hint
+	c.Assert(dump.Response.ServiceAccountToken, check.Equals, "Redacted...oken")
+	c.Assert(dump.Response.ClientKey, check.Equals, "Redacted...eQ==")
+	c.Assert(dump.Response.Endpoint, check.Not(check.Equals), "Redacted")
+
+	// the stored cluster keeps the credentials
+	cls, err := cliPersistStore{}.Get("dumped")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.ServiceAccountToken, check.Equals, "dumped-token")
+}
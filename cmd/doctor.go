@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	generic "github.com/rancher/kontainer-engine/driver"
+	"github.com/rancher/kontainer-engine/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+var (
+	verifyConnectivityFlag = cli.BoolFlag{
+		Name:  "verify-connectivity",
+		Usage: "Wait until the API server of the created cluster accepts the generated credential, polling as set by the wait options",
+	}
+	// apiVerifyOptions are the user agent and the headers of the calls to the API servers of the clusters
+	apiVerifyOptions = cluster.VerifyOptions{UserAgent: cluster.DefaultUserAgent}
+)
+
+// SetAPIClient sets the user agent of the calls to the API servers to kontainer-engine/version and adds the headers,
+// given as NAME=VALUE
+func SetAPIClient(version string, headers []string) error {
+	opts := cluster.VerifyOptions{
+		UserAgent: cluster.DefaultUserAgent + "/" + version,
+		Headers:   map[string]string{},
+	}
+	for _, header := range headers {
+		parts := strings.SplitN(header, "=", 2)
+		name := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || name == "" || strings.ContainsAny(name, " \t:") {
+			return newUsageError("invalid API header %q, must be NAME=VALUE", header)
+		}
+		opts.Headers[name] = parts[1]
+	}
+	apiVerifyOptions = opts
+	return nil
+}
+
+// DoctorCommand defines the doctor command
+func DoctorCommand() cli.Command {
+	return cli.Command{
+		Name:            "doctor",
+		Usage:           "Check the credential and connectivity of a driver without creating a cluster",
+		Action:          doctorWrapper,
+		SkipFlagParsing: true,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "driver",
+				Usage: "Driver to check",
+			},
+			cli.StringFlag{
+				Name:  "cluster",
+				Usage: "Also check that the API server of this cluster accepts its stored credential, the driver defaults to the driver of the cluster",
+			},
+		},
+	}
+}
+
+func doctorWrapper(ctx *cli.Context) error {
+	driverName := flagHackLookup("--driver")
+	if name := flagHackLookup("--cluster"); driverName == "" && name != "" {
+		// ignore the error as we only care if the cluster is present
+		cls, _ := newPersistStore(kubeConfigOptions{}).Get(name)
+		driverName = cls.DriverName
+	}
+	if driverName == "" {
+		return usageErrorWithHelp(ctx, "doctor", "driver name is required")
+	}
+	rpcClient, addr, err := runRPCDriver(driverName)
+	if err != nil {
+		return err
+	}
+	// the credential options are part of the create options
+	driverFlags, err := rpcClient.GetDriverCreateOptions()
+	if err != nil {
+		return err
+	}
+	return rerunWithDriverFlags(ctx, "doctor", getDriverFlags(driverFlags), doctor, addr)
+}
+
+func doctor(ctx *cli.Context) error {
+	driverName := ctx.String("driver")
+	rpcClient, err := generic.NewClient(driverName, ctx.GlobalString("plugin-listen-addr"))
+	if err != nil {
+		return err
+	}
+	driverOptions, err := getDriverOpts(ctx)
+	if err != nil {
+		return err
+	}
+	if err := checkConnectivity(rpcClient, driverOptions); err != nil {
+		return err
+	}
+	fmt.Printf("Driver %s can reach its provider\n", driverName)
+	name := ctx.String("cluster")
+	if name == "" {
+		return nil
+	}
+	clusters, err := getAllClusters()
+	if err != nil {
+		return err
+	}
+	cls, ok := clusters[name]
+	if !ok {
+		return newNotFoundError("cluster %v can't be found", name)
+	}
+	if err := cluster.VerifyConnectivityWithOptions(&cls, apiVerifyOptions); err != nil {
+		return err
+	}
+	fmt.Printf("The API server of cluster %s accepts its credential\n", name)
+	return nil
+}
+
+// waitForConnectivity polls the API server of cls until it accepts the stored credential. A rejected credential fails
+// right away, the other failures are retried until the wait timeout.
+func waitForConnectivity(ctx *cli.Context, cls cluster.Cluster) error {
+	driverOptions, err := getDriverOpts(ctx)
+	if err != nil {
+		return err
+	}
+	backoff, err := generic.BackoffFromOptions(&driverOptions)
+	if err != nil {
+		return err
+	}
+	var lastErr error
+	err = utils.PollWithBackoff(backoff, func() (bool, error) {
+		lastErr = cluster.VerifyConnectivityWithOptions(&cls, apiVerifyOptions)
+		if lastErr == nil {
+			return true, nil
+		}
+		if connectivityErr, ok := lastErr.(*cluster.ConnectivityError); ok && connectivityErr.Status == generic.ConnectivityBadCredentials {
+			return false, lastErr
+		}
+		logrus.Debugf("Waiting for the API server of cluster %s: %v", cls.Name, lastErr)
+		return false, nil
+	})
+	if err != nil && lastErr != nil && err != lastErr {
+		return fmt.Errorf("%v: %v", err, lastErr)
+	}
+	return err
+}
+
+// checkConnectivity asks the driver to check its credential and connectivity and turns any failure into an error
+func checkConnectivity(rpcClient *generic.GrpcClient, driverOptions generic.DriverOptions) error {
+	result, err := rpcClient.CheckConnectivity(driverOptions)
+	if err != nil {
+		return err
+	}
+	switch result.Status {
+	case generic.ConnectivityOK:
+		return nil
+	case generic.ConnectivityUnsupported:
+		return fmt.Errorf("driver %s doesn't support connectivity checks", rpcClient.DriverName())
+	}
+	return fmt.Errorf("driver %s connectivity check failed (%s): %s", rpcClient.DriverName(), result.Status, result.Message)
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"flag"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	"github.com/urfave/cli"
+	"gopkg.in/check.v1"
+)
+
+type DoctorTestSuite struct {
+}
+
+var _ = check.Suite(&DoctorTestSuite{})
+
+func (s *DoctorTestSuite) TestCheckConnectivity(c *check.C) {
+	rpcClient, _, err := runRPCDriver("mock")
+	c.Assert(err, check.IsNil)
+
+	driverOptions := newDriverOptions()
+	driverOptions.StringOptions["credential"] = "secret"
+	c.Assert(checkConnectivity(rpcClient, driverOptions), check.IsNil)
+
+	driverOptions.StringOptions["credential"] = "invalid"
+	err = checkConnectivity(rpcClient, driverOptions)
+	c.Assert(err, check.ErrorMatches, `driver mock connectivity check failed \(bad-credentials\): the credential is invalid`)
+}
+
+func (s *DoctorTestSuite) TestCheckConnectivityUnsupported(c *check.C) {
+	rpcClient, _, err := runRPCDriver("rke")
+	c.Assert(err, check.IsNil)
+	err = checkConnectivity(rpcClient, newDriverOptions())
+	c.Assert(err, check.ErrorMatches, "driver rke doesn't support connectivity checks")
+}
+
+// waitContext returns a create context with the wait options set to wait at most timeout
+func waitContext(c *check.C, timeout string) *cli.Context {
+	set := flag.NewFlagSet("create", flag.ContinueOnError)
+	for _, f := range waitFlags {
+		f.Apply(set)
+	}
+	c.Assert(set.Parse([]string{"--wait-initial-interval", "10ms", "--wait-timeout", timeout}), check.IsNil)
+	ctx := cli.NewContext(nil, set, nil)
+	ctx.Command = cli.Command{Name: "create", Flags: waitFlags}
+	return ctx
+}
+
+func (s *DoctorTestSuite) TestWaitForConnectivity(c *check.C) {
+	ready := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("Authorization") != "Bearer token":
+			w.WriteHeader(http.StatusUnauthorized)
+		case !ready:
+			// the API server comes up after the first poll
+			ready = true
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	cls := cluster.Cluster{Name: "foo", Endpoint: server.URL, ServiceAccountToken: "token"}
+	c.Assert(waitForConnectivity(waitContext(c, "5s"), cls), check.IsNil)
+	c.Assert(ready, check.Equals, true)
+
+	// a rejected credential isn't retried
+	cls.ServiceAccountToken = "bad"
+	err := waitForConnectivity(waitContext(c, "1h"), cls)
+	c.Assert(err, check.ErrorMatches, `API server .* failed the connectivity check \(bad-credentials\): .*401 Unauthorized`)
+}
+
+func (s *DoctorTestSuite) TestSetAPIClient(c *check.C) {
+	defer func(opts cluster.VerifyOptions) { apiVerifyOptions = opts }(apiVerifyOptions)
+	c.Assert(SetAPIClient("v1.2.3", []string{"X-Gateway-Key=abc", "X-Empty=", "X-Query=a=b"}), check.IsNil)
+	c.Assert(apiVerifyOptions, check.DeepEquals, cluster.VerifyOptions{
+		UserAgent: "kontainer-engine/v1.2.3",
+		Headers:   map[string]string{"X-Gateway-Key": "abc", "X-Empty": "", "X-Query": "a=b"},
+	})
+
+	for _, header := range []string{"X-Gateway-Key", "=abc", "X Key=abc"} {
+		err := SetAPIClient("v1.2.3", []string{header})
+		c.Assert(err, check.ErrorMatches, "invalid API header .*, must be NAME=VALUE")
+		c.Assert(ExitCode(err), check.Equals, ExitUsage)
+	}
+}
+
+func (s *DoctorTestSuite) TestWaitForConnectivitySendsAPIHeaders(c *check.C) {
+	defer func(opts cluster.VerifyOptions) { apiVerifyOptions = opts }(apiVerifyOptions)
+	c.Assert(SetAPIClient("v1.2.3", []string{"X-Gateway-Key=abc"}), check.IsNil)
+	headers := make(chan http.Header, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header
+	}))
+	defer server.Close()
+
+	cls := cluster.Cluster{Name: "foo", Endpoint: server.URL, ServiceAccountToken: "token"}
+	c.Assert(waitForConnectivity(waitContext(c, "5s"), cls), check.IsNil)
+	header := <-headers
+	c.Assert(header.Get("User-Agent"), check.Equals, "kontainer-engine/v1.2.3")
+	c.Assert(header.Get("X-Gateway-Key"), check.Equals, "abc")
+}
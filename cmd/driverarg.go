@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"strings"
+
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+var driverArgFlag = cli.StringSliceFlag{
+	Name:  "driver-arg",
+	Usage: "A string option passed to the driver as KEY=VALUE even if the driver doesn't declare it, it isn't validated. Repeat the flag for several options",
+}
+
+// mapDriverArgs sets the string options of the driver from the values of --driver-arg. The options aren't validated,
+// only the options the driver or create don't declare can be given, the declared ones have their own flag.
+func mapDriverArgs(driverOptions *rpcDriver.DriverOptions, driverFlags rpcDriver.DriverFlags) error {
+	values := driverOptions.StringSliceOptions[driverArgFlag.Name]
+	if values == nil || len(values.Value) == 0 {
+		return nil
+	}
+	given := map[string]bool{}
+	for _, value := range values.Value {
+		kv := strings.SplitN(value, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return newUsageError("invalid driver arg %q, use KEY=VALUE", value)
+		}
+		key := kv[0]
+		if _, declared := driverFlags.Options[key]; declared || isCreateFlag(key) {
+			return newUsageError("%s is an option of create or of the driver, use --%s instead of --%s", key, key, driverArgFlag.Name)
+		}
+		if given[key] {
+			return newUsageError("driver arg %s is given more than once", key)
+		}
+		given[key] = true
+		logrus.Warnf("--%s %s is passed to the driver without validation", driverArgFlag.Name, key)
+		driverOptions.StringOptions[key] = kv[1]
+		driverOptions.SetKeys = append(driverOptions.SetKeys, key)
+	}
+	return nil
+}
+
+// isCreateFlag tells whether name is a flag of create, or the name option create sets itself
+func isCreateFlag(name string) bool {
+	return name == "name" || lookupFlag(CreateCommand().Flags, "--"+name) != nil
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"os"
+
+	"gopkg.in/check.v1"
+)
+
+type DriverArgTestSuite struct {
+	tempHomeSuite
+	oldArgs []string
+}
+
+var _ = check.Suite(&DriverArgTestSuite{})
+
+func (s *DriverArgTestSuite) SetUpTest(c *check.C) {
+	s.tempHomeSuite.SetUpTest(c)
+	s.oldArgs = os.Args
+}
+
+func (s *DriverArgTestSuite) TearDownTest(c *check.C) {
+	os.Args = s.oldArgs
+	s.tempHomeSuite.TearDownTest(c)
+}
+
+func (s *DriverArgTestSuite) TestMapToDriverOptions(c *check.C) {
+	driverOptions, err := resolveDriverOptions([]string{"--driver-arg", "beta-feature=x=1", "--driver-arg", "empty=", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.IsNil)
+	c.Assert(driverOptions.StringOptions["beta-feature"], check.Equals, "x=1")
+	c.Assert(driverOptions.StringOptions["empty"], check.Equals, "")
+	c.Assert(driverOptions.IsSet("beta-feature"), check.Equals, true)
+	c.Assert(driverOptions.IsSet("empty"), check.Equals, true)
+}
+
+func (s *DriverArgTestSuite) TestInvalidDriverArgs(c *check.C) {
+	for _, test := range []struct{ value, err string }{
+		{"beta-feature", `invalid driver arg "beta-feature", use KEY=VALUE`},
+		{"=1", `invalid driver arg "=1", use KEY=VALUE`},
+		{"description=x", "description is an option of create or of the driver, use --description instead of --driver-arg"},
+		{"driver=gke", "driver is an option of create or of the driver, use --driver instead of --driver-arg"},
+		{"name=other", "name is an option of create or of the driver, use --name instead of --driver-arg"},
+	} {
+		_, err := resolveDriverOptions([]string{"--driver-arg", test.value, "foo"}, mockCreateFlags(c))
+		c.Assert(err, check.ErrorMatches, test.err)
+		c.Assert(ExitCode(err), check.Equals, ExitUsage)
+	}
+	_, err := resolveDriverOptions([]string{"--driver-arg", "a=1", "--driver-arg", "a=2", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.ErrorMatches, "driver arg a is given more than once")
+}
+
+func (s *DriverArgTestSuite) TestCreateSendsDriverArgs(c *check.C) {
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "--driver-arg", "beta-feature=on", "raw"}
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+	cls, err := cliPersistStore{}.Get("raw")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.Options.StringOptions["beta-feature"], check.Equals, "on")
+}
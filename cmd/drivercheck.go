@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	generic "github.com/rancher/kontainer-engine/driver"
+	"github.com/rancher/kontainer-engine/plugin"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+// protocolCheck is the outcome of the protocol version handshake with a driver
+type protocolCheck struct {
+	Driver string `json:"driver"`
+	// Version is the version of the driver itself
+	Version string `json:"version"`
+	// Expected is the rpc protocol version of the CLI and Actual the one of the driver, 0 if it predates the handshake
+	Expected   int32 `json:"expectedProtocolVersion"`
+	Actual     int32 `json:"protocolVersion"`
+	Compatible bool  `json:"compatible"`
+}
+
+// DriverCheckCommand defines the driver-check command
+func DriverCheckCommand() cli.Command {
+	return cli.Command{
+		Name:      "driver-check",
+		Usage:     "Start a driver and check that it speaks the rpc protocol version of this CLI",
+		ArgsUsage: "DRIVER",
+		Action:    driverCheck,
+	}
+}
+
+func driverCheck(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return usageErrorWithHelp(ctx, "driver-check", "driver name is required")
+	}
+	driverName := ctx.Args().Get(0)
+	// the driver of --driver-addr runs elsewhere, it can be any driver
+	if driverAddr == "" && !plugin.BuiltInDrivers[driverName] {
+		return usageErrorWithHelp(ctx, "driver-check", fmt.Sprintf("driver %s is not supported, 'kontainer-engine drivers' lists the supported drivers", driverName))
+	}
+	// the handshake is done here, runRPCDriver would refuse the driver before it is reported
+	rpcClient, _, err := startRPCDriver(driverName)
+	if err != nil {
+		return err
+	}
+	check, err := protocolHandshake(driverName, rpcClient)
+	if err != nil {
+		return err
+	}
+	if err := writeProtocolCheck(os.Stdout, ctx.GlobalString("output"), check); err != nil {
+		return err
+	}
+	if !check.Compatible {
+		return incompatibleDriverError(check)
+	}
+	return nil
+}
+
+// protocolHandshake asks the driver for its version and the version of the rpc protocol it speaks
+func protocolHandshake(driverName string, rpcClient *generic.GrpcClient) (protocolCheck, error) {
+	check := protocolCheck{Driver: driverName, Expected: generic.CurrentProtocolVersion}
+	var err error
+	if check.Version, err = rpcClient.GetVersion(); err != nil {
+		return check, &exitError{code: ExitDriverFailure, err: fmt.Errorf("failed to get the version of driver %s: %v", driverName, err)}
+	}
+	if check.Actual, err = rpcClient.GetProtocolVersion(); err != nil {
+		return check, &exitError{code: ExitDriverFailure, err: fmt.Errorf("failed to get the protocol version of driver %s: %v", driverName, err)}
+	}
+	check.Compatible = check.Actual == check.Expected
+	return check, nil
+}
+
+// checkProtocolVersion refuses a driver speaking another rpc protocol version than the CLI, unless
+// --allow-incompatible-driver is set
+func checkProtocolVersion(driverName string, rpcClient *generic.GrpcClient) error {
+	check, err := protocolHandshake(driverName, rpcClient)
+	if err != nil || check.Compatible {
+		return err
+	}
+	err = incompatibleDriverError(check)
+	if allowIncompatibleDriver {
+		logrus.Warnf("%v, running it anyway as --allow-incompatible-driver is set", err)
+		return nil
+	}
+	return err
+}
+
+func incompatibleDriverError(check protocolCheck) error {
+	if check.Actual == 0 {
+		return &exitError{code: ExitDriverFailure, err: fmt.Errorf("driver %s %s doesn't report its rpc protocol version, it predates version %d of this CLI",
+			check.Driver, check.Version, check.Expected)}
+	}
+	return &exitError{code: ExitDriverFailure, err: fmt.Errorf("driver %s %s speaks rpc protocol version %d, this CLI speaks version %d",
+		check.Driver, check.Version, check.Actual, check.Expected)}
+}
+
+// writeProtocolCheck prints whether the driver of check is compatible, as a json object with the json output
+func writeProtocolCheck(w io.Writer, output string, check protocolCheck) error {
+	if output == OutputJSON {
+		data, err := json.Marshal(check)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	}
+	compatibility := "compatible"
+	if !check.Compatible {
+		compatibility = "incompatible"
+	}
+	_, err := fmt.Fprintf(w, "Driver %s %s is %s: expected rpc protocol version %d, got %d\n",
+		check.Driver, check.Version, compatibility, check.Expected, check.Actual)
+	return err
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+
+	generic "github.com/rancher/kontainer-engine/driver"
+	"github.com/rancher/kontainer-engine/driver/mock"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	"gopkg.in/check.v1"
+)
+
+type DriverCheckTestSuite struct {
+	tempHomeSuite
+}
+
+var _ = check.Suite(&DriverCheckTestSuite{})
+
+func (s *DriverCheckTestSuite) TearDownTest(c *check.C) {
+	mock.ProtocolVersion = generic.CurrentProtocolVersion
+	SetAllowIncompatibleDriver(false)
+	s.tempHomeSuite.TearDownTest(c)
+}
+
+// driverCheck runs driver-check with args and returns what it printed
+func (s *DriverCheckTestSuite) driverCheck(c *check.C, args ...string) (string, error) {
+	app := newTestApp()
+	app.Commands = []cli.Command{DriverCheckCommand()}
+	var err error
+	output := captureStdout(c, func() {
+		err = app.Run(append([]string{"kontainer-engine"}, args...))
+	})
+	return output, err
+}
+
+func (s *DriverCheckTestSuite) TestCompatibleDriver(c *check.C) {
+	output, err := s.driverCheck(c, "driver-check", "mock")
+	c.Assert(err, check.IsNil)
+	c.Assert(output, check.Equals, "Driver mock v0.1.0 is compatible: expected rpc protocol version 1, got 1\n")
+
+	output, err = s.driverCheck(c, "--output", "json", "driver-check", "mock")
+	c.Assert(err, check.IsNil)
+	result := protocolCheck{}
+	c.Assert(json.Unmarshal([]byte(output), &result), check.IsNil)
+	c.Assert(result, check.DeepEquals, protocolCheck{Driver: "mock", Version: "v0.1.0", Expected: 1, Actual: 1, Compatible: true})
+
+	results, err := applyManifest(clusterManifest{Clusters: []clusterSpec{mockSpec("compatible", 1)}}, applyOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(results[0].Status, check.Equals, "Success")
+}
+
+func (s *DriverCheckTestSuite) TestIncompatibleDriver(c *check.C) {
+	for version, message := range map[int32]string{
+		2: "driver mock v0.1.0 speaks rpc protocol version 2, this CLI speaks version 1",
+		0: "driver mock v0.1.0 doesn't report its rpc protocol version, it predates version 1 of this CLI",
+	} {
+		mock.ProtocolVersion = version
+		output, err := s.driverCheck(c, "driver-check", "mock")
+		c.Assert(err, check.ErrorMatches, message)
+		c.Assert(ExitCode(err), check.Equals, ExitDriverFailure)
+		c.Assert(output, check.Matches, "Driver mock v0.1.0 is incompatible: expected rpc protocol version 1, got [02]\n")
+	}
+
+	// the other commands refuse the driver
+	mock.ProtocolVersion = 2
+	results, err := applyManifest(clusterManifest{Clusters: []clusterSpec{mockSpec("refused", 1)}}, applyOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(results[0].Status, check.Equals, "Failed")
+	c.Assert(results[0].Error, check.Equals, "driver mock v0.1.0 speaks rpc protocol version 2, this CLI speaks version 1")
+	_, err = (cliPersistStore{}).Get("refused")
+	c.Assert(err, check.NotNil)
+}
+
+func (s *DriverCheckTestSuite) TestAllowIncompatibleDriver(c *check.C) {
+	logs := bytes.Buffer{}
+	logrus.SetOutput(&logs)
+	defer logrus.SetOutput(os.Stderr)
+	mock.ProtocolVersion = 2
+	SetAllowIncompatibleDriver(true)
+
+	results, err := applyManifest(clusterManifest{Clusters: []clusterSpec{mockSpec("allowed", 1)}}, applyOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(results[0].Status, check.Equals, "Success")
+	c.Assert(logs.String(), check.Matches, `(?s).*driver mock v0.1.0 speaks rpc protocol version 2, this CLI speaks version 1, running it anyway as --allow-incompatible-driver is set.*`)
+
+	// driver-check still reports the driver incompatible
+	_, err = s.driverCheck(c, "driver-check", "mock")
+	c.Assert(ExitCode(err), check.Equals, ExitDriverFailure)
+}
+
+func (s *DriverCheckTestSuite) TestUnsupportedDriver(c *check.C) {
+	_, err := s.driverCheck(c, "driver-check", "unknown")
+	c.Assert(err, check.ErrorMatches, "driver unknown is not supported, 'kontainer-engine drivers' lists the supported drivers")
+	c.Assert(ExitCode(err), check.Equals, ExitUsage)
+	_, err = s.driverCheck(c, "driver-check")
+	c.Assert(ExitCode(err), check.Equals, ExitUsage)
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"sort"
+
+	"github.com/rancher/kontainer-engine/plugin"
+	"github.com/rancher/kontainer-engine/utils"
+	"github.com/urfave/cli"
+)
+
+// driverInfo is a driver listed by the drivers command
+type driverInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// DriversCommand defines the drivers command
+func DriversCommand() cli.Command {
+	return cli.Command{
+		Name:   "drivers",
+		Usage:  "List the drivers that can create kubernetes clusters",
+		Action: lsDrivers,
+	}
+}
+
+// listDrivers returns the built-in drivers sorted by name. The drivers run in this process, there are no external
+// driver binaries to discover.
+func listDrivers() []driverInfo {
+	drivers := []driverInfo{}
+	for name := range plugin.BuiltInDrivers {
+		drivers = append(drivers, driverInfo{
+			Name:        name,
+			Description: plugin.Descriptions[name],
+		})
+	}
+	sort.Slice(drivers, func(i, j int) bool {
+		return drivers[i].Name < drivers[j].Name
+	})
+	return drivers
+}
+
+func lsDrivers(ctx *cli.Context) error {
+	writer := utils.NewTableWriter([][]string{
+		{"NAME", "Name"},
+		{"DESCRIPTION", "Description"},
+	}, ctx)
+	for _, driver := range listDrivers() {
+		writer.Write(driver)
+	}
+	return writer.Close()
+}
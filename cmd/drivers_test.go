@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"github.com/rancher/kontainer-engine/plugin"
+	"gopkg.in/check.v1"
+)
+
+type DriversTestSuite struct {
+}
+
+var _ = check.Suite(&DriversTestSuite{})
+
+func (s *DriversTestSuite) TestBuiltInDriversListed(c *check.C) {
+	drivers := listDrivers()
+	names := []string{}
+	for _, driver := range drivers {
+		c.Assert(driver.Description, check.Not(check.Equals), "", check.Commentf("driver %s has no description", driver.Name))
+		names = append(names, driver.Name)
+	}
+	c.Assert(names, check.DeepEquals, []string{"gke", "mock", "rke"})
+	c.Assert(len(drivers), check.Equals, len(plugin.BuiltInDrivers))
+}
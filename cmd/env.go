@@ -3,7 +3,6 @@ package cmd
 import (
 	"fmt"
 
-	"github.com/rancher/kontainer-engine/store"
 	"github.com/rancher/kontainer-engine/utils"
 	"github.com/urfave/cli"
 )
@@ -19,29 +18,33 @@ func EnvCommand() cli.Command {
 
 func env(ctx *cli.Context) error {
 	name := ctx.Args().Get(0)
-	if name == "" || name == "--help" {
+	if name == "" {
+		return usageErrorWithHelp(ctx, "env", "cluster name is required")
+	}
+	if name == "--help" {
 		return cli.ShowCommandHelp(ctx, "env")
 	}
 
-	clusters, err := store.GetAllClusterFromStore()
+	clusters, err := getAllClusters()
 	if err != nil {
 		return err
 	}
 	_, ok := clusters[name]
 	if !ok {
-		return fmt.Errorf("cluster %v can't be found", name)
+		return newNotFoundError("cluster %v can't be found", name)
 	}
 	config, err := getConfigFromFile()
 	if err != nil {
 		return err
 	}
-	config.CurrentContext = name
+	context := kubeConfigEntryNames(name).Context
+	config.CurrentContext = context
 	if err := setConfigToFile(config); err != nil {
 		return err
 	}
 
 	configFile := utils.KubeConfigFilePath()
-	fmt.Printf("Current context is set to %s\n", name)
+	fmt.Printf("Current context is set to %s\n", context)
 	fmt.Printf("run `export KUBECONFIG=%v` or `--kubeconfig %s` to use the config file\n", configFile, configFile)
 	return nil
 }
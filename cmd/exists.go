@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	"github.com/urfave/cli"
+)
+
+const (
+	existsRunning    = 0
+	existsNotRunning = 1
+	existsNotFound   = 2
+	// existsUnknown means the state of the cluster can't be read, e.g. its config is corrupt, so that it isn't taken
+	// for a cluster that isn't running
+	existsUnknown = 3
+	// existsUsage means no cluster name was given, so that a missing name isn't taken for a running cluster
+	existsUsage = 4
+)
+
+// ExistsCommand defines the exists command
+func ExistsCommand() cli.Command {
+	return cli.Command{
+		Name:      "exists",
+		Usage:     "Check whether a kubernetes cluster exists. Exits 0 if it is running, 1 if it isn't running, 2 if it doesn't exist, 3 if its state can't be read and 4 if no cluster name is given",
+		ArgsUsage: "cluster-name",
+		Action:    clusterExists,
+	}
+}
+
+func clusterExists(ctx *cli.Context) error {
+	name := ctx.Args().Get(0)
+	if name == "--help" {
+		return cli.ShowCommandHelp(ctx, "exists")
+	}
+	if name == "" {
+		if _, err := showErrorHelp(ctx, "exists"); err != nil {
+			return err
+		}
+		return &exitError{code: existsUsage, err: errors.New("cluster name is required")}
+	}
+	code, err := existsCode(name)
+	if err != nil {
+		return &exitError{code: existsUnknown, err: err}
+	}
+	if code != existsRunning {
+		return cli.NewExitError("", code)
+	}
+	return nil
+}
+
+// existsCode returns the exit code of the exists command for a cluster
+func existsCode(name string) (int, error) {
+	state, err := newPersistStore(kubeConfigOptions{}).Check(name)
+	if err != nil {
+		return 0, err
+	}
+	switch state {
+	case cluster.StateRunning:
+		return existsRunning, nil
+	case cluster.StateNotRunning:
+		return existsNotRunning, nil
+	}
+	return existsNotFound, nil
+}
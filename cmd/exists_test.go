@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"github.com/urfave/cli"
+	"gopkg.in/check.v1"
+)
+
+type ExistsTestSuite struct {
+	tempHomeSuite
+}
+
+var _ = check.Suite(&ExistsTestSuite{})
+
+func (s *ExistsTestSuite) TestExistsCode(c *check.C) {
+	writeClusterConfig(c, "running", `{"driverName":"mock","name":"running","status":"Running"}`)
+	writeClusterConfig(c, "creating", `{"driverName":"mock","name":"creating","status":"Creating"}`)
+
+	for name, expected := range map[string]int{
+		"running":  existsRunning,
+		"creating": existsNotRunning,
+		"missing":  existsNotFound,
+	} {
+		code, err := existsCode(name)
+		c.Assert(err, check.IsNil)
+		c.Assert(code, check.Equals, expected, check.Commentf("cluster %s", name))
+	}
+}
+
+func (s *ExistsTestSuite) TestCorruptConfig(c *check.C) {
+	writeClusterConfig(c, "corrupt", `{"driverName":`)
+	app := newTestApp()
+	app.Commands = []cli.Command{ExistsCommand()}
+	err := app.Run([]string{"kontainer-engine", "exists", "corrupt"})
+	c.Assert(err, check.ErrorMatches, "failed to parse config of cluster corrupt from .*")
+	// a store that can't be read isn't taken for a cluster that isn't running
+	c.Assert(ExitCode(err), check.Equals, existsUnknown)
+}
+
+func (s *ExistsTestSuite) TestMissingName(c *check.C) {
+	app := newTestApp()
+	app.Commands = []cli.Command{ExistsCommand()}
+	var err error
+	captureStdout(c, func() {
+		err = app.Run([]string{"kontainer-engine", "exists"})
+	})
+	c.Assert(err, check.ErrorMatches, "cluster name is required")
+	// a missing name isn't taken for a running cluster
+	c.Assert(ExitCode(err), check.Equals, existsUsage)
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/urfave/cli"
+	"google.golang.org/grpc/status"
+)
+
+// The exit codes of the commands. The exists command reports the state of a cluster with its own codes instead
+const (
+	// ExitGeneric is the exit code of any failure without a more specific code
+	ExitGeneric = 1
+	// ExitUsage means the command was called with missing or invalid arguments
+	ExitUsage = 2
+	// ExitNotFound means the cluster or the profile the command works on doesn't exist
+	ExitNotFound = 3
+	// ExitDriverFailure means the driver or its provider failed
+	ExitDriverFailure = 4
+	// ExitValidation means an option or a manifest is invalid
+	ExitValidation = 5
+	// ExitRetryable means the provider refused the request for now, e.g. because of a quota, it can be retried unchanged
+	ExitRetryable = 6
+	// ExitInterrupted means the command was interrupted with SIGINT or SIGTERM
+	ExitInterrupted = 130
+)
+
+// exitError is an error with the exit code it makes the command exit with
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func newUsageError(format string, a ...interface{}) error {
+	return &exitError{code: ExitUsage, err: fmt.Errorf(format, a...)}
+}
+
+func newNotFoundError(format string, a ...interface{}) error {
+	return &exitError{code: ExitNotFound, err: fmt.Errorf(format, a...)}
+}
+
+func newValidationError(format string, a ...interface{}) error {
+	return &exitError{code: ExitValidation, err: fmt.Errorf(format, a...)}
+}
+
+// showErrorHelp prints the help of command before an error is reported, except with the json output whose stdout is
+// only the json error. It reports whether the help was printed.
+func showErrorHelp(ctx *cli.Context, command string) (bool, error) {
+	if ctx.GlobalString("output") == OutputJSON {
+		return false, nil
+	}
+	return true, cli.ShowCommandHelp(ctx, command)
+}
+
+// usageErrorWithHelp prints the help of command and returns a usage error with message
+func usageErrorWithHelp(ctx *cli.Context, command, message string) error {
+	if _, err := showErrorHelp(ctx, command); err != nil {
+		return err
+	}
+	return &exitError{code: ExitUsage, err: errors.New(message)}
+}
+
+// driverDiscoveryError prints the help of command, which lacks the flags of the driver, and returns a driver failure
+// for err, the failure to get the flags of driverName
+func driverDiscoveryError(ctx *cli.Context, command, driverName string, err error) error {
+	shown, helpErr := showErrorHelp(ctx, command)
+	if helpErr != nil {
+		return helpErr
+	}
+	helpNote := ""
+	if shown {
+		helpNote = fmt.Sprintf(", the help above lists the %s flags without them", command)
+	}
+	return &exitError{
+		code: ExitDriverFailure,
+		err: fmt.Errorf("failed to get the options of driver %s%s: %v. Run 'kontainer-engine doctor --driver %s' to check the driver",
+			driverName, helpNote, err, driverName),
+	}
+}
+
+// ExitCode returns the code the command failing with err exits with. The errors returned through the driver rpc are
+// driver failures unless the driver classified them, the errors without a known code are generic failures.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if err == cluster.ErrInterrupted {
+		return ExitInterrupted
+	}
+	switch e := err.(type) {
+	case *exitError:
+		return e.code
+	case cli.ExitCoder:
+		return e.ExitCode()
+	case *cluster.DriverError:
+		return driverErrorExitCode(e)
+	case *cluster.UnsupportedError:
+		return ExitValidation
+	}
+	if _, ok := status.FromError(err); ok {
+		return ExitDriverFailure
+	}
+	return ExitGeneric
+}
+
+// driverErrorExitCode returns the exit code of a failure the driver classified
+func driverErrorExitCode(err *cluster.DriverError) int {
+	switch {
+	case err.Retryable():
+		return ExitRetryable
+	case err.Code == rpcDriver.ErrorInvalid:
+		return ExitValidation
+	case err.Code == rpcDriver.ErrorNotFound:
+		return ExitNotFound
+	}
+	return ExitDriverFailure
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/urfave/cli"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/check.v1"
+)
+
+type ExitTestSuite struct {
+	tempHomeSuite
+}
+
+var _ = check.Suite(&ExitTestSuite{})
+
+func (s *ExitTestSuite) TestExitCodes(c *check.C) {
+	_, validateErr := parseNodePools([]string{"name=default"})
+	_, manifestErr := readManifest("testdata/does-not-exist.yml")
+	_, profileErr := getCredentialProfile("missing")
+	_, resolveErr := resolveDriverOptions([]string{"--driver", "mock"}, mockCreateFlags(c))
+
+	for _, test := range []struct {
+		err  error
+		code int
+	}{
+		{nil, 0},
+		{errors.New("boom"), ExitGeneric},
+		{resolveErr, ExitUsage},
+		{profileErr, ExitNotFound},
+		{status.Error(codes.Unknown, "quota exceeded"), ExitDriverFailure},
+		{validateErr, ExitValidation},
+		{validateEndpointType("internal"), ExitValidation},
+		{validateManifest(clusterManifest{}), ExitValidation},
+		// a missing manifest file isn't a validation failure
+		{manifestErr, ExitGeneric},
+		// the exit code of exists is kept
+		{cli.NewExitError("", existsNotFound), existsNotFound},
+	} {
+		c.Assert(ExitCode(test.err), check.Equals, test.code, check.Commentf("error: %v", test.err))
+	}
+}
+
+func (s *ExitTestSuite) TestDriverFailureExitCode(c *check.C) {
+	rpcClient, _, err := runRPCDriver("mock")
+	c.Assert(err, check.IsNil)
+	driverOptions := newDriverOptions()
+	// the mock driver requires a name
+	err = rpcClient.SetDriverOptions(driverOptions)
+	c.Assert(err, check.NotNil)
+	c.Assert(ExitCode(err), check.Equals, ExitDriverFailure)
+}
+
+func (s *ExitTestSuite) TestClassifiedDriverFailureExitCodes(c *check.C) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	for code, exitCode := range map[string]int{
+		rpcDriver.ErrorQuotaExceeded: ExitRetryable,
+		rpcDriver.ErrorConflict:      ExitRetryable,
+		rpcDriver.ErrorAuthFailed:    ExitDriverFailure,
+		rpcDriver.ErrorNotFound:      ExitNotFound,
+		rpcDriver.ErrorInvalid:       ExitValidation,
+	} {
+		// the mock fails the create of the clusters named fail-CODE with an error classified as CODE
+		os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "fail-" + code}
+		err := newTestApp().Run(os.Args)
+		c.Assert(err, check.FitsTypeOf, &cluster.DriverError{})
+		c.Assert(err.(*cluster.DriverError).Code, check.Equals, code)
+		c.Assert(ExitCode(err), check.Equals, exitCode, check.Commentf("error: %v", err))
+	}
+}
+
+func (s *ExitTestSuite) TestNotFoundExitCode(c *check.C) {
+	app := cli.NewApp()
+	app.Commands = []cli.Command{InspectCommand()}
+	err := app.Run([]string{"kontainer-engine", "inspect", "missing"})
+	c.Assert(err, check.ErrorMatches, "cluster missing can't be found")
+	c.Assert(ExitCode(err), check.Equals, ExitNotFound)
+}
+
+func (s *ExitTestSuite) TestMissingArgumentExitCodes(c *check.C) {
+	oldArgs := os.Args
+	defer func() {
+		os.Args = oldArgs
+	}()
+	for args, message := range map[string]string{
+		"config resolve":    "cluster name is required",
+		"credential add":    "profile name is required",
+		"credential remove": "profile name is required",
+		"env":               "cluster name is required",
+		"kubeconfig":        "cluster name is required",
+		"rm":                "cluster name is required",
+		"rm --dry-run":      "cluster name is required",
+		"update":            "cluster name is required",
+	} {
+		os.Args = append([]string{"kontainer-engine"}, strings.Fields(args)...)
+		app := newTestApp()
+		app.Commands = []cli.Command{ConfigCommand(), CredentialCommand(), EnvCommand(), KubeConfigCommand(), RmCommand(), UpdateCommand()}
+		var err error
+		captureStdout(c, func() {
+			err = app.Run(os.Args)
+		})
+		// the help is printed, but a missing argument is a usage error
+		c.Assert(err, check.ErrorMatches, message, check.Commentf("%s", args))
+		c.Assert(ExitCode(err), check.Equals, ExitUsage, check.Commentf("%s", args))
+	}
+}
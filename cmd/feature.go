@@ -0,0 +1,14 @@
+package cmd
+
+import (
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/urfave/cli"
+)
+
+var featureFlag = cli.StringSliceFlag{
+	Name:  "feature",
+	Usage: "Turn a feature gate of the provider on or off as NAME=on|off, e.g. kubernetes-alpha=on, passed to the feature option of the driver whatever its name. Repeat the flag for several features",
+}
+
+// features maps --feature to the feature options of the driver
+var features = toggleOptions{flag: featureFlag, kind: "feature", prefix: rpcDriver.FeatureCanonicalPrefix}
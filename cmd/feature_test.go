@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/rancher/kontainer-engine/driver/gke"
+	"gopkg.in/check.v1"
+)
+
+type FeatureTestSuite struct{}
+
+var _ = check.Suite(&FeatureTestSuite{})
+
+func (s *FeatureTestSuite) TestMapToDriverOptions(c *check.C) {
+	driverOptions, err := resolveDriverOptions([]string{"--feature", "kubernetes-alpha=on", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.IsNil)
+	c.Assert(driverOptions.BoolOptions["enable-alpha-feature"], check.Equals, true)
+	c.Assert(driverOptions.IsSet("enable-alpha-feature"), check.Equals, true)
+
+	driverOptions, err = resolveDriverOptions([]string{"--feature", "kubernetes-alpha=off", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.IsNil)
+	c.Assert(driverOptions.BoolOptions["enable-alpha-feature"], check.Equals, false)
+	c.Assert(driverOptions.IsSet("enable-alpha-feature"), check.Equals, true)
+
+	_, err = resolveDriverOptions([]string{"--feature", "kubernetes-alpha=off", "--enable-alpha-feature", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.ErrorMatches, "--feature kubernetes-alpha=off and --enable-alpha-feature are set to different values")
+	c.Assert(ExitCode(err), check.Equals, ExitUsage)
+	_, err = resolveDriverOptions([]string{"--feature", "kubernetes-alpha=on", "--feature", "kubernetes-alpha=off", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.ErrorMatches, "feature kubernetes-alpha is turned both on and off")
+
+	gkeFlags, err := gke.NewDriver().GetDriverCreateOptions()
+	c.Assert(err, check.IsNil)
+	driverOptions, err = resolveDriverOptions([]string{"--feature", "kubernetes-alpha=on", "foo"}, *gkeFlags)
+	c.Assert(err, check.IsNil)
+	c.Assert(driverOptions.BoolOptions["enable-alpha-feature"], check.Equals, true)
+}
+
+func (s *FeatureTestSuite) TestValidateFeature(c *check.C) {
+	_, err := resolveDriverOptions([]string{"--feature", "kubernetes-alpha=true", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.ErrorMatches, `invalid feature "kubernetes-alpha=true", the value must be on or off`)
+	c.Assert(ExitCode(err), check.Equals, ExitValidation)
+
+	_, err = resolveDriverOptions([]string{"--feature", "preview-api=on", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.ErrorMatches, "the driver has no preview-api feature, its features are kubernetes-alpha")
+	c.Assert(ExitCode(err), check.Equals, ExitValidation)
+
+	// an addon isn't a feature
+	_, err = resolveDriverOptions([]string{"--feature", "dashboard=on", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.ErrorMatches, "the driver has no dashboard feature, its features are kubernetes-alpha")
+
+	_, err = resolveDriverOptions([]string{"--feature", "kubernetes-alpha=on", "foo"}, rpcDriver.DriverFlags{Options: map[string]*rpcDriver.Flag{
+		"region": {Type: rpcDriver.StringType},
+	}})
+	c.Assert(err, check.ErrorMatches, "the driver has no kubernetes-alpha feature, its features are none")
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	"github.com/urfave/cli"
+)
+
+const (
+	generatedSuffixLength   = 5
+	generatedSuffixAlphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+	// generateNameAttempts is how many names are tried before giving up on finding one that isn't used
+	generateNameAttempts = 10
+)
+
+var generateNameFlag = cli.StringFlag{
+	Name:  "generate-name",
+	Usage: "Generate a unique cluster name from this prefix and a random suffix when no cluster name is given, e.g. ci- gives ci-x7k2p",
+}
+
+// randomSuffix returns the random part of a generated cluster name, tests replace it to collide with existing clusters
+var randomSuffix = func() (string, error) {
+	suffix := make([]byte, generatedSuffixLength)
+	for i := range suffix {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(generatedSuffixAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		suffix[i] = generatedSuffixAlphabet[n.Int64()]
+	}
+	return string(suffix), nil
+}
+
+// generateClusterName returns prefix followed by a random suffix, trying another suffix while the name is used by a
+// cluster of store
+func generateClusterName(store cluster.PersistStore, prefix string) (string, error) {
+	for i := 0; i < generateNameAttempts; i++ {
+		suffix, err := randomSuffix()
+		if err != nil {
+			return "", err
+		}
+		name := prefix + suffix
+		state, err := store.Check(name)
+		if err != nil {
+			return "", err
+		}
+		if state == cluster.StateNotFound {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("failed to generate a cluster name from prefix %q that isn't used after %d attempts", prefix, generateNameAttempts)
+}
+
+// generatedClusterName returns the cluster name generated from --generate-name if no cluster name is given, name otherwise.
+// The generated name is printed to stderr so that it is seen before the create output.
+func generatedClusterName(ctx *cli.Context, store cluster.PersistStore, name string) (string, error) {
+	prefix := ctx.String(generateNameFlag.Name)
+	if name != "" || !ctx.IsSet(generateNameFlag.Name) {
+		return name, nil
+	}
+	name, err := generateClusterName(store, prefix)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(os.Stderr, "Creating cluster %s\n", name)
+	return name, nil
+}
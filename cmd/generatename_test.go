@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+
+	"github.com/rancher/kontainer-engine/store"
+	"gopkg.in/check.v1"
+)
+
+type GenerateNameTestSuite struct {
+	tempHomeSuite
+	oldArgs         []string
+	oldRandomSuffix func() (string, error)
+}
+
+var _ = check.Suite(&GenerateNameTestSuite{})
+
+func (s *GenerateNameTestSuite) SetUpTest(c *check.C) {
+	s.tempHomeSuite.SetUpTest(c)
+	s.oldArgs = os.Args
+	s.oldRandomSuffix = randomSuffix
+}
+
+func (s *GenerateNameTestSuite) TearDownTest(c *check.C) {
+	os.Args = s.oldArgs
+	randomSuffix = s.oldRandomSuffix
+	s.tempHomeSuite.TearDownTest(c)
+}
+
+// suffixes makes randomSuffix return values in turn, repeating the last one
+func suffixes(values ...string) func() (string, error) {
+	return func() (string, error) {
+		value := values[0]
+		if len(values) > 1 {
+			values = values[1:]
+		}
+		return value, nil
+	}
+}
+
+func (s *GenerateNameTestSuite) TestGeneratedNameAvoidsExistingCluster(c *check.C) {
+	c.Assert(cliPersistStore{}.Store(migratedCluster("ci-aaaaa", "ci-aaaaa.mock.local")), check.IsNil)
+
+	// the first suffix collides with the existing cluster, the next one is used
+	randomSuffix = suffixes("aaaaa", "bbbbb")
+	name, err := generateClusterName(cliPersistStore{}, "ci-")
+	c.Assert(err, check.IsNil)
+	c.Assert(name, check.Equals, "ci-bbbbb")
+
+	randomSuffix = suffixes("aaaaa")
+	_, err = generateClusterName(cliPersistStore{}, "ci-")
+	c.Assert(err, check.ErrorMatches, `failed to generate a cluster name from prefix "ci-" that isn't used after 10 attempts`)
+}
+
+func (s *GenerateNameTestSuite) TestRandomSuffix(c *check.C) {
+	first, err := randomSuffix()
+	c.Assert(err, check.IsNil)
+	c.Assert(first, check.Matches, "[a-z0-9]{5}")
+	second, err := randomSuffix()
+	c.Assert(err, check.IsNil)
+	c.Assert(second, check.Not(check.Equals), first)
+}
+
+func (s *GenerateNameTestSuite) TestCreateWithGeneratedName(c *check.C) {
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "--generate-name", "tmp-"}
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+	clusters, err := store.GetAllClusterFromStore()
+	c.Assert(err, check.IsNil)
+	c.Assert(clusters, check.HasLen, 1)
+	for name, cls := range clusters {
+		c.Assert(strings.HasPrefix(name, "tmp-"), check.Equals, true, check.Commentf("name %s", name))
+		c.Assert(name, check.HasLen, len("tmp-")+generatedSuffixLength)
+		c.Assert(cls.Endpoint, check.Equals, name+".mock.local")
+	}
+
+	// an explicit name is kept
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "--generate-name", "tmp-", "explicit"}
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+	_, err = cliPersistStore{}.Get("explicit")
+	c.Assert(err, check.IsNil)
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/urfave/cli"
+)
+
+// flagsHelp is the help of a command printed with --help --output json, so that tools can render a form for it
+type flagsHelp struct {
+	Command string     `json:"command"`
+	Driver  string     `json:"driver"`
+	Flags   []flagHelp `json:"flags"`
+}
+
+// flagHelp describes a single flag of a command
+type flagHelp struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Default  string `json:"default"`
+	Usage    string `json:"usage"`
+	Required bool   `json:"required"`
+	// EnvVar is the environment variable used when the flag isn't set
+	EnvVar string `json:"envVar,omitempty"`
+	// DriverOption is set for the flags declared by the driver
+	DriverOption bool `json:"driverOption"`
+	// Canonical is the driver independent name of a driver option, e.g. kubernetes-version
+	Canonical string `json:"canonical,omitempty"`
+	// Secret is set for the driver options declared secret, their default is left out
+	Secret bool `json:"secret,omitempty"`
+}
+
+// helpRequested reports whether the arguments ask for the help of the command
+func helpRequested() bool {
+	for _, arg := range os.Args {
+		if arg == "--help" || arg == "-h" {
+			return true
+		}
+	}
+	return false
+}
+
+// writeCreateHelp writes the flags of create followed by the create flags of the driver sorted by name as json
+func writeCreateHelp(w io.Writer, driverName string, driverFlags rpcDriver.DriverFlags) error {
+	help := flagsHelp{
+		Command: "create",
+		Driver:  driverName,
+		Flags:   []flagHelp{},
+	}
+	for _, flag := range CreateCommand().Flags {
+		entry := newFlagHelp(flag)
+		// the driver is the only required flag of create, the cluster name is an argument
+		entry.Required = entry.Name == "driver"
+		help.Flags = append(help.Flags, entry)
+	}
+	driverHelp := []flagHelp{}
+	for _, flag := range getDriverFlags(driverFlags) {
+		entry := newFlagHelp(flag)
+		entry.Required = driverFlags.Options[entry.Name].Required
+		entry.Canonical = driverFlags.Options[entry.Name].Canonical
+		entry.Secret = driverFlags.Options[entry.Name].Secret
+		entry.DriverOption = true
+		driverHelp = append(driverHelp, entry)
+	}
+	sort.Slice(driverHelp, func(i, j int) bool {
+		return driverHelp[i].Name < driverHelp[j].Name
+	})
+	help.Flags = append(help.Flags, driverHelp...)
+
+	data, err := json.MarshalIndent(help, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+func newFlagHelp(flag cli.Flag) flagHelp {
+	// the name of a flag can list its aliases, e.g. "file,f"
+	help := flagHelp{Name: strings.Split(flag.GetName(), ",")[0]}
+	switch f := flag.(type) {
+	case cli.StringFlag:
+		help.Type, help.Default, help.Usage, help.EnvVar = rpcDriver.StringType, f.Value, f.Usage, f.EnvVar
+	case cli.BoolFlag:
+		help.Type, help.Default, help.Usage, help.EnvVar = rpcDriver.BoolType, "false", f.Usage, f.EnvVar
+	case cli.Int64Flag:
+		help.Type, help.Default, help.Usage, help.EnvVar = rpcDriver.IntType, strconv.FormatInt(f.Value, 10), f.Usage, f.EnvVar
+	case cli.StringSliceFlag:
+		help.Type, help.Usage, help.EnvVar = rpcDriver.StringSliceType, f.Usage, f.EnvVar
+	}
+	return help
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"gopkg.in/check.v1"
+)
+
+type HelpTestSuite struct {
+}
+
+var _ = check.Suite(&HelpTestSuite{})
+
+func (s *HelpTestSuite) TestCreateHelpJSON(c *check.C) {
+	driverFlags := mockCreateFlags(c)
+	// a driver can require an option
+	driverFlags.Options["credential"].Required = true
+
+	buf := &bytes.Buffer{}
+	c.Assert(writeCreateHelp(buf, "mock", driverFlags), check.IsNil)
+	help := map[string]interface{}{}
+	c.Assert(json.Unmarshal(buf.Bytes(), &help), check.IsNil)
+	c.Assert(help["command"], check.Equals, "create")
+	c.Assert(help["driver"], check.Equals, "mock")
+
+	flags := help["flags"].([]interface{})
+	byName := map[string]map[string]interface{}{}
+	names := []string{}
+	for _, flag := range flags {
+		flag := flag.(map[string]interface{})
+		for _, key := range []string{"name", "type", "default", "usage", "required", "driverOption"} {
+			_, ok := flag[key]
+			c.Assert(ok, check.Equals, true, check.Commentf("flag %v has no %s", flag["name"], key))
+		}
+		name := flag["name"].(string)
+		byName[name] = flag
+		if flag["driverOption"] == true {
+			names = append(names, name)
+		}
+	}
+	c.Assert(len(flags), check.Equals, len(CreateCommand().Flags)+len(driverFlags.Options))
+	// the driver flags come after the create flags, sorted by name
+	c.Assert(flags[0].(map[string]interface{})["name"], check.Equals, "driver")
+	c.Assert(names, check.DeepEquals, []string{"credential", "dashboard", "description", "enable-alpha-feature", "identity-audiences", "identity-client-id",
+		"identity-groups-claim", "identity-issuer", "identity-username-claim", "labels", "monitoring", "node-count", "registry-auth", "subnet-id", "taints",
+		"upgrade-blackouts", "upgrade-window", "version", "vpc-id"})
+
+	c.Assert(byName["driver"]["required"], check.Equals, true)
+	c.Assert(byName["driver"]["driverOption"], check.Equals, false)
+	c.Assert(byName["node-count"], check.DeepEquals, map[string]interface{}{
+		"name":         "node-count",
+		"type":         rpcDriver.IntType,
+		"default":      "1",
+		"usage":        "The number of nodes to create in this cluster",
+		"required":     false,
+		"envVar":       "KE_DRIVER_NODE_COUNT",
+		"driverOption": true,
+	})
+	c.Assert(byName["credential"]["required"], check.Equals, true)
+	c.Assert(byName["version"]["canonical"], check.Equals, rpcDriver.KubernetesVersionCanonical)
+	c.Assert(byName["labels"]["type"], check.Equals, rpcDriver.StringSliceType)
+	c.Assert(byName["enable-alpha-feature"]["default"], check.Equals, "false")
+	c.Assert(byName["kubeconfig-api-version"]["default"], check.Equals, defaultKubeConfigAPIVersion)
+}
+
+func (s *HelpTestSuite) TestSecretOptionHelp(c *check.C) {
+	buf := &bytes.Buffer{}
+	c.Assert(writeCreateHelp(buf, "mock", mockCreateFlags(c)), check.IsNil)
+	help := flagsHelp{}
+	c.Assert(json.Unmarshal(buf.Bytes(), &help), check.IsNil)
+	for _, flag := range help.Flags {
+		if flag.Name == "registry-auth" {
+			c.Assert(flag.Secret, check.Equals, true)
+			c.Assert(flag.Default, check.Equals, "")
+		}
+	}
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	buf.Reset()
+	app := newTestApp()
+	app.Writer = buf
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "--help"}
+	c.Assert(app.Run(os.Args), check.IsNil)
+	c.Assert(buf.String(), check.Matches, `(?s).*--registry-auth value +The auth .* \(secret, its default isn't shown\).*`)
+	c.Assert(buf.String(), check.Not(check.Matches), "(?s).*mock-registry-auth-d3f4.*")
+}
+
+func (s *HelpTestSuite) TestCreateHelpWhenDriverDiscoveryFails(c *check.C) {
+	oldArgs, oldDiscover := os.Args, discoverCreateOptions
+	defer func() {
+		os.Args, discoverCreateOptions = oldArgs, oldDiscover
+	}()
+	discoverCreateOptions = func(driverName string) (rpcDriver.DriverFlags, string, error) {
+		return rpcDriver.DriverFlags{}, "", errors.New("plugin crashed")
+	}
+
+	buf := &bytes.Buffer{}
+	app := newTestApp()
+	app.Writer = buf
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "--help"}
+	err := app.Run(os.Args)
+	c.Assert(err, check.ErrorMatches, "failed to get the options of driver mock, the help above lists the create flags without them: "+
+		"plugin crashed. Run 'kontainer-engine doctor --driver mock' to check the driver")
+	c.Assert(ExitCode(err), check.Equals, ExitDriverFailure)
+	// the base flags of create are listed
+	c.Assert(buf.String(), check.Matches, "(?s).*--driver value.*--interactive.*")
+	c.Assert(buf.String(), check.Not(check.Matches), "(?s).*--node-count.*")
+
+	// an unknown driver is a usage error, the driver isn't run
+	buf.Reset()
+	app = newTestApp()
+	app.Writer = buf
+	os.Args = []string{"kontainer-engine", "create", "--driver", "unknown", "foo"}
+	err = app.Run(os.Args)
+	c.Assert(err, check.ErrorMatches, "driver unknown is not supported, 'kontainer-engine drivers' lists the supported drivers")
+	c.Assert(ExitCode(err), check.Equals, ExitUsage)
+	c.Assert(buf.String(), check.Matches, "(?s).*--driver value.*")
+}
+
+func (s *HelpTestSuite) TestErrorHelpWithJSONOutput(c *check.C) {
+	oldArgs, oldDiscover := os.Args, discoverCreateOptions
+	defer func() {
+		os.Args, discoverCreateOptions = oldArgs, oldDiscover
+	}()
+	discoverCreateOptions = func(driverName string) (rpcDriver.DriverFlags, string, error) {
+		return rpcDriver.DriverFlags{}, "", errors.New("plugin crashed")
+	}
+
+	for args, expected := range map[string]string{
+		"create --driver unknown foo": "driver unknown is not supported, 'kontainer-engine drivers' lists the supported drivers",
+		"create --driver mock foo":    "failed to get the options of driver mock: plugin crashed. Run 'kontainer-engine doctor --driver mock' to check the driver",
+	} {
+		os.Args = append([]string{"kontainer-engine", "--output", "json"}, strings.Fields(args)...)
+		// stdout is the help and the error main reports, without the help it is only the json error
+		output := captureStdout(c, func() {
+			ReportError(OutputJSON, newTestApp().Run(os.Args))
+		})
+		decoder := json.NewDecoder(strings.NewReader(output))
+		reported := jsonError{}
+		c.Assert(decoder.Decode(&reported), check.IsNil, check.Commentf("%s: %s", args, output))
+		c.Assert(reported.Error, check.Equals, expected)
+		c.Assert(decoder.Decode(&reported), check.Equals, io.EOF)
+	}
+}
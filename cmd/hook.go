@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	"github.com/rancher/kontainer-engine/utils"
+	"github.com/urfave/cli"
+)
+
+const hookEnvPrefix = "KONTAINER_ENGINE_"
+
+var (
+	postCreateHookFlag = cli.StringFlag{
+		Name:  "post-create-hook",
+		Usage: "A shell command run once the cluster is created, with KUBECONFIG and the cluster info in its environment",
+	}
+	hookRequiredFlag = cli.BoolFlag{
+		Name:  "hook-required",
+		Usage: "Fail the command if the post-create hook fails, the created cluster is kept either way",
+	}
+)
+
+// postCreate runs the post-create hook of a created cluster. A failing hook is only reported unless --hook-required is set
+func postCreate(operation context.Context, ctx *cli.Context, cls cluster.Cluster, kubeConfig kubeConfigOptions) error {
+	hook := ctx.String(postCreateHookFlag.Name)
+	if hook == "" {
+		return nil
+	}
+	kubeConfigPath, cleanup, err := hookKubeConfig(ctx, cls, kubeConfig)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	// the output of the hook goes to stderr so that the output of create can still be parsed
+	exitCode, err := runHook(hook, cls, kubeConfigPath, os.Stderr, os.Stderr)
+	if err == nil && exitCode != 0 {
+		err = fmt.Errorf("post-create hook of cluster %s exited with code %d", cls.Name, exitCode)
+	}
+	if err == nil {
+		return nil
+	}
+	if ctx.Bool(hookRequiredFlag.Name) {
+		return err
+	}
+	utils.OperationLogger(operation).Warnf("%v, the cluster is created anyway", err)
+	return nil
+}
+
+// hookKubeConfig returns the path of a kubeconfig with the created cluster for the hook: the --kubeconfig-out file, the
+// kubeconfig file unless create leaves it alone, or else a temporary file that cleanup removes
+func hookKubeConfig(ctx *cli.Context, cls cluster.Cluster, kubeConfig kubeConfigOptions) (string, func(), error) {
+	cleanup := func() {}
+	if path := ctx.String(kubeConfigOutFlag.Name); path != "" {
+		path, err := filepath.Abs(path)
+		return path, cleanup, err
+	}
+	if !kubeConfig.skip && !ctx.Bool(noStoreFlag.Name) {
+		return utils.KubeConfigFilePath(), cleanup, nil
+	}
+	file, err := ioutil.TempFile("", "kubeconfig")
+	if err != nil {
+		return "", cleanup, err
+	}
+	cleanup = func() { os.Remove(file.Name()) }
+	err = writeKubeConfig(file, cls, kubeConfig)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return "", func() {}, fmt.Errorf("failed to write the kubeconfig of the post-create hook of cluster %s: %v", cls.Name, err)
+	}
+	return file.Name(), cleanup, nil
+}
+
+// runHook runs hook in a shell with the environment of the cluster and KUBECONFIG set to kubeConfigPath, it returns
+// the exit code of the hook. The error is only set if the hook couldn't be run at all.
+func runHook(hook string, cls cluster.Cluster, kubeConfigPath string, stdout, stderr io.Writer) (int, error) {
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	command := exec.Command(shell, flag, hook)
+	command.Env = append(os.Environ(), hookEnv(cls, kubeConfigPath)...)
+	command.Stdout = stdout
+	command.Stderr = stderr
+	err := command.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+			return status.ExitStatus(), nil
+		}
+		return 1, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to run post-create hook of cluster %s: %v", cls.Name, err)
+	}
+	return 0, nil
+}
+
+// hookEnv returns the environment variables describing cls, its metadata keys are upper cased with '_' for any other character than letters and digits
+func hookEnv(cls cluster.Cluster, kubeConfigPath string) []string {
+	env := []string{
+		"KUBECONFIG=" + kubeConfigPath,
+		hookEnvPrefix + "CLUSTER_NAME=" + cls.Name,
+		hookEnvPrefix + "DRIVER=" + cls.DriverName,
+		hookEnvPrefix + "ENDPOINT=" + kubeConfigEndpoint(cls),
+		hookEnvPrefix + "VERSION=" + cls.Version,
+		hookEnvPrefix + "NODE_COUNT=" + strconv.FormatInt(cls.NodeCount, 10),
+	}
+	keys := []string{}
+	for k := range cls.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = append(env, hookEnvPrefix+"METADATA_"+envName(k)+"="+cls.Metadata[k])
+	}
+	return env
+}
+
+func envName(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, key)
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	"github.com/rancher/kontainer-engine/utils"
+	"github.com/urfave/cli"
+	"gopkg.in/check.v1"
+)
+
+type HookTestSuite struct {
+	tempHomeSuite
+}
+
+var _ = check.Suite(&HookTestSuite{})
+
+var hookCluster = cluster.Cluster{
+	Name:       "foo",
+	DriverName: "mock",
+	Endpoint:   "foo.mock.local",
+	Version:    "v1.8.4",
+	NodeCount:  3,
+	Metadata: map[string]string{
+		"project-id": "test",
+		"zone":       "us-central1-a",
+	},
+}
+
+func hookContext(c *check.C, args ...string) *cli.Context {
+	set := flag.NewFlagSet("create", flag.ContinueOnError)
+	for _, f := range []cli.Flag{postCreateHookFlag, hookRequiredFlag, kubeConfigOutFlag, noKubeConfigFlag, noStoreFlag} {
+		f.Apply(set)
+	}
+	c.Assert(set.Parse(args), check.IsNil)
+	return cli.NewContext(nil, set, nil)
+}
+
+func (s *HookTestSuite) TestRunHookEnvironment(c *check.C) {
+	stdout := &bytes.Buffer{}
+	exitCode, err := runHook(`env | grep -E '^(KUBECONFIG|KONTAINER_ENGINE_)' | sort`, hookCluster, utils.KubeConfigFilePath(), stdout, stdout)
+	c.Assert(err, check.IsNil)
+	c.Assert(exitCode, check.Equals, 0)
+	c.Assert(strings.Split(strings.TrimSpace(stdout.String()), "\n"), check.DeepEquals, []string{
+		"KONTAINER_ENGINE_CLUSTER_NAME=foo",
+		"KONTAINER_ENGINE_DRIVER=mock",
+		"KONTAINER_ENGINE_ENDPOINT=foo.mock.local",
+		"KONTAINER_ENGINE_METADATA_PROJECT_ID=test",
+		"KONTAINER_ENGINE_METADATA_ZONE=us-central1-a",
+		"KONTAINER_ENGINE_NODE_COUNT=3",
+		"KONTAINER_ENGINE_VERSION=v1.8.4",
+		"KUBECONFIG=" + utils.KubeConfigFilePath(),
+	})
+}
+
+func (s *HookTestSuite) TestRunHookExitCode(c *check.C) {
+	stdout := &bytes.Buffer{}
+	exitCode, err := runHook("echo failing >&2; exit 3", hookCluster, utils.KubeConfigFilePath(), stdout, stdout)
+	c.Assert(err, check.IsNil)
+	c.Assert(exitCode, check.Equals, 3)
+	c.Assert(stdout.String(), check.Equals, "failing\n")
+}
+
+func (s *HookTestSuite) TestPostCreateHookRequired(c *check.C) {
+	// a failing hook is only reported by default
+	c.Assert(postCreate(context.Background(), hookContext(c, "--post-create-hook", "exit 3"), hookCluster, kubeConfigOptions{}), check.IsNil)
+	c.Assert(postCreate(context.Background(), hookContext(c, "--post-create-hook", "exit 3", "--hook-required"), hookCluster, kubeConfigOptions{}),
+		check.ErrorMatches, "post-create hook of cluster foo exited with code 3")
+	c.Assert(postCreate(context.Background(), hookContext(c, "--post-create-hook", "true", "--hook-required"), hookCluster, kubeConfigOptions{}), check.IsNil)
+	c.Assert(postCreate(context.Background(), hookContext(c), hookCluster, kubeConfigOptions{}), check.IsNil)
+}
+
+func (s *HookTestSuite) TestHookKubeConfig(c *check.C) {
+	dir := c.MkDir()
+	seen := filepath.Join(dir, "seen")
+	hook := `echo "$KUBECONFIG" > ` + seen + `; cat "$KUBECONFIG" >> ` + seen
+	cls := migratedCluster("hooked", "1.1.1.1")
+
+	// the hook gets the --kubeconfig-out file
+	out := filepath.Join(dir, "out.yaml")
+	c.Assert(ioutil.WriteFile(out, []byte("written by create"), 0600), check.IsNil)
+	ctx := hookContext(c, "--post-create-hook", hook, "--hook-required", "--kubeconfig-out", out)
+	c.Assert(postCreate(context.Background(), ctx, cls, kubeConfigOptions{}), check.IsNil)
+	data, err := ioutil.ReadFile(seen)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, out+"\nwritten by create")
+
+	// with --no-kubeconfig the kubeconfig file doesn't have the cluster, the hook gets a temporary one that has it
+	ctx = hookContext(c, "--post-create-hook", hook, "--hook-required", "--no-kubeconfig")
+	c.Assert(postCreate(context.Background(), ctx, cls, kubeConfigOptions{skip: true}), check.IsNil)
+	data, err = ioutil.ReadFile(seen)
+	c.Assert(err, check.IsNil)
+	lines := strings.SplitN(string(data), "\n", 2)
+	c.Assert(lines[0], check.Not(check.Equals), utils.KubeConfigFilePath())
+	c.Assert(lines[1], check.Matches, `(?s).*server: https://1\.1\.1\.1.*current-context: hooked.*`)
+	_, err = os.Stat(lines[0])
+	c.Assert(os.IsNotExist(err), check.Equals, true)
+}
@@ -2,11 +2,9 @@ package cmd
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"os"
 
-	"github.com/rancher/kontainer-engine/store"
 	"github.com/urfave/cli"
 )
 
@@ -24,7 +22,7 @@ func InspectCommand() cli.Command {
 		Name:               "inspect",
 		Usage:              "inspect kubernetes clusters",
 		Action:             inspectCluster,
-		Flags:              []cli.Flag{},
+		Flags:              []cli.Flag{asCommandFlag},
 		CustomHelpTemplate: inspectHelpTemplate,
 	}
 }
@@ -32,20 +30,25 @@ func InspectCommand() cli.Command {
 func inspectCluster(ctx *cli.Context) error {
 	name := ctx.Args().Get(0)
 	if name == "" {
-		return errors.New("name is required when inspecting cluster")
+		return newUsageError("name is required when inspecting cluster")
 	}
-	clusters, err := store.GetAllClusterFromStore()
+	clusters, err := getAllClusters()
 	if err != nil {
 		return err
 	}
 	cluster, ok := clusters[name]
 	if !ok {
-		return fmt.Errorf("cluster %v can't be found", name)
+		return newNotFoundError("cluster %v can't be found", name)
 	}
-	cluster.ClientKey = "Redacted"
-	cluster.ClientCertificate = "Redacted"
-	cluster.RootCACert = "Redacted"
-	data, err := json.MarshalIndent(cluster, "", "\t")
+	if ctx.Bool(asCommandFlag.Name) {
+		args, err := createCommandArgs(cluster)
+		if err != nil {
+			return err
+		}
+		fmt.Println(shellJoin(args))
+		return nil
+	}
+	data, err := json.MarshalIndent(redactCluster(cluster), "", "\t")
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/urfave/cli"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+var (
+	interactiveFlag = cli.BoolFlag{
+		Name:  "interactive",
+		Usage: "Prompt for the driver options and the cluster name that aren't given on the command line",
+	}
+	// newPrompter returns the prompter of create --interactive, tests replace it with a scripted one
+	newPrompter = newTerminalPrompter
+)
+
+// prompter asks for the values of the options
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+	// readSecret reads a line without echoing it
+	readSecret func() (string, error)
+}
+
+// newTerminalPrompter prompts on stderr so that the output of create stays on stdout, the secrets typed in a
+// terminal aren't echoed
+func newTerminalPrompter() *prompter {
+	p := &prompter{
+		in:  bufio.NewReader(os.Stdin),
+		out: os.Stderr,
+	}
+	p.readSecret = func() (string, error) {
+		fd := int(os.Stdin.Fd())
+		if !terminal.IsTerminal(fd) {
+			return p.readLine()
+		}
+		data, err := terminal.ReadPassword(fd)
+		fmt.Fprintln(p.out)
+		return string(data), err
+	}
+	return p
+}
+
+// interactiveRequested tells whether --interactive is in the arguments before they are parsed
+func interactiveRequested() bool {
+	for _, arg := range os.Args {
+		if arg == "--"+interactiveFlag.Name || arg == "--"+interactiveFlag.Name+"=true" {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *prompter) readLine() (string, error) {
+	line, err := p.in.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+// prompt asks for a value until read returns a valid one, empty if it is skipped
+func (p *prompter) prompt(question string, read func() (string, error), validate func(string) error) (string, error) {
+	for {
+		fmt.Fprint(p.out, question)
+		value, err := read()
+		if err == io.EOF {
+			return "", newUsageError("the input ended before all the options were given")
+		} else if err != nil {
+			return "", err
+		}
+		value = strings.TrimSpace(value)
+		if err := validate(value); err != nil {
+			fmt.Fprintf(p.out, "  %v\n", err)
+			continue
+		}
+		return value, nil
+	}
+}
+
+// interactiveArgs prompts for the driver options and the cluster name missing from the create arguments in args and
+// returns args with them. A driver option is missing if it is neither given as a flag nor in its environment variable.
+func interactiveArgs(p *prompter, args []string, driverFlags rpcDriver.DriverFlags) ([]string, error) {
+	command := -1
+	for i, arg := range args {
+		if arg == "create" {
+			command = i
+			break
+		}
+	}
+	if command < 0 {
+		return args, nil
+	}
+	createArgs := args[command+1:]
+	set := flag.NewFlagSet("create", flag.ContinueOnError)
+	set.SetOutput(ioutil.Discard)
+	for _, f := range append(CreateCommand().Flags, getDriverFlags(driverFlags)...) {
+		f.Apply(set)
+	}
+	if err := set.Parse(createArgs); err != nil {
+		return nil, newUsageError("%v", err)
+	}
+	given := map[string]bool{}
+	set.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+	if given[credentialStdinFlag.Name] {
+		return nil, newUsageError("--%s reads the answers from stdin, it can't be used with --%s", interactiveFlag.Name, credentialStdinFlag.Name)
+	}
+
+	names := []string{}
+	for name := range driverFlags.Options {
+		if !given[name] && (driverOptionEnv(name) == "" || os.Getenv(driverOptionEnv(name)) == "") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	prompted := []string{}
+	for _, name := range names {
+		values, err := p.promptOption(name, driverFlags.Options[name])
+		if err != nil {
+			return nil, err
+		}
+		prompted = append(prompted, values...)
+	}
+
+	positional := set.Args()
+	if len(positional) == 0 && !given[generateNameFlag.Name] {
+		name, err := p.prompt("cluster name: ", p.readLine, func(value string) error {
+			if value == "" {
+				return fmt.Errorf("the cluster name is required")
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		positional = []string{name}
+	}
+
+	result := append([]string{}, args[:command+1]...)
+	result = append(result, createArgs[:len(createArgs)-len(set.Args())]...)
+	result = append(result, prompted...)
+	return append(result, positional...), nil
+}
+
+// promptOption asks for the value of a driver option and returns its flags, none if the default is kept
+func (p *prompter) promptOption(name string, option *rpcDriver.Flag) ([]string, error) {
+	question := fmt.Sprintf("%s (%s): %s", name, option.Type, option.Usage)
+	switch {
+	case option.Required && option.Value == "":
+		question += " [required]"
+	case option.Type == rpcDriver.StringSliceType:
+		question += " [comma separated, empty to skip]"
+	case option.Secret && option.Value != "":
+		question += " [default hidden]"
+	case option.Value != "":
+		question += fmt.Sprintf(" [default %s]", option.Value)
+	}
+	read := p.readLine
+	if option.Secret || isSecretOption(name) {
+		read = p.readSecret
+	}
+	value, err := p.prompt(question+": ", read, func(value string) error {
+		return validateOptionValue(value, option)
+	})
+	if err != nil || value == "" {
+		return nil, err
+	}
+	if option.Type != rpcDriver.StringSliceType {
+		return []string{fmt.Sprintf("--%s=%s", name, value)}, nil
+	}
+	flags := []string{}
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			flags = append(flags, "--"+name, v)
+		}
+	}
+	return flags, nil
+}
+
+// validateOptionValue checks that a prompted value has the type of option, empty keeps the default
+func validateOptionValue(value string, option *rpcDriver.Flag) error {
+	if value == "" {
+		if option.Required && option.Value == "" {
+			return fmt.Errorf("a value is required")
+		}
+		return nil
+	}
+	switch option.Type {
+	case rpcDriver.IntType:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("%q isn't an integer", value)
+		}
+	case rpcDriver.BoolType:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%q isn't true or false", value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"strings"
+
+	generic "github.com/rancher/kontainer-engine/driver"
+	"gopkg.in/check.v1"
+)
+
+type InteractiveTestSuite struct {
+	tempHomeSuite
+	oldArgs     []string
+	oldPrompter func() *prompter
+}
+
+var _ = check.Suite(&InteractiveTestSuite{})
+
+func (s *InteractiveTestSuite) SetUpTest(c *check.C) {
+	s.tempHomeSuite.SetUpTest(c)
+	s.oldArgs = os.Args
+	s.oldPrompter = newPrompter
+}
+
+func (s *InteractiveTestSuite) TearDownTest(c *check.C) {
+	os.Args = s.oldArgs
+	newPrompter = s.oldPrompter
+	s.tempHomeSuite.TearDownTest(c)
+}
+
+// scriptedPrompter answers the prompts with the lines of input, secrets included, and writes the prompts to out
+func scriptedPrompter(input string, out *bytes.Buffer) *prompter {
+	p := &prompter{
+		in:  bufio.NewReader(strings.NewReader(input)),
+		out: out,
+	}
+	p.readSecret = p.readLine
+	return p
+}
+
+func (s *InteractiveTestSuite) TestPromptDriverOptions(c *check.C) {
+	// credential, dashboard, description, enable-alpha-feature, identity-audiences, identity-client-id,
+	// identity-groups-claim, identity-issuer, identity-username-claim, labels, monitoring, node-count, registry-auth,
+	// subnet-id, taints, upgrade-blackouts, upgrade-window, version, vpc-id then the cluster name
+	input := strings.Join([]string{"s3cret", "", "", "maybe", "true", "", "", "", "", "", "a=b, c=d", "", "x", "3", "", "", "", "", "", "", "", "wizard"}, "\n") + "\n"
+	out := &bytes.Buffer{}
+	args := []string{"kontainer-engine", "--debug", "create", "--driver", "mock", "--interactive"}
+	args, err := interactiveArgs(scriptedPrompter(input, out), args, mockCreateFlags(c))
+	c.Assert(err, check.IsNil)
+	c.Assert(args, check.DeepEquals, []string{"kontainer-engine", "--debug", "create", "--driver", "mock", "--interactive",
+		"--credential=s3cret", "--enable-alpha-feature=true", "--labels", "a=b", "--labels", "c=d", "--node-count=3", "wizard"})
+	c.Assert(out.String(), check.Matches, `(?s)credential \(string\): .*node-count \(int\): The number of nodes to create in this cluster \[default 1\]: .*`)
+	c.Assert(out.String(), check.Matches, `(?s).*"maybe" isn't true or false.*"x" isn't an integer.*cluster name: `)
+}
+
+func (s *InteractiveTestSuite) TestPromptSkipsGivenOptions(c *check.C) {
+	input := strings.Repeat("\n", 18)
+	args := []string{"kontainer-engine", "create", "--driver", "mock", "--interactive", "--node-count", "2", "given"}
+	args, err := interactiveArgs(scriptedPrompter(input, &bytes.Buffer{}), args, mockCreateFlags(c))
+	c.Assert(err, check.IsNil)
+	c.Assert(args, check.DeepEquals, []string{"kontainer-engine", "create", "--driver", "mock", "--interactive", "--node-count", "2", "given"})
+}
+
+func (s *InteractiveTestSuite) TestPromptRequiredOption(c *check.C) {
+	driverFlags := generic.DriverFlags{Options: map[string]*generic.Flag{
+		"project-id": {Type: generic.StringType, Usage: "The project", Required: true},
+	}}
+	out := &bytes.Buffer{}
+	args, err := interactiveArgs(scriptedPrompter("\nmy-project\nname\n", out), []string{"kontainer-engine", "create"}, driverFlags)
+	c.Assert(err, check.IsNil)
+	c.Assert(args, check.DeepEquals, []string{"kontainer-engine", "create", "--project-id=my-project", "name"})
+	c.Assert(out.String(), check.Matches, `(?s)project-id \(string\): The project \[required\]: .*a value is required.*`)
+
+	// the input ending before a required option is given fails the create
+	_, err = interactiveArgs(scriptedPrompter("\n", &bytes.Buffer{}), []string{"kontainer-engine", "create"}, driverFlags)
+	c.Assert(err, check.ErrorMatches, "the input ended before all the options were given")
+	c.Assert(ExitCode(err), check.Equals, ExitUsage)
+}
+
+func (s *InteractiveTestSuite) TestInteractiveCreate(c *check.C) {
+	input := strings.Join([]string{"", "", "from the wizard", "", "", "", "", "", "", "", "", "4", "", "", "", "", "", "", "", "wizard"}, "\n") + "\n"
+	newPrompter = func() *prompter {
+		return scriptedPrompter(input, &bytes.Buffer{})
+	}
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "--interactive"}
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+	cls, err := cliPersistStore{}.Get("wizard")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.NodeCount, check.Equals, int64(4))
+	c.Assert(cls.Options.StringOptions["description"], check.Equals, "from the wizard")
+	// the secret option isn't stored, only that it is secret
+	_, stored := cls.Options.StringOptions["registry-auth"]
+	c.Assert(stored, check.Equals, false)
+	c.Assert(cls.Options.SecretKeys, check.DeepEquals, []string{"credential", "registry-auth"})
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/rancher/kontainer-engine/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// notifyInterrupts calls onInterrupt on the first SIGINT or SIGTERM until stop is called
+func notifyInterrupts(onInterrupt func(os.Signal)) (stop func()) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	stopWatching := watchInterrupts(signals, onInterrupt)
+	return func() {
+		signal.Stop(signals)
+		stopWatching()
+	}
+}
+
+// watchInterrupts runs onInterrupt for the first signal received from signals. The next signals are only reported so
+// that pressing Ctrl-C twice doesn't stop the cleanup halfway.
+func watchInterrupts(signals <-chan os.Signal, onInterrupt func(os.Signal)) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		interrupting := false
+		for {
+			select {
+			case sig := <-signals:
+				if interrupting {
+					logrus.Warnf("received %v again, the create is already being interrupted", sig)
+					continue
+				}
+				interrupting = true
+				go onInterrupt(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+	}
+}
+
+// contextDriver is a driver whose calls are children of a context, e.g. the rpc client
+type contextDriver interface {
+	SetContext(ctx context.Context)
+}
+
+// createWithInterrupts creates cls and handles SIGINT and SIGTERM: the create is canceled, the stored cluster is marked
+// Interrupted and once the create returned the driver removes what it already provisioned. It then returns
+// ErrInterrupted rather than exiting from the signal handler, so that the command exits with ExitInterrupted once the
+// notifications are sent and the store is unlocked.
+func createWithInterrupts(operation context.Context, cls *cluster.Cluster, persistStore cluster.PersistStore) error {
+	ctx, cancel := context.WithCancel(operation)
+	defer cancel()
+	interrupted := make(chan struct{})
+	stop := notifyInterrupts(func(sig os.Signal) {
+		utils.OperationLogger(ctx).Warnf("received %v, interrupting the create of cluster %s", sig, cls.Name)
+		interruptCreate(ctx, cls, persistStore, cancel)
+		close(interrupted)
+	})
+	// the signals are watched until the cleanup is done, so that a second one doesn't stop it halfway
+	defer stop()
+	// the call creating the cluster is canceled with the create, the driver stops creating it before it is removed
+	driver, cancelable := cls.Driver.(contextDriver)
+	if cancelable {
+		driver.SetContext(rpcDriver.WithPolicies(ctx, rpcDriver.PoliciesFrom(driverContext)))
+	}
+	err := cls.CreateContext(ctx)
+	if cancelable {
+		driver.SetContext(driverContext)
+	}
+	if ctx.Err() == nil || operation.Err() != nil {
+		return err
+	}
+	<-interrupted
+	removeInterrupted(ctx, cls)
+	return cluster.ErrInterrupted
+}
+
+// interruptCreate cancels the create of cls and marks the stored cluster Interrupted, the driver cleans up once the
+// create returned
+func interruptCreate(ctx context.Context, cls *cluster.Cluster, persistStore cluster.PersistStore, cancel context.CancelFunc) {
+	cancel()
+	// the create may be blocked in the driver, so the status is written here rather than when the create returns
+	if stored, err := persistStore.Get(cls.Name); err == nil {
+		if err := persistStore.PersistStatus(stored, cluster.Interrupted); err != nil {
+			utils.OperationLogger(ctx).Errorf("failed to persist the interrupted status of cluster %s: %v", cls.Name, err)
+		}
+	}
+}
+
+// removeInterrupted asks the driver to remove what the interrupted create of cls already provisioned
+func removeInterrupted(ctx context.Context, cls *cluster.Cluster) {
+	if err := cls.Driver.Remove(); err != nil {
+		utils.OperationLogger(ctx).Errorf("failed to clean up the interrupted cluster %s, remove it with 'rm --force': %v", cls.Name, err)
+	}
+}
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"gopkg.in/check.v1"
+)
+
+type InterruptTestSuite struct {
+}
+
+var _ = check.Suite(&InterruptTestSuite{})
+
+// interruptingDriver interrupts the create while the driver is creating the cluster
+type interruptingDriver struct {
+	cluster.Driver
+	interrupt  func()
+	removed    bool
+	postChecks int
+}
+
+func (d *interruptingDriver) Create() (rpcDriver.OperationResult, error) {
+	result, err := d.Driver.Create()
+	if err != nil {
+		return result, err
+	}
+	d.interrupt()
+	return result, nil
+}
+
+func (d *interruptingDriver) Remove() error {
+	d.removed = true
+	return d.Driver.Remove()
+}
+
+func (d *interruptingDriver) PostCheck() error {
+	d.postChecks++
+	return d.Driver.PostCheck()
+}
+
+func (s *InterruptTestSuite) TestInterruptMidCreate(c *check.C) {
+	rpcClient, _, err := runRPCDriver("mock")
+	c.Assert(err, check.IsNil)
+	persistStore := newInMemoryPersistStore()
+	driverOptions := newDriverOptions()
+	driverOptions.StringOptions["name"] = "interrupted"
+	driver := &interruptingDriver{Driver: rpcClient}
+	cls := &cluster.Cluster{
+		Name:         "interrupted",
+		DriverName:   "mock",
+		Driver:       driver,
+		PersistStore: persistStore,
+		ConfigGetter: staticConfigGetter{driverOptions},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	driver.interrupt = func() {
+		interruptCreate(ctx, cls, persistStore, cancel)
+	}
+
+	err = cls.CreateContext(ctx)
+	c.Assert(err, check.Equals, cluster.ErrInterrupted)
+	c.Assert(ExitCode(err), check.Equals, ExitInterrupted)
+	// the driver is only asked to clean up once the create returned, by createWithInterrupts
+	c.Assert(driver.removed, check.Equals, false)
+	c.Assert(driver.postChecks, check.Equals, 0)
+	stored, err := persistStore.Get("interrupted")
+	c.Assert(err, check.IsNil)
+	c.Assert(stored.Status, check.Equals, cluster.Interrupted)
+}
+
+func (s *InterruptTestSuite) TestWatchInterruptsHandlesTheFirstSignalOnly(c *check.C) {
+	signals := make(chan os.Signal, 2)
+	interrupts := make(chan os.Signal, 2)
+	stop := watchInterrupts(signals, func(sig os.Signal) {
+		interrupts <- sig
+	})
+	defer stop()
+
+	signals <- os.Interrupt
+	signals <- syscall.SIGTERM
+	c.Assert(<-interrupts, check.Equals, os.Interrupt)
+	select {
+	case sig := <-interrupts:
+		c.Fatalf("the second signal %v was handled too", sig)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func (s *InterruptTestSuite) TestInterruptReturnsThroughMain(c *check.C) {
+	rpcClient, _, err := runRPCDriver("mock")
+	c.Assert(err, check.IsNil)
+	persistStore := newInMemoryPersistStore()
+	driverOptions := newDriverOptions()
+	driverOptions.StringOptions["name"] = "signaled"
+	driver := &interruptingDriver{Driver: rpcClient}
+	cls := &cluster.Cluster{
+		Name:         "signaled",
+		DriverName:   "mock",
+		Driver:       driver,
+		PersistStore: persistStore,
+		ConfigGetter: staticConfigGetter{driverOptions},
+	}
+	driver.interrupt = func() {
+		c.Assert(syscall.Kill(os.Getpid(), syscall.SIGINT), check.IsNil)
+		// wait for the handler to mark the cluster
+		for i := 0; i < 100; i++ {
+			if stored, err := persistStore.Get("signaled"); err == nil && stored.Status == cluster.Interrupted {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// the process doesn't exit from the signal handler, the error is returned to main
+	err = createWithInterrupts(context.Background(), cls, persistStore)
+	c.Assert(err, check.Equals, cluster.ErrInterrupted)
+	c.Assert(ExitCode(err), check.Equals, ExitInterrupted)
+	c.Assert(driver.removed, check.Equals, true)
+	stored, err := persistStore.Get("signaled")
+	c.Assert(err, check.IsNil)
+	c.Assert(stored.Status, check.Equals, cluster.Interrupted)
+}
+
+// blockingDriver creates until the context of its calls is canceled
+type blockingDriver struct {
+	cluster.Driver
+	ctx      context.Context
+	creating chan struct{}
+	// removedWhileCreating is set if the driver was asked to remove the cluster before the create returned
+	removedWhileCreating bool
+	created, removed     bool
+}
+
+func (d *blockingDriver) SetContext(ctx context.Context) {
+	d.ctx = ctx
+}
+
+func (d *blockingDriver) Create() (rpcDriver.OperationResult, error) {
+	close(d.creating)
+	<-d.ctx.Done()
+	d.created = true
+	return rpcDriver.OperationResult{}, d.ctx.Err()
+}
+
+func (d *blockingDriver) Remove() error {
+	d.removed = true
+	d.removedWhileCreating = !d.created
+	return nil
+}
+
+func (s *InterruptTestSuite) TestInterruptCancelsTheDriverCall(c *check.C) {
+	rpcClient, _, err := runRPCDriver("mock")
+	c.Assert(err, check.IsNil)
+	persistStore := newInMemoryPersistStore()
+	driverOptions := newDriverOptions()
+	driverOptions.StringOptions["name"] = "blocked"
+	driver := &blockingDriver{Driver: rpcClient, ctx: context.Background(), creating: make(chan struct{})}
+	cls := &cluster.Cluster{
+		Name:         "blocked",
+		DriverName:   "mock",
+		Driver:       driver,
+		PersistStore: persistStore,
+		ConfigGetter: staticConfigGetter{driverOptions},
+	}
+	go func() {
+		<-driver.creating
+		syscall.Kill(os.Getpid(), syscall.SIGINT)
+	}()
+
+	err = createWithInterrupts(context.Background(), cls, persistStore)
+	c.Assert(err, check.Equals, cluster.ErrInterrupted)
+	// the create returned before the driver cleaned up
+	c.Assert(driver.removed, check.Equals, true)
+	c.Assert(driver.removedWhileCreating, check.Equals, false)
+	stored, err := persistStore.Get("blocked")
+	c.Assert(err, check.IsNil)
+	c.Assert(stored.Status, check.Equals, cluster.Interrupted)
+	// the later calls aren't canceled
+	c.Assert(driver.ctx, check.Equals, driverContext)
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	"github.com/urfave/cli"
+	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// kubeConfigSecretKey is the data key of the kubeconfig in the secret printed by kubeconfig --as-secret
+	kubeConfigSecretKey = "kubeconfig"
+	// defaultSecretNamespace is the namespace of the secret when --namespace isn't given
+	defaultSecretNamespace = "default"
+)
+
+var noKubeConfigFlag = cli.BoolFlag{
+	Name:  "no-kubeconfig",
+	Usage: "Don't write the cluster to the kubeconfig file, 'kubeconfig cluster-name' generates it later",
+}
+
+var kubeConfigOutFlag = cli.StringFlag{
+	Name:  "kubeconfig-out",
+	Usage: "Also write a kubeconfig with only the cluster to this path, e.g. in a shared directory. With --no-kubeconfig it is the only kubeconfig written",
+}
+
+// KubeConfigCommand defines the kubeconfig command
+func KubeConfigCommand() cli.Command {
+	return cli.Command{
+		Name:      "kubeconfig",
+		Usage:     "Print the kubeconfig of a cluster, e.g. one created with --no-kubeconfig",
+		ArgsUsage: "cluster-name",
+		Action:    printKubeConfig,
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "merge",
+				Usage: "Add the cluster to the kubeconfig file instead of printing it",
+			},
+			cli.BoolFlag{
+				Name:  "as-secret",
+				Usage: "Print the kubeconfig wrapped in a kubernetes Secret manifest, under the " + kubeConfigSecretKey + " key, to apply it to another cluster",
+			},
+			cli.StringFlag{
+				Name:  "namespace",
+				Usage: "The namespace of the Secret printed with --as-secret",
+				Value: defaultSecretNamespace,
+			},
+			cli.StringFlag{
+				Name:  "name",
+				Usage: "The name of the Secret printed with --as-secret, defaults to CLUSTER-kubeconfig",
+			},
+		},
+	}
+}
+
+func printKubeConfig(ctx *cli.Context) error {
+	name := ctx.Args().Get(0)
+	if name == "" {
+		return usageErrorWithHelp(ctx, "kubeconfig", "cluster name is required")
+	}
+	clusters, err := getAllClusters()
+	if err != nil {
+		return err
+	}
+	cls, ok := clusters[name]
+	if !ok {
+		return newNotFoundError("cluster %v can't be found", name)
+	}
+	if ctx.Bool("as-secret") {
+		if ctx.Bool("merge") {
+			return newUsageError("--as-secret and --merge can't be used together")
+		}
+		secretName := ctx.String("name")
+		if secretName == "" {
+			secretName = name + "-kubeconfig"
+		}
+		return writeKubeConfigSecret(os.Stdout, cls, ctx.String("namespace"), secretName)
+	}
+	if ctx.Bool("merge") {
+		return storeConfig(cls, kubeConfigOptions{})
+	}
+	return writeKubeConfig(os.Stdout, cls, kubeConfigOptions{})
+}
+
+type secretManifest struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   secretMetadata    `yaml:"metadata"`
+	Type       string            `yaml:"type"`
+	Data       map[string]string `yaml:"data"`
+}
+
+type secretMetadata struct {
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace"`
+}
+
+// writeKubeConfigSecret writes a v1 Secret manifest called name in namespace holding the kubeconfig of c
+func writeKubeConfigSecret(w io.Writer, c cluster.Cluster, namespace, name string) error {
+	if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+		return newValidationError("invalid secret namespace %q: %s", namespace, strings.Join(errs, ", "))
+	}
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return newValidationError("invalid secret name %q: %s", name, strings.Join(errs, ", "))
+	}
+	config := bytes.Buffer{}
+	if err := writeKubeConfig(&config, c, kubeConfigOptions{}); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(secretManifest{
+		APIVersion: "v1",
+		Kind:       "Secret",
+		Metadata:   secretMetadata{Name: name, Namespace: namespace},
+		Type:       "Opaque",
+		Data:       map[string]string{kubeConfigSecretKey: base64.StdEncoding.EncodeToString(config.Bytes())},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
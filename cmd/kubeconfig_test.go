@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/rancher/kontainer-engine/utils"
+	"gopkg.in/check.v1"
+	"gopkg.in/yaml.v2"
+)
+
+type KubeConfigCommandTestSuite struct {
+	tempHomeSuite
+	oldArgs []string
+}
+
+var _ = check.Suite(&KubeConfigCommandTestSuite{})
+
+func (s *KubeConfigCommandTestSuite) SetUpTest(c *check.C) {
+	s.tempHomeSuite.SetUpTest(c)
+	s.oldArgs = os.Args
+}
+
+func (s *KubeConfigCommandTestSuite) TearDownTest(c *check.C) {
+	os.Args = s.oldArgs
+	s.tempHomeSuite.TearDownTest(c)
+}
+
+func (s *KubeConfigCommandTestSuite) TestCreateKubeConfigOut(c *check.C) {
+	out := filepath.Join(utils.HomeDir(), "team", "shared", "foo.yaml")
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "--no-kubeconfig", "--kubeconfig-out", out, "foo"}
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+
+	// the kubeconfig is written to the given path instead of the default one
+	_, err := os.Stat(utils.KubeConfigFilePath())
+	c.Assert(os.IsNotExist(err), check.Equals, true, check.Commentf("kubeconfig written: %v", err))
+	info, err := os.Stat(out)
+	c.Assert(err, check.IsNil)
+	c.Assert(info.Mode().Perm(), check.Equals, os.FileMode(0600))
+	info, err = os.Stat(filepath.Dir(out))
+	c.Assert(err, check.IsNil)
+	c.Assert(info.Mode().Perm(), check.Equals, os.FileMode(0700))
+	data, err := ioutil.ReadFile(out)
+	c.Assert(err, check.IsNil)
+	config := kubeConfig{}
+	c.Assert(yaml.Unmarshal(data, &config), check.IsNil)
+	c.Assert(config.CurrentContext, check.Equals, "foo")
+	c.Assert(config.Clusters, check.HasLen, 1)
+	c.Assert(config.Clusters[0].Cluster.Server, check.Equals, "https://foo.mock.local")
+
+	cls, err := cliPersistStore{}.Get("foo")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.Metadata[rpcDriver.KubeConfigOutMetadata], check.Equals, out)
+
+	// without --no-kubeconfig both are written
+	out = filepath.Join(utils.HomeDir(), "team", "bar.yaml")
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "--kubeconfig-out", out, "bar"}
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+	_, err = os.Stat(out)
+	c.Assert(err, check.IsNil)
+	config, err = getConfigFromFile()
+	c.Assert(err, check.IsNil)
+	c.Assert(config.Clusters, check.HasLen, 1)
+	c.Assert(config.Clusters[0].Name, check.Equals, "bar")
+}
+
+func (s *KubeConfigCommandTestSuite) TestCreateWithoutKubeConfig(c *check.C) {
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "--no-kubeconfig", "foo"}
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+
+	_, err := os.Stat(filepath.Join(utils.HomeDir(), "clusters", "foo", defaultConfigName))
+	c.Assert(err, check.IsNil)
+	_, err = os.Stat(utils.KubeConfigFilePath())
+	c.Assert(os.IsNotExist(err), check.Equals, true, check.Commentf("kubeconfig written: %v", err))
+
+	// the kubeconfig is generated on demand
+	cls, err := cliPersistStore{}.Get("foo")
+	c.Assert(err, check.IsNil)
+	buf := bytes.Buffer{}
+	c.Assert(writeKubeConfig(&buf, cls, kubeConfigOptions{}), check.IsNil)
+	config := kubeConfig{}
+	c.Assert(yaml.Unmarshal(buf.Bytes(), &config), check.IsNil)
+	c.Assert(config.CurrentContext, check.Equals, "foo")
+	c.Assert(config.Clusters, check.HasLen, 1)
+	c.Assert(config.Clusters[0].Cluster.Server, check.Equals, "https://foo.mock.local")
+
+	app := newTestApp()
+	app.Commands = append(app.Commands, KubeConfigCommand())
+	os.Args = []string{"kontainer-engine", "kubeconfig", "--merge", "foo"}
+	c.Assert(app.Run(os.Args), check.IsNil)
+	config, err = getConfigFromFile()
+	c.Assert(err, check.IsNil)
+	c.Assert(config.Clusters, check.HasLen, 1)
+	c.Assert(config.Clusters[0].Name, check.Equals, "foo")
+}
+
+func (s *KubeConfigCommandTestSuite) TestCreateWritesKubeConfig(c *check.C) {
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "foo"}
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+	_, err := os.Stat(utils.KubeConfigFilePath())
+	c.Assert(err, check.IsNil)
+}
+
+func (s *KubeConfigCommandTestSuite) TestEntryNamesStayConsistent(c *check.C) {
+	run := func(args ...string) error {
+		app := newTestApp()
+		app.Commands = append(app.Commands, KubeConfigCommand(), EnvCommand())
+		os.Args = append([]string{"kontainer-engine"}, args...)
+		return app.Run(os.Args)
+	}
+	c.Assert(run("create", "--driver", "mock", "foo"), check.IsNil)
+	c.Assert(run("create", "--driver", "mock", "bar"), check.IsNil)
+
+	// regenerating the kubeconfig finds the entries written by create instead of adding new ones
+	c.Assert(run("kubeconfig", "--merge", "foo"), check.IsNil)
+	names := kubeConfigEntryNames("foo")
+	config, err := getConfigFromFile()
+	c.Assert(err, check.IsNil)
+	c.Assert(config.Clusters, check.HasLen, 2)
+	c.Assert(config.Users, check.HasLen, 2)
+	c.Assert(config.Contexts, check.HasLen, 2)
+	c.Assert(config.Contexts[0].Name, check.Equals, names.Context)
+	c.Assert(config.Contexts[0].Context, check.Equals, contextData{Cluster: names.Cluster, User: names.User})
+	c.Assert(config.Clusters[0].Name, check.Equals, names.Cluster)
+	c.Assert(config.Users[0].Name, check.Equals, names.User)
+
+	// the generated kubeconfig uses the same names
+	cls, err := cliPersistStore{}.Get("foo")
+	c.Assert(err, check.IsNil)
+	buf := bytes.Buffer{}
+	c.Assert(writeKubeConfig(&buf, cls, kubeConfigOptions{}), check.IsNil)
+	generated := kubeConfig{}
+	c.Assert(yaml.Unmarshal(buf.Bytes(), &generated), check.IsNil)
+	c.Assert(generated.CurrentContext, check.Equals, names.Context)
+	c.Assert(generated.Contexts, check.DeepEquals, config.Contexts[:1])
+
+	// env selects the context and rm deletes the entries of the cluster only
+	c.Assert(run("env", "foo"), check.IsNil)
+	config, err = getConfigFromFile()
+	c.Assert(err, check.IsNil)
+	c.Assert(config.CurrentContext, check.Equals, names.Context)
+	c.Assert(run("rm", "foo"), check.IsNil)
+	config, err = getConfigFromFile()
+	c.Assert(err, check.IsNil)
+	c.Assert(config.Contexts, check.HasLen, 1)
+	c.Assert(config.Contexts[0].Name, check.Equals, kubeConfigEntryNames("bar").Context)
+	c.Assert(config.Clusters, check.HasLen, 1)
+	c.Assert(config.Users, check.HasLen, 1)
+}
+
+func (s *KubeConfigCommandTestSuite) TestKubeConfigSecretGolden(c *check.C) {
+	buf := bytes.Buffer{}
+	c.Assert(writeKubeConfigSecret(&buf, goldenCluster, "fleet", "foo-kubeconfig"), check.IsNil)
+	expected, err := ioutil.ReadFile(filepath.Join("testdata", "kubeconfig-secret.yaml"))
+	c.Assert(err, check.IsNil)
+	c.Assert(buf.String(), check.Equals, string(expected))
+
+	// the secret holds the kubeconfig kubeconfig prints
+	secret := secretManifest{}
+	c.Assert(yaml.Unmarshal(buf.Bytes(), &secret), check.IsNil)
+	config, err := base64.StdEncoding.DecodeString(secret.Data[kubeConfigSecretKey])
+	c.Assert(err, check.IsNil)
+	plain := bytes.Buffer{}
+	c.Assert(writeKubeConfig(&plain, goldenCluster, kubeConfigOptions{}), check.IsNil)
+	c.Assert(string(config), check.Equals, plain.String())
+}
+
+func (s *KubeConfigCommandTestSuite) TestKubeConfigAsSecret(c *check.C) {
+	c.Assert(cliPersistStore{}.Store(migratedCluster("foo", "1.1.1.1")), check.IsNil)
+	app := newTestApp()
+	app.Commands = append(app.Commands, KubeConfigCommand())
+	for _, test := range []struct {
+		args []string
+		err  string
+		code int
+	}{
+		{[]string{"--namespace", "Fleet"}, `invalid secret namespace "Fleet": .*`, ExitValidation},
+		{[]string{"--namespace", ""}, `invalid secret namespace "": .*`, ExitValidation},
+		{[]string{"--name", "foo_kubeconfig"}, `invalid secret name "foo_kubeconfig": .*`, ExitValidation},
+		{[]string{"--merge"}, "--as-secret and --merge can't be used together", ExitUsage},
+	} {
+		args := append([]string{"kontainer-engine", "kubeconfig", "--as-secret"}, test.args...)
+		err := app.Run(append(args, "foo"))
+		c.Assert(err, check.ErrorMatches, test.err)
+		c.Assert(ExitCode(err), check.Equals, test.code)
+	}
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rancher/kontainer-engine/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+var noWaitFlag = cli.BoolFlag{
+	Name:  "no-wait",
+	Usage: "Fail right away if another command holds the lock of the cluster, e.g. while it updates it, instead of waiting for it to finish",
+}
+
+// lockRetryInterval is the wait between two attempts to take the lock of a cluster held by another command
+var lockRetryInterval = 200 * time.Millisecond
+
+// clusterLockPath returns the lock file of the operations on the cluster called name
+func clusterLockPath(name string) string {
+	return filepath.Join(utils.HomeDir(), "locks", name+".lock")
+}
+
+// lockCluster takes the lock of the cluster called name for operation, e.g. update, so that the commands creating,
+// updating or removing the same cluster on this machine run one after the other. It waits for the lock unless
+// --no-wait is set, in which case a held lock is a retryable failure naming the operation holding it. The caller must
+// release the lock with unlockCluster, it is released by the system if the process dies. With --no-store nothing is
+// shared with the other commands and the lock is nil.
+func lockCluster(ctx *cli.Context, name, operation string) (*utils.FileLock, error) {
+	if ctx.Bool(noStoreFlag.Name) {
+		return nil, nil
+	}
+	noWait := ctx.Bool(noWaitFlag.Name)
+	path := clusterLockPath(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	holder := fmt.Sprintf("%s (pid %d)", operation, os.Getpid())
+	waiting := false
+	for {
+		lock, err := utils.TryLockFile(path, holder)
+		if err != utils.ErrLocked {
+			return lock, err
+		}
+		current := utils.LockHolder(path)
+		if current == "" {
+			current = "another command"
+		}
+		if noWait {
+			return nil, &exitError{
+				code: ExitRetryable,
+				err:  fmt.Errorf("cluster %s is locked by %s, retry once it is done or run without --%s to wait", name, current, noWaitFlag.Name),
+			}
+		}
+		if !waiting {
+			logrus.Infof("Waiting for %s to release cluster %s", current, name)
+			waiting = true
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// unlockCluster releases the lock of a cluster, a failure is only logged as the operation itself is done
+func unlockCluster(name string, lock *utils.FileLock) {
+	if lock == nil {
+		return
+	}
+	if err := lock.Unlock(); err != nil {
+		logrus.Warnf("Failed to release the lock of cluster %s: %v", name, err)
+	}
+}
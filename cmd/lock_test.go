@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rancher/kontainer-engine/store"
+	"github.com/rancher/kontainer-engine/utils"
+	"gopkg.in/check.v1"
+)
+
+type LockTestSuite struct {
+	tempHomeSuite
+	oldArgs          []string
+	oldRetryInterval time.Duration
+}
+
+var _ = check.Suite(&LockTestSuite{})
+
+func (s *LockTestSuite) SetUpTest(c *check.C) {
+	s.tempHomeSuite.SetUpTest(c)
+	s.oldArgs = os.Args
+	s.oldRetryInterval = lockRetryInterval
+	lockRetryInterval = 5 * time.Millisecond
+}
+
+func (s *LockTestSuite) TearDownTest(c *check.C) {
+	os.Args = s.oldArgs
+	lockRetryInterval = s.oldRetryInterval
+	s.tempHomeSuite.TearDownTest(c)
+}
+
+// holdLock takes the lock of the cluster called name as another command would
+func holdLock(c *check.C, name string) *utils.FileLock {
+	path := clusterLockPath(name)
+	c.Assert(os.MkdirAll(filepath.Dir(path), 0700), check.IsNil)
+	lock, err := utils.TryLockFile(path, "update (pid 1)")
+	c.Assert(err, check.IsNil)
+	return lock
+}
+
+func (s *LockTestSuite) createMockCluster(c *check.C, name string) {
+	_, err := applyManifest(clusterManifest{
+		Clusters: []clusterSpec{mockSpec(name, 1)},
+	}, applyOptions{})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *LockTestSuite) TestNoWaitFailsFast(c *check.C) {
+	s.createMockCluster(c, "locked")
+	lock := holdLock(c, "locked")
+	defer lock.Unlock()
+
+	err := newTestApp().Run([]string{"kontainer-engine", "rm", "--no-wait", "locked"})
+	c.Assert(err, check.ErrorMatches, "cluster locked is locked by update \\(pid 1\\), retry once it is done or run without --no-wait to wait")
+	c.Assert(ExitCode(err), check.Equals, ExitRetryable)
+	_, err = cliPersistStore{}.Get("locked")
+	c.Assert(err, check.IsNil)
+}
+
+func (s *LockTestSuite) TestRemoveWaitsForLock(c *check.C) {
+	s.createMockCluster(c, "waiting")
+	lock := holdLock(c, "waiting")
+
+	done := make(chan error)
+	go func() {
+		done <- newTestApp().Run([]string{"kontainer-engine", "rm", "waiting"})
+	}()
+	select {
+	case err := <-done:
+		c.Fatalf("rm didn't wait for the lock: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	_, err := cliPersistStore{}.Get("waiting")
+	c.Assert(err, check.IsNil)
+
+	c.Assert(lock.Unlock(), check.IsNil)
+	c.Assert(<-done, check.IsNil)
+	clusters, err := store.GetAllClusterFromStore()
+	c.Assert(err, check.IsNil)
+	c.Assert(clusters, check.HasLen, 0)
+}
+
+func (s *LockTestSuite) TestConcurrentUpdateAndRemove(c *check.C) {
+	for i := 0; i < 5; i++ {
+		s.createMockCluster(c, "contended")
+
+		// update reruns itself with os.Args, rm is given its args
+		os.Args = []string{"kontainer-engine", "update", "--node-count", "3", "contended"}
+		updated, removed := make(chan error), make(chan error)
+		go func() {
+			app := newTestApp()
+			app.Commands = append(app.Commands, UpdateCommand())
+			updated <- app.Run(os.Args)
+		}()
+		go func() {
+			removed <- newTestApp().Run([]string{"kontainer-engine", "rm", "contended"})
+		}()
+
+		// whichever runs first, the other sees its result: the update never stores the removed cluster again
+		c.Assert(<-removed, check.IsNil)
+		if err := <-updated; err != nil {
+			c.Assert(err, check.ErrorMatches, "cluster contended can't be found")
+			c.Assert(ExitCode(err), check.Equals, ExitNotFound)
+		}
+		clusters, err := store.GetAllClusterFromStore()
+		c.Assert(err, check.IsNil)
+		c.Assert(clusters, check.HasLen, 0)
+	}
+}
@@ -1,6 +1,10 @@
 package cmd
 
 import (
+	"fmt"
+	"sort"
+
+	"github.com/rancher/kontainer-engine/cluster"
 	"github.com/rancher/kontainer-engine/store"
 	"github.com/rancher/kontainer-engine/utils"
 	"github.com/urfave/cli"
@@ -13,13 +17,80 @@ func LsCommand() cli.Command {
 		ShortName: "ls",
 		Usage:     "list kubernetes clusters",
 		Action:    lsCluster,
-		Flags:     []cli.Flag{},
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "sort-by",
+				Usage: "Sort the clusters by name, status or created",
+				Value: "name",
+			},
+			cli.BoolFlag{
+				Name:  "reverse",
+				Usage: "Reverse the sort order",
+			},
+			cli.IntFlag{
+				Name:  "limit",
+				Usage: "The maximum number of clusters to list. 0 means no limit",
+			},
+			cli.IntFlag{
+				Name:  "offset",
+				Usage: "The number of clusters to skip",
+			},
+		},
+	}
+}
+
+// CompleteClustersCommand defines the hidden command printing the cluster names for shell completion
+func CompleteClustersCommand() cli.Command {
+	return cli.Command{
+		Name:   "__complete-clusters",
+		Usage:  "Print the cluster names one per line for shell completion",
+		Hidden: true,
+		Action: completeClusters,
+	}
+}
+
+func completeClusters(ctx *cli.Context) error {
+	names, err := storedClusterNames()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		fmt.Println(name)
+	}
+	return nil
+}
+
+// storedClusterNames returns the sorted names of the stored clusters, the file store doesn't parse the cluster configs
+func storedClusterNames() ([]string, error) {
+	if selectedStore == nil {
+		return store.ListClusterNames()
+	}
+	clusters, err := getAllClusters()
+	if err != nil {
+		return nil, err
 	}
+	names := []string{}
+	for name := range clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
 }
 
 func lsCluster(ctx *cli.Context) error {
 	// todo: add filter support
-	clusters, err := store.GetAllClusterFromStore()
+	clusters, err := getAllClusters()
+	if err != nil {
+		return err
+	}
+	list := []cluster.Cluster{}
+	for _, cls := range clusters {
+		list = append(list, cls)
+	}
+	if err := sortClusters(list, ctx.String("sort-by"), ctx.Bool("reverse")); err != nil {
+		return err
+	}
+	list, err = paginateClusters(list, ctx.Int("offset"), ctx.Int("limit"))
 	if err != nil {
 		return err
 	}
@@ -27,14 +98,58 @@ func lsCluster(ctx *cli.Context) error {
 	writer := utils.NewTableWriter([][]string{
 		{"NAME", "Name"},
 		{"DRIVER", "DriverName"},
-		{"VERISON", "Version"},
+		{"VERSION", "Version"},
 		{"ENDPOINT", "Endpoint"},
 		{"NODE_COUNT", "NodeCount"},
 		{"STATUS", "Status"},
 	}, ctx)
 	defer writer.Close()
-	for _, cluster := range clusters {
-		writer.Write(cluster)
+	for _, cls := range list {
+		writer.Write(cls)
 	}
 	return writer.Err()
 }
+
+// sortClusters sorts clusters by the given key. Clusters with equal keys are ordered by name so the order is always the same
+func sortClusters(clusters []cluster.Cluster, sortBy string, reverse bool) error {
+	var key func(cls cluster.Cluster) string
+	switch sortBy {
+	case "", "name":
+		key = func(cls cluster.Cluster) string { return cls.Name }
+	case "status":
+		key = func(cls cluster.Cluster) string { return cls.Status }
+	case "created":
+		// RFC3339 timestamps in UTC sort chronologically
+		key = func(cls cluster.Cluster) string { return cls.CreatedAt }
+	default:
+		return fmt.Errorf("invalid sort key %s, must be one of name, status or created", sortBy)
+	}
+	sort.SliceStable(clusters, func(i, j int) bool {
+		ki, kj := key(clusters[i]), key(clusters[j])
+		if ki == kj {
+			return clusters[i].Name < clusters[j].Name
+		}
+		return ki < kj
+	})
+	if reverse {
+		for i, j := 0, len(clusters)-1; i < j; i, j = i+1, j-1 {
+			clusters[i], clusters[j] = clusters[j], clusters[i]
+		}
+	}
+	return nil
+}
+
+// paginateClusters skips offset clusters and returns at most limit of the rest. A limit of 0 returns all of them
+func paginateClusters(clusters []cluster.Cluster, offset, limit int) ([]cluster.Cluster, error) {
+	if offset < 0 || limit < 0 {
+		return nil, fmt.Errorf("offset and limit can't be negative")
+	}
+	if offset >= len(clusters) {
+		return []cluster.Cluster{}, nil
+	}
+	clusters = clusters[offset:]
+	if limit > 0 && limit < len(clusters) {
+		clusters = clusters[:limit]
+	}
+	return clusters, nil
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	"github.com/rancher/kontainer-engine/utils"
+	"gopkg.in/check.v1"
+)
+
+type LsTestSuite struct {
+}
+
+var _ = check.Suite(&LsTestSuite{})
+
+func fakeClusters() []cluster.Cluster {
+	return []cluster.Cluster{
+		{Name: "c", Status: cluster.Running, CreatedAt: "2017-12-01T10:00:00Z"},
+		{Name: "a", Status: cluster.Error, CreatedAt: "2017-12-03T10:00:00Z"},
+		{Name: "d", Status: cluster.Running, CreatedAt: "2017-12-02T10:00:00Z"},
+		{Name: "b", Status: cluster.Running, CreatedAt: "2017-12-02T10:00:00Z"},
+	}
+}
+
+func clusterNames(clusters []cluster.Cluster) []string {
+	names := []string{}
+	for _, cls := range clusters {
+		names = append(names, cls.Name)
+	}
+	return names
+}
+
+func (s *LsTestSuite) TestSortClusters(c *check.C) {
+	for _, t := range []struct {
+		sortBy   string
+		reverse  bool
+		expected []string
+	}{
+		{"name", false, []string{"a", "b", "c", "d"}},
+		{"name", true, []string{"d", "c", "b", "a"}},
+		{"status", false, []string{"a", "b", "c", "d"}},
+		{"created", false, []string{"c", "b", "d", "a"}},
+		{"created", true, []string{"a", "d", "b", "c"}},
+	} {
+		clusters := fakeClusters()
+		c.Assert(sortClusters(clusters, t.sortBy, t.reverse), check.IsNil)
+		c.Assert(clusterNames(clusters), check.DeepEquals, t.expected, check.Commentf("sort by %s, reverse %v", t.sortBy, t.reverse))
+	}
+
+	c.Assert(sortClusters(fakeClusters(), "size", false), check.ErrorMatches, "invalid sort key size.*")
+}
+
+func (s *LsTestSuite) TestPaginateClusters(c *check.C) {
+	clusters := fakeClusters()
+	c.Assert(sortClusters(clusters, "name", false), check.IsNil)
+	for _, t := range []struct {
+		offset   int
+		limit    int
+		expected []string
+	}{
+		{0, 0, []string{"a", "b", "c", "d"}},
+		{0, 2, []string{"a", "b"}},
+		{2, 2, []string{"c", "d"}},
+		{3, 2, []string{"d"}},
+		{4, 1, []string{}},
+		{10, 0, []string{}},
+		{1, 10, []string{"b", "c", "d"}},
+	} {
+		page, err := paginateClusters(clusters, t.offset, t.limit)
+		c.Assert(err, check.IsNil)
+		c.Assert(clusterNames(page), check.DeepEquals, t.expected, check.Commentf("offset %d, limit %d", t.offset, t.limit))
+	}
+
+	_, err := paginateClusters(clusters, -1, 0)
+	c.Assert(err, check.NotNil)
+}
+
+type CompleteClustersTestSuite struct {
+	tempHomeSuite
+}
+
+var _ = check.Suite(&CompleteClustersTestSuite{})
+
+func (s *CompleteClustersTestSuite) TestStoredClusterNames(c *check.C) {
+	writeClusterConfig(c, "b", `{"name":"b"}`)
+	writeClusterConfig(c, "a", `{"name":"a"}`)
+	// an unparseable config is still listed as it isn't read
+	writeClusterConfig(c, "broken", `{not json`)
+	// skipped: a hidden dir left by an interrupted write, a dir without config, a file and a config that is a dir
+	writeClusterConfig(c, ".c.tmp123", `{"name":"c"}`)
+	clustersDir := filepath.Join(utils.HomeDir(), "clusters")
+	c.Assert(os.MkdirAll(filepath.Join(clustersDir, "empty"), 0755), check.IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(clustersDir, "file"), []byte("x"), 0600), check.IsNil)
+	c.Assert(os.MkdirAll(filepath.Join(clustersDir, "dir", defaultConfigName), 0755), check.IsNil)
+
+	names, err := storedClusterNames()
+	c.Assert(err, check.IsNil)
+	c.Assert(names, check.DeepEquals, []string{"a", "b", "broken"})
+}
+
+func (s *CompleteClustersTestSuite) TestStoredClusterNamesWithoutClusters(c *check.C) {
+	names, err := storedClusterNames()
+	c.Assert(err, check.IsNil)
+	c.Assert(names, check.HasLen, 0)
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"strings"
+	"time"
+
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/urfave/cli"
+)
+
+var (
+	maintenanceWindowFlag = cli.StringFlag{
+		Name:  "maintenance-window",
+		Usage: "The recurring window the provider can run its maintenance in as [DAYS ]HH:MM-HH:MM in UTC, e.g. 'sat,sun 02:00-06:00', passed to the maintenance window option of the driver whatever its name",
+	}
+	maintenanceExclusionFlag = cli.StringSliceFlag{
+		Name:  "maintenance-exclusion",
+		Usage: "A period the provider must not run its maintenance in as START/END in RFC 3339, e.g. 2026-12-24T00:00:00Z/2026-12-27T00:00:00Z, passed to the maintenance exclusion option of the driver whatever its name. Repeat the flag for several periods",
+	}
+	// maintenanceDays are the days of a maintenance window
+	maintenanceDays = []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}
+)
+
+// parseMaintenanceWindow validates a --maintenance-window value
+func parseMaintenanceWindow(window string) error {
+	fields := strings.Fields(window)
+	if len(fields) == 0 || len(fields) > 2 {
+		return newValidationError("invalid maintenance window %q, use [DAYS ]HH:MM-HH:MM", window)
+	}
+	if len(fields) == 2 {
+		for _, day := range strings.Split(fields[0], ",") {
+			if !isMaintenanceDay(day) {
+				return newValidationError("invalid day %q of maintenance window %q, use %s", day, window, strings.Join(maintenanceDays, ", "))
+			}
+		}
+	}
+	hours := strings.Split(fields[len(fields)-1], "-")
+	if len(hours) != 2 {
+		return newValidationError("invalid maintenance window %q, use [DAYS ]HH:MM-HH:MM", window)
+	}
+	start, err := time.Parse("15:04", hours[0])
+	if err != nil {
+		return newValidationError("invalid start %q of maintenance window %q, use HH:MM", hours[0], window)
+	}
+	end, err := time.Parse("15:04", hours[1])
+	if err != nil {
+		return newValidationError("invalid end %q of maintenance window %q, use HH:MM", hours[1], window)
+	}
+	// a window ending before it starts runs over midnight
+	if start.Equal(end) {
+		return newValidationError("maintenance window %q is empty, it starts when it ends", window)
+	}
+	return nil
+}
+
+func isMaintenanceDay(day string) bool {
+	for _, d := range maintenanceDays {
+		if day == d {
+			return true
+		}
+	}
+	return false
+}
+
+// parseMaintenanceExclusion validates a --maintenance-exclusion value
+func parseMaintenanceExclusion(exclusion string) error {
+	period := strings.Split(exclusion, "/")
+	if len(period) != 2 {
+		return newValidationError("invalid maintenance exclusion %q, use START/END", exclusion)
+	}
+	start, err := time.Parse(time.RFC3339, period[0])
+	if err != nil {
+		return newValidationError("invalid start %q of maintenance exclusion %q, use RFC 3339, e.g. 2026-12-24T00:00:00Z", period[0], exclusion)
+	}
+	end, err := time.Parse(time.RFC3339, period[1])
+	if err != nil {
+		return newValidationError("invalid end %q of maintenance exclusion %q, use RFC 3339, e.g. 2026-12-27T00:00:00Z", period[1], exclusion)
+	}
+	if !end.After(start) {
+		return newValidationError("maintenance exclusion %q ends before it starts", exclusion)
+	}
+	return nil
+}
+
+// mapMaintenanceOptions sets the maintenance window and exclusion options of the driver from the values of
+// --maintenance-window and --maintenance-exclusion. Like --network, they are an error if the driver has no such option.
+func mapMaintenanceOptions(driverOptions *rpcDriver.DriverOptions, driverFlags rpcDriver.DriverFlags) error {
+	if window := driverOptions.StringOptions[maintenanceWindowFlag.Name]; window != "" {
+		if err := parseMaintenanceWindow(window); err != nil {
+			return err
+		}
+	}
+	if err := mapStringOption(driverOptions, driverFlags, maintenanceWindowFlag.Name, rpcDriver.MaintenanceWindowCanonical); err != nil {
+		return err
+	}
+	return mapSliceOption(driverOptions, driverFlags, maintenanceExclusionFlag.Name, rpcDriver.MaintenanceExclusionsCanonical, parseMaintenanceExclusion)
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"gopkg.in/check.v1"
+)
+
+type MaintenanceTestSuite struct{}
+
+var _ = check.Suite(&MaintenanceTestSuite{})
+
+func (s *MaintenanceTestSuite) TestParseMaintenanceWindow(c *check.C) {
+	for _, window := range []string{"02:00-06:00", "sat,sun 02:00-06:00", "mon 22:00-02:00"} {
+		c.Assert(parseMaintenanceWindow(window), check.IsNil, check.Commentf("window %s", window))
+	}
+	for window, expected := range map[string]string{
+		"":                     `invalid maintenance window "", use \[DAYS \]HH:MM-HH:MM`,
+		"02:00":                `invalid maintenance window "02:00", use \[DAYS \]HH:MM-HH:MM`,
+		"sat sun 02:00-06:00":  `invalid maintenance window "sat sun 02:00-06:00", use \[DAYS \]HH:MM-HH:MM`,
+		"saturday 02:00-06:00": `invalid day "saturday" of maintenance window "saturday 02:00-06:00", use mon, tue, wed, thu, fri, sat, sun`,
+		"2am-06:00":            `invalid start "2am" of maintenance window "2am-06:00", use HH:MM`,
+		"02:00-25:00":          `invalid end "25:00" of maintenance window "02:00-25:00", use HH:MM`,
+		"02:00-02:00":          `maintenance window "02:00-02:00" is empty, it starts when it ends`,
+	} {
+		err := parseMaintenanceWindow(window)
+		c.Assert(err, check.ErrorMatches, expected)
+		c.Assert(ExitCode(err), check.Equals, ExitValidation)
+	}
+}
+
+func (s *MaintenanceTestSuite) TestParseMaintenanceExclusion(c *check.C) {
+	c.Assert(parseMaintenanceExclusion("2026-12-24T00:00:00Z/2026-12-27T00:00:00+01:00"), check.IsNil)
+	for exclusion, expected := range map[string]string{
+		"2026-12-24T00:00:00Z":                      `invalid maintenance exclusion "2026-12-24T00:00:00Z", use START/END`,
+		"2026-12-24/2026-12-27T00:00:00Z":           `invalid start "2026-12-24" of maintenance exclusion .*, use RFC 3339, .*`,
+		"2026-12-24T00:00:00Z/tomorrow":             `invalid end "tomorrow" of maintenance exclusion .*, use RFC 3339, .*`,
+		"2026-12-27T00:00:00Z/2026-12-24T00:00:00Z": `maintenance exclusion ".*" ends before it starts`,
+	} {
+		err := parseMaintenanceExclusion(exclusion)
+		c.Assert(err, check.ErrorMatches, expected)
+		c.Assert(ExitCode(err), check.Equals, ExitValidation)
+	}
+}
+
+func (s *MaintenanceTestSuite) TestMapToDriverOptions(c *check.C) {
+	driverOptions, err := resolveDriverOptions([]string{"--maintenance-window", "sat,sun 02:00-06:00",
+		"--maintenance-exclusion", "2026-12-24T00:00:00Z/2026-12-27T00:00:00Z", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.IsNil)
+	c.Assert(driverOptions.StringOptions["upgrade-window"], check.Equals, "sat,sun 02:00-06:00")
+	c.Assert(driverOptions.StringSliceOptions["upgrade-blackouts"].Value, check.DeepEquals, []string{"2026-12-24T00:00:00Z/2026-12-27T00:00:00Z"})
+	c.Assert(driverOptions.IsSet("upgrade-window"), check.Equals, true)
+	c.Assert(driverOptions.IsSet("upgrade-blackouts"), check.Equals, true)
+
+	// the options of the driver can still be given, unvalidated as the driver declares them
+	driverOptions, err = resolveDriverOptions([]string{"--upgrade-window", "weekends", "--upgrade-blackouts", "xmas", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.IsNil)
+	c.Assert(driverOptions.StringOptions["upgrade-window"], check.Equals, "weekends")
+	c.Assert(driverOptions.StringSliceOptions["upgrade-blackouts"].Value, check.DeepEquals, []string{"xmas"})
+	_, err = resolveDriverOptions([]string{"--maintenance-window", "02:00-06:00", "--upgrade-window", "weekends", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.ErrorMatches, "--maintenance-window and --upgrade-window are set to different values")
+	c.Assert(ExitCode(err), check.Equals, ExitUsage)
+
+	_, err = resolveDriverOptions([]string{"--maintenance-window", "02:00", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.ErrorMatches, `invalid maintenance window "02:00", .*`)
+}
+
+func (s *MaintenanceTestSuite) TestDriverWithoutOption(c *check.C) {
+	driverFlags := rpcDriver.DriverFlags{Options: map[string]*rpcDriver.Flag{
+		"region": {Type: rpcDriver.StringType},
+	}}
+	_, err := resolveDriverOptions([]string{"--maintenance-window", "02:00-06:00", "foo"}, driverFlags)
+	c.Assert(err, check.ErrorMatches, "the driver has no maintenance-window option, --maintenance-window can't be used")
+	c.Assert(ExitCode(err), check.Equals, ExitValidation)
+	_, err = resolveDriverOptions([]string{"--maintenance-exclusion", "2026-12-24T00:00:00Z/2026-12-27T00:00:00Z", "foo"}, driverFlags)
+	c.Assert(err, check.ErrorMatches, "the driver has no maintenance-exclusions option, --maintenance-exclusion can't be used")
+}
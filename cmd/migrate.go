@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	"github.com/rancher/kontainer-engine/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+const (
+	migrateCopy      = "copy"
+	migrateOverwrite = "overwrite"
+	migrateSkip      = "skip"
+)
+
+// MigrateStoreCommand defines the migrate-store command
+func MigrateStoreCommand() cli.Command {
+	return cli.Command{
+		Name:   "migrate-store",
+		Usage:  "Copy the clusters of a store to another store, e.g. from the file store to s3",
+		Action: migrateStore,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "from",
+				Usage: "The store the clusters are read from, file, memory or s3",
+				Value: fileStore,
+			},
+			cli.StringFlag{
+				Name:  "to",
+				Usage: "The store the clusters are written to, file, memory or s3. The s3 store is configured by the global s3 flags",
+			},
+			cli.BoolFlag{
+				Name:  "overwrite",
+				Usage: "Replace the clusters already in the destination store instead of skipping them",
+			},
+		},
+	}
+}
+
+type migrateResult struct {
+	Name   string
+	Action string
+	Status string
+	Error  string
+}
+
+func migrateStore(ctx *cli.Context) error {
+	fromName, toName := ctx.String("from"), ctx.String("to")
+	if toName == "" {
+		return usageErrorWithHelp(ctx, "migrate-store", "the destination store is required")
+	}
+	if fromName == toName {
+		return newUsageError("the source and the destination store are both %s", fromName)
+	}
+	from, err := openStore(ctx, fromName)
+	if err != nil {
+		return err
+	}
+	to, err := openStore(ctx, toName)
+	if err != nil {
+		return err
+	}
+	results, err := migrateClusters(from, to, ctx.Bool("overwrite"))
+	if err != nil {
+		return err
+	}
+
+	writer := utils.NewTableWriter([][]string{
+		{"NAME", "Name"},
+		{"ACTION", "Action"},
+		{"STATUS", "Status"},
+		{"ERROR", "Error"},
+	}, ctx)
+	failed := 0
+	for _, result := range results {
+		writer.Write(result)
+		if result.Error != "" {
+			failed++
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d clusters failed to migrate", failed, len(results))
+	}
+	return nil
+}
+
+// migrateClusters writes the clusters of from to to in the order of their names. The clusters already in to are
+// skipped unless overwrite is set. A cluster failing doesn't stop the others, it is recorded in its result instead.
+func migrateClusters(from, to clusterStore, overwrite bool) ([]migrateResult, error) {
+	clusters, err := from.list()
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for name := range clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := []migrateResult{}
+	for _, name := range names {
+		action, err := migrateCluster(clusters[name], to, overwrite)
+		result := migrateResult{Name: name, Action: action, Status: "Success"}
+		switch {
+		case err != nil:
+			logrus.Errorf("Failed to migrate cluster %s: %v", name, err)
+			result.Status = "Failed"
+			result.Error = err.Error()
+		case action == migrateSkip:
+			result.Status = "Skipped"
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func migrateCluster(cls cluster.Cluster, to clusterStore, overwrite bool) (string, error) {
+	state, err := to.Check(cls.Name)
+	if err != nil {
+		return migrateCopy, err
+	}
+	action := migrateCopy
+	if state != cluster.StateNotFound {
+		if !overwrite {
+			return migrateSkip, nil
+		}
+		action = migrateOverwrite
+	}
+	return action, to.Store(cls)
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"os"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	"gopkg.in/check.v1"
+)
+
+type MigrateStoreTestSuite struct {
+	tempHomeSuite
+	oldArgs []string
+}
+
+var _ = check.Suite(&MigrateStoreTestSuite{})
+
+func (s *MigrateStoreTestSuite) SetUpTest(c *check.C) {
+	s.tempHomeSuite.SetUpTest(c)
+	s.oldArgs = os.Args
+}
+
+func (s *MigrateStoreTestSuite) TearDownTest(c *check.C) {
+	os.Args = s.oldArgs
+	c.Assert(setStore(fileStore), check.IsNil)
+	s.tempHomeSuite.TearDownTest(c)
+}
+
+func migratedCluster(name, endpoint string) cluster.Cluster {
+	return cluster.Cluster{
+		Name:                name,
+		DriverName:          "mock",
+		Status:              cluster.Running,
+		Endpoint:            endpoint,
+		ServiceAccountToken: name + "-token",
+		RootCACert:          base64.StdEncoding.EncodeToString([]byte(name + "-ca")),
+	}
+}
+
+func (s *MigrateStoreTestSuite) TestMigrateMemoryToFile(c *check.C) {
+	memory := newInMemoryPersistStore()
+	c.Assert(memory.Store(migratedCluster("foo", "foo.memory")), check.IsNil)
+	c.Assert(memory.Store(migratedCluster("bar", "bar.memory")), check.IsNil)
+	file := fileClusterStore{}
+	c.Assert(file.Store(migratedCluster("bar", "bar.file")), check.IsNil)
+
+	// the clusters already in the destination are skipped
+	results, err := migrateClusters(memory, file, false)
+	c.Assert(err, check.IsNil)
+	c.Assert(results, check.DeepEquals, []migrateResult{
+		{Name: "bar", Action: migrateSkip, Status: "Skipped"},
+		{Name: "foo", Action: migrateCopy, Status: "Success"},
+	})
+	foo, err := cliPersistStore{}.Get("foo")
+	c.Assert(err, check.IsNil)
+	c.Assert(foo.Endpoint, check.Equals, "foo.memory")
+	c.Assert(foo.ServiceAccountToken, check.Equals, "foo-token")
+	c.Assert(foo.Status, check.Equals, cluster.Running)
+	bar, err := cliPersistStore{}.Get("bar")
+	c.Assert(err, check.IsNil)
+	c.Assert(bar.Endpoint, check.Equals, "bar.file")
+
+	// or replaced
+	results, err = migrateClusters(memory, file, true)
+	c.Assert(err, check.IsNil)
+	c.Assert(results, check.DeepEquals, []migrateResult{
+		{Name: "bar", Action: migrateOverwrite, Status: "Success"},
+		{Name: "foo", Action: migrateOverwrite, Status: "Success"},
+	})
+	bar, err = cliPersistStore{}.Get("bar")
+	c.Assert(err, check.IsNil)
+	c.Assert(bar.Endpoint, check.Equals, "bar.memory")
+}
+
+func (s *MigrateStoreTestSuite) TestMigrateStoreCommand(c *check.C) {
+	c.Assert(cliPersistStore{}.Store(migratedCluster("foo", "foo.file")), check.IsNil)
+	// the memory store of the process is the destination
+	c.Assert(setStore(memoryStore), check.IsNil)
+
+	app := newTestApp()
+	app.Commands = append(app.Commands, MigrateStoreCommand())
+	os.Args = []string{"kontainer-engine", "migrate-store", "--from", "file", "--to", "memory"}
+	c.Assert(app.Run(os.Args), check.IsNil)
+	foo, err := selectedStore.Get("foo")
+	c.Assert(err, check.IsNil)
+	c.Assert(foo.Endpoint, check.Equals, "foo.file")
+	c.Assert(foo.RootCACert, check.Equals, base64.StdEncoding.EncodeToString([]byte("foo-ca")))
+
+	os.Args = []string{"kontainer-engine", "migrate-store", "--to", "file"}
+	c.Assert(app.Run(os.Args), check.ErrorMatches, "the source and the destination store are both file")
+	os.Args = []string{"kontainer-engine", "migrate-store", "--to", "sqlite"}
+	err = app.Run(os.Args)
+	c.Assert(err, check.ErrorMatches, "store sqlite is not supported, must be file, memory or s3")
+	c.Assert(ExitCode(err), check.Equals, ExitUsage)
+}
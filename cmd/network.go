@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/urfave/cli"
+)
+
+var (
+	networkFlag = cli.StringFlag{
+		Name:  "network",
+		Usage: "The existing network, or VPC, to place the cluster in, passed to the network option of the driver whatever its name",
+	}
+	subnetFlag = cli.StringFlag{
+		Name:  "subnet",
+		Usage: "The existing subnet of the network to place the nodes in, passed to the subnet option of the driver whatever its name",
+	}
+)
+
+// mapNetworkOptions sets the network and subnet options of the driver to the values of --network and --subnet. Like
+// --node-label, they are an error if the driver has no such option rather than creating the cluster in another network.
+func mapNetworkOptions(driverOptions *rpcDriver.DriverOptions, driverFlags rpcDriver.DriverFlags) error {
+	if err := mapStringOption(driverOptions, driverFlags, networkFlag.Name, rpcDriver.NetworkCanonical); err != nil {
+		return err
+	}
+	return mapStringOption(driverOptions, driverFlags, subnetFlag.Name, rpcDriver.SubnetCanonical)
+}
+
+// mapStringOption sets the string option of the driver with the canonical name to the value of the flag called name
+func mapStringOption(driverOptions *rpcDriver.DriverOptions, driverFlags rpcDriver.DriverFlags, name, canonical string) error {
+	value := driverOptions.StringOptions[name]
+	if value == "" {
+		return nil
+	}
+	option := canonicalOption(driverFlags, canonical)
+	if option == "" || driverFlags.Options[option].Type != rpcDriver.StringType {
+		return newValidationError("the driver has no %s option, --%s can't be used", canonical, name)
+	}
+	if driverOptions.IsSet(option) && driverOptions.StringOptions[option] != value {
+		return newUsageError("--%s and --%s are set to different values", name, option)
+	}
+	if !driverOptions.IsSet(option) {
+		driverOptions.SetKeys = append(driverOptions.SetKeys, option)
+	}
+	driverOptions.StringOptions[option] = value
+	return nil
+}
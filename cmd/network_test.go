@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/rancher/kontainer-engine/driver/gke"
+	"gopkg.in/check.v1"
+)
+
+type NetworkTestSuite struct{}
+
+var _ = check.Suite(&NetworkTestSuite{})
+
+func (s *NetworkTestSuite) TestMapToDriverOptions(c *check.C) {
+	gkeFlags, err := gke.NewDriver().GetDriverCreateOptions()
+	c.Assert(err, check.IsNil)
+	for _, t := range []struct {
+		driverFlags     rpcDriver.DriverFlags
+		network, subnet string
+	}{
+		{mockCreateFlags(c), "vpc-id", "subnet-id"},
+		{*gkeFlags, "gke-network", "gke-subnetwork"},
+	} {
+		driverOptions, err := resolveDriverOptions([]string{"--network", "shared", "--subnet", "shared-nodes", "foo"}, t.driverFlags)
+		c.Assert(err, check.IsNil)
+		c.Assert(driverOptions.StringOptions[t.network], check.Equals, "shared")
+		c.Assert(driverOptions.StringOptions[t.subnet], check.Equals, "shared-nodes")
+		c.Assert(driverOptions.IsSet(t.network), check.Equals, true)
+
+		// the option of the driver can still be given, to the same value
+		driverOptions, err = resolveDriverOptions([]string{"--network", "shared", "--" + t.network, "shared", "foo"}, t.driverFlags)
+		c.Assert(err, check.IsNil)
+		c.Assert(driverOptions.StringOptions[t.network], check.Equals, "shared")
+		driverOptions, err = resolveDriverOptions([]string{"--" + t.subnet, "direct", "foo"}, t.driverFlags)
+		c.Assert(err, check.IsNil)
+		c.Assert(driverOptions.StringOptions[t.subnet], check.Equals, "direct")
+
+		_, err = resolveDriverOptions([]string{"--network", "shared", "--" + t.network, "other", "foo"}, t.driverFlags)
+		c.Assert(err, check.ErrorMatches, "--network and --"+t.network+" are set to different values")
+		c.Assert(ExitCode(err), check.Equals, ExitUsage)
+	}
+}
+
+func (s *NetworkTestSuite) TestDriverWithoutOption(c *check.C) {
+	driverFlags := rpcDriver.DriverFlags{Options: map[string]*rpcDriver.Flag{
+		"region": {Type: rpcDriver.StringType},
+	}}
+	_, err := resolveDriverOptions([]string{"--subnet", "nodes", "foo"}, driverFlags)
+	c.Assert(err, check.ErrorMatches, "the driver has no subnet option, --subnet can't be used")
+	c.Assert(ExitCode(err), check.Equals, ExitValidation)
+	driverOptions, err := resolveDriverOptions([]string{"foo"}, driverFlags)
+	c.Assert(err, check.IsNil)
+	c.Assert(driverOptions.StringOptions["region"], check.Equals, "")
+}
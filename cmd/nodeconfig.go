@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"strings"
+
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/urfave/cli"
+)
+
+var (
+	nodeLabelFlag = cli.StringSliceFlag{
+		Name:  "node-label",
+		Usage: "A label of the nodes as KEY=VALUE, passed to the node label option of the driver whatever its name. Repeat the flag for several labels",
+	}
+	nodeTaintFlag = cli.StringSliceFlag{
+		Name:  "node-taint",
+		Usage: "A taint of the nodes as KEY[=VALUE]:EFFECT, passed to the node taint option of the driver whatever its name. Repeat the flag for several taints",
+	}
+	// taintEffects are the effects kubernetes accepts for a taint
+	taintEffects = []string{"NoSchedule", "PreferNoSchedule", "NoExecute"}
+)
+
+// parseNodeLabel validates a --node-label value
+func parseNodeLabel(label string) error {
+	kv := strings.SplitN(label, "=", 2)
+	if len(kv) != 2 || kv[0] == "" {
+		return newValidationError("invalid node label %q, use KEY=VALUE", label)
+	}
+	return nil
+}
+
+// parseNodeTaint validates a --node-taint value
+func parseNodeTaint(taint string) error {
+	i := strings.LastIndex(taint, ":")
+	if i < 0 {
+		return newValidationError("invalid node taint %q, use KEY[=VALUE]:EFFECT", taint)
+	}
+	if key := strings.SplitN(taint[:i], "=", 2)[0]; key == "" {
+		return newValidationError("invalid node taint %q, the key is required", taint)
+	}
+	effect := taint[i+1:]
+	for _, e := range taintEffects {
+		if effect == e {
+			return nil
+		}
+	}
+	return newValidationError("invalid effect %q of node taint %q, use %s", effect, taint, strings.Join(taintEffects, ", "))
+}
+
+// mapNodeLabelsAndTaints adds the values of --node-label and --node-taint to the node label and taint options of the
+// driver. Unlike --kubernetes-version, they are an error if the driver has no such option.
+func mapNodeLabelsAndTaints(driverOptions *rpcDriver.DriverOptions, driverFlags rpcDriver.DriverFlags) error {
+	if err := mapSliceOption(driverOptions, driverFlags, nodeLabelFlag.Name, rpcDriver.NodeLabelsCanonical, parseNodeLabel); err != nil {
+		return err
+	}
+	return mapSliceOption(driverOptions, driverFlags, nodeTaintFlag.Name, rpcDriver.NodeTaintsCanonical, parseNodeTaint)
+}
+
+// mapSliceOption validates the values of the flag called name and appends them to the string slice option of the
+// driver with the canonical name
+func mapSliceOption(driverOptions *rpcDriver.DriverOptions, driverFlags rpcDriver.DriverFlags, name, canonical string, validate func(string) error) error {
+	values := driverOptions.StringSliceOptions[name]
+	if values == nil || len(values.Value) == 0 {
+		return nil
+	}
+	for _, value := range values.Value {
+		if err := validate(value); err != nil {
+			return err
+		}
+	}
+	option := canonicalOption(driverFlags, canonical)
+	if option == "" || driverFlags.Options[option].Type != rpcDriver.StringSliceType {
+		return newValidationError("the driver has no %s option, --%s can't be used", canonical, name)
+	}
+	merged := &rpcDriver.StringSlice{}
+	if existing := driverOptions.StringSliceOptions[option]; existing != nil {
+		merged.Value = append(merged.Value, existing.Value...)
+	}
+	merged.Value = append(merged.Value, values.Value...)
+	driverOptions.StringSliceOptions[option] = merged
+	if !driverOptions.IsSet(option) {
+		driverOptions.SetKeys = append(driverOptions.SetKeys, option)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/rancher/kontainer-engine/driver/gke"
+	"gopkg.in/check.v1"
+)
+
+type NodeConfigTestSuite struct{}
+
+var _ = check.Suite(&NodeConfigTestSuite{})
+
+func (s *NodeConfigTestSuite) TestParseNodeLabel(c *check.C) {
+	for _, label := range []string{"team=core", "empty=", "k8s.io/role=worker=1"} {
+		c.Assert(parseNodeLabel(label), check.IsNil, check.Commentf("label %s", label))
+	}
+	for _, label := range []string{"team", "=core", ""} {
+		err := parseNodeLabel(label)
+		c.Assert(err, check.NotNil, check.Commentf("label %s", label))
+		c.Assert(ExitCode(err), check.Equals, ExitValidation)
+	}
+}
+
+func (s *NodeConfigTestSuite) TestParseNodeTaint(c *check.C) {
+	for _, taint := range []string{"dedicated=gpu:NoSchedule", "spot:PreferNoSchedule", "k8s.io/drain=true:NoExecute"} {
+		c.Assert(parseNodeTaint(taint), check.IsNil, check.Commentf("taint %s", taint))
+	}
+	c.Assert(parseNodeTaint("dedicated=gpu"), check.ErrorMatches, `invalid node taint "dedicated=gpu", use KEY\[=VALUE\]:EFFECT`)
+	c.Assert(parseNodeTaint("=gpu:NoSchedule"), check.ErrorMatches, `invalid node taint "=gpu:NoSchedule", the key is required`)
+	c.Assert(parseNodeTaint("dedicated=gpu:noschedule"), check.ErrorMatches, `invalid effect "noschedule" of node taint "dedicated=gpu:noschedule", use NoSchedule, PreferNoSchedule, NoExecute`)
+}
+
+func (s *NodeConfigTestSuite) TestMapToDriverOptions(c *check.C) {
+	driverOptions, err := resolveDriverOptions([]string{
+		"--labels", "direct=1",
+		"--node-label", "team=core",
+		"--node-label", "env=prod",
+		"--node-taint", "dedicated=gpu:NoSchedule",
+		"foo",
+	}, mockCreateFlags(c))
+	c.Assert(err, check.IsNil)
+	// the labels add up with the ones given to the driver option
+	c.Assert(driverOptions.StringSliceOptions["labels"].Value, check.DeepEquals, []string{"direct=1", "team=core", "env=prod"})
+	c.Assert(driverOptions.StringSliceOptions["taints"].Value, check.DeepEquals, []string{"dedicated=gpu:NoSchedule"})
+	c.Assert(driverOptions.IsSet("taints"), check.Equals, true)
+
+	_, err = resolveDriverOptions([]string{"--node-taint", "dedicated=gpu:Sometimes", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.ErrorMatches, `invalid effect "Sometimes" .*`)
+}
+
+func (s *NodeConfigTestSuite) TestDriverWithoutOption(c *check.C) {
+	driverFlags, err := gke.NewDriver().GetDriverCreateOptions()
+	c.Assert(err, check.IsNil)
+	driverOptions, err := resolveDriverOptions([]string{"--node-label", "team=core", "foo"}, *driverFlags)
+	c.Assert(err, check.IsNil)
+	c.Assert(driverOptions.StringSliceOptions["labels"].Value, check.DeepEquals, []string{"team=core"})
+
+	// gke has no taint option
+	_, err = resolveDriverOptions([]string{"--node-taint", "dedicated=gpu:NoSchedule", "foo"}, *driverFlags)
+	c.Assert(err, check.ErrorMatches, "the driver has no node-taints option, --node-taint can't be used")
+	c.Assert(ExitCode(err), check.Equals, ExitValidation)
+
+	// nor a driver declaring the canonical name on an option of another type
+	_, err = resolveDriverOptions([]string{"--node-label", "team=core", "foo"}, rpcDriver.DriverFlags{Options: map[string]*rpcDriver.Flag{
+		"labels": {Type: rpcDriver.StringType, Canonical: rpcDriver.NodeLabelsCanonical},
+	}})
+	c.Assert(err, check.ErrorMatches, "the driver has no node-labels option, --node-label can't be used")
+}
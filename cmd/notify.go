@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+const (
+	notifyURLFlag    = "notify-url"
+	notifySecretFlag = "notify-secret"
+	// notifySignatureHeader carries the HMAC-SHA256 of the body of an event, keyed with the notify secret
+	notifySignatureHeader = "X-Kontainer-Engine-Signature"
+	// notifyAttempts is how many times the delivery of an event is attempted
+	notifyAttempts = 3
+	// notifyQueueSize is how many events wait for their delivery before the next ones are dropped
+	notifyQueueSize = 100
+	notifyTimeout   = 10 * time.Second
+	// notifyFlushTimeout is how long a command waits for the delivery of the events still queued when it exits
+	notifyFlushTimeout = 30 * time.Second
+)
+
+var (
+	// statusNotifier delivers the status transitions of the clusters, nil if --notify-url isn't set
+	statusNotifier *notifier
+	// notifyRetryInterval is the wait between two attempts to deliver an event, tests set it to 0
+	notifyRetryInterval = time.Second
+)
+
+// NotifyFlags returns the global flags of the notifications of the status transitions of the clusters
+func NotifyFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  notifyURLFlag,
+			Usage: "POST a json event to this url every time a cluster changes status, e.g. from Creating to Running, or is removed",
+		},
+		cli.StringFlag{
+			Name:   notifySecretFlag,
+			Usage:  "The secret the events of --" + notifyURLFlag + " are signed with, their HMAC-SHA256 is sent in the " + notifySignatureHeader + " header",
+			EnvVar: "KONTAINER_ENGINE_NOTIFY_SECRET",
+		},
+	}
+}
+
+// SetNotifier starts the delivery of the status events to the url of the global flags
+func SetNotifier(ctx *cli.Context) error {
+	notifyURL, secret := ctx.GlobalString(notifyURLFlag), ctx.GlobalString(notifySecretFlag)
+	if notifyURL == "" {
+		return nil
+	}
+	if parsed, err := url.Parse(notifyURL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return newUsageError("invalid --%s %q, it must be an http or https url", notifyURLFlag, notifyURL)
+	}
+	if secret == "" {
+		return newUsageError("--%s is required with --%s, the events are signed with it", notifySecretFlag, notifyURLFlag)
+	}
+	// the app runs again to parse the driver flags, keep the notifier of the first run
+	if statusNotifier != nil && statusNotifier.url == notifyURL && statusNotifier.secret == secret {
+		return nil
+	}
+	statusNotifier = newNotifier(notifyURL, secret)
+	return nil
+}
+
+// FlushNotifications waits for the delivery of the events still queued, at most notifyFlushTimeout
+func FlushNotifications() {
+	if statusNotifier != nil {
+		statusNotifier.flush(notifyFlushTimeout)
+	}
+}
+
+// statusEvent is the body of the notification of a status transition
+type statusEvent struct {
+	Cluster        string `json:"cluster"`
+	Driver         string `json:"driver"`
+	PreviousStatus string `json:"previousStatus,omitempty"`
+	Status         string `json:"status"`
+	Time           string `json:"time"`
+}
+
+// notifier posts the status events to url one after the other in the order they happen, in the background so that
+// a slow or failing receiver never holds or fails an operation
+type notifier struct {
+	url    string
+	secret string
+	client *http.Client
+	events chan statusEvent
+	done   chan struct{}
+	lock   sync.Mutex
+	closed bool
+	// statuses are the last statuses notified of the clusters
+	statuses map[string]string
+}
+
+func newNotifier(url, secret string) *notifier {
+	n := &notifier{
+		url:      url,
+		secret:   secret,
+		client:   &http.Client{Timeout: notifyTimeout},
+		events:   make(chan statusEvent, notifyQueueSize),
+		done:     make(chan struct{}),
+		statuses: map[string]string{},
+	}
+	go n.deliver()
+	return n
+}
+
+// notify queues the event of cls changing from previous to status, it is dropped if the queue is full
+func (n *notifier) notify(cls cluster.Cluster, previous, status string) {
+	event := statusEvent{
+		Cluster:        cls.Name,
+		Driver:         cls.DriverName,
+		PreviousStatus: previous,
+		Status:         status,
+		Time:           time.Now().UTC().Format(time.RFC3339),
+	}
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.statuses[cls.Name] = status
+	if n.closed {
+		logrus.Warnf("Not notifying cluster %s is %s, the notifications are stopped", cls.Name, status)
+		return
+	}
+	select {
+	case n.events <- event:
+	default:
+		logrus.Warnf("Not notifying cluster %s is %s, %d notifications are already waiting", cls.Name, status, notifyQueueSize)
+	}
+}
+
+// previous returns the last status notified of the cluster name, or stored if none was
+func (n *notifier) previous(name, stored string) string {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if status, ok := n.statuses[name]; ok {
+		return status
+	}
+	return stored
+}
+
+func (n *notifier) deliver() {
+	for event := range n.events {
+		if err := n.send(event); err != nil {
+			logrus.Warnf("Failed to notify %s that cluster %s is %s: %v", n.url, event.Cluster, event.Status, err)
+		}
+	}
+	close(n.done)
+}
+
+// send posts event, the attempts failing or answered with a status other than 2xx are retried up to notifyAttempts
+func (n *notifier) send(event statusEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	mac := hmac.New(sha256.New, []byte(n.secret))
+	mac.Write(body)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	for attempt := 1; ; attempt++ {
+		err = n.post(body, signature)
+		if err == nil || attempt == notifyAttempts {
+			return err
+		}
+		time.Sleep(notifyRetryInterval)
+	}
+}
+
+func (n *notifier) post(body []byte, signature string) error {
+	req, err := http.NewRequest(http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(notifySignatureHeader, signature)
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("the receiver answered %s", resp.Status)
+	}
+	return nil
+}
+
+// flush stops queuing events and waits up to timeout for the delivery of the queued ones
+func (n *notifier) flush(timeout time.Duration) {
+	n.lock.Lock()
+	if !n.closed {
+		n.closed = true
+		close(n.events)
+	}
+	n.lock.Unlock()
+	select {
+	case <-n.done:
+	case <-time.After(timeout):
+		logrus.Warnf("Gave up on the notifications still waiting for %s after %v", n.url, timeout)
+	}
+}
+
+// notifyingStore is a PersistStore notifying the status transitions persisted through it
+type notifyingStore struct {
+	cluster.PersistStore
+	notifier *notifier
+}
+
+func (s notifyingStore) PersistStatus(cls cluster.Cluster, status string) error {
+	// the status of cls may be stale and Store persists it as is, so the stored status is only used for the clusters
+	// this process hasn't notified of yet
+	stored := ""
+	if existing, err := s.PersistStore.Get(cls.Name); err == nil {
+		stored = existing.Status
+	}
+	previous := s.notifier.previous(cls.Name, stored)
+	if err := s.PersistStore.PersistStatus(cls, status); err != nil {
+		return err
+	}
+	if previous != status {
+		s.notifier.notify(cls, previous, status)
+	}
+	return nil
+}
+
+// withNotifications returns persistStore notifying its status transitions if --notify-url is set
+func withNotifications(persistStore cluster.PersistStore) cluster.PersistStore {
+	if statusNotifier == nil {
+		return persistStore
+	}
+	return notifyingStore{PersistStore: persistStore, notifier: statusNotifier}
+}
+
+// notifyRemoved notifies that cls was removed along with its stored config
+func notifyRemoved(cls cluster.Cluster) {
+	if statusNotifier != nil && cls.Status != cluster.Removed {
+		statusNotifier.notify(cls, statusNotifier.previous(cls.Name, cls.Status), cluster.Removed)
+	}
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	"gopkg.in/check.v1"
+)
+
+type NotifyTestSuite struct {
+	tempHomeSuite
+	receiver *httptest.Server
+	lock     sync.Mutex
+	events   []statusEvent
+	attempts int
+	// failures is how many requests the receiver fails before it accepts the events, -1 fails them all
+	failures int
+}
+
+var _ = check.Suite(&NotifyTestSuite{})
+
+func (s *NotifyTestSuite) SetUpTest(c *check.C) {
+	s.tempHomeSuite.SetUpTest(c)
+	notifyRetryInterval = 0
+	s.events, s.attempts, s.failures = nil, 0, 0
+	s.receiver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write(body)
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.attempts++
+		if r.Header.Get(notifySignatureHeader) != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if s.failures != 0 {
+			s.failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		event := statusEvent{}
+		if err := json.Unmarshal(body, &event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.events = append(s.events, event)
+	}))
+	statusNotifier = newNotifier(s.receiver.URL, "s3cret")
+}
+
+func (s *NotifyTestSuite) TearDownTest(c *check.C) {
+	statusNotifier.flush(time.Second)
+	statusNotifier = nil
+	notifyRetryInterval = time.Second
+	s.receiver.Close()
+	s.tempHomeSuite.TearDownTest(c)
+}
+
+// transitions returns the previous and the new status of the events received for cluster
+func (s *NotifyTestSuite) transitions(c *check.C, name string) [][2]string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	transitions := [][2]string{}
+	for _, event := range s.events {
+		c.Assert(event.Driver, check.Equals, "mock")
+		_, err := time.Parse(time.RFC3339, event.Time)
+		c.Assert(err, check.IsNil)
+		if event.Cluster == name {
+			transitions = append(transitions, [2]string{event.PreviousStatus, event.Status})
+		}
+	}
+	return transitions
+}
+
+func (s *NotifyTestSuite) TestNotifyStatusTransitions(c *check.C) {
+	_, err := applyManifest(clusterManifest{Clusters: []clusterSpec{mockSpec("notified", 1), mockSpec("fail-invalid", 1)}}, applyOptions{})
+	c.Assert(err, check.IsNil)
+	cls, err := cliPersistStore{}.Get("notified")
+	c.Assert(err, check.IsNil)
+	c.Assert(removeCluster(context.Background(), cls, staticConfigGetter{driverOptions: newDriverOptions()}, removeOptions{}), check.IsNil)
+	statusNotifier.flush(5 * time.Second)
+
+	c.Assert(s.transitions(c, "notified"), check.DeepEquals, [][2]string{
+		{"", cluster.PreCreating},
+		{cluster.PreCreating, cluster.Creating},
+		{cluster.Creating, cluster.PostCheck},
+		{cluster.PostCheck, cluster.Running},
+		{cluster.Running, cluster.Removed},
+	})
+	c.Assert(s.transitions(c, "fail-invalid"), check.DeepEquals, [][2]string{
+		{"", cluster.PreCreating},
+		{cluster.PreCreating, cluster.Creating},
+		{cluster.Creating, cluster.Error},
+	})
+}
+
+func (s *NotifyTestSuite) TestNotifyRetries(c *check.C) {
+	s.failures = notifyAttempts - 1
+	c.Assert(cliPersistStore{}.Store(migratedCluster("retried", "1.1.1.1")), check.IsNil)
+	cls, err := cliPersistStore{}.Get("retried")
+	c.Assert(err, check.IsNil)
+	c.Assert(newPersistStore(kubeConfigOptions{}).PersistStatus(cls, cluster.Error), check.IsNil)
+	statusNotifier.flush(5 * time.Second)
+	c.Assert(s.transitions(c, "retried"), check.DeepEquals, [][2]string{{cluster.Running, cluster.Error}})
+	c.Assert(s.attempts, check.Equals, notifyAttempts)
+}
+
+func (s *NotifyTestSuite) TestNotifyFailureDoesntFailOperation(c *check.C) {
+	logs := bytes.Buffer{}
+	logrus.SetOutput(&logs)
+	defer logrus.SetOutput(os.Stderr)
+	s.failures = -1
+
+	results, err := applyManifest(clusterManifest{Clusters: []clusterSpec{mockSpec("unheard", 1)}}, applyOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(results[0].Status, check.Equals, "Success")
+	cls, err := cliPersistStore{}.Get("unheard")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.Status, check.Equals, cluster.Running)
+	statusNotifier.flush(5 * time.Second)
+	// every event is attempted notifyAttempts times
+	c.Assert(s.attempts, check.Equals, 4*notifyAttempts)
+	c.Assert(s.transitions(c, "unheard"), check.HasLen, 0)
+	c.Assert(logs.String(), check.Matches, `(?s).*Failed to notify .* that cluster unheard is Running: the receiver answered 503 Service Unavailable.*`)
+}
+
+func (s *NotifyTestSuite) TestSetNotifier(c *check.C) {
+	statusNotifier.flush(time.Second)
+	for _, test := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"--notify-url", "ftp://example.com"}, `invalid --notify-url "ftp://example.com", it must be an http or https url`},
+		{[]string{"--notify-url", s.receiver.URL}, "--notify-secret is required with --notify-url, the events are signed with it"},
+		{[]string{"--notify-url", s.receiver.URL, "--notify-secret", "s3cret"}, ""},
+	} {
+		statusNotifier = nil
+		app := cli.NewApp()
+		app.Flags = NotifyFlags()
+		app.Before = SetNotifier
+		app.Action = func(*cli.Context) error { return nil }
+		err := app.Run(append([]string{"kontainer-engine"}, test.args...))
+		if test.err != "" {
+			c.Assert(err, check.ErrorMatches, test.err)
+			c.Assert(ExitCode(err), check.Equals, ExitUsage)
+			c.Assert(statusNotifier, check.IsNil)
+			continue
+		}
+		c.Assert(err, check.IsNil)
+		c.Assert(statusNotifier, check.NotNil)
+		c.Assert(statusNotifier.url, check.Equals, s.receiver.URL)
+	}
+}
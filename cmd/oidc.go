@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"net/url"
+	"strings"
+
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/urfave/cli"
+)
+
+var (
+	oidcIssuerFlag = cli.StringFlag{
+		Name:  "oidc-issuer",
+		Usage: "The https URL of the OIDC issuer the API server trusts the tokens of, e.g. for workload identity federation, passed to the OIDC issuer option of the driver whatever its name",
+	}
+	oidcClientIDFlag = cli.StringFlag{
+		Name:  "oidc-client-id",
+		Usage: "The client id the tokens of --oidc-issuer must be issued for, passed to the OIDC client id option of the driver whatever its name",
+	}
+	oidcUsernameClaimFlag = cli.StringFlag{
+		Name:  "oidc-username-claim",
+		Usage: "The claim of the OIDC tokens used as the user name, e.g. email, passed to the username claim option of the driver whatever its name",
+	}
+	oidcGroupsClaimFlag = cli.StringFlag{
+		Name:  "oidc-groups-claim",
+		Usage: "The claim of the OIDC tokens used as the groups of the user, passed to the groups claim option of the driver whatever its name",
+	}
+	apiAudienceFlag = cli.StringSliceFlag{
+		Name:  "api-audience",
+		Usage: "An audience the API server accepts the tokens of, passed to the API audiences option of the driver whatever its name. Repeat the flag for several audiences",
+	}
+)
+
+// parseOIDCIssuer validates an --oidc-issuer value, the API servers only trust the issuers served over https
+func parseOIDCIssuer(issuer string) error {
+	parsed, err := url.Parse(issuer)
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" || parsed.User != nil || parsed.RawQuery != "" || parsed.Fragment != "" {
+		return newValidationError("invalid OIDC issuer %q, use an https URL without credentials, query or fragment, e.g. https://issuer.example.com", issuer)
+	}
+	return nil
+}
+
+// parseAPIAudience validates an --api-audience value
+func parseAPIAudience(audience string) error {
+	if audience == "" || strings.ContainsAny(audience, " \t\n,") {
+		return newValidationError("invalid API audience %q, it must be non-empty without spaces or commas", audience)
+	}
+	return nil
+}
+
+// mapOIDCOptions sets the OIDC and API audience options of the driver from the values of --oidc-issuer,
+// --oidc-client-id, the claim flags and --api-audience. Like --network, they are an error if the driver has no such
+// option.
+func mapOIDCOptions(driverOptions *rpcDriver.DriverOptions, driverFlags rpcDriver.DriverFlags) error {
+	// the issuer and the client id can also be given with the options of the driver
+	issuer := driverOptions.StringOptions[oidcIssuerFlag.Name]
+	hasIssuer := issuer != "" || driverOptions.StringOptions[canonicalOption(driverFlags, rpcDriver.OIDCIssuerCanonical)] != ""
+	hasClientID := driverOptions.StringOptions[oidcClientIDFlag.Name] != "" ||
+		driverOptions.StringOptions[canonicalOption(driverFlags, rpcDriver.OIDCClientIDCanonical)] != ""
+	if issuer != "" {
+		if err := parseOIDCIssuer(issuer); err != nil {
+			return err
+		}
+		if !hasClientID {
+			return newUsageError("--%s requires --%s, the API server only accepts the tokens issued for a client id", oidcIssuerFlag.Name, oidcClientIDFlag.Name)
+		}
+	}
+	for _, mapping := range [][2]string{
+		{oidcIssuerFlag.Name, rpcDriver.OIDCIssuerCanonical},
+		{oidcClientIDFlag.Name, rpcDriver.OIDCClientIDCanonical},
+		{oidcUsernameClaimFlag.Name, rpcDriver.OIDCUsernameClaimCanonical},
+		{oidcGroupsClaimFlag.Name, rpcDriver.OIDCGroupsClaimCanonical},
+	} {
+		if mapping[0] != oidcIssuerFlag.Name && !hasIssuer && driverOptions.StringOptions[mapping[0]] != "" {
+			return newUsageError("--%s requires --%s", mapping[0], oidcIssuerFlag.Name)
+		}
+		if err := mapStringOption(driverOptions, driverFlags, mapping[0], mapping[1]); err != nil {
+			return err
+		}
+	}
+	return mapSliceOption(driverOptions, driverFlags, apiAudienceFlag.Name, rpcDriver.APIAudiencesCanonical, parseAPIAudience)
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/rancher/kontainer-engine/driver/mock"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/check.v1"
+)
+
+type OperationTestSuite struct {
+	tempHomeSuite
+	logs bytes.Buffer
+}
+
+var _ = check.Suite(&OperationTestSuite{})
+
+var operationFieldRegexp = regexp.MustCompile(`operation=(\S+)`)
+
+func (s *OperationTestSuite) SetUpTest(c *check.C) {
+	s.tempHomeSuite.SetUpTest(c)
+	s.logs.Reset()
+	logrus.SetOutput(&s.logs)
+	mock.OperationWarnings = []string{"the node quota is 90% used", "the nodes are replaced one at a time"}
+}
+
+func (s *OperationTestSuite) TearDownTest(c *check.C) {
+	mock.OperationWarnings = nil
+	logrus.SetOutput(os.Stderr)
+	s.tempHomeSuite.TearDownTest(c)
+}
+
+// operations returns the operation id of every logged line containing msg, in the order they were logged
+func (s *OperationTestSuite) operations(c *check.C, msg string) []string {
+	ids := []string{}
+	for _, line := range strings.Split(s.logs.String(), "\n") {
+		if !strings.Contains(line, msg) {
+			continue
+		}
+		match := operationFieldRegexp.FindStringSubmatch(line)
+		c.Assert(match, check.NotNil, check.Commentf("line without operation: %s", line))
+		ids = append(ids, match[1])
+	}
+	return ids
+}
+
+func (s *OperationTestSuite) TestOperationIDPerCluster(c *check.C) {
+	spec := mockSpec("unsupported", 1)
+	spec.Driver = "unknown"
+	results, err := applyManifest(clusterManifest{Clusters: []clusterSpec{mockSpec("traced-a", 1), mockSpec("traced-b", 1), spec}}, applyOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(results[2].Status, check.Equals, "Failed")
+	// each create logs both warnings of the driver under its own id
+	creates := s.operations(c, "Driver mock:")
+	c.Assert(creates, check.HasLen, 4)
+	c.Assert(creates[0], check.Equals, creates[1])
+	c.Assert(creates[2], check.Equals, creates[3])
+	c.Assert(creates[0], check.Not(check.Equals), creates[2])
+	failed := s.operations(c, "Failed to create cluster unsupported")
+	c.Assert(failed, check.HasLen, 1)
+	c.Assert(failed[0], check.Not(check.Equals), creates[0])
+	c.Assert(failed[0], check.Not(check.Equals), creates[2])
+
+	// the update of a cluster is another operation than its create
+	s.logs.Reset()
+	_, err = applyManifest(clusterManifest{Clusters: []clusterSpec{mockSpec("traced-a", 2)}}, applyOptions{})
+	c.Assert(err, check.IsNil)
+	updates := s.operations(c, "Driver mock:")
+	c.Assert(updates, check.HasLen, 2)
+	c.Assert(updates[0], check.Equals, updates[1])
+	c.Assert(updates[0], check.Not(check.Equals), creates[0])
+}
+
+func (s *OperationTestSuite) TestServeOperationID(c *check.C) {
+	server := httptest.NewServer(newServeHandler(serveOptions{token: "s3cret"}))
+	defer server.Close()
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/v1/clusters", strings.NewReader(`{"name": "traced", "driver": "mock"}`))
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	req.Header.Set(requestIDHeader, "create-traced")
+	resp, err := http.DefaultClient.Do(req)
+	c.Assert(err, check.IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, check.Equals, http.StatusCreated)
+
+	// the lines of the create have the id of the request, like the line of its outcome
+	c.Assert(s.operations(c, "Driver mock:"), check.DeepEquals, []string{"create-traced", "create-traced"})
+	c.Assert(s.operations(c, "POST /v1/clusters"), check.DeepEquals, []string{"create-traced"})
+}
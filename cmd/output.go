@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	"github.com/sirupsen/logrus"
+)
+
+// OutputJSON is the value of the global output flag that makes results and errors printed as json
+const OutputJSON = "json"
+
+// jsonError is the shape of an error printed with --output json
+type jsonError struct {
+	Error string `json:"error"`
+}
+
+// WriteError reports err to w with the secrets it quotes redacted. With the json output the error is a json object so that callers parsing the output can always decode it
+func WriteError(w io.Writer, output string, err error) error {
+	message := redactMessage(err.Error())
+	if output != OutputJSON {
+		_, writeErr := fmt.Fprintln(w, message)
+		return writeErr
+	}
+	data, marshalErr := json.Marshal(jsonError{Error: message})
+	if marshalErr != nil {
+		return marshalErr
+	}
+	_, writeErr := w.Write(append(data, '\n'))
+	return writeErr
+}
+
+// ReportError reports err, the error a command failed with: it is logged, or with the json output it is written to
+// stdout as a json error
+func ReportError(output string, err error) {
+	if output != OutputJSON {
+		logrus.Error(err)
+		return
+	}
+	WriteError(os.Stdout, output, err)
+}
+
+// operationResult is the result of a create or an update printed with --output json
+type operationResult struct {
+	Name     string   `json:"name"`
+	Driver   string   `json:"driver"`
+	Warnings []string `json:"warnings"`
+}
+
+// writeOperationResult writes the result of the create or the update of cls to w with the json output, the warnings
+// are already logged otherwise
+func writeOperationResult(w io.Writer, output string, cls cluster.Cluster) error {
+	if output != OutputJSON {
+		return nil
+	}
+	result := operationResult{Name: cls.Name, Driver: cls.DriverName, Warnings: cls.Warnings}
+	if result.Warnings == nil {
+		result.Warnings = []string{}
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// resultWriter writes the result of a create to one sink, e.g. stdout or the --write-credentials directory
+type resultWriter struct {
+	// sink names the sink in the errors
+	sink string
+	// onSuccess is set for the sinks reporting the create as done, e.g. stdout, they are only written once the sinks
+	// before them succeeded
+	onSuccess bool
+	write     func(cls cluster.Cluster) error
+}
+
+// writeResults writes cls with every writer, one failing doesn't keep the next ones from writing except the onSuccess
+// ones. The error of a single failing writer is returned as is, the errors of several are returned together with the
+// exit code of the first one.
+func writeResults(writers []resultWriter, cls cluster.Cluster) error {
+	failures := []string{}
+	var first error
+	for _, writer := range writers {
+		if writer.onSuccess && first != nil {
+			continue
+		}
+		if err := writer.write(cls); err != nil {
+			if first == nil {
+				first = err
+			}
+			failures = append(failures, fmt.Sprintf("%s: %v", writer.sink, err))
+		}
+	}
+	if len(failures) <= 1 {
+		return first
+	}
+	return &exitError{code: ExitCode(first), err: fmt.Errorf("failed to write the result to %d sinks: %s", len(failures), strings.Join(failures, "; "))}
+}
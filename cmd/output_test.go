@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	"github.com/rancher/kontainer-engine/driver/mock"
+	"github.com/rancher/kontainer-engine/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	"gopkg.in/check.v1"
+)
+
+type OutputTestSuite struct {
+	tempHomeSuite
+	oldArgs []string
+}
+
+var _ = check.Suite(&OutputTestSuite{})
+
+func (s *OutputTestSuite) SetUpTest(c *check.C) {
+	s.tempHomeSuite.SetUpTest(c)
+	s.oldArgs = os.Args
+}
+
+func (s *OutputTestSuite) TearDownTest(c *check.C) {
+	os.Args = s.oldArgs
+	s.tempHomeSuite.TearDownTest(c)
+}
+
+func newTestApp() *cli.App {
+	app := cli.NewApp()
+	app.Flags = []cli.Flag{
+		cli.BoolFlag{Name: "debug"},
+		cli.StringFlag{Name: "plugin-listen-addr"},
+		cli.StringFlag{Name: "output"},
+	}
+	app.Commands = []cli.Command{
+		CreateCommand(),
+		RmCommand(),
+	}
+	return app
+}
+
+func (s *OutputTestSuite) TestFailingCreateJSONError(c *check.C) {
+	// create looks up the driver in os.Args and runs the app again with them
+	os.Args = []string{"kontainer-engine", "--output", "json", "create", "--driver", "mock", "--node-pool", "count=1", "foo"}
+	err := newTestApp().Run(os.Args)
+	c.Assert(err, check.NotNil)
+
+	buf := bytes.Buffer{}
+	c.Assert(WriteError(&buf, OutputJSON, err), check.IsNil)
+	result := map[string]interface{}{}
+	c.Assert(json.Unmarshal(buf.Bytes(), &result), check.IsNil)
+	c.Assert(result, check.DeepEquals, map[string]interface{}{
+		"error": `invalid node pool "count=1", name is required`,
+	})
+}
+
+func (s *OutputTestSuite) TestPlainError(c *check.C) {
+	buf := bytes.Buffer{}
+	c.Assert(WriteError(&buf, "", errors.New("cluster foo not found")), check.IsNil)
+	c.Assert(buf.String(), check.Equals, "cluster foo not found\n")
+}
+
+func (s *OutputTestSuite) TestNoStoreCreateAndRemove(c *check.C) {
+	defer setStore(fileStore)
+	clusterConfig := filepath.Join(c.MkDir(), "foo.json")
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "--no-store", "--cluster-config", clusterConfig, "foo"}
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+
+	_, err := os.Stat(utils.HomeDir())
+	c.Assert(os.IsNotExist(err), check.Equals, true)
+	data, err := ioutil.ReadFile(clusterConfig)
+	c.Assert(err, check.IsNil)
+	cls, _, err := cluster.Migrate(data)
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.Name, check.Equals, "foo")
+	c.Assert(cls.DriverName, check.Equals, "mock")
+
+	// a new process starts with the file store, --no-store selects the memory store again
+	c.Assert(setStore(fileStore), check.IsNil)
+	os.Args = []string{"kontainer-engine", "rm", "--no-store", "--cluster-config", clusterConfig, "foo"}
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+	_, err = os.Stat(clusterConfig)
+	c.Assert(os.IsNotExist(err), check.Equals, true)
+	_, err = os.Stat(utils.HomeDir())
+	c.Assert(os.IsNotExist(err), check.Equals, true)
+}
+
+// failingPostCheckDriver creates the cluster and then fails to check it
+type failingPostCheckDriver struct {
+	cluster.Driver
+}
+
+func (d failingPostCheckDriver) PostCheck() error {
+	return errors.New("post check failed")
+}
+
+func (s *OutputTestSuite) TestNoStoreFailedCreateCanBeRemoved(c *check.C) {
+	defer setStore(fileStore)
+	clusterConfig := filepath.Join(c.MkDir(), "foo.json")
+	set := flag.NewFlagSet("create", flag.ContinueOnError)
+	for _, f := range []cli.Flag{noStoreFlag, clusterConfigFlag} {
+		f.Apply(set)
+	}
+	c.Assert(set.Parse([]string{"--no-store", "--cluster-config", clusterConfig}), check.IsNil)
+	noStore, err := useNoStore(cli.NewContext(nil, set, nil))
+	c.Assert(err, check.IsNil)
+	c.Assert(noStore, check.Equals, true)
+
+	rpcClient, _, err := runRPCDriver("mock")
+	c.Assert(err, check.IsNil)
+	driverOptions := newDriverOptions()
+	driverOptions.StringOptions["name"] = "foo"
+	cls := &cluster.Cluster{
+		Name:         "foo",
+		DriverName:   "mock",
+		Driver:       failingPostCheckDriver{rpcClient},
+		PersistStore: newPersistStore(kubeConfigOptions{}),
+		ConfigGetter: staticConfigGetter{driverOptions},
+	}
+	c.Assert(cls.CreateContext(context.Background()), check.ErrorMatches, "post check failed")
+
+	// the driver created the cluster, the file has what is needed to remove it
+	data, err := ioutil.ReadFile(clusterConfig)
+	c.Assert(err, check.IsNil)
+	stored, _, err := cluster.Migrate(data)
+	c.Assert(err, check.IsNil)
+	c.Assert(stored.Name, check.Equals, "foo")
+	c.Assert(stored.Status, check.Equals, cluster.Error)
+
+	c.Assert(setStore(fileStore), check.IsNil)
+	os.Args = []string{"kontainer-engine", "rm", "--no-store", "--cluster-config", clusterConfig, "foo"}
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+	_, err = os.Stat(clusterConfig)
+	c.Assert(os.IsNotExist(err), check.Equals, true)
+}
+
+// captureStdout returns what run writes to the standard output
+func captureStdout(c *check.C, run func()) string {
+	file, err := ioutil.TempFile("", "stdout")
+	c.Assert(err, check.IsNil)
+	defer os.Remove(file.Name())
+	stdout := os.Stdout
+	os.Stdout = file
+	defer func() { os.Stdout = stdout }()
+	run()
+	data, err := ioutil.ReadFile(file.Name())
+	c.Assert(err, check.IsNil)
+	return string(data)
+}
+
+func (s *OutputTestSuite) TestDriverWarnings(c *check.C) {
+	logs := bytes.Buffer{}
+	logrus.SetOutput(&logs)
+	defer logrus.SetOutput(os.Stderr)
+	mock.OperationWarnings = []string{"option labels is deprecated", "the node quota is 90% used"}
+	defer func() { mock.OperationWarnings = nil }()
+
+	os.Args = []string{"kontainer-engine", "--output", "json", "create", "--driver", "mock", "foo"}
+	output := captureStdout(c, func() {
+		c.Assert(newTestApp().Run(os.Args), check.IsNil)
+	})
+	result := operationResult{}
+	c.Assert(json.Unmarshal([]byte(output), &result), check.IsNil)
+	c.Assert(result, check.DeepEquals, operationResult{
+		Name:     "foo",
+		Driver:   "mock",
+		Warnings: []string{"option labels is deprecated", "the node quota is 90% used"},
+	})
+	c.Assert(logs.String(), check.Matches, `(?s).*level=warning msg="Driver mock: option labels is deprecated".*`)
+	c.Assert(logs.String(), check.Matches, `(?s).*level=warning msg="Driver mock: the node quota is 90% used".*`)
+
+	// an update reports the warnings too
+	mock.OperationWarnings = []string{"the nodes are replaced one at a time"}
+	app := newTestApp()
+	app.Commands = append(app.Commands, UpdateCommand())
+	os.Args = []string{"kontainer-engine", "--output", "json", "update", "--node-count", "5", "foo"}
+	output = captureStdout(c, func() {
+		c.Assert(app.Run(os.Args), check.IsNil)
+	})
+	c.Assert(output, check.Equals, `{"name":"foo","driver":"mock","warnings":["the nodes are replaced one at a time"]}`+"\n")
+
+	// no warning is an empty list
+	buf := bytes.Buffer{}
+	c.Assert(writeOperationResult(&buf, OutputJSON, cluster.Cluster{Name: "foo", DriverName: "mock"}), check.IsNil)
+	c.Assert(buf.String(), check.Equals, `{"name":"foo","driver":"mock","warnings":[]}`+"\n")
+
+	// the plain output only has the logs
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "bar"}
+	output = captureStdout(c, func() {
+		c.Assert(newTestApp().Run(os.Args), check.IsNil)
+	})
+	c.Assert(output, check.Equals, "")
+}
+
+func (s *OutputTestSuite) TestResultAndCredentials(c *check.C) {
+	dir := filepath.Join(c.MkDir(), "credentials")
+	os.Args = []string{"kontainer-engine", "--output", "json", "create", "--driver", "mock", "--write-credentials", dir, "sinks"}
+	output := captureStdout(c, func() {
+		c.Assert(newTestApp().Run(os.Args), check.IsNil)
+	})
+	// stdout is only the result so that it decodes, the credentials are in the directory
+	c.Assert(output, check.Equals, `{"name":"sinks","driver":"mock","warnings":[]}`+"\n")
+	cls, err := cliPersistStore{}.Get("sinks")
+	c.Assert(err, check.IsNil)
+	token, err := ioutil.ReadFile(filepath.Join(dir, tokenFile))
+	c.Assert(err, check.IsNil)
+	c.Assert(string(token), check.Equals, cls.ServiceAccountToken)
+
+	// a sink failing keeps the result from being printed, stdout is only the error main prints
+	file := filepath.Join(c.MkDir(), "file")
+	c.Assert(ioutil.WriteFile(file, nil, 0600), check.IsNil)
+	os.Args = []string{"kontainer-engine", "--output", "json", "create", "--driver", "mock", "--write-credentials", file, "sinks-failing"}
+	output = captureStdout(c, func() {
+		err := newTestApp().Run(os.Args)
+		c.Assert(err, check.ErrorMatches, ".*not a directory")
+		c.Assert(WriteError(os.Stdout, OutputJSON, err), check.IsNil)
+	})
+	decoder := json.NewDecoder(strings.NewReader(output))
+	failure := map[string]interface{}{}
+	c.Assert(decoder.Decode(&failure), check.IsNil)
+	c.Assert(failure["error"], check.Matches, ".*not a directory")
+	c.Assert(decoder.Decode(&failure), check.Equals, io.EOF)
+}
+
+func (s *OutputTestSuite) TestWriteResults(c *check.C) {
+	written := []string{}
+	writer := func(sink string, err error) resultWriter {
+		return resultWriter{sink: sink, write: func(cls cluster.Cluster) error {
+			written = append(written, sink)
+			return err
+		}}
+	}
+	cls := cluster.Cluster{Name: "foo"}
+	c.Assert(writeResults([]resultWriter{writer("a", nil), writer("b", nil)}, cls), check.IsNil)
+
+	// the error of a single sink is kept as is
+	err := writeResults([]resultWriter{writer("a", newValidationError("a failed")), writer("b", nil)}, cls)
+	c.Assert(err, check.ErrorMatches, "a failed")
+	c.Assert(ExitCode(err), check.Equals, ExitValidation)
+
+	// the sinks reporting the create as done are only written once the others succeeded
+	written = nil
+	done := writer("stdout", nil)
+	done.onSuccess = true
+	c.Assert(writeResults([]resultWriter{writer("a", nil), done}, cls), check.IsNil)
+	c.Assert(written, check.DeepEquals, []string{"a", "stdout"})
+	written = nil
+	c.Assert(writeResults([]resultWriter{writer("a", errors.New("a failed")), writer("b", nil), done}, cls), check.ErrorMatches, "a failed")
+	c.Assert(written, check.DeepEquals, []string{"a", "b"})
+
+	// the errors of several sinks are all reported, with the code of the first one
+	written = nil
+	err = writeResults([]resultWriter{writer("a", newNotFoundError("a failed")), writer("b", errors.New("b failed")), writer("c", nil)}, cls)
+	c.Assert(written, check.DeepEquals, []string{"a", "b", "c"})
+	c.Assert(err, check.ErrorMatches, "failed to write the result to 2 sinks: a: a failed; b: b failed")
+	c.Assert(ExitCode(err), check.Equals, ExitNotFound)
+}
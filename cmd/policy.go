@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"context"
+
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/urfave/cli"
+)
+
+const (
+	operationTimeoutFlag = "operation-timeout"
+	operationRetriesFlag = "operation-retries"
+)
+
+// driverContext is the parent of the calls to the drivers, it carries the operation policies set by
+// SetOperationPolicies
+var driverContext = context.Background()
+
+// OperationFlags returns the global flags setting the timeout and the retries of the driver operations, the default ones
+// and the ones of every operation, e.g. --create-timeout
+func OperationFlags() []cli.Flag {
+	flags := []cli.Flag{
+		cli.DurationFlag{
+			Name:  operationTimeoutFlag,
+			Usage: "How long a create, update, remove or status call of the driver can take before it fails, e.g. 30m. The driver defaults apply if it isn't set",
+		},
+		cli.IntFlag{
+			Name:  operationRetriesFlag,
+			Usage: "How many times a create, update, remove or status call of the driver failing with a transient error, e.g. a conflict, is attempted again",
+		},
+	}
+	for _, operation := range rpcDriver.Operations {
+		flags = append(flags,
+			cli.DurationFlag{
+				Name:  operation + "-timeout",
+				Usage: "How long a " + operation + " call of the driver can take, it takes precedence over --" + operationTimeoutFlag,
+			},
+			cli.IntFlag{
+				Name:  operation + "-retries",
+				Usage: "How many times a failed " + operation + " call of the driver is attempted again, it takes precedence over --" + operationRetriesFlag,
+			},
+		)
+	}
+	return flags
+}
+
+// SetOperationPolicies resolves the policy of every driver operation from the global flags: the flag of the operation
+// takes precedence over the default flag, which takes precedence over the driver defaults
+func SetOperationPolicies(ctx *cli.Context) error {
+	policies, err := operationPolicies(ctx)
+	if err != nil {
+		return err
+	}
+	driverContext = rpcDriver.WithPolicies(context.Background(), policies)
+	return nil
+}
+
+func operationPolicies(ctx *cli.Context) (rpcDriver.Policies, error) {
+	policies := rpcDriver.Policies{}
+	for _, operation := range rpcDriver.Operations {
+		policy := rpcDriver.OperationPolicy{
+			Timeout: ctx.GlobalDuration(operationTimeoutFlag),
+			Retries: ctx.GlobalInt(operationRetriesFlag),
+		}
+		if ctx.GlobalIsSet(operation + "-timeout") {
+			policy.Timeout = ctx.GlobalDuration(operation + "-timeout")
+		}
+		if ctx.GlobalIsSet(operation + "-retries") {
+			policy.Retries = ctx.GlobalInt(operation + "-retries")
+		}
+		if policy.Timeout < 0 || policy.Retries < 0 {
+			return nil, newUsageError("the timeout and the retries of the %s operation can't be negative", operation)
+		}
+		policies[operation] = policy
+	}
+	return policies, nil
+}
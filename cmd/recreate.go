@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/urfave/cli"
+)
+
+var (
+	asCommandFlag = cli.BoolFlag{
+		Name:  "as-command",
+		Usage: "Print the create command that would create the cluster again from its stored options instead of the cluster, secrets are redacted",
+	}
+	// recreateSkippedOptions are the create flags left out of the command, they only change how the original command
+	// asked for its input
+	recreateSkippedOptions = []string{"driver", "name", interactiveFlag.Name, generateNameFlag.Name, credentialStdinFlag.Name}
+	// shellSafe matches the words the shell reads as they are
+	shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+)
+
+// createCommandArgs returns the arguments of the create command that would create cls again, the options that were
+// set when it was created in the order of their names then the name of the cluster. The secrets are redacted since
+// they aren't stored.
+func createCommandArgs(cls cluster.Cluster) ([]string, error) {
+	if cls.Options == nil {
+		return nil, newValidationError("cluster %s has no stored options to create it again from", cls.Name)
+	}
+	options := cls.Options
+	args := []string{"kontainer-engine", "create", "--driver", cls.DriverName}
+	keys := map[string]bool{}
+	for _, key := range options.SetKeys {
+		keys[key] = true
+	}
+	for _, skipped := range recreateSkippedOptions {
+		delete(keys, skipped)
+	}
+	names := []string{}
+	for key := range keys {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		flag := "--" + name + "="
+		if isSecretOption(name, options.SecretKeys...) {
+			args = append(args, flag+redactValue(options.StringOptions[name]))
+			continue
+		}
+		if name == nodePoolFlag.Name {
+			for _, nodePool := range options.NodePools {
+				args = append(args, flag+formatNodePool(nodePool))
+			}
+			continue
+		}
+		if value, ok := options.StringOptions[name]; ok {
+			args = append(args, flag+value)
+		} else if value, ok := options.BoolOptions[name]; ok {
+			args = append(args, flag+strconv.FormatBool(value))
+		} else if value, ok := options.IntOptions[name]; ok {
+			args = append(args, flag+strconv.FormatInt(value, 10))
+		} else if value, ok := options.StringSliceOptions[name]; ok && value != nil {
+			for _, v := range value.Value {
+				args = append(args, flag+v)
+			}
+		}
+	}
+	return append(args, cls.Name), nil
+}
+
+// formatNodePool formats nodePool as a value of --node-pool
+func formatNodePool(nodePool *rpcDriver.NodePool) string {
+	value := fmt.Sprintf("name=%s,count=%d", nodePool.Name, nodePool.Count)
+	if nodePool.MachineType != "" {
+		value += ",machine=" + nodePool.MachineType
+	}
+	return value
+}
+
+// shellQuote quotes word in single quotes unless the shell reads it as it is. The value of a --flag=value word is
+// quoted on its own so that the flag stays readable.
+func shellQuote(word string) string {
+	if shellSafe.MatchString(word) {
+		return word
+	}
+	if strings.HasPrefix(word, "--") {
+		if i := strings.Index(word, "="); i > 0 && shellSafe.MatchString(word[:i+1]) {
+			return word[:i+1] + shellQuote(word[i+1:])
+		}
+	}
+	return "'" + strings.Replace(word, "'", `'\''`, -1) + "'"
+}
+
+// shellJoin joins args in a command line the shell splits back into args
+func shellJoin(args []string) string {
+	words := make([]string, len(args))
+	for i, arg := range args {
+		words[i] = shellQuote(arg)
+	}
+	return strings.Join(words, " ")
+}
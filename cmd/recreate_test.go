@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"os"
+
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"gopkg.in/check.v1"
+)
+
+type RecreateTestSuite struct {
+	tempHomeSuite
+	oldArgs []string
+}
+
+var _ = check.Suite(&RecreateTestSuite{})
+
+func (s *RecreateTestSuite) SetUpTest(c *check.C) {
+	s.tempHomeSuite.SetUpTest(c)
+	s.oldArgs = os.Args
+}
+
+func (s *RecreateTestSuite) TearDownTest(c *check.C) {
+	os.Args = s.oldArgs
+	s.tempHomeSuite.TearDownTest(c)
+}
+
+// splitShellWords splits a command line of words quoted like shellQuote does
+func splitShellWords(line string) []string {
+	words := []string{}
+	word, inWord, quoted := "", false, false
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+		switch {
+		case quoted && ch == '\'':
+			quoted = false
+		case quoted:
+			word += string(ch)
+		case ch == '\'':
+			quoted, inWord = true, true
+		case ch == '\\' && i+1 < len(line):
+			i++
+			word, inWord = word+string(line[i]), true
+		case ch == ' ':
+			if inWord {
+				words = append(words, word)
+			}
+			word, inWord = "", false
+		default:
+			word, inWord = word+string(ch), true
+		}
+	}
+	if inWord {
+		words = append(words, word)
+	}
+	return words
+}
+
+func (s *RecreateTestSuite) TestCreateCommandRoundTrip(c *check.C) {
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "--credential", "s3cret",
+		"--description", "it's a test", "--labels", "a=b", "--labels", "c=d", "--node-count", "3",
+		"--enable-alpha-feature", "original"}
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+	cls, err := cliPersistStore{}.Get("original")
+	c.Assert(err, check.IsNil)
+
+	args, err := createCommandArgs(cls)
+	c.Assert(err, check.IsNil)
+	line := shellJoin(args)
+	c.Assert(line, check.Equals, `kontainer-engine create --driver mock --credential=Redacted --description='it'\''s a test' `+
+		`--enable-alpha-feature=true --labels=a=b --labels=c=d --node-count=3 original`)
+	c.Assert(splitShellWords(line), check.DeepEquals, args)
+
+	// the command sends the driver the stored options again, the credential aside
+	driverOptions, err := resolveDriverOptions(args[2:], mockCreateFlags(c))
+	c.Assert(err, check.IsNil)
+	c.Assert(driverOptions.StringOptions[rpcDriver.CredentialOption], check.Equals, "Redacted")
+	delete(driverOptions.StringOptions, rpcDriver.CredentialOption)
+	delete(driverOptions.StringOptions, "name")
+	for _, key := range []string{"description", "vpc-id"} {
+		c.Assert(driverOptions.StringOptions[key], check.Equals, cls.Options.StringOptions[key])
+	}
+	c.Assert(driverOptions.IntOptions["node-count"], check.Equals, cls.Options.IntOptions["node-count"])
+	c.Assert(driverOptions.BoolOptions["enable-alpha-feature"], check.Equals, true)
+	c.Assert(driverOptions.StringSliceOptions["labels"].Value, check.DeepEquals, []string{"a=b", "c=d"})
+}
+
+func (s *RecreateTestSuite) TestShellQuote(c *check.C) {
+	c.Assert(shellQuote("plain-value_1.2"), check.Equals, "plain-value_1.2")
+	c.Assert(shellQuote(""), check.Equals, "''")
+	c.Assert(shellQuote("--description="), check.Equals, "--description=")
+	c.Assert(shellQuote("--description=$HOME `id`"), check.Equals, "--description='$HOME `id`'")
+	c.Assert(shellQuote("a b"), check.Equals, "'a b'")
+	c.Assert(shellQuote("it's"), check.Equals, `'it'\''s'`)
+}
+
+func (s *RecreateTestSuite) TestInspectAsCommand(c *check.C) {
+	app := newTestApp()
+	app.Commands = append(app.Commands, InspectCommand())
+	err := app.Run([]string{"kontainer-engine", "inspect", "--as-command", "missing"})
+	c.Assert(err, check.ErrorMatches, "cluster missing can't be found")
+	c.Assert(ExitCode(err), check.Equals, ExitNotFound)
+
+	c.Assert(cliPersistStore{}.Store(migratedCluster("no-options", "1.1.1.1")), check.IsNil)
+	err = app.Run([]string{"kontainer-engine", "inspect", "--as-command", "no-options"})
+	c.Assert(err, check.ErrorMatches, "cluster no-options has no stored options to create it again from")
+	c.Assert(ExitCode(err), check.Equals, ExitValidation)
+}
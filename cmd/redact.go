@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+)
+
+const (
+	// redactedValue replaces the secrets in what is printed, logged or returned in an error
+	redactedValue = "Redacted"
+	// redactionHintLength is how many of the last characters of a secret are kept as a hint of which secret is used
+	redactionHintLength = 4
+	// minHintedSecretLength is the length of the shortest secret given a hint, the hint of a shorter one would give
+	// away too much of it
+	minHintedSecretLength = 12
+)
+
+var (
+	// secretOptionNames are the parts of option names whose values are redacted, the drivers can declare more secret
+	// options with the secret flag of their options
+	secretOptionNames = []string{"credential", "password", "secret", "token", "-key"}
+	// publicOptionNames are the names of options that only point to secrets, they help to debug which secret is used
+	publicOptionNames = []string{"gke-credential-path", "write-credentials", "credential-profile"}
+
+	// secretValues are the values of the secret options resolved by this process, they are redacted from the error
+	// messages as the drivers may quote them
+	secretValues     = map[string]bool{}
+	secretValuesLock sync.Mutex
+)
+
+// isSecretOption reports whether the option or metadata key called name holds a secret, because its name looks like
+// one or it is in declared, the options the driver declares secret
+func isSecretOption(name string, declared ...string) bool {
+	for _, secret := range declared {
+		if name == secret {
+			return true
+		}
+	}
+	for _, public := range publicOptionNames {
+		if name == public {
+			return false
+		}
+	}
+	for _, secret := range secretOptionNames {
+		if strings.Contains(name, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactValue returns what is shown instead of secret: Redacted followed by the last characters of secret as a hint,
+// e.g. Redacted...f00d, or only Redacted if the secret is too short for a hint
+func redactValue(secret string) string {
+	runes := []rune(strings.TrimSpace(secret))
+	if len(runes) < minHintedSecretLength {
+		return redactedValue
+	}
+	return redactedValue + "..." + string(runes[len(runes)-redactionHintLength:])
+}
+
+// markSecretOptions records in driverOptions the options driverFlags declares secret, so that they are still redacted
+// once the cluster is stored, and remembers the values of all the secret options to redact them from the errors
+func markSecretOptions(driverOptions *rpcDriver.DriverOptions, driverFlags rpcDriver.DriverFlags) {
+	driverOptions.SecretKeys = nil
+	for name, flag := range driverFlags.Options {
+		if flag.Secret {
+			driverOptions.SecretKeys = append(driverOptions.SecretKeys, name)
+		}
+	}
+	sort.Strings(driverOptions.SecretKeys)
+
+	secretValuesLock.Lock()
+	defer secretValuesLock.Unlock()
+	for name, value := range driverOptions.StringOptions {
+		if isSecretOption(name, driverOptions.SecretKeys...) {
+			rememberSecret(value)
+		}
+	}
+	for name, values := range driverOptions.StringSliceOptions {
+		if values != nil && isSecretOption(name, driverOptions.SecretKeys...) {
+			for _, value := range values.Value {
+				rememberSecret(value)
+			}
+		}
+	}
+}
+
+// applySecretDefaults sets the secret string options that aren't set to their default, which getDriverFlags leaves
+// out of the flags so that the help doesn't print it
+func applySecretDefaults(driverOptions *rpcDriver.DriverOptions, driverFlags rpcDriver.DriverFlags) {
+	for name, flag := range driverFlags.Options {
+		if flag.Secret && flag.Type == rpcDriver.StringType && flag.Value != "" && !driverOptions.IsSet(name) {
+			driverOptions.StringOptions[name] = flag.Value
+		}
+	}
+}
+
+// rememberSecret records value as a secret to redact from the error messages. The short values aren't, they would
+// match the other words of the messages.
+func rememberSecret(value string) {
+	if len(strings.TrimSpace(value)) >= minHintedSecretLength {
+		secretValues[value] = true
+	}
+}
+
+// redactMessage replaces the secret values of the options resolved by this process in message, e.g. an error message
+func redactMessage(message string) string {
+	secretValuesLock.Lock()
+	defer secretValuesLock.Unlock()
+	for secret := range secretValues {
+		message = strings.Replace(message, secret, redactValue(secret), -1)
+	}
+	return message
+}
+
+// redactDriverOptions replaces the values of the options that look like secrets or are declared secret
+func redactDriverOptions(driverOptions rpcDriver.DriverOptions) rpcDriver.DriverOptions {
+	redacted := driverOptions
+	redacted.StringOptions = map[string]string{}
+	for k, v := range driverOptions.StringOptions {
+		if v != "" && isSecretOption(k, driverOptions.SecretKeys...) {
+			v = redactValue(v)
+		}
+		redacted.StringOptions[k] = v
+	}
+	redacted.StringSliceOptions = map[string]*rpcDriver.StringSlice{}
+	for k, v := range driverOptions.StringSliceOptions {
+		if v != nil && len(v.Value) > 0 && isSecretOption(k, driverOptions.SecretKeys...) {
+			values := []string{}
+			for _, value := range v.Value {
+				values = append(values, redactValue(value))
+			}
+			v = &rpcDriver.StringSlice{Value: values}
+		}
+		redacted.StringSliceOptions[k] = v
+	}
+	return redacted
+}
+
+// redactMetadata returns a copy of metadata with the values of the secret keys redacted
+func redactMetadata(metadata map[string]string) map[string]string {
+	if metadata == nil {
+		return nil
+	}
+	redacted := map[string]string{}
+	for k, v := range metadata {
+		if v != "" && isSecretOption(k) {
+			v = redactValue(v)
+		}
+		redacted[k] = v
+	}
+	return redacted
+}
+
+// redactCluster returns a copy of cls without its credentials, its secret metadata and its secret options
+func redactCluster(cls cluster.Cluster) cluster.Cluster {
+	for _, secret := range []*string{&cls.Password, &cls.ServiceAccountToken, &cls.ClientCertificate, &cls.ClientKey, &cls.RootCACert} {
+		if *secret != "" {
+			*secret = redactValue(*secret)
+		}
+	}
+	cls.Metadata = redactMetadata(cls.Metadata)
+	if cls.Options != nil {
+		options := redactDriverOptions(*cls.Options)
+		cls.Options = &options
+	}
+	return cls
+}
+
+// redactClusterInfo returns a copy of info without the credentials and the secret metadata
+func redactClusterInfo(info rpcDriver.ClusterInfo) rpcDriver.ClusterInfo {
+	for _, secret := range []*string{&info.Password, &info.ServiceAccountToken, &info.ClientCertificate, &info.ClientKey} {
+		if *secret != "" {
+			*secret = redactValue(*secret)
+		}
+	}
+	info.Metadata = redactMetadata(info.Metadata)
+	return info
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/rancher/kontainer-engine/utils"
+	"github.com/urfave/cli"
+	"gopkg.in/check.v1"
+)
+
+type RedactTestSuite struct {
+	tempHomeSuite
+}
+
+var _ = check.Suite(&RedactTestSuite{})
+
+// patFlags are driver flags declaring secret an option whose name doesn't look like a secret
+var patFlags = rpcDriver.DriverFlags{Options: map[string]*rpcDriver.Flag{
+	"provider-pat": {Type: rpcDriver.StringType, Secret: true},
+	"region":       {Type: rpcDriver.StringType},
+}}
+
+// redactedCluster returns a stored cluster with credentials, secret metadata and a declared secret option
+func redactedCluster(name string) cluster.Cluster {
+	cls := migratedCluster(name, "1.1.1.1")
+	cls.ServiceAccountToken = "sa-token-0123456789-a1b2"
+	cls.Password = "short"
+	cls.Metadata = map[string]string{"access-token": "metadata-token-c3d4", "zone": "us-east"}
+	options := newDriverOptions()
+	options.StringOptions["provider-pat"] = "pat-0123456789-e5f6"
+	options.StringOptions["region"] = "us-east-1"
+	options.SetKeys = []string{"provider-pat", "region"}
+	options.SecretKeys = []string{"provider-pat"}
+	cls.Options = &options
+	return cls
+}
+
+func (s *RedactTestSuite) TestRedactValue(c *check.C) {
+	c.Assert(redactValue("short"), check.Equals, "Redacted")
+	c.Assert(redactValue("0123456789a"), check.Equals, "Redacted")
+	c.Assert(redactValue("0123456789ab"), check.Equals, "Redacted...89ab")
+	c.Assert(redactValue("-----BEGIN KEY-----\nMIIEvQIBADAN\n"), check.Equals, "Redacted...ADAN")
+}
+
+func (s *RedactTestSuite) TestDeclaredSecretOptions(c *check.C) {
+	c.Assert(isSecretOption("provider-pat"), check.Equals, false)
+	c.Assert(isSecretOption("provider-pat", "provider-pat"), check.Equals, true)
+	c.Assert(isSecretOption("client-key"), check.Equals, true)
+
+	driverOptions := newDriverOptions()
+	driverOptions.StringOptions["provider-pat"] = "pat-declared-0123-g7h8"
+	driverOptions.StringOptions["region"] = "us-east-1"
+	markSecretOptions(&driverOptions, patFlags)
+	c.Assert(driverOptions.SecretKeys, check.DeepEquals, []string{"provider-pat"})
+	redacted := redactDriverOptions(driverOptions)
+	c.Assert(redacted.StringOptions["provider-pat"], check.Equals, "Redacted...g7h8")
+	c.Assert(redacted.StringOptions["region"], check.Equals, "us-east-1")
+
+	// the values of the secret options are redacted from the messages once they are resolved
+	c.Assert(redactMessage("the driver rejected pat-declared-0123-g7h8 for us-east-1"), check.Equals,
+		"the driver rejected Redacted...g7h8 for us-east-1")
+}
+
+func (s *RedactTestSuite) TestInspect(c *check.C) {
+	c.Assert(cliPersistStore{}.Store(redactedCluster("inspected")), check.IsNil)
+	app := newTestApp()
+	app.Commands = []cli.Command{InspectCommand()}
+	var err error
+	output := captureStdout(c, func() {
+		err = app.Run([]string{"kontainer-engine", "inspect", "inspected"})
+	})
+	c.Assert(err, check.IsNil)
+	inspected := cluster.Cluster{}
+	c.Assert(json.Unmarshal([]byte(output), &inspected), check.IsNil)
+	c.Assert(inspected.ServiceAccountToken, check.Equals, "Redacted...a1b2")
+	c.Assert(inspected.Password, check.Equals, "Redacted")
+	c.Assert(inspected.RootCACert, check.Equals, "Redacted...LWNh")
+	c.Assert(inspected.Metadata, check.DeepEquals, map[string]string{"access-token": "Redacted...c3d4", "zone": "us-east"})
+	c.Assert(inspected.Options.StringOptions["provider-pat"], check.Equals, "Redacted...e5f6")
+	c.Assert(inspected.Options.StringOptions["region"], check.Equals, "us-east-1")
+}
+
+func (s *RedactTestSuite) TestSecretDriverOption(c *check.C) {
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "--registry-auth", "registry-auth-custom-9z8y", "declared"}
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+	app := newTestApp()
+	app.Commands = []cli.Command{InspectCommand(), ConfigCommand()}
+	output := captureStdout(c, func() {
+		c.Assert(app.Run([]string{"kontainer-engine", "inspect", "declared"}), check.IsNil)
+	})
+	inspected := cluster.Cluster{}
+	c.Assert(json.Unmarshal([]byte(output), &inspected), check.IsNil)
+	c.Assert(inspected.Options.SecretKeys, check.DeepEquals, []string{"credential", "registry-auth"})
+	// the secret isn't stored, so there is nothing to redact
+	_, stored := inspected.Options.StringOptions["registry-auth"]
+	c.Assert(stored, check.Equals, false)
+	data, err := ioutil.ReadFile(filepath.Join(utils.HomeDir(), "clusters", "declared", defaultConfigName))
+	c.Assert(err, check.IsNil)
+	c.Assert(strings.Contains(string(data), "registry-auth-custom-9z8y"), check.Equals, false)
+
+	// the hidden default is redacted like a given value
+	output = captureStdout(c, func() {
+		c.Assert(app.Run([]string{"kontainer-engine", "config", "resolve", "--driver", "mock", "resolved"}), check.IsNil)
+	})
+	resolved := rpcDriver.DriverOptions{}
+	c.Assert(json.Unmarshal([]byte(output), &resolved), check.IsNil)
+	c.Assert(resolved.StringOptions["registry-auth"], check.Equals, "Redacted...d3f4")
+}
+
+func (s *RedactTestSuite) TestAsCommand(c *check.C) {
+	args, err := createCommandArgs(redactedCluster("recreated"))
+	c.Assert(err, check.IsNil)
+	c.Assert(shellJoin(args), check.Equals, "kontainer-engine create --driver mock --provider-pat=Redacted...e5f6 --region=us-east-1 recreated")
+}
+
+func (s *RedactTestSuite) TestCompare(c *check.C) {
+	a, b := redactedCluster("a"), redactedCluster("b")
+	b.Options.StringOptions["provider-pat"] = "pat-9876543210-i9j0"
+	c.Assert(diffClusterConfigs(a, b), check.DeepEquals, []configDifference{
+		{Field: "options.provider-pat", A: "Redacted...e5f6", B: "Redacted...i9j0"},
+	})
+}
+
+func (s *RedactTestSuite) TestErrors(c *check.C) {
+	// an invalid value doesn't give away the secret
+	spec := clusterSpec{Name: "invalid", Driver: "mock", Options: map[string]interface{}{"provider-pat": []interface{}{"pat-in-a-list-k1l2"}}}
+	_, err := toDriverOptions(spec, patFlags, true)
+	c.Assert(err, check.ErrorMatches, `invalid value for option provider-pat of cluster invalid: expected string but got Redacted\.\.\.1l2\]`)
+
+	// neither do the errors quoting the value of a secret option once it is resolved
+	spec.Options["provider-pat"] = "pat-resolved-0123-m3n4"
+	_, err = toDriverOptions(spec, patFlags, true)
+	c.Assert(err, check.IsNil)
+	quoting := errors.New("the token pat-resolved-0123-m3n4 is expired")
+	for output, expected := range map[string]string{
+		"":         "the token Redacted...m3n4 is expired\n",
+		OutputJSON: `{"error":"the token Redacted...m3n4 is expired"}` + "\n",
+	} {
+		written := bytes.Buffer{}
+		c.Assert(WriteError(&written, output, quoting), check.IsNil)
+		c.Assert(written.String(), check.Equals, expected)
+	}
+	result := newApplyResult(newOperationContext(), "expired", applyCreate, quoting)
+	c.Assert(result.Error, check.Equals, "the token Redacted...m3n4 is expired")
+	recorder := httptest.NewRecorder()
+	writeServeError(recorder, quoting)
+	c.Assert(recorder.Body.String(), check.Matches, `\{"error":"the token Redacted\.\.\.m3n4 is expired",.*\n`)
+}
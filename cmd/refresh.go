@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/rancher/kontainer-engine/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+// RefreshCommand defines the refresh command
+func RefreshCommand() cli.Command {
+	return cli.Command{
+		Name:      "refresh",
+		Usage:     "Store the current endpoint, CA and credentials the driver reports for a cluster and regenerate its kubeconfig, without changing the cluster",
+		ArgsUsage: "cluster-name",
+		Action:    refreshCluster,
+		Flags: []cli.Flag{
+			allowVersionMismatchFlag,
+			noWaitFlag,
+		},
+	}
+}
+
+func refreshCluster(ctx *cli.Context) error {
+	name := ctx.Args().First()
+	if name == "" {
+		return usageErrorWithHelp(ctx, "refresh", "cluster name is required")
+	}
+	if err := checkWritable(name); err != nil {
+		return err
+	}
+	lock, err := lockCluster(ctx, name, "refresh")
+	if err != nil {
+		return err
+	}
+	defer unlockCluster(name, lock)
+	clusters, err := getAllClusters()
+	if err != nil {
+		return err
+	}
+	cls, ok := clusters[name]
+	if !ok {
+		return newNotFoundError("cluster %v can't be found", name)
+	}
+	rpcClient, _, err := runRPCDriver(cls.DriverName)
+	if err != nil {
+		return err
+	}
+	cls.ConfigGetter = updateConfigGetter{
+		name:   name,
+		ctx:    ctx,
+		stored: cls.Options,
+	}
+	// the kubeconfig entries are written again below, also when they already exist
+	cls.PersistStore = newPersistStore(kubeConfigOptions{skip: true})
+	cls.Driver = rpcClient
+	if err := verifyDriverVersion(context.Background(), &cls, ctx.Bool(allowVersionMismatchFlag.Name)); err != nil {
+		return err
+	}
+	changed, err := cls.Refresh()
+	if err != nil {
+		return err
+	}
+	if len(changed) == 0 {
+		logrus.Infof("Cluster %s is up to date", name)
+	} else {
+		logrus.Infof("Refreshed %s of cluster %s", strings.Join(changed, ", "), name)
+	}
+	if selectedStore != nil {
+		return nil
+	}
+	if _, err := os.Stat(utils.KubeConfigFilePath()); err == nil {
+		if err := deleteKubeConfigEntries(name); err != nil {
+			return err
+		}
+	}
+	return storeConfig(cls, kubeConfigOptions{})
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	"github.com/rancher/kontainer-engine/driver/mock"
+	"gopkg.in/check.v1"
+)
+
+type RefreshTestSuite struct {
+	tempHomeSuite
+	oldArgs []string
+}
+
+var _ = check.Suite(&RefreshTestSuite{})
+
+func (s *RefreshTestSuite) SetUpTest(c *check.C) {
+	s.tempHomeSuite.SetUpTest(c)
+	s.oldArgs = os.Args
+}
+
+func (s *RefreshTestSuite) TearDownTest(c *check.C) {
+	os.Args = s.oldArgs
+	s.tempHomeSuite.TearDownTest(c)
+}
+
+func (s *RefreshTestSuite) TestRefreshStoresMovedEndpoint(c *check.C) {
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "--node-count", "2", "moved"}
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+	created, err := cliPersistStore{}.Get("moved")
+	c.Assert(err, check.IsNil)
+	c.Assert(created.Endpoint, check.Equals, "moved.mock.local")
+
+	c.Assert(mock.MoveEndpoint("moved", "10.0.0.2"), check.IsNil)
+	app := newTestApp()
+	app.Commands = append(app.Commands, RefreshCommand())
+	c.Assert(app.Run([]string{"kontainer-engine", "refresh", "moved"}), check.IsNil)
+
+	cls, err := cliPersistStore{}.Get("moved")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.Endpoint, check.Equals, "10.0.0.2")
+	c.Assert(cls.RootCACert, check.Not(check.Equals), created.RootCACert)
+	// nothing else changes
+	c.Assert(cls.Status, check.Equals, cluster.Running)
+	c.Assert(cls.NodeCount, check.Equals, int64(2))
+	c.Assert(cls.SpecHash, check.Equals, created.SpecHash)
+
+	// the stale kubeconfig entries are replaced
+	config, err := getConfigFromFile()
+	c.Assert(err, check.IsNil)
+	c.Assert(config.Clusters, check.HasLen, 1)
+	c.Assert(config.Clusters[0].Cluster.Server, check.Equals, "https://10.0.0.2")
+	c.Assert(config.Clusters[0].Cluster.CertificateAuthorityData, check.Equals, cls.RootCACert)
+	c.Assert(config.Users, check.HasLen, 1)
+	c.Assert(config.Contexts, check.HasLen, 1)
+}
+
+func (s *RefreshTestSuite) TestRefreshUnchanged(c *check.C) {
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "--no-kubeconfig", "same"}
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+	cls, err := cliPersistStore{}.Get("same")
+	c.Assert(err, check.IsNil)
+	rpcClient, _, err := runRPCDriver(cls.DriverName)
+	c.Assert(err, check.IsNil)
+	cls.Driver = rpcClient
+	cls.ConfigGetter = updateConfigGetter{name: "same", ctx: updateContext(c, "same"), stored: cls.Options}
+	cls.PersistStore = cliPersistStore{kubeConfig: kubeConfigOptions{skip: true}}
+	changed, err := cls.Refresh()
+	c.Assert(err, check.IsNil)
+	c.Assert(changed, check.HasLen, 0)
+
+	app := newTestApp()
+	app.Commands = append(app.Commands, RefreshCommand())
+	err = app.Run([]string{"kontainer-engine", "refresh", "missing"})
+	c.Assert(err, check.ErrorMatches, "cluster missing can't be found")
+	c.Assert(ExitCode(err), check.Equals, ExitNotFound)
+}
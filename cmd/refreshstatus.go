@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	generic "github.com/rancher/kontainer-engine/driver"
+	"github.com/rancher/kontainer-engine/plugin"
+	"github.com/rancher/kontainer-engine/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+const (
+	statusChanged   = "Changed"
+	statusUnchanged = "Unchanged"
+	statusSkipped   = "Skipped"
+)
+
+// RefreshStatusCommand defines the refresh-status command
+func RefreshStatusCommand() cli.Command {
+	return cli.Command{
+		Name:      "refresh-status",
+		Usage:     "Ask the drivers for the live status of clusters and store it",
+		ArgsUsage: "[cluster-name...]",
+		Action:    refreshStatuses,
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "all",
+				Usage: "Refresh the status of every stored cluster",
+			},
+			cli.IntFlag{
+				Name:  "concurrency",
+				Usage: "How many drivers are asked for the status of their cluster at the same time",
+				Value: 4,
+			},
+		},
+	}
+}
+
+type statusRefresh struct {
+	Name     string
+	Driver   string
+	Previous string
+	Status   string
+	Result   string
+	// Message is why the cluster was skipped
+	Message string
+}
+
+func refreshStatuses(ctx *cli.Context) error {
+	all := ctx.Bool("all")
+	if all == (ctx.NArg() > 0) {
+		return usageErrorWithHelp(ctx, "refresh-status", "either --all or the cluster names are required")
+	}
+	concurrency := ctx.Int("concurrency")
+	if concurrency < 1 {
+		return newUsageError("--concurrency must be at least 1")
+	}
+	if readOnly {
+		return newUsageError("refresh-status can't be used with --read-only")
+	}
+	clusters, err := getAllClusters()
+	if err != nil {
+		return err
+	}
+	names := ctx.Args()
+	if all {
+		names = []string{}
+		for name := range clusters {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	selected := []cluster.Cluster{}
+	for _, name := range names {
+		cls, ok := clusters[name]
+		if !ok {
+			return newNotFoundError("cluster %v can't be found", name)
+		}
+		selected = append(selected, cls)
+	}
+
+	results := refreshClusterStatuses(selected, newPersistStore(kubeConfigOptions{skip: true}), concurrency)
+	writer := utils.NewTableWriter([][]string{
+		{"NAME", "Name"},
+		{"DRIVER", "Driver"},
+		{"PREVIOUS", "Previous"},
+		{"STATUS", "Status"},
+		{"RESULT", "Result"},
+		{"MESSAGE", "Message"},
+	}, ctx)
+	changed, skipped := 0, 0
+	for _, result := range results {
+		writer.Write(result)
+		switch result.Result {
+		case statusChanged:
+			changed++
+		case statusSkipped:
+			skipped++
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	logrus.Infof("Refreshed the status of %d clusters, %d changed, %d skipped", len(results), changed, skipped)
+	return nil
+}
+
+// refreshClusterStatuses asks the drivers of clusters for their live status, concurrency drivers at a time, and
+// persists the statuses that changed with persistStore. The results are in the order of clusters. A cluster whose
+// driver isn't available or can't report statuses is skipped with a warning, so that it doesn't stop the others.
+func refreshClusterStatuses(clusters []cluster.Cluster, persistStore cluster.PersistStore, concurrency int) []statusRefresh {
+	results := make([]statusRefresh, len(clusters))
+	// the options are resolved before the drivers are started, the credential providers aren't safe to share
+	options := make([]generic.DriverOptions, len(clusters))
+	for i, cls := range clusters {
+		results[i] = statusRefresh{Name: cls.Name, Driver: cls.DriverName, Previous: cls.Status, Status: cls.Status}
+		driverOptions, err := statusDriverOptions(cls)
+		if err != nil {
+			skipStatusRefresh(&results[i], err)
+			continue
+		}
+		options[i] = driverOptions
+	}
+
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	for worker := 0; worker < concurrency; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				refreshClusterStatus(clusters[i], options[i], persistStore, &results[i])
+			}
+		}()
+	}
+	for i := range clusters {
+		if results[i].Result == "" {
+			indexes <- i
+		}
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+func refreshClusterStatus(cls cluster.Cluster, driverOptions generic.DriverOptions, persistStore cluster.PersistStore, result *statusRefresh) {
+	// a cluster being created, updated or removed is left to the command holding its lock
+	lock, err := tryLockCluster(cls.Name, "refresh-status")
+	if err != nil {
+		skipStatusRefresh(result, err)
+		return
+	}
+	defer unlockCluster(cls.Name, lock)
+	status, err := liveClusterStatus(cls, driverOptions)
+	if err != nil {
+		skipStatusRefresh(result, err)
+		return
+	}
+	result.Status = status
+	if status == cls.Status {
+		result.Result = statusUnchanged
+		return
+	}
+	if err := persistStore.PersistStatus(cls, status); err != nil {
+		result.Status = cls.Status
+		skipStatusRefresh(result, fmt.Errorf("failed to store the status: %v", err))
+		return
+	}
+	result.Result = statusChanged
+}
+
+func skipStatusRefresh(result *statusRefresh, err error) {
+	logrus.Warnf("Skipping the status of cluster %s: %v", result.Name, err)
+	result.Result = statusSkipped
+	result.Message = err.Error()
+}
+
+// statusDriverOptions returns the stored options of cls with its credential, which the driver needs to find the
+// cluster
+func statusDriverOptions(cls cluster.Cluster) (generic.DriverOptions, error) {
+	driverOptions := *generic.CopyDriverOptions(cls.Options)
+	if err := resolveCredentialProfile(&driverOptions); err != nil {
+		return driverOptions, err
+	}
+	if err := resolveCredentialProvider(&driverOptions); err != nil {
+		return driverOptions, err
+	}
+	for k, v := range cls.Metadata {
+		driverOptions.StringOptions[k] = v
+	}
+	driverOptions.StringOptions["name"] = cls.Name
+	return driverOptions, nil
+}
+
+// liveClusterStatus asks the driver of cls where the cluster is and returns the status to store for it: Running if
+// the provider has it, Deleting while the provider deletes it and Removed once the provider doesn't have it
+func liveClusterStatus(cls cluster.Cluster, driverOptions generic.DriverOptions) (string, error) {
+	if driverAddr == "" && !plugin.BuiltInDrivers[cls.DriverName] {
+		return "", fmt.Errorf("driver %s isn't available", cls.DriverName)
+	}
+	rpcClient, _, err := runRPCDriver(cls.DriverName)
+	if err != nil {
+		return "", err
+	}
+	if err := rpcClient.SetDriverOptions(driverOptions); err != nil {
+		return "", err
+	}
+	status, err := rpcClient.GetClusterStatus()
+	if err != nil {
+		return "", err
+	}
+	switch status.Status {
+	case generic.ClusterStatusExists:
+		return cluster.Running, nil
+	case generic.ClusterStatusDeleting:
+		return cluster.Deleting, nil
+	case generic.ClusterStatusNotFound:
+		return cluster.Removed, nil
+	}
+	return "", fmt.Errorf("driver %s can't report the status of its clusters", cls.DriverName)
+}
+
+// tryLockCluster takes the lock of the cluster called name for operation without waiting, a held lock is an error
+// naming the operation holding it
+func tryLockCluster(name, operation string) (*utils.FileLock, error) {
+	path := clusterLockPath(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	lock, err := utils.TryLockFile(path, fmt.Sprintf("%s (pid %d)", operation, os.Getpid()))
+	if err == utils.ErrLocked {
+		holder := utils.LockHolder(path)
+		if holder == "" {
+			holder = "another command"
+		}
+		return nil, fmt.Errorf("cluster %s is locked by %s", name, holder)
+	}
+	return lock, err
+}
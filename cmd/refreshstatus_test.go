@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	"github.com/rancher/kontainer-engine/driver/mock"
+	"gopkg.in/check.v1"
+)
+
+type RefreshStatusTestSuite struct {
+	tempHomeSuite
+}
+
+var _ = check.Suite(&RefreshStatusTestSuite{})
+
+func (s *RefreshStatusTestSuite) TearDownTest(c *check.C) {
+	mock.RemovalPolls = 0
+	s.tempHomeSuite.TearDownTest(c)
+}
+
+func (s *RefreshStatusTestSuite) TestRefreshStatuses(c *check.C) {
+	_, err := applyManifest(clusterManifest{
+		Clusters: []clusterSpec{mockSpec("live", 1), mockSpec("failed", 1), mockSpec("deleting", 1)},
+	}, applyOptions{})
+	c.Assert(err, check.IsNil)
+
+	// the create of failed was reported as failed although the provider has the cluster
+	failed, err := cliPersistStore{}.Get("failed")
+	c.Assert(err, check.IsNil)
+	c.Assert(cliPersistStore{}.PersistStatus(failed, cluster.Error), check.IsNil)
+	// the provider takes a while to delete deleting
+	deleting, err := cliPersistStore{}.Get("deleting")
+	c.Assert(err, check.IsNil)
+	mock.RemovalPolls = 5
+	c.Assert(removeCluster(context.Background(), deleting, staticConfigGetter{driverOptions: newDriverOptions()}, removeOptions{keepLocal: true}), check.IsNil)
+	// the provider never had gone, the driver of unknown isn't installed
+	c.Assert(cliPersistStore{}.Store(migratedCluster("gone", "gone.local")), check.IsNil)
+	unknown := migratedCluster("unknown", "unknown.local")
+	unknown.DriverName = "unknown"
+	c.Assert(cliPersistStore{}.Store(unknown), check.IsNil)
+
+	app := newTestApp()
+	app.Commands = append(app.Commands, RefreshStatusCommand())
+	c.Assert(app.Run([]string{"kontainer-engine", "refresh-status", "--all", "--concurrency", "2"}), check.IsNil)
+
+	for name, status := range map[string]string{
+		"deleting": cluster.Deleting,
+		"failed":   cluster.Running,
+		"gone":     cluster.Removed,
+		"live":     cluster.Running,
+		"unknown":  cluster.Running,
+	} {
+		cls, err := cliPersistStore{}.Get(name)
+		c.Assert(err, check.IsNil)
+		c.Assert(cls.Status, check.Equals, status, check.Commentf("cluster %s", name))
+	}
+}
+
+func (s *RefreshStatusTestSuite) TestRefreshStatusResults(c *check.C) {
+	_, err := applyManifest(clusterManifest{
+		Clusters: []clusterSpec{mockSpec("live", 1)},
+	}, applyOptions{})
+	c.Assert(err, check.IsNil)
+	live, err := cliPersistStore{}.Get("live")
+	c.Assert(err, check.IsNil)
+	gone := migratedCluster("gone", "gone.local")
+	c.Assert(cliPersistStore{}.Store(gone), check.IsNil)
+	unknown := migratedCluster("unknown", "unknown.local")
+	unknown.DriverName = "unknown"
+
+	results := refreshClusterStatuses([]cluster.Cluster{live, gone, unknown}, cliPersistStore{}, 1)
+	c.Assert(results, check.DeepEquals, []statusRefresh{
+		{Name: "live", Driver: "mock", Previous: cluster.Running, Status: cluster.Running, Result: statusUnchanged},
+		{Name: "gone", Driver: "mock", Previous: cluster.Running, Status: cluster.Removed, Result: statusChanged},
+		{Name: "unknown", Driver: "unknown", Previous: cluster.Running, Status: cluster.Running, Result: statusSkipped,
+			Message: "driver unknown isn't available"},
+	})
+
+	// a cluster another command holds the lock of is skipped
+	lock, err := tryLockCluster("live", "update")
+	c.Assert(err, check.IsNil)
+	defer unlockCluster("live", lock)
+	results = refreshClusterStatuses([]cluster.Cluster{live}, cliPersistStore{}, 1)
+	c.Assert(results[0].Result, check.Equals, statusSkipped)
+	c.Assert(results[0].Message, check.Matches, `cluster live is locked by update \(pid \d+\)`)
+}
+
+func (s *RefreshStatusTestSuite) TestRefreshStatusUsage(c *check.C) {
+	app := newTestApp()
+	app.Commands = append(app.Commands, RefreshStatusCommand())
+	err := app.Run([]string{"kontainer-engine", "refresh-status"})
+	c.Assert(err, check.ErrorMatches, "either --all or the cluster names are required")
+	c.Assert(ExitCode(err), check.Equals, ExitUsage)
+	err = app.Run([]string{"kontainer-engine", "refresh-status", "--all", "--concurrency", "0"})
+	c.Assert(err, check.ErrorMatches, "--concurrency must be at least 1")
+	err = app.Run([]string{"kontainer-engine", "refresh-status", "missing"})
+	c.Assert(ExitCode(err), check.Equals, ExitNotFound)
+}
@@ -1,15 +1,15 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
-
 	"os"
-
 	"path/filepath"
+	"sort"
 
-	"github.com/rancher/kontainer-engine/store"
+	"github.com/rancher/kontainer-engine/cluster"
+	generic "github.com/rancher/kontainer-engine/driver"
 	"github.com/rancher/kontainer-engine/utils"
-	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
 )
 
@@ -20,59 +20,251 @@ func RmCommand() cli.Command {
 		ShortName: "rm",
 		Usage:     "Remove kubernetes clusters",
 		Action:    rmCluster,
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			cli.BoolFlag{
 				Name:  "force,f",
 				Usage: "force to remove a cluster",
 			},
-		},
+			cli.BoolFlag{
+				Name:  "keep-local",
+				Usage: "Only remove the cloud resources, keep the cluster config with status Removed so that create can provision it again",
+			},
+			cli.BoolFlag{
+				Name:  "wait",
+				Usage: "Poll the driver until the provider has deleted the cluster, as set by the --wait-* options, before deleting the local state",
+			},
+			cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "Print what the remove would delete, locally and through the driver, without deleting anything",
+			},
+			noWaitFlag,
+			allowVersionMismatchFlag,
+			noStoreFlag,
+			clusterConfigFlag,
+		}, waitFlags...),
 	}
 }
 
 func rmCluster(ctx *cli.Context) error {
+	noStore, err := useNoStore(ctx)
+	if err != nil {
+		return err
+	}
+	opts := removeOptions{
+		force:                ctx.Bool("force"),
+		allowVersionMismatch: ctx.Bool(allowVersionMismatchFlag.Name),
+		keepLocal:            ctx.Bool("keep-local"),
+		wait:                 ctx.Bool("wait"),
+	}
+	if opts.wait {
+		driverOptions, err := getDriverOpts(ctx)
+		if err != nil {
+			return err
+		}
+		if opts.backoff, err = generic.BackoffFromOptions(&driverOptions); err != nil {
+			return newValidationError("%v", err)
+		}
+	}
+	if ctx.NArg() == 0 {
+		return usageErrorWithHelp(ctx, "remove", "cluster name is required")
+	}
+	if ctx.Bool("dry-run") {
+		return printRemovePlans(ctx, opts, noStore)
+	}
 	for _, name := range ctx.Args() {
 		if name == "" || name == "--help" {
 			return cli.ShowCommandHelp(ctx, "remove")
 		}
-		clusters, err := store.GetAllClusterFromStore()
-		if err != nil {
+		if err := rmLockedCluster(ctx, name, opts); err != nil {
 			return err
 		}
-		cluster, ok := clusters[name]
+	}
+	if file := ctx.String(clusterConfigFlag.Name); noStore && file != "" && !ctx.Bool("keep-local") {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// rmLockedCluster removes the cluster called name while holding its lock, the cluster is read once the lock is held
+func rmLockedCluster(ctx *cli.Context, name string, opts removeOptions) error {
+	lock, err := lockCluster(ctx, name, "remove")
+	if err != nil {
+		return err
+	}
+	defer unlockCluster(name, lock)
+	clusters, err := getAllClusters()
+	if err != nil {
+		return err
+	}
+	cluster, ok := clusters[name]
+	if !ok {
+		return newNotFoundError("cluster %v can't be found", name)
+	}
+	configGetter := cliConfigGetter{
+		name: name,
+		ctx:  ctx,
+	}
+	if err := removeCluster(newOperationContext(), cluster, configGetter, opts); err != nil {
+		return err
+	}
+	fmt.Println(cluster.Name)
+	return nil
+}
+
+// removeStep is a deletion the remove of a cluster would make
+type removeStep struct {
+	Cluster string
+	Action  string
+	Target  string
+}
+
+// printRemovePlans prints the steps of the remove of every cluster of the arguments. Nothing is locked or changed, the
+// driver isn't even started.
+func printRemovePlans(ctx *cli.Context, opts removeOptions, noStore bool) error {
+	clusters, err := getAllClusters()
+	if err != nil {
+		return err
+	}
+	steps := []removeStep{}
+	for _, name := range ctx.Args() {
+		cls, ok := clusters[name]
 		if !ok {
-			return fmt.Errorf("cluster %v can't be found", name)
+			return newNotFoundError("cluster %v can't be found", name)
 		}
-		rpcClient, _, err := runRPCDriver(cluster.DriverName)
-		if err != nil {
-			return err
+		configFile := ""
+		if noStore {
+			configFile = ctx.String(clusterConfigFlag.Name)
 		}
-		configGetter := cliConfigGetter{
-			name: name,
-			ctx:  ctx,
+		steps = append(steps, removePlan(cls, opts, configFile, ctx.GlobalString("store"))...)
+	}
+	writer := utils.NewTableWriter([][]string{
+		{"CLUSTER", "Cluster"},
+		{"ACTION", "Action"},
+		{"TARGET", "Target"},
+	}, ctx)
+	for _, step := range steps {
+		writer.Write(step)
+	}
+	return writer.Close()
+}
+
+// removePlan returns the steps removeCluster takes for cls. configFile is the --cluster-config file of a cluster
+// removed with --no-store, storeName is the --store the cluster is read from.
+func removePlan(cls cluster.Cluster, opts removeOptions, configFile, storeName string) []removeStep {
+	steps := []removeStep{{Cluster: cls.Name, Action: "call the driver remove", Target: cls.DriverName}}
+	keys := []string{}
+	for key := range cls.ProviderMetadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		steps = append(steps, removeStep{Cluster: cls.Name, Action: "delete the provider resource", Target: key + "=" + cls.ProviderMetadata[key]})
+	}
+	if opts.wait {
+		steps = append(steps, removeStep{Cluster: cls.Name, Action: "wait for the driver to report it deleted", Target: cls.DriverName})
+	}
+
+	switch {
+	case opts.keepLocal && selectedStore == nil:
+		steps = append(steps, removeStep{Cluster: cls.Name, Action: "mark the config removed", Target: filepath.Join(utils.HomeDir(), "clusters", cls.Name, defaultConfigName)})
+	case opts.keepLocal:
+		steps = append(steps, removeStep{Cluster: cls.Name, Action: "mark the config removed", Target: storeName + " store"})
+	case configFile != "":
+		steps = append(steps, removeStep{Cluster: cls.Name, Action: "delete the cluster config file", Target: configFile})
+	case selectedStore == nil:
+		steps = append(steps, removeStep{Cluster: cls.Name, Action: "delete the local directory", Target: filepath.Join(utils.HomeDir(), "clusters", cls.Name)})
+	case storeName != memoryStore:
+		steps = append(steps, removeStep{Cluster: cls.Name, Action: "delete the stored config", Target: storeName + " store"})
+	}
+	if selectedStore == nil {
+		steps = append(steps, removeStep{Cluster: cls.Name, Action: "delete the kubeconfig entries", Target: utils.KubeConfigFilePath()})
+	}
+	return steps
+}
+
+// removeOptions controls how a cluster is removed
+type removeOptions struct {
+	force                bool
+	allowVersionMismatch bool
+	keepLocal            bool
+	// wait deletes the local state only once the driver reports the cluster deleted, polling it with backoff
+	wait    bool
+	backoff utils.Backoff
+}
+
+// removeCluster removes the cluster through its driver and deletes its local storage and kubeconfig entries.
+// With keepLocal the local config is kept with status Removed, only the kubeconfig entries are deleted.
+// With wait the local state is only deleted once the driver reports the cluster not found.
+func removeCluster(ctx context.Context, cls cluster.Cluster, configGetter cluster.ConfigGetter, opts removeOptions) error {
+	if err := checkWritable(cls.Name); err != nil {
+		return err
+	}
+	rpcClient, _, err := runRPCDriver(cls.DriverName)
+	if err != nil {
+		return err
+	}
+	cls.ConfigGetter = configGetter
+	cls.PersistStore = newPersistStore(kubeConfigOptions{})
+	cls.Driver = rpcClient
+	if err := verifyDriverVersion(ctx, &cls, opts.allowVersionMismatch); err != nil {
+		return err
+	}
+	if err := cls.Remove(); err != nil {
+		if !opts.force {
+			return err
 		}
-		cluster.ConfigGetter = configGetter
-		cluster.PersistStore = cliPersistStore{}
-		cluster.Driver = rpcClient
-		if err := cluster.Remove(); err != nil {
-			if !ctx.Bool("force") {
+	}
+	if opts.wait {
+		if err := waitForRemoval(ctx, rpcClient, cls.Name, opts.backoff); err != nil {
+			if !opts.force {
 				return err
 			}
+			utils.OperationLogger(ctx).Warnf("Deleting the local state of cluster %s anyway: %v", cls.Name, err)
 		}
-		clusterFilePath := filepath.Join(utils.HomeDir(), "clusters", cluster.Name)
-		logrus.Debugf("Deleting cluster storage path %v", clusterFilePath)
-		if err := os.RemoveAll(clusterFilePath); err != nil && !os.IsNotExist(err) {
+	}
+	if opts.keepLocal {
+		if err := cls.PersistStore.PersistStatus(cls, cluster.Removed); err != nil {
 			return err
 		}
+		return forgetKubeConfig(cls.Name)
+	}
+	if err := forgetCluster(cls.Name); err != nil {
+		return err
+	}
+	notifyRemoved(cls)
+	return nil
+}
 
-		config, err := getConfigFromFile()
+// waitForRemoval polls the status of the cluster until the driver reports it not found. The failures to get the
+// status are retried until the wait timeout, a driver that can't report statuses fails right away.
+func waitForRemoval(ctx context.Context, rpcClient *generic.GrpcClient, name string, backoff utils.Backoff) error {
+	log := utils.OperationLogger(ctx)
+	var lastErr error
+	err := utils.PollWithBackoff(backoff, func() (bool, error) {
+		status, err := rpcClient.GetClusterStatus()
 		if err != nil {
-			return err
+			lastErr = err
+			log.Debugf("Waiting for cluster %s to be deleted: %v", name, err)
+			return false, nil
 		}
-		deleteConfigByName(&config, name)
-		if err := setConfigToFile(config); err != nil {
-			return err
+		switch status.Status {
+		case generic.ClusterStatusNotFound:
+			return true, nil
+		case generic.ClusterStatusUnsupported:
+			lastErr = nil
+			return false, newValidationError("driver %s can't report whether cluster %s is deleted, remove it without --wait", rpcClient.DriverName(), name)
 		}
-		fmt.Println(cluster.Name)
+		lastErr = fmt.Errorf("the cluster is %s", status.Status)
+		if status.Message != "" {
+			lastErr = fmt.Errorf("the cluster is %s: %s", status.Status, status.Message)
+		}
+		log.Infof("Waiting for cluster %s to be deleted, %v", name, lastErr)
+		return false, nil
+	})
+	if err != nil && lastErr != nil && err != lastErr {
+		return fmt.Errorf("cluster %s isn't deleted, its local state is kept: %v: %v", name, err, lastErr)
 	}
-	return nil
+	return err
 }
@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	"github.com/rancher/kontainer-engine/driver/mock"
+	"github.com/rancher/kontainer-engine/store"
+	"github.com/rancher/kontainer-engine/utils"
+	"gopkg.in/check.v1"
+)
+
+type RemoveTestSuite struct {
+	tempHomeSuite
+	oldArgs []string
+}
+
+var _ = check.Suite(&RemoveTestSuite{})
+
+func (s *RemoveTestSuite) SetUpTest(c *check.C) {
+	s.tempHomeSuite.SetUpTest(c)
+	s.oldArgs = os.Args
+}
+
+func (s *RemoveTestSuite) TearDownTest(c *check.C) {
+	os.Args = s.oldArgs
+	mock.RemovalPolls = 0
+	s.tempHomeSuite.TearDownTest(c)
+}
+
+func (s *RemoveTestSuite) TestRemoveDriverVersionMismatch(c *check.C) {
+	_, err := applyManifest(clusterManifest{
+		Clusters: []clusterSpec{mockSpec("version-mismatch", 1)},
+	}, applyOptions{})
+	c.Assert(err, check.IsNil)
+	cls, err := cliPersistStore{}.Get("version-mismatch")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.DriverVersion, check.Not(check.Equals), "")
+
+	// pretend the cluster was created by an older driver
+	cls.DriverVersion = "v0.0.1"
+	c.Assert(cliPersistStore{}.PersistStatus(cls, cls.Status), check.IsNil)
+
+	configGetter := staticConfigGetter{driverOptions: newDriverOptions()}
+	err = removeCluster(context.Background(), cls, configGetter, removeOptions{})
+	c.Assert(err, check.ErrorMatches, "cluster version-mismatch was created by mock driver v0.0.1 but the available driver is .*, use --allow-version-mismatch to continue anyway")
+	clusters, err := store.GetAllClusterFromStore()
+	c.Assert(err, check.IsNil)
+	c.Assert(clusters, check.HasLen, 1)
+
+	c.Assert(removeCluster(context.Background(), cls, configGetter, removeOptions{allowVersionMismatch: true}), check.IsNil)
+	clusters, err = store.GetAllClusterFromStore()
+	c.Assert(err, check.IsNil)
+	c.Assert(clusters, check.HasLen, 0)
+}
+
+func (s *RemoveTestSuite) TestRemoveKeepLocal(c *check.C) {
+	_, err := applyManifest(clusterManifest{
+		Clusters: []clusterSpec{mockSpec("keep-local", 2)},
+	}, applyOptions{})
+	c.Assert(err, check.IsNil)
+	cls, err := cliPersistStore{}.Get("keep-local")
+	c.Assert(err, check.IsNil)
+
+	configGetter := staticConfigGetter{driverOptions: newDriverOptions()}
+	c.Assert(removeCluster(context.Background(), cls, configGetter, removeOptions{keepLocal: true}), check.IsNil)
+
+	// the config is kept with the Removed status, the kubeconfig entries are gone
+	removed, err := cliPersistStore{}.Get("keep-local")
+	c.Assert(err, check.IsNil)
+	c.Assert(removed.Status, check.Equals, cluster.Removed)
+	c.Assert(removed.CreatedAt, check.Equals, cls.CreatedAt)
+	c.Assert(removed.DriverName, check.Equals, "mock")
+	state, err := cliPersistStore{}.Check("keep-local")
+	c.Assert(err, check.IsNil)
+	c.Assert(state, check.Equals, cluster.StateNotRunning)
+	config, err := getConfigFromFile()
+	c.Assert(err, check.IsNil)
+	for _, context := range config.Contexts {
+		c.Assert(context.Name, check.Not(check.Equals), "keep-local")
+	}
+
+	// create provisions the cluster again from the retained config
+	_, addr, err := runRPCDriver("mock")
+	c.Assert(err, check.IsNil)
+	configGetter.driverOptions.StringOptions["name"] = "keep-local"
+	recreated, err := cluster.FromCluster(&removed, addr, configGetter, cliPersistStore{})
+	c.Assert(err, check.IsNil)
+	c.Assert(recreated.Create(), check.IsNil)
+	cls, err = cliPersistStore{}.Get("keep-local")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.Status, check.Equals, cluster.Running)
+	c.Assert(cls.CreatedAt, check.Equals, removed.CreatedAt)
+}
+
+func (s *RemoveTestSuite) TestApplyRecreatesKeptCluster(c *check.C) {
+	manifest := clusterManifest{
+		Clusters: []clusterSpec{mockSpec("keep-local-apply", 1)},
+	}
+	_, err := applyManifest(manifest, applyOptions{})
+	c.Assert(err, check.IsNil)
+	cls, err := cliPersistStore{}.Get("keep-local-apply")
+	c.Assert(err, check.IsNil)
+	c.Assert(removeCluster(context.Background(), cls, staticConfigGetter{driverOptions: newDriverOptions()}, removeOptions{keepLocal: true}), check.IsNil)
+
+	results, err := applyManifest(manifest, applyOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(results, check.DeepEquals, []applyResult{{Name: "keep-local-apply", Action: applyCreate, Status: "Success"}})
+	cls, err = cliPersistStore{}.Get("keep-local-apply")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.Status, check.Equals, cluster.Running)
+}
+
+func (s *RemoveTestSuite) TestRemoveWaitsForDeletion(c *check.C) {
+	_, err := applyManifest(clusterManifest{
+		Clusters: []clusterSpec{mockSpec("slow-delete", 1)},
+	}, applyOptions{})
+	c.Assert(err, check.IsNil)
+	cls, err := cliPersistStore{}.Get("slow-delete")
+	c.Assert(err, check.IsNil)
+
+	// the provider takes a couple of polls to delete the cluster
+	mock.RemovalPolls = 2
+	c.Assert(removeCluster(context.Background(), cls, staticConfigGetter{driverOptions: newDriverOptions()}, removeOptions{
+		wait:    true,
+		backoff: utils.NewBackoff(time.Millisecond, time.Millisecond, time.Second),
+	}), check.IsNil)
+	clusters, err := store.GetAllClusterFromStore()
+	c.Assert(err, check.IsNil)
+	c.Assert(clusters, check.HasLen, 0)
+}
+
+func (s *RemoveTestSuite) TestRemoveWaitTimeout(c *check.C) {
+	_, err := applyManifest(clusterManifest{
+		Clusters: []clusterSpec{mockSpec("stuck-delete", 1)},
+	}, applyOptions{})
+	c.Assert(err, check.IsNil)
+
+	// the local state is kept while the provider still reports the cluster
+	mock.RemovalPolls = 1000
+	os.Args = []string{"kontainer-engine", "rm", "--wait", "--wait-initial-interval", "1ms", "--wait-max-interval", "1ms",
+		"--wait-timeout", "20ms", "stuck-delete"}
+	err = newTestApp().Run(os.Args)
+	c.Assert(err, check.ErrorMatches, "cluster stuck-delete isn't deleted, its local state is kept: timed out after 20ms: the cluster is deleting: cluster stuck-delete is being deleted")
+	clusters, err := store.GetAllClusterFromStore()
+	c.Assert(err, check.IsNil)
+	c.Assert(clusters, check.HasLen, 1)
+
+	// a cluster the provider doesn't have is deleted
+	mock.RemovalPolls = 0
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+	clusters, err = store.GetAllClusterFromStore()
+	c.Assert(err, check.IsNil)
+	c.Assert(clusters, check.HasLen, 0)
+}
+
+func (s *RemoveTestSuite) TestRemoveDryRun(c *check.C) {
+	_, err := applyManifest(clusterManifest{
+		Clusters: []clusterSpec{mockSpec("dry-run", 1)},
+	}, applyOptions{})
+	c.Assert(err, check.IsNil)
+
+	c.Assert(newTestApp().Run([]string{"kontainer-engine", "rm", "--dry-run", "dry-run"}), check.IsNil)
+
+	// the config, the kubeconfig entries and the cluster of the driver are all left
+	_, err = os.Stat(filepath.Join(utils.HomeDir(), "clusters", "dry-run", defaultConfigName))
+	c.Assert(err, check.IsNil)
+	config, err := getConfigFromFile()
+	c.Assert(err, check.IsNil)
+	contexts := []string{}
+	for _, context := range config.Contexts {
+		contexts = append(contexts, context.Name)
+	}
+	c.Assert(contexts, check.DeepEquals, []string{"dry-run"})
+	cls, err := cliPersistStore{}.Get("dry-run")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.Status, check.Equals, cluster.Running)
+	c.Assert(mock.MoveEndpoint("dry-run", cls.Endpoint), check.IsNil)
+
+	err = newTestApp().Run([]string{"kontainer-engine", "rm", "--dry-run", "missing"})
+	c.Assert(err, check.ErrorMatches, "cluster missing can't be found")
+	c.Assert(ExitCode(err), check.Equals, ExitNotFound)
+}
+
+func (s *RemoveTestSuite) TestRemovePlan(c *check.C) {
+	_, err := applyManifest(clusterManifest{
+		Clusters: []clusterSpec{mockSpec("plan", 1)},
+	}, applyOptions{})
+	c.Assert(err, check.IsNil)
+	cls, err := cliPersistStore{}.Get("plan")
+	c.Assert(err, check.IsNil)
+
+	c.Assert(removePlan(cls, removeOptions{wait: true}, "", ""), check.DeepEquals, []removeStep{
+		{Cluster: "plan", Action: "call the driver remove", Target: "mock"},
+		{Cluster: "plan", Action: "delete the provider resource", Target: "id=mock-plan"},
+		{Cluster: "plan", Action: "delete the provider resource", Target: "selfLink=https://mock.local/clusters/plan"},
+		{Cluster: "plan", Action: "wait for the driver to report it deleted", Target: "mock"},
+		{Cluster: "plan", Action: "delete the local directory", Target: filepath.Join(utils.HomeDir(), "clusters", "plan")},
+		{Cluster: "plan", Action: "delete the kubeconfig entries", Target: utils.KubeConfigFilePath()},
+	})
+
+	steps := removePlan(cls, removeOptions{keepLocal: true}, "", "")
+	c.Assert(steps[len(steps)-2], check.DeepEquals, removeStep{
+		Cluster: "plan", Action: "mark the config removed", Target: filepath.Join(utils.HomeDir(), "clusters", "plan", defaultConfigName),
+	})
+}
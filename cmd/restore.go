@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/urfave/cli"
+)
+
+var (
+	snapshotFlag = cli.StringFlag{
+		Name:  "snapshot",
+		Usage: "The identifier of the snapshot to restore, the last snapshot taken by the snapshot command by default",
+	}
+	yesFlag = cli.BoolFlag{
+		Name:  "yes",
+		Usage: "Restore a running cluster without asking for confirmation",
+	}
+)
+
+// RestoreCommand defines the restore command
+func RestoreCommand() cli.Command {
+	return cli.Command{
+		Name:      "restore",
+		Usage:     "Restore a cluster from one of its snapshots, with the drivers supporting it",
+		ArgsUsage: "cluster-name",
+		Action:    restoreCluster,
+		Flags: []cli.Flag{
+			snapshotFlag,
+			yesFlag,
+			allowVersionMismatchFlag,
+			noWaitFlag,
+		},
+	}
+}
+
+// restoreCluster asks the driver of the cluster to restore it from a snapshot then stores the cluster as the driver
+// reports it. A running cluster is only restored once the user confirms it, or with --yes.
+func restoreCluster(ctx *cli.Context) error {
+	name := ctx.Args().First()
+	if name == "" {
+		return usageErrorWithHelp(ctx, "restore", "cluster name is required")
+	}
+	lock, err := lockCluster(ctx, name, "restore")
+	if err != nil {
+		return err
+	}
+	defer unlockCluster(name, lock)
+	clusters, err := getAllClusters()
+	if err != nil {
+		return err
+	}
+	cls, ok := clusters[name]
+	if !ok {
+		return newNotFoundError("cluster %v can't be found", name)
+	}
+	id := ctx.String(snapshotFlag.Name)
+	if id == "" {
+		id = cls.Metadata[rpcDriver.SnapshotIDMetadata]
+	}
+	if id == "" {
+		return newUsageError("cluster %s has no recorded snapshot, use --%s to give one", name, snapshotFlag.Name)
+	}
+	if cls.Status == cluster.Running && !ctx.Bool(yesFlag.Name) {
+		if err := confirmRestore(newPrompter(), name, id); err != nil {
+			return err
+		}
+	}
+	rpcClient, _, err := runRPCDriver(cls.DriverName)
+	if err != nil {
+		return err
+	}
+	cls.ConfigGetter = updateConfigGetter{
+		name:   name,
+		ctx:    ctx,
+		stored: cls.Options,
+	}
+	cls.PersistStore = newPersistStore(kubeConfigOptions{})
+	cls.Driver = rpcClient
+	if err := verifyDriverVersion(context.Background(), &cls, ctx.Bool(allowVersionMismatchFlag.Name)); err != nil {
+		return err
+	}
+	return cls.Restore(id)
+}
+
+// confirmRestore asks whether the running cluster should be replaced by the snapshot. Anything but yes, the end of the
+// input included, refuses the restore.
+func confirmRestore(p *prompter, name, id string) error {
+	fmt.Fprintf(p.out, "Cluster %s is running, restoring snapshot %s replaces its current state. Continue? [y/N]: ", name, id)
+	answer, _ := p.readLine()
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return newUsageError("cluster %s is running and the restore wasn't confirmed, use --%s to restore it anyway", name, yesFlag.Name)
+}
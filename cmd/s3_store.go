@@ -0,0 +1,430 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rancher/kontainer-engine/cluster"
+)
+
+var (
+	// errS3NoSuchKey is returned by s3Client.get when the object doesn't exist
+	errS3NoSuchKey = errors.New("no such key")
+	// errS3PreconditionFailed is returned by s3Client.put when the object was changed since it was read
+	errS3PreconditionFailed = errors.New("precondition failed")
+)
+
+// s3Client is the subset of the S3 API used by the s3 store
+type s3Client interface {
+	// get returns the object and its etag
+	get(key string) ([]byte, string, error)
+	// put writes the object and returns its new etag. If etag is empty the object must not exist, otherwise its etag must match
+	put(key string, data []byte, etag string) (string, error)
+	delete(key string) error
+	// list returns the keys starting with prefix
+	list(prefix string) ([]string, error)
+}
+
+// s3PersistStore stores the config of each cluster as <prefix>/<name>/config.json in a bucket.
+// The writes are conditional on the etag last seen by the store, so a cluster changed by another runner is never overwritten.
+type s3PersistStore struct {
+	client s3Client
+	prefix string
+
+	lock  sync.Mutex
+	etags map[string]string
+}
+
+func newS3PersistStore(client s3Client, prefix string) *s3PersistStore {
+	return &s3PersistStore{
+		client: client,
+		prefix: strings.Trim(prefix, "/"),
+		etags:  map[string]string{},
+	}
+}
+
+func (s *s3PersistStore) key(name string) string {
+	return path.Join(s.prefix, name, defaultConfigName)
+}
+
+// read gets the config of a cluster and remembers its etag for the next write
+func (s *s3PersistStore) read(name string) ([]byte, error) {
+	data, etag, err := s.client.get(s.key(name))
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err == errS3NoSuchKey {
+		// an empty etag makes the next write fail if someone else creates the cluster meanwhile
+		s.etags[name] = ""
+		return nil, err
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read config of cluster %s from s3 key %s: %v", name, s.key(name), err)
+	}
+	s.etags[name] = etag
+	return data, nil
+}
+
+func (s *s3PersistStore) write(cls cluster.Cluster) error {
+	s.lock.Lock()
+	etag, seen := s.etags[cls.Name]
+	s.lock.Unlock()
+	if !seen {
+		// the cluster was never read by this store, only guard against changes from now on
+		if _, err := s.read(cls.Name); err != nil && err != errS3NoSuchKey {
+			return err
+		}
+		s.lock.Lock()
+		etag = s.etags[cls.Name]
+		s.lock.Unlock()
+	}
+
+	if err := cluster.StampSchemaVersion(&cls); err != nil {
+		return err
+	}
+	data, err := json.Marshal(cls)
+	if err != nil {
+		return err
+	}
+	newEtag, err := s.client.put(s.key(cls.Name), data, etag)
+	if err == errS3PreconditionFailed {
+		return fmt.Errorf("cluster %s was changed by someone else since it was read, try again", cls.Name)
+	} else if err != nil {
+		return fmt.Errorf("failed to write config of cluster %s to s3 key %s: %v", cls.Name, s.key(cls.Name), err)
+	}
+	s.lock.Lock()
+	s.etags[cls.Name] = newEtag
+	s.lock.Unlock()
+	return nil
+}
+
+func (s *s3PersistStore) Check(name string) (cluster.State, error) {
+	data, err := s.read(name)
+	if err == errS3NoSuchKey {
+		return cluster.StateNotFound, nil
+	} else if err != nil {
+		return cluster.StateNotFound, err
+	}
+	cls := cluster.Cluster{}
+	if err := json.Unmarshal(data, &cls); err != nil {
+		return cluster.StateNotFound, fmt.Errorf("failed to parse config of cluster %s from s3 key %s: %v", name, s.key(name), err)
+	}
+	if cls.Status != cluster.Running {
+		return cluster.StateNotRunning, nil
+	}
+	return cluster.StateRunning, nil
+}
+
+func (s *s3PersistStore) Get(name string) (cluster.Cluster, error) {
+	data, err := s.read(name)
+	if err == errS3NoSuchKey {
+		return cluster.Cluster{}, fmt.Errorf("%s not found", name)
+	} else if err != nil {
+		return cluster.Cluster{}, err
+	}
+	cls, _, err := cluster.Migrate(data)
+	if err != nil {
+		return cluster.Cluster{}, fmt.Errorf("failed to parse config of cluster %s from s3 key %s: %v", name, s.key(name), err)
+	}
+	return cls, nil
+}
+
+func (s *s3PersistStore) Store(cls cluster.Cluster) error {
+	return s.write(cls)
+}
+
+func (s *s3PersistStore) PersistStatus(cls cluster.Cluster, status string) error {
+	cls.Status = status
+	return s.write(cls)
+}
+
+func (s *s3PersistStore) list() (map[string]cluster.Cluster, error) {
+	// the keys never start with a slash, the whole bucket is listed without a prefix
+	prefix := ""
+	if s.prefix != "" {
+		prefix = s.prefix + "/"
+	}
+	keys, err := s.client.list(prefix)
+	if err != nil {
+		return nil, err
+	}
+	clusters := map[string]cluster.Cluster{}
+	for _, key := range keys {
+		name := path.Base(path.Dir(key))
+		// only <prefix>/<name>/config.json is a cluster, not the objects deeper in the bucket
+		if key != s.key(name) {
+			continue
+		}
+		cls, err := s.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		clusters[cls.Name] = cls
+	}
+	return clusters, nil
+}
+
+func (s *s3PersistStore) remove(name string) error {
+	if err := s.client.delete(s.key(name)); err != nil && err != errS3NoSuchKey {
+		return err
+	}
+	s.lock.Lock()
+	delete(s.etags, name)
+	s.lock.Unlock()
+	return nil
+}
+
+// s3Config is how to reach the bucket of the s3 store
+type s3Config struct {
+	bucket       string
+	region       string
+	endpoint     string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+}
+
+// httpS3Client talks to S3 or any S3 compatible endpoint with path style requests signed with AWS signature version 4
+type httpS3Client struct {
+	config   s3Config
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+func newHTTPS3Client(config s3Config) (*httpS3Client, error) {
+	if config.bucket == "" {
+		return nil, errors.New("s3 bucket is required for the s3 store")
+	}
+	if config.accessKey == "" || config.secretKey == "" {
+		return nil, errors.New("s3 access key and secret key are required for the s3 store")
+	}
+	if config.region == "" {
+		config.region = "us-east-1"
+	}
+	endpoint := config.endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", config.region)
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid s3 endpoint %s: %v", endpoint, err)
+	}
+	return &httpS3Client{
+		config:   config,
+		endpoint: u,
+		client:   &http.Client{Timeout: time.Minute},
+		now:      time.Now,
+	}, nil
+}
+
+func (h *httpS3Client) get(key string) ([]byte, string, error) {
+	resp, err := h.do(http.MethodGet, key, nil, nil, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, resp.Header.Get("ETag"), nil
+}
+
+func (h *httpS3Client) put(key string, data []byte, etag string) (string, error) {
+	headers := map[string]string{"Content-Type": "application/json"}
+	if etag == "" {
+		headers["If-None-Match"] = "*"
+	} else {
+		headers["If-Match"] = etag
+	}
+	resp, err := h.do(http.MethodPut, key, nil, data, headers)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return resp.Header.Get("ETag"), nil
+}
+
+func (h *httpS3Client) delete(key string) error {
+	resp, err := h.do(http.MethodDelete, key, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (h *httpS3Client) list(prefix string) ([]string, error) {
+	keys := []string{}
+	token := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", prefix)
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := h.do(http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		result := struct {
+			Contents []struct {
+				Key string
+			}
+			IsTruncated           bool
+			NextContinuationToken string
+		}{}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse s3 list response: %v", err)
+		}
+		for _, content := range result.Contents {
+			keys = append(keys, content.Key)
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return keys, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// do sends a signed request for key in the bucket and turns the error responses into errors
+func (h *httpS3Client) do(method, key string, query url.Values, body []byte, headers map[string]string) (*http.Response, error) {
+	u := *h.endpoint
+	u.Path = path.Join("/", h.endpoint.Path, h.config.bucket, key)
+	u.RawPath = awsEscape(u.Path, false)
+	u.RawQuery = canonicalQuery(query)
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	if h.config.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", h.config.sessionToken)
+	}
+	signV4(req, body, h.config.accessKey, h.config.secretKey, h.config.region, h.now())
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return nil, errS3NoSuchKey
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		return nil, errS3PreconditionFailed
+	}
+	message, _ := ioutil.ReadAll(resp.Body)
+	return nil, fmt.Errorf("s3 %s %s failed with %s: %s", method, u.Path, resp.Status, strings.TrimSpace(string(message)))
+}
+
+// signV4 signs req with AWS signature version 4, every header already set on req is signed
+func signV4(req *http.Request, body []byte, accessKey, secretKey, region string, t time.Time) {
+	t = t.UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := []string{}
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	canonicalHeaders := ""
+	for _, name := range names {
+		canonicalHeaders += name + ":" + headers[name] + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := strings.Join([]string{date, region, "s3", "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	for _, part := range []string{region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", accessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery encodes the query sorted by key the way signature version 4 expects it
+func canonicalQuery(query url.Values) string {
+	keys := []string{}
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := []string{}
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, awsEscape(k, true)+"="+awsEscape(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsEscape percent-encodes everything but the unreserved characters, and '/' unless encodeSlash is set
+func awsEscape(s string, encodeSlash bool) string {
+	buf := bytes.Buffer{}
+	for _, b := range []byte(s) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9', b == '-', b == '_', b == '.', b == '~':
+			buf.WriteByte(b)
+		case b == '/' && !encodeSlash:
+			buf.WriteByte(b)
+		default:
+			fmt.Fprintf(&buf, "%%%02X", b)
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	"github.com/rancher/kontainer-engine/utils"
+	"github.com/urfave/cli"
+)
+
+const (
+	// selfTestCluster is the name of the cluster the self-test creates
+	selfTestCluster = "selftest"
+	selfTestPassed  = "Passed"
+	selfTestFailed  = "Failed"
+	selfTestSkipped = "Skipped"
+)
+
+// SelfTestCommand defines the hidden selftest command
+func SelfTestCommand() cli.Command {
+	return cli.Command{
+		Name:   "selftest",
+		Usage:  "Create, inspect and remove a cluster with the mock driver in a temporary store to check the installation",
+		Hidden: true,
+		Action: selfTest,
+	}
+}
+
+type selfTestResult struct {
+	Step   string
+	Result string
+	Error  string
+}
+
+type selfTestStep struct {
+	name string
+	run  func() error
+}
+
+// selfTest runs a create, an inspect and a remove with the mock driver against a file store in a temporary home dir, so
+// no credential is needed and the stores of the user are left alone. A step fails the ones after it.
+func selfTest(ctx *cli.Context) error {
+	home, err := ioutil.TempDir("", "kontainer-engine-selftest")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(home)
+	restore := useSelfTestHome(home)
+	defer restore()
+
+	results := []selfTestResult{}
+	failed := false
+	for _, step := range selfTestSteps(ctx) {
+		result := selfTestResult{Step: step.name, Result: selfTestPassed}
+		if failed {
+			result.Result = selfTestSkipped
+		} else if err := step.run(); err != nil {
+			result.Result = selfTestFailed
+			result.Error = err.Error()
+			failed = true
+		}
+		results = append(results, result)
+	}
+
+	writer := utils.NewTableWriter([][]string{
+		{"STEP", "Step"},
+		{"RESULT", "Result"},
+		{"ERROR", "Error"},
+	}, ctx)
+	for _, result := range results {
+		writer.Write(result)
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	if failed {
+		return fmt.Errorf("the self-test failed, the installation doesn't work")
+	}
+	return nil
+}
+
+func selfTestSteps(ctx *cli.Context) []selfTestStep {
+	return []selfTestStep{
+		{"create", func() error {
+			return runSelfTestCommand(ctx, "create", "--driver", "mock", selfTestCluster)
+		}},
+		{"inspect", func() error {
+			clusters, err := getAllClusters()
+			if err != nil {
+				return err
+			}
+			cls, ok := clusters[selfTestCluster]
+			switch {
+			case !ok:
+				return fmt.Errorf("the created cluster isn't in the store")
+			case cls.Status != cluster.Running:
+				return fmt.Errorf("the created cluster has status %s instead of %s", cls.Status, cluster.Running)
+			case cls.Endpoint == "":
+				return fmt.Errorf("the created cluster has no endpoint")
+			}
+			return nil
+		}},
+		{"remove", func() error {
+			if err := runSelfTestCommand(ctx, "rm", selfTestCluster); err != nil {
+				return err
+			}
+			state, err := cliPersistStore{}.Check(selfTestCluster)
+			if err != nil {
+				return err
+			}
+			if state != cluster.StateNotFound {
+				return fmt.Errorf("the removed cluster is still in the store")
+			}
+			return nil
+		}},
+	}
+}
+
+// runSelfTestCommand runs the app again with args, create reads its flags from os.Args
+func runSelfTestCommand(ctx *cli.Context, args ...string) error {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = append([]string{oldArgs[0]}, args...)
+	return ctx.App.Run(os.Args)
+}
+
+// useSelfTestHome points the home dir and the store at home, the returned func restores them
+func useSelfTestHome(home string) func() {
+	homeEnv := "HOME"
+	if runtime.GOOS == "windows" {
+		homeEnv = "USERPROFILE"
+	}
+	oldHome, oldStore, oldReadOnly := os.Getenv(homeEnv), selectedStore, readOnly
+	os.Setenv(homeEnv, home)
+	selectedStore, readOnly = nil, false
+	return func() {
+		os.Setenv(homeEnv, oldHome)
+		selectedStore, readOnly = oldStore, oldReadOnly
+	}
+}
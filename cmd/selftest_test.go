@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	"github.com/urfave/cli"
+	"gopkg.in/check.v1"
+)
+
+type SelfTestTestSuite struct {
+	tempHomeSuite
+	oldArgs []string
+}
+
+var _ = check.Suite(&SelfTestTestSuite{})
+
+func (s *SelfTestTestSuite) SetUpTest(c *check.C) {
+	s.tempHomeSuite.SetUpTest(c)
+	s.oldArgs = os.Args
+}
+
+func (s *SelfTestTestSuite) TearDownTest(c *check.C) {
+	os.Args = s.oldArgs
+	s.tempHomeSuite.TearDownTest(c)
+}
+
+func (s *SelfTestTestSuite) TestSelfTest(c *check.C) {
+	// the clusters of the user are left alone
+	c.Assert(cliPersistStore{}.Store(migratedCluster(selfTestCluster, "1.1.1.1")), check.IsNil)
+
+	app := newTestApp()
+	app.Commands = append(app.Commands, SelfTestCommand())
+	os.Args = []string{"kontainer-engine", "selftest"}
+	c.Assert(app.Run(os.Args), check.IsNil)
+	c.Assert(os.Args, check.DeepEquals, []string{"kontainer-engine", "selftest"})
+
+	cls, err := cliPersistStore{}.Get(selfTestCluster)
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.Endpoint, check.Equals, "1.1.1.1")
+	c.Assert(cls.Status, check.Not(check.Equals), cluster.Removed)
+}
+
+func (s *SelfTestTestSuite) TestSelfTestFailure(c *check.C) {
+	app := newTestApp()
+	app.Commands = append(app.Commands, SelfTestCommand())
+	app.Before = func(ctx *cli.Context) error {
+		if ctx.Args().First() == "create" {
+			return errors.New("broken plumbing")
+		}
+		return nil
+	}
+	os.Args = []string{"kontainer-engine", "selftest"}
+	c.Assert(app.Run(os.Args), check.ErrorMatches, "the self-test failed, the installation doesn't work")
+}
@@ -0,0 +1,565 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	"github.com/rancher/kontainer-engine/plugin"
+	"github.com/rancher/kontainer-engine/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+const (
+	// servePrefix is the path of the clusters in the API
+	servePrefix = "/v1/clusters"
+	// maxServeBody is the largest request body serve reads
+	maxServeBody = 1 << 20
+	// requestIDHeader carries the correlation id of a request
+	requestIDHeader = "X-Request-ID"
+)
+
+// The codes of the errors returned by serve
+const (
+	serveInvalid       = "invalid"
+	serveUnauthorized  = "unauthorized"
+	serveForbidden     = "forbidden"
+	serveNotFound      = "not-found"
+	serveNotAllowed    = "method-not-allowed"
+	serveConflict      = "conflict"
+	serveRetryable     = "retryable"
+	serveInterrupted   = "interrupted"
+	serveDriverFailure = "driver-failure"
+	serveInternal      = "internal"
+)
+
+var (
+	clusterNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+	requestIDRegexp   = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,64}$`)
+)
+
+// ServeCommand defines the serve command
+func ServeCommand() cli.Command {
+	return cli.Command{
+		Name:   "serve",
+		Usage:  "Serve the create, update, remove and status of the clusters over an authenticated HTTP+JSON API",
+		Action: serveClusters,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "listen",
+				Usage: "The address the API listens on",
+				Value: "127.0.0.1:8080",
+			},
+			serveTokenFlag,
+			serveTokenFileFlag,
+			serveAllowFromFlag,
+			cli.DurationFlag{
+				Name:  "shutdown-timeout",
+				Usage: "How long serve waits for the requests in flight once it is asked to stop, the creates still running are then interrupted",
+				Value: 5 * time.Minute,
+			},
+			serveMetricsIntervalFlag,
+			allowVersionMismatchFlag,
+		},
+	}
+}
+
+// serveOptions controls who serve accepts requests from and how it runs the operations
+type serveOptions struct {
+	// token is the bearer token the clients must send
+	token string
+	// allowFrom are the networks the clients must connect from, any network if empty
+	allowFrom []*net.IPNet
+	// metricsInterval is how long the inventory metrics are cached
+	metricsInterval      time.Duration
+	allowVersionMismatch bool
+}
+
+// servedCluster is the state of a cluster returned by the API, it has no credential
+type servedCluster struct {
+	Name      string   `json:"name"`
+	Driver    string   `json:"driver"`
+	Status    string   `json:"status"`
+	Version   string   `json:"version,omitempty"`
+	Endpoint  string   `json:"endpoint,omitempty"`
+	NodeCount int64    `json:"nodeCount,omitempty"`
+	Warnings  []string `json:"warnings,omitempty"`
+}
+
+// serveError is an error of the API with its HTTP status and code
+type serveError struct {
+	status int
+	code   string
+	err    error
+}
+
+func (e *serveError) Error() string {
+	return e.err.Error()
+}
+
+func newServeError(status int, code, format string, a ...interface{}) error {
+	return &serveError{status: status, code: code, err: fmt.Errorf(format, a...)}
+}
+
+func serveClusters(ctx *cli.Context) error {
+	if ctx.NArg() > 0 {
+		return usageErrorWithHelp(ctx, "serve", "serve takes no argument")
+	}
+	token, err := serveToken(ctx.String(serveTokenFlag.Name), ctx.String(serveTokenFileFlag.Name))
+	if err != nil {
+		return err
+	}
+	allowFrom, err := parseAllowFrom(ctx.StringSlice(serveAllowFromFlag.Name))
+	if err != nil {
+		return err
+	}
+	timeout := ctx.Duration("shutdown-timeout")
+	if timeout < 0 {
+		return newUsageError("--shutdown-timeout can't be negative")
+	}
+	if ctx.Duration(serveMetricsIntervalFlag.Name) < 0 {
+		return newUsageError("--%s can't be negative", serveMetricsIntervalFlag.Name)
+	}
+	handler := newServeHandler(serveOptions{
+		token:                token,
+		allowFrom:            allowFrom,
+		metricsInterval:      ctx.Duration(serveMetricsIntervalFlag.Name),
+		allowVersionMismatch: ctx.Bool(allowVersionMismatchFlag.Name),
+	})
+	server := &http.Server{Addr: ctx.String("listen"), Handler: handler}
+	stopping := make(chan os.Signal, 1)
+	stop := notifyInterrupts(func(sig os.Signal) {
+		stopping <- sig
+	})
+	defer stop()
+	served := make(chan error, 1)
+	go func() {
+		served <- server.ListenAndServe()
+	}()
+	logrus.Infof("Serving the clusters on http://%s%s", server.Addr, servePrefix)
+	select {
+	case err := <-served:
+		return err
+	case sig := <-stopping:
+		logrus.Infof("Received %v, stopping once the requests in flight are done, at most in %v", sig, timeout)
+	}
+	return handler.shutdown(server, timeout)
+}
+
+// serveHandler serves the clusters of the selected store to the clients allowed by its options
+type serveHandler struct {
+	opts serveOptions
+	// api serves the requests once their correlation id is set
+	api       http.Handler
+	inventory *inventoryMetrics
+	// ctx is canceled to interrupt the creates in flight when serve stops
+	ctx    context.Context
+	cancel context.CancelFunc
+	// inFlight counts the requests being served
+	inFlight sync.WaitGroup
+}
+
+// newServeHandler returns the handler of the API, the requests from outside the allowed networks or without the token
+// of opts as their bearer token are refused
+func newServeHandler(opts serveOptions) *serveHandler {
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &serveHandler{opts: opts, inventory: newInventoryMetrics(opts.metricsInterval), ctx: ctx, cancel: cancel}
+	h.api = allowFrom(opts.allowFrom, requireToken(opts.token, http.HandlerFunc(h.route)))
+	return h
+}
+
+// shutdown stops server from accepting requests and waits up to timeout for the requests in flight. The creates still
+// in flight are then interrupted: they are stored Interrupted and the drivers remove what they provisioned. The driver
+// plugins are stopped once every request is done.
+func (h *serveHandler) shutdown(server *http.Server, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := server.Shutdown(ctx)
+	if err == context.DeadlineExceeded {
+		logrus.Warnf("Interrupting the requests still in flight after %v", timeout)
+		h.cancel()
+		err = nil
+	}
+	// the updates and the removes can't be interrupted, they are waited for
+	h.inFlight.Wait()
+	h.cancel()
+	plugin.Shutdown()
+	if closeErr := server.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// ServeHTTP serves a request under its correlation id, which is returned in the X-Request-ID header and logged as the
+// operation of every line of the request. The id sent by the client is kept if it is valid.
+func (h *serveHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.inFlight.Add(1)
+	defer h.inFlight.Done()
+	id := r.Header.Get(requestIDHeader)
+	if !requestIDRegexp.MatchString(id) {
+		id = utils.NewOperationID()
+	}
+	w.Header().Set(requestIDHeader, id)
+	r = r.WithContext(utils.WithOperationID(r.Context(), id))
+	log := utils.OperationLogger(r.Context())
+	log.Debugf("%s %s", r.Method, r.URL.Path)
+	recorder := &serveRecorder{ResponseWriter: w, status: http.StatusOK}
+	h.api.ServeHTTP(recorder, r)
+
+	log = log.WithField("status", recorder.status)
+	message := ""
+	if recorder.err != nil {
+		message = redactMessage(recorder.err.Error())
+	}
+	switch {
+	case recorder.status >= http.StatusInternalServerError:
+		log.Errorf("%s %s failed: %s", r.Method, r.URL.Path, message)
+	case recorder.err != nil:
+		log.Warnf("%s %s refused: %s", r.Method, r.URL.Path, message)
+	case r.URL.Path == metricsPath:
+		// the scrapes would flood the log
+		log.Debugf("%s %s", r.Method, r.URL.Path)
+	default:
+		log.Infof("%s %s", r.Method, r.URL.Path)
+	}
+}
+
+func (h *serveHandler) route(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	if path == metricsPath {
+		if r.Method != http.MethodGet {
+			writeServeError(w, notAllowed(w, r, http.MethodGet))
+			return
+		}
+		h.metrics(w)
+		return
+	}
+	if path == servePrefix {
+		if r.Method != http.MethodPost {
+			writeServeError(w, notAllowed(w, r, http.MethodPost))
+			return
+		}
+		h.create(w, r)
+		return
+	}
+	name := strings.TrimPrefix(path, servePrefix+"/")
+	if name == path || strings.Contains(name, "/") {
+		writeServeError(w, newServeError(http.StatusNotFound, serveNotFound, "%s isn't a path of the API", r.URL.Path))
+		return
+	}
+	if err := validateClusterName(name); err != nil {
+		writeServeError(w, err)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		h.status(w, r, name)
+	case http.MethodPut:
+		h.update(w, r, name)
+	case http.MethodDelete:
+		h.remove(w, r, name)
+	default:
+		writeServeError(w, notAllowed(w, r, http.MethodGet, http.MethodPut, http.MethodDelete))
+	}
+}
+
+// create creates the cluster of the clusterSpec of the body, a stored cluster that isn't removed is a conflict
+func (h *serveHandler) create(w http.ResponseWriter, r *http.Request) {
+	spec := clusterSpec{}
+	if err := readServeBody(r, &spec); err != nil {
+		writeServeError(w, err)
+		return
+	}
+	if err := validateServedSpec(spec); err != nil {
+		writeServeError(w, err)
+		return
+	}
+	lock, err := lockServedCluster(spec.Name, "create")
+	if err != nil {
+		writeServeError(w, err)
+		return
+	}
+	defer unlockCluster(spec.Name, lock)
+	clusters, err := getAllClusters()
+	if err != nil {
+		writeServeError(w, err)
+		return
+	}
+	if existing, ok := clusters[spec.Name]; ok && existing.Status != cluster.Removed {
+		writeServeError(w, newServeError(http.StatusConflict, serveConflict, "cluster %s already exists", spec.Name))
+		return
+	}
+	persistStore := newPersistStore(kubeConfigOptions{skip: true})
+	cls, err := newClusterFromSpec(spec, persistStore)
+	if err != nil {
+		writeServeError(w, err)
+		return
+	}
+	// the create is interrupted when serve stops rather than when the client goes away
+	operation := utils.WithOperationID(h.ctx, utils.OperationID(r.Context()))
+	if err := cls.CreateContext(operation); err != nil {
+		if err == cluster.ErrInterrupted {
+			utils.OperationLogger(operation).Warnf("Serve is stopping, the create of cluster %s is interrupted", cls.Name)
+			removeInterrupted(operation, cls)
+		}
+		writeServeError(w, err)
+		return
+	}
+	result, err := operationState(cls)
+	if err != nil {
+		writeServeError(w, err)
+		return
+	}
+	writeServeJSON(w, http.StatusCreated, result)
+}
+
+// update updates the cluster called name with the options of the clusterSpec of the body, the driver of the cluster
+// can't be changed
+func (h *serveHandler) update(w http.ResponseWriter, r *http.Request, name string) {
+	spec := clusterSpec{}
+	if err := readServeBody(r, &spec); err != nil {
+		writeServeError(w, err)
+		return
+	}
+	if spec.Name != "" && spec.Name != name {
+		writeServeError(w, newValidationError("the name %s of the body doesn't match the cluster %s", spec.Name, name))
+		return
+	}
+	spec.Name = name
+	lock, err := lockServedCluster(name, "update")
+	if err != nil {
+		writeServeError(w, err)
+		return
+	}
+	defer unlockCluster(name, lock)
+	existing, err := getServedCluster(name)
+	if err != nil {
+		writeServeError(w, err)
+		return
+	}
+	if existing.Status == cluster.Removed {
+		writeServeError(w, newServeError(http.StatusConflict, serveConflict, "cluster %s is removed, create it again instead", name))
+		return
+	}
+	if spec.Driver == "" {
+		spec.Driver = existing.DriverName
+	}
+	if spec.Driver != existing.DriverName {
+		writeServeError(w, newValidationError("cluster %s is managed by driver %s, not %s", name, existing.DriverName, spec.Driver))
+		return
+	}
+	cls, err := updateFromSpec(r.Context(), existing, spec, newPersistStore(kubeConfigOptions{skip: true}), h.opts.allowVersionMismatch)
+	if err != nil {
+		writeServeError(w, err)
+		return
+	}
+	result, err := operationState(cls)
+	if err != nil {
+		writeServeError(w, err)
+		return
+	}
+	writeServeJSON(w, http.StatusOK, result)
+}
+
+// remove removes the cluster called name at its provider and deletes it from the store
+func (h *serveHandler) remove(w http.ResponseWriter, r *http.Request, name string) {
+	lock, err := lockServedCluster(name, "remove")
+	if err != nil {
+		writeServeError(w, err)
+		return
+	}
+	defer unlockCluster(name, lock)
+	cls, err := getServedCluster(name)
+	if err != nil {
+		writeServeError(w, err)
+		return
+	}
+	// the driver finds the cluster with its stored options and credential
+	driverOptions, err := statusDriverOptions(cls)
+	if err != nil {
+		writeServeError(w, err)
+		return
+	}
+	opts := removeOptions{allowVersionMismatch: h.opts.allowVersionMismatch}
+	if err := removeCluster(r.Context(), cls, staticConfigGetter{driverOptions}, opts); err != nil {
+		writeServeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// status returns the stored state of the cluster called name. With live=true the status is the one the driver reports
+// instead, it isn't stored.
+func (h *serveHandler) status(w http.ResponseWriter, r *http.Request, name string) {
+	cls, err := getServedCluster(name)
+	if err != nil {
+		writeServeError(w, err)
+		return
+	}
+	if r.URL.Query().Get("live") == "true" {
+		driverOptions, err := statusDriverOptions(cls)
+		if err != nil {
+			writeServeError(w, err)
+			return
+		}
+		if cls.Status, err = liveClusterStatus(cls, driverOptions); err != nil {
+			writeServeError(w, err)
+			return
+		}
+	}
+	writeServeJSON(w, http.StatusOK, toServedCluster(cls))
+}
+
+func validateClusterName(name string) error {
+	if !clusterNameRegexp.MatchString(name) {
+		return newValidationError("invalid cluster name %q, use letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+}
+
+func validateServedSpec(spec clusterSpec) error {
+	if spec.Name == "" {
+		return newValidationError("name is required")
+	}
+	if err := validateClusterName(spec.Name); err != nil {
+		return err
+	}
+	if spec.Driver == "" {
+		return newValidationError("driver is required for cluster %s", spec.Name)
+	}
+	if !plugin.BuiltInDrivers[spec.Driver] {
+		return newValidationError("driver %s is not supported", spec.Driver)
+	}
+	return nil
+}
+
+// readServeBody decodes the json body of r into v, the unknown fields are an error
+func readServeBody(r *http.Request, v interface{}) error {
+	data, err := ioutil.ReadAll(http.MaxBytesReader(nil, r.Body, maxServeBody))
+	if err != nil {
+		return newValidationError("invalid request body: %v", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if err := decoder.Decode(v); err != nil {
+		return newValidationError("invalid request body: %v", err)
+	}
+	if decoder.More() {
+		return newValidationError("invalid request body: it has more than one json value")
+	}
+	if err := checkJSONFields(data, v); err != nil {
+		return newValidationError("invalid request body: %v", err)
+	}
+	return nil
+}
+
+// lockServedCluster takes the lock of the cluster called name without waiting, a held lock is a conflict
+func lockServedCluster(name, operation string) (*utils.FileLock, error) {
+	lock, err := tryLockCluster(name, "serve "+operation)
+	if err != nil {
+		return nil, &serveError{status: http.StatusConflict, code: serveConflict, err: err}
+	}
+	return lock, nil
+}
+
+func getServedCluster(name string) (cluster.Cluster, error) {
+	clusters, err := getAllClusters()
+	if err != nil {
+		return cluster.Cluster{}, err
+	}
+	cls, ok := clusters[name]
+	if !ok {
+		return cls, newNotFoundError("cluster %v can't be found", name)
+	}
+	return cls, nil
+}
+
+// operationState returns the state of cls stored by a create or an update, with the warnings the driver returned
+func operationState(cls *cluster.Cluster) (servedCluster, error) {
+	stored, err := getServedCluster(cls.Name)
+	if err != nil {
+		return servedCluster{}, err
+	}
+	stored.Warnings = cls.Warnings
+	return toServedCluster(stored), nil
+}
+
+func toServedCluster(cls cluster.Cluster) servedCluster {
+	return servedCluster{
+		Name:      cls.Name,
+		Driver:    cls.DriverName,
+		Status:    cls.Status,
+		Version:   cls.Version,
+		Endpoint:  cls.Endpoint,
+		NodeCount: cls.NodeCount,
+		Warnings:  cls.Warnings,
+	}
+}
+
+func notAllowed(w http.ResponseWriter, r *http.Request, methods ...string) error {
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	return newServeError(http.StatusMethodNotAllowed, serveNotAllowed, "%s isn't allowed on %s", r.Method, r.URL.Path)
+}
+
+// serveErrorStatus returns the HTTP status and the code of err, the errors of the commands keep the meaning of their
+// exit code
+func serveErrorStatus(err error) (int, string) {
+	if e, ok := err.(*serveError); ok {
+		return e.status, e.code
+	}
+	switch ExitCode(err) {
+	case ExitUsage, ExitValidation:
+		return http.StatusBadRequest, serveInvalid
+	case ExitNotFound:
+		return http.StatusNotFound, serveNotFound
+	case ExitRetryable:
+		return http.StatusServiceUnavailable, serveRetryable
+	case ExitDriverFailure:
+		return http.StatusBadGateway, serveDriverFailure
+	case ExitInterrupted:
+		return http.StatusServiceUnavailable, serveInterrupted
+	}
+	return http.StatusInternalServerError, serveInternal
+}
+
+// serveRecorder records the status and the error of the response for the log of the request
+type serveRecorder struct {
+	http.ResponseWriter
+	status int
+	err    error
+}
+
+func (r *serveRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func writeServeError(w http.ResponseWriter, err error) {
+	if recorder, ok := w.(*serveRecorder); ok {
+		recorder.err = err
+	}
+	status, code := serveErrorStatus(err)
+	writeServeJSON(w, status, struct {
+		Error     string `json:"error"`
+		Code      string `json:"code"`
+		RequestID string `json:"requestId,omitempty"`
+	}{redactMessage(err.Error()), code, w.Header().Get(requestIDHeader)})
+}
+
+func writeServeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logrus.Warnf("Failed to write the response: %v", err)
+	}
+}
@@ -0,0 +1,367 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	"github.com/rancher/kontainer-engine/driver/mock"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/check.v1"
+)
+
+type ServeTestSuite struct {
+	tempHomeSuite
+	server *httptest.Server
+}
+
+var _ = check.Suite(&ServeTestSuite{})
+
+func (s *ServeTestSuite) SetUpTest(c *check.C) {
+	s.tempHomeSuite.SetUpTest(c)
+	s.server = httptest.NewServer(newServeHandler(serveOptions{token: "s3cret"}))
+}
+
+func (s *ServeTestSuite) TearDownTest(c *check.C) {
+	s.server.Close()
+	s.tempHomeSuite.TearDownTest(c)
+}
+
+// send sends body to path with the token and decodes the json response into result if it isn't nil, it can be called
+// from any goroutine
+func (s *ServeTestSuite) send(method, path, token, body string, result interface{}) (*http.Response, error) {
+	req, err := http.NewRequest(method, s.server.URL+path, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if result != nil {
+		return resp, json.NewDecoder(resp.Body).Decode(result)
+	}
+	return resp, nil
+}
+
+func (s *ServeTestSuite) request(c *check.C, method, path, token, body string, result interface{}) *http.Response {
+	resp, err := s.send(method, path, token, body, result)
+	c.Assert(err, check.IsNil)
+	return resp
+}
+
+// concurrently sends every request at the same time and returns their statuses in the same order
+func (s *ServeTestSuite) concurrently(c *check.C, requests ...[2]string) []int {
+	statuses := make([]int, len(requests))
+	errs := make([]error, len(requests))
+	start := make(chan struct{})
+	wg := sync.WaitGroup{}
+	for i, request := range requests {
+		wg.Add(1)
+		go func(i int, method, path string) {
+			defer wg.Done()
+			<-start
+			body := ""
+			if method == http.MethodPost {
+				body = `{"name": "contended", "driver": "mock"}`
+			}
+			resp, err := s.send(method, path, "s3cret", body, nil)
+			if errs[i] = err; err == nil {
+				statuses[i] = resp.StatusCode
+			}
+		}(i, request[0], request[1])
+	}
+	close(start)
+	wg.Wait()
+	for _, err := range errs {
+		c.Assert(err, check.IsNil)
+	}
+	return statuses
+}
+
+type serveErrorBody struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+func (s *ServeTestSuite) TestServeLifecycle(c *check.C) {
+	created := servedCluster{}
+	resp := s.request(c, http.MethodPost, "/v1/clusters", "s3cret",
+		`{"name": "served", "driver": "mock", "options": {"node-count": 2, "labels": ["a=b"]}}`, &created)
+	c.Assert(resp.StatusCode, check.Equals, http.StatusCreated)
+	c.Assert(created.Name, check.Equals, "served")
+	c.Assert(created.Driver, check.Equals, "mock")
+	c.Assert(created.Status, check.Equals, cluster.Running)
+	c.Assert(created.NodeCount, check.Equals, int64(2))
+
+	// the same name can't be created twice
+	failure := serveErrorBody{}
+	resp = s.request(c, http.MethodPost, "/v1/clusters", "s3cret", `{"name": "served", "driver": "mock"}`, &failure)
+	c.Assert(resp.StatusCode, check.Equals, http.StatusConflict)
+	c.Assert(failure, check.DeepEquals, serveErrorBody{Error: "cluster served already exists", Code: serveConflict})
+
+	status := servedCluster{}
+	resp = s.request(c, http.MethodGet, "/v1/clusters/served", "s3cret", "", &status)
+	c.Assert(resp.StatusCode, check.Equals, http.StatusOK)
+	c.Assert(status, check.DeepEquals, servedCluster{
+		Name:      "served",
+		Driver:    "mock",
+		Status:    cluster.Running,
+		Version:   created.Version,
+		Endpoint:  created.Endpoint,
+		NodeCount: 2,
+	})
+	resp = s.request(c, http.MethodGet, "/v1/clusters/served?live=true", "s3cret", "", &status)
+	c.Assert(resp.StatusCode, check.Equals, http.StatusOK)
+	c.Assert(status.Status, check.Equals, cluster.Running)
+
+	updated := servedCluster{}
+	resp = s.request(c, http.MethodPut, "/v1/clusters/served", "s3cret", `{"options": {"node-count": 5}}`, &updated)
+	c.Assert(resp.StatusCode, check.Equals, http.StatusOK)
+	c.Assert(updated.NodeCount, check.Equals, int64(5))
+	stored, err := cliPersistStore{}.Get("served")
+	c.Assert(err, check.IsNil)
+	c.Assert(stored.NodeCount, check.Equals, int64(5))
+
+	resp = s.request(c, http.MethodDelete, "/v1/clusters/served", "s3cret", "", nil)
+	c.Assert(resp.StatusCode, check.Equals, http.StatusNoContent)
+
+	resp = s.request(c, http.MethodGet, "/v1/clusters/served", "s3cret", "", &failure)
+	c.Assert(resp.StatusCode, check.Equals, http.StatusNotFound)
+	c.Assert(failure, check.DeepEquals, serveErrorBody{Error: "cluster served can't be found", Code: serveNotFound})
+}
+
+func (s *ServeTestSuite) TestServeUnauthorized(c *check.C) {
+	for _, token := range []string{"", "wrong"} {
+		failure := serveErrorBody{}
+		resp := s.request(c, http.MethodPost, "/v1/clusters", token, `{"name": "served", "driver": "mock"}`, &failure)
+		c.Assert(resp.StatusCode, check.Equals, http.StatusUnauthorized)
+		c.Assert(resp.Header.Get("WWW-Authenticate"), check.Equals, `Bearer realm="kontainer-engine"`)
+		c.Assert(failure.Code, check.Equals, serveUnauthorized)
+	}
+	clusters, err := getAllClusters()
+	c.Assert(err, check.IsNil)
+	c.Assert(clusters, check.HasLen, 0)
+}
+
+func (s *ServeTestSuite) TestServeInvalidRequests(c *check.C) {
+	c.Assert(cliPersistStore{}.Store(migratedCluster("existing", "1.1.1.1")), check.IsNil)
+	for _, test := range []struct {
+		method, path, body string
+		status             int
+		code, message      string
+	}{
+		{http.MethodPost, "/v1/clusters", `{"driver": "mock"}`, http.StatusBadRequest, serveInvalid, "name is required"},
+		{http.MethodPost, "/v1/clusters", `{"name": "../up", "driver": "mock"}`, http.StatusBadRequest, serveInvalid,
+			`invalid cluster name "../up", use letters, digits, '.', '_' and '-'`},
+		{http.MethodPost, "/v1/clusters", `{"name": "new"}`, http.StatusBadRequest, serveInvalid, "driver is required for cluster new"},
+		{http.MethodPost, "/v1/clusters", `{"name": "new", "driver": "unknown"}`, http.StatusBadRequest, serveInvalid, "driver unknown is not supported"},
+		{http.MethodPost, "/v1/clusters", `{"name": "new", "driver": "mock", "extra": true}`, http.StatusBadRequest, serveInvalid,
+			`invalid request body: json: unknown field "extra"`},
+		{http.MethodPost, "/v1/clusters", `{"name": "new", "driver": "mock", "options": {"node-count": "two"}}`, http.StatusBadRequest,
+			serveInvalid, "invalid value for option node-count of cluster new: expected int but got two"},
+		{http.MethodPost, "/v1/clusters", `{"name": "new", "driver": "mock", "options": {"unknown": 1}}`, http.StatusBadRequest,
+			serveInvalid, "option unknown is not supported by driver mock"},
+		{http.MethodGet, "/v1/clusters", "", http.StatusMethodNotAllowed, serveNotAllowed, "GET isn't allowed on /v1/clusters"},
+		{http.MethodPost, "/v1/clusters/existing", "{}", http.StatusMethodNotAllowed, serveNotAllowed, "POST isn't allowed on /v1/clusters/existing"},
+		{http.MethodGet, "/v2/clusters/existing", "", http.StatusNotFound, serveNotFound, "/v2/clusters/existing isn't a path of the API"},
+		{http.MethodPut, "/v1/clusters/existing", `{"name": "other"}`, http.StatusBadRequest, serveInvalid,
+			"the name other of the body doesn't match the cluster existing"},
+		{http.MethodPut, "/v1/clusters/existing", `{"driver": "gke"}`, http.StatusBadRequest, serveInvalid,
+			"cluster existing is managed by driver mock, not gke"},
+		{http.MethodPut, "/v1/clusters/missing", `{}`, http.StatusNotFound, serveNotFound, "cluster missing can't be found"},
+		{http.MethodDelete, "/v1/clusters/missing", "", http.StatusNotFound, serveNotFound, "cluster missing can't be found"},
+	} {
+		failure := serveErrorBody{}
+		resp := s.request(c, test.method, test.path, "s3cret", test.body, &failure)
+		comment := check.Commentf("%s %s %s", test.method, test.path, test.body)
+		c.Assert(resp.StatusCode, check.Equals, test.status, comment)
+		c.Assert(failure, check.DeepEquals, serveErrorBody{Error: test.message, Code: test.code}, comment)
+	}
+	clusters, err := getAllClusters()
+	c.Assert(err, check.IsNil)
+	c.Assert(clusters, check.HasLen, 1)
+}
+
+func (s *ServeTestSuite) TestServeLockedCluster(c *check.C) {
+	c.Assert(cliPersistStore{}.Store(migratedCluster("busy", "1.1.1.1")), check.IsNil)
+	lock, err := tryLockCluster("busy", "update")
+	c.Assert(err, check.IsNil)
+	defer unlockCluster("busy", lock)
+
+	failure := serveErrorBody{}
+	resp := s.request(c, http.MethodDelete, "/v1/clusters/busy", "s3cret", "", &failure)
+	c.Assert(resp.StatusCode, check.Equals, http.StatusConflict)
+	c.Assert(failure.Code, check.Equals, serveConflict)
+	c.Assert(failure.Error, check.Matches, `cluster busy is locked by update \(pid \d+\)`)
+	_, err = cliPersistStore{}.Get("busy")
+	c.Assert(err, check.IsNil)
+}
+
+func (s *ServeTestSuite) TestServeErrorStatus(c *check.C) {
+	for err, status := range map[error]int{
+		newUsageError("usage"):                                 http.StatusBadRequest,
+		newValidationError("invalid"):                          http.StatusBadRequest,
+		newNotFoundError("missing"):                            http.StatusNotFound,
+		&exitError{code: ExitRetryable, err: os.ErrClosed}:     http.StatusServiceUnavailable,
+		&exitError{code: ExitDriverFailure, err: os.ErrClosed}: http.StatusBadGateway,
+		os.ErrClosed: http.StatusInternalServerError,
+	} {
+		got, _ := serveErrorStatus(err)
+		c.Assert(got, check.Equals, status, check.Commentf("%v", err))
+	}
+}
+
+func (s *ServeTestSuite) TestServeConcurrentCreates(c *check.C) {
+	requests := [][2]string{}
+	for i := 0; i < 8; i++ {
+		requests = append(requests, [2]string{http.MethodPost, "/v1/clusters"})
+	}
+	counts := map[int]int{}
+	for _, status := range s.concurrently(c, requests...) {
+		counts[status]++
+	}
+	// the creates that find the lock held or the cluster created are conflicts
+	c.Assert(counts, check.DeepEquals, map[int]int{http.StatusCreated: 1, http.StatusConflict: 7})
+	cls, err := cliPersistStore{}.Get("contended")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.Status, check.Equals, cluster.Running)
+
+	requests = [][2]string{}
+	for i := 0; i < 8; i++ {
+		requests = append(requests, [2]string{http.MethodDelete, "/v1/clusters/contended"})
+	}
+	counts = map[int]int{}
+	for _, status := range s.concurrently(c, requests...) {
+		c.Assert(status == http.StatusNoContent || status == http.StatusConflict || status == http.StatusNotFound, check.Equals, true)
+		counts[status]++
+	}
+	c.Assert(counts[http.StatusNoContent], check.Equals, 1)
+	clusters, err := getAllClusters()
+	c.Assert(err, check.IsNil)
+	c.Assert(clusters, check.HasLen, 0)
+}
+
+func (s *ServeTestSuite) TestServeConcurrentCreateRemove(c *check.C) {
+	for round := 0; round < 5; round++ {
+		comment := check.Commentf("round %d", round)
+		if _, err := (cliPersistStore{}).Get("contended"); err != nil {
+			resp := s.request(c, http.MethodPost, "/v1/clusters", "s3cret", `{"name": "contended", "driver": "mock"}`, nil)
+			c.Assert(resp.StatusCode, check.Equals, http.StatusCreated, comment)
+		}
+		statuses := s.concurrently(c, [2]string{http.MethodPost, "/v1/clusters"}, [2]string{http.MethodDelete, "/v1/clusters/contended"})
+		created, removed := statuses[0], statuses[1]
+		// the create only succeeds once the remove is done, one of them is refused while the other holds the lock
+		c.Assert(created == http.StatusCreated || created == http.StatusConflict, check.Equals, true, comment)
+		c.Assert(removed == http.StatusNoContent || removed == http.StatusConflict, check.Equals, true, comment)
+		if created == http.StatusCreated {
+			c.Assert(removed, check.Equals, http.StatusNoContent, comment)
+		}
+		_, err := cliPersistStore{}.Get("contended")
+		exists := err == nil
+		c.Assert(exists, check.Equals, created == http.StatusCreated || removed != http.StatusNoContent, comment)
+	}
+}
+
+func (s *ServeTestSuite) TestServeRequestID(c *check.C) {
+	logs := bytes.Buffer{}
+	logrus.SetOutput(&logs)
+	defer logrus.SetOutput(os.Stderr)
+
+	req, err := http.NewRequest(http.MethodGet, s.server.URL+"/v1/clusters/missing", nil)
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	req.Header.Set(requestIDHeader, "client-id.1")
+	resp, err := http.DefaultClient.Do(req)
+	c.Assert(err, check.IsNil)
+	defer resp.Body.Close()
+	c.Assert(resp.Header.Get(requestIDHeader), check.Equals, "client-id.1")
+	failure := map[string]string{}
+	c.Assert(json.NewDecoder(resp.Body).Decode(&failure), check.IsNil)
+	c.Assert(failure["requestId"], check.Equals, "client-id.1")
+	c.Assert(logs.String(), check.Matches, `(?s).*GET /v1/clusters/missing refused: cluster missing can't be found.*operation=client-id.1 status=404.*`)
+
+	// an invalid id is replaced by a generated one, every request gets its own
+	ids := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		req.Header.Set(requestIDHeader, "not valid")
+		resp, err := http.DefaultClient.Do(req)
+		c.Assert(err, check.IsNil)
+		resp.Body.Close()
+		id := resp.Header.Get(requestIDHeader)
+		c.Assert(id, check.Matches, "[0-9a-f]{16}")
+		ids[id] = true
+	}
+	c.Assert(ids, check.HasLen, 3)
+}
+
+// startSlowCreate serves handler instead of the handler of the suite and sends the create of a cluster taking delay in the
+// driver, it returns once the driver creates it
+func (s *ServeTestSuite) startSlowCreate(c *check.C, handler *serveHandler, delay time.Duration) chan serveErrorBody {
+	mock.CreateDelay = delay
+	s.server.Close()
+	s.server = httptest.NewServer(handler)
+	results := make(chan serveErrorBody, 1)
+	go func() {
+		result := serveErrorBody{}
+		resp, err := s.send(http.MethodPost, "/v1/clusters", "s3cret", `{"name": "slow", "driver": "mock"}`, &result)
+		if err != nil {
+			result.Error = err.Error()
+		} else if resp.StatusCode != http.StatusCreated {
+			result.Code = fmt.Sprintf("%d %s", resp.StatusCode, result.Code)
+		}
+		results <- result
+	}()
+	for i := 0; i < 100; i++ {
+		if cls, err := (cliPersistStore{}).Get("slow"); err == nil && cls.Status == cluster.Creating {
+			return results
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Fatal("the create of slow didn't reach the driver")
+	return nil
+}
+
+func (s *ServeTestSuite) TestServeShutdownWaitsForCreate(c *check.C) {
+	defer func() { mock.CreateDelay = 0 }()
+	handler := newServeHandler(serveOptions{token: "s3cret"})
+	results := s.startSlowCreate(c, handler, 200*time.Millisecond)
+
+	c.Assert(handler.shutdown(s.server.Config, time.Minute), check.IsNil)
+	c.Assert(<-results, check.DeepEquals, serveErrorBody{})
+	cls, err := cliPersistStore{}.Get("slow")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.Status, check.Equals, cluster.Running)
+	// no request is accepted once serve stopped
+	_, err = s.send(http.MethodGet, "/v1/clusters/slow", "s3cret", "", nil)
+	c.Assert(err, check.NotNil)
+}
+
+func (s *ServeTestSuite) TestServeShutdownInterruptsCreate(c *check.C) {
+	defer func() { mock.CreateDelay = 0 }()
+	handler := newServeHandler(serveOptions{token: "s3cret"})
+	results := s.startSlowCreate(c, handler, 300*time.Millisecond)
+
+	c.Assert(handler.shutdown(s.server.Config, 20*time.Millisecond), check.IsNil)
+	c.Assert(<-results, check.DeepEquals, serveErrorBody{Error: "create interrupted", Code: "503 " + serveInterrupted})
+	cls, err := cliPersistStore{}.Get("slow")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.Status, check.Equals, cluster.Interrupted)
+	// the driver removed the cluster it created once the create returned
+	driverOptions, err := statusDriverOptions(cls)
+	c.Assert(err, check.IsNil)
+	status, err := liveClusterStatus(cls, driverOptions)
+	c.Assert(err, check.IsNil)
+	c.Assert(status, check.Equals, cluster.Removed)
+}
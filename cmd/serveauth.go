@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"crypto/subtle"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+// serveTokenEnv is the environment variable with the token the clients of serve must send
+const serveTokenEnv = "KONTAINER_ENGINE_SERVE_TOKEN"
+
+var (
+	serveTokenFlag = cli.StringFlag{
+		Name:   "token",
+		Usage:  "The bearer token the clients must send. Prefer " + serveTokenEnv + " or --token-file, a flag value shows in the process list",
+		EnvVar: serveTokenEnv,
+	}
+	serveTokenFileFlag = cli.StringFlag{
+		Name:  "token-file",
+		Usage: "Read the bearer token the clients must send from this file, e.g. a mounted secret",
+	}
+	serveAllowFromFlag = cli.StringSliceFlag{
+		Name:  "allow-from",
+		Usage: "Only accept the requests from this IP address or CIDR network, e.g. 10.0.0.0/8. Repeat the flag for several, any address is accepted by default",
+	}
+)
+
+// serveToken returns the token of --token, or of its environment variable, or the one read from tokenFile. Exactly
+// one of them must be set.
+func serveToken(token, tokenFile string) (string, error) {
+	switch {
+	case token != "" && tokenFile != "":
+		return "", newUsageError("only one of --%s, %s and --%s can be set", serveTokenFlag.Name, serveTokenEnv, serveTokenFileFlag.Name)
+	case tokenFile != "":
+		data, err := ioutil.ReadFile(tokenFile)
+		if err != nil {
+			return "", newUsageError("failed to read the token: %v", err)
+		}
+		// the files written by editors and secret mounts often end with a new line
+		token = strings.TrimSpace(string(data))
+		if token == "" {
+			return "", newValidationError("token file %s is empty", tokenFile)
+		}
+	case token == "":
+		return "", newUsageError("a token is required, set --%s, %s or --%s", serveTokenFlag.Name, serveTokenEnv, serveTokenFileFlag.Name)
+	}
+	return token, nil
+}
+
+// parseAllowFrom parses the IP addresses and the CIDR networks of --allow-from, an address is a network of itself
+func parseAllowFrom(values []string) ([]*net.IPNet, error) {
+	networks := []*net.IPNet{}
+	for _, value := range values {
+		if ip := net.ParseIP(value); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, newUsageError("invalid --%s %q, it must be an IP address or a CIDR network", serveAllowFromFlag.Name, value)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// allowFrom refuses the requests whose client address isn't in networks, any address is allowed if networks is empty.
+// The address is the one of the connection, the forwarded headers are ignored as any client can set them.
+func allowFrom(networks []*net.IPNet, next http.Handler) http.Handler {
+	if len(networks) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		ip := net.ParseIP(host)
+		for _, network := range networks {
+			if ip != nil && network.Contains(ip) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		writeServeError(w, newServeError(http.StatusForbidden, serveForbidden, "requests from %s aren't allowed", host))
+	})
+}
+
+// requireToken refuses the requests without token as their bearer token, the tokens are compared in constant time
+func requireToken(token string, next http.Handler) http.Handler {
+	expected := []byte(token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme, credentials := "", ""
+		if parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2); len(parts) == 2 {
+			scheme, credentials = parts[0], strings.TrimSpace(parts[1])
+		}
+		// an empty token would let the requests without credentials in
+		if len(expected) == 0 || !strings.EqualFold(scheme, "Bearer") ||
+			subtle.ConstantTimeCompare([]byte(credentials), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="kontainer-engine"`)
+			writeServeError(w, newServeError(http.StatusUnauthorized, serveUnauthorized, "a valid bearer token is required"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
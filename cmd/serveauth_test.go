@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+
+	"gopkg.in/check.v1"
+)
+
+type ServeAuthTestSuite struct {
+	tempHomeSuite
+}
+
+var _ = check.Suite(&ServeAuthTestSuite{})
+
+// get sends a GET of the stored cluster to a server of opts with the Authorization header and returns the status
+func (s *ServeAuthTestSuite) get(c *check.C, opts serveOptions, authorization string) int {
+	server := httptest.NewServer(newServeHandler(opts))
+	defer server.Close()
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/v1/clusters/existing", nil)
+	c.Assert(err, check.IsNil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	c.Assert(err, check.IsNil)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func (s *ServeAuthTestSuite) TestRequireToken(c *check.C) {
+	c.Assert(cliPersistStore{}.Store(migratedCluster("existing", "1.1.1.1")), check.IsNil)
+	opts := serveOptions{token: "s3cret"}
+	for authorization, status := range map[string]int{
+		"Bearer s3cret":   http.StatusOK,
+		"bearer s3cret":   http.StatusOK,
+		"Bearer  s3cret ": http.StatusOK,
+		"":                http.StatusUnauthorized,
+		"s3cret":          http.StatusUnauthorized,
+		"Bearer":          http.StatusUnauthorized,
+		"Bearer s3cre":    http.StatusUnauthorized,
+		"Bearer s3cret2":  http.StatusUnauthorized,
+		"Basic s3cret":    http.StatusUnauthorized,
+	} {
+		c.Assert(s.get(c, opts, authorization), check.Equals, status, check.Commentf("%q", authorization))
+	}
+	// a handler without a token refuses everything
+	c.Assert(s.get(c, serveOptions{}, "Bearer "), check.Equals, http.StatusUnauthorized)
+}
+
+func (s *ServeAuthTestSuite) TestAllowFrom(c *check.C) {
+	c.Assert(cliPersistStore{}.Store(migratedCluster("existing", "1.1.1.1")), check.IsNil)
+	for _, test := range []struct {
+		allowFrom     []string
+		authorization string
+		status        int
+	}{
+		{nil, "Bearer s3cret", http.StatusOK},
+		{[]string{"127.0.0.1"}, "Bearer s3cret", http.StatusOK},
+		{[]string{"10.0.0.0/8", "127.0.0.0/8"}, "Bearer s3cret", http.StatusOK},
+		{[]string{"10.0.0.0/8", "::1"}, "Bearer s3cret", http.StatusForbidden},
+		// the address is checked before the token
+		{[]string{"10.0.0.0/8"}, "", http.StatusForbidden},
+		{[]string{"127.0.0.1"}, "", http.StatusUnauthorized},
+	} {
+		networks, err := parseAllowFrom(test.allowFrom)
+		c.Assert(err, check.IsNil)
+		status := s.get(c, serveOptions{token: "s3cret", allowFrom: networks}, test.authorization)
+		c.Assert(status, check.Equals, test.status, check.Commentf("%v %q", test.allowFrom, test.authorization))
+	}
+
+	_, err := parseAllowFrom([]string{"10.0.0.0/33"})
+	c.Assert(err, check.ErrorMatches, `invalid --allow-from "10.0.0.0/33", it must be an IP address or a CIDR network`)
+	c.Assert(ExitCode(err), check.Equals, ExitUsage)
+}
+
+func (s *ServeAuthTestSuite) TestServeToken(c *check.C) {
+	token, err := serveToken("from-flag", "")
+	c.Assert(err, check.IsNil)
+	c.Assert(token, check.Equals, "from-flag")
+
+	file := filepath.Join(c.MkDir(), "token")
+	c.Assert(ioutil.WriteFile(file, []byte("from-file\n"), 0600), check.IsNil)
+	token, err = serveToken("", file)
+	c.Assert(err, check.IsNil)
+	c.Assert(token, check.Equals, "from-file")
+
+	_, err = serveToken("from-flag", file)
+	c.Assert(err, check.ErrorMatches, "only one of --token, KONTAINER_ENGINE_SERVE_TOKEN and --token-file can be set")
+	c.Assert(ExitCode(err), check.Equals, ExitUsage)
+	_, err = serveToken("", "")
+	c.Assert(err, check.ErrorMatches, "a token is required, set --token, KONTAINER_ENGINE_SERVE_TOKEN or --token-file")
+	c.Assert(ExitCode(err), check.Equals, ExitUsage)
+
+	c.Assert(ioutil.WriteFile(file, []byte(" \n"), 0600), check.IsNil)
+	_, err = serveToken("", file)
+	c.Assert(err, check.ErrorMatches, "token file .* is empty")
+	c.Assert(ExitCode(err), check.Equals, ExitValidation)
+}
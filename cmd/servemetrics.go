@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+// metricsPath is the path of the inventory metrics in the Prometheus text format
+const metricsPath = "/metrics"
+
+var serveMetricsIntervalFlag = cli.DurationFlag{
+	Name:  "metrics-interval",
+	Usage: "How long the inventory of " + metricsPath + " is cached, the store is listed at most once per interval",
+	Value: 30 * time.Second,
+}
+
+// inventoryMetrics caches the gauges of the clusters of the selected store so that a scrape doesn't list the store
+type inventoryMetrics struct {
+	interval time.Duration
+	lock     sync.Mutex
+	// body is the last exposition, empty until the store is listed successfully
+	body      []byte
+	refreshed time.Time
+}
+
+func newInventoryMetrics(interval time.Duration) *inventoryMetrics {
+	return &inventoryMetrics{interval: interval}
+}
+
+// exposition returns the metrics, the store is listed again if they are older than the interval. When it can't be the
+// last metrics are returned, with the time they were refreshed at.
+func (m *inventoryMetrics) exposition(now time.Time) ([]byte, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.body != nil && now.Sub(m.refreshed) < m.interval {
+		return m.body, nil
+	}
+	clusters, err := getAllClusters()
+	if err != nil {
+		if m.body == nil {
+			return nil, err
+		}
+		logrus.Warnf("Serving the inventory metrics of %s, failed to list the clusters: %v", m.refreshed.Format(time.RFC3339), err)
+		return m.body, nil
+	}
+	counts := map[[2]string]int{}
+	for _, cls := range clusters {
+		counts[[2]string{cls.DriverName, cls.Status}]++
+	}
+	m.body, m.refreshed = formatInventory(counts, len(clusters), now), now
+	return m.body, nil
+}
+
+// formatInventory returns the gauges of counts, the clusters by driver and status, in the Prometheus text format
+func formatInventory(counts map[[2]string]int, total int, refreshed time.Time) []byte {
+	lines := []string{}
+	for key, count := range counts {
+		lines = append(lines, fmt.Sprintf("kontainer_engine_clusters_by_status{driver=%s,status=%s} %d", metricLabel(key[0]), metricLabel(key[1]), count))
+	}
+	sort.Strings(lines)
+
+	body := bytes.Buffer{}
+	body.WriteString("# HELP kontainer_engine_clusters_by_status The stored clusters by driver and status.\n")
+	body.WriteString("# TYPE kontainer_engine_clusters_by_status gauge\n")
+	for _, line := range lines {
+		body.WriteString(line + "\n")
+	}
+	// a gauge, not a counter, so it has no _total suffix
+	body.WriteString("# HELP kontainer_engine_clusters The stored clusters.\n")
+	body.WriteString("# TYPE kontainer_engine_clusters gauge\n")
+	fmt.Fprintf(&body, "kontainer_engine_clusters %d\n", total)
+	body.WriteString("# HELP kontainer_engine_inventory_refreshed_timestamp_seconds When the clusters were last listed.\n")
+	body.WriteString("# TYPE kontainer_engine_inventory_refreshed_timestamp_seconds gauge\n")
+	fmt.Fprintf(&body, "kontainer_engine_inventory_refreshed_timestamp_seconds %d\n", refreshed.Unix())
+	return body.Bytes()
+}
+
+// metricLabel quotes a label value, escaping the backslashes, the double quotes and the new lines
+func metricLabel(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
+
+func (h *serveHandler) metrics(w http.ResponseWriter) {
+	body, err := h.inventory.exposition(time.Now())
+	if err != nil {
+		writeServeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(body)
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	"gopkg.in/check.v1"
+)
+
+type ServeMetricsTestSuite struct {
+	tempHomeSuite
+}
+
+var _ = check.Suite(&ServeMetricsTestSuite{})
+
+// seed stores a cluster of driver in status
+func (s *ServeMetricsTestSuite) seed(c *check.C, name, driver, status string) {
+	cls := migratedCluster(name, name+".example.com")
+	cls.DriverName, cls.Status = driver, status
+	c.Assert(cliPersistStore{}.Store(cls), check.IsNil)
+}
+
+// scrape gets the metrics of handler and returns them without the comments and the refresh time
+func (s *ServeMetricsTestSuite) scrape(c *check.C, handler http.Handler, token string) (int, []string) {
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	req, err := http.NewRequest(http.MethodGet, server.URL+metricsPath, nil)
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	c.Assert(err, check.IsNil)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	c.Assert(err, check.IsNil)
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, nil
+	}
+	c.Assert(resp.Header.Get("Content-Type"), check.Equals, "text/plain; version=0.0.4; charset=utf-8")
+	samples := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(body)), "\n") {
+		if !strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "kontainer_engine_inventory_refreshed_timestamp_seconds ") {
+			samples = append(samples, line)
+		}
+	}
+	return resp.StatusCode, samples
+}
+
+func (s *ServeMetricsTestSuite) TestInventoryGauges(c *check.C) {
+	s.seed(c, "prod-a", "gke", cluster.Running)
+	s.seed(c, "prod-b", "gke", cluster.Running)
+	s.seed(c, "prod-c", "gke", cluster.Error)
+	s.seed(c, "staging", "eks", cluster.Running)
+	s.seed(c, "dev", "mock", cluster.Interrupted)
+	handler := newServeHandler(serveOptions{token: "s3cret"})
+
+	status, samples := s.scrape(c, handler, "s3cret")
+	c.Assert(status, check.Equals, http.StatusOK)
+	c.Assert(samples, check.DeepEquals, []string{
+		`kontainer_engine_clusters_by_status{driver="eks",status="Running"} 1`,
+		`kontainer_engine_clusters_by_status{driver="gke",status="Error"} 1`,
+		`kontainer_engine_clusters_by_status{driver="gke",status="Running"} 2`,
+		`kontainer_engine_clusters_by_status{driver="mock",status="Interrupted"} 1`,
+		`kontainer_engine_clusters 5`,
+	})
+
+	status, _ = s.scrape(c, handler, "wrong")
+	c.Assert(status, check.Equals, http.StatusUnauthorized)
+}
+
+func (s *ServeMetricsTestSuite) TestEmptyStore(c *check.C) {
+	status, samples := s.scrape(c, newServeHandler(serveOptions{token: "s3cret"}), "s3cret")
+	c.Assert(status, check.Equals, http.StatusOK)
+	c.Assert(samples, check.DeepEquals, []string{"kontainer_engine_clusters 0"})
+}
+
+func (s *ServeMetricsTestSuite) TestInventoryCached(c *check.C) {
+	s.seed(c, "prod-a", "gke", cluster.Running)
+	inventory := newInventoryMetrics(time.Minute)
+	start := time.Unix(1500000000, 0)
+	cached, err := inventory.exposition(start)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(cached), check.Matches, `(?s).*kontainer_engine_clusters 1\n.*`)
+	c.Assert(string(cached), check.Matches, `(?s).*kontainer_engine_inventory_refreshed_timestamp_seconds 1500000000\n`)
+
+	// the scrapes within the interval don't list the store
+	s.seed(c, "prod-b", "gke", cluster.Running)
+	body, err := inventory.exposition(start.Add(59 * time.Second))
+	c.Assert(err, check.IsNil)
+	c.Assert(string(body), check.Equals, string(cached))
+
+	body, err = inventory.exposition(start.Add(time.Minute))
+	c.Assert(err, check.IsNil)
+	c.Assert(string(body), check.Matches, `(?s).*kontainer_engine_clusters_by_status\{driver="gke",status="Running"\} 2\n.*`)
+	c.Assert(string(body), check.Matches, fmt.Sprintf(`(?s).*kontainer_engine_inventory_refreshed_timestamp_seconds %d\n`, start.Unix()+60))
+}
+
+func (s *ServeMetricsTestSuite) TestMetricLabel(c *check.C) {
+	c.Assert(metricLabel(`a"b\c`+"\nd"), check.Equals, `"a\"b\\c\nd"`)
+	c.Assert(metricLabel(""), check.Equals, `""`)
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/urfave/cli"
+)
+
+// SnapshotCommand defines the snapshot command
+func SnapshotCommand() cli.Command {
+	return cli.Command{
+		Name:      "snapshot",
+		Usage:     "Take a snapshot of a cluster, e.g. of its etcd, with the drivers supporting it",
+		ArgsUsage: "cluster-name",
+		Action:    snapshotCluster,
+		Flags: []cli.Flag{
+			allowVersionMismatchFlag,
+			noWaitFlag,
+		},
+	}
+}
+
+// snapshotCluster asks the driver of the cluster to take a snapshot and prints its identifier, which is recorded in
+// the metadata of the cluster
+func snapshotCluster(ctx *cli.Context) error {
+	name := ctx.Args().First()
+	if name == "" {
+		return usageErrorWithHelp(ctx, "snapshot", "cluster name is required")
+	}
+	if err := checkWritable(name); err != nil {
+		return err
+	}
+	lock, err := lockCluster(ctx, name, "snapshot")
+	if err != nil {
+		return err
+	}
+	defer unlockCluster(name, lock)
+	clusters, err := getAllClusters()
+	if err != nil {
+		return err
+	}
+	cls, ok := clusters[name]
+	if !ok {
+		return newNotFoundError("cluster %v can't be found", name)
+	}
+	rpcClient, _, err := runRPCDriver(cls.DriverName)
+	if err != nil {
+		return err
+	}
+	cls.ConfigGetter = updateConfigGetter{
+		name:   name,
+		ctx:    ctx,
+		stored: cls.Options,
+	}
+	cls.PersistStore = newPersistStore(kubeConfigOptions{})
+	cls.Driver = rpcClient
+	if err := verifyDriverVersion(context.Background(), &cls, ctx.Bool(allowVersionMismatchFlag.Name)); err != nil {
+		return err
+	}
+	id, err := cls.Snapshot()
+	if err != nil {
+		return err
+	}
+	fmt.Println(id)
+	return nil
+}
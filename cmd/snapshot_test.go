@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"bytes"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"gopkg.in/check.v1"
+)
+
+type SnapshotTestSuite struct {
+	tempHomeSuite
+}
+
+var _ = check.Suite(&SnapshotTestSuite{})
+
+func (s *SnapshotTestSuite) TestSnapshotRecordsID(c *check.C) {
+	_, err := applyManifest(clusterManifest{
+		Clusters: []clusterSpec{mockSpec("with-snapshot", 1)},
+	}, applyOptions{})
+	c.Assert(err, check.IsNil)
+
+	app := newTestApp()
+	app.Commands = append(app.Commands, SnapshotCommand())
+	c.Assert(app.Run([]string{"kontainer-engine", "snapshot", "with-snapshot"}), check.IsNil)
+	cls, err := cliPersistStore{}.Get("with-snapshot")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.Metadata[rpcDriver.SnapshotIDMetadata], check.Matches, "with-snapshot-snapshot-[0-9]+")
+	c.Assert(cls.Status, check.Equals, cluster.Running)
+
+	err = app.Run([]string{"kontainer-engine", "snapshot", "missing"})
+	c.Assert(err, check.ErrorMatches, "cluster missing can't be found")
+	c.Assert(ExitCode(err), check.Equals, ExitNotFound)
+}
+
+func (s *SnapshotTestSuite) TestUnsupportedExitCode(c *check.C) {
+	err := &cluster.UnsupportedError{DriverName: "gke", Operation: "snapshots"}
+	c.Assert(err, check.ErrorMatches, "driver gke doesn't support snapshots")
+	c.Assert(ExitCode(err), check.Equals, ExitValidation)
+}
+
+func (s *SnapshotTestSuite) TestRestore(c *check.C) {
+	oldPrompter := newPrompter
+	defer func() { newPrompter = oldPrompter }()
+	_, err := applyManifest(clusterManifest{
+		Clusters: []clusterSpec{mockSpec("restored", 1)},
+	}, applyOptions{})
+	c.Assert(err, check.IsNil)
+
+	app := newTestApp()
+	app.Commands = append(app.Commands, SnapshotCommand(), RestoreCommand())
+	err = app.Run([]string{"kontainer-engine", "restore", "restored"})
+	c.Assert(err, check.ErrorMatches, "cluster restored has no recorded snapshot, use --snapshot to give one")
+	c.Assert(ExitCode(err), check.Equals, ExitUsage)
+
+	c.Assert(app.Run([]string{"kontainer-engine", "snapshot", "restored"}), check.IsNil)
+	_, err = applyManifest(clusterManifest{
+		Clusters: []clusterSpec{mockSpec("restored", 3)},
+	}, applyOptions{})
+	c.Assert(err, check.IsNil)
+	cls, err := cliPersistStore{}.Get("restored")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.NodeCount, check.Equals, int64(3))
+
+	// the running cluster is only restored once it is confirmed
+	out := &bytes.Buffer{}
+	newPrompter = func() *prompter { return scriptedPrompter("n\n", out) }
+	err = app.Run([]string{"kontainer-engine", "restore", "restored"})
+	c.Assert(err, check.ErrorMatches, "cluster restored is running and the restore wasn't confirmed, use --yes to restore it anyway")
+	c.Assert(ExitCode(err), check.Equals, ExitUsage)
+	c.Assert(out.String(), check.Matches, `Cluster restored is running, restoring snapshot restored-snapshot-[0-9]+ replaces .*\[y/N\]: `)
+
+	newPrompter = func() *prompter { return scriptedPrompter("y\n", &bytes.Buffer{}) }
+	c.Assert(app.Run([]string{"kontainer-engine", "restore", "restored"}), check.IsNil)
+	cls, err = cliPersistStore{}.Get("restored")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.NodeCount, check.Equals, int64(1))
+	c.Assert(cls.Status, check.Equals, cluster.Running)
+
+	err = app.Run([]string{"kontainer-engine", "restore", "--yes", "--snapshot", "other-snapshot-1", "restored"})
+	c.Assert(err, check.ErrorMatches, "cluster restored has no snapshot other-snapshot-1.*")
+	c.Assert(ExitCode(err), check.Equals, ExitNotFound)
+}
@@ -0,0 +1,518 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/rancher/kontainer-engine/store"
+	"github.com/rancher/kontainer-engine/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+const (
+	// fileStore keeps the clusters under the home dir, it is the default
+	fileStore = "file"
+	// memoryStore keeps the clusters in memory, they are lost when the process exits
+	memoryStore = "memory"
+	// s3Store keeps the clusters in an S3 bucket
+	s3Store = "s3"
+)
+
+var (
+	noStoreFlag = cli.BoolFlag{
+		Name:  "no-store",
+		Usage: "Keep the cluster in memory only, nothing is written to the home dir. Create prints the kubeconfig instead",
+	}
+	clusterConfigFlag = cli.StringFlag{
+		Name:  "cluster-config",
+		Usage: "With --no-store, the file create writes the cluster config to and remove reads it from",
+	}
+)
+
+// clusterStore is a PersistStore that can also list and delete the clusters
+type clusterStore interface {
+	cluster.PersistStore
+	list() (map[string]cluster.Cluster, error)
+	remove(name string) error
+}
+
+// selectedStore is the store selected with --store, nil means the file store
+var selectedStore clusterStore
+
+// readOnly is set with --read-only, the stores refuse the writes
+var readOnly bool
+
+// readOnlyStore is a clusterStore refusing the writes, the reads go to the store it wraps
+type readOnlyStore struct {
+	clusterStore
+}
+
+func (readOnlyStore) Store(cls cluster.Cluster) error {
+	return readOnlyError(cls.Name)
+}
+
+func (readOnlyStore) PersistStatus(cls cluster.Cluster, status string) error {
+	return readOnlyError(cls.Name)
+}
+
+func (readOnlyStore) remove(name string) error {
+	return readOnlyError(name)
+}
+
+// readOnlyError is the error of a write to the store with --read-only
+func readOnlyError(name string) error {
+	return newUsageError("cluster %s can't be changed, the store is read-only, run without --read-only to change it", name)
+}
+
+// checkWritable fails with --read-only, the commands changing the cloud resources of a cluster call it first so that
+// they don't change them without storing the result
+func checkWritable(name string) error {
+	if readOnly {
+		return readOnlyError(name)
+	}
+	return nil
+}
+
+// guardReadOnly wraps store in a readOnlyStore with --read-only
+func guardReadOnly(store clusterStore) clusterStore {
+	if readOnly {
+		return readOnlyStore{store}
+	}
+	return store
+}
+
+// readRetry is how the file store retries a read failing with a transient error, e.g. on a NFS home dir
+type readRetry struct {
+	retries int
+	delay   time.Duration
+	// clock waits between two reads, nil means the wall clock
+	clock utils.Clock
+}
+
+var (
+	// fileReadRetry is set with --store-read-retries, the reads aren't retried by default
+	fileReadRetry = readRetry{}
+	// readStoreFile reads the files of the file store, tests replace it to simulate a flaky file system
+	readStoreFile = ioutil.ReadFile
+)
+
+// read reads path and retries the errors other than a missing file or a denied access
+func (r readRetry) read(path string) ([]byte, error) {
+	data, err := readStoreFile(path)
+	for retry := 0; retry < r.retries && isRetryableReadError(err); retry++ {
+		logrus.Debugf("Retrying the read of %s in %v: %v", path, r.delay, err)
+		if r.clock == nil {
+			time.Sleep(r.delay)
+		} else {
+			r.clock.Sleep(r.delay)
+		}
+		data, err = readStoreFile(path)
+	}
+	return data, err
+}
+
+func isRetryableReadError(err error) bool {
+	return err != nil && !os.IsNotExist(err) && !os.IsPermission(err)
+}
+
+// StoreFlags returns the global flags selecting where the clusters are persisted
+func StoreFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  "store",
+			Usage: "Where the clusters are persisted, file, memory or s3. The memory store is lost when the process exits",
+			Value: fileStore,
+		},
+		cli.BoolFlag{
+			Name:  "read-only",
+			Usage: "Never write to the store, the commands only reading the clusters work while the ones changing them fail",
+		},
+		cli.IntFlag{
+			Name:  "store-read-retries",
+			Usage: "How many times the file store retries a read failing with a transient error, e.g. on a NFS home dir",
+		},
+		cli.DurationFlag{
+			Name:  "store-read-retry-delay",
+			Usage: "The wait between two reads of the file store",
+			Value: 200 * time.Millisecond,
+		},
+		cli.StringFlag{
+			Name:   "s3-bucket",
+			Usage:  "The bucket of the s3 store",
+			EnvVar: "KONTAINER_ENGINE_S3_BUCKET",
+		},
+		cli.StringFlag{
+			Name:   "s3-prefix",
+			Usage:  "The key prefix of the clusters in the s3 bucket",
+			EnvVar: "KONTAINER_ENGINE_S3_PREFIX",
+			Value:  "kontainer-engine",
+		},
+		cli.StringFlag{
+			Name:   "s3-region",
+			Usage:  "The region of the s3 bucket",
+			EnvVar: "AWS_REGION",
+			Value:  "us-east-1",
+		},
+		cli.StringFlag{
+			Name:   "s3-endpoint",
+			Usage:  "The s3 endpoint, defaults to the AWS endpoint of the region",
+			EnvVar: "KONTAINER_ENGINE_S3_ENDPOINT",
+		},
+		cli.StringFlag{
+			Name:   "s3-access-key",
+			Usage:  "The access key of the s3 store",
+			EnvVar: "AWS_ACCESS_KEY_ID",
+		},
+		cli.StringFlag{
+			Name:   "s3-secret-key",
+			Usage:  "The secret key of the s3 store",
+			EnvVar: "AWS_SECRET_ACCESS_KEY",
+		},
+	}
+}
+
+// StoreCommand defines the store command
+func StoreCommand() cli.Command {
+	return cli.Command{
+		Name:  "store",
+		Usage: "Inspect the store the clusters are persisted in",
+		Subcommands: []cli.Command{
+			{
+				Name:      "path",
+				Usage:     "Print where the selected store keeps the clusters, or the cluster given",
+				ArgsUsage: "[cluster-name]",
+				Action:    printStorePath,
+			},
+		},
+	}
+}
+
+func printStorePath(ctx *cli.Context) error {
+	location, err := storeLocation(ctx.GlobalString("store"), ctx.GlobalString("s3-bucket"), ctx.GlobalString("s3-prefix"), ctx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	fmt.Println(location)
+	return nil
+}
+
+// storeLocation returns the directory of the file store, or the s3 url of the s3 store, where the clusters are kept.
+// With a cluster name it is the location of that cluster, whether it exists or not.
+func storeLocation(storeName, s3Bucket, s3Prefix, name string) (string, error) {
+	switch storeName {
+	case "", fileStore:
+		return filepath.Join(utils.HomeDir(), "clusters", name), nil
+	case s3Store:
+		if s3Bucket == "" {
+			return "", newUsageError("s3 bucket is required for the s3 store")
+		}
+		return "s3://" + path.Join(s3Bucket, strings.Trim(s3Prefix, "/"), name), nil
+	case memoryStore:
+		return "", newUsageError("the memory store keeps the clusters in the memory of the process, they have no location")
+	}
+	return "", newUsageError("store %s is not supported, must be %s, %s or %s", storeName, fileStore, memoryStore, s3Store)
+}
+
+// SetStore selects the store the commands persist the clusters in from the global flags
+func SetStore(ctx *cli.Context) error {
+	retries, delay := ctx.GlobalInt("store-read-retries"), ctx.GlobalDuration("store-read-retry-delay")
+	if retries < 0 || delay < 0 {
+		return newUsageError("--store-read-retries and --store-read-retry-delay can't be negative")
+	}
+	fileReadRetry = readRetry{retries: retries, delay: delay}
+	readOnly = ctx.GlobalBool("read-only")
+	name := ctx.GlobalString("store")
+	if name != s3Store {
+		return setStore(name)
+	}
+	s3, err := newS3Store(ctx)
+	if err != nil {
+		return err
+	}
+	selectedStore = s3
+	return nil
+}
+
+// newS3Store returns the s3 store configured by the global flags of ctx
+func newS3Store(ctx *cli.Context) (clusterStore, error) {
+	client, err := newHTTPS3Client(s3Config{
+		bucket:       ctx.GlobalString("s3-bucket"),
+		region:       ctx.GlobalString("s3-region"),
+		endpoint:     ctx.GlobalString("s3-endpoint"),
+		accessKey:    ctx.GlobalString("s3-access-key"),
+		secretKey:    ctx.GlobalString("s3-secret-key"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newS3PersistStore(client, ctx.GlobalString("s3-prefix")), nil
+}
+
+// openStore returns the store called name whatever the selected store, the memory store is the one of the process if
+// it is selected
+func openStore(ctx *cli.Context, name string) (clusterStore, error) {
+	switch name {
+	case "", fileStore:
+		return guardReadOnly(fileClusterStore{}), nil
+	case memoryStore:
+		if memory, ok := selectedStore.(*inMemoryPersistStore); ok {
+			return guardReadOnly(memory), nil
+		}
+		return guardReadOnly(newInMemoryPersistStore()), nil
+	case s3Store:
+		store, err := newS3Store(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return guardReadOnly(store), nil
+	}
+	return nil, newUsageError("store %s is not supported, must be %s, %s or %s", name, fileStore, memoryStore, s3Store)
+}
+
+// setStore selects the file or the memory store
+func setStore(name string) error {
+	switch name {
+	case "", fileStore:
+		selectedStore = nil
+	case memoryStore:
+		// the app runs again to parse the driver flags, keep the clusters of the first run
+		if _, ok := selectedStore.(*inMemoryPersistStore); !ok {
+			selectedStore = newInMemoryPersistStore()
+		}
+	default:
+		return fmt.Errorf("store %s is not supported, must be %s, %s or %s", name, fileStore, memoryStore, s3Store)
+	}
+	return nil
+}
+
+// newPersistStore returns the selected store, kubeConfig is only used by the file store. The status transitions
+// persisted through it are notified to --notify-url.
+func newPersistStore(kubeConfig kubeConfigOptions) cluster.PersistStore {
+	if selectedStore != nil {
+		return withNotifications(guardReadOnly(selectedStore))
+	}
+	if readOnly {
+		return readOnlyStore{fileClusterStore{cliPersistStore{kubeConfig: kubeConfig}}}
+	}
+	return withNotifications(cliPersistStore{
+		kubeConfig: kubeConfig,
+	})
+}
+
+// getAllClusters returns all the clusters in the selected store
+func getAllClusters() (map[string]cluster.Cluster, error) {
+	if selectedStore != nil {
+		return selectedStore.list()
+	}
+	return store.GetAllClusterFromStore()
+}
+
+// forgetCluster deletes a cluster from the selected store, for the file store its kubeconfig entries are deleted too
+func forgetCluster(name string) error {
+	if selectedStore != nil {
+		return guardReadOnly(selectedStore).remove(name)
+	}
+	return guardReadOnly(fileClusterStore{}).remove(name)
+}
+
+// forgetKubeConfig deletes the kubeconfig entries of a cluster, only the file store writes them
+func forgetKubeConfig(name string) error {
+	if selectedStore != nil {
+		return nil
+	}
+	if err := checkWritable(name); err != nil {
+		return err
+	}
+	return deleteKubeConfigEntries(name)
+}
+
+// deleteKubeConfigEntries deletes the entries of the cluster from the kubeconfig file, if there is one
+func deleteKubeConfigEntries(name string) error {
+	kubeConfigLock.Lock()
+	defer kubeConfigLock.Unlock()
+	config, err := getConfigFromFile()
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	deleteConfigByName(&config, name)
+	return setConfigToFile(config)
+}
+
+// fileClusterStore is the file store as a clusterStore, for the commands working on a store other than the selected one
+type fileClusterStore struct {
+	cliPersistStore
+}
+
+func (fileClusterStore) list() (map[string]cluster.Cluster, error) {
+	return store.GetAllClusterFromStore()
+}
+
+// remove deletes the cluster and its kubeconfig entries
+func (fileClusterStore) remove(name string) error {
+	clusterFilePath := filepath.Join(utils.HomeDir(), "clusters", name)
+	logrus.Debugf("Deleting cluster storage path %v", clusterFilePath)
+	if err := os.RemoveAll(clusterFilePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return deleteKubeConfigEntries(name)
+}
+
+// inMemoryPersistStore is a PersistStore backed by a map, it is safe for concurrent use
+type inMemoryPersistStore struct {
+	lock     sync.Mutex
+	clusters map[string]cluster.Cluster
+	// clusterConfig is the --cluster-config file each stored cluster is also written to, so that a create failing
+	// halfway still leaves what is needed to remove the cluster
+	clusterConfig string
+}
+
+func newInMemoryPersistStore() *inMemoryPersistStore {
+	return &inMemoryPersistStore{
+		clusters: map[string]cluster.Cluster{},
+	}
+}
+
+func (m *inMemoryPersistStore) Check(name string) (cluster.State, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	cls, ok := m.clusters[name]
+	if !ok {
+		return cluster.StateNotFound, nil
+	}
+	if cls.Status != cluster.Running {
+		return cluster.StateNotRunning, nil
+	}
+	return cluster.StateRunning, nil
+}
+
+func (m *inMemoryPersistStore) Get(name string) (cluster.Cluster, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	cls, ok := m.clusters[name]
+	if !ok {
+		return cluster.Cluster{}, fmt.Errorf("%s not found", name)
+	}
+	return cls, nil
+}
+
+func (m *inMemoryPersistStore) Store(cls cluster.Cluster) error {
+	if err := cluster.StampSchemaVersion(&cls); err != nil {
+		return err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.clusters[cls.Name] = stored(cls)
+	return m.writeClusterConfig(cls)
+}
+
+func (m *inMemoryPersistStore) PersistStatus(cls cluster.Cluster, status string) error {
+	if err := cluster.StampSchemaVersion(&cls); err != nil {
+		return err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	cls.Status = status
+	m.clusters[cls.Name] = stored(cls)
+	return m.writeClusterConfig(cls)
+}
+
+// writeClusterConfig writes cls to the --cluster-config file if there is one, the caller holds the lock
+func (m *inMemoryPersistStore) writeClusterConfig(cls cluster.Cluster) error {
+	if m.clusterConfig == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(stored(cls), "", "\t")
+	if err != nil {
+		return err
+	}
+	return utils.WritePrivateFile(data, m.clusterConfig)
+}
+
+func (m *inMemoryPersistStore) list() (map[string]cluster.Cluster, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	clusters := map[string]cluster.Cluster{}
+	for name, cls := range m.clusters {
+		clusters[name] = cls
+	}
+	return clusters, nil
+}
+
+func (m *inMemoryPersistStore) remove(name string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.clusters, name)
+	return nil
+}
+
+// stored strips what the file store doesn't persist either and copies the maps, so that both stores return the same clusters
+func stored(cls cluster.Cluster) cluster.Cluster {
+	cls.SchemaVersion = cluster.CurrentSchemaVersion
+	cls.Driver = nil
+	cls.PersistStore = nil
+	cls.ConfigGetter = nil
+	cls.Metadata = copyStringMap(cls.Metadata)
+	cls.Endpoints = copyStringMap(cls.Endpoints)
+	cls.ProviderMetadata = copyStringMap(cls.ProviderMetadata)
+	if cls.Options != nil {
+		cls.Options = rpcDriver.CopyDriverOptions(cls.Options)
+	}
+	return cls
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	copied := map[string]string{}
+	for k, v := range m {
+		copied[k] = v
+	}
+	return copied
+}
+
+// useNoStore selects the memory store if --no-store is set. The cluster given with --cluster-config is loaded into it
+// and the file is rewritten each time the cluster is stored
+func useNoStore(ctx *cli.Context) (bool, error) {
+	if !ctx.Bool(noStoreFlag.Name) {
+		return false, nil
+	}
+	if err := setStore(memoryStore); err != nil {
+		return true, err
+	}
+	file := ctx.String(clusterConfigFlag.Name)
+	if file == "" {
+		return true, nil
+	}
+	memory := selectedStore.(*inMemoryPersistStore)
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		// create writes the file once the cluster is stored
+		memory.clusterConfig = file
+		return true, nil
+	} else if err != nil {
+		return true, fmt.Errorf("failed to read cluster config: %v", err)
+	}
+	cls, _, err := cluster.Migrate(data)
+	if err != nil {
+		return true, newValidationError("failed to parse cluster config %s: %v", file, err)
+	}
+	if err := memory.Store(cls); err != nil {
+		return true, err
+	}
+	memory.clusterConfig = file
+	return true, nil
+}
@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	"github.com/rancher/kontainer-engine/cluster/clustertest"
+	"github.com/rancher/kontainer-engine/utils"
+	"gopkg.in/check.v1"
+)
+
+func TestFileStoreConformance(t *testing.T) {
+	oldHome := os.Getenv("HOME")
+	defer os.Setenv("HOME", oldHome)
+	homes := []string{}
+	defer func() {
+		for _, home := range homes {
+			os.RemoveAll(home)
+		}
+	}()
+	clustertest.StoreConformanceTest(t, func() cluster.PersistStore {
+		// every case gets an empty home dir
+		home, err := ioutil.TempDir("", "kontainer-engine")
+		if err != nil {
+			t.Fatal(err)
+		}
+		homes = append(homes, home)
+		os.Setenv("HOME", home)
+		return cliPersistStore{}
+	})
+}
+
+func TestInMemoryStoreConformance(t *testing.T) {
+	clustertest.StoreConformanceTest(t, func() cluster.PersistStore {
+		return newInMemoryPersistStore()
+	})
+}
+
+type InMemoryStoreTestSuite struct {
+	tempHomeSuite
+}
+
+var _ = check.Suite(&InMemoryStoreTestSuite{})
+
+func (s *InMemoryStoreTestSuite) TestInMemoryConcurrentAccess(c *check.C) {
+	persistStore := newInMemoryPersistStore()
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			persistStore.PersistStatus(cluster.Cluster{Name: "foo"}, cluster.Running)
+			persistStore.Check("foo")
+			persistStore.Get("foo")
+		}()
+	}
+	wg.Wait()
+	state, err := persistStore.Check("foo")
+	c.Assert(err, check.IsNil)
+	c.Assert(state, check.Equals, cluster.StateRunning)
+}
+
+func (s *InMemoryStoreTestSuite) TestSetStore(c *check.C) {
+	defer setStore(fileStore)
+	c.Assert(setStore(memoryStore), check.IsNil)
+	c.Assert(newPersistStore(kubeConfigOptions{}).Store(cluster.Cluster{Name: "foo"}), check.IsNil)
+	clusters, err := getAllClusters()
+	c.Assert(err, check.IsNil)
+	c.Assert(clusters, check.HasLen, 1)
+
+	// setting the memory store again keeps the clusters
+	c.Assert(setStore(memoryStore), check.IsNil)
+	c.Assert(forgetCluster("foo"), check.IsNil)
+	clusters, err = getAllClusters()
+	c.Assert(err, check.IsNil)
+	c.Assert(clusters, check.HasLen, 0)
+
+	c.Assert(setStore("sqlite"), check.ErrorMatches, "store sqlite is not supported, must be file, memory or s3")
+}
+
+// flakyRead fails the first reads of every file with a transient error
+func flakyRead(failures int) func(string) ([]byte, error) {
+	reads := map[string]int{}
+	return func(path string) ([]byte, error) {
+		reads[path]++
+		if reads[path] <= failures {
+			return nil, &os.PathError{Op: "read", Path: path, Err: syscall.EIO}
+		}
+		return ioutil.ReadFile(path)
+	}
+}
+
+func (s *PersistStoreTestSuite) TestReadRetry(c *check.C) {
+	defer func(read func(string) ([]byte, error), retry readRetry) {
+		readStoreFile, fileReadRetry = read, retry
+	}(readStoreFile, fileReadRetry)
+	writeClusterConfig(c, "flaky", `{"name":"flaky","driverName":"mock","status":"Running"}`)
+
+	// no retry by default
+	readStoreFile = flakyRead(1)
+	_, err := cliPersistStore{}.Get("flaky")
+	c.Assert(err, check.ErrorMatches, "failed to read config of cluster flaky from .*: read .*: input/output error")
+
+	clock := utils.NewFakeClock(time.Now())
+	fileReadRetry = readRetry{retries: 2, delay: time.Second, clock: clock}
+	readStoreFile = flakyRead(1)
+	cls, err := cliPersistStore{}.Get("flaky")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.Name, check.Equals, "flaky")
+	state, err := cliPersistStore{}.Check("flaky")
+	c.Assert(err, check.IsNil)
+	c.Assert(state, check.Equals, cluster.StateRunning)
+	c.Assert(clock.Sleeps(), check.DeepEquals, []time.Duration{time.Second})
+
+	// the retries are bounded
+	readStoreFile = flakyRead(3)
+	_, err = cliPersistStore{}.Get("flaky")
+	c.Assert(err, check.NotNil)
+
+	// a missing cluster isn't retried
+	reads := 0
+	readStoreFile = func(path string) ([]byte, error) {
+		reads++
+		return ioutil.ReadFile(path)
+	}
+	state, err = cliPersistStore{}.Check("missing")
+	c.Assert(err, check.IsNil)
+	c.Assert(state, check.Equals, cluster.StateNotFound)
+	c.Assert(reads, check.Equals, 1)
+}
+
+func (s *InMemoryStoreTestSuite) TestStoreLocation(c *check.C) {
+	// the tests run with HOME in a temp dir
+	home := os.Getenv("HOME")
+	location, err := storeLocation(fileStore, "", "", "")
+	c.Assert(err, check.IsNil)
+	c.Assert(location, check.Equals, filepath.Join(home, ".kontainer", "clusters"))
+	location, err = storeLocation("", "", "", "foo")
+	c.Assert(err, check.IsNil)
+	c.Assert(location, check.Equals, filepath.Join(home, ".kontainer", "clusters", "foo"))
+	// it is the directory the file store writes the cluster to
+	c.Assert(cliPersistStore{}.Store(cluster.Cluster{Name: "foo"}), check.IsNil)
+	_, err = os.Stat(filepath.Join(location, defaultConfigName))
+	c.Assert(err, check.IsNil)
+
+	location, err = storeLocation(s3Store, "my-bucket", "/ci/", "foo")
+	c.Assert(err, check.IsNil)
+	c.Assert(location, check.Equals, "s3://my-bucket/ci/foo")
+	location, err = storeLocation(s3Store, "my-bucket", "", "")
+	c.Assert(err, check.IsNil)
+	c.Assert(location, check.Equals, "s3://my-bucket")
+
+	_, err = storeLocation(s3Store, "", "ci", "foo")
+	c.Assert(err, check.ErrorMatches, "s3 bucket is required for the s3 store")
+	_, err = storeLocation(memoryStore, "", "", "foo")
+	c.Assert(ExitCode(err), check.Equals, ExitUsage)
+}
+
+type ReadOnlyStoreTestSuite struct {
+	tempHomeSuite
+	oldArgs []string
+}
+
+var _ = check.Suite(&ReadOnlyStoreTestSuite{})
+
+func (s *ReadOnlyStoreTestSuite) SetUpTest(c *check.C) {
+	s.tempHomeSuite.SetUpTest(c)
+	s.oldArgs = os.Args
+}
+
+func (s *ReadOnlyStoreTestSuite) TearDownTest(c *check.C) {
+	readOnly = false
+	os.Args = s.oldArgs
+	s.tempHomeSuite.TearDownTest(c)
+}
+
+func (s *ReadOnlyStoreTestSuite) TestWritesFail(c *check.C) {
+	c.Assert(cliPersistStore{}.Store(migratedCluster("foo", "1.1.1.1")), check.IsNil)
+	readOnly = true
+
+	store := newPersistStore(kubeConfigOptions{})
+	cls, err := store.Get("foo")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.Endpoint, check.Equals, "1.1.1.1")
+	clusters, err := getAllClusters()
+	c.Assert(err, check.IsNil)
+	c.Assert(clusters, check.HasLen, 1)
+
+	cls.Endpoint = "2.2.2.2"
+	err = store.Store(cls)
+	c.Assert(err, check.ErrorMatches, "cluster foo can't be changed, the store is read-only, run without --read-only to change it")
+	c.Assert(ExitCode(err), check.Equals, ExitUsage)
+	c.Assert(store.PersistStatus(cls, cluster.Updating), check.NotNil)
+	c.Assert(forgetCluster("foo"), check.NotNil)
+
+	// the commands changing the cluster fail before the driver is called
+	err = newTestApp().Run([]string{"kontainer-engine", "rm", "foo"})
+	c.Assert(err, check.ErrorMatches, "cluster foo can't be changed.*")
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "bar"}
+	c.Assert(newTestApp().Run(os.Args), check.ErrorMatches, "cluster bar can't be changed.*")
+
+	readOnly = false
+	cls, err = cliPersistStore{}.Get("foo")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.Endpoint, check.Equals, "1.1.1.1")
+	c.Assert(cls.Status, check.Equals, cluster.Running)
+	_, err = os.Stat(filepath.Join(utils.HomeDir(), "clusters", "bar"))
+	c.Assert(os.IsNotExist(err), check.Equals, true)
+}
+
+func (s *ReadOnlyStoreTestSuite) TestMemoryStore(c *check.C) {
+	memory := newInMemoryPersistStore()
+	c.Assert(memory.Store(migratedCluster("foo", "1.1.1.1")), check.IsNil)
+	readOnly = true
+	store := guardReadOnly(memory)
+	_, err := store.Get("foo")
+	c.Assert(err, check.IsNil)
+	c.Assert(store.remove("foo"), check.NotNil)
+	_, err = memory.Get("foo")
+	c.Assert(err, check.IsNil)
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"github.com/rancher/kontainer-engine/cluster"
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/urfave/cli"
+)
+
+var fromTemplateFlag = cli.StringFlag{
+	Name:  "from-template",
+	Usage: "The name of an existing cluster whose stored driver options are the defaults of the created cluster, the flags override them",
+}
+
+// templateCluster returns the stored cluster named by --from-template in ctx, nil if the flag isn't set
+func templateCluster(ctx *cli.Context, persistStore cluster.PersistStore, name string) (*cluster.Cluster, error) {
+	templateName := ctx.String(fromTemplateFlag.Name)
+	if templateName == "" {
+		return nil, nil
+	}
+	if templateName == name {
+		return nil, newUsageError("the cluster name must differ from the name of the template cluster %s", templateName)
+	}
+	template, err := getTemplateCluster(persistStore, templateName)
+	if err != nil {
+		return nil, err
+	}
+	if driverName := ctx.String("driver"); driverName != "" && driverName != template.DriverName {
+		return nil, newValidationError("template cluster %s uses driver %s, not %s", templateName, template.DriverName, driverName)
+	}
+	return template, nil
+}
+
+// getTemplateCluster returns the stored cluster named templateName if its driver options were stored with it
+func getTemplateCluster(persistStore cluster.PersistStore, templateName string) (*cluster.Cluster, error) {
+	template, err := persistStore.Get(templateName)
+	if err != nil || template.DriverName == "" {
+		return nil, newNotFoundError("template cluster %s not found", templateName)
+	}
+	if template.Options == nil {
+		return nil, newValidationError("template cluster %s was created before the driver options were stored with the clusters and can't be used as a template", templateName)
+	}
+	return &template, nil
+}
+
+// applyTemplate overlays the options set on the command line or in the environment on a copy of the stored options of
+// a template cluster. The credential isn't stored, so a credential profile or provider of the template is resolved again
+// unless a credential is set.
+func applyTemplate(template *rpcDriver.DriverOptions, driverOpts rpcDriver.DriverOptions) rpcDriver.DriverOptions {
+	merged := rpcDriver.CopyDriverOptions(template)
+	if driverOpts.IsSet(rpcDriver.CredentialOption) {
+		delete(merged.StringOptions, credentialProfileFlag.Name)
+		delete(merged.StringOptions, credentialProviderFlag.Name)
+	}
+	overlaySetOptions(merged, driverOpts)
+	return *merged
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+
+	generic "github.com/rancher/kontainer-engine/driver"
+	"github.com/rancher/kontainer-engine/driver/mock"
+	"github.com/urfave/cli"
+	"gopkg.in/check.v1"
+)
+
+type TemplateTestSuite struct {
+	tempHomeSuite
+	oldArgs []string
+}
+
+var _ = check.Suite(&TemplateTestSuite{})
+
+func (s *TemplateTestSuite) SetUpTest(c *check.C) {
+	s.tempHomeSuite.SetUpTest(c)
+	s.oldArgs = os.Args
+}
+
+func (s *TemplateTestSuite) TearDownTest(c *check.C) {
+	os.Args = s.oldArgs
+	s.tempHomeSuite.TearDownTest(c)
+}
+
+// createContext parses args with the create flags and the create flags of the mock driver
+func createContext(c *check.C, args ...string) *cli.Context {
+	driverFlags, err := mock.NewDriver().GetDriverCreateOptions()
+	c.Assert(err, check.IsNil)
+	flags := append(CreateCommand().Flags, getDriverFlags(*driverFlags)...)
+	set := flag.NewFlagSet("create", flag.ContinueOnError)
+	set.SetOutput(ioutil.Discard)
+	for _, f := range flags {
+		f.Apply(set)
+	}
+	c.Assert(set.Parse(args), check.IsNil)
+	ctx := cli.NewContext(nil, set, nil)
+	ctx.Command = cli.Command{Name: "create", Flags: flags}
+	return ctx
+}
+
+func (s *TemplateTestSuite) TestCloneFromTemplate(c *check.C) {
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "--node-count", "2", "--description", "base", "--labels", "team=core", "template"}
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+
+	// the driver is the one of the template
+	os.Args = []string{"kontainer-engine", "create", "--from-template", "template", "--node-count", "5", "clone"}
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+	clone, err := cliPersistStore{}.Get("clone")
+	c.Assert(err, check.IsNil)
+	c.Assert(clone.DriverName, check.Equals, "mock")
+	c.Assert(clone.NodeCount, check.Equals, int64(5))
+	c.Assert(clone.Options.IntOptions["node-count"], check.Equals, int64(5))
+	c.Assert(clone.Options.StringOptions["name"], check.Equals, "clone")
+	c.Assert(clone.Options.StringOptions["description"], check.Equals, "base")
+	c.Assert(clone.Options.StringSliceOptions["labels"].Value, check.DeepEquals, []string{"team=core"})
+
+	// the template is unchanged
+	template, err := cliPersistStore{}.Get("template")
+	c.Assert(err, check.IsNil)
+	c.Assert(template.NodeCount, check.Equals, int64(2))
+	c.Assert(template.Options.StringOptions["name"], check.Equals, "template")
+}
+
+func (s *TemplateTestSuite) TestCloneResolvesCredentialProfile(c *check.C) {
+	c.Assert(storeCredentialProfile(credentialProfile{Name: "prod", Driver: "mock", Credential: "rotated"}), check.IsNil)
+	template := newDriverOptions()
+	template.StringOptions["driver"] = "mock"
+	template.StringOptions[credentialProfileFlag.Name] = "prod"
+
+	driverOptions, err := cliConfigGetter{name: "clone", ctx: createContext(c, "clone"), template: &template}.GetConfig()
+	c.Assert(err, check.IsNil)
+	c.Assert(driverOptions.StringOptions[generic.CredentialOption], check.Equals, "rotated")
+
+	// a credential on the command line replaces the profile of the template
+	driverOptions, err = cliConfigGetter{name: "clone", ctx: createContext(c, "--credential", "own", "clone"), template: &template}.GetConfig()
+	c.Assert(err, check.IsNil)
+	c.Assert(driverOptions.StringOptions[generic.CredentialOption], check.Equals, "own")
+	c.Assert(driverOptions.StringOptions[credentialProfileFlag.Name], check.Equals, "")
+}
+
+func (s *TemplateTestSuite) TestTemplateErrors(c *check.C) {
+	os.Args = []string{"kontainer-engine", "create", "--from-template", "missing", "clone"}
+	err := newTestApp().Run(os.Args)
+	c.Assert(err, check.ErrorMatches, "template cluster missing not found")
+
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "template"}
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+	os.Args = []string{"kontainer-engine", "create", "--from-template", "template", "template"}
+	err = newTestApp().Run(os.Args)
+	c.Assert(err, check.ErrorMatches, "the cluster name must differ .*")
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"sort"
+	"strings"
+
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/urfave/cli"
+)
+
+// toggleOptions maps the NAME=on|off values of a create flag to the bool options of the driver whose canonical names
+// are prefix followed by NAME, e.g. --addon dashboard=on to the option with the canonical name addon-dashboard
+type toggleOptions struct {
+	flag cli.StringSliceFlag
+	// kind names the toggles in the errors, e.g. addon
+	kind   string
+	prefix string
+}
+
+// parse parses a value of the flag into the name of the toggle and whether it is on
+func (t toggleOptions) parse(value string) (string, bool, error) {
+	kv := strings.SplitN(value, "=", 2)
+	if len(kv) != 2 || kv[0] == "" {
+		return "", false, newValidationError("invalid %s %q, use NAME=on or NAME=off", t.kind, value)
+	}
+	switch kv[1] {
+	case "on":
+		return kv[0], true, nil
+	case "off":
+		return kv[0], false, nil
+	}
+	return "", false, newValidationError("invalid %s %q, the value must be on or off", t.kind, value)
+}
+
+// declared returns the sorted names of the toggles the driver declares an option for
+func (t toggleOptions) declared(driverFlags rpcDriver.DriverFlags) []string {
+	names := []string{}
+	for _, flag := range driverFlags.Options {
+		if flag != nil && flag.Type == rpcDriver.BoolType && strings.HasPrefix(flag.Canonical, t.prefix) {
+			names = append(names, strings.TrimPrefix(flag.Canonical, t.prefix))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// mapOptions sets the options of the driver from the values of the flag. A toggle the driver has no option for is a
+// validation error listing the toggles it supports.
+func (t toggleOptions) mapOptions(driverOptions *rpcDriver.DriverOptions, driverFlags rpcDriver.DriverFlags) error {
+	values := driverOptions.StringSliceOptions[t.flag.Name]
+	if values == nil || len(values.Value) == 0 {
+		return nil
+	}
+	given := map[string]bool{}
+	for _, value := range values.Value {
+		name, on, err := t.parse(value)
+		if err != nil {
+			return err
+		}
+		if previous, ok := given[name]; ok && previous != on {
+			return newUsageError("%s %s is turned both on and off", t.kind, name)
+		}
+		given[name] = on
+
+		option := canonicalOption(driverFlags, t.prefix+name)
+		if option == "" || driverFlags.Options[option].Type != rpcDriver.BoolType {
+			supported := strings.Join(t.declared(driverFlags), ", ")
+			if supported == "" {
+				supported = "none"
+			}
+			return newValidationError("the driver has no %s %s, its %ss are %s", name, t.kind, t.kind, supported)
+		}
+		if driverOptions.IsSet(option) && driverOptions.BoolOptions[option] != on {
+			return newUsageError("--%s %s and --%s are set to different values", t.flag.Name, value, option)
+		}
+		if !driverOptions.IsSet(option) {
+			driverOptions.SetKeys = append(driverOptions.SetKeys, option)
+		}
+		driverOptions.BoolOptions[option] = on
+	}
+	return nil
+}
@@ -1,13 +1,14 @@
 package cmd
 
 type kubeConfig struct {
-	APIVersion     string          `yaml:"apiVersion,omitempty"`
-	Clusters       []configCluster `yaml:"clusters,omitempty"`
-	Contexts       []configContext `yaml:"contexts,omitempty"`
-	Users          []configUser    `yaml:"users,omitempty"`
-	CurrentContext string          `yaml:"current-context,omitempty"`
-	Kind           string          `yaml:"kind,omitempty"`
-	Preferences    string          `yaml:"preferences,omitempty"`
+	APIVersion     string                 `yaml:"apiVersion,omitempty"`
+	Clusters       []configCluster        `yaml:"clusters,omitempty"`
+	Contexts       []configContext        `yaml:"contexts,omitempty"`
+	Users          []configUser           `yaml:"users,omitempty"`
+	CurrentContext string                 `yaml:"current-context,omitempty"`
+	Kind           string                 `yaml:"kind,omitempty"`
+	Preferences    map[string]interface{} `yaml:"preferences,omitempty"`
+	Extensions     []namedExtension       `yaml:"extensions,omitempty"`
 }
 
 type configCluster struct {
@@ -16,8 +17,14 @@ type configCluster struct {
 }
 
 type dataCluster struct {
-	CertificateAuthorityData string `yaml:"certificate-authority-data,omitempty"`
-	Server                   string `yaml:"server,omitempty"`
+	CertificateAuthorityData string           `yaml:"certificate-authority-data,omitempty"`
+	Server                   string           `yaml:"server,omitempty"`
+	Extensions               []namedExtension `yaml:"extensions,omitempty"`
+}
+
+type namedExtension struct {
+	Name      string                 `yaml:"name,omitempty"`
+	Extension map[string]interface{} `yaml:"extension,omitempty"`
 }
 
 type configContext struct {
@@ -1,16 +1,48 @@
 package cmd
 
 import (
-	"errors"
-	"fmt"
-
 	"os"
 
 	generic "github.com/rancher/kontainer-engine/driver"
-	"github.com/rancher/kontainer-engine/store"
 	"github.com/urfave/cli"
 )
 
+var forceUpdateFlag = cli.BoolFlag{
+	Name:  "force",
+	Usage: "Call the driver even if the options are the ones the cluster was created or last updated with",
+}
+
+// volatileOptions are the create and update flags that change what the commands write or check, not the cluster, they
+// are left out of the spec hash of the clusters
+var volatileOptions = []string{
+	"write-credentials",
+	"endpoint-type",
+	"kubeconfig-api-version",
+	"kubeconfig-extension",
+	noKubeConfigFlag.Name,
+	kubeConfigOutFlag.Name,
+	interactiveFlag.Name,
+	generateNameFlag.Name,
+	credentialStdinFlag.Name,
+	debugDumpFlag.Name,
+	debugDumpRedactFlag.Name,
+	postCreateHookFlag.Name,
+	hookRequiredFlag.Name,
+	credentialProfileFlag.Name,
+	credentialProviderFlag.Name,
+	vaultAddrFlag.Name,
+	vaultPathFlag.Name,
+	vaultKeyFlag.Name,
+	fromTemplateFlag.Name,
+	fromCRDFlag.Name,
+	verifyConnectivityFlag.Name,
+	noStoreFlag.Name,
+	clusterConfigFlag.Name,
+	allowVersionMismatchFlag.Name,
+	forceUpdateFlag.Name,
+	noWaitFlag.Name,
+}
+
 var updateHelpTmeplate = `{{.Usage}}
 {{if .Description}}{{.Description}}{{end}}
 Usage: kontainer-engine [global option] {{.Name}} {{if .Flags}}[OPTIONS] {{end}}{{if ne "None" .ArgsUsage}}{{if ne "" .ArgsUsage}}{{.ArgsUsage}}{{else}}[cluster-name]{{end}}{{end}}
@@ -27,10 +59,18 @@ func UpdateCommand() cli.Command {
 		Action:             updateWrapper,
 		SkipFlagParsing:    true,
 		CustomHelpTemplate: updateHelpTmeplate,
+		Flags: append([]cli.Flag{
+			allowVersionMismatchFlag,
+			forceUpdateFlag,
+			noWaitFlag,
+		}, waitFlags...),
 	}
 }
 
 func updateWrapper(ctx *cli.Context) error {
+	if ctx.NArg() == 0 {
+		return usageErrorWithHelp(ctx, "update", "cluster name is required")
+	}
 	name := ctx.Args().Get(len(ctx.Args()) - 1)
 	if name == "--help" {
 		if len(ctx.Args())-2 >= 0 {
@@ -39,13 +79,13 @@ func updateWrapper(ctx *cli.Context) error {
 			return cli.ShowCommandHelp(ctx, "update")
 		}
 	}
-	clusters, err := store.GetAllClusterFromStore()
+	clusters, err := getAllClusters()
 	if err != nil {
 		return err
 	}
 	cluster, ok := clusters[name]
 	if !ok {
-		return fmt.Errorf("cluster %v can't be found", name)
+		return newNotFoundError("cluster %v can't be found", name)
 	}
 	rpcClient, addr, err := runRPCDriver(cluster.DriverName)
 	if err != nil {
@@ -56,50 +96,103 @@ func updateWrapper(ctx *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	flags := getDriverFlags(driverFlags)
-	for i, command := range ctx.App.Commands {
-		if command.Name == "update" {
-			updateCmd := &ctx.App.Commands[i]
-			updateCmd.SkipFlagParsing = false
-			updateCmd.Flags = append(updateCmd.Flags, flags...)
-			updateCmd.Action = updateCluster
-		}
-	}
-	if len(os.Args) > 1 && addr != "" {
-		args := []string{os.Args[0], "--plugin-listen-addr", addr}
-		args = append(args, os.Args[1:len(os.Args)]...)
-		return ctx.App.Run(args)
-	}
-	return ctx.App.Run(os.Args)
+	return rerunWithDriverFlags(ctx, "update", getUpdateDriverFlags(driverFlags), updateCluster, addr)
 }
 
 func updateCluster(ctx *cli.Context) error {
 	name := ctx.Args().Get(0)
 	if name == "" {
-		return errors.New("name is required when inspecting cluster")
+		return usageErrorWithHelp(ctx, "update", "cluster name is required")
 	} else if name == "--help" {
 		// in case of `./kontainer-engine update cluster1 --help`
 		return cli.ShowCommandHelp(ctx, "update")
 	}
-	clusters, err := store.GetAllClusterFromStore()
+	// the cluster is read once the lock is held, so that the changes of the command holding it are seen
+	lock, err := lockCluster(ctx, name, "update")
+	if err != nil {
+		return err
+	}
+	defer unlockCluster(name, lock)
+	clusters, err := getAllClusters()
 	if err != nil {
 		return err
 	}
 	cluster, ok := clusters[name]
 	if !ok {
-		return fmt.Errorf("cluster %v can't be found", name)
+		return newNotFoundError("cluster %v can't be found", name)
 	}
 	addr := ctx.GlobalString("plugin-listen-addr")
 	rpcClient, err := generic.NewClient(cluster.DriverName, addr)
 	if err != nil {
 		return err
 	}
-	configGetter := cliConfigGetter{
-		name: name,
-		ctx:  ctx,
+	configGetter := updateConfigGetter{
+		name:   name,
+		ctx:    ctx,
+		stored: cluster.Options,
 	}
 	cluster.ConfigGetter = configGetter
-	cluster.PersistStore = cliPersistStore{}
+	cluster.PersistStore = newPersistStore(kubeConfigOptions{})
 	cluster.Driver = rpcClient
-	return cluster.Update()
+	cluster.VolatileOptions = volatileOptions
+	cluster.ForceUpdate = ctx.Bool(forceUpdateFlag.Name)
+	operation := newOperationContext()
+	if err := verifyDriverVersion(operation, &cluster, ctx.Bool(allowVersionMismatchFlag.Name)); err != nil {
+		return err
+	}
+	if err := cluster.UpdateContext(operation); err != nil {
+		return err
+	}
+	return writeOperationResult(os.Stdout, ctx.GlobalString("output"), cluster)
+}
+
+// updateConfigGetter starts from the stored options of the cluster and overlays the flags that are set, so that an
+// update only changes the options given on the command line instead of resetting the others to their defaults
+type updateConfigGetter struct {
+	name   string
+	ctx    *cli.Context
+	stored *generic.DriverOptions
+}
+
+func (u updateConfigGetter) GetConfig() (generic.DriverOptions, error) {
+	driverOpts, err := getDriverOpts(u.ctx)
+	if err != nil {
+		return driverOpts, err
+	}
+	// the clusters created before the options were stored get all the flags as before
+	if u.stored != nil {
+		merged := generic.CopyDriverOptions(u.stored)
+		overlaySetOptions(merged, driverOpts)
+		driverOpts = *merged
+	}
+	if err := resolveCredentialProfile(&driverOpts); err != nil {
+		return driverOpts, err
+	}
+	if err := resolveCredentialProvider(&driverOpts); err != nil {
+		return driverOpts, err
+	}
+	driverOpts.StringOptions["name"] = u.name
+	return driverOpts, nil
+}
+
+// overlaySetOptions copies the options set on the command line or in the environment from driverOpts to merged
+func overlaySetOptions(merged *generic.DriverOptions, driverOpts generic.DriverOptions) {
+	for _, name := range driverOpts.SetKeys {
+		if v, ok := driverOpts.StringOptions[name]; ok {
+			merged.StringOptions[name] = v
+		}
+		if v, ok := driverOpts.BoolOptions[name]; ok {
+			merged.BoolOptions[name] = v
+		}
+		if v, ok := driverOpts.IntOptions[name]; ok {
+			merged.IntOptions[name] = v
+		}
+		if v, ok := driverOpts.StringSliceOptions[name]; ok {
+			merged.StringSliceOptions[name] = v
+		}
+		if name == nodePoolFlag.Name {
+			merged.NodePools = driverOpts.NodePools
+		}
+	}
+	merged.SetKeys = driverOpts.SetKeys
 }
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	generic "github.com/rancher/kontainer-engine/driver"
+	"github.com/rancher/kontainer-engine/driver/mock"
+	"github.com/rancher/kontainer-engine/utils"
+	"github.com/urfave/cli"
+	"gopkg.in/check.v1"
+)
+
+type UpdateTestSuite struct {
+	tempHomeSuite
+	oldArgs []string
+}
+
+var _ = check.Suite(&UpdateTestSuite{})
+
+func (s *UpdateTestSuite) SetUpTest(c *check.C) {
+	s.tempHomeSuite.SetUpTest(c)
+	s.oldArgs = os.Args
+}
+
+func (s *UpdateTestSuite) TearDownTest(c *check.C) {
+	os.Args = s.oldArgs
+	s.tempHomeSuite.TearDownTest(c)
+}
+
+// updateContext parses args with the update flags and the update flags of the mock driver
+func updateContext(c *check.C, args ...string) *cli.Context {
+	driverFlags, err := mock.NewDriver().GetDriverUpdateOptions()
+	c.Assert(err, check.IsNil)
+	flags := append(UpdateCommand().Flags, getUpdateDriverFlags(*driverFlags)...)
+	set := flag.NewFlagSet("update", flag.ContinueOnError)
+	set.SetOutput(ioutil.Discard)
+	for _, f := range flags {
+		f.Apply(set)
+	}
+	c.Assert(set.Parse(args), check.IsNil)
+	ctx := cli.NewContext(nil, set, nil)
+	ctx.Command = cli.Command{Name: "update", Flags: flags}
+	return ctx
+}
+
+func (s *UpdateTestSuite) TestUpdateKeepsStoredOptions(c *check.C) {
+	stored := newDriverOptions()
+	stored.StringOptions["description"] = "stored"
+	stored.StringOptions[generic.WaitTimeoutOption] = "2h"
+	stored.IntOptions["node-count"] = 3
+
+	driverOptions, err := updateConfigGetter{
+		name:   "foo",
+		ctx:    updateContext(c, "--node-count", "5", "foo"),
+		stored: &stored,
+	}.GetConfig()
+	c.Assert(err, check.IsNil)
+	c.Assert(driverOptions.IntOptions["node-count"], check.Equals, int64(5))
+	// not given, so the stored values are kept instead of the flag defaults
+	c.Assert(driverOptions.StringOptions[generic.WaitTimeoutOption], check.Equals, "2h")
+	c.Assert(driverOptions.StringOptions["description"], check.Equals, "stored")
+	c.Assert(driverOptions.StringOptions["name"], check.Equals, "foo")
+	// the stored options aren't changed
+	c.Assert(stored.IntOptions["node-count"], check.Equals, int64(3))
+}
+
+func (s *UpdateTestSuite) TestUpdateIgnoresDriverEnv(c *check.C) {
+	c.Assert(os.Setenv("KE_DRIVER_NODE_COUNT", "7"), check.IsNil)
+	defer os.Unsetenv("KE_DRIVER_NODE_COUNT")
+	stored := newDriverOptions()
+	stored.IntOptions["node-count"] = 3
+
+	driverOptions, err := updateConfigGetter{
+		name:   "foo",
+		ctx:    updateContext(c, "foo"),
+		stored: &stored,
+	}.GetConfig()
+	c.Assert(err, check.IsNil)
+	// a variable left over from a create doesn't resize the cluster
+	c.Assert(driverOptions.IntOptions["node-count"], check.Equals, int64(3))
+	c.Assert(driverOptions.IsSet("node-count"), check.Equals, false)
+}
+
+func (s *UpdateTestSuite) TestUpdateWithoutStoredOptions(c *check.C) {
+	driverOptions, err := updateConfigGetter{
+		name: "foo",
+		ctx:  updateContext(c, "foo"),
+	}.GetConfig()
+	c.Assert(err, check.IsNil)
+	// a cluster stored before the options gets all the flags
+	c.Assert(driverOptions.StringOptions[generic.WaitTimeoutOption], check.Equals, utils.DefaultPollTimeout.String())
+}
+
+func (s *UpdateTestSuite) TestUpdatePersistsMergedOptions(c *check.C) {
+	app := newTestApp()
+	app.Commands = append(app.Commands, UpdateCommand())
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "--wait-timeout", "2h", "--credential", "secret", "--node-count", "2", "update-me"}
+	c.Assert(app.Run(os.Args), check.IsNil)
+	cls, err := cliPersistStore{}.Get("update-me")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.Options, check.NotNil)
+	c.Assert(cls.Options.StringOptions[generic.WaitTimeoutOption], check.Equals, "2h")
+	c.Assert(cls.SpecHash, check.Not(check.Equals), "")
+	createHash := cls.SpecHash
+	// the credential isn't persisted
+	_, ok := cls.Options.StringOptions[generic.CredentialOption]
+	c.Assert(ok, check.Equals, false)
+
+	app = newTestApp()
+	app.Commands = append(app.Commands, UpdateCommand())
+	os.Args = []string{"kontainer-engine", "update", "--node-count", "4", "update-me"}
+	c.Assert(app.Run(os.Args), check.IsNil)
+	cls, err = cliPersistStore{}.Get("update-me")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.Status, check.Equals, cluster.Running)
+	c.Assert(cls.NodeCount, check.Equals, int64(4))
+	c.Assert(cls.Options.IntOptions["node-count"], check.Equals, int64(4))
+	c.Assert(cls.Options.StringOptions[generic.WaitTimeoutOption], check.Equals, "2h")
+	c.Assert(cls.SpecHash, check.Not(check.Equals), createHash)
+}
+
+func (s *UpdateTestSuite) TestUpdateWithUnchangedOptions(c *check.C) {
+	app := newTestApp()
+	app.Commands = append(app.Commands, UpdateCommand())
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "--node-count", "2", "--write-credentials", c.MkDir(), "unchanged"}
+	c.Assert(app.Run(os.Args), check.IsNil)
+	created, err := cliPersistStore{}.Get("unchanged")
+	c.Assert(err, check.IsNil)
+
+	// the flags of the commands and the wait options aren't part of the hash
+	for _, args := range [][]string{
+		{"--node-count", "2"},
+		{"--wait-timeout", "5m", "--allow-version-mismatch"},
+		{"--force"},
+	} {
+		app = newTestApp()
+		app.Commands = append(app.Commands, UpdateCommand())
+		os.Args = append(append([]string{"kontainer-engine", "update"}, args...), "unchanged")
+		c.Assert(app.Run(os.Args), check.IsNil)
+		cls, err := cliPersistStore{}.Get("unchanged")
+		c.Assert(err, check.IsNil)
+		c.Assert(cls.SpecHash, check.Equals, created.SpecHash, check.Commentf("update %v", args))
+	}
+}
+
+func (s *UpdateTestSuite) TestUpdateRefreshesVersion(c *check.C) {
+	app := newTestApp()
+	app.Commands = append(app.Commands, UpdateCommand())
+	// the version the driver reports is stored, not the one requested
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "versioned"}
+	c.Assert(app.Run(os.Args), check.IsNil)
+	cls, err := cliPersistStore{}.Get("versioned")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.Version, check.Equals, "v1.8.4")
+
+	app = newTestApp()
+	app.Commands = append(app.Commands, UpdateCommand())
+	os.Args = []string{"kontainer-engine", "update", "--version", "v1.9.2", "versioned"}
+	c.Assert(app.Run(os.Args), check.IsNil)
+	cls, err = cliPersistStore{}.Get("versioned")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.Version, check.Equals, "v1.9.2")
+
+	// ls and inspect read it from the store
+	clusters, err := getAllClusters()
+	c.Assert(err, check.IsNil)
+	c.Assert(clusters["versioned"].Version, check.Equals, "v1.9.2")
+}
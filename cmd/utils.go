@@ -1,7 +1,14 @@
 package cmd
 
 import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"io"
 	"io/ioutil"
+	"net"
+	"strconv"
+	"sync"
 
 	"fmt"
 	"os"
@@ -17,8 +24,94 @@ import (
 	"strings"
 )
 
-// runRPCDriver runs the rpc server and returns
+// kubeConfigLock serializes the changes to the kubeconfig file made by the goroutines of the process, e.g. by the
+// requests of serve
+var kubeConfigLock sync.Mutex
+
+var allowVersionMismatchFlag = cli.BoolFlag{
+	Name:  "allow-version-mismatch",
+	Usage: "Continue even if the cluster was created by another version of the driver",
+}
+
+// newOperationContext returns the context of a create, an update or a remove with a new operation id, so that the
+// lines it logs can be told from the ones of the other operations
+func newOperationContext() context.Context {
+	return utils.WithOperationID(context.Background(), utils.NewOperationID())
+}
+
+// verifyDriverVersion refuses to operate on a cluster created by another driver version unless allowMismatch is set,
+// the mismatch allowed is logged with the operation id of ctx
+func verifyDriverVersion(ctx context.Context, cls *cluster.Cluster, allowMismatch bool) error {
+	if err := cls.VerifyDriverVersionContext(ctx, allowMismatch); err != nil {
+		return fmt.Errorf("%v, use --%s to continue anyway", err, allowVersionMismatchFlag.Name)
+	}
+	return nil
+}
+
+// sslCertFileEnv is read by the go TLS stack of the drivers to locate the trusted CA bundle
+const sslCertFileEnv = "SSL_CERT_FILE"
+
+// cloudCABundle is the CA bundle the drivers use to verify the cloud APIs, empty means the system bundle
+var cloudCABundle string
+
+// SetCloudCABundle validates the PEM bundle at path and makes the drivers started afterwards trust it
+func SetCloudCABundle(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read cloud CA bundle: %v", err)
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(data) {
+		return fmt.Errorf("cloud CA bundle %s doesn't contain any PEM certificate", path)
+	}
+	cloudCABundle = path
+	return nil
+}
+
+// driverAddr is the address of an already running driver the commands connect to instead of running their own
+var driverAddr string
+
+// SetDriverAddr makes the commands connect to the driver listening on addr, a host:port, instead of starting the
+// driver plugin themselves. Empty starts the plugin.
+func SetDriverAddr(addr string) error {
+	if addr != "" {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return newUsageError("invalid driver address %q, use host:port: %v", addr, err)
+		}
+	}
+	driverAddr = addr
+	return nil
+}
+
+// allowIncompatibleDriver makes the commands run the drivers speaking another rpc protocol version, with a warning
+var allowIncompatibleDriver bool
+
+// SetAllowIncompatibleDriver makes the commands run the drivers failing the protocol version handshake instead of
+// refusing them
+func SetAllowIncompatibleDriver(allow bool) {
+	allowIncompatibleDriver = allow
+}
+
+// runRPCDriver runs the rpc server and returns a client of it once the driver passed the protocol version handshake
 func runRPCDriver(driverName string) (*generic.GrpcClient, string, error) {
+	rpcClient, addr, err := startRPCDriver(driverName)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := checkProtocolVersion(driverName, rpcClient); err != nil {
+		return nil, "", err
+	}
+	return rpcClient, addr, nil
+}
+
+// startRPCDriver runs the rpc server, or connects to the one of --driver-addr, and returns a client of it
+func startRPCDriver(driverName string) (*generic.GrpcClient, string, error) {
+	if driverAddr != "" {
+		return connectRPCDriver(driverName, driverAddr)
+	}
+	if cloudCABundle != "" {
+		// the drivers run in this process so they pick up the bundle from the environment
+		os.Setenv(sslCertFileEnv, cloudCABundle)
+	}
 	// addrChan is the channel to receive the server listen address
 	addrChan := make(chan string)
 	plugin.Run(driverName, addrChan)
@@ -28,9 +121,43 @@ func runRPCDriver(driverName string) (*generic.GrpcClient, string, error) {
 	if err != nil {
 		return nil, "", err
 	}
+	rpcClient.SetContext(driverContext)
 	return rpcClient, addr, nil
 }
 
+// connectRPCDriver connects to the driver already listening on addr, it fails if the driver doesn't answer
+func connectRPCDriver(driverName, addr string) (*generic.GrpcClient, string, error) {
+	rpcClient, err := generic.NewClient(driverName, addr)
+	if err != nil {
+		return nil, "", err
+	}
+	rpcClient.SetContext(driverContext)
+	if _, err := rpcClient.GetVersion(); err != nil {
+		return nil, "", &exitError{code: ExitDriverFailure, err: fmt.Errorf("failed to connect to driver %s at %s: %v", driverName, addr, err)}
+	}
+	return rpcClient, addr, nil
+}
+
+// rerunWithDriverFlags adds the driver flags to the command and runs the app again, so that the driver flags get parsed
+// and the command runs action. The plugin address is passed along so that the new run reuses the same driver.
+func rerunWithDriverFlags(ctx *cli.Context, commandName string, flags []cli.Flag, action func(*cli.Context) error, addr string) error {
+	for i, command := range ctx.App.Commands {
+		if command.Name == commandName {
+			cmd := &ctx.App.Commands[i]
+			cmd.SkipFlagParsing = false
+			cmd.Flags = append(cmd.Flags, flags...)
+			cmd.Action = action
+		}
+	}
+	// append plugin addr if it is built-in driver
+	if len(os.Args) > 1 && addr != "" {
+		args := []string{os.Args[0], "--plugin-listen-addr", addr}
+		args = append(args, os.Args[1:len(os.Args)]...)
+		return ctx.App.Run(args)
+	}
+	return ctx.App.Run(os.Args)
+}
+
 func getConfigFromFile() (kubeConfig, error) {
 	configFile := utils.KubeConfigFilePath()
 	config := kubeConfig{}
@@ -49,25 +176,44 @@ func setConfigToFile(config kubeConfig) error {
 	if err != nil {
 		return err
 	}
-	return utils.WriteToFile(data, utils.KubeConfigFilePath())
+	return utils.WritePrivateFile(data, utils.KubeConfigFilePath())
+}
+
+// kubeConfigNames are the names of the cluster, user and context entries of a cluster in kubeconfig
+type kubeConfigNames struct {
+	Cluster string
+	User    string
+	Context string
+}
+
+// kubeConfigEntryNames returns the names of the kubeconfig entries of the cluster named name. The commands writing,
+// selecting and deleting the entries all derive them here so that they find the entries the others wrote
+func kubeConfigEntryNames(name string) kubeConfigNames {
+	return kubeConfigNames{
+		Cluster: name,
+		User:    name,
+		Context: name,
+	}
 }
 
+// deleteConfigByName deletes the kubeconfig entries of the cluster named name
 func deleteConfigByName(config *kubeConfig, name string) {
+	names := kubeConfigEntryNames(name)
 	contexts := []configContext{}
 	for _, context := range config.Contexts {
-		if context.Name != name {
+		if context.Name != names.Context {
 			contexts = append(contexts, context)
 		}
 	}
 	clusters := []configCluster{}
 	for _, cls := range config.Clusters {
-		if cls.Name != name {
+		if cls.Name != names.Cluster {
 			clusters = append(clusters, cls)
 		}
 	}
 	users := []configUser{}
 	for _, user := range config.Users {
-		if user.Name != name {
+		if user.Name != names.User {
 			users = append(users, user)
 		}
 	}
@@ -76,15 +222,55 @@ func deleteConfigByName(config *kubeConfig, name string) {
 	config.Users = users
 }
 
-// getDriverOpts get the flags and value and generate DriverOptions
-func getDriverOpts(ctx *cli.Context) rpcDriver.DriverOptions {
-	driverOptions := rpcDriver.DriverOptions{
+// newDriverOptions returns DriverOptions with all the option maps initialized
+func newDriverOptions() rpcDriver.DriverOptions {
+	return rpcDriver.DriverOptions{
 		BoolOptions:        make(map[string]bool),
 		StringOptions:      make(map[string]string),
 		IntOptions:         make(map[string]int64),
 		StringSliceOptions: make(map[string]*rpcDriver.StringSlice),
 	}
+}
+
+// waitFlags set how the drivers poll a cluster while waiting for it to be ready
+var waitFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  rpcDriver.WaitInitialIntervalOption,
+		Usage: "The first wait between two polls of the cluster status, it doubles after every poll",
+		Value: utils.DefaultPollInitialInterval.String(),
+	},
+	cli.StringFlag{
+		Name:  rpcDriver.WaitMaxIntervalOption,
+		Usage: "The longest wait between two polls of the cluster status",
+		Value: utils.DefaultPollMaxInterval.String(),
+	},
+	cli.StringFlag{
+		Name:  rpcDriver.WaitTimeoutOption,
+		Usage: "How long to wait for the cluster to be ready",
+		Value: utils.DefaultPollTimeout.String(),
+	},
+}
+
+var nodePoolFlag = cli.StringSliceFlag{
+	Name:  "node-pool",
+	Usage: "A node pool as name=NAME,count=COUNT[,machine=TYPE], repeat the flag for multiple pools. Only used by the drivers that support node pools",
+}
+
+// getDriverOpts get the flags and value and generate DriverOptions, SetKeys lists the flags that aren't defaulted
+func getDriverOpts(ctx *cli.Context) (rpcDriver.DriverOptions, error) {
+	driverOptions := newDriverOptions()
 	for _, flag := range ctx.Command.Flags {
+		if ctx.IsSet(flag.GetName()) {
+			driverOptions.SetKeys = append(driverOptions.SetKeys, flag.GetName())
+		}
+		if flag.GetName() == nodePoolFlag.Name {
+			nodePools, err := parseNodePools(ctx.StringSlice(nodePoolFlag.Name))
+			if err != nil {
+				return driverOptions, err
+			}
+			driverOptions.NodePools = nodePools
+			continue
+		}
 		switch flag.(type) {
 		case cli.StringFlag:
 			driverOptions.StringOptions[flag.GetName()] = ctx.String(flag.GetName())
@@ -98,22 +284,130 @@ func getDriverOpts(ctx *cli.Context) rpcDriver.DriverOptions {
 			}
 		}
 	}
-	return driverOptions
+	return driverOptions, nil
 }
 
-func storeConfig(c cluster.Cluster) error {
-	isBasicOn := false
-	if c.Username != "" && c.Password != "" {
-		isBasicOn = true
+// parseNodePools parses the values of the node-pool flag, name and count are required in each of them
+func parseNodePools(values []string) ([]*rpcDriver.NodePool, error) {
+	nodePools := []*rpcDriver.NodePool{}
+	names := map[string]bool{}
+	for _, value := range values {
+		nodePool := &rpcDriver.NodePool{}
+		hasCount := false
+		for _, field := range strings.Split(value, ",") {
+			parts := strings.SplitN(field, "=", 2)
+			if len(parts) != 2 || parts[1] == "" {
+				return nil, newValidationError("invalid node pool %q, %q is not a key=value pair", value, field)
+			}
+			switch parts[0] {
+			case "name":
+				nodePool.Name = parts[1]
+			case "count":
+				count, err := strconv.ParseInt(parts[1], 10, 64)
+				if err != nil || count <= 0 {
+					return nil, newValidationError("invalid node pool %q, count must be a positive number", value)
+				}
+				nodePool.Count = count
+				hasCount = true
+			case "machine":
+				nodePool.MachineType = parts[1]
+			default:
+				return nil, newValidationError("invalid node pool %q, unknown field %s", value, parts[0])
+			}
+		}
+		if nodePool.Name == "" {
+			return nil, newValidationError("invalid node pool %q, name is required", value)
+		}
+		if !hasCount {
+			return nil, newValidationError("invalid node pool %q, count is required", value)
+		}
+		if names[nodePool.Name] {
+			return nil, newValidationError("node pool %s is declared more than once", nodePool.Name)
+		}
+		names[nodePool.Name] = true
+		nodePools = append(nodePools, nodePool)
 	}
-	username, password, token := "", "", ""
-	if isBasicOn {
-		username = c.Username
-		password = c.Password
-	} else {
-		token = c.ServiceAccountToken
+	return nodePools, nil
+}
+
+func validateEndpointType(endpointType string) error {
+	switch endpointType {
+	case "", rpcDriver.EndpointPublic, rpcDriver.EndpointPrivate:
+		return nil
+	}
+	return newValidationError("invalid endpoint type %s, must be %s or %s", endpointType, rpcDriver.EndpointPublic, rpcDriver.EndpointPrivate)
+}
+
+// kubeConfigEndpoint returns the endpoint of the requested type, it falls back to the default endpoint if the driver didn't report that type
+func kubeConfigEndpoint(c cluster.Cluster) string {
+	if c.EndpointType == "" {
+		return c.Endpoint
+	}
+	if endpoint, ok := c.Endpoints[c.EndpointType]; ok && endpoint != "" {
+		return endpoint
+	}
+	logrus.Warnf("Driver %s doesn't report a %s endpoint for cluster %s, using %s instead", c.DriverName, c.EndpointType, c.Name, c.Endpoint)
+	return c.Endpoint
+}
+
+// defaultKubeConfigAPIVersion is the only apiVersion of the kubeconfig format so far
+const defaultKubeConfigAPIVersion = "v1"
+
+// kubeConfigOptions customizes the kubeconfig entry written for a new cluster
+type kubeConfigOptions struct {
+	// apiVersion of the kubeconfig file, empty means defaultKubeConfigAPIVersion
+	apiVersion string
+	// extensions added to the cluster entry
+	extensions []namedExtension
+	// skip leaves the kubeconfig file alone, the file store only writes the cluster config
+	skip bool
+}
+
+// newKubeConfigOptions validates the kubeconfig flags of ctx
+func newKubeConfigOptions(ctx *cli.Context) (kubeConfigOptions, error) {
+	opts := kubeConfigOptions{
+		apiVersion: ctx.String("kubeconfig-api-version"),
+		skip:       ctx.Bool(noKubeConfigFlag.Name),
 	}
+	if opts.apiVersion != "" && opts.apiVersion != defaultKubeConfigAPIVersion {
+		return opts, fmt.Errorf("kubeconfig api version %s is not supported, must be %s", opts.apiVersion, defaultKubeConfigAPIVersion)
+	}
+	extensions, err := parseKubeConfigExtensions(ctx.StringSlice("kubeconfig-extension"))
+	if err != nil {
+		return opts, err
+	}
+	opts.extensions = extensions
+	return opts, nil
+}
+
+// parseKubeConfigExtensions parses NAME=JSON values, the json must be an object
+func parseKubeConfigExtensions(values []string) ([]namedExtension, error) {
+	extensions := []namedExtension{}
+	names := map[string]bool{}
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, newValidationError("invalid kubeconfig extension %q, must be NAME=JSON", value)
+		}
+		extension := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(parts[1]), &extension); err != nil {
+			return nil, newValidationError("invalid kubeconfig extension %s, the value must be a json object: %v", parts[0], err)
+		}
+		if names[parts[0]] {
+			return nil, newValidationError("kubeconfig extension %s is declared more than once", parts[0])
+		}
+		names[parts[0]] = true
+		extensions = append(extensions, namedExtension{
+			Name:      parts[0],
+			Extension: extension,
+		})
+	}
+	return extensions, nil
+}
 
+func storeConfig(c cluster.Cluster, opts kubeConfigOptions) error {
+	kubeConfigLock.Lock()
+	defer kubeConfigLock.Unlock()
 	configFile := utils.KubeConfigFilePath()
 	config := kubeConfig{}
 	if _, err := os.Stat(configFile); err == nil {
@@ -125,27 +419,74 @@ func storeConfig(c cluster.Cluster) error {
 			return err
 		}
 	}
-	config.APIVersion = "v1"
+	addToKubeConfig(&config, c, opts)
+
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	fileToWrite := utils.KubeConfigFilePath()
+	if err := utils.WritePrivateFile(data, fileToWrite); err != nil {
+		return err
+	}
+	// the content isn't logged, it has the credentials of every cluster
+	logrus.Debugf("KubeConfig files is saved to %s", fileToWrite)
+
+	return nil
+}
+
+// writeKubeConfig writes a kubeconfig with only c, as its current context
+func writeKubeConfig(w io.Writer, c cluster.Cluster, opts kubeConfigOptions) error {
+	config := kubeConfig{CurrentContext: kubeConfigEntryNames(c.Name).Context}
+	addToKubeConfig(&config, c, opts)
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// addToKubeConfig adds the cluster, user and context entries of c to config unless they are already there
+func addToKubeConfig(config *kubeConfig, c cluster.Cluster, opts kubeConfigOptions) {
+	isBasicOn := false
+	if c.Username != "" && c.Password != "" {
+		isBasicOn = true
+	}
+	username, password, token := "", "", ""
+	if isBasicOn {
+		username = c.Username
+		password = c.Password
+	} else {
+		token = c.ServiceAccountToken
+	}
+
+	config.APIVersion = defaultKubeConfigAPIVersion
+	if opts.apiVersion != "" {
+		config.APIVersion = opts.apiVersion
+	}
 	config.Kind = "Config"
+	names := kubeConfigEntryNames(c.Name)
 
 	// setup clusters
-	host := c.Endpoint
+	host := kubeConfigEndpoint(c)
 	if !strings.HasPrefix(host, "https://") {
 		host = fmt.Sprintf("https://%s", host)
 	}
 	cluster := configCluster{
 		Cluster: dataCluster{
 			CertificateAuthorityData: string(c.RootCACert),
-			Server: host,
+			Server:                   host,
+			Extensions:               opts.extensions,
 		},
-		Name: c.Name,
+		Name: names.Cluster,
 	}
 	if config.Clusters == nil || len(config.Clusters) == 0 {
 		config.Clusters = []configCluster{cluster}
 	} else {
 		exist := false
 		for _, cluster := range config.Clusters {
-			if cluster.Name == c.Name {
+			if cluster.Name == names.Cluster {
 				exist = true
 				break
 			}
@@ -162,14 +503,14 @@ func storeConfig(c cluster.Cluster) error {
 			Password: password,
 			Token:    token,
 		},
-		Name: c.Name,
+		Name: names.User,
 	}
 	if config.Users == nil || len(config.Users) == 0 {
 		config.Users = []configUser{user}
 	} else {
 		exist := false
 		for _, user := range config.Users {
-			if user.Name == c.Name {
+			if user.Name == names.User {
 				exist = true
 				break
 			}
@@ -182,17 +523,17 @@ func storeConfig(c cluster.Cluster) error {
 	// setup context
 	context := configContext{
 		Context: contextData{
-			Cluster: c.Name,
-			User:    c.Name,
+			Cluster: names.Cluster,
+			User:    names.User,
 		},
-		Name: c.Name,
+		Name: names.Context,
 	}
 	if config.Contexts == nil || len(config.Contexts) == 0 {
 		config.Contexts = []configContext{context}
 	} else {
 		exist := false
 		for _, context := range config.Contexts {
-			if context.Name == c.Name {
+			if context.Name == names.Context {
 				exist = true
 				break
 			}
@@ -201,17 +542,4 @@ func storeConfig(c cluster.Cluster) error {
 			config.Contexts = append(config.Contexts, context)
 		}
 	}
-
-	data, err := yaml.Marshal(config)
-	if err != nil {
-		return err
-	}
-	fileToWrite := utils.KubeConfigFilePath()
-	if err := utils.WriteToFile(data, fileToWrite); err != nil {
-		return err
-	}
-	logrus.Debugf("KubeConfig files is saved to %s", fileToWrite)
-	logrus.Debug("Kubeconfig file\n" + string(data))
-
-	return nil
 }
@@ -0,0 +1,374 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/rancher/kontainer-engine/driver/mock"
+	"github.com/rancher/kontainer-engine/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	"gopkg.in/check.v1"
+	yaml "gopkg.in/yaml.v2"
+)
+
+type KubeConfigTestSuite struct {
+	tempHomeSuite
+}
+
+var _ = check.Suite(&KubeConfigTestSuite{})
+
+func readKubeConfigServer(c *check.C, name string) string {
+	data, err := ioutil.ReadFile(utils.KubeConfigFilePath())
+	c.Assert(err, check.IsNil)
+	config := kubeConfig{}
+	c.Assert(yaml.Unmarshal(data, &config), check.IsNil)
+	for _, cls := range config.Clusters {
+		if cls.Name == name {
+			return cls.Cluster.Server
+		}
+	}
+	c.Fatalf("cluster %s not found in kubeconfig", name)
+	return ""
+}
+
+func (s *KubeConfigTestSuite) TestStoreConfigEndpointType(c *check.C) {
+	endpoints := map[string]string{
+		rpcDriver.EndpointPublic:  "1.1.1.1",
+		rpcDriver.EndpointPrivate: "10.0.0.1",
+	}
+	for endpointType, expected := range map[string]string{
+		"":                        "https://2.2.2.2",
+		rpcDriver.EndpointPublic:  "https://1.1.1.1",
+		rpcDriver.EndpointPrivate: "https://10.0.0.1",
+	} {
+		name := "cluster-" + endpointType
+		cls := cluster.Cluster{
+			Name:         name,
+			Endpoint:     "2.2.2.2",
+			Endpoints:    endpoints,
+			EndpointType: endpointType,
+		}
+		c.Assert(storeConfig(cls, kubeConfigOptions{}), check.IsNil)
+		c.Assert(readKubeConfigServer(c, name), check.Equals, expected, check.Commentf("endpoint type %q", endpointType))
+	}
+}
+
+func (s *KubeConfigTestSuite) TestStoreConfigMissingEndpointType(c *check.C) {
+	cls := cluster.Cluster{
+		Name:         "foo",
+		Endpoint:     "1.1.1.1",
+		Endpoints:    map[string]string{rpcDriver.EndpointPublic: "1.1.1.1"},
+		EndpointType: rpcDriver.EndpointPrivate,
+	}
+	c.Assert(storeConfig(cls, kubeConfigOptions{}), check.IsNil)
+	c.Assert(readKubeConfigServer(c, "foo"), check.Equals, "https://1.1.1.1")
+}
+
+func (s *KubeConfigTestSuite) TestValidateEndpointType(c *check.C) {
+	c.Assert(validateEndpointType(""), check.IsNil)
+	c.Assert(validateEndpointType(rpcDriver.EndpointPrivate), check.IsNil)
+	c.Assert(validateEndpointType("internal"), check.ErrorMatches, "invalid endpoint type internal.*")
+}
+
+type CloudCABundleTestSuite struct {
+	oldEnv string
+}
+
+var _ = check.Suite(&CloudCABundleTestSuite{})
+
+func (s *CloudCABundleTestSuite) SetUpTest(c *check.C) {
+	s.oldEnv = os.Getenv(sslCertFileEnv)
+}
+
+func (s *CloudCABundleTestSuite) TearDownTest(c *check.C) {
+	cloudCABundle = ""
+	os.Setenv(sslCertFileEnv, s.oldEnv)
+}
+
+// writeCABundle writes a self-signed CA certificate to a temp file
+func writeCABundle(c *check.C) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.Assert(err, check.IsNil)
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	c.Assert(err, check.IsNil)
+	path := filepath.Join(c.MkDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	c.Assert(ioutil.WriteFile(path, data, 0600), check.IsNil)
+	return path
+}
+
+func (s *CloudCABundleTestSuite) TestDriverGetsCABundle(c *check.C) {
+	path := writeCABundle(c)
+	c.Assert(SetCloudCABundle(path), check.IsNil)
+
+	_, _, err := runRPCDriver("mock")
+	c.Assert(err, check.IsNil)
+	c.Assert(os.Getenv(sslCertFileEnv), check.Equals, path)
+}
+
+func (s *CloudCABundleTestSuite) TestInvalidCABundle(c *check.C) {
+	path := filepath.Join(c.MkDir(), "ca.pem")
+	c.Assert(ioutil.WriteFile(path, []byte("not a certificate"), 0600), check.IsNil)
+	c.Assert(SetCloudCABundle(path), check.ErrorMatches, "cloud CA bundle .* doesn't contain any PEM certificate")
+
+	c.Assert(SetCloudCABundle(filepath.Join(c.MkDir(), "missing.pem")), check.ErrorMatches, "failed to read cloud CA bundle: .*")
+	c.Assert(cloudCABundle, check.Equals, "")
+}
+
+type NodePoolTestSuite struct {
+}
+
+var _ = check.Suite(&NodePoolTestSuite{})
+
+func (s *NodePoolTestSuite) TestParseSingleNodePool(c *check.C) {
+	nodePools, err := parseNodePools([]string{"name=default,count=3,machine=n1-standard-2"})
+	c.Assert(err, check.IsNil)
+	c.Assert(nodePools, check.DeepEquals, []*rpcDriver.NodePool{
+		{Name: "default", Count: 3, MachineType: "n1-standard-2"},
+	})
+}
+
+func (s *NodePoolTestSuite) TestParseMultipleNodePools(c *check.C) {
+	nodePools, err := parseNodePools([]string{"name=small,count=1", "count=2,name=large,machine=n1-highmem-8"})
+	c.Assert(err, check.IsNil)
+	c.Assert(nodePools, check.DeepEquals, []*rpcDriver.NodePool{
+		{Name: "small", Count: 1},
+		{Name: "large", Count: 2, MachineType: "n1-highmem-8"},
+	})
+
+	nodePools, err = parseNodePools(nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(nodePools, check.HasLen, 0)
+}
+
+func (s *NodePoolTestSuite) TestParseMalformedNodePools(c *check.C) {
+	for value, expected := range map[string]string{
+		"count=3":                   `invalid node pool "count=3", name is required`,
+		"name=foo":                  `invalid node pool "name=foo", count is required`,
+		"name=foo,count=zero":       `invalid node pool "name=foo,count=zero", count must be a positive number`,
+		"name=foo,count=0":          `invalid node pool "name=foo,count=0", count must be a positive number`,
+		"name=foo,count=1,disk=10":  `invalid node pool "name=foo,count=1,disk=10", unknown field disk`,
+		"name=foo,count":            `invalid node pool "name=foo,count", "count" is not a key=value pair`,
+		"name=foo,count=1,machine=": `invalid node pool "name=foo,count=1,machine=", "machine=" is not a key=value pair`,
+	} {
+		_, err := parseNodePools([]string{value})
+		c.Assert(err, check.ErrorMatches, expected)
+	}
+
+	_, err := parseNodePools([]string{"name=foo,count=1", "name=foo,count=2"})
+	c.Assert(err, check.ErrorMatches, "node pool foo is declared more than once")
+}
+
+// goldenCluster is the cluster written by the kubeconfig golden tests
+var goldenCluster = cluster.Cluster{
+	Name:                "foo",
+	Endpoint:            "1.1.1.1",
+	RootCACert:          "Y2E=",
+	ServiceAccountToken: "token",
+}
+
+func assertKubeConfigGolden(c *check.C, golden string) {
+	expected, err := ioutil.ReadFile(filepath.Join("testdata", golden))
+	c.Assert(err, check.IsNil)
+	data, err := ioutil.ReadFile(utils.KubeConfigFilePath())
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, string(expected))
+}
+
+func (s *KubeConfigTestSuite) TestStoreConfigDefaultGolden(c *check.C) {
+	c.Assert(storeConfig(goldenCluster, kubeConfigOptions{}), check.IsNil)
+	assertKubeConfigGolden(c, "kubeconfig-default.yaml")
+}
+
+func (s *KubeConfigTestSuite) TestStoreConfigExtensionsGolden(c *check.C) {
+	extensions, err := parseKubeConfigExtensions([]string{
+		`kontainer-engine={"uid":"8d0c3d3a","driver":"mock"}`,
+		`labels={"env":"test"}`,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(storeConfig(goldenCluster, kubeConfigOptions{
+		apiVersion: defaultKubeConfigAPIVersion,
+		extensions: extensions,
+	}), check.IsNil)
+	assertKubeConfigGolden(c, "kubeconfig-extensions.yaml")
+}
+
+func (s *KubeConfigTestSuite) TestStoreConfigKeepsPreferences(c *check.C) {
+	existing := "apiVersion: v1\nkind: Config\npreferences:\n  colors: true\n"
+	c.Assert(utils.WriteToFile([]byte(existing), utils.KubeConfigFilePath()), check.IsNil)
+	c.Assert(storeConfig(goldenCluster, kubeConfigOptions{}), check.IsNil)
+
+	data, err := ioutil.ReadFile(utils.KubeConfigFilePath())
+	c.Assert(err, check.IsNil)
+	config := kubeConfig{}
+	c.Assert(yaml.Unmarshal(data, &config), check.IsNil)
+	c.Assert(config.Preferences["colors"], check.Equals, true)
+}
+
+func (s *KubeConfigTestSuite) TestStoreConfigDebugLog(c *check.C) {
+	logs := bytes.Buffer{}
+	logrus.SetOutput(&logs)
+	defer logrus.SetOutput(os.Stderr)
+	level := logrus.GetLevel()
+	logrus.SetLevel(logrus.DebugLevel)
+	defer logrus.SetLevel(level)
+
+	cls := goldenCluster
+	cls.ServiceAccountToken = "debug-log-token"
+	c.Assert(storeConfig(cls, kubeConfigOptions{}), check.IsNil)
+	c.Assert(logs.String(), check.Matches, `(?s).*KubeConfig files is saved to .*`)
+	c.Assert(strings.Contains(logs.String(), cls.ServiceAccountToken), check.Equals, false)
+}
+
+func (s *KubeConfigTestSuite) TestKubeConfigIsPrivate(c *check.C) {
+	// the kubeconfig holds the credentials of the clusters, only the current user can read it
+	c.Assert(storeConfig(goldenCluster, kubeConfigOptions{}), check.IsNil)
+	info, err := os.Stat(utils.KubeConfigFilePath())
+	c.Assert(err, check.IsNil)
+	c.Assert(info.Mode().Perm(), check.Equals, os.FileMode(0600))
+
+	c.Assert(os.Remove(utils.KubeConfigFilePath()), check.IsNil)
+	c.Assert(setConfigToFile(kubeConfig{}), check.IsNil)
+	info, err = os.Stat(utils.KubeConfigFilePath())
+	c.Assert(err, check.IsNil)
+	c.Assert(info.Mode().Perm(), check.Equals, os.FileMode(0600))
+}
+
+func (s *KubeConfigTestSuite) TestParseInvalidKubeConfigExtensions(c *check.C) {
+	for value, expected := range map[string]string{
+		`{"uid":"1"}`:             `invalid kubeconfig extension "{\\"uid\\":\\"1\\"}", must be NAME=JSON`,
+		`=x`:                      `invalid kubeconfig extension "=x", must be NAME=JSON`,
+		`kontainer-engine=1234`:   `invalid kubeconfig extension kontainer-engine, the value must be a json object: .*`,
+		`kontainer-engine={"uid"`: `invalid kubeconfig extension kontainer-engine, the value must be a json object: .*`,
+	} {
+		_, err := parseKubeConfigExtensions([]string{value})
+		c.Assert(err, check.ErrorMatches, expected)
+	}
+	_, err := parseKubeConfigExtensions([]string{`a={}`, `a={}`})
+	c.Assert(err, check.ErrorMatches, "kubeconfig extension a is declared more than once")
+}
+
+type DriverAddrTestSuite struct {
+	tempHomeSuite
+	oldArgs []string
+}
+
+var _ = check.Suite(&DriverAddrTestSuite{})
+
+func (s *DriverAddrTestSuite) SetUpTest(c *check.C) {
+	s.tempHomeSuite.SetUpTest(c)
+	s.oldArgs = os.Args
+}
+
+func (s *DriverAddrTestSuite) TearDownTest(c *check.C) {
+	os.Args = s.oldArgs
+	c.Assert(SetDriverAddr(""), check.IsNil)
+	s.tempHomeSuite.TearDownTest(c)
+}
+
+// freeAddr returns a local address no server listens on
+func freeAddr(c *check.C) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, check.IsNil)
+	addr := listener.Addr().String()
+	c.Assert(listener.Close(), check.IsNil)
+	return addr
+}
+
+func (s *DriverAddrTestSuite) TestConnectToRunningDriver(c *check.C) {
+	addr := freeAddr(c)
+	addrChan := make(chan string)
+	go rpcDriver.NewServer(mock.NewDriver(), addrChan).ServeAt(addr)
+	c.Assert(<-addrChan, check.Equals, addr)
+
+	// the commands use the driver listening on addr instead of starting one
+	c.Assert(SetDriverAddr(addr), check.IsNil)
+	_, runAddr, err := runRPCDriver("mock")
+	c.Assert(err, check.IsNil)
+	c.Assert(runAddr, check.Equals, addr)
+
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "external"}
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+	cls, err := cliPersistStore{}.Get("external")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.Endpoint, check.Equals, "external.mock.local")
+}
+
+func (s *DriverAddrTestSuite) TestUnreachableDriver(c *check.C) {
+	addr := freeAddr(c)
+	c.Assert(SetDriverAddr(addr), check.IsNil)
+	_, _, err := runRPCDriver("mock")
+	c.Assert(err, check.ErrorMatches, "failed to connect to driver mock at "+addr+": .*")
+	c.Assert(ExitCode(err), check.Equals, ExitDriverFailure)
+
+	err = SetDriverAddr("no-port")
+	c.Assert(err, check.ErrorMatches, `invalid driver address "no-port", use host:port: .*`)
+	c.Assert(ExitCode(err), check.Equals, ExitUsage)
+}
+
+type OperationPolicyTestSuite struct{}
+
+var _ = check.Suite(&OperationPolicyTestSuite{})
+
+// resolvePolicies runs an app with the operation flags and args and returns the policies it resolves
+func resolvePolicies(c *check.C, args ...string) (rpcDriver.Policies, error) {
+	app := cli.NewApp()
+	app.Flags = OperationFlags()
+	var policies rpcDriver.Policies
+	app.Action = func(ctx *cli.Context) error {
+		if err := SetOperationPolicies(ctx); err != nil {
+			return err
+		}
+		policies = rpcDriver.PoliciesFrom(driverContext)
+		return nil
+	}
+	err := app.Run(append([]string{"kontainer-engine"}, args...))
+	driverContext = context.Background()
+	return policies, err
+}
+
+func (s *OperationPolicyTestSuite) TestOperationOverrides(c *check.C) {
+	policies, err := resolvePolicies(c, "--operation-timeout", "20m", "--operation-retries", "2",
+		"--create-timeout", "1h", "--remove-retries", "5", "--update-retries", "0", "--status-timeout", "10s")
+	c.Assert(err, check.IsNil)
+	c.Assert(policies, check.DeepEquals, rpcDriver.Policies{
+		rpcDriver.OperationCreate: {Timeout: time.Hour, Retries: 2},
+		rpcDriver.OperationUpdate: {Timeout: 20 * time.Minute, Retries: 0},
+		rpcDriver.OperationRemove: {Timeout: 20 * time.Minute, Retries: 5},
+		rpcDriver.OperationStatus: {Timeout: 10 * time.Second, Retries: 2},
+	})
+	// the operations without any flag keep the timeouts of the driver client
+	c.Assert(policies.For(rpcDriver.OperationUpdate, 10*time.Minute).Timeout, check.Equals, 20*time.Minute)
+
+	policies, err = resolvePolicies(c)
+	c.Assert(err, check.IsNil)
+	c.Assert(policies.For(rpcDriver.OperationRemove, 5*time.Minute), check.DeepEquals, rpcDriver.OperationPolicy{Timeout: 5 * time.Minute})
+
+	_, err = resolvePolicies(c, "--remove-retries", "-1")
+	c.Assert(err, check.ErrorMatches, "the timeout and the retries of the remove operation can't be negative")
+	c.Assert(ExitCode(err), check.Equals, ExitUsage)
+}
@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	"github.com/rancher/kontainer-engine/utils"
+	"github.com/urfave/cli"
+)
+
+const (
+	configValid    = "Valid"
+	configBroken   = "Broken"
+	configOutdated = "Outdated"
+	configFixed    = "Fixed"
+)
+
+// knownStatuses are the statuses a stored cluster can have
+var knownStatuses = []string{
+	cluster.PreCreating,
+	cluster.Creating,
+	cluster.PostCheck,
+	cluster.Running,
+	cluster.Error,
+	cluster.Updating,
+	cluster.Removed,
+	cluster.Interrupted,
+	cluster.Restoring,
+	cluster.Deleting,
+}
+
+// ValidateStoreCommand defines the validate-store command
+func ValidateStoreCommand() cli.Command {
+	return cli.Command{
+		Name:   "validate-store",
+		Usage:  "Check the config of every cluster of the file store and report the broken ones",
+		Action: validateStore,
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "fix",
+				Usage: "Upgrade the configs written by an older version to the current schema instead of reporting them",
+			},
+		},
+	}
+}
+
+type validateResult struct {
+	Name   string
+	Status string
+	Error  string
+}
+
+func validateStore(ctx *cli.Context) error {
+	if selectedStore != nil {
+		return newUsageError("validate-store checks the file store, not the %s store", ctx.GlobalString("store"))
+	}
+	fix := ctx.Bool("fix")
+	if fix && readOnly {
+		return newUsageError("--fix can't be used with --read-only")
+	}
+	results, err := validateClusterConfigs(filepath.Join(utils.HomeDir(), "clusters"), fix)
+	if err != nil {
+		return err
+	}
+
+	writer := utils.NewTableWriter([][]string{
+		{"NAME", "Name"},
+		{"STATUS", "Status"},
+		{"ERROR", "Error"},
+	}, ctx)
+	failed := 0
+	for _, result := range results {
+		writer.Write(result)
+		if result.Status == configBroken || result.Status == configOutdated {
+			failed++
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return newValidationError("%d of %d cluster configs are invalid", failed, len(results))
+	}
+	return nil
+}
+
+// validateClusterConfigs checks the config of every cluster directory of dir in the order of their names. The configs
+// of an older schema are reported outdated, or upgraded with fix. A broken config doesn't stop the others.
+func validateClusterConfigs(dir string, fix bool) ([]validateResult, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	results := []validateResult{}
+	for _, file := range files {
+		// the hidden directories are the temp directories of the writes
+		if !file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		result := validateResult{Name: file.Name(), Status: configValid}
+		configPath := filepath.Join(dir, file.Name(), defaultConfigName)
+		outdated, err := validateClusterConfig(file.Name(), configPath)
+		switch {
+		case err != nil:
+			result.Status = configBroken
+			result.Error = err.Error()
+		case outdated && fix:
+			result.Status = configFixed
+			if err := fixClusterConfig(configPath); err != nil {
+				result.Status = configBroken
+				result.Error = fmt.Sprintf("failed to upgrade the config: %v", err)
+			}
+		case outdated:
+			result.Status = configOutdated
+			result.Error = fmt.Sprintf("the config has an older schema, run with --fix to upgrade it to version %d", cluster.CurrentSchemaVersion)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// validateClusterConfig loads the config of the cluster of the directory called name and checks its fields. It
+// returns true if the config has to be migrated to the current schema.
+func validateClusterConfig(name, configPath string) (bool, error) {
+	data, err := ioutil.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return false, fmt.Errorf("%s is missing", defaultConfigName)
+	} else if err != nil {
+		return false, err
+	}
+	raw := map[string]interface{}{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return false, fmt.Errorf("invalid json: %v", err)
+	}
+	if version, ok := raw["schemaVersion"].(float64); ok && int(version) > cluster.CurrentSchemaVersion {
+		return false, fmt.Errorf("schema version %d is newer than the supported version %d", int(version), cluster.CurrentSchemaVersion)
+	}
+	cls, migrated, err := cluster.Migrate(data)
+	if err != nil {
+		return false, fmt.Errorf("invalid config: %v", err)
+	}
+	switch {
+	case cls.Name == "":
+		return false, fmt.Errorf("name is missing")
+	case cls.Name != name:
+		return false, fmt.Errorf("name %s doesn't match the directory %s", cls.Name, name)
+	case cls.DriverName == "":
+		return false, fmt.Errorf("driverName is missing")
+	case !isKnownStatus(cls.Status):
+		return false, fmt.Errorf("unknown status %q", cls.Status)
+	}
+	for _, certificate := range []struct{ field, value string }{
+		{"rootCACert", cls.RootCACert},
+		{"clientCertificate", cls.ClientCertificate},
+		{"clientKey", cls.ClientKey},
+	} {
+		if _, err := base64.StdEncoding.DecodeString(certificate.value); err != nil {
+			return false, fmt.Errorf("%s isn't base64: %v", certificate.field, err)
+		}
+	}
+	return migrated, nil
+}
+
+func isKnownStatus(status string) bool {
+	// a config written before the first status is stored has none
+	if status == "" {
+		return true
+	}
+	for _, known := range knownStatuses {
+		if status == known {
+			return true
+		}
+	}
+	return false
+}
+
+// fixClusterConfig rewrites the config at configPath migrated to the current schema
+func fixClusterConfig(configPath string) error {
+	data, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+	cls, _, err := cluster.Migrate(data)
+	if err != nil {
+		return err
+	}
+	if data, err = json.Marshal(cls); err != nil {
+		return err
+	}
+	return utils.WritePrivateFile(data, configPath)
+}
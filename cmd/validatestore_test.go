@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	"github.com/rancher/kontainer-engine/utils"
+	"gopkg.in/check.v1"
+)
+
+type ValidateStoreTestSuite struct {
+	tempHomeSuite
+}
+
+var _ = check.Suite(&ValidateStoreTestSuite{})
+
+func (s *ValidateStoreTestSuite) TestValidateMixedConfigs(c *check.C) {
+	c.Assert(cliPersistStore{}.Store(migratedCluster("valid", "1.1.1.1")), check.IsNil)
+	writeClusterConfig(c, "old", `{"driverName":"gke","name":"old","status":"Running"}`)
+	writeClusterConfig(c, "corrupt", `{"driverName":`)
+	writeClusterConfig(c, "moved", `{"schemaVersion":1,"driverName":"gke","name":"other","status":"Running"}`)
+	writeClusterConfig(c, "future", `{"schemaVersion":99,"driverName":"gke","name":"future"}`)
+	writeClusterConfig(c, "status", `{"schemaVersion":1,"driverName":"gke","name":"status","status":"Sleeping"}`)
+	writeClusterConfig(c, "cert", `{"schemaVersion":1,"driverName":"gke","name":"cert","rootCACert":"not base64!"}`)
+	c.Assert(os.MkdirAll(filepath.Join(utils.HomeDir(), "clusters", "empty"), 0700), check.IsNil)
+	c.Assert(os.MkdirAll(filepath.Join(utils.HomeDir(), "clusters", ".valid.tmp123"), 0700), check.IsNil)
+
+	dir := filepath.Join(utils.HomeDir(), "clusters")
+	results, err := validateClusterConfigs(dir, false)
+	c.Assert(err, check.IsNil)
+	c.Assert(results, check.HasLen, 8)
+	c.Assert(results[0], check.DeepEquals, validateResult{Name: "cert", Status: configBroken, Error: "rootCACert isn't base64: illegal base64 data at input byte 3"})
+	c.Assert(results[1].Status, check.Equals, configBroken)
+	c.Assert(results[1].Error, check.Matches, "invalid json: .*")
+	c.Assert(results[2], check.DeepEquals, validateResult{Name: "empty", Status: configBroken, Error: "config.json is missing"})
+	c.Assert(results[3], check.DeepEquals, validateResult{Name: "future", Status: configBroken, Error: "schema version 99 is newer than the supported version 1"})
+	c.Assert(results[4], check.DeepEquals, validateResult{Name: "moved", Status: configBroken, Error: "name other doesn't match the directory moved"})
+	c.Assert(results[5], check.DeepEquals, validateResult{Name: "old", Status: configOutdated, Error: "the config has an older schema, run with --fix to upgrade it to version 1"})
+	c.Assert(results[6], check.DeepEquals, validateResult{Name: "status", Status: configBroken, Error: `unknown status "Sleeping"`})
+	c.Assert(results[7], check.DeepEquals, validateResult{Name: "valid", Status: configValid})
+
+	// the outdated configs are upgraded by --fix, the broken ones are left as they are
+	results, err = validateClusterConfigs(dir, true)
+	c.Assert(err, check.IsNil)
+	c.Assert(results[5], check.DeepEquals, validateResult{Name: "old", Status: configFixed})
+	results, err = validateClusterConfigs(dir, false)
+	c.Assert(err, check.IsNil)
+	c.Assert(results[5], check.DeepEquals, validateResult{Name: "old", Status: configValid})
+	cls, err := cliPersistStore{}.Get("old")
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.SchemaVersion, check.Equals, cluster.CurrentSchemaVersion)
+	c.Assert(cls.Status, check.Equals, cluster.Running)
+}
+
+func (s *ValidateStoreTestSuite) TestValidateStoreCommand(c *check.C) {
+	app := newTestApp()
+	app.Commands = append(app.Commands, ValidateStoreCommand())
+	c.Assert(app.Run([]string{"kontainer-engine", "validate-store"}), check.IsNil)
+
+	c.Assert(cliPersistStore{}.Store(migratedCluster("valid", "1.1.1.1")), check.IsNil)
+	writeClusterConfig(c, "corrupt", `{"driverName":`)
+	err := app.Run([]string{"kontainer-engine", "validate-store", "--fix"})
+	c.Assert(err, check.ErrorMatches, "1 of 2 cluster configs are invalid")
+	c.Assert(ExitCode(err), check.Equals, ExitValidation)
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/urfave/cli"
+)
+
+const (
+	// vaultProvider is the --credential-provider fetching the credential from HashiCorp Vault
+	vaultProvider = "vault"
+	// vaultTokenEnv is the environment variable with the Vault token, it isn't a flag so that it is never stored with
+	// the options of the clusters
+	vaultTokenEnv = "VAULT_TOKEN"
+	vaultTimeout  = 30 * time.Second
+)
+
+var (
+	vaultAddrFlag = cli.StringFlag{
+		Name:   "vault-addr",
+		Usage:  "The address of the Vault server of --credential-provider vault, e.g. https://vault.example.com:8200. The token is read from " + vaultTokenEnv,
+		EnvVar: "VAULT_ADDR",
+	}
+	vaultPathFlag = cli.StringFlag{
+		Name:  "vault-path",
+		Usage: "The path of the Vault secret holding the credential, e.g. secret/data/gke for a KV version 2 engine",
+	}
+	vaultKeyFlag = cli.StringFlag{
+		Name:  "vault-key",
+		Usage: "The key of the credential in the Vault secret",
+		Value: "credential",
+	}
+	// vaultCache holds the credentials fetched from Vault by address, path and key. Create runs again to parse the
+	// driver flags and Vault is only called once, the credentials are never written to disk.
+	vaultCache     = map[string]string{}
+	vaultCacheLock sync.Mutex
+)
+
+// vaultCredentialProvider reads the credential from the key of a KV secret of Vault
+type vaultCredentialProvider struct {
+	addr  string
+	token string
+	path  string
+	key   string
+}
+
+func newVaultCredentialProvider(driverOptions rpcDriver.DriverOptions) (credentialProvider, error) {
+	provider := vaultCredentialProvider{
+		addr:  strings.TrimRight(driverOptions.StringOptions[vaultAddrFlag.Name], "/"),
+		token: os.Getenv(vaultTokenEnv),
+		path:  strings.Trim(driverOptions.StringOptions[vaultPathFlag.Name], "/"),
+		key:   driverOptions.StringOptions[vaultKeyFlag.Name],
+	}
+	switch {
+	case provider.addr == "":
+		return nil, newUsageError("--%s or VAULT_ADDR is required with --%s %s", vaultAddrFlag.Name, credentialProviderFlag.Name, vaultProvider)
+	case provider.path == "":
+		return nil, newUsageError("--%s is required with --%s %s", vaultPathFlag.Name, credentialProviderFlag.Name, vaultProvider)
+	case provider.key == "":
+		return nil, newUsageError("--%s can't be empty", vaultKeyFlag.Name)
+	case provider.token == "":
+		return nil, newUsageError("%s is required with --%s %s", vaultTokenEnv, credentialProviderFlag.Name, vaultProvider)
+	}
+	return provider, nil
+}
+
+type vaultSecret struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []string               `json:"errors"`
+}
+
+func (v vaultCredentialProvider) credential() (string, error) {
+	cacheKey := v.addr + "/" + v.path + "#" + v.key
+	vaultCacheLock.Lock()
+	credential, ok := vaultCache[cacheKey]
+	vaultCacheLock.Unlock()
+	if ok {
+		return credential, nil
+	}
+	req, err := http.NewRequest(http.MethodGet, v.addr+"/v1/"+v.path, nil)
+	if err != nil {
+		return "", newUsageError("invalid vault address %s: %v", v.addr, err)
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	resp, err := (&http.Client{Timeout: vaultTimeout}).Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to read vault secret %s: %v", v.path, err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read vault secret %s: %v", v.path, err)
+	}
+	secret := vaultSecret{}
+	// the error responses carry the errors in the same shape
+	decodeErr := json.Unmarshal(data, &secret)
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return "", newNotFoundError("vault secret %s not found", v.path)
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("vault refused to read secret %s: %s %s", v.path, resp.Status, strings.Join(secret.Errors, ", "))
+	case decodeErr != nil:
+		return "", fmt.Errorf("failed to parse vault secret %s: %v", v.path, decodeErr)
+	}
+
+	fields := secret.Data
+	// a KV version 2 engine nests the fields of the secret under data next to its metadata
+	if nested, ok := fields["data"].(map[string]interface{}); ok {
+		if _, ok := fields["metadata"]; ok {
+			fields = nested
+		}
+	}
+	value, ok := fields[v.key]
+	if !ok {
+		return "", newNotFoundError("vault secret %s has no %s key", v.path, v.key)
+	}
+	credential, ok = value.(string)
+	if !ok || credential == "" {
+		return "", newValidationError("key %s of vault secret %s isn't a non-empty string", v.key, v.path)
+	}
+	vaultCacheLock.Lock()
+	vaultCache[cacheKey] = credential
+	vaultCacheLock.Unlock()
+	return credential, nil
+}
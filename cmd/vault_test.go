@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"gopkg.in/check.v1"
+)
+
+type VaultTestSuite struct {
+	tempHomeSuite
+	server   *httptest.Server
+	requests int
+	oldArgs  []string
+	oldToken string
+}
+
+var _ = check.Suite(&VaultTestSuite{})
+
+func (s *VaultTestSuite) SetUpTest(c *check.C) {
+	s.tempHomeSuite.SetUpTest(c)
+	s.oldArgs = os.Args
+	s.oldToken = os.Getenv(vaultTokenEnv)
+	os.Setenv(vaultTokenEnv, "root-token")
+	vaultCache = map[string]string{}
+	s.requests = 0
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests++
+		if r.Header.Get("X-Vault-Token") != "root-token" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/mock":
+			w.Write([]byte(`{"data":{"credential":"kv1-secret","count":1}}`))
+		case "/v1/secret/data/mock":
+			w.Write([]byte(`{"data":{"data":{"credential":"kv2-secret"},"metadata":{"version":3}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+}
+
+func (s *VaultTestSuite) TearDownTest(c *check.C) {
+	s.server.Close()
+	os.Setenv(vaultTokenEnv, s.oldToken)
+	vaultCache = map[string]string{}
+	os.Args = s.oldArgs
+	s.tempHomeSuite.TearDownTest(c)
+}
+
+func (s *VaultTestSuite) resolve(c *check.C, args ...string) (rpcDriver.DriverOptions, error) {
+	args = append([]string{"--credential-provider", "vault", "--vault-addr", s.server.URL + "/"}, args...)
+	return resolveDriverOptions(append(args, "foo"), mockCreateFlags(c))
+}
+
+func (s *VaultTestSuite) TestResolveCredential(c *check.C) {
+	driverOptions, err := s.resolve(c, "--vault-path", "secret/mock")
+	c.Assert(err, check.IsNil)
+	c.Assert(driverOptions.StringOptions[rpcDriver.CredentialOption], check.Equals, "kv1-secret")
+
+	// the fields of a KV version 2 secret are nested under data
+	driverOptions, err = s.resolve(c, "--vault-path", "/secret/data/mock")
+	c.Assert(err, check.IsNil)
+	c.Assert(driverOptions.StringOptions[rpcDriver.CredentialOption], check.Equals, "kv2-secret")
+
+	// the fetched secrets are cached in memory
+	_, err = s.resolve(c, "--vault-path", "secret/mock")
+	c.Assert(err, check.IsNil)
+	c.Assert(s.requests, check.Equals, 2)
+}
+
+func (s *VaultTestSuite) TestVaultErrors(c *check.C) {
+	_, err := s.resolve(c, "--vault-path", "secret/missing")
+	c.Assert(err, check.ErrorMatches, "vault secret secret/missing not found")
+	c.Assert(ExitCode(err), check.Equals, ExitNotFound)
+	_, err = s.resolve(c, "--vault-path", "secret/mock", "--vault-key", "password")
+	c.Assert(err, check.ErrorMatches, "vault secret secret/mock has no password key")
+	_, err = s.resolve(c, "--vault-path", "secret/mock", "--vault-key", "count")
+	c.Assert(err, check.ErrorMatches, "key count of vault secret secret/mock isn't a non-empty string")
+	c.Assert(ExitCode(err), check.Equals, ExitValidation)
+
+	os.Setenv(vaultTokenEnv, "wrong")
+	_, err = s.resolve(c, "--vault-path", "secret/mock")
+	c.Assert(err, check.ErrorMatches, "vault refused to read secret secret/mock: 403 Forbidden permission denied")
+	os.Setenv(vaultTokenEnv, "")
+	_, err = s.resolve(c, "--vault-path", "secret/mock")
+	c.Assert(err, check.ErrorMatches, "VAULT_TOKEN is required with --credential-provider vault")
+	c.Assert(ExitCode(err), check.Equals, ExitUsage)
+}
+
+func (s *VaultTestSuite) TestInvalidProviderOptions(c *check.C) {
+	_, err := s.resolve(c)
+	c.Assert(err, check.ErrorMatches, "--vault-path is required with --credential-provider vault")
+	_, err = s.resolve(c, "--vault-path", "secret/mock", "--credential", "given")
+	c.Assert(err, check.ErrorMatches, "--credential-provider can't be used with --credential or --credential-profile")
+	_, err = resolveDriverOptions([]string{"--credential-provider", "keychain", "foo"}, mockCreateFlags(c))
+	c.Assert(err, check.ErrorMatches, "unknown credential provider keychain, use vault")
+	c.Assert(ExitCode(err), check.Equals, ExitUsage)
+}
+
+func (s *VaultTestSuite) TestCreateDoesNotStoreCredential(c *check.C) {
+	os.Args = []string{"kontainer-engine", "create", "--driver", "mock", "--credential-provider", "vault",
+		"--vault-addr", s.server.URL, "--vault-path", "secret/mock", "from-vault"}
+	c.Assert(newTestApp().Run(os.Args), check.IsNil)
+	cls, err := cliPersistStore{}.Get("from-vault")
+	c.Assert(err, check.IsNil)
+	_, ok := cls.Options.StringOptions[rpcDriver.CredentialOption]
+	c.Assert(ok, check.Equals, false)
+	c.Assert(cls.Options.StringOptions[vaultPathFlag.Name], check.Equals, "secret/mock")
+	// create runs twice to parse the driver flags, vault is called once
+	c.Assert(s.requests, check.Equals, 1)
+}
@@ -12,6 +12,15 @@ It has these top-level messages:
 	DriverFlags
 	Flag
 	DriverOptions
+	NodePool
+	DriverVersion
+	ProtocolVersion
+	ConnectivityResult
+	ClusterStatus
+	SnapshotRequest
+	SnapshotResult
+	OperationResult
+	ProviderError
 	StringSlice
 	ClusterInfo
 */
@@ -62,9 +71,12 @@ func (m *DriverFlags) GetOptions() map[string]*Flag {
 }
 
 type Flag struct {
-	Type  string `protobuf:"bytes,1,opt,name=type" json:"type,omitempty"`
-	Usage string `protobuf:"bytes,2,opt,name=usage" json:"usage,omitempty"`
-	Value string `protobuf:"bytes,3,opt,name=value" json:"value,omitempty"`
+	Type      string `protobuf:"bytes,1,opt,name=type" json:"type,omitempty"`
+	Usage     string `protobuf:"bytes,2,opt,name=usage" json:"usage,omitempty"`
+	Value     string `protobuf:"bytes,3,opt,name=value" json:"value,omitempty"`
+	Required  bool   `protobuf:"varint,4,opt,name=required" json:"required,omitempty"`
+	Canonical string `protobuf:"bytes,5,opt,name=canonical" json:"canonical,omitempty"`
+	Secret    bool   `protobuf:"varint,6,opt,name=secret" json:"secret,omitempty"`
 }
 
 func (m *Flag) Reset()                    { *m = Flag{} }
@@ -93,11 +105,35 @@ func (m *Flag) GetValue() string {
 	return ""
 }
 
+func (m *Flag) GetRequired() bool {
+	if m != nil {
+		return m.Required
+	}
+	return false
+}
+
+func (m *Flag) GetCanonical() string {
+	if m != nil {
+		return m.Canonical
+	}
+	return ""
+}
+
+func (m *Flag) GetSecret() bool {
+	if m != nil {
+		return m.Secret
+	}
+	return false
+}
+
 type DriverOptions struct {
 	BoolOptions        map[string]bool         `protobuf:"bytes,1,rep,name=bool_options,json=boolOptions" json:"bool_options,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
 	StringOptions      map[string]string       `protobuf:"bytes,2,rep,name=string_options,json=stringOptions" json:"string_options,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	IntOptions         map[string]int64        `protobuf:"bytes,3,rep,name=int_options,json=intOptions" json:"int_options,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
 	StringSliceOptions map[string]*StringSlice `protobuf:"bytes,4,rep,name=string_slice_options,json=stringSliceOptions" json:"string_slice_options,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	NodePools          []*NodePool             `protobuf:"bytes,5,rep,name=node_pools,json=nodePools" json:"node_pools,omitempty"`
+	SetKeys            []string                `protobuf:"bytes,6,rep,name=set_keys,json=setKeys" json:"set_keys,omitempty"`
+	SecretKeys         []string                `protobuf:"bytes,7,rep,name=secret_keys,json=secretKeys" json:"secret_keys,omitempty"`
 }
 
 func (m *DriverOptions) Reset()                    { *m = DriverOptions{} }
@@ -133,6 +169,227 @@ func (m *DriverOptions) GetStringSliceOptions() map[string]*StringSlice {
 	return nil
 }
 
+func (m *DriverOptions) GetNodePools() []*NodePool {
+	if m != nil {
+		return m.NodePools
+	}
+	return nil
+}
+
+func (m *DriverOptions) GetSetKeys() []string {
+	if m != nil {
+		return m.SetKeys
+	}
+	return nil
+}
+
+func (m *DriverOptions) GetSecretKeys() []string {
+	if m != nil {
+		return m.SecretKeys
+	}
+	return nil
+}
+
+type NodePool struct {
+	Name        string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Count       int64  `protobuf:"varint,2,opt,name=count" json:"count,omitempty"`
+	MachineType string `protobuf:"bytes,3,opt,name=machine_type,json=machineType" json:"machine_type,omitempty"`
+}
+
+func (m *NodePool) Reset()                    { *m = NodePool{} }
+func (m *NodePool) String() string            { return proto.CompactTextString(m) }
+func (*NodePool) ProtoMessage()               {}
+func (*NodePool) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *NodePool) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *NodePool) GetCount() int64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *NodePool) GetMachineType() string {
+	if m != nil {
+		return m.MachineType
+	}
+	return ""
+}
+
+type DriverVersion struct {
+	Version string `protobuf:"bytes,1,opt,name=version" json:"version,omitempty"`
+}
+
+func (m *DriverVersion) Reset()                    { *m = DriverVersion{} }
+func (m *DriverVersion) String() string            { return proto.CompactTextString(m) }
+func (*DriverVersion) ProtoMessage()               {}
+func (*DriverVersion) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *DriverVersion) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+type ProtocolVersion struct {
+	Version int32 `protobuf:"varint,1,opt,name=version" json:"version,omitempty"`
+}
+
+func (m *ProtocolVersion) Reset()                    { *m = ProtocolVersion{} }
+func (m *ProtocolVersion) String() string            { return proto.CompactTextString(m) }
+func (*ProtocolVersion) ProtoMessage()               {}
+func (*ProtocolVersion) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *ProtocolVersion) GetVersion() int32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+type ConnectivityResult struct {
+	Status  string `protobuf:"bytes,1,opt,name=status" json:"status,omitempty"`
+	Message string `protobuf:"bytes,2,opt,name=message" json:"message,omitempty"`
+}
+
+func (m *ConnectivityResult) Reset()                    { *m = ConnectivityResult{} }
+func (m *ConnectivityResult) String() string            { return proto.CompactTextString(m) }
+func (*ConnectivityResult) ProtoMessage()               {}
+func (*ConnectivityResult) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *ConnectivityResult) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *ConnectivityResult) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+type ClusterStatus struct {
+	Status  string `protobuf:"bytes,1,opt,name=status" json:"status,omitempty"`
+	Message string `protobuf:"bytes,2,opt,name=message" json:"message,omitempty"`
+}
+
+func (m *ClusterStatus) Reset()                    { *m = ClusterStatus{} }
+func (m *ClusterStatus) String() string            { return proto.CompactTextString(m) }
+func (*ClusterStatus) ProtoMessage()               {}
+func (*ClusterStatus) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+func (m *ClusterStatus) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *ClusterStatus) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+type SnapshotRequest struct {
+	Id string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+}
+
+func (m *SnapshotRequest) Reset()                    { *m = SnapshotRequest{} }
+func (m *SnapshotRequest) String() string            { return proto.CompactTextString(m) }
+func (*SnapshotRequest) ProtoMessage()               {}
+func (*SnapshotRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func (m *SnapshotRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type SnapshotResult struct {
+	Status  string `protobuf:"bytes,1,opt,name=status" json:"status,omitempty"`
+	Id      string `protobuf:"bytes,2,opt,name=id" json:"id,omitempty"`
+	Message string `protobuf:"bytes,3,opt,name=message" json:"message,omitempty"`
+}
+
+func (m *SnapshotResult) Reset()                    { *m = SnapshotResult{} }
+func (m *SnapshotResult) String() string            { return proto.CompactTextString(m) }
+func (*SnapshotResult) ProtoMessage()               {}
+func (*SnapshotResult) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *SnapshotResult) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *SnapshotResult) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *SnapshotResult) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+type OperationResult struct {
+	Warnings []string `protobuf:"bytes,1,rep,name=warnings" json:"warnings,omitempty"`
+}
+
+func (m *OperationResult) Reset()                    { *m = OperationResult{} }
+func (m *OperationResult) String() string            { return proto.CompactTextString(m) }
+func (*OperationResult) ProtoMessage()               {}
+func (*OperationResult) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+func (m *OperationResult) GetWarnings() []string {
+	if m != nil {
+		return m.Warnings
+	}
+	return nil
+}
+
+type ProviderError struct {
+	Code    string `protobuf:"bytes,1,opt,name=code" json:"code,omitempty"`
+	Message string `protobuf:"bytes,2,opt,name=message" json:"message,omitempty"`
+}
+
+func (m *ProviderError) Reset()                    { *m = ProviderError{} }
+func (m *ProviderError) String() string            { return proto.CompactTextString(m) }
+func (*ProviderError) ProtoMessage()               {}
+func (*ProviderError) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *ProviderError) GetCode() string {
+	if m != nil {
+		return m.Code
+	}
+	return ""
+}
+
+func (m *ProviderError) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
 type StringSlice struct {
 	Value []string `protobuf:"bytes,1,rep,name=value" json:"value,omitempty"`
 }
@@ -140,7 +397,7 @@ type StringSlice struct {
 func (m *StringSlice) Reset()                    { *m = StringSlice{} }
 func (m *StringSlice) String() string            { return proto.CompactTextString(m) }
 func (*StringSlice) ProtoMessage()               {}
-func (*StringSlice) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+func (*StringSlice) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
 
 func (m *StringSlice) GetValue() []string {
 	if m != nil {
@@ -160,12 +417,14 @@ type ClusterInfo struct {
 	ClientKey           string            `protobuf:"bytes,8,opt,name=client_key,json=clientKey" json:"client_key,omitempty"`
 	NodeCount           int64             `protobuf:"varint,9,opt,name=node_count,json=nodeCount" json:"node_count,omitempty"`
 	Metadata            map[string]string `protobuf:"bytes,10,rep,name=metadata" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Endpoints           map[string]string `protobuf:"bytes,11,rep,name=endpoints" json:"endpoints,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	ProviderMetadata    map[string]string `protobuf:"bytes,12,rep,name=provider_metadata,json=providerMetadata" json:"provider_metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 }
 
 func (m *ClusterInfo) Reset()                    { *m = ClusterInfo{} }
 func (m *ClusterInfo) String() string            { return proto.CompactTextString(m) }
 func (*ClusterInfo) ProtoMessage()               {}
-func (*ClusterInfo) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+func (*ClusterInfo) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
 
 func (m *ClusterInfo) GetVersion() string {
 	if m != nil {
@@ -237,11 +496,34 @@ func (m *ClusterInfo) GetMetadata() map[string]string {
 	return nil
 }
 
+func (m *ClusterInfo) GetEndpoints() map[string]string {
+	if m != nil {
+		return m.Endpoints
+	}
+	return nil
+}
+
+func (m *ClusterInfo) GetProviderMetadata() map[string]string {
+	if m != nil {
+		return m.ProviderMetadata
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*Empty)(nil), "drivers.Empty")
 	proto.RegisterType((*DriverFlags)(nil), "drivers.DriverFlags")
 	proto.RegisterType((*Flag)(nil), "drivers.Flag")
 	proto.RegisterType((*DriverOptions)(nil), "drivers.DriverOptions")
+	proto.RegisterType((*NodePool)(nil), "drivers.NodePool")
+	proto.RegisterType((*DriverVersion)(nil), "drivers.DriverVersion")
+	proto.RegisterType((*ProtocolVersion)(nil), "drivers.ProtocolVersion")
+	proto.RegisterType((*ConnectivityResult)(nil), "drivers.ConnectivityResult")
+	proto.RegisterType((*ClusterStatus)(nil), "drivers.ClusterStatus")
+	proto.RegisterType((*SnapshotRequest)(nil), "drivers.SnapshotRequest")
+	proto.RegisterType((*SnapshotResult)(nil), "drivers.SnapshotResult")
+	proto.RegisterType((*OperationResult)(nil), "drivers.OperationResult")
+	proto.RegisterType((*ProviderError)(nil), "drivers.ProviderError")
 	proto.RegisterType((*StringSlice)(nil), "drivers.StringSlice")
 	proto.RegisterType((*ClusterInfo)(nil), "drivers.ClusterInfo")
 }
@@ -257,14 +539,21 @@ const _ = grpc.SupportPackageIsVersion4
 // Client API for Driver service
 
 type DriverClient interface {
-	Create(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error)
-	Update(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error)
+	Create(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*OperationResult, error)
+	Update(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*OperationResult, error)
 	Get(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ClusterInfo, error)
 	PostCheck(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error)
 	Remove(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error)
 	GetDriverCreateOptions(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*DriverFlags, error)
 	GetDriverUpdateOptions(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*DriverFlags, error)
 	SetDriverOptions(ctx context.Context, in *DriverOptions, opts ...grpc.CallOption) (*Empty, error)
+	GetVersion(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*DriverVersion, error)
+	CheckConnectivity(ctx context.Context, in *DriverOptions, opts ...grpc.CallOption) (*ConnectivityResult, error)
+	GetClusterStatus(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ClusterStatus, error)
+	Snapshot(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*SnapshotResult, error)
+	Restore(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*SnapshotResult, error)
+	Resume(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*OperationResult, error)
+	GetProtocolVersion(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ProtocolVersion, error)
 }
 
 type driverClient struct {
@@ -275,8 +564,8 @@ func NewDriverClient(cc *grpc.ClientConn) DriverClient {
 	return &driverClient{cc}
 }
 
-func (c *driverClient) Create(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error) {
-	out := new(Empty)
+func (c *driverClient) Create(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*OperationResult, error) {
+	out := new(OperationResult)
 	err := grpc.Invoke(ctx, "/drivers.Driver/Create", in, out, c.cc, opts...)
 	if err != nil {
 		return nil, err
@@ -284,8 +573,8 @@ func (c *driverClient) Create(ctx context.Context, in *Empty, opts ...grpc.CallO
 	return out, nil
 }
 
-func (c *driverClient) Update(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error) {
-	out := new(Empty)
+func (c *driverClient) Update(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*OperationResult, error) {
+	out := new(OperationResult)
 	err := grpc.Invoke(ctx, "/drivers.Driver/Update", in, out, c.cc, opts...)
 	if err != nil {
 		return nil, err
@@ -347,17 +636,87 @@ func (c *driverClient) SetDriverOptions(ctx context.Context, in *DriverOptions,
 	return out, nil
 }
 
+func (c *driverClient) GetVersion(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*DriverVersion, error) {
+	out := new(DriverVersion)
+	err := grpc.Invoke(ctx, "/drivers.Driver/GetVersion", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *driverClient) CheckConnectivity(ctx context.Context, in *DriverOptions, opts ...grpc.CallOption) (*ConnectivityResult, error) {
+	out := new(ConnectivityResult)
+	err := grpc.Invoke(ctx, "/drivers.Driver/CheckConnectivity", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *driverClient) GetClusterStatus(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ClusterStatus, error) {
+	out := new(ClusterStatus)
+	err := grpc.Invoke(ctx, "/drivers.Driver/GetClusterStatus", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *driverClient) Snapshot(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*SnapshotResult, error) {
+	out := new(SnapshotResult)
+	err := grpc.Invoke(ctx, "/drivers.Driver/Snapshot", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *driverClient) Restore(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*SnapshotResult, error) {
+	out := new(SnapshotResult)
+	err := grpc.Invoke(ctx, "/drivers.Driver/Restore", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *driverClient) Resume(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*OperationResult, error) {
+	out := new(OperationResult)
+	err := grpc.Invoke(ctx, "/drivers.Driver/Resume", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *driverClient) GetProtocolVersion(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ProtocolVersion, error) {
+	out := new(ProtocolVersion)
+	err := grpc.Invoke(ctx, "/drivers.Driver/GetProtocolVersion", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Driver service
 
 type DriverServer interface {
-	Create(context.Context, *Empty) (*Empty, error)
-	Update(context.Context, *Empty) (*Empty, error)
+	Create(context.Context, *Empty) (*OperationResult, error)
+	Update(context.Context, *Empty) (*OperationResult, error)
 	Get(context.Context, *Empty) (*ClusterInfo, error)
 	PostCheck(context.Context, *Empty) (*Empty, error)
 	Remove(context.Context, *Empty) (*Empty, error)
 	GetDriverCreateOptions(context.Context, *Empty) (*DriverFlags, error)
 	GetDriverUpdateOptions(context.Context, *Empty) (*DriverFlags, error)
 	SetDriverOptions(context.Context, *DriverOptions) (*Empty, error)
+	GetVersion(context.Context, *Empty) (*DriverVersion, error)
+	CheckConnectivity(context.Context, *DriverOptions) (*ConnectivityResult, error)
+	GetClusterStatus(context.Context, *Empty) (*ClusterStatus, error)
+	Snapshot(context.Context, *Empty) (*SnapshotResult, error)
+	Restore(context.Context, *SnapshotRequest) (*SnapshotResult, error)
+	Resume(context.Context, *Empty) (*OperationResult, error)
+	GetProtocolVersion(context.Context, *Empty) (*ProtocolVersion, error)
 }
 
 func RegisterDriverServer(s *grpc.Server, srv DriverServer) {
@@ -508,6 +867,132 @@ func _Driver_SetDriverOptions_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Driver_GetVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DriverServer).GetVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/drivers.Driver/GetVersion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DriverServer).GetVersion(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Driver_CheckConnectivity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DriverOptions)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DriverServer).CheckConnectivity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/drivers.Driver/CheckConnectivity",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DriverServer).CheckConnectivity(ctx, req.(*DriverOptions))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Driver_GetClusterStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DriverServer).GetClusterStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/drivers.Driver/GetClusterStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DriverServer).GetClusterStatus(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Driver_Snapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DriverServer).Snapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/drivers.Driver/Snapshot",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DriverServer).Snapshot(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Driver_Restore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SnapshotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DriverServer).Restore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/drivers.Driver/Restore",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DriverServer).Restore(ctx, req.(*SnapshotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Driver_Resume_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DriverServer).Resume(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/drivers.Driver/Resume",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DriverServer).Resume(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Driver_GetProtocolVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DriverServer).GetProtocolVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/drivers.Driver/GetProtocolVersion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DriverServer).GetProtocolVersion(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Driver_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "drivers.Driver",
 	HandlerType: (*DriverServer)(nil),
@@ -544,6 +1029,34 @@ var _Driver_serviceDesc = grpc.ServiceDesc{
 			MethodName: "SetDriverOptions",
 			Handler:    _Driver_SetDriverOptions_Handler,
 		},
+		{
+			MethodName: "GetVersion",
+			Handler:    _Driver_GetVersion_Handler,
+		},
+		{
+			MethodName: "CheckConnectivity",
+			Handler:    _Driver_CheckConnectivity_Handler,
+		},
+		{
+			MethodName: "GetClusterStatus",
+			Handler:    _Driver_GetClusterStatus_Handler,
+		},
+		{
+			MethodName: "Snapshot",
+			Handler:    _Driver_Snapshot_Handler,
+		},
+		{
+			MethodName: "Restore",
+			Handler:    _Driver_Restore_Handler,
+		},
+		{
+			MethodName: "Resume",
+			Handler:    _Driver_Resume_Handler,
+		},
+		{
+			MethodName: "GetProtocolVersion",
+			Handler:    _Driver_GetProtocolVersion_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "drivers.proto",
@@ -552,47 +1065,76 @@ var _Driver_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("drivers.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 672 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x95, 0xdd, 0x6e, 0xd3, 0x30,
-	0x14, 0xc7, 0xe9, 0xd2, 0xaf, 0x9c, 0xac, 0x63, 0x78, 0x65, 0x44, 0x95, 0x90, 0x46, 0x27, 0xc1,
-	0x98, 0xb4, 0x5c, 0x94, 0x1b, 0xc4, 0x60, 0x1a, 0x94, 0x6d, 0x1a, 0x08, 0x31, 0x75, 0x70, 0xc5,
-	0x45, 0xf1, 0x52, 0x6f, 0x44, 0x4b, 0xed, 0xc8, 0x76, 0x8b, 0xfa, 0x20, 0xbc, 0x05, 0x2f, 0xc2,
-	0x0b, 0x71, 0x8d, 0x6c, 0x27, 0x99, 0xd3, 0x0f, 0xd6, 0xdd, 0xf5, 0x9c, 0xff, 0xdf, 0xbf, 0x9e,
-	0x73, 0x7c, 0x92, 0x40, 0x63, 0xc0, 0xa3, 0x31, 0xe1, 0x22, 0x48, 0x38, 0x93, 0x0c, 0xd5, 0xd2,
-	0xb0, 0x5d, 0x83, 0xca, 0xd1, 0x30, 0x91, 0x93, 0xf6, 0xaf, 0x12, 0x78, 0xef, 0x75, 0xf2, 0x38,
-	0xc6, 0x57, 0x02, 0xed, 0x43, 0x8d, 0x25, 0x32, 0x62, 0x54, 0xf8, 0xa5, 0x2d, 0x67, 0xc7, 0xeb,
-	0x3c, 0x09, 0x32, 0x84, 0x65, 0x0b, 0x3e, 0x1b, 0xcf, 0x11, 0x95, 0x7c, 0xd2, 0xcb, 0x4e, 0xb4,
-	0x4e, 0x61, 0xd5, 0x16, 0xd0, 0x3a, 0x38, 0xd7, 0x64, 0xe2, 0x97, 0xb6, 0x4a, 0x3b, 0x6e, 0x4f,
-	0xfd, 0x44, 0xdb, 0x50, 0x19, 0xe3, 0x78, 0x44, 0xfc, 0x95, 0xad, 0xd2, 0x8e, 0xd7, 0x69, 0xe4,
-	0x70, 0x85, 0xed, 0x19, 0xed, 0xd5, 0xca, 0xcb, 0x52, 0xfb, 0x18, 0xca, 0x2a, 0x85, 0x10, 0x94,
-	0xe5, 0x24, 0x21, 0x29, 0x43, 0xff, 0x46, 0x4d, 0xa8, 0x8c, 0x04, 0xbe, 0x32, 0x10, 0xb7, 0x67,
-	0x02, 0x95, 0x35, 0x68, 0xc7, 0x64, 0x75, 0xd0, 0xfe, 0x5b, 0x86, 0x86, 0x29, 0x3c, 0xad, 0x0c,
-	0x7d, 0x80, 0xd5, 0x0b, 0xc6, 0xe2, 0x7e, 0xb1, 0xcd, 0x67, 0x53, 0x6d, 0xa6, 0xee, 0xe0, 0x1d,
-	0x63, 0x71, 0xa1, 0x59, 0xef, 0xe2, 0x26, 0x83, 0xce, 0x60, 0x4d, 0x48, 0x1e, 0xd1, 0xab, 0x9c,
-	0xb6, 0xa2, 0x69, 0xcf, 0x17, 0xd0, 0xce, 0xb5, 0xb9, 0xc0, 0x6b, 0x08, 0x3b, 0x87, 0x4e, 0xc0,
-	0x8b, 0xa8, 0xcc, 0x71, 0x8e, 0xc6, 0x3d, 0x5d, 0x80, 0x3b, 0xa5, 0xb2, 0xc0, 0x82, 0x28, 0x4f,
-	0xa0, 0xef, 0xd0, 0x4c, 0x4b, 0x13, 0x71, 0x14, 0x92, 0x9c, 0x58, 0xd6, 0xc4, 0xe0, 0xbf, 0x05,
-	0x9e, 0xab, 0x13, 0x05, 0x32, 0x12, 0x33, 0x42, 0xeb, 0x00, 0xd6, 0xa7, 0xa7, 0x33, 0xe7, 0xc6,
-	0x9b, 0xf6, 0x8d, 0xd7, 0xad, 0x2b, 0x6e, 0x1d, 0x02, 0x9a, 0x9d, 0xc7, 0x6d, 0x04, 0xd7, 0x26,
-	0xbc, 0x81, 0xfb, 0x53, 0x23, 0xb8, 0xed, 0xb8, 0x63, 0x1f, 0xff, 0x06, 0x8f, 0x16, 0xf4, 0x3b,
-	0x07, 0xb3, 0x5b, 0xdc, 0xdc, 0x66, 0x3e, 0x40, 0x0b, 0x61, 0x2f, 0xf0, 0x36, 0x78, 0x96, 0x72,
-	0x53, 0x85, 0x5a, 0xb7, 0x7c, 0x3b, 0xff, 0x38, 0xe0, 0x75, 0xe3, 0x91, 0x90, 0x84, 0x9f, 0xd2,
-	0x4b, 0x86, 0x7c, 0xa8, 0x29, 0x66, 0xc4, 0x68, 0xfa, 0xd7, 0x59, 0x88, 0x3a, 0xf0, 0x50, 0x10,
-	0x3e, 0x56, 0x37, 0x89, 0xc3, 0x90, 0x8d, 0xa8, 0xec, 0x4b, 0x76, 0x4d, 0x68, 0x3a, 0x94, 0x8d,
-	0x54, 0x7c, 0x6b, 0xb4, 0x2f, 0x4a, 0x42, 0x2d, 0xa8, 0x13, 0x3a, 0x48, 0x58, 0x44, 0x65, 0xfa,
-	0x50, 0xe4, 0xb1, 0xd2, 0x46, 0x82, 0x70, 0x8a, 0x87, 0xc4, 0x2f, 0x1b, 0x2d, 0x8b, 0x95, 0x96,
-	0x60, 0x21, 0x7e, 0x32, 0x3e, 0xf0, 0x2b, 0x46, 0xcb, 0x62, 0x14, 0xc0, 0x06, 0x67, 0x4c, 0xf6,
-	0x43, 0xdc, 0x0f, 0x09, 0x97, 0xd1, 0x65, 0x14, 0x62, 0x49, 0xfc, 0xaa, 0xb6, 0x3d, 0x50, 0x52,
-	0x17, 0x77, 0x6f, 0x04, 0xb4, 0x07, 0x28, 0x8c, 0x23, 0x42, 0x65, 0xc1, 0x5e, 0x33, 0x76, 0xa3,
-	0xd8, 0xf6, 0xc7, 0x00, 0xa9, 0x5d, 0x8d, 0xbf, 0xae, 0x6d, 0xae, 0xc9, 0x7c, 0x24, 0x13, 0x25,
-	0x53, 0x36, 0x20, 0x7d, 0xdd, 0xa4, 0xef, 0xea, 0x0b, 0x75, 0x55, 0xa6, 0xab, 0x12, 0xe8, 0x00,
-	0xea, 0x43, 0x22, 0xf1, 0x00, 0x4b, 0xec, 0x83, 0xde, 0xf3, 0x76, 0x7e, 0x4d, 0xd6, 0x98, 0x83,
-	0x4f, 0xa9, 0xc9, 0xec, 0x76, 0x7e, 0xa6, 0xb5, 0x0f, 0x8d, 0x82, 0x74, 0x97, 0x65, 0xec, 0xfc,
-	0x76, 0xa0, 0x6a, 0x1e, 0x26, 0xb4, 0x0b, 0xd5, 0x2e, 0x27, 0xaa, 0x9f, 0xb5, 0xfc, 0xff, 0xf5,
-	0xeb, 0xb6, 0x35, 0x15, 0xb7, 0xef, 0x29, 0xef, 0xd7, 0x64, 0xb0, 0x9c, 0x77, 0x0f, 0x9c, 0x13,
-	0x22, 0x67, 0x8c, 0xcd, 0x79, 0x4d, 0x6a, 0xbb, 0x7b, 0xc6, 0x84, 0xec, 0xfe, 0x20, 0xe1, 0xf5,
-	0x72, 0x95, 0xf4, 0xc8, 0x90, 0x8d, 0x97, 0xa9, 0xe4, 0x10, 0x36, 0x4f, 0x88, 0x34, 0xed, 0x9a,
-	0x56, 0xb3, 0xf7, 0xce, 0xe2, 0xe2, 0xac, 0xef, 0xc7, 0x14, 0xc1, 0x0c, 0xe0, 0xae, 0x84, 0xd7,
-	0xb0, 0x7e, 0x9e, 0x11, 0xb2, 0xb3, 0x9b, 0xf3, 0xdf, 0x6b, 0xb3, 0x1d, 0x5c, 0x54, 0xf5, 0x17,
-	0xf1, 0xc5, 0xbf, 0x00, 0x00, 0x00, 0xff, 0xff, 0x05, 0x85, 0xcb, 0x62, 0x22, 0x07, 0x00, 0x00,
+	// 1134 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x57, 0x59, 0x6f, 0xdb, 0xc6,
+	0x13, 0xd7, 0x61, 0x5d, 0x23, 0xcb, 0xc7, 0xc6, 0x49, 0xf8, 0xd7, 0xbf, 0x45, 0x1d, 0x06, 0x68,
+	0x9d, 0x14, 0x11, 0x0a, 0x37, 0x2d, 0x8a, 0xe6, 0x80, 0x5d, 0xd5, 0x11, 0x9c, 0xa0, 0x8d, 0x41,
+	0xa7, 0xcd, 0x43, 0x1f, 0x54, 0x9a, 0x9c, 0xd8, 0x84, 0xa9, 0x5d, 0x66, 0x77, 0xa5, 0x40, 0x1f,
+	0xa4, 0x40, 0x3f, 0x60, 0x1f, 0xfa, 0xda, 0x6f, 0x50, 0xec, 0x41, 0x8a, 0xd4, 0xe1, 0x54, 0x6f,
+	0x9c, 0x99, 0xdf, 0xfc, 0x76, 0x76, 0x76, 0x0e, 0x09, 0x3a, 0x21, 0x8f, 0x26, 0xc8, 0x45, 0x2f,
+	0xe1, 0x4c, 0x32, 0xd2, 0xb0, 0xa2, 0xdb, 0x80, 0xda, 0xc9, 0x28, 0x91, 0x53, 0xf7, 0x8f, 0x32,
+	0xb4, 0x7f, 0xd4, 0xca, 0x17, 0xb1, 0x7f, 0x29, 0xc8, 0x13, 0x68, 0xb0, 0x44, 0x46, 0x8c, 0x0a,
+	0xa7, 0xbc, 0x5f, 0x3d, 0x68, 0x1f, 0xde, 0xeb, 0xa5, 0x14, 0x39, 0x58, 0xef, 0xb5, 0xc1, 0x9c,
+	0x50, 0xc9, 0xa7, 0x5e, 0xea, 0xd1, 0x3d, 0x85, 0xcd, 0xbc, 0x81, 0xec, 0x40, 0xf5, 0x1a, 0xa7,
+	0x4e, 0x79, 0xbf, 0x7c, 0xd0, 0xf2, 0xd4, 0x27, 0xb9, 0x0f, 0xb5, 0x89, 0x1f, 0x8f, 0xd1, 0xa9,
+	0xec, 0x97, 0x0f, 0xda, 0x87, 0x9d, 0x8c, 0x5c, 0xd1, 0x7a, 0xc6, 0xf6, 0x7d, 0xe5, 0xbb, 0xb2,
+	0xfb, 0x67, 0x19, 0x36, 0x94, 0x8e, 0x10, 0xd8, 0x90, 0xd3, 0x04, 0x2d, 0x89, 0xfe, 0x26, 0x7b,
+	0x50, 0x1b, 0x0b, 0xff, 0xd2, 0xb0, 0xb4, 0x3c, 0x23, 0x28, 0xad, 0xe1, 0xae, 0x1a, 0xad, 0x16,
+	0x48, 0x17, 0x9a, 0x1c, 0xdf, 0x8f, 0x23, 0x8e, 0xa1, 0xb3, 0xb1, 0x5f, 0x3e, 0x68, 0x7a, 0x99,
+	0x4c, 0x3e, 0x81, 0x56, 0xe0, 0x53, 0x46, 0xa3, 0xc0, 0x8f, 0x9d, 0x9a, 0xf6, 0x9a, 0x29, 0xc8,
+	0x1d, 0xa8, 0x0b, 0x0c, 0x38, 0x4a, 0xa7, 0xae, 0xfd, 0xac, 0xe4, 0xfe, 0x55, 0x83, 0x8e, 0xc9,
+	0x85, 0xbd, 0x2c, 0x79, 0x09, 0x9b, 0x17, 0x8c, 0xc5, 0xc3, 0x62, 0xe6, 0xbe, 0x98, 0xcb, 0x9c,
+	0x45, 0xf7, 0x7e, 0x60, 0x2c, 0x2e, 0xe4, 0xaf, 0x7d, 0x31, 0xd3, 0x90, 0x33, 0xd8, 0x12, 0x92,
+	0x47, 0xf4, 0x32, 0x63, 0xab, 0x68, 0xb6, 0x07, 0x2b, 0xd8, 0xce, 0x35, 0xb8, 0xc0, 0xd7, 0x11,
+	0x79, 0x1d, 0x19, 0x40, 0x3b, 0xa2, 0x32, 0xa3, 0xab, 0x6a, 0xba, 0xcf, 0x57, 0xd0, 0x9d, 0x52,
+	0x59, 0xe0, 0x82, 0x28, 0x53, 0x90, 0xdf, 0x61, 0xcf, 0x86, 0x26, 0xe2, 0x28, 0xc0, 0x8c, 0x71,
+	0x43, 0x33, 0xf6, 0x6e, 0x0c, 0xf0, 0x5c, 0x79, 0x14, 0x98, 0x89, 0x58, 0x30, 0x90, 0xaf, 0x00,
+	0x28, 0x0b, 0x71, 0x98, 0x30, 0x16, 0x0b, 0xa7, 0xa6, 0x79, 0x77, 0x33, 0xde, 0x9f, 0x59, 0x88,
+	0x67, 0x8c, 0xc5, 0x5e, 0x8b, 0xda, 0x2f, 0x41, 0xfe, 0x07, 0x4d, 0x81, 0x72, 0x78, 0x8d, 0x53,
+	0xe1, 0xd4, 0xf7, 0xab, 0x07, 0x2d, 0xaf, 0x21, 0x50, 0xbe, 0xc2, 0xa9, 0x20, 0x9f, 0x41, 0xdb,
+	0xbc, 0x98, 0xb1, 0x36, 0xb4, 0x15, 0x8c, 0x4a, 0x01, 0xba, 0xcf, 0x61, 0x67, 0xfe, 0x2d, 0x96,
+	0x94, 0xec, 0x5e, 0xbe, 0x64, 0x9b, 0xb9, 0x1a, 0xed, 0x1e, 0x01, 0x59, 0xcc, 0xfe, 0xc7, 0x18,
+	0x5a, 0x79, 0x86, 0x67, 0xb0, 0x3d, 0x97, 0xf0, 0x8f, 0xb9, 0x57, 0xf3, 0xee, 0xbf, 0xc1, 0xdd,
+	0x15, 0xd9, 0x5d, 0x42, 0xf3, 0xb0, 0xd8, 0x7a, 0x7b, 0x59, 0x5a, 0x73, 0x14, 0xf9, 0x0e, 0x7c,
+	0x0b, 0xcd, 0x34, 0xe1, 0xaa, 0x09, 0xa9, 0x3f, 0xca, 0x9a, 0x50, 0x7d, 0xab, 0xb0, 0x02, 0x36,
+	0xa6, 0x32, 0x0d, 0x4b, 0x0b, 0xe4, 0x1e, 0x6c, 0x8e, 0xfc, 0xe0, 0x2a, 0xa2, 0x38, 0xd4, 0x6d,
+	0x6b, 0x7a, 0xb1, 0x6d, 0x75, 0x6f, 0xa6, 0x09, 0xba, 0x0f, 0xd2, 0xf6, 0xf9, 0x15, 0xb9, 0x88,
+	0x18, 0x25, 0x0e, 0x34, 0x26, 0xe6, 0xd3, 0x1e, 0x90, 0x8a, 0xee, 0x97, 0xb0, 0x7d, 0xa6, 0x06,
+	0x57, 0xc0, 0xe2, 0x15, 0xe0, 0xda, 0x0c, 0xfc, 0x02, 0x48, 0x9f, 0x51, 0x8a, 0x81, 0x8c, 0x26,
+	0x91, 0x9c, 0x7a, 0x28, 0xc6, 0xb1, 0xd4, 0x5d, 0x2c, 0x7d, 0x39, 0x16, 0x96, 0xdb, 0x4a, 0x8a,
+	0x67, 0x84, 0x22, 0x37, 0x45, 0x52, 0xd1, 0x3d, 0x86, 0x4e, 0x3f, 0x1e, 0x0b, 0x89, 0xfc, 0xdc,
+	0x40, 0xd7, 0xa7, 0xb8, 0x07, 0xdb, 0xe7, 0xd4, 0x4f, 0xc4, 0x15, 0x93, 0x1e, 0xbe, 0x1f, 0xa3,
+	0x90, 0x64, 0x0b, 0x2a, 0x51, 0x68, 0x09, 0x2a, 0x51, 0xe8, 0x7a, 0xb0, 0x35, 0x83, 0xdc, 0x18,
+	0xa9, 0xf1, 0xac, 0xa4, 0x9e, 0xf9, 0x63, 0xab, 0xc5, 0x63, 0x1f, 0xc1, 0xf6, 0xeb, 0x04, 0xb9,
+	0xaf, 0xea, 0xc0, 0x92, 0x76, 0xa1, 0xf9, 0xc1, 0xe7, 0x34, 0xa2, 0x97, 0x66, 0x2c, 0xb5, 0xbc,
+	0x4c, 0x76, 0x9f, 0x41, 0xe7, 0x8c, 0xb3, 0x49, 0x14, 0x22, 0x3f, 0xe1, 0x9c, 0x71, 0xf5, 0xcc,
+	0x01, 0x0b, 0xb3, 0x67, 0x56, 0xdf, 0x37, 0x5c, 0xf2, 0x3e, 0xb4, 0x73, 0xa5, 0x33, 0x2b, 0x53,
+	0x73, 0x8c, 0x11, 0xdc, 0xbf, 0x6b, 0xd0, 0xb6, 0xd9, 0x3c, 0xa5, 0xef, 0xd8, 0xea, 0xb7, 0x26,
+	0x87, 0x70, 0x5b, 0x20, 0x9f, 0xa8, 0xc1, 0xe2, 0x07, 0xba, 0x98, 0x86, 0x92, 0x5d, 0x23, 0xb5,
+	0xc7, 0xde, 0xb2, 0xc6, 0x63, 0x63, 0x7b, 0xa3, 0x4c, 0xea, 0x76, 0x48, 0xc3, 0x84, 0x45, 0x54,
+	0xda, 0x5c, 0x64, 0xb2, 0xb2, 0x8d, 0x05, 0x72, 0x5d, 0xb7, 0x1b, 0xc6, 0x96, 0xca, 0xca, 0x96,
+	0xf8, 0x42, 0x7c, 0x60, 0x3c, 0xb4, 0x73, 0x3f, 0x93, 0x49, 0x0f, 0x6e, 0x71, 0xc6, 0xe4, 0x30,
+	0xf0, 0x87, 0x01, 0x72, 0x19, 0xbd, 0x8b, 0x02, 0x5f, 0xa2, 0xde, 0x01, 0x2d, 0x6f, 0x57, 0x99,
+	0xfa, 0x7e, 0x7f, 0x66, 0x20, 0x8f, 0x80, 0x04, 0x71, 0x84, 0x54, 0x16, 0xe0, 0x0d, 0x03, 0x37,
+	0x96, 0x3c, 0xfc, 0x53, 0x00, 0x0b, 0x57, 0xfd, 0xd9, 0xb4, 0x4b, 0x47, 0x6b, 0x5e, 0xe1, 0x54,
+	0x99, 0xf5, 0x04, 0x34, 0xad, 0xd5, 0xd2, 0xad, 0xa5, 0xc7, 0x5d, 0x5f, 0x29, 0xc8, 0x73, 0x68,
+	0x8e, 0x50, 0xfa, 0xa1, 0x2f, 0x7d, 0x07, 0xf4, 0x78, 0x74, 0xb3, 0x3e, 0xce, 0xa5, 0xb9, 0xf7,
+	0x93, 0x05, 0x99, 0x51, 0x9b, 0xf9, 0x90, 0x63, 0x68, 0xa5, 0x09, 0x12, 0x4e, 0x5b, 0x13, 0xdc,
+	0x5f, 0x4a, 0x70, 0x92, 0xa2, 0x0c, 0xc3, 0xcc, 0x8b, 0xbc, 0x85, 0xdd, 0xc4, 0x56, 0xcd, 0x30,
+	0x8b, 0x65, 0x53, 0x53, 0x3d, 0x5c, 0x4a, 0x95, 0xd6, 0x58, 0x31, 0xa6, 0x9d, 0x64, 0x4e, 0xdd,
+	0x7d, 0x02, 0x9d, 0x02, 0x64, 0xad, 0x49, 0xfa, 0x14, 0xb6, 0x8a, 0x21, 0xaf, 0xe5, 0xdd, 0x87,
+	0xdb, 0x4b, 0xa3, 0x5c, 0x87, 0xe4, 0xf0, 0x9f, 0x3a, 0xd4, 0xcd, 0x60, 0x23, 0x8f, 0xa1, 0xde,
+	0xe7, 0xa8, 0x9e, 0x7b, 0x2b, 0x4b, 0x89, 0xfe, 0xbd, 0xd5, 0x75, 0x32, 0x79, 0xae, 0x53, 0xdd,
+	0x92, 0xf2, 0xfa, 0x25, 0x09, 0xd7, 0xf5, 0x7a, 0x04, 0xd5, 0x01, 0xca, 0x05, 0x97, 0xbd, 0x65,
+	0x6f, 0xa1, 0xe1, 0xad, 0x33, 0x26, 0x64, 0xff, 0x0a, 0x83, 0xeb, 0x05, 0xa7, 0x39, 0xd9, 0x2d,
+	0x91, 0x87, 0x50, 0xf7, 0x70, 0xc4, 0x26, 0xf8, 0x1f, 0xb0, 0x47, 0x70, 0x67, 0x80, 0xd2, 0xa4,
+	0xc0, 0x5c, 0x3f, 0xdd, 0xeb, 0xab, 0x83, 0xcb, 0xfd, 0xa8, 0x9c, 0x63, 0x30, 0xa9, 0x58, 0x97,
+	0xe1, 0x29, 0xec, 0x9c, 0xa7, 0x0c, 0xa9, 0xef, 0x9d, 0xe5, 0xbf, 0x4c, 0x96, 0xdc, 0xe0, 0x5b,
+	0x80, 0x01, 0xca, 0x74, 0xd5, 0xcc, 0x9f, 0x39, 0xcf, 0x63, 0x71, 0x6e, 0x89, 0xbc, 0x84, 0x5d,
+	0x9d, 0xd0, 0xfc, 0xfe, 0x59, 0x79, 0xec, 0xff, 0x67, 0x2f, 0xb3, 0xb0, 0xae, 0xcc, 0x0d, 0x06,
+	0x28, 0x8b, 0x1b, 0x68, 0x75, 0x24, 0x05, 0x9c, 0x5b, 0x22, 0xdf, 0x40, 0x33, 0x5d, 0x2b, 0x0b,
+	0x5e, 0x77, 0x67, 0x2b, 0xbf, 0xb0, 0x79, 0xdc, 0x12, 0x79, 0x0e, 0x0d, 0x0f, 0x85, 0x64, 0x1c,
+	0x89, 0xb3, 0x04, 0xa5, 0x57, 0xd8, 0x4d, 0xfe, 0x8f, 0x55, 0x99, 0x88, 0xf1, 0x68, 0xbd, 0xd2,
+	0x3d, 0x02, 0x32, 0x40, 0x39, 0xbf, 0xe1, 0x57, 0x33, 0xcc, 0x21, 0xdd, 0xd2, 0x45, 0x5d, 0xff,
+	0xaf, 0xf9, 0xfa, 0xdf, 0x01, 0x00, 0x5a, 0x3b, 0x15, 0x5f, 0xe8, 0x0c, 0x00, 0x00,
 }
@@ -0,0 +1,62 @@
+package drivers
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// grpcCodes are the rpc status codes of the failures classified with each error code
+var grpcCodes = map[string]codes.Code{
+	ErrorQuotaExceeded: codes.ResourceExhausted,
+	ErrorAuthFailed:    codes.PermissionDenied,
+	ErrorConflict:      codes.Aborted,
+	ErrorNotFound:      codes.NotFound,
+	ErrorInvalid:       codes.InvalidArgument,
+}
+
+// NewProviderError returns a failure of the provider classified with code, one of the Error codes. The server passes the
+// code to the client in the status of the rpc so that it can tell e.g. a quota from a rejected credential
+func NewProviderError(code, format string, a ...interface{}) error {
+	return &ProviderError{Code: code, Message: fmt.Sprintf(format, a...)}
+}
+
+func (e *ProviderError) Error() string {
+	return e.Message
+}
+
+// ProviderErrorFromStatus returns the classification of the failure carried by the status of a failed rpc, nil if the
+// driver didn't classify it
+func ProviderErrorFromStatus(err error) *ProviderError {
+	st, ok := status.FromError(err)
+	if !ok || st == nil {
+		return nil
+	}
+	for _, detail := range st.Details() {
+		if providerErr, ok := detail.(*ProviderError); ok {
+			return providerErr
+		}
+	}
+	return nil
+}
+
+// providerErrorInterceptor turns the ProviderErrors returned by the driver into rpc statuses carrying them
+func providerErrorInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	providerErr, ok := err.(*ProviderError)
+	if !ok {
+		return resp, err
+	}
+	code, ok := grpcCodes[providerErr.Code]
+	if !ok {
+		code = codes.Unknown
+	}
+	st, detailErr := status.New(code, providerErr.Message).WithDetails(providerErr)
+	if detailErr != nil {
+		return resp, err
+	}
+	return resp, st.Err()
+}
@@ -3,15 +3,19 @@ package gke
 import (
 	"encoding/base64"
 	"fmt"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
 	"strings"
-	"time"
 
 	generic "github.com/rancher/kontainer-engine/driver"
+	"github.com/rancher/kontainer-engine/utils"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/net/context"
 	"golang.org/x/oauth2/google"
 	raw "google.golang.org/api/container/v1"
+	"google.golang.org/api/googleapi"
 	"io/ioutil"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -20,6 +24,7 @@ import (
 const (
 	runningStatus        = "RUNNING"
 	defaultCredentialEnv = "GOOGLE_APPLICATION_CREDENTIALS"
+	version              = "v0.1.0"
 )
 
 // Driver defines the struct of gke driver
@@ -44,6 +49,10 @@ type Driver struct {
 	Name string
 	// Parameters used in creating the cluster's nodes
 	NodeConfig *raw.NodeConfig
+	// The node pools to create instead of the default pool, each of them uses NodeConfig with its own machine type
+	NodePools []*generic.NodePool
+	// How to poll the cluster and node pool status while waiting for them to be ready
+	Backoff utils.Backoff
 	// The path to the credential file(key.json)
 	CredentialPath string
 	// The content of the credential
@@ -92,8 +101,9 @@ func (d *Driver) GetDriverCreateOptions() (*generic.DriverFlags, error) {
 		Options: make(map[string]*generic.Flag),
 	}
 	driverFlag.Options["project-id"] = &generic.Flag{
-		Type:  generic.StringType,
-		Usage: "the ID of your project to use when creating a cluster",
+		Type:     generic.StringType,
+		Usage:    "the ID of your project to use when creating a cluster",
+		Required: true,
 	}
 	driverFlag.Options["zone"] = &generic.Flag{
 		Type:  generic.StringType,
@@ -113,8 +123,9 @@ func (d *Driver) GetDriverCreateOptions() (*generic.DriverFlags, error) {
 		Usage: "An optional description of this cluster",
 	}
 	driverFlag.Options["master-version"] = &generic.Flag{
-		Type:  generic.StringType,
-		Usage: "The kubernetes master version",
+		Type:      generic.StringType,
+		Usage:     "The kubernetes master version",
+		Canonical: generic.KubernetesVersionCanonical,
 	}
 	driverFlag.Options["node-count"] = &generic.Flag{
 		Type:  generic.IntType,
@@ -127,16 +138,48 @@ func (d *Driver) GetDriverCreateOptions() (*generic.DriverFlags, error) {
 		Value: "100",
 	}
 	driverFlag.Options["labels"] = &generic.Flag{
-		Type:  generic.StringSliceType,
-		Usage: "The map of Kubernetes labels (key/value pairs) to be applied to each node",
+		Type:      generic.StringSliceType,
+		Usage:     "The map of Kubernetes labels (key/value pairs) to be applied to each node",
+		Canonical: generic.NodeLabelsCanonical,
 	}
 	driverFlag.Options["machine-type"] = &generic.Flag{
 		Type:  generic.StringType,
 		Usage: "The machine type of a Google Compute Engine",
 	}
 	driverFlag.Options["enable-alpha-feature"] = &generic.Flag{
-		Type:  generic.BoolType,
-		Usage: "To enable kubernetes alpha feature",
+		Type:      generic.BoolType,
+		Usage:     "To enable kubernetes alpha feature",
+		Canonical: generic.FeatureCanonical("kubernetes-alpha"),
+	}
+	driverFlag.Options["http-load-balancing"] = &generic.Flag{
+		Type:      generic.BoolType,
+		Usage:     "Enable the HTTP load balancing addon",
+		Canonical: generic.AddonCanonical("http-load-balancing"),
+	}
+	driverFlag.Options["horizontal-pod-autoscaling"] = &generic.Flag{
+		Type:      generic.BoolType,
+		Usage:     "Enable the horizontal pod autoscaling addon",
+		Canonical: generic.AddonCanonical("horizontal-pod-autoscaling"),
+	}
+	driverFlag.Options["kubernetes-dashboard"] = &generic.Flag{
+		Type:      generic.BoolType,
+		Usage:     "Enable the kubernetes dashboard addon",
+		Canonical: generic.AddonCanonical("dashboard"),
+	}
+	driverFlag.Options["network-policy-config"] = &generic.Flag{
+		Type:      generic.BoolType,
+		Usage:     "Enable the network policy addon",
+		Canonical: generic.AddonCanonical("network-policy"),
+	}
+	driverFlag.Options["gke-network"] = &generic.Flag{
+		Type:      generic.StringType,
+		Usage:     "The existing network to place the cluster in, empty means the default network",
+		Canonical: generic.NetworkCanonical,
+	}
+	driverFlag.Options["gke-subnetwork"] = &generic.Flag{
+		Type:      generic.StringType,
+		Usage:     "The existing subnetwork of the network to place the nodes in",
+		Canonical: generic.SubnetCanonical,
 	}
 	return &driverFlag, nil
 }
@@ -174,15 +217,15 @@ func (d *Driver) SetDriverOptions(driverOptions *generic.DriverOptions) error {
 	d.NodeConfig.DiskSizeGb = getValueFromDriverOptions(driverOptions, generic.IntType, "disk-size-gb", "diskSizeGb").(int64)
 	d.NodeConfig.MachineType = getValueFromDriverOptions(driverOptions, generic.StringType, "machine-type", "machineType").(string)
 	d.CredentialPath = getValueFromDriverOptions(driverOptions, generic.StringType, "gke-credential-path").(string)
-	d.CredentialContent = getValueFromDriverOptions(driverOptions, generic.StringType, "credential").(string)
+	d.CredentialContent = getValueFromDriverOptions(driverOptions, generic.StringType, generic.CredentialOption).(string)
 	d.EnableAlphaFeature = getValueFromDriverOptions(driverOptions, generic.BoolType, "enable-alpha-feature", "enableAlphaFeature").(bool)
-	d.HorizontalPodAutoscaling = getValueFromDriverOptions(driverOptions, generic.BoolType, "horizontalPodAutoscaling").(bool)
-	d.HTTPLoadBalancing = getValueFromDriverOptions(driverOptions, generic.BoolType, "httpLoadBalancing").(bool)
-	d.KubernetesDashboard = getValueFromDriverOptions(driverOptions, generic.BoolType, "kubernetesDashboard").(bool)
-	d.NetworkPolicyConfig = getValueFromDriverOptions(driverOptions, generic.BoolType, "networkPolicyConfig").(bool)
+	d.HorizontalPodAutoscaling = getValueFromDriverOptions(driverOptions, generic.BoolType, "horizontal-pod-autoscaling", "horizontalPodAutoscaling").(bool)
+	d.HTTPLoadBalancing = getValueFromDriverOptions(driverOptions, generic.BoolType, "http-load-balancing", "httpLoadBalancing").(bool)
+	d.KubernetesDashboard = getValueFromDriverOptions(driverOptions, generic.BoolType, "kubernetes-dashboard", "kubernetesDashboard").(bool)
+	d.NetworkPolicyConfig = getValueFromDriverOptions(driverOptions, generic.BoolType, "network-policy-config", "networkPolicyConfig").(bool)
 	d.NodeConfig.ImageType = getValueFromDriverOptions(driverOptions, generic.StringType, "imageType").(string)
-	d.Network = getValueFromDriverOptions(driverOptions, generic.StringType, "network").(string)
-	d.SubNetwork = getValueFromDriverOptions(driverOptions, generic.StringType, "subNetwork").(string)
+	d.Network = getValueFromDriverOptions(driverOptions, generic.StringType, "gke-network", "network").(string)
+	d.SubNetwork = getValueFromDriverOptions(driverOptions, generic.StringType, "gke-subnetwork", "subNetwork").(string)
 	d.LegacyAbac = getValueFromDriverOptions(driverOptions, generic.BoolType, "legacyAbac").(bool)
 	d.Locations = []string{}
 	locations := getValueFromDriverOptions(driverOptions, generic.StringSliceType, "locations").(*generic.StringSlice)
@@ -191,6 +234,12 @@ func (d *Driver) SetDriverOptions(driverOptions *generic.DriverOptions) error {
 	}
 
 	d.NodeCount = getValueFromDriverOptions(driverOptions, generic.IntType, "node-count", "nodeCount").(int64)
+	d.NodePools = driverOptions.NodePools
+	backoff, err := generic.BackoffFromOptions(driverOptions)
+	if err != nil {
+		return err
+	}
+	d.Backoff = backoff
 	labelValues := getValueFromDriverOptions(driverOptions, generic.StringSliceType, "labels").(*generic.StringSlice)
 	for _, part := range labelValues.Value {
 		kv := strings.Split(part, "=")
@@ -198,6 +247,15 @@ func (d *Driver) SetDriverOptions(driverOptions *generic.DriverOptions) error {
 			d.NodeConfig.Labels[kv[0]] = kv[1]
 		}
 	}
+	if err := d.setupCredential(); err != nil {
+		return err
+	}
+	// updateConfig
+	return d.validate()
+}
+
+// setupCredential points the google client to the credential given in the options
+func (d *Driver) setupCredential() error {
 	if d.CredentialPath != "" {
 		os.Setenv(defaultCredentialEnv, d.CredentialPath)
 	}
@@ -212,8 +270,89 @@ func (d *Driver) SetDriverOptions(driverOptions *generic.DriverOptions) error {
 		os.Setenv(defaultCredentialEnv, file.Name())
 		d.TempCredentialPath = file.Name()
 	}
-	// updateConfig
-	return d.validate()
+	return nil
+}
+
+// CheckConnectivity lists the clusters of the project to verify the credential
+func (d *Driver) CheckConnectivity(driverOptions *generic.DriverOptions) (*generic.ConnectivityResult, error) {
+	d.ProjectID = getValueFromDriverOptions(driverOptions, generic.StringType, "project-id", "projectId").(string)
+	d.Zone = getValueFromDriverOptions(driverOptions, generic.StringType, "zone").(string)
+	d.CredentialPath = getValueFromDriverOptions(driverOptions, generic.StringType, "gke-credential-path").(string)
+	d.CredentialContent = getValueFromDriverOptions(driverOptions, generic.StringType, generic.CredentialOption).(string)
+	if d.ProjectID == "" {
+		return nil, fmt.Errorf("project ID is required")
+	} else if d.Zone == "" {
+		return nil, fmt.Errorf("zone is required")
+	}
+	if err := d.setupCredential(); err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(d.TempCredentialPath)
+
+	svc, err := d.getServiceClient()
+	if err != nil {
+		return &generic.ConnectivityResult{
+			Status:  generic.ConnectivityBadCredentials,
+			Message: err.Error(),
+		}, nil
+	}
+	_, err = svc.Projects.Zones.Clusters.List(d.ProjectID, d.Zone).Context(context.Background()).Do()
+	return classifyConnectivityError(err), nil
+}
+
+func classifyConnectivityError(err error) *generic.ConnectivityResult {
+	if err == nil {
+		return &generic.ConnectivityResult{Status: generic.ConnectivityOK}
+	}
+	result := &generic.ConnectivityResult{
+		Status:  generic.ConnectivityUnknown,
+		Message: err.Error(),
+	}
+	switch e := err.(type) {
+	case *googleapi.Error:
+		for _, item := range e.Errors {
+			if strings.Contains(item.Reason, "quota") || strings.Contains(item.Reason, "rateLimit") {
+				result.Status = generic.ConnectivityQuota
+				return result
+			}
+		}
+		switch e.Code {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			result.Status = generic.ConnectivityBadCredentials
+		case http.StatusTooManyRequests:
+			result.Status = generic.ConnectivityQuota
+		}
+	case *url.Error, net.Error:
+		result.Status = generic.ConnectivityNetwork
+	}
+	return result
+}
+
+// classifyError classifies the failures of the GKE API so that the callers can tell e.g. a quota from a rejected
+// credential, the other errors are returned unchanged
+func classifyError(err error) error {
+	e, ok := err.(*googleapi.Error)
+	if !ok {
+		return err
+	}
+	for _, item := range e.Errors {
+		if strings.Contains(item.Reason, "quota") || strings.Contains(item.Reason, "rateLimit") {
+			return generic.NewProviderError(generic.ErrorQuotaExceeded, "%v", err)
+		}
+	}
+	switch e.Code {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return generic.NewProviderError(generic.ErrorAuthFailed, "%v", err)
+	case http.StatusTooManyRequests:
+		return generic.NewProviderError(generic.ErrorQuotaExceeded, "%v", err)
+	case http.StatusConflict:
+		return generic.NewProviderError(generic.ErrorConflict, "%v", err)
+	case http.StatusNotFound:
+		return generic.NewProviderError(generic.ErrorNotFound, "%v", err)
+	case http.StatusBadRequest:
+		return generic.NewProviderError(generic.ErrorInvalid, "%v", err)
+	}
+	return err
 }
 
 func getValueFromDriverOptions(driverOptions *generic.DriverOptions, optionType string, keys ...string) interface{} {
@@ -269,7 +408,7 @@ func (d *Driver) Create() error {
 	}
 	operation, err := svc.Projects.Zones.Clusters.Create(d.ProjectID, d.Zone, d.generateClusterCreateRequest()).Context(context.Background()).Do()
 	if err != nil && !strings.Contains(err.Error(), "alreadyExists") {
-		return err
+		return classifyError(err)
 	}
 	if err == nil {
 		logrus.Debugf("Cluster %s create is called for project %s and zone %s. Status Code %v", d.Name, d.ProjectID, d.Zone, operation.HTTPStatusCode)
@@ -287,7 +426,7 @@ func (d *Driver) Update() error {
 	if d.NodePoolID == "" {
 		cluster, err := svc.Projects.Zones.Clusters.Get(d.ProjectID, d.Zone, d.Name).Context(context.Background()).Do()
 		if err != nil {
-			return err
+			return classifyError(err)
 		}
 		d.NodePoolID = cluster.NodePools[0].Name
 	}
@@ -300,7 +439,7 @@ func (d *Driver) Update() error {
 			},
 		}).Context(context.Background()).Do()
 		if err != nil {
-			return err
+			return classifyError(err)
 		}
 		logrus.Debugf("Cluster %s update is called for project %s and zone %s. Status Code %v", d.Name, d.ProjectID, d.Zone, operation.HTTPStatusCode)
 		if err := d.waitCluster(svc); err != nil {
@@ -314,7 +453,7 @@ func (d *Driver) Update() error {
 			NodeVersion: d.NodeVersion,
 		}).Context(context.Background()).Do()
 		if err != nil {
-			return err
+			return classifyError(err)
 		}
 		logrus.Debugf("Nodepool %s update is called for project %s, zone %s and cluster %s. Status Code %v", d.NodePoolID, d.ProjectID, d.Zone, d.Name, operation.HTTPStatusCode)
 		if err := d.waitNodePool(svc); err != nil {
@@ -328,7 +467,7 @@ func (d *Driver) Update() error {
 			NodeCount: d.NodeCount,
 		}).Context(context.Background()).Do()
 		if err != nil {
-			return err
+			return classifyError(err)
 		}
 		logrus.Debugf("Nodepool %s setSize is called for project %s, zone %s and cluster %s. Status Code %v", d.NodePoolID, d.ProjectID, d.Zone, d.Name, operation.HTTPStatusCode)
 		if err := d.waitCluster(svc); err != nil {
@@ -364,6 +503,22 @@ func (d *Driver) generateClusterCreateRequest() *raw.CreateClusterRequest {
 		Username: "admin",
 	}
 	request.Cluster.NodeConfig = d.NodeConfig
+	if len(d.NodePools) > 0 {
+		// gke rejects the default pool settings when node pools are given
+		request.Cluster.InitialNodeCount = 0
+		request.Cluster.NodeConfig = nil
+		for _, nodePool := range d.NodePools {
+			config := *d.NodeConfig
+			if nodePool.MachineType != "" {
+				config.MachineType = nodePool.MachineType
+			}
+			request.Cluster.NodePools = append(request.Cluster.NodePools, &raw.NodePool{
+				Name:             nodePool.Name,
+				InitialNodeCount: nodePool.Count,
+				Config:           &config,
+			})
+		}
+	}
 	return &request
 }
 
@@ -382,9 +537,12 @@ func (d *Driver) PostCheck() error {
 	}
 	cluster, err := svc.Projects.Zones.Clusters.Get(d.ProjectID, d.Zone, d.Name).Context(context.Background()).Do()
 	if err != nil {
-		return err
+		return classifyError(err)
 	}
 	d.ClusterInfo.Endpoint = cluster.Endpoint
+	d.ClusterInfo.Endpoints = map[string]string{
+		generic.EndpointPublic: cluster.Endpoint,
+	}
 	d.ClusterInfo.Version = cluster.CurrentMasterVersion
 	d.ClusterInfo.Username = cluster.MasterAuth.Username
 	d.ClusterInfo.Password = cluster.MasterAuth.Password
@@ -393,6 +551,11 @@ func (d *Driver) PostCheck() error {
 	d.ClusterInfo.ClientKey = cluster.MasterAuth.ClientKey
 	d.ClusterInfo.NodeCount = cluster.CurrentNodeCount
 	d.ClusterInfo.Metadata["nodePool"] = cluster.NodePools[0].Name
+	d.ClusterInfo.ProviderMetadata = map[string]string{
+		"selfLink": cluster.SelfLink,
+		"zone":     cluster.Zone,
+		"network":  cluster.Network,
+	}
 	serviceAccountToken, err := generateServiceAccountTokenForGke(cluster)
 	if err != nil {
 		return err
@@ -412,7 +575,7 @@ func (d *Driver) Remove() error {
 	logrus.Debugf("Removing cluster %v from project %v, zone %v", d.Name, d.ProjectID, d.Zone)
 	operation, err := svc.Projects.Zones.Clusters.Delete(d.ProjectID, d.Zone, d.Name).Context(context.Background()).Do()
 	if err != nil && !strings.Contains(err.Error(), "notFound") {
-		return err
+		return classifyError(err)
 	} else if err == nil {
 		logrus.Debugf("Cluster %v delete is called. Status Code %v", d.Name, operation.HTTPStatusCode)
 	} else {
@@ -422,6 +585,32 @@ func (d *Driver) Remove() error {
 	return nil
 }
 
+// GetClusterStatus gets the cluster from the project, a cluster the project doesn't have is reported not found
+func (d *Driver) GetClusterStatus() (*generic.ClusterStatus, error) {
+	// Remove deletes the temporary credential file, write it again for the polls following it
+	if d.CredentialContent != "" {
+		if err := d.setupCredential(); err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(d.TempCredentialPath)
+	}
+	svc, err := d.getServiceClient()
+	if err != nil {
+		return nil, err
+	}
+	cluster, err := svc.Projects.Zones.Clusters.Get(d.ProjectID, d.Zone, d.Name).Context(context.Background()).Do()
+	if err != nil {
+		if strings.Contains(err.Error(), "notFound") {
+			return &generic.ClusterStatus{Status: generic.ClusterStatusNotFound}, nil
+		}
+		return nil, err
+	}
+	if cluster.Status == "STOPPING" {
+		return &generic.ClusterStatus{Status: generic.ClusterStatusDeleting, Message: cluster.StatusMessage}, nil
+	}
+	return &generic.ClusterStatus{Status: generic.ClusterStatusExists, Message: cluster.Status}, nil
+}
+
 func (d *Driver) getServiceClient() (*raw.Service, error) {
 	client, err := google.DefaultClient(context.Background(), raw.CloudPlatformScope)
 	if err != nil {
@@ -462,38 +651,51 @@ func generateServiceAccountTokenForGke(cluster *raw.Cluster) (string, error) {
 
 func (d *Driver) waitCluster(svc *raw.Service) error {
 	lastMsg := ""
-	for {
+	err := utils.PollWithBackoff(d.Backoff, func() (bool, error) {
 		cluster, err := svc.Projects.Zones.Clusters.Get(d.ProjectID, d.Zone, d.Name).Context(context.TODO()).Do()
 		if err != nil {
-			return err
+			return false, err
 		}
 		if cluster.Status == runningStatus {
 			logrus.Infof("Cluster %v is running", d.Name)
-			return nil
+			return true, nil
 		}
 		if cluster.Status != lastMsg {
 			logrus.Infof("%v cluster %v......", strings.ToLower(cluster.Status), d.Name)
 			lastMsg = cluster.Status
 		}
-		time.Sleep(time.Second * 5)
+		return false, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to wait for cluster %s to be running: %v", d.Name, err)
 	}
+	return nil
 }
 
 func (d *Driver) waitNodePool(svc *raw.Service) error {
 	lastMsg := ""
-	for {
+	err := utils.PollWithBackoff(d.Backoff, func() (bool, error) {
 		nodepool, err := svc.Projects.Zones.Clusters.NodePools.Get(d.ProjectID, d.Zone, d.Name, d.NodePoolID).Context(context.TODO()).Do()
 		if err != nil {
-			return err
+			return false, err
 		}
 		if nodepool.Status == runningStatus {
 			logrus.Infof("Nodepool %v is running", d.Name)
-			return nil
+			return true, nil
 		}
 		if nodepool.Status != lastMsg {
 			logrus.Infof("%v nodepool %v......", strings.ToLower(nodepool.Status), d.NodePoolID)
 			lastMsg = nodepool.Status
 		}
-		time.Sleep(time.Second * 5)
+		return false, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to wait for nodepool %s to be running: %v", d.NodePoolID, err)
 	}
+	return nil
+}
+
+// GetVersion returns the version of the gke driver
+func (d *Driver) GetVersion() (*generic.DriverVersion, error) {
+	return &generic.DriverVersion{Version: version}, nil
 }
@@ -0,0 +1,408 @@
+package mock
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	generic "github.com/rancher/kontainer-engine/driver"
+)
+
+const (
+	defaultVersion    = "v1.8.4"
+	version           = "v0.1.0"
+	invalidCredential = "invalid"
+	// failPrefix makes the create of a cluster named fail-CODE fail with the error classified as CODE, e.g. fail-conflict
+	failPrefix = "fail-"
+)
+
+var (
+	// clusters are shared between driver instances so that a cluster created by one plugin process can be updated or removed by another
+	clusters     = map[string]generic.ClusterInfo{}
+	clustersLock sync.Mutex
+	// snapshots are the clusters as they were when their snapshots were taken, by snapshot identifier
+	snapshots = map[string]generic.ClusterInfo{}
+	// removing counts down the status polls left before a removed cluster is reported not found
+	removing = map[string]int{}
+
+	// RemovalPolls is how many status polls report a removed cluster as still deleting, so that tests can wait on a
+	// deletion taking a while like in a real provider
+	RemovalPolls int
+	// OperationWarnings are returned as the warnings of every create and update, so that tests can see the non-fatal
+	// warnings of a driver surfaced
+	OperationWarnings []string
+	// CreateDelay is how long a create takes before the cluster exists, so that tests can stop a create in flight
+	CreateDelay time.Duration
+	// ProtocolVersion is the version of the rpc protocol the mock driver reports, so that tests can see an incompatible
+	// driver refused
+	ProtocolVersion int32 = generic.CurrentProtocolVersion
+)
+
+// ResumedMetadata is the metadata key the mock driver sets on the clusters whose create was resumed
+const ResumedMetadata = "resumed"
+
+// MoveEndpoint simulates the provider moving the API server of the cluster called name to endpoint with a new CA
+// certificate, e.g. when a private endpoint is turned on, so that tests can see the stored cluster get stale
+func MoveEndpoint(name, endpoint string) error {
+	clustersLock.Lock()
+	defer clustersLock.Unlock()
+	info, ok := clusters[name]
+	if !ok {
+		return fmt.Errorf("cluster %s not found", name)
+	}
+	info.Endpoint = endpoint
+	info.Endpoints = map[string]string{generic.EndpointPublic: endpoint}
+	info.RootCaCertificate = base64.StdEncoding.EncodeToString([]byte(endpoint + "-ca"))
+	clusters[name] = info
+	return nil
+}
+
+// Driver is a driver that provisions in-memory clusters. It needs no credentials and is used for testing
+type Driver struct {
+	// The name of the cluster
+	Name string
+	// The number of nodes in the cluster
+	NodeCount int64
+	// An optional description of this cluster
+	Description string
+	// The kubernetes version of this cluster, the default version if empty
+	Version string
+	// The node pools of this cluster, NodeCount is their total if any is given
+	NodePools []*generic.NodePool
+	// The impersonated service account and user, reported in the metadata so that tests see they reach the driver
+	ImpersonateServiceAccount string
+	ImpersonateUser           string
+	// Cluster info
+	ClusterInfo generic.ClusterInfo
+}
+
+// NewDriver creates a new mock driver
+func NewDriver() *Driver {
+	return &Driver{
+		ClusterInfo: generic.ClusterInfo{
+			Metadata: map[string]string{},
+		},
+	}
+}
+
+// GetDriverCreateOptions returns create flags for mock driver
+func (d *Driver) GetDriverCreateOptions() (*generic.DriverFlags, error) {
+	driverFlag := generic.DriverFlags{
+		Options: make(map[string]*generic.Flag),
+	}
+	driverFlag.Options["node-count"] = &generic.Flag{
+		Type:  generic.IntType,
+		Usage: "The number of nodes to create in this cluster",
+		Value: "1",
+	}
+	driverFlag.Options["description"] = &generic.Flag{
+		Type:  generic.StringType,
+		Usage: "An optional description of this cluster",
+	}
+	driverFlag.Options["labels"] = &generic.Flag{
+		Type:      generic.StringSliceType,
+		Usage:     "The list of labels (key=value) to be applied to each node",
+		Canonical: generic.NodeLabelsCanonical,
+	}
+	driverFlag.Options["taints"] = &generic.Flag{
+		Type:      generic.StringSliceType,
+		Usage:     "The list of taints (key=value:effect) to be applied to each node",
+		Canonical: generic.NodeTaintsCanonical,
+	}
+	driverFlag.Options["enable-alpha-feature"] = &generic.Flag{
+		Type:      generic.BoolType,
+		Usage:     "To enable kubernetes alpha feature",
+		Canonical: generic.FeatureCanonical("kubernetes-alpha"),
+	}
+	driverFlag.Options[generic.CredentialOption] = &generic.Flag{
+		Type:   generic.StringType,
+		Usage:  "The credential of the mock provider, the value 'invalid' is rejected",
+		Secret: true,
+	}
+	driverFlag.Options["registry-auth"] = &generic.Flag{
+		Type:   generic.StringType,
+		Usage:  "The auth the nodes of the mock cluster pull images from the private registry with",
+		Value:  "mock-registry-auth-d3f4",
+		Secret: true,
+	}
+	driverFlag.Options["version"] = &generic.Flag{
+		Type:      generic.StringType,
+		Usage:     "The kubernetes version of the cluster",
+		Canonical: generic.KubernetesVersionCanonical,
+	}
+	driverFlag.Options["dashboard"] = &generic.Flag{
+		Type:      generic.BoolType,
+		Usage:     "Enable the dashboard addon",
+		Canonical: generic.AddonCanonical("dashboard"),
+	}
+	driverFlag.Options["monitoring"] = &generic.Flag{
+		Type:      generic.BoolType,
+		Usage:     "Enable the monitoring addon",
+		Canonical: generic.AddonCanonical("monitoring"),
+	}
+	driverFlag.Options["vpc-id"] = &generic.Flag{
+		Type:      generic.StringType,
+		Usage:     "The existing VPC of the mock provider to place the cluster in",
+		Canonical: generic.NetworkCanonical,
+	}
+	driverFlag.Options["subnet-id"] = &generic.Flag{
+		Type:      generic.StringType,
+		Usage:     "The existing subnet of the VPC to place the nodes in",
+		Canonical: generic.SubnetCanonical,
+	}
+	driverFlag.Options["upgrade-window"] = &generic.Flag{
+		Type:      generic.StringType,
+		Usage:     "The window the mock provider upgrades the cluster in",
+		Canonical: generic.MaintenanceWindowCanonical,
+	}
+	driverFlag.Options["upgrade-blackouts"] = &generic.Flag{
+		Type:      generic.StringSliceType,
+		Usage:     "The periods the mock provider doesn't upgrade the cluster in",
+		Canonical: generic.MaintenanceExclusionsCanonical,
+	}
+	driverFlag.Options["identity-issuer"] = &generic.Flag{
+		Type:      generic.StringType,
+		Usage:     "The OIDC issuer the API server of the mock cluster trusts",
+		Canonical: generic.OIDCIssuerCanonical,
+	}
+	driverFlag.Options["identity-client-id"] = &generic.Flag{
+		Type:      generic.StringType,
+		Usage:     "The client id of the tokens of the OIDC issuer",
+		Canonical: generic.OIDCClientIDCanonical,
+	}
+	driverFlag.Options["identity-username-claim"] = &generic.Flag{
+		Type:      generic.StringType,
+		Usage:     "The claim of the tokens used as the user name",
+		Canonical: generic.OIDCUsernameClaimCanonical,
+	}
+	driverFlag.Options["identity-groups-claim"] = &generic.Flag{
+		Type:      generic.StringType,
+		Usage:     "The claim of the tokens used as the groups",
+		Canonical: generic.OIDCGroupsClaimCanonical,
+	}
+	driverFlag.Options["identity-audiences"] = &generic.Flag{
+		Type:      generic.StringSliceType,
+		Usage:     "The audiences the API server of the mock cluster accepts",
+		Canonical: generic.APIAudiencesCanonical,
+	}
+	return &driverFlag, nil
+}
+
+// GetDriverUpdateOptions returns update flags for mock driver
+func (d *Driver) GetDriverUpdateOptions() (*generic.DriverFlags, error) {
+	driverFlag := generic.DriverFlags{
+		Options: make(map[string]*generic.Flag),
+	}
+	driverFlag.Options["node-count"] = &generic.Flag{
+		Type:  generic.IntType,
+		Usage: "The node number for your cluster to update. 0 means no updates",
+	}
+	driverFlag.Options["version"] = &generic.Flag{
+		Type:      generic.StringType,
+		Usage:     "The kubernetes version to upgrade the cluster to. Empty means no upgrade",
+		Canonical: generic.KubernetesVersionCanonical,
+	}
+	return &driverFlag, nil
+}
+
+// CheckConnectivity rejects the invalid credential and accepts anything else
+func (d *Driver) CheckConnectivity(driverOptions *generic.DriverOptions) (*generic.ConnectivityResult, error) {
+	if driverOptions.StringOptions[generic.CredentialOption] == invalidCredential {
+		return &generic.ConnectivityResult{
+			Status:  generic.ConnectivityBadCredentials,
+			Message: "the credential is invalid",
+		}, nil
+	}
+	return &generic.ConnectivityResult{Status: generic.ConnectivityOK}, nil
+}
+
+// SetDriverOptions sets the drivers options to mock driver
+func (d *Driver) SetDriverOptions(driverOptions *generic.DriverOptions) error {
+	d.Name = driverOptions.StringOptions["name"]
+	d.NodeCount = driverOptions.IntOptions["node-count"]
+	d.Description = driverOptions.StringOptions["description"]
+	d.Version = driverOptions.StringOptions["version"]
+	d.NodePools = driverOptions.NodePools
+	d.ImpersonateServiceAccount = driverOptions.StringOptions[generic.ImpersonateServiceAccountOption]
+	d.ImpersonateUser = driverOptions.StringOptions[generic.ImpersonateUserOption]
+	if len(d.NodePools) > 0 {
+		d.NodeCount = 0
+		for _, nodePool := range d.NodePools {
+			d.NodeCount += nodePool.Count
+		}
+	}
+	if d.Name == "" {
+		return fmt.Errorf("cluster name is required")
+	}
+	return nil
+}
+
+// Create creates the mock cluster
+func (d *Driver) Create() error {
+	if strings.HasPrefix(d.Name, failPrefix) {
+		code := strings.TrimPrefix(d.Name, failPrefix)
+		return generic.NewProviderError(code, "the mock provider failed to create cluster %s", d.Name)
+	}
+	time.Sleep(CreateDelay)
+	clustersLock.Lock()
+	defer clustersLock.Unlock()
+	if _, ok := clusters[d.Name]; ok {
+		return nil
+	}
+	delete(removing, d.Name)
+	version := d.Version
+	if version == "" {
+		version = defaultVersion
+	}
+	clusters[d.Name] = generic.ClusterInfo{
+		Version:             version,
+		Endpoint:            fmt.Sprintf("%s.mock.local", d.Name),
+		ServiceAccountToken: fmt.Sprintf("%s-token", d.Name),
+		RootCaCertificate:   base64.StdEncoding.EncodeToString([]byte(d.Name + "-ca")),
+		ClientCertificate:   base64.StdEncoding.EncodeToString([]byte(d.Name + "-cert")),
+		ClientKey:           base64.StdEncoding.EncodeToString([]byte(d.Name + "-key")),
+		NodeCount:           d.NodeCount,
+		Metadata: map[string]string{
+			"description": d.Description,
+		},
+		Endpoints: map[string]string{
+			generic.EndpointPublic:  fmt.Sprintf("%s.mock.local", d.Name),
+			generic.EndpointPrivate: fmt.Sprintf("%s.private.mock.local", d.Name),
+		},
+		ProviderMetadata: map[string]string{
+			"id":       fmt.Sprintf("mock-%s", d.Name),
+			"selfLink": fmt.Sprintf("https://mock.local/clusters/%s", d.Name),
+		},
+	}
+	for option, value := range map[string]string{
+		generic.ImpersonateServiceAccountOption: d.ImpersonateServiceAccount,
+		generic.ImpersonateUserOption:           d.ImpersonateUser,
+	} {
+		if value != "" {
+			clusters[d.Name].Metadata[option] = value
+		}
+	}
+	return nil
+}
+
+// Resume finishes the create of the mock cluster, it is reported in the metadata so that tests see the create was
+// resumed
+func (d *Driver) Resume() error {
+	if err := d.Create(); err != nil {
+		return err
+	}
+	clustersLock.Lock()
+	defer clustersLock.Unlock()
+	clusters[d.Name].Metadata[ResumedMetadata] = "true"
+	return nil
+}
+
+// Update updates the mock cluster
+func (d *Driver) Update() error {
+	clustersLock.Lock()
+	defer clustersLock.Unlock()
+	info, ok := clusters[d.Name]
+	if !ok {
+		return fmt.Errorf("cluster %s not found", d.Name)
+	}
+	if d.NodeCount != 0 {
+		info.NodeCount = d.NodeCount
+	}
+	if d.Version != "" {
+		info.Version = d.Version
+	}
+	clusters[d.Name] = info
+	return nil
+}
+
+// Get retrieve the cluster info by name
+func (d *Driver) Get() (*generic.ClusterInfo, error) {
+	return &d.ClusterInfo, nil
+}
+
+// PostCheck does post action
+func (d *Driver) PostCheck() error {
+	clustersLock.Lock()
+	defer clustersLock.Unlock()
+	info, ok := clusters[d.Name]
+	if !ok {
+		return fmt.Errorf("cluster %s not found", d.Name)
+	}
+	d.ClusterInfo = info
+	return nil
+}
+
+// Warnings returns OperationWarnings
+func (d *Driver) Warnings() []string {
+	return OperationWarnings
+}
+
+// Remove removes the cluster
+func (d *Driver) Remove() error {
+	clustersLock.Lock()
+	defer clustersLock.Unlock()
+	delete(clusters, d.Name)
+	if RemovalPolls > 0 {
+		removing[d.Name] = RemovalPolls
+	} else {
+		delete(removing, d.Name)
+	}
+	return nil
+}
+
+// GetClusterStatus reports a removed cluster as deleting for RemovalPolls polls, then as not found
+func (d *Driver) GetClusterStatus() (*generic.ClusterStatus, error) {
+	clustersLock.Lock()
+	defer clustersLock.Unlock()
+	if polls, ok := removing[d.Name]; ok {
+		if polls > 0 {
+			removing[d.Name] = polls - 1
+			return &generic.ClusterStatus{
+				Status:  generic.ClusterStatusDeleting,
+				Message: fmt.Sprintf("cluster %s is being deleted", d.Name),
+			}, nil
+		}
+		delete(removing, d.Name)
+	}
+	if _, ok := clusters[d.Name]; ok {
+		return &generic.ClusterStatus{Status: generic.ClusterStatusExists}, nil
+	}
+	return &generic.ClusterStatus{Status: generic.ClusterStatusNotFound}, nil
+}
+
+// Snapshot records the cluster as it is under a new snapshot identifier
+func (d *Driver) Snapshot() (*generic.SnapshotResult, error) {
+	clustersLock.Lock()
+	defer clustersLock.Unlock()
+	info, ok := clusters[d.Name]
+	if !ok {
+		return nil, generic.NewProviderError(generic.ErrorNotFound, "cluster %s not found", d.Name)
+	}
+	id := fmt.Sprintf("%s-snapshot-%d", d.Name, len(snapshots)+1)
+	snapshots[id] = info
+	return &generic.SnapshotResult{Status: generic.SnapshotOK, Id: id}, nil
+}
+
+// Restore puts the cluster back as it was when the snapshot was taken
+func (d *Driver) Restore(id string) (*generic.SnapshotResult, error) {
+	clustersLock.Lock()
+	defer clustersLock.Unlock()
+	info, ok := snapshots[id]
+	if !ok || !strings.HasPrefix(id, d.Name+"-snapshot-") {
+		return nil, generic.NewProviderError(generic.ErrorNotFound, "cluster %s has no snapshot %s", d.Name, id)
+	}
+	clusters[d.Name] = info
+	return &generic.SnapshotResult{Status: generic.SnapshotOK, Id: id}, nil
+}
+
+// ProtocolVersion returns the version of the rpc protocol the mock driver reports
+func (d *Driver) ProtocolVersion() int32 {
+	return ProtocolVersion
+}
+
+// GetVersion returns the version of the mock driver
+func (d *Driver) GetVersion() (*generic.DriverVersion, error) {
+	return &generic.DriverVersion{Version: version}, nil
+}
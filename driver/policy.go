@@ -0,0 +1,86 @@
+package drivers
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// The operations of the drivers whose timeout and retries can be set
+const (
+	OperationCreate = "create"
+	OperationUpdate = "update"
+	OperationRemove = "remove"
+	// OperationStatus is the get of the cluster info and of the cluster status
+	OperationStatus = "status"
+)
+
+// Operations lists the operations whose timeout and retries can be set
+var Operations = []string{OperationCreate, OperationUpdate, OperationRemove, OperationStatus}
+
+// retryInterval is the wait between two attempts of an operation, tests set it to 0
+var retryInterval = 2 * time.Second
+
+// OperationPolicy is how long an attempt of an operation can take and how many times a failed attempt is retried
+type OperationPolicy struct {
+	// Timeout of every attempt, 0 means the timeout of the client
+	Timeout time.Duration
+	// Retries is how many times an attempt failing with a transient error is retried
+	Retries int
+}
+
+// Policies are the policies of the operations by name
+type Policies map[string]OperationPolicy
+
+// For returns the policy of operation, timeout is its timeout when the policy doesn't set one
+func (p Policies) For(operation string, timeout time.Duration) OperationPolicy {
+	policy := p[operation]
+	if policy.Timeout == 0 {
+		policy.Timeout = timeout
+	}
+	return policy
+}
+
+type policiesKey struct{}
+
+// WithPolicies returns a child of ctx carrying the policies of the operations
+func WithPolicies(ctx context.Context, policies Policies) context.Context {
+	return context.WithValue(ctx, policiesKey{}, policies)
+}
+
+// PoliciesFrom returns the policies carried by ctx, nil if it carries none
+func PoliciesFrom(ctx context.Context) Policies {
+	policies, _ := ctx.Value(policiesKey{}).(Policies)
+	return policies
+}
+
+// isTransient tells whether a failed rpc can be attempted again: the driver wasn't reachable, or it reported a quota or a
+// conflict the provider may clear
+func isTransient(err error) bool {
+	switch grpc.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted:
+		return true
+	}
+	return false
+}
+
+// call runs the rpc of operation with the policy of the operation carried by the context of the client
+func (rpc *GrpcClient) call(operation string, timeout time.Duration, rpcCall func(ctx context.Context) error) error {
+	parent := rpc.context()
+	policy := PoliciesFrom(parent).For(operation, timeout)
+	var err error
+	for attempt := 0; attempt <= policy.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(retryInterval)
+		}
+		ctx, cancel := context.WithTimeout(parent, policy.Timeout)
+		err = rpcCall(ctx)
+		cancel()
+		if err == nil || !isTransient(err) {
+			return err
+		}
+	}
+	return err
+}
@@ -0,0 +1,123 @@
+package drivers
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/check.v1"
+)
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) {
+	check.TestingT(t)
+}
+
+type PolicyTestSuite struct {
+	oldRetryInterval time.Duration
+}
+
+var _ = check.Suite(&PolicyTestSuite{})
+
+func (s *PolicyTestSuite) SetUpTest(c *check.C) {
+	s.oldRetryInterval = retryInterval
+	retryInterval = 0
+}
+
+func (s *PolicyTestSuite) TearDownTest(c *check.C) {
+	retryInterval = s.oldRetryInterval
+}
+
+// recordingClient records the timeout and the attempts of every call, the calls fail with err until failures reaches 0
+type recordingClient struct {
+	DriverClient
+	timeouts map[string]time.Duration
+	attempts map[string]int
+	failures int
+	err      error
+}
+
+func newRecordingClient() *recordingClient {
+	return &recordingClient{timeouts: map[string]time.Duration{}, attempts: map[string]int{}}
+}
+
+func (r *recordingClient) record(ctx context.Context, operation string) error {
+	deadline, _ := ctx.Deadline()
+	// drop the time spent since the context was created
+	r.timeouts[operation] = time.Until(deadline).Round(time.Second)
+	r.attempts[operation]++
+	if r.failures > 0 {
+		r.failures--
+		return r.err
+	}
+	return nil
+}
+
+func (r *recordingClient) Create(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*OperationResult, error) {
+	return &OperationResult{}, r.record(ctx, OperationCreate)
+}
+
+func (r *recordingClient) Update(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*OperationResult, error) {
+	return &OperationResult{}, r.record(ctx, OperationUpdate)
+}
+
+func (r *recordingClient) Remove(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error) {
+	return &Empty{}, r.record(ctx, OperationRemove)
+}
+
+func (r *recordingClient) GetClusterStatus(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ClusterStatus, error) {
+	return &ClusterStatus{Status: ClusterStatusExists}, r.record(ctx, OperationStatus)
+}
+
+func (s *PolicyTestSuite) TestOperationsUseTheirPolicy(c *check.C) {
+	client := newRecordingClient()
+	rpc := &GrpcClient{client: client}
+	rpc.SetContext(WithPolicies(context.Background(), Policies{
+		OperationCreate: {Timeout: 30 * time.Minute},
+		OperationRemove: {Timeout: 20 * time.Minute},
+	}))
+	_, err := rpc.Create()
+	c.Assert(err, check.IsNil)
+	_, err = rpc.Update()
+	c.Assert(err, check.IsNil)
+	c.Assert(rpc.Remove(), check.IsNil)
+	_, err = rpc.GetClusterStatus()
+	c.Assert(err, check.IsNil)
+	// the operations without a policy keep the timeout of the client
+	c.Assert(client.timeouts, check.DeepEquals, map[string]time.Duration{
+		OperationCreate: 30 * time.Minute,
+		OperationUpdate: 10 * time.Minute,
+		OperationRemove: 20 * time.Minute,
+		OperationStatus: 30 * time.Second,
+	})
+}
+
+func (s *PolicyTestSuite) TestRetries(c *check.C) {
+	client := newRecordingClient()
+	rpc := &GrpcClient{client: client}
+	rpc.SetContext(WithPolicies(context.Background(), Policies{
+		OperationRemove: {Retries: 2},
+	}))
+	client.failures, client.err = 2, status.Error(codes.Aborted, "the cluster is being updated")
+	c.Assert(rpc.Remove(), check.IsNil)
+	c.Assert(client.attempts[OperationRemove], check.Equals, 3)
+
+	// create has no retries, the conflict fails it right away
+	client.failures = 2
+	_, err := rpc.Create()
+	c.Assert(err, check.ErrorMatches, ".*the cluster is being updated")
+	c.Assert(client.attempts[OperationCreate], check.Equals, 1)
+
+	// a failure that isn't transient isn't retried
+	client.failures, client.err = 1, status.Error(codes.PermissionDenied, "denied")
+	c.Assert(rpc.Remove(), check.ErrorMatches, ".*denied")
+	c.Assert(client.attempts[OperationRemove], check.Equals, 4)
+
+	// the last failure is returned once the retries are exhausted
+	client.failures, client.err = 5, status.Error(codes.Unavailable, "unreachable")
+	c.Assert(rpc.Remove(), check.ErrorMatches, ".*unreachable")
+	c.Assert(client.attempts[OperationRemove], check.Equals, 7)
+}
@@ -13,6 +13,10 @@ import (
 	"github.com/rancher/rke/cmd"
 )
 
+const (
+	version = "v0.1.0"
+)
+
 // Driver is the struct of rke driver
 type Driver struct {
 	// The string representation of Config Yaml
@@ -158,3 +162,8 @@ func (d *Driver) Remove() error {
 	}
 	return cmd.ClusterRemove(&rkeConfig, nil)
 }
+
+// GetVersion returns the version of the rke driver
+func (d *Driver) GetVersion() (*generic.DriverVersion, error) {
+	return &generic.DriverVersion{Version: version}, nil
+}
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // NewClient creates a grpc client for a driver plugin
@@ -24,33 +26,74 @@ func NewClient(driverName string, addr string) (*GrpcClient, error) {
 type GrpcClient struct {
 	client     DriverClient
 	driverName string
+	// ctx is the parent of the contexts of the calls, it carries the policies of the operations
+	ctx context.Context
 }
 
-// Create call grpc create
-func (rpc *GrpcClient) Create() error {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*10)
-	defer cancel()
-	_, err := rpc.client.Create(ctx, &Empty{})
-	return err
+// SetContext sets the parent of the contexts of the calls, the operation policies it carries with WithPolicies set the
+// timeout and the retries of the operations
+func (rpc *GrpcClient) SetContext(ctx context.Context) {
+	rpc.ctx = ctx
 }
 
-// Update call grpc update
-func (rpc *GrpcClient) Update() error {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*10)
-	defer cancel()
-	_, err := rpc.client.Update(ctx, &Empty{})
-	return err
+func (rpc *GrpcClient) context() context.Context {
+	if rpc.ctx == nil {
+		return context.Background()
+	}
+	return rpc.ctx
+}
+
+// Create call grpc create, the result has the warnings of the driver
+func (rpc *GrpcClient) Create() (OperationResult, error) {
+	result := OperationResult{}
+	err := rpc.call(OperationCreate, time.Minute*10, func(ctx context.Context) error {
+		reply, err := rpc.client.Create(ctx, &Empty{})
+		if err == nil {
+			result = *reply
+		}
+		return err
+	})
+	return result, err
+}
+
+// Resume call grpc resume, it finishes a create that stopped after it was started and has the timeout and the retries
+// of the create
+func (rpc *GrpcClient) Resume() (OperationResult, error) {
+	result := OperationResult{}
+	err := rpc.call(OperationCreate, time.Minute*10, func(ctx context.Context) error {
+		reply, err := rpc.client.Resume(ctx, &Empty{})
+		if err == nil {
+			result = *reply
+		}
+		return err
+	})
+	return result, err
+}
+
+// Update call grpc update, the result has the warnings of the driver
+func (rpc *GrpcClient) Update() (OperationResult, error) {
+	result := OperationResult{}
+	err := rpc.call(OperationUpdate, time.Minute*10, func(ctx context.Context) error {
+		reply, err := rpc.client.Update(ctx, &Empty{})
+		if err == nil {
+			result = *reply
+		}
+		return err
+	})
+	return result, err
 }
 
 // Get call grpc get
 func (rpc *GrpcClient) Get() ClusterInfo {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
-	defer cancel()
-	info, err := rpc.client.Get(ctx, &Empty{})
-	if err != nil {
-		return ClusterInfo{}
-	}
-	return *info
+	info := ClusterInfo{}
+	rpc.call(OperationStatus, time.Second*30, func(ctx context.Context) error {
+		result, err := rpc.client.Get(ctx, &Empty{})
+		if err == nil {
+			info = *result
+		}
+		return err
+	})
+	return info
 }
 
 func (rpc *GrpcClient) PostCheck() error {
@@ -64,10 +107,10 @@ func (rpc *GrpcClient) PostCheck() error {
 
 // Remove call grpc remove
 func (rpc *GrpcClient) Remove() error {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*5)
-	defer cancel()
-	_, err := rpc.client.Remove(ctx, &Empty{})
-	return err
+	return rpc.call(OperationRemove, time.Minute*5, func(ctx context.Context) error {
+		_, err := rpc.client.Remove(ctx, &Empty{})
+		return err
+	})
 }
 
 // GetDriverCreateOptions call grpc getDriverCreateOptions
@@ -100,6 +143,78 @@ func (rpc *GrpcClient) SetDriverOptions(options DriverOptions) error {
 	return err
 }
 
+// GetVersion call grpc getVersion
+func (rpc *GrpcClient) GetVersion() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+	version, err := rpc.client.GetVersion(ctx, &Empty{})
+	if err != nil {
+		return "", err
+	}
+	return version.Version, nil
+}
+
+// GetProtocolVersion call grpc getProtocolVersion, the drivers predating the handshake don't implement it and speak
+// version 0
+func (rpc *GrpcClient) GetProtocolVersion() (int32, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+	version, err := rpc.client.GetProtocolVersion(ctx, &Empty{})
+	if st, ok := status.FromError(err); err != nil && ok && st.Code() == codes.Unimplemented {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return version.Version, nil
+}
+
+// CheckConnectivity call grpc checkConnectivity
+func (rpc *GrpcClient) CheckConnectivity(options DriverOptions) (ConnectivityResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+	result, err := rpc.client.CheckConnectivity(ctx, &options)
+	if err != nil {
+		return ConnectivityResult{}, err
+	}
+	return *result, nil
+}
+
+// GetClusterStatus call grpc getClusterStatus
+func (rpc *GrpcClient) GetClusterStatus() (ClusterStatus, error) {
+	status := ClusterStatus{}
+	err := rpc.call(OperationStatus, time.Second*30, func(ctx context.Context) error {
+		result, err := rpc.client.GetClusterStatus(ctx, &Empty{})
+		if err == nil {
+			status = *result
+		}
+		return err
+	})
+	return status, err
+}
+
+// Snapshot call grpc snapshot
+func (rpc *GrpcClient) Snapshot() (SnapshotResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*10)
+	defer cancel()
+	result, err := rpc.client.Snapshot(ctx, &Empty{})
+	if err != nil {
+		return SnapshotResult{}, err
+	}
+	return *result, nil
+}
+
+// Restore call grpc restore
+func (rpc *GrpcClient) Restore(id string) (SnapshotResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*10)
+	defer cancel()
+	result, err := rpc.client.Restore(ctx, &SnapshotRequest{Id: id})
+	if err != nil {
+		return SnapshotResult{}, err
+	}
+	return *result, nil
+}
+
 // DriverName returns the driver name
 func (rpc *GrpcClient) DriverName() string {
 	return rpc.driverName
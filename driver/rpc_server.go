@@ -2,6 +2,7 @@ package drivers
 
 import (
 	"net"
+	"sync"
 
 	"github.com/sirupsen/logrus"
 	"golang.org/x/net/context"
@@ -38,12 +39,66 @@ type Driver interface {
 
 	// Remove removes the cluster
 	Remove() error
+
+	// GetVersion returns the version of the driver
+	GetVersion() (*DriverVersion, error)
+}
+
+// ConnectivityChecker is implemented by drivers that can verify the credential and connectivity to their provider without creating anything
+type ConnectivityChecker interface {
+	// CheckConnectivity performs a read-only call to the provider with the given options
+	CheckConnectivity(driverOptions *DriverOptions) (*ConnectivityResult, error)
+}
+
+// StatusReporter is implemented by drivers that can tell whether the provider still has the cluster set by the last
+// SetDriverOptions, e.g. while it is being deleted
+type StatusReporter interface {
+	// GetClusterStatus returns the status of the cluster, ClusterStatusNotFound once the provider has no such cluster
+	GetClusterStatus() (*ClusterStatus, error)
+}
+
+// Snapshotter is implemented by drivers that can take a snapshot, e.g. of etcd, of the cluster set by the last
+// SetDriverOptions
+type Snapshotter interface {
+	// Snapshot backs up the cluster and returns the identifier of the snapshot
+	Snapshot() (*SnapshotResult, error)
+}
+
+// Restorer is implemented by drivers that can restore the cluster set by the last SetDriverOptions from one of its
+// snapshots
+type Restorer interface {
+	// Restore replaces the state of the cluster with the snapshot called id
+	Restore(id string) (*SnapshotResult, error)
+}
+
+// Resumer is implemented by drivers that can finish a create that stopped after it was started, e.g. when the process
+// creating the cluster died. The create of the other drivers is run again, it must succeed if the cluster is already
+// partly or fully created.
+type Resumer interface {
+	// Resume finishes the create of the cluster set by the last SetDriverOptions
+	Resume() error
+}
+
+// ProtocolVersioner is implemented by drivers speaking another version of the rpc protocol than CurrentProtocolVersion
+type ProtocolVersioner interface {
+	// ProtocolVersion returns the version of the rpc protocol the driver speaks
+	ProtocolVersion() int32
+}
+
+// WarningsReporter is implemented by drivers that can report non-fatal warnings of their last Create or Update, e.g. the
+// use of a deprecated option or a quota nearly exhausted
+type WarningsReporter interface {
+	// Warnings returns the warnings of the last Create or Update
+	Warnings() []string
 }
 
 // GrpcServer defines the server struct
 type GrpcServer struct {
 	driver  Driver
 	address chan string
+	lock    sync.Mutex
+	server  *grpc.Server
+	stopped bool
 }
 
 // NewServer creates a grpc server for a specific plugin
@@ -70,13 +125,40 @@ func (s *GrpcServer) SetDriverOptions(ctx context.Context, in *DriverOptions) (*
 }
 
 // Create implements grpc method
-func (s *GrpcServer) Create(ctx context.Context, in *Empty) (*Empty, error) {
-	return &Empty{}, s.driver.Create()
+func (s *GrpcServer) Create(ctx context.Context, in *Empty) (*OperationResult, error) {
+	if err := s.driver.Create(); err != nil {
+		return nil, err
+	}
+	return s.operationResult(), nil
 }
 
 // Update implements grpc method
-func (s *GrpcServer) Update(ctx context.Context, in *Empty) (*Empty, error) {
-	return &Empty{}, s.driver.Update()
+func (s *GrpcServer) Update(ctx context.Context, in *Empty) (*OperationResult, error) {
+	if err := s.driver.Update(); err != nil {
+		return nil, err
+	}
+	return s.operationResult(), nil
+}
+
+// Resume implements grpc method, the drivers that can't resume a create run it again
+func (s *GrpcServer) Resume(ctx context.Context, in *Empty) (*OperationResult, error) {
+	resumer, ok := s.driver.(Resumer)
+	if !ok {
+		return s.Create(ctx, in)
+	}
+	if err := resumer.Resume(); err != nil {
+		return nil, err
+	}
+	return s.operationResult(), nil
+}
+
+// operationResult returns the warnings of the last create or update of a driver reporting them
+func (s *GrpcServer) operationResult() *OperationResult {
+	reporter, ok := s.driver.(WarningsReporter)
+	if !ok {
+		return &OperationResult{}
+	}
+	return &OperationResult{Warnings: reporter.Warnings()}
 }
 
 // Get implements grpc method
@@ -93,20 +175,106 @@ func (s *GrpcServer) Remove(ctx context.Context, in *Empty) (*Empty, error) {
 	return &Empty{}, s.driver.Remove()
 }
 
+// GetProtocolVersion implements grpc method, it tells the CLI which version of the rpc protocol the driver speaks
+func (s *GrpcServer) GetProtocolVersion(ctx context.Context, in *Empty) (*ProtocolVersion, error) {
+	if versioner, ok := s.driver.(ProtocolVersioner); ok {
+		return &ProtocolVersion{Version: versioner.ProtocolVersion()}, nil
+	}
+	return &ProtocolVersion{Version: CurrentProtocolVersion}, nil
+}
+
+// GetVersion implements grpc method
+func (s *GrpcServer) GetVersion(ctx context.Context, in *Empty) (*DriverVersion, error) {
+	return s.driver.GetVersion()
+}
+
+// CheckConnectivity implements grpc method
+func (s *GrpcServer) CheckConnectivity(ctx context.Context, in *DriverOptions) (*ConnectivityResult, error) {
+	checker, ok := s.driver.(ConnectivityChecker)
+	if !ok {
+		return &ConnectivityResult{
+			Status:  ConnectivityUnsupported,
+			Message: "the driver doesn't support connectivity checks",
+		}, nil
+	}
+	return checker.CheckConnectivity(in)
+}
+
+// GetClusterStatus implements grpc method
+func (s *GrpcServer) GetClusterStatus(ctx context.Context, in *Empty) (*ClusterStatus, error) {
+	reporter, ok := s.driver.(StatusReporter)
+	if !ok {
+		return &ClusterStatus{
+			Status:  ClusterStatusUnsupported,
+			Message: "the driver doesn't report cluster statuses",
+		}, nil
+	}
+	return reporter.GetClusterStatus()
+}
+
+// Snapshot implements grpc method
+func (s *GrpcServer) Snapshot(ctx context.Context, in *Empty) (*SnapshotResult, error) {
+	snapshotter, ok := s.driver.(Snapshotter)
+	if !ok {
+		return &SnapshotResult{
+			Status:  SnapshotUnsupported,
+			Message: "the driver doesn't support snapshots",
+		}, nil
+	}
+	return snapshotter.Snapshot()
+}
+
+// Restore implements grpc method
+func (s *GrpcServer) Restore(ctx context.Context, in *SnapshotRequest) (*SnapshotResult, error) {
+	restorer, ok := s.driver.(Restorer)
+	if !ok {
+		return &SnapshotResult{
+			Status:  SnapshotUnsupported,
+			Message: "the driver doesn't support restoring snapshots",
+		}, nil
+	}
+	return restorer.Restore(in.Id)
+}
+
 // Serve serves a grpc server
 func (s *GrpcServer) Serve() {
-	listen, err := net.Listen("tcp", listenAddr)
+	s.ServeAt(listenAddr)
+}
+
+// ServeAt serves a grpc server listening on address, e.g. for a driver running as a long-lived service the clients
+// connect to with --driver-addr
+func (s *GrpcServer) ServeAt(address string) {
+	listen, err := net.Listen("tcp", address)
 	if err != nil {
 		logrus.Fatal(err)
 	}
 	addr := listen.Addr().String()
-	s.address <- addr
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(providerErrorInterceptor))
 	RegisterDriverServer(grpcServer, s)
 	reflection.Register(grpcServer)
+	s.lock.Lock()
+	s.server = grpcServer
+	s.lock.Unlock()
+	s.address <- addr
 	logrus.Debugf("RPC GrpcServer listening on address %s", addr)
-	if err := grpcServer.Serve(listen); err != nil {
+	if err := grpcServer.Serve(listen); err != nil && !s.isStopped() {
 		logrus.Fatal(err)
 	}
 	return
 }
+
+// Stop stops the server, the calls in flight are canceled
+func (s *GrpcServer) Stop() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.stopped = true
+	if s.server != nil {
+		s.server.Stop()
+	}
+}
+
+func (s *GrpcServer) isStopped() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.stopped
+}
@@ -1,5 +1,9 @@
 package drivers
 
+// CurrentProtocolVersion is the version of the rpc protocol the drivers of this package speak. It is bumped when a change
+// of the protocol breaks the drivers built against the previous version, the CLI refuses to run a driver speaking another.
+const CurrentProtocolVersion = 1
+
 const (
 	// StringType is the type for string flag
 	StringType = "string"
@@ -11,7 +15,158 @@ const (
 	StringSliceType = "stringSlice"
 )
 
+const (
+	// ConnectivityOK means the driver reached the provider with the given credential
+	ConnectivityOK = "ok"
+	// ConnectivityUnsupported means the driver can't check connectivity
+	ConnectivityUnsupported = "unsupported"
+	// ConnectivityBadCredentials means the provider rejected the credential
+	ConnectivityBadCredentials = "bad-credentials"
+	// ConnectivityNetwork means the provider couldn't be reached
+	ConnectivityNetwork = "network"
+	// ConnectivityQuota means the provider refused the request because of a quota
+	ConnectivityQuota = "quota"
+	// ConnectivityUnknown means the check failed for another reason
+	ConnectivityUnknown = "unknown"
+)
+
+const (
+	// ClusterStatusExists means the provider has the cluster
+	ClusterStatusExists = "exists"
+	// ClusterStatusDeleting means the provider is deleting the cluster
+	ClusterStatusDeleting = "deleting"
+	// ClusterStatusNotFound means the provider has no such cluster
+	ClusterStatusNotFound = "not-found"
+	// ClusterStatusUnsupported means the driver can't report the status of a cluster
+	ClusterStatusUnsupported = "unsupported"
+)
+
+const (
+	// SnapshotOK means the driver took a snapshot of the cluster, its identifier is in the result, or restored it
+	SnapshotOK = "ok"
+	// SnapshotUnsupported means the driver can't take snapshots of its clusters or restore them
+	SnapshotUnsupported = "unsupported"
+)
+
+// SnapshotIDMetadata is the metadata key of the cluster with the identifier of its last snapshot
+const SnapshotIDMetadata = "snapshot-id"
+
+// KubeConfigOutMetadata is the metadata key of the cluster with the path create --kubeconfig-out wrote its kubeconfig to
+const KubeConfigOutMetadata = "kubeconfig-out"
+
+// The codes a driver classifies the failures of its provider with, see NewProviderError
+const (
+	// ErrorQuotaExceeded means the provider refused the request because of a quota or a rate limit, it can be retried later
+	ErrorQuotaExceeded = "quota-exceeded"
+	// ErrorAuthFailed means the provider rejected the credential or its permissions
+	ErrorAuthFailed = "auth-failed"
+	// ErrorConflict means the request conflicts with the current state of the resource, e.g. another operation on it
+	ErrorConflict = "conflict"
+	// ErrorNotFound means the provider has no such resource
+	ErrorNotFound = "not-found"
+	// ErrorInvalid means the provider rejected an option
+	ErrorInvalid = "invalid"
+)
+
+const (
+	// EndpointPublic is the key of the public API endpoint in ClusterInfo.Endpoints
+	EndpointPublic = "public"
+	// EndpointPrivate is the key of the private API endpoint in ClusterInfo.Endpoints
+	EndpointPrivate = "private"
+)
+
+const (
+	// WaitInitialIntervalOption is the option with the first wait between two readiness polls, e.g. 5s
+	WaitInitialIntervalOption = "wait-initial-interval"
+	// WaitMaxIntervalOption is the option capping the wait between two readiness polls
+	WaitMaxIntervalOption = "wait-max-interval"
+	// WaitTimeoutOption is the option with how long to wait for a cluster to be ready
+	WaitTimeoutOption = "wait-timeout"
+)
+
+// CredentialOption is the option with the content of the credential used to call the provider
+const CredentialOption = "credential"
+
+const (
+	// ImpersonateServiceAccountOption is the option with the service account the driver impersonates with the credential
+	// instead of using the credential itself, for the drivers supporting it
+	ImpersonateServiceAccountOption = "impersonate-service-account"
+	// ImpersonateUserOption is the option with the user the driver impersonates with the credential, for the drivers
+	// supporting it
+	ImpersonateUserOption = "impersonate-user"
+)
+
+// KubernetesVersionCanonical is the canonical name a driver gives to its kubernetes version option, create maps
+// --kubernetes-version to the option with this canonical name
+const KubernetesVersionCanonical = "kubernetes-version"
+
+const (
+	// NodeLabelsCanonical is the canonical name of the string slice option with the KEY=VALUE labels of the nodes,
+	// create maps --node-label to it
+	NodeLabelsCanonical = "node-labels"
+	// NodeTaintsCanonical is the canonical name of the string slice option with the KEY[=VALUE]:EFFECT taints of the
+	// nodes, create maps --node-taint to it
+	NodeTaintsCanonical = "node-taints"
+)
+
+const (
+	// NetworkCanonical is the canonical name of the string option with the existing network, or VPC, the cluster is
+	// placed in, create maps --network to it
+	NetworkCanonical = "network"
+	// SubnetCanonical is the canonical name of the string option with the existing subnet of the network the nodes are
+	// placed in, create maps --subnet to it
+	SubnetCanonical = "subnet"
+)
+
+const (
+	// MaintenanceWindowCanonical is the canonical name of the string option with the recurring window, as
+	// [DAYS ]HH:MM-HH:MM in UTC, the provider can run its maintenance in, create maps --maintenance-window to it
+	MaintenanceWindowCanonical = "maintenance-window"
+	// MaintenanceExclusionsCanonical is the canonical name of the string slice option with the START/END periods, in
+	// RFC 3339, the provider must not run its maintenance in, create maps --maintenance-exclusion to it
+	MaintenanceExclusionsCanonical = "maintenance-exclusions"
+)
+
+const (
+	// OIDCIssuerCanonical is the canonical name of the string option with the https URL of the OIDC issuer the API
+	// server trusts the tokens of, create maps --oidc-issuer to it
+	OIDCIssuerCanonical = "oidc-issuer"
+	// OIDCClientIDCanonical is the canonical name of the string option with the client id the tokens of the issuer must
+	// be issued for, create maps --oidc-client-id to it
+	OIDCClientIDCanonical = "oidc-client-id"
+	// OIDCUsernameClaimCanonical is the canonical name of the string option with the claim of the tokens used as the
+	// user name, create maps --oidc-username-claim to it
+	OIDCUsernameClaimCanonical = "oidc-username-claim"
+	// OIDCGroupsClaimCanonical is the canonical name of the string option with the claim of the tokens used as the
+	// groups of the user, create maps --oidc-groups-claim to it
+	OIDCGroupsClaimCanonical = "oidc-groups-claim"
+	// APIAudiencesCanonical is the canonical name of the string slice option with the audiences the API server accepts
+	// the tokens of, e.g. of its service accounts, create maps --api-audience to it
+	APIAudiencesCanonical = "api-audiences"
+)
+
+// AddonCanonicalPrefix prefixes the name of an addon in the canonical name of the bool option enabling it, e.g.
+// addon-dashboard. create maps --addon NAME=on|off to the option with the canonical name AddonCanonical(NAME)
+const AddonCanonicalPrefix = "addon-"
+
+// AddonCanonical returns the canonical name of the bool option enabling the addon called name
+func AddonCanonical(name string) string {
+	return AddonCanonicalPrefix + name
+}
+
+// FeatureCanonicalPrefix prefixes the name of a feature gate of the provider in the canonical name of the bool option
+// turning it on, e.g. feature-kubernetes-alpha. create maps --feature NAME=on|off to the option with the canonical name
+// FeatureCanonical(NAME)
+const FeatureCanonicalPrefix = "feature-"
+
+// FeatureCanonical returns the canonical name of the bool option turning the feature called name on
+func FeatureCanonical(name string) string {
+	return FeatureCanonicalPrefix + name
+}
+
 // RPCServer defines the interface for a rpc server
 type RPCServer interface {
 	Serve()
+	// Stop stops serving, the calls in flight are canceled
+	Stop()
 }
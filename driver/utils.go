@@ -2,6 +2,9 @@ package drivers
 
 import (
 	"fmt"
+	"time"
+
+	"github.com/rancher/kontainer-engine/utils"
 
 	"github.com/rancher/types/apis/management.cattle.io/v3"
 	"gopkg.in/yaml.v2"
@@ -101,3 +104,67 @@ func ConvertToRkeConfig(config string) (v3.RancherKubernetesEngineConfig, error)
 	}
 	return rkeConfig, nil
 }
+
+// BackoffFromOptions returns the readiness polling backoff set by the wait options, the missing options take the default values
+func BackoffFromOptions(driverOptions *DriverOptions) (utils.Backoff, error) {
+	durations := map[string]time.Duration{}
+	for _, option := range []string{WaitInitialIntervalOption, WaitMaxIntervalOption, WaitTimeoutOption} {
+		value := driverOptions.StringOptions[option]
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return utils.Backoff{}, fmt.Errorf("invalid duration %q for %s", value, option)
+		}
+		durations[option] = d
+	}
+	return utils.NewBackoff(durations[WaitInitialIntervalOption], durations[WaitMaxIntervalOption], durations[WaitTimeoutOption]), nil
+}
+
+// CopyDriverOptions returns a deep copy of driverOptions with all the maps set
+func CopyDriverOptions(driverOptions *DriverOptions) *DriverOptions {
+	copied := &DriverOptions{
+		BoolOptions:        map[string]bool{},
+		StringOptions:      map[string]string{},
+		IntOptions:         map[string]int64{},
+		StringSliceOptions: map[string]*StringSlice{},
+	}
+	if driverOptions == nil {
+		return copied
+	}
+	for k, v := range driverOptions.BoolOptions {
+		copied.BoolOptions[k] = v
+	}
+	for k, v := range driverOptions.StringOptions {
+		copied.StringOptions[k] = v
+	}
+	for k, v := range driverOptions.IntOptions {
+		copied.IntOptions[k] = v
+	}
+	for k, v := range driverOptions.StringSliceOptions {
+		if v == nil {
+			copied.StringSliceOptions[k] = nil
+			continue
+		}
+		copied.StringSliceOptions[k] = &StringSlice{Value: append([]string{}, v.Value...)}
+	}
+	for _, nodePool := range driverOptions.NodePools {
+		pool := *nodePool
+		copied.NodePools = append(copied.NodePools, &pool)
+	}
+	copied.SetKeys = append([]string(nil), driverOptions.SetKeys...)
+	copied.SecretKeys = append([]string(nil), driverOptions.SecretKeys...)
+	return copied
+}
+
+// IsSet reports whether the option name was set explicitly, on the command line or in the environment, rather than
+// taking its default value
+func (m *DriverOptions) IsSet(name string) bool {
+	for _, key := range m.SetKeys {
+		if key == name {
+			return true
+		}
+	}
+	return false
+}
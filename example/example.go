@@ -1,46 +1,111 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"time"
 
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
 	"github.com/rancher/kontainer-engine/stub"
 	"github.com/rancher/types/apis/management.cattle.io/v3"
 	"github.com/sirupsen/logrus"
 )
 
 func main() {
-	time.Sleep(time.Second * 2)
-	credentialPath := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
-	data, err := ioutil.ReadFile(credentialPath)
-	if err != nil {
+	driverName := flag.String("driver", "mock", "The driver to provision the cluster with, mock or gke")
+	name := flag.String("name", "example", "The name of the cluster")
+	projectID := flag.String("project-id", "rancher-dev", "The google project to create the cluster in, only used by the gke driver")
+	timeout := flag.Duration("timeout", 30*time.Second, "How long to wait for the drivers to be activated")
+	flag.Parse()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	if err := run(ctx, *driverName, *name, *projectID); err != nil {
 		logrus.Fatal(err)
 	}
-	gkeSpec := &v3.GoogleKubernetesEngineConfig{
-		ProjectID:           "rancher-dev",
-		Zone:                "us-central1-a",
-		NodeCount:           1,
-		KubernetesDashboard: true,
-		HTTPLoadBalancing:   true,
-		ImageType:           "ubuntu",
-		LegacyAbac:          true,
-		Locations:           []string{"us-central1-a", "us-central1-b"},
-		Credential:          string(data),
+}
+
+// run creates a cluster with the given driver, prints how to connect to it and removes it
+func run(ctx context.Context, driverName, name, projectID string) error {
+	if err := stub.WaitForDrivers(ctx); err != nil {
+		return fmt.Errorf("drivers are not activated: %v", err)
 	}
-	spec := v3.ClusterSpec{
-		GoogleKubernetesEngineConfig: gkeSpec,
+
+	var create func() (string, string, string, error)
+	var remove func() error
+	switch driverName {
+	case "mock":
+		// the mock driver keeps the cluster in memory and needs no credentials
+		driverOptions := rpcDriver.DriverOptions{
+			StringOptions:      map[string]string{"description": "created by the kontainer-engine example"},
+			IntOptions:         map[string]int64{"node-count": 1},
+			BoolOptions:        map[string]bool{},
+			StringSliceOptions: map[string]*rpcDriver.StringSlice{},
+		}
+		create = func() (string, string, string, error) {
+			return stub.CreateWithOptions(name, driverName, driverOptions)
+		}
+		remove = func() error {
+			return stub.RemoveWithOptions(name, driverName, driverOptions)
+		}
+	case "gke":
+		// the gke driver reads the service account from GOOGLE_APPLICATION_CREDENTIALS
+		spec, err := gkeSpec(projectID)
+		if err != nil {
+			return err
+		}
+		create = func() (string, string, string, error) {
+			return stub.Create(name, spec)
+		}
+		remove = func() error {
+			return stub.Remove(name, spec)
+		}
+	default:
+		return fmt.Errorf("driver %s is not supported by the example, use mock or gke", driverName)
 	}
-	endpoint, token, cert, err := stub.Create("daishan-test", spec)
+
+	endpoint, token, cert, err := create()
 	if err != nil {
-		logrus.Fatal(err)
+		// the cluster may be partially provisioned, try to clean it up
+		if removeErr := remove(); removeErr != nil {
+			logrus.Errorf("Failed to clean up cluster %s: %v", name, removeErr)
+		}
+		return fmt.Errorf("failed to create cluster %s: %v", name, err)
 	}
 	fmt.Println(endpoint)
 	fmt.Println(token)
 	fmt.Println(cert)
-	err = stub.Remove("daishan-test", spec)
+
+	if err := remove(); err != nil {
+		return fmt.Errorf("failed to remove cluster %s: %v", name, err)
+	}
+	return nil
+}
+
+func gkeSpec(projectID string) (v3.ClusterSpec, error) {
+	credentialPath := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if credentialPath == "" {
+		return v3.ClusterSpec{}, errors.New("GOOGLE_APPLICATION_CREDENTIALS must point to a service account file to use the gke driver")
+	}
+	data, err := ioutil.ReadFile(credentialPath)
 	if err != nil {
-		logrus.Fatal(err)
+		return v3.ClusterSpec{}, err
 	}
+	return v3.ClusterSpec{
+		GoogleKubernetesEngineConfig: &v3.GoogleKubernetesEngineConfig{
+			ProjectID:           projectID,
+			Zone:                "us-central1-a",
+			NodeCount:           1,
+			KubernetesDashboard: true,
+			HTTPLoadBalancing:   true,
+			ImageType:           "ubuntu",
+			LegacyAbac:          true,
+			Locations:           []string{"us-central1-a", "us-central1-b"},
+			Credential:          string(data),
+		},
+	}, nil
 }
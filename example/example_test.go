@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) {
+	check.TestingT(t)
+}
+
+type ExampleTestSuite struct {
+}
+
+var _ = check.Suite(&ExampleTestSuite{})
+
+// TestRunWithMock runs the example end to end with the mock driver, it needs no credentials
+func (s *ExampleTestSuite) TestRunWithMock(c *check.C) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	c.Assert(run(ctx, "mock", "example-test", ""), check.IsNil)
+}
+
+func (s *ExampleTestSuite) TestRunUnknownDriver(c *check.C) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	c.Assert(run(ctx, "foo", "example-test", ""), check.ErrorMatches, "driver foo is not supported by the example.*")
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 
 	"github.com/rancher/kontainer-engine/cmd"
+	"github.com/rancher/kontainer-engine/utils"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
@@ -22,7 +23,7 @@ Options:
   {{range .Flags}}{{if .Hidden}}{{else}}{{.}}
   {{end}}{{end}}{{end}}
 Commands:
-  {{range .Commands}}{{.Name}}{{with .ShortName}}, {{.}}{{end}}{{ "\t" }}{{.Usage}}
+  {{range .VisibleCommands}}{{.Name}}{{with .ShortName}}, {{.}}{{end}}{{ "\t" }}{{.Usage}}
   {{end}}
 Run '{{.Name}} COMMAND --help' for more information on a command.
 `
@@ -43,21 +44,65 @@ func main() {
 	app.Name = "kontainer-engine"
 	app.Version = VERSION
 	app.Usage = "CLI tool for creating and managing kubernetes clusters"
+	output := ""
 	app.Before = func(ctx *cli.Context) error {
+		output = ctx.GlobalString("output")
 		if ctx.GlobalBool("debug") {
 			logrus.SetLevel(logrus.DebugLevel)
 		}
 		logrus.Debugf("kontainer-engine version: %v", VERSION)
+		if err := cmd.SetColor(ctx.GlobalString("color")); err != nil {
+			return err
+		}
+		cmd.SetDriverEnvPrefix(ctx.GlobalString("driver-env-prefix"))
+		if err := cmd.SetStore(ctx); err != nil {
+			return err
+		}
+		if err := cmd.SetAPIClient(VERSION, ctx.GlobalStringSlice("api-header")); err != nil {
+			return err
+		}
+		if err := cmd.SetDriverAddr(ctx.GlobalString("driver-addr")); err != nil {
+			return err
+		}
+		cmd.SetAllowIncompatibleDriver(ctx.GlobalBool("allow-incompatible-driver"))
+		if err := cmd.SetOperationPolicies(ctx); err != nil {
+			return err
+		}
+		if err := cmd.SetNotifier(ctx); err != nil {
+			return err
+		}
+		if bundle := ctx.GlobalString("cloud-ca-bundle"); bundle != "" {
+			return cmd.SetCloudCABundle(bundle)
+		}
 		return nil
 	}
 	app.Author = "Rancher Labs, Inc."
 	app.Commands = []cli.Command{
 		cmd.CreateCommand(),
 		cmd.UpdateCommand(),
+		cmd.RefreshCommand(),
+		cmd.RefreshStatusCommand(),
 		cmd.InspectCommand(),
+		cmd.CompareCommand(),
 		cmd.LsCommand(),
 		cmd.RmCommand(),
+		cmd.SnapshotCommand(),
+		cmd.RestoreCommand(),
 		cmd.EnvCommand(),
+		cmd.KubeConfigCommand(),
+		cmd.MigrateStoreCommand(),
+		cmd.StoreCommand(),
+		cmd.ValidateStoreCommand(),
+		cmd.ApplyCommand(),
+		cmd.ExistsCommand(),
+		cmd.DoctorCommand(),
+		cmd.ConfigCommand(),
+		cmd.CredentialCommand(),
+		cmd.DriversCommand(),
+		cmd.DriverCheckCommand(),
+		cmd.CompleteClustersCommand(),
+		cmd.ServeCommand(),
+		cmd.SelfTestCommand(),
 	}
 	app.Flags = []cli.Flag{
 		cli.BoolFlag{
@@ -68,9 +113,45 @@ func main() {
 			Name:  "plugin-listen-addr",
 			Usage: "The listening address for rpc plugin server",
 		},
+		cli.StringFlag{
+			Name:  "driver-addr",
+			Usage: "The host:port of an already running driver to connect to instead of starting the driver plugin, e.g. a driver running as a service",
+		},
+		cli.BoolFlag{
+			Name:  "allow-incompatible-driver",
+			Usage: "Run a driver speaking another rpc protocol version than this CLI, with a warning, instead of refusing it",
+		},
+		cli.StringFlag{
+			Name:  "cloud-ca-bundle",
+			Usage: "A PEM bundle the drivers use instead of the system CAs to verify the cloud APIs, e.g. behind a TLS-intercepting proxy",
+		},
+		cli.StringSliceFlag{
+			Name:  "api-header",
+			Usage: "A header added to the calls to the API servers of the clusters as NAME=VALUE, e.g. for an API gateway. Repeat the flag for several headers",
+		},
+		cli.StringFlag{
+			Name:  "driver-env-prefix",
+			Usage: "The prefix of the environment variables defaulting the driver options, e.g. KE_DRIVER_NODE_COUNT for --node-count. Empty disables them",
+			Value: cmd.DefaultDriverEnvPrefix,
+		},
+		cli.StringFlag{
+			Name:  "color",
+			Usage: "Color the logs and the table headers: auto when they are written to a terminal, always or never",
+			Value: utils.ColorAuto,
+		},
+		cli.StringFlag{
+			Name:  "output",
+			Usage: "The output format, json prints the results and the errors as json objects on stdout",
+		},
 	}
+	app.Flags = append(app.Flags, cmd.StoreFlags()...)
+	app.Flags = append(app.Flags, cmd.OperationFlags()...)
+	app.Flags = append(app.Flags, cmd.NotifyFlags()...)
 
-	if err := app.Run(os.Args); err != nil {
-		logrus.Fatal(err)
+	err := app.Run(os.Args)
+	cmd.FlushNotifications()
+	if err != nil {
+		cmd.ReportError(output, err)
+		os.Exit(cmd.ExitCode(err))
 	}
 }
@@ -1,8 +1,11 @@
 package plugin
 
 import (
+	"sync"
+
 	rpcDriver "github.com/rancher/kontainer-engine/driver"
 	"github.com/rancher/kontainer-engine/driver/gke"
+	"github.com/rancher/kontainer-engine/driver/mock"
 	"github.com/rancher/kontainer-engine/driver/rke"
 	"github.com/sirupsen/logrus"
 )
@@ -10,9 +13,20 @@ import (
 var (
 	// BuiltInDrivers includes all the buildin supported drivers
 	BuiltInDrivers = map[string]bool{
-		"gke": true,
-		"rke": true,
+		"gke":  true,
+		"rke":  true,
+		"mock": true,
+	}
+	// Descriptions are the short descriptions of the built-in drivers
+	Descriptions = map[string]string{
+		"gke":  "Google Kubernetes Engine clusters",
+		"rke":  "Kubernetes clusters on your own nodes with the Rancher Kubernetes Engine",
+		"mock": "In-memory clusters for testing, no provider or credential needed",
 	}
+
+	// servers are the driver plugins started by Run
+	servers     = []rpcDriver.RPCServer{}
+	serversLock sync.Mutex
 )
 
 // Run starts a driver plugin in a go routine, and send its listen address back to addrChan
@@ -23,11 +37,17 @@ func Run(driverName string, addrChan chan string) error {
 		driver = gke.NewDriver()
 	case "rke":
 		driver = rke.NewDriver()
+	case "mock":
+		driver = mock.NewDriver()
 	default:
 		addrChan <- ""
 	}
 	if BuiltInDrivers[driverName] {
-		go startRPCServer(rpcDriver.NewServer(driver, addrChan))
+		server := rpcDriver.NewServer(driver, addrChan)
+		serversLock.Lock()
+		servers = append(servers, server)
+		serversLock.Unlock()
+		go startRPCServer(server)
 		return nil
 	}
 	logrus.Fatal("driver not supported")
@@ -37,3 +57,13 @@ func Run(driverName string, addrChan chan string) error {
 func startRPCServer(server rpcDriver.RPCServer) {
 	server.Serve()
 }
+
+// Shutdown stops the driver plugins started by Run, the calls in flight are canceled
+func Shutdown() {
+	serversLock.Lock()
+	defer serversLock.Unlock()
+	for _, server := range servers {
+		server.Stop()
+	}
+	servers = nil
+}
@@ -1,16 +1,38 @@
 package store
 
 import (
-	"encoding/json"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/rancher/kontainer-engine/cluster"
 	"github.com/rancher/kontainer-engine/utils"
 )
 
+// ListClusterNames returns the sorted names of the clusters in the disk store without reading their config, for shell
+// completion. The hidden directories and the directories without a config are skipped.
+func ListClusterNames() ([]string, error) {
+	homeDir := filepath.Join(utils.HomeDir(), "clusters")
+	dir, err := ioutil.ReadDir(homeDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	names := []string{}
+	for _, file := range dir {
+		if !file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		if config, err := os.Stat(filepath.Join(homeDir, file.Name(), "config.json")); err != nil || !config.Mode().IsRegular() {
+			continue
+		}
+		names = append(names, file.Name())
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
 // GetAllClusterFromStore retrieves all the cluster info from disk store
 func GetAllClusterFromStore() (map[string]cluster.Cluster, error) {
 	homeDir := filepath.Join(utils.HomeDir(), "clusters")
@@ -29,12 +51,12 @@ func GetAllClusterFromStore() (map[string]cluster.Cluster, error) {
 			}
 			for _, subFile := range subDir {
 				if !subFile.IsDir() && strings.HasSuffix(subFile.Name(), "config.json") {
-					cls := cluster.Cluster{}
 					data, err := ioutil.ReadFile(filepath.Join(homeDir, file.Name(), subFile.Name()))
 					if err != nil {
 						return nil, err
 					}
-					if err := json.Unmarshal(data, &cls); err != nil {
+					cls, _, err := cluster.Migrate(data)
+					if err != nil {
 						return nil, err
 					}
 					clusters[cls.Name] = cls
@@ -0,0 +1,125 @@
+package stub
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+
+	"github.com/rancher/kontainer-engine/cluster"
+	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/rancher/types/apis/management.cattle.io/v3"
+)
+
+var (
+	// reconciled are the clusters created or updated by CreateOrUpdate with the hash of their last applied spec
+	reconciled     = map[string]reconciledCluster{}
+	reconciledLock sync.Mutex
+	// createCluster and updateCluster call the driver, tests replace them to count the calls
+	createCluster = (*cluster.Cluster).Create
+	updateCluster = (*cluster.Cluster).Update
+)
+
+type reconciledCluster struct {
+	cluster  cluster.Cluster
+	specHash string
+}
+
+// CreateOrUpdate makes the cluster match clusterSpec, creating it if CreateOrUpdate didn't create it yet and updating it
+// if the spec changed since the last call. It returns the info of the cluster either way, without calling the driver
+// when the spec is unchanged, so a controller can call it on every reconcile. The calls for one cluster must not run
+// concurrently. The clusters are remembered by the process, the first call after a restart creates the cluster again
+// which the drivers handle as for Create.
+func CreateOrUpdate(name string, clusterSpec v3.ClusterSpec) (rpcDriver.ClusterInfo, error) {
+	hash, err := specHash(clusterSpec)
+	if err != nil {
+		return rpcDriver.ClusterInfo{}, err
+	}
+	cls, err := convertCluster(name, clusterSpec)
+	if err != nil {
+		return rpcDriver.ClusterInfo{}, err
+	}
+	return createOrUpdate(cls, hash)
+}
+
+// CreateOrUpdateWithOptions is CreateOrUpdate for any built-in driver, the options are passed to the driver as they are
+func CreateOrUpdateWithOptions(name, driverName string, driverOptions rpcDriver.DriverOptions) (rpcDriver.ClusterInfo, error) {
+	// the config getter adds the name, hash a copy with it so that passing the same options again is a no-op
+	options := rpcDriver.CopyDriverOptions(&driverOptions)
+	options.StringOptions["name"] = name
+	hash, err := specHash(options)
+	if err != nil {
+		return rpcDriver.ClusterInfo{}, err
+	}
+	cls, err := newCluster(name, driverName, optionsConfigGetter{*options, name})
+	if err != nil {
+		return rpcDriver.ClusterInfo{}, err
+	}
+	return createOrUpdate(cls, hash)
+}
+
+func createOrUpdate(cls cluster.Cluster, hash string) (rpcDriver.ClusterInfo, error) {
+	reconciledLock.Lock()
+	last, ok := reconciled[cls.Name]
+	reconciledLock.Unlock()
+
+	switch {
+	case !ok:
+		if err := createCluster(&cls); err != nil {
+			return rpcDriver.ClusterInfo{}, err
+		}
+	case last.specHash == hash:
+		return clusterInfo(last.cluster), nil
+	default:
+		// the driver finds the cluster with the metadata it reported when it was created
+		updated := last.cluster
+		updated.Driver = cls.Driver
+		updated.ConfigGetter = cls.ConfigGetter
+		updated.PersistStore = cls.PersistStore
+		if err := updateCluster(&updated); err != nil {
+			// the spec is applied again on the next call
+			return rpcDriver.ClusterInfo{}, err
+		}
+		cls = updated
+	}
+
+	reconciledLock.Lock()
+	reconciled[cls.Name] = reconciledCluster{cluster: cls, specHash: hash}
+	reconciledLock.Unlock()
+	return clusterInfo(cls), nil
+}
+
+// forgetReconciled makes the next CreateOrUpdate of the cluster create it again, e.g. once it is removed
+func forgetReconciled(name string) {
+	reconciledLock.Lock()
+	defer reconciledLock.Unlock()
+	delete(reconciled, name)
+}
+
+// specHash hashes the json of spec, encoding/json sorts the map keys so equal specs have the same hash
+func specHash(spec interface{}) (string, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// clusterInfo returns the info the driver reported for cls
+func clusterInfo(cls cluster.Cluster) rpcDriver.ClusterInfo {
+	return rpcDriver.ClusterInfo{
+		Version:             cls.Version,
+		ServiceAccountToken: cls.ServiceAccountToken,
+		Endpoint:            cls.Endpoint,
+		Username:            cls.Username,
+		Password:            cls.Password,
+		RootCaCertificate:   cls.RootCACert,
+		ClientCertificate:   cls.ClientCertificate,
+		ClientKey:           cls.ClientKey,
+		NodeCount:           cls.NodeCount,
+		Metadata:            cls.Metadata,
+		Endpoints:           cls.Endpoints,
+		ProviderMetadata:    cls.ProviderMetadata,
+	}
+}
@@ -4,6 +4,7 @@ Package stub can only be imported if it is running as a library. The init functi
 package stub
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -11,6 +12,7 @@ import (
 	"github.com/rancher/kontainer-engine/cluster"
 	rpcDriver "github.com/rancher/kontainer-engine/driver"
 	"github.com/rancher/kontainer-engine/plugin"
+	"github.com/rancher/kontainer-engine/utils"
 	"github.com/rancher/types/apis/management.cattle.io/v3"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
@@ -18,6 +20,10 @@ import (
 
 var (
 	pluginAddress = map[string]string{}
+	// driversActivated is closed once all the driver plugins are listening
+	driversActivated = make(chan struct{})
+	// clock is given to every cluster of the stub, tests replace it with a fake one
+	clock utils.Clock = utils.RealClock{}
 )
 
 func init() {
@@ -30,9 +36,20 @@ func init() {
 			pluginAddress[driver] = listenAddr
 			logrus.Infof("Activating driver %s done", driver)
 		}
+		close(driversActivated)
 	}()
 }
 
+// WaitForDrivers blocks until all the driver plugins are activated or ctx is done
+func WaitForDrivers(ctx context.Context) error {
+	select {
+	case <-driversActivated:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 type controllerConfigGetter struct {
 	driverName  string
 	clusterSpec v3.ClusterSpec
@@ -94,11 +111,22 @@ func flatten(data map[string]interface{}, driverOptions *rpcDriver.DriverOptions
 	}
 }
 
+// optionsConfigGetter passes driver options to the driver as they are
+type optionsConfigGetter struct {
+	driverOptions rpcDriver.DriverOptions
+	clusterName   string
+}
+
+func (o optionsConfigGetter) GetConfig() (rpcDriver.DriverOptions, error) {
+	o.driverOptions.StringOptions["name"] = o.clusterName
+	return o.driverOptions, nil
+}
+
 type controllerPersistStore struct{}
 
 // no-op
-func (c controllerPersistStore) Check(name string) (bool, error) {
-	return false, nil
+func (c controllerPersistStore) Check(name string) (cluster.State, error) {
+	return cluster.StateNotFound, nil
 }
 
 // no-op
@@ -154,20 +182,38 @@ func convertCluster(name string, spec v3.ClusterSpec) (cluster.Cluster, error) {
 	if driverName == "" {
 		return cluster.Cluster{}, fmt.Errorf("no driver config found")
 	}
-	pluginAddr := pluginAddress[driverName]
 	configGetter := controllerConfigGetter{
 		driverName:  driverName,
 		clusterSpec: spec,
 		clusterName: name,
 	}
+	return newCluster(name, driverName, configGetter)
+}
+
+func newCluster(name, driverName string, configGetter cluster.ConfigGetter) (cluster.Cluster, error) {
+	<-driversActivated
+	pluginAddr, ok := pluginAddress[driverName]
+	if !ok {
+		return cluster.Cluster{}, fmt.Errorf("driver %s is not supported", driverName)
+	}
 	persistStore := controllerPersistStore{}
 	clusterPlugin, err := cluster.NewCluster(driverName, pluginAddr, name, configGetter, persistStore)
 	if err != nil {
 		return cluster.Cluster{}, err
 	}
+	clusterPlugin.Clock = clock
 	return *clusterPlugin, nil
 }
 
+// connectionInfo returns the endpoint, token and ca cert of a cluster
+func connectionInfo(cls cluster.Cluster) (string, string, string) {
+	endpoint := cls.Endpoint
+	if !strings.HasPrefix(endpoint, "https://") {
+		endpoint = fmt.Sprintf("https://%s", cls.Endpoint)
+	}
+	return endpoint, cls.ServiceAccountToken, cls.RootCACert
+}
+
 // Create creates the stub for cluster manager to call
 func Create(name string, clusterSpec v3.ClusterSpec) (string, string, string, error) {
 	cls, err := convertCluster(name, clusterSpec)
@@ -177,11 +223,8 @@ func Create(name string, clusterSpec v3.ClusterSpec) (string, string, string, er
 	if err := cls.Create(); err != nil {
 		return "", "", "", err
 	}
-	endpoint := cls.Endpoint
-	if !strings.HasPrefix(endpoint, "https://") {
-		endpoint = fmt.Sprintf("https://%s", cls.Endpoint)
-	}
-	return endpoint, cls.ServiceAccountToken, cls.RootCACert, nil
+	endpoint, token, cert := connectionInfo(cls)
+	return endpoint, token, cert, nil
 }
 
 // Update creates the stub for cluster manager to call
@@ -193,11 +236,8 @@ func Update(name string, clusterSpec v3.ClusterSpec) (string, string, string, er
 	if err := cls.Update(); err != nil {
 		return "", "", "", err
 	}
-	endpoint := cls.Endpoint
-	if !strings.HasPrefix(endpoint, "https://") {
-		endpoint = fmt.Sprintf("https://%s", cls.Endpoint)
-	}
-	return endpoint, cls.ServiceAccountToken, cls.RootCACert, nil
+	endpoint, token, cert := connectionInfo(cls)
+	return endpoint, token, cert, nil
 }
 
 // Remove removes stub for cluster manager to call
@@ -206,5 +246,46 @@ func Remove(name string, clusterSpec v3.ClusterSpec) error {
 	if err != nil {
 		return err
 	}
-	return cls.Remove()
+	if err := cls.Remove(); err != nil {
+		return err
+	}
+	forgetReconciled(name)
+	return nil
+}
+
+// CreateWithOptions creates a cluster with any built-in driver, including the ones that have no config in ClusterSpec.
+// The options are passed to the driver as they are.
+func CreateWithOptions(name, driverName string, driverOptions rpcDriver.DriverOptions) (string, string, string, error) {
+	cls, err := newCluster(name, driverName, optionsConfigGetter{driverOptions, name})
+	if err != nil {
+		return "", "", "", err
+	}
+	if err := cls.Create(); err != nil {
+		return "", "", "", err
+	}
+	endpoint, token, cert := connectionInfo(cls)
+	return endpoint, token, cert, nil
+}
+
+// RemoveWithOptions removes a cluster created by CreateWithOptions
+func RemoveWithOptions(name, driverName string, driverOptions rpcDriver.DriverOptions) error {
+	cls, err := newCluster(name, driverName, optionsConfigGetter{driverOptions, name})
+	if err != nil {
+		return err
+	}
+	if err := cls.Remove(); err != nil {
+		return err
+	}
+	forgetReconciled(name)
+	return nil
+}
+
+// VerifyConnectivity checks that the API server at endpoint accepts token, trusting cert, the base64 encoded CA
+// certificate. These are the values returned by Create. The error is a *cluster.ConnectivityError.
+func VerifyConnectivity(endpoint, token, cert string) error {
+	return cluster.VerifyConnectivity(&cluster.Cluster{
+		Endpoint:            endpoint,
+		ServiceAccountToken: token,
+		RootCACert:          cert,
+	})
 }
@@ -3,8 +3,11 @@ package stub
 import (
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/rancher/kontainer-engine/cluster"
 	rpcDriver "github.com/rancher/kontainer-engine/driver"
+	"github.com/rancher/kontainer-engine/utils"
 	"github.com/rancher/types/apis/management.cattle.io/v3"
 	"gopkg.in/check.v1"
 )
@@ -70,3 +73,110 @@ func (s *StubTestSuite) TestFlatten(c *check.C) {
 	c.Assert(driverOptions.StringOptions, check.DeepEquals, stringResult)
 	c.Assert(driverOptions.StringSliceOptions["labels"].Value, check.DeepEquals, stringSliceResult["labels"].Value)
 }
+
+func (s *StubTestSuite) TestCreatedAtUsesClock(c *check.C) {
+	oldClock := clock
+	defer func() { clock = oldClock }()
+	clock = utils.NewFakeClock(time.Date(2017, 12, 1, 10, 0, 0, 0, time.FixedZone("CET", 3600)))
+
+	driverOptions := rpcDriver.DriverOptions{
+		BoolOptions:        make(map[string]bool),
+		StringOptions:      make(map[string]string),
+		IntOptions:         map[string]int64{"node-count": 1},
+		StringSliceOptions: make(map[string]*rpcDriver.StringSlice),
+	}
+	cls, err := newCluster("clock", "mock", optionsConfigGetter{driverOptions, "clock"})
+	c.Assert(err, check.IsNil)
+	c.Assert(cls.Create(), check.IsNil)
+	defer cls.Remove()
+	c.Assert(cls.CreatedAt, check.Equals, "2017-12-01T09:00:00Z")
+}
+
+func mockOptions(nodeCount int64) rpcDriver.DriverOptions {
+	return rpcDriver.DriverOptions{
+		BoolOptions:        make(map[string]bool),
+		StringOptions:      map[string]string{"description": "reconciled"},
+		IntOptions:         map[string]int64{"node-count": nodeCount},
+		StringSliceOptions: make(map[string]*rpcDriver.StringSlice),
+	}
+}
+
+// countDriverCalls counts the creates and updates of CreateOrUpdate until the returned func restores them
+func countDriverCalls(creates, updates *int) func() {
+	oldCreate, oldUpdate := createCluster, updateCluster
+	createCluster = func(cls *cluster.Cluster) error {
+		*creates++
+		return oldCreate(cls)
+	}
+	updateCluster = func(cls *cluster.Cluster) error {
+		*updates++
+		return oldUpdate(cls)
+	}
+	return func() {
+		createCluster, updateCluster = oldCreate, oldUpdate
+	}
+}
+
+func (s *StubTestSuite) TestCreateOrUpdate(c *check.C) {
+	creates, updates := 0, 0
+	defer countDriverCalls(&creates, &updates)()
+	defer RemoveWithOptions("reconcile", "mock", mockOptions(0))
+
+	info, err := CreateOrUpdateWithOptions("reconcile", "mock", mockOptions(3))
+	c.Assert(err, check.IsNil)
+	c.Assert(creates, check.Equals, 1)
+	c.Assert(info.Endpoint, check.Equals, "reconcile.mock.local")
+	c.Assert(info.NodeCount, check.Equals, int64(3))
+	c.Assert(info.Metadata["description"], check.Equals, "reconciled")
+
+	// the same spec again doesn't call the driver and returns the same info
+	again, err := CreateOrUpdateWithOptions("reconcile", "mock", mockOptions(3))
+	c.Assert(err, check.IsNil)
+	c.Assert(creates, check.Equals, 1)
+	c.Assert(updates, check.Equals, 0)
+	c.Assert(again, check.DeepEquals, info)
+
+	// a changed spec updates the cluster
+	info, err = CreateOrUpdateWithOptions("reconcile", "mock", mockOptions(5))
+	c.Assert(err, check.IsNil)
+	c.Assert(creates, check.Equals, 1)
+	c.Assert(updates, check.Equals, 1)
+	c.Assert(info.NodeCount, check.Equals, int64(5))
+	c.Assert(info.Endpoint, check.Equals, "reconcile.mock.local")
+
+	// and the updated spec is the new no-op
+	_, err = CreateOrUpdateWithOptions("reconcile", "mock", mockOptions(5))
+	c.Assert(err, check.IsNil)
+	c.Assert(updates, check.Equals, 1)
+}
+
+func (s *StubTestSuite) TestCreateOrUpdateAfterRemove(c *check.C) {
+	creates, updates := 0, 0
+	defer countDriverCalls(&creates, &updates)()
+
+	_, err := CreateOrUpdateWithOptions("recreated", "mock", mockOptions(1))
+	c.Assert(err, check.IsNil)
+	c.Assert(RemoveWithOptions("recreated", "mock", mockOptions(1)), check.IsNil)
+	_, err = CreateOrUpdateWithOptions("recreated", "mock", mockOptions(1))
+	c.Assert(err, check.IsNil)
+	defer RemoveWithOptions("recreated", "mock", mockOptions(1))
+	c.Assert(creates, check.Equals, 2)
+	c.Assert(updates, check.Equals, 0)
+}
+
+func (s *StubTestSuite) TestSpecHash(c *check.C) {
+	first, err := specHash(v3.ClusterSpec{GoogleKubernetesEngineConfig: &v3.GoogleKubernetesEngineConfig{
+		ProjectID: "test",
+		Labels:    map[string]string{"a": "1", "b": "2", "c": "3"},
+	}})
+	c.Assert(err, check.IsNil)
+	second, err := specHash(v3.ClusterSpec{GoogleKubernetesEngineConfig: &v3.GoogleKubernetesEngineConfig{
+		Labels:    map[string]string{"c": "3", "b": "2", "a": "1"},
+		ProjectID: "test",
+	}})
+	c.Assert(err, check.IsNil)
+	c.Assert(first, check.Equals, second)
+	changed, err := specHash(v3.ClusterSpec{GoogleKubernetesEngineConfig: &v3.GoogleKubernetesEngineConfig{ProjectID: "other"}})
+	c.Assert(err, check.IsNil)
+	c.Assert(changed, check.Not(check.Equals), first)
+}
@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+const (
+	// DefaultPollInitialInterval is the first wait between two polls
+	DefaultPollInitialInterval = 5 * time.Second
+	// DefaultPollMaxInterval caps the wait between two polls
+	DefaultPollMaxInterval = time.Minute
+	// DefaultPollTimeout is how long to poll before giving up
+	DefaultPollTimeout = time.Hour
+)
+
+// Backoff controls how PollWithBackoff waits between two polls. The wait starts at InitialInterval, grows by Factor
+// after every poll up to MaxInterval and is randomized by +-Jitter of its value so that many pollers don't hit the API together.
+type Backoff struct {
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Factor          float64
+	Jitter          float64
+	// Timeout is the total time to poll, 0 means no limit
+	Timeout time.Duration
+	// Clock measures the timeout and waits between two polls, nil means the wall clock
+	Clock Clock
+
+	random func() float64
+}
+
+// NewBackoff returns a backoff doubling the wait with 20% jitter.
+// Zero durations take the default values.
+func NewBackoff(initialInterval, maxInterval, timeout time.Duration) Backoff {
+	if initialInterval <= 0 {
+		initialInterval = DefaultPollInitialInterval
+	}
+	if maxInterval <= 0 {
+		maxInterval = DefaultPollMaxInterval
+	}
+	if maxInterval < initialInterval {
+		maxInterval = initialInterval
+	}
+	if timeout <= 0 {
+		timeout = DefaultPollTimeout
+	}
+	return Backoff{
+		InitialInterval: initialInterval,
+		MaxInterval:     maxInterval,
+		Factor:          2,
+		Jitter:          0.2,
+		Timeout:         timeout,
+	}
+}
+
+// PollWithBackoff calls condition until it returns true or an error, waiting longer and longer between two calls.
+// It returns an error if condition isn't true before the timeout.
+func PollWithBackoff(b Backoff, condition func() (bool, error)) error {
+	clock, random := b.Clock, b.random
+	if clock == nil {
+		clock = RealClock{}
+	}
+	if random == nil {
+		random = rand.Float64
+	}
+
+	start := clock.Now()
+	interval := b.InitialInterval
+	for {
+		done, err := condition()
+		if err != nil || done {
+			return err
+		}
+
+		wait := time.Duration(float64(interval) * (1 + b.Jitter*(2*random()-1)))
+		if wait > b.MaxInterval {
+			wait = b.MaxInterval
+		}
+		if b.Timeout > 0 {
+			remaining := b.Timeout - clock.Now().Sub(start)
+			if remaining <= 0 {
+				return fmt.Errorf("timed out after %v", b.Timeout)
+			}
+			if wait > remaining {
+				wait = remaining
+			}
+		}
+		clock.Sleep(wait)
+
+		interval = time.Duration(float64(interval) * b.Factor)
+		if interval > b.MaxInterval {
+			interval = b.MaxInterval
+		}
+	}
+}
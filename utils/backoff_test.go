@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) {
+	check.TestingT(t)
+}
+
+type BackoffTestSuite struct {
+}
+
+var _ = check.Suite(&BackoffTestSuite{})
+
+func newTestBackoff(clock *FakeClock, random float64) Backoff {
+	b := NewBackoff(time.Second, 10*time.Second, time.Minute)
+	b.Clock = clock
+	b.random = func() float64 { return random }
+	return b
+}
+
+func (s *BackoffTestSuite) TestBackoffSequence(c *check.C) {
+	clock := NewFakeClock(time.Time{})
+	polls := 0
+	err := PollWithBackoff(newTestBackoff(clock, 0.5), func() (bool, error) {
+		polls++
+		return polls == 6, nil
+	})
+	c.Assert(err, check.IsNil)
+	// no jitter with random 0.5, the wait doubles up to the cap
+	c.Assert(clock.Sleeps(), check.DeepEquals, []time.Duration{
+		time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second,
+	})
+}
+
+func (s *BackoffTestSuite) TestBackoffJitterRespectsCap(c *check.C) {
+	for _, random := range []float64{0, 0.99} {
+		clock := NewFakeClock(time.Time{})
+		polls := 0
+		err := PollWithBackoff(newTestBackoff(clock, random), func() (bool, error) {
+			polls++
+			return polls == 10, nil
+		})
+		c.Assert(err, check.IsNil)
+		for i, sleep := range clock.Sleeps() {
+			c.Assert(sleep <= 10*time.Second, check.Equals, true, check.Commentf("sleep %d is %v", i, sleep))
+		}
+		// the first wait is randomized by at most 20%
+		c.Assert(clock.Sleeps()[0] >= 800*time.Millisecond && clock.Sleeps()[0] <= 1200*time.Millisecond, check.Equals, true)
+	}
+}
+
+func (s *BackoffTestSuite) TestBackoffDeadline(c *check.C) {
+	clock := NewFakeClock(time.Time{})
+	err := PollWithBackoff(newTestBackoff(clock, 0.99), func() (bool, error) {
+		return false, nil
+	})
+	c.Assert(err, check.ErrorMatches, "timed out after 1m0s")
+	total := time.Duration(0)
+	for _, sleep := range clock.Sleeps() {
+		total += sleep
+	}
+	c.Assert(total, check.Equals, time.Minute)
+}
+
+func (s *BackoffTestSuite) TestBackoffConditionError(c *check.C) {
+	clock := NewFakeClock(time.Time{})
+	err := PollWithBackoff(newTestBackoff(clock, 0.5), func() (bool, error) {
+		return false, errors.New("cluster is broken")
+	})
+	c.Assert(err, check.ErrorMatches, "cluster is broken")
+	c.Assert(clock.Sleeps(), check.HasLen, 0)
+}
+
+func (s *BackoffTestSuite) TestNewBackoffDefaults(c *check.C) {
+	b := NewBackoff(0, 0, 0)
+	c.Assert(b.InitialInterval, check.Equals, DefaultPollInitialInterval)
+	c.Assert(b.MaxInterval, check.Equals, DefaultPollMaxInterval)
+	c.Assert(b.Timeout, check.Equals, DefaultPollTimeout)
+}
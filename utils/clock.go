@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the source of time of everything that timestamps, times out or waits, so that tests can control it
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	Sleep(d time.Duration)
+}
+
+// RealClock is the wall clock
+type RealClock struct{}
+
+// Now returns the current local time
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+// After waits for the duration to elapse and then sends the current time on the returned channel
+func (RealClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// Sleep pauses the current goroutine for at least the duration d
+func (RealClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// FakeClock is a clock for tests that only moves when Advance or Sleep is called
+type FakeClock struct {
+	lock    sync.Mutex
+	current time.Time
+	waiters []fakeWaiter
+	sleeps  []time.Duration
+}
+
+type fakeWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+// NewFakeClock returns a fake clock set to t
+func NewFakeClock(t time.Time) *FakeClock {
+	return &FakeClock{current: t}
+}
+
+// Now returns the time of the fake clock
+func (f *FakeClock) Now() time.Time {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.current
+}
+
+// After returns a channel that receives the time once the clock is advanced by d
+func (f *FakeClock) After(d time.Duration) <-chan time.Time {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- f.current
+		return ch
+	}
+	f.waiters = append(f.waiters, fakeWaiter{deadline: f.current.Add(d), ch: ch})
+	return ch
+}
+
+// Sleep records d and advances the clock by it instead of blocking
+func (f *FakeClock) Sleep(d time.Duration) {
+	f.lock.Lock()
+	f.sleeps = append(f.sleeps, d)
+	f.lock.Unlock()
+	f.Advance(d)
+}
+
+// Sleeps returns the durations passed to Sleep so far
+func (f *FakeClock) Sleeps() []time.Duration {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]time.Duration{}, f.sleeps...)
+}
+
+// Advance moves the clock forward by d and fires the After channels whose deadline is reached
+func (f *FakeClock) Advance(d time.Duration) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.current = f.current.Add(d)
+	waiters := f.waiters[:0]
+	for _, waiter := range f.waiters {
+		if waiter.deadline.After(f.current) {
+			waiters = append(waiters, waiter)
+			continue
+		}
+		waiter.ch <- f.current
+	}
+	f.waiters = waiters
+}
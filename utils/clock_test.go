@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+type ClockTestSuite struct {
+}
+
+var _ = check.Suite(&ClockTestSuite{})
+
+func (s *ClockTestSuite) TestFakeClockAfter(c *check.C) {
+	start := time.Date(2017, 12, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	soon := clock.After(time.Second)
+	later := clock.After(time.Minute)
+
+	clock.Advance(30 * time.Second)
+	select {
+	case t := <-soon:
+		c.Assert(t, check.Equals, start.Add(30*time.Second))
+	default:
+		c.Fatal("the channel of a reached deadline didn't fire")
+	}
+	select {
+	case <-later:
+		c.Fatal("the channel fired before its deadline")
+	default:
+	}
+
+	clock.Sleep(30 * time.Second)
+	c.Assert(clock.Now(), check.Equals, start.Add(time.Minute))
+	c.Assert(clock.Sleeps(), check.DeepEquals, []time.Duration{30 * time.Second})
+	select {
+	case <-later:
+	default:
+		c.Fatal("sleeping didn't fire the channel of a reached deadline")
+	}
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const (
+	// ColorAuto colors the output written to a terminal only
+	ColorAuto = "auto"
+	// ColorAlways colors the output even in a pipe or a file
+	ColorAlways = "always"
+	// ColorNever never colors the output
+	ColorNever = "never"
+
+	boldStart = "\x1b[1m"
+	colorEnd  = "\x1b[0m"
+)
+
+// ValidateColorMode returns an error if mode isn't auto, always or never. Empty is auto
+func ValidateColorMode(mode string) error {
+	switch mode {
+	case "", ColorAuto, ColorAlways, ColorNever:
+		return nil
+	}
+	return fmt.Errorf("invalid color mode %q, use %s, %s or %s", mode, ColorAuto, ColorAlways, ColorNever)
+}
+
+// UseColor tells whether the output written to w is colored with mode. auto, or empty, colors it when w is a terminal
+func UseColor(mode string, w io.Writer) bool {
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	return IsTerminal(w)
+}
+
+// IsTerminal tells whether w is a file opened on a terminal
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && terminal.IsTerminal(int(f.Fd()))
+}
+
+// headerColorWriter writes the first line in bold, it is below the tabwriter so that the escape codes don't count in the
+// width of the columns
+type headerColorWriter struct {
+	out io.Writer
+	// header buffers the first line until it is complete, the tabwriter writes it cell by cell
+	header bytes.Buffer
+	done   bool
+}
+
+func (w *headerColorWriter) Write(p []byte) (int, error) {
+	if w.done {
+		return w.out.Write(p)
+	}
+	w.header.Write(p)
+	line := w.header.Bytes()
+	i := bytes.IndexByte(line, '\n')
+	if i < 0 {
+		return len(p), nil
+	}
+	w.done = true
+	buf := bytes.Buffer{}
+	buf.WriteString(boldStart)
+	buf.Write(line[:i])
+	buf.WriteString(colorEnd)
+	buf.Write(line[i:])
+	if _, err := w.out.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
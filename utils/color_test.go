@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"bytes"
+	"os"
+	"text/tabwriter"
+
+	"gopkg.in/check.v1"
+)
+
+type ColorTestSuite struct{}
+
+var _ = check.Suite(&ColorTestSuite{})
+
+func (s *ColorTestSuite) TestNoColorWithoutTerminal(c *check.C) {
+	r, w, err := os.Pipe()
+	c.Assert(err, check.IsNil)
+	defer r.Close()
+	defer w.Close()
+	c.Assert(IsTerminal(w), check.Equals, false)
+	c.Assert(IsTerminal(&bytes.Buffer{}), check.Equals, false)
+	c.Assert(UseColor(ColorAuto, w), check.Equals, false)
+	c.Assert(UseColor("", w), check.Equals, false)
+	c.Assert(UseColor(ColorNever, w), check.Equals, false)
+	c.Assert(UseColor(ColorAlways, w), check.Equals, true)
+}
+
+func (s *ColorTestSuite) TestValidateColorMode(c *check.C) {
+	for _, mode := range []string{"", ColorAuto, ColorAlways, ColorNever} {
+		c.Assert(ValidateColorMode(mode), check.IsNil)
+	}
+	c.Assert(ValidateColorMode("yes"), check.ErrorMatches, `invalid color mode "yes", use auto, always or never`)
+}
+
+func (s *ColorTestSuite) TestHeaderColorWriter(c *check.C) {
+	buf := bytes.Buffer{}
+	writer := tabwriter.NewWriter(&headerColorWriter{out: &buf}, 10, 1, 3, ' ', 0)
+	header, value := SimpleFormat([][]string{{"NAME", "Name"}, {"DRIVER", "Driver"}})
+	c.Assert(printTemplate(writer, header, struct{}{}), check.IsNil)
+	c.Assert(printTemplate(writer, value, struct{ Name, Driver string }{"foo", "mock"}), check.IsNil)
+	c.Assert(writer.Flush(), check.IsNil)
+	// the escape codes don't shift the columns
+	c.Assert(buf.String(), check.Equals, boldStart+"NAME      DRIVER"+colorEnd+"\nfoo       mock\n")
+}
@@ -0,0 +1,252 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// patchOperation is an operation of a JSON patch, RFC 6902
+type patchOperation struct {
+	Op    string           `json:"op"`
+	Path  *string          `json:"path"`
+	From  *string          `json:"from"`
+	Value *json.RawMessage `json:"value"`
+}
+
+// ApplyJSONPatch applies the JSON patch, RFC 6902, to the JSON document doc and returns the patched document. The
+// operations are applied in order and the first failing one fails the whole patch.
+func ApplyJSONPatch(doc, patch []byte) ([]byte, error) {
+	operations := []patchOperation{}
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, fmt.Errorf("invalid JSON patch: %v", err)
+	}
+	var root interface{}
+	if err := json.Unmarshal(doc, &root); err != nil {
+		return nil, err
+	}
+	for i, operation := range operations {
+		var err error
+		if root, err = applyOperation(root, operation); err != nil {
+			return nil, fmt.Errorf("operation %d of the JSON patch: %v", i, err)
+		}
+	}
+	return json.Marshal(root)
+}
+
+func applyOperation(root interface{}, operation patchOperation) (interface{}, error) {
+	if operation.Path == nil {
+		return nil, fmt.Errorf("%s has no path", operation.Op)
+	}
+	path, err := parsePointer(*operation.Path)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	switch operation.Op {
+	case "add", "replace", "test":
+		if operation.Value == nil {
+			return nil, fmt.Errorf("%s %s has no value", operation.Op, *operation.Path)
+		}
+		if err := json.Unmarshal(*operation.Value, &value); err != nil {
+			return nil, err
+		}
+	case "move", "copy":
+		if operation.From == nil {
+			return nil, fmt.Errorf("%s %s has no from", operation.Op, *operation.Path)
+		}
+		from, err := parsePointer(*operation.From)
+		if err != nil {
+			return nil, err
+		}
+		if value, err = getPointer(root, from); err != nil {
+			return nil, err
+		}
+		value = deepCopyJSON(value)
+		if operation.Op == "move" {
+			if *operation.Path != *operation.From && strings.HasPrefix(*operation.Path+"/", *operation.From+"/") {
+				return nil, fmt.Errorf("%s can't be moved into itself", *operation.From)
+			}
+			if root, err = removePointer(root, from); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	switch operation.Op {
+	case "add", "move", "copy":
+		return addPointer(root, path, value)
+	case "remove":
+		return removePointer(root, path)
+	case "replace":
+		if _, err := getPointer(root, path); err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return value, nil
+		}
+		if root, err = removePointer(root, path); err != nil {
+			return nil, err
+		}
+		return addPointer(root, path, value)
+	case "test":
+		current, err := getPointer(root, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(current, value) {
+			return nil, fmt.Errorf("test %s failed, the value is %v", *operation.Path, current)
+		}
+		return root, nil
+	}
+	return nil, fmt.Errorf("unknown operation %q", operation.Op)
+}
+
+// parsePointer splits a JSON pointer, RFC 6901, in its unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid path %q, it must start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+// arrayIndex parses the token of an array of length n, end is allowed to be n, e.g. for "-" when adding
+func arrayIndex(token string, n int, end bool) (int, error) {
+	if end && token == "-" {
+		return n, nil
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index > n || (index == n && !end) || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid index %q of an array of %d elements", token, n)
+	}
+	return index, nil
+}
+
+func getPointer(node interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch typed := node.(type) {
+		case map[string]interface{}:
+			value, ok := typed[token]
+			if !ok {
+				return nil, fmt.Errorf("%s doesn't exist", token)
+			}
+			node = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(typed), false)
+			if err != nil {
+				return nil, err
+			}
+			node = typed[index]
+		default:
+			return nil, fmt.Errorf("%s doesn't exist, its parent is neither an object nor an array", token)
+		}
+	}
+	return node, nil
+}
+
+// addPointer adds value at path in node and returns node, which is value itself for the root path
+func addPointer(node interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := getPointer(node, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+	switch typed := parent.(type) {
+	case map[string]interface{}:
+		typed[token] = value
+		return node, nil
+	case []interface{}:
+		index, err := arrayIndex(token, len(typed), true)
+		if err != nil {
+			return nil, err
+		}
+		typed = append(typed, nil)
+		copy(typed[index+1:], typed[index:])
+		typed[index] = value
+		return setPointer(node, path[:len(path)-1], typed)
+	}
+	return nil, fmt.Errorf("%s can't be added, its parent is neither an object nor an array", token)
+}
+
+// removePointer removes the value at path from node and returns node
+func removePointer(node interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("the whole document can't be removed")
+	}
+	parent, err := getPointer(node, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+	switch typed := parent.(type) {
+	case map[string]interface{}:
+		if _, ok := typed[token]; !ok {
+			return nil, fmt.Errorf("%s doesn't exist", token)
+		}
+		delete(typed, token)
+		return node, nil
+	case []interface{}:
+		index, err := arrayIndex(token, len(typed), false)
+		if err != nil {
+			return nil, err
+		}
+		typed = append(typed[:index:index], typed[index+1:]...)
+		return setPointer(node, path[:len(path)-1], typed)
+	}
+	return nil, fmt.Errorf("%s doesn't exist, its parent is neither an object nor an array", token)
+}
+
+// setPointer replaces the value at path in node, the arrays are replaced when their length changes
+func setPointer(node interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := getPointer(node, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+	switch typed := parent.(type) {
+	case map[string]interface{}:
+		typed[token] = value
+		return node, nil
+	case []interface{}:
+		index, err := arrayIndex(token, len(typed), false)
+		if err != nil {
+			return nil, err
+		}
+		typed[index] = value
+		return node, nil
+	}
+	return nil, fmt.Errorf("%s doesn't exist, its parent is neither an object nor an array", token)
+}
+
+func deepCopyJSON(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		copied := map[string]interface{}{}
+		for k, v := range typed {
+			copied[k] = deepCopyJSON(v)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(typed))
+		for i, v := range typed {
+			copied[i] = deepCopyJSON(v)
+		}
+		return copied
+	}
+	return value
+}
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"gopkg.in/check.v1"
+)
+
+type JSONPatchTestSuite struct{}
+
+var _ = check.Suite(&JSONPatchTestSuite{})
+
+func (s *JSONPatchTestSuite) TestOperations(c *check.C) {
+	doc := `{"name":"foo","nodeCount":1,"metadata":{"zone":"us-central1-a"},"endpoints":["a","c"]}`
+	for _, t := range []struct {
+		patch    string
+		expected string
+	}{
+		{`[{"op":"add","path":"/metadata/region","value":"us-central1"}]`,
+			`{"endpoints":["a","c"],"metadata":{"region":"us-central1","zone":"us-central1-a"},"name":"foo","nodeCount":1}`},
+		{`[{"op":"add","path":"/endpoints/1","value":"b"},{"op":"add","path":"/endpoints/-","value":"d"}]`,
+			`{"endpoints":["a","b","c","d"],"metadata":{"zone":"us-central1-a"},"name":"foo","nodeCount":1}`},
+		{`[{"op":"replace","path":"/nodeCount","value":3}]`,
+			`{"endpoints":["a","c"],"metadata":{"zone":"us-central1-a"},"name":"foo","nodeCount":3}`},
+		{`[{"op":"remove","path":"/metadata/zone"},{"op":"remove","path":"/endpoints/0"}]`,
+			`{"endpoints":["c"],"metadata":{},"name":"foo","nodeCount":1}`},
+		{`[{"op":"test","path":"/nodeCount","value":1},{"op":"copy","from":"/metadata","path":"/labels"},{"op":"move","from":"/endpoints","path":"/hosts"}]`,
+			`{"hosts":["a","c"],"labels":{"zone":"us-central1-a"},"metadata":{"zone":"us-central1-a"},"name":"foo","nodeCount":1}`},
+	} {
+		patched, err := ApplyJSONPatch([]byte(doc), []byte(t.patch))
+		c.Assert(err, check.IsNil, check.Commentf("%s", t.patch))
+		c.Assert(string(patched), check.Equals, t.expected, check.Commentf("%s", t.patch))
+	}
+}
+
+func (s *JSONPatchTestSuite) TestErrors(c *check.C) {
+	doc := `{"name":"foo","metadata":{"a~/b":"c"},"endpoints":["a"]}`
+	patched, err := ApplyJSONPatch([]byte(doc), []byte(`[{"op":"remove","path":"/metadata/a~0~1b"}]`))
+	c.Assert(err, check.IsNil)
+	c.Assert(string(patched), check.Equals, `{"endpoints":["a"],"metadata":{},"name":"foo"}`)
+
+	for patch, message := range map[string]string{
+		`{"op":"add"}`: "invalid JSON patch: .*",
+		`[{"op":"replace","path":"/missing","value":1}]`:                                  "operation 0 of the JSON patch: missing doesn't exist",
+		`[{"op":"remove","path":"/endpoints/1"}]`:                                         `operation 0 of the JSON patch: invalid index "1" of an array of 1 elements`,
+		`[{"op":"add","path":"name","value":1}]`:                                          `operation 0 of the JSON patch: invalid path "name", it must start with /`,
+		`[{"op":"add","path":"/name"}]`:                                                   "operation 0 of the JSON patch: add /name has no value",
+		`[{"op":"add","path":"/a","value":1},{"op":"test","path":"/name","value":"bar"}]`: "operation 1 of the JSON patch: test /name failed, the value is foo",
+		`[{"op":"move","from":"/metadata","path":"/metadata/nested"}]`:                    "operation 0 of the JSON patch: /metadata can't be moved into itself",
+		`[{"op":"merge","path":"/name"}]`:                                                 `operation 0 of the JSON patch: unknown operation "merge"`,
+	} {
+		_, err := ApplyJSONPatch([]byte(doc), []byte(patch))
+		c.Assert(err, check.ErrorMatches, message, check.Commentf("%s", patch))
+	}
+}
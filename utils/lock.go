@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+)
+
+// ErrLocked is returned by TryLockFile when another process, or another lock in this process, holds the lock
+var ErrLocked = errors.New("the lock is held by another command")
+
+// FileLock is an exclusive lock on a file, held across the processes of the machine until Unlock
+type FileLock struct {
+	file *os.File
+	path string
+}
+
+// TryLockFile takes the lock on the file at path, creating the file if needed, and writes holder to it so that the
+// commands waiting for the lock can tell who holds it. It returns ErrLocked right away if the lock is already held.
+func TryLockFile(path, holder string) (*FileLock, error) {
+	lock, err := tryLockFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := lock.file.Truncate(0); err != nil {
+		lock.Unlock()
+		return nil, err
+	}
+	if _, err := lock.file.WriteAt([]byte(holder), 0); err != nil {
+		lock.Unlock()
+		return nil, err
+	}
+	return lock, nil
+}
+
+// LockHolder returns the holder written to the lock file at path by the last TryLockFile, empty if it can't be read
+func LockHolder(path string) string {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
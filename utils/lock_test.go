@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"path/filepath"
+
+	"gopkg.in/check.v1"
+)
+
+type LockTestSuite struct {
+}
+
+var _ = check.Suite(&LockTestSuite{})
+
+func (s *LockTestSuite) TestTryLockFile(c *check.C) {
+	path := filepath.Join(c.MkDir(), "foo.lock")
+	lock, err := TryLockFile(path, "update (pid 1)")
+	c.Assert(err, check.IsNil)
+	c.Assert(LockHolder(path), check.Equals, "update (pid 1)")
+
+	_, err = TryLockFile(path, "remove (pid 2)")
+	c.Assert(err, check.Equals, ErrLocked)
+	c.Assert(LockHolder(path), check.Equals, "update (pid 1)")
+
+	// the lock can be taken again once released, the shorter holder replaces the previous one
+	c.Assert(lock.Unlock(), check.IsNil)
+	lock, err = TryLockFile(path, "rm (pid 3)")
+	c.Assert(err, check.IsNil)
+	c.Assert(LockHolder(path), check.Equals, "rm (pid 3)")
+	c.Assert(lock.Unlock(), check.IsNil)
+}
@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"os"
+)
+
+// tryLockFile creates the file exclusively, the lock is held as long as the file exists. A process killed while
+// holding it leaves the file behind, it has to be deleted by hand.
+func tryLockFile(path string) (*FileLock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		if os.IsExist(err) {
+			return nil, ErrLocked
+		}
+		return nil, err
+	}
+	return &FileLock{file: file, path: path}, nil
+}
+
+// Unlock releases the lock by deleting the file
+func (l *FileLock) Unlock() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	return os.Remove(l.path)
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// OperationField is the log field with the correlation id of the operation a log line belongs to
+const OperationField = "operation"
+
+// operationIDKey is the context key of the correlation id of an operation
+type operationIDKey struct{}
+
+// NewOperationID returns a random correlation id for an operation, e.g. a create
+func NewOperationID() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(id)
+}
+
+// WithOperationID returns a copy of ctx carrying id as the correlation id of the operation it runs
+func WithOperationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, operationIDKey{}, id)
+}
+
+// OperationID returns the correlation id carried by ctx, empty if it has none
+func OperationID(ctx context.Context) string {
+	id, _ := ctx.Value(operationIDKey{}).(string)
+	return id
+}
+
+// OperationLogger returns the logger of the operation of ctx, its lines have the correlation id as the operation field
+func OperationLogger(ctx context.Context) *logrus.Entry {
+	if id := OperationID(ctx); id != "" {
+		return logrus.WithField(OperationField, id)
+	}
+	return logrus.NewEntry(logrus.StandardLogger())
+}
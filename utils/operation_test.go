@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/check.v1"
+)
+
+type OperationTestSuite struct {
+}
+
+var _ = check.Suite(&OperationTestSuite{})
+
+func (s *OperationTestSuite) TestOperationID(c *check.C) {
+	c.Assert(OperationID(context.Background()), check.Equals, "")
+	id := NewOperationID()
+	c.Assert(id, check.Matches, "[0-9a-f]{16}")
+	c.Assert(NewOperationID(), check.Not(check.Equals), id)
+	c.Assert(OperationID(WithOperationID(context.Background(), id)), check.Equals, id)
+}
+
+func (s *OperationTestSuite) TestOperationLogger(c *check.C) {
+	logs := bytes.Buffer{}
+	logrus.SetOutput(&logs)
+	defer logrus.SetOutput(os.Stderr)
+
+	OperationLogger(context.Background()).Info("outside")
+	OperationLogger(WithOperationID(context.Background(), "abc123")).Info("inside")
+	c.Assert(logs.String(), check.Matches, `(?s)[^\n]*msg=outside\n[^\n]*msg=inside operation=abc123\n`)
+	c.Assert(logs.String(), check.Not(check.Matches), `(?s)[^\n]*outside[^\n]*operation=.*`)
+}
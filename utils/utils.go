@@ -5,18 +5,35 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strings"
 )
 
 const (
 	defaultFileName = "kubeconfig"
 )
 
+// writeFile is replaced in tests to inject write failures
+var writeFile = ioutil.WriteFile
+
 func WriteToFile(data []byte, file string) error {
-	if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+	return writeToFile(data, file, os.ModePerm, 0644)
+}
+
+// WritePrivateFile writes a file only readable by the current user, its parent directory is created with 0700
+func WritePrivateFile(data []byte, file string) error {
+	if err := writeToFile(data, file, 0700, 0600); err != nil {
+		return err
+	}
+	return os.Chmod(file, 0600)
+}
+
+func writeToFile(data []byte, file string, dirMode, fileMode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(file), dirMode); err != nil {
 		return err
 	}
 	if _, err := os.Stat(file); os.IsNotExist(err) {
-		return ioutil.WriteFile(file, data, 0644)
+		return ioutil.WriteFile(file, data, fileMode)
 	}
 
 	tmpfi, err := ioutil.TempFile(filepath.Dir(file), "file.tmp")
@@ -25,7 +42,7 @@ func WriteToFile(data []byte, file string) error {
 	}
 	defer os.Remove(tmpfi.Name())
 
-	if err = ioutil.WriteFile(tmpfi.Name(), data, 0644); err != nil {
+	if err = ioutil.WriteFile(tmpfi.Name(), data, fileMode); err != nil {
 		return err
 	}
 
@@ -41,6 +58,100 @@ func WriteToFile(data []byte, file string) error {
 	return err
 }
 
+// WriteDirectory replaces the files of dir with files keyed by name. The files are written to a temp directory first so
+// a failure never leaves a partially written dir behind. A new dir is renamed into place once all of them are written,
+// the files of an existing dir are renamed over the current ones so that it never disappears for its readers.
+func WriteDirectory(dir string, files map[string][]byte) error {
+	return writeDirectory(dir, files, os.ModePerm, 0755, 0644)
+}
+
+// WritePrivateDirectory is WriteDirectory for files only readable by the current user, dir is 0700 and the files are
+// 0600 whatever the previous dir was. The missing parents of dir are created with 0700.
+func WritePrivateDirectory(dir string, files map[string][]byte) error {
+	return writeDirectory(dir, files, 0700, 0700, 0600)
+}
+
+// stagingPrefix starts the names of the temp directories the files are written to
+const stagingPrefix = ".tmp"
+
+func writeDirectory(dir string, files map[string][]byte, parentMode, dirMode, fileMode os.FileMode) error {
+	parent := filepath.Dir(dir)
+	if err := os.MkdirAll(parent, parentMode); err != nil {
+		return err
+	}
+	if _, err := os.Stat(dir); err == nil {
+		return replaceFiles(dir, files, dirMode, fileMode)
+	}
+	tmpDir, err := ioutil.TempDir(parent, "."+filepath.Base(dir)+stagingPrefix)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	if err := os.Chmod(tmpDir, dirMode); err != nil {
+		return err
+	}
+	if _, err := writeFiles(tmpDir, files, fileMode); err != nil {
+		return err
+	}
+	return os.Rename(tmpDir, dir)
+}
+
+// replaceFiles writes files to a temp directory inside dir, then renames each of them over the current one and removes
+// the files of dir that aren't in files. Each file is replaced at once, a reader sees either the old or the new one.
+func replaceFiles(dir string, files map[string][]byte, dirMode, fileMode os.FileMode) error {
+	staging, err := ioutil.TempDir(dir, stagingPrefix)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+	names, err := writeFiles(staging, files, fileMode)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(dir, dirMode); err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := os.Rename(filepath.Join(staging, name), filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		// the temp directories of the other writes of dir are left to them
+		if _, ok := files[info.Name()]; ok || strings.HasPrefix(info.Name(), stagingPrefix) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, info.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFiles writes files to dir in name order and returns their names
+func writeFiles(dir string, files map[string][]byte, fileMode os.FileMode) ([]string, error) {
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := writeFile(path, files[name], fileMode); err != nil {
+			return nil, err
+		}
+		// the mode given to WriteFile is masked by the umask
+		if err := os.Chmod(path, fileMode); err != nil {
+			return nil, err
+		}
+	}
+	return names, nil
+}
+
 func HomeDir() string {
 	homeDir := ""
 	if runtime.GOOS == "windows" {
@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"gopkg.in/check.v1"
+)
+
+type WriteDirectoryTestSuite struct {
+	parent string
+}
+
+var _ = check.Suite(&WriteDirectoryTestSuite{})
+
+func (s *WriteDirectoryTestSuite) SetUpTest(c *check.C) {
+	s.parent = c.MkDir()
+}
+
+func (s *WriteDirectoryTestSuite) TearDownTest(c *check.C) {
+	writeFile = ioutil.WriteFile
+}
+
+// failOn makes the write of the file called name fail as if the disk was full
+func failOn(name string) {
+	writeFile = func(file string, data []byte, perm os.FileMode) error {
+		if filepath.Base(file) == name {
+			return errors.New("no space left on device")
+		}
+		return ioutil.WriteFile(file, data, perm)
+	}
+}
+
+func (s *WriteDirectoryTestSuite) readDir(c *check.C, dir string) map[string]string {
+	infos, err := ioutil.ReadDir(dir)
+	c.Assert(err, check.IsNil)
+	files := map[string]string{}
+	for _, info := range infos {
+		data, err := ioutil.ReadFile(filepath.Join(dir, info.Name()))
+		c.Assert(err, check.IsNil)
+		files[info.Name()] = string(data)
+	}
+	return files
+}
+
+func (s *WriteDirectoryTestSuite) listDir(c *check.C, dir string) []string {
+	infos, err := ioutil.ReadDir(dir)
+	c.Assert(err, check.IsNil)
+	names := []string{}
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	return names
+}
+
+var bundle = map[string][]byte{
+	"ca.pem":      []byte("ca"),
+	"cert.pem":    []byte("cert"),
+	"config.json": []byte("{}"),
+	"key.pem":     []byte("key"),
+}
+
+func (s *WriteDirectoryTestSuite) TestWriteFailureLeavesNoDirectory(c *check.C) {
+	dir := filepath.Join(s.parent, "foo")
+	// the files are written in name order, key.pem is the last one
+	failOn("key.pem")
+	c.Assert(WriteDirectory(dir, bundle), check.ErrorMatches, "no space left on device")
+
+	_, err := os.Stat(dir)
+	c.Assert(os.IsNotExist(err), check.Equals, true)
+	// the temp directory is cleaned up too
+	c.Assert(s.listDir(c, s.parent), check.HasLen, 0)
+}
+
+func (s *WriteDirectoryTestSuite) TestWriteFailureKeepsPreviousDirectory(c *check.C) {
+	dir := filepath.Join(s.parent, "foo")
+	c.Assert(WriteDirectory(dir, bundle), check.IsNil)
+
+	failOn("key.pem")
+	c.Assert(WriteDirectory(dir, map[string][]byte{
+		"ca.pem":      []byte("new ca"),
+		"config.json": []byte(`{"name":"foo"}`),
+		"key.pem":     []byte("new key"),
+	}), check.NotNil)
+	c.Assert(s.readDir(c, dir), check.DeepEquals, map[string]string{
+		"ca.pem":      "ca",
+		"cert.pem":    "cert",
+		"config.json": "{}",
+		"key.pem":     "key",
+	})
+	c.Assert(s.listDir(c, s.parent), check.DeepEquals, []string{"foo"})
+}
+
+func (s *WriteDirectoryTestSuite) TestWriteReplacesDirectory(c *check.C) {
+	dir := filepath.Join(s.parent, "clusters", "foo")
+	c.Assert(WriteDirectory(dir, bundle), check.IsNil)
+	c.Assert(WriteDirectory(dir, map[string][]byte{"config.json": []byte(`{"name":"foo"}`)}), check.IsNil)
+
+	c.Assert(s.readDir(c, dir), check.DeepEquals, map[string]string{"config.json": `{"name":"foo"}`})
+	c.Assert(s.listDir(c, filepath.Dir(dir)), check.DeepEquals, []string{"foo"})
+	info, err := os.Stat(dir)
+	c.Assert(err, check.IsNil)
+	c.Assert(info.Mode().Perm(), check.Equals, os.FileMode(0755))
+}
+
+func (s *WriteDirectoryTestSuite) TestWriteKeepsExistingDirectory(c *check.C) {
+	dir := filepath.Join(s.parent, "foo")
+	c.Assert(WriteDirectory(dir, bundle), check.IsNil)
+	before, err := os.Stat(dir)
+	c.Assert(err, check.IsNil)
+
+	// the current files are readable until the new ones replace them
+	writeFile = func(file string, data []byte, perm os.FileMode) error {
+		current, err := ioutil.ReadFile(filepath.Join(dir, "config.json"))
+		c.Check(err, check.IsNil)
+		c.Check(string(current), check.Equals, "{}")
+		return ioutil.WriteFile(file, data, perm)
+	}
+	c.Assert(WriteDirectory(dir, map[string][]byte{"config.json": []byte(`{"name":"foo"}`)}), check.IsNil)
+
+	// the files are replaced in the directory, it isn't swapped with a new one
+	after, err := os.Stat(dir)
+	c.Assert(err, check.IsNil)
+	c.Assert(os.SameFile(before, after), check.Equals, true)
+	c.Assert(s.readDir(c, dir), check.DeepEquals, map[string]string{"config.json": `{"name":"foo"}`})
+	c.Assert(s.listDir(c, s.parent), check.DeepEquals, []string{"foo"})
+}
+
+func (s *WriteDirectoryTestSuite) TestWritePrivateDirectory(c *check.C) {
+	if runtime.GOOS == "windows" {
+		c.Skip("file modes aren't enforced on windows")
+	}
+	dir := filepath.Join(s.parent, "clusters", "foo")
+	c.Assert(WriteDirectory(dir, bundle), check.IsNil)
+	c.Assert(WritePrivateDirectory(dir, bundle), check.IsNil)
+	// writing it again changes nothing
+	c.Assert(WritePrivateDirectory(dir, bundle), check.IsNil)
+
+	info, err := os.Stat(dir)
+	c.Assert(err, check.IsNil)
+	c.Assert(info.Mode().Perm(), check.Equals, os.FileMode(0700))
+	for name := range bundle {
+		info, err := os.Stat(filepath.Join(dir, name))
+		c.Assert(err, check.IsNil)
+		c.Assert(info.Mode().Perm(), check.Equals, os.FileMode(0600), check.Commentf("%s", name))
+	}
+	c.Assert(s.readDir(c, dir), check.DeepEquals, map[string]string{"ca.pem": "ca", "cert.pem": "cert", "config.json": "{}", "key.pem": "key"})
+}
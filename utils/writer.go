@@ -23,9 +23,7 @@ type TableWriter struct {
 }
 
 func NewTableWriter(values [][]string, ctx *cli.Context) *TableWriter {
-	t := &TableWriter{
-		Writer: tabwriter.NewWriter(os.Stdout, 10, 1, 3, ' ', 0),
-	}
+	t := &TableWriter{}
 	t.HeaderFormat, t.ValueFormat = SimpleFormat(values)
 
 	if ctx.Bool("quiet") {
@@ -34,7 +32,7 @@ func NewTableWriter(values [][]string, ctx *cli.Context) *TableWriter {
 	}
 
 	customFormat := ctx.String("format")
-	if customFormat == "json" {
+	if customFormat == "json" || ctx.GlobalString("output") == "json" {
 		t.HeaderFormat = ""
 		t.ValueFormat = "json"
 	} else if customFormat != "" {
@@ -42,6 +40,12 @@ func NewTableWriter(values [][]string, ctx *cli.Context) *TableWriter {
 		t.HeaderFormat = ""
 	}
 
+	// the header is in bold when the table goes to a terminal, see the global --color flag
+	out := io.Writer(os.Stdout)
+	if t.HeaderFormat != "" && UseColor(ctx.GlobalString("color"), os.Stdout) {
+		out = &headerColorWriter{out: os.Stdout}
+	}
+	t.Writer = tabwriter.NewWriter(out, 10, 1, 3, ' ', 0)
 	return t
 }
 